@@ -67,6 +67,17 @@ func (arguments Arguments) LengthNonIndexed() int {
 	return out
 }
 
+// NonIndexed returns the arguments with indexed arguments filtered out
+func (arguments Arguments) NonIndexed() Arguments {
+	var ret []Argument
+	for _, arg := range arguments {
+		if !arg.Indexed {
+			ret = append(ret, arg)
+		}
+	}
+	return ret
+}
+
 // isTuple returns true for non-atomic constructs, like (uint,uint) or uint[]
 func (arguments Arguments) isTuple() bool {
 	return len(arguments) > 1
@@ -165,6 +176,25 @@ func (arguments Arguments) unpackTuple(v interface{}, output []byte) error {
 	return nil
 }
 
+// UnpackValues unpacks the non-indexed arguments of hexdata without a typed
+// destination, returning their values in order.
+func (arguments Arguments) UnpackValues(data []byte) ([]interface{}, error) {
+	values := make([]interface{}, 0, arguments.LengthNonIndexed())
+	virtualArgs := 0
+	for index, arg := range arguments.NonIndexed() {
+		marshalledValue, err := toGoType((index+virtualArgs)*32, arg.Type, data)
+		if err != nil {
+			return nil, err
+		}
+		if arg.Type.T == ArrayTy {
+			// static arrays are laid out inline, occupying one word per element
+			virtualArgs += arg.Type.Size - 1
+		}
+		values = append(values, marshalledValue)
+	}
+	return values, nil
+}
+
 // unpackAtomic unpacks ( hexdata -> go ) a single value
 func (arguments Arguments) unpackAtomic(v interface{}, output []byte) error {
 	// make sure the passed value is arguments pointer
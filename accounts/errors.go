@@ -66,3 +66,14 @@ func NewAuthNeededError(needed string) error {
 func (err *AuthNeededError) Error() string {
 	return fmt.Sprintf("authentication needed: %s", err.Needed)
 }
+
+// SigningUnavailableError is returned by backends for signing requests on
+// accounts they track, but hold no keys for (e.g. watch-only addresses).
+type SigningUnavailableError struct {
+	Account Account // Account the signing was requested for
+}
+
+// Error implements the standard error interface.
+func (err *SigningUnavailableError) Error() string {
+	return fmt.Sprintf("signing unavailable for watch-only account %s", err.Account.Address.Hex())
+}
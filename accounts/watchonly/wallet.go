@@ -0,0 +1,113 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package watchonly
+
+import (
+	"math/big"
+
+	goola "github.com/goola-team/goola"
+	"github.com/goola-team/goola/accounts"
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+)
+
+// wallet implements the accounts.Wallet interface for a single watch-only
+// address.
+type wallet struct {
+	account accounts.Account // Watched account contained in this wallet
+}
+
+// newWallet wraps a watch-only address into a wallet.
+func newWallet(addr common.Address) *wallet {
+	return &wallet{
+		account: accounts.Account{
+			Address: addr,
+			URL:     accounts.URL{Scheme: URLScheme, Path: addr.Hex()},
+		},
+	}
+}
+
+// URL implements accounts.Wallet, returning the URL of the watched account.
+func (w *wallet) URL() accounts.URL {
+	return w.account.URL
+}
+
+// Status implements accounts.Wallet, always reporting the wallet as watch-only.
+func (w *wallet) Status() (string, error) {
+	return "Watch-only", nil
+}
+
+// Open implements accounts.Wallet, but is a noop since there are no keys to
+// decrypt or devices to connect to.
+func (w *wallet) Open(passphrase string) error { return nil }
+
+// Close implements accounts.Wallet, but is a noop since there is no meaningful
+// open operation.
+func (w *wallet) Close() error { return nil }
+
+// Accounts implements accounts.Wallet, returning an account list consisting of
+// the single watched account.
+func (w *wallet) Accounts() []accounts.Account {
+	return []accounts.Account{w.account}
+}
+
+// Contains implements accounts.Wallet, returning whether a particular account is
+// or is not wrapped by this wallet instance.
+func (w *wallet) Contains(account accounts.Account) bool {
+	return account.Address == w.account.Address && (account.URL == (accounts.URL{}) || account.URL == w.account.URL)
+}
+
+// Derive implements accounts.Wallet, but is not supported for watch-only
+// addresses.
+func (w *wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, accounts.ErrNotSupported
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop since there is no notion
+// of hierarchical account derivation for watch-only addresses.
+func (w *wallet) SelfDerive(base accounts.DerivationPath, chain goola.ChainStateReader) {}
+
+// signingUnavailable returns the error to report for any signing request on the
+// given account.
+func (w *wallet) signingUnavailable(account accounts.Account) error {
+	if !w.Contains(account) {
+		return accounts.ErrUnknownAccount
+	}
+	return &accounts.SigningUnavailableError{Account: w.account}
+}
+
+// SignHash implements accounts.Wallet, failing since no key is available.
+func (w *wallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return nil, w.signingUnavailable(account)
+}
+
+// SignTx implements accounts.Wallet, failing since no key is available.
+func (w *wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, w.signingUnavailable(account)
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, failing since no key is
+// available.
+func (w *wallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, w.signingUnavailable(account)
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, failing since no key is
+// available.
+func (w *wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, w.signingUnavailable(account)
+}
@@ -0,0 +1,188 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package watchonly implements an account backend tracking addresses without
+// holding any of their keys.
+package watchonly
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/goola-team/goola/accounts"
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/event"
+)
+
+// BackendType is the reflect type of the watch-only account backend.
+var BackendType = reflect.TypeOf(&Backend{})
+
+// URLScheme is the protocol scheme prefixing the URLs of watch-only accounts.
+const URLScheme = "watch"
+
+// Backend is an account backend of watch-only addresses. Each address is wrapped
+// into its own wallet, which lists the account like any other, but refuses to
+// sign anything with it.
+type Backend struct {
+	path    string                     // File persisting the watched addresses, empty if ephemeral
+	static  map[common.Address]bool    // Addresses from the configuration, not persisted
+	wallets map[common.Address]*wallet // Wallets of the currently watched addresses
+
+	updateFeed  event.Feed              // Event feed to notify wallet additions/removals
+	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
+
+	lock sync.RWMutex
+}
+
+// NewBackend creates a watch-only account backend, loading the addresses stored
+// at the given path, if any, and watching the static addresses given. Only the
+// addresses watched at runtime are persisted.
+func NewBackend(path string, static []common.Address) (*Backend, error) {
+	b := &Backend{
+		path:    path,
+		static:  make(map[common.Address]bool),
+		wallets: make(map[common.Address]*wallet),
+	}
+	if path != "" {
+		blob, err := ioutil.ReadFile(path)
+		switch {
+		case err == nil:
+			var stored []common.Address
+			if err := json.Unmarshal(blob, &stored); err != nil {
+				return nil, err
+			}
+			for _, addr := range stored {
+				b.wallets[addr] = newWallet(addr)
+			}
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+	}
+	for _, addr := range static {
+		if _, ok := b.wallets[addr]; !ok {
+			b.static[addr] = true
+			b.wallets[addr] = newWallet(addr)
+		}
+	}
+	return b, nil
+}
+
+// Wallets implements accounts.Backend, returning the wallets of all watched
+// addresses, sorted by URL.
+func (b *Backend) Wallets() []accounts.Wallet {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	wallets := make([]accounts.Wallet, 0, len(b.wallets))
+	for _, w := range b.wallets {
+		wallets = append(wallets, w)
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		return wallets[i].URL().Cmp(wallets[j].URL()) < 0
+	})
+	return wallets
+}
+
+// Subscribe implements accounts.Backend, creating an async subscription to
+// receive notifications on the addition or removal of watched addresses.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return b.updateScope.Track(b.updateFeed.Subscribe(sink))
+}
+
+// Watch starts tracking the given address as a watch-only account.
+func (b *Backend) Watch(addr common.Address) (accounts.Account, error) {
+	b.lock.Lock()
+	if w, ok := b.wallets[addr]; ok {
+		b.lock.Unlock()
+		return w.account, nil
+	}
+	w := newWallet(addr)
+	b.wallets[addr] = w
+	if err := b.store(); err != nil {
+		delete(b.wallets, addr)
+		b.lock.Unlock()
+		return accounts.Account{}, err
+	}
+	b.lock.Unlock()
+
+	b.updateFeed.Send(accounts.WalletEvent{Wallet: w, Kind: accounts.WalletArrived})
+	return w.account, nil
+}
+
+// Unwatch stops tracking the given watch-only address.
+func (b *Backend) Unwatch(addr common.Address) error {
+	b.lock.Lock()
+	w, ok := b.wallets[addr]
+	if !ok {
+		b.lock.Unlock()
+		return accounts.ErrUnknownAccount
+	}
+	delete(b.wallets, addr)
+	if err := b.store(); err != nil {
+		b.wallets[addr] = w
+		b.lock.Unlock()
+		return err
+	}
+	delete(b.static, addr)
+	b.lock.Unlock()
+
+	b.updateFeed.Send(accounts.WalletEvent{Wallet: w, Kind: accounts.WalletDropped})
+	return nil
+}
+
+// Watching returns whether the given address is tracked as watch-only.
+func (b *Backend) Watching(addr common.Address) bool {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	_, ok := b.wallets[addr]
+	return ok
+}
+
+// store persists the watched addresses, if the backend is not ephemeral. The
+// caller must hold the write lock.
+func (b *Backend) store() error {
+	if b.path == "" {
+		return nil
+	}
+	addresses := make([]common.Address, 0, len(b.wallets))
+	for addr := range b.wallets {
+		if !b.static[addr] {
+			addresses = append(addresses, addr)
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].Hex() < addresses[j].Hex()
+	})
+	blob, err := json.MarshalIndent(addresses, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(b.path, blob, 0600)
+}
+
+// Close terminates all live subscriptions of the backend.
+func (b *Backend) Close() {
+	b.updateScope.Close()
+}
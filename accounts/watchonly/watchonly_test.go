@@ -0,0 +1,84 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package watchonly
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/accounts"
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+)
+
+// Tests that watch-only accounts are listed by the account manager, refuse to
+// sign and survive a restart.
+func TestWatchOnlyAccounts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watchonly-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "watchonly.json")
+
+	static, dynamic := common.Address{0x01}, common.Address{0x02}
+	backend, err := NewBackend(path, []common.Address{static})
+	if err != nil {
+		t.Fatalf("failed to create backend: %v", err)
+	}
+	am := accounts.NewManager(backend)
+	defer am.Close()
+
+	if _, err := backend.Watch(dynamic); err != nil {
+		t.Fatalf("failed to watch address: %v", err)
+	}
+	// Wait for the manager to pick up the new wallet
+	for i := 0; i < 100 && len(am.Wallets()) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if wallets := am.Wallets(); len(wallets) != 2 {
+		t.Fatalf("wallet count mismatch: have %d, want 2", len(wallets))
+	}
+	wallet, err := am.Find(accounts.Account{Address: dynamic})
+	if err != nil {
+		t.Fatalf("failed to find watch-only account: %v", err)
+	}
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), types.TxTypeTransfer, nil)
+	if _, err := wallet.SignTx(accounts.Account{Address: dynamic}, tx, big.NewInt(1)); err == nil {
+		t.Fatalf("watch-only account signed transaction")
+	} else if _, ok := err.(*accounts.SigningUnavailableError); !ok {
+		t.Fatalf("signing error type mismatch: have %T, want *accounts.SigningUnavailableError", err)
+	}
+	// Reload the backend and ensure the runtime addition was persisted
+	reloaded, err := NewBackend(path, nil)
+	if err != nil {
+		t.Fatalf("failed to reload backend: %v", err)
+	}
+	if !reloaded.Watching(dynamic) || reloaded.Watching(static) {
+		t.Fatalf("persisted addresses mismatch: dynamic %v, static %v", reloaded.Watching(dynamic), reloaded.Watching(static))
+	}
+	if err := backend.Unwatch(dynamic); err != nil {
+		t.Fatalf("failed to unwatch address: %v", err)
+	}
+	if err := backend.Unwatch(dynamic); err != accounts.ErrUnknownAccount {
+		t.Fatalf("unwatching unknown address error mismatch: have %v, want %v", err, accounts.ErrUnknownAccount)
+	}
+}
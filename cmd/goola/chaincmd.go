@@ -34,6 +34,7 @@ import (
 	"github.com/goola-team/goola/goolabackend/downloader"
 	"github.com/goola-team/goola/gooladb"
 	"github.com/goola-team/goola/event"
+	"github.com/goola-team/goola/light"
 	"github.com/goola-team/goola/log"
 	"github.com/goola-team/goola/trie"
 	"github.com/syndtr/goleveldb/leveldb/util"
@@ -70,6 +71,8 @@ It expects the genesis file as argument.`,
 			utils.GCModeFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			importFastFlag,
+			importCheckpointFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -77,7 +80,20 @@ The import command imports blocks from an RLP-encoded form. The form can be one
 with several RLP-encoded blocks, or several files can be used.
 
 If only one file is used, import error will result in failure. If several files are used,
-processing will proceed even if an individual RLP-file import failure occurs.`,
+processing will proceed even if an individual RLP-file import failure occurs.
+
+With --fast, the blocks are decoded and their transaction senders recovered in
+parallel ahead of processing, and state commits are deferred into large batches.
+With --checkpoint, the file is first scanned to prove which blocks are ancestors of
+the given trusted block hash, and their seals are not verified again.`,
+	}
+	importFastFlag = cli.BoolFlag{
+		Name:  "fast",
+		Usage: "Import with parallel pre-verification and batched state commits",
+	}
+	importCheckpointFlag = cli.StringFlag{
+		Name:  "checkpoint",
+		Usage: "Trusted hash of an imported block, skipping the seal checks of its ancestors (implies --fast)",
 	}
 	exportCommand = cli.Command{
 		Action:    utils.MigrateFlags(exportChain),
@@ -95,6 +111,82 @@ Requires a first argument of the file to write to.
 Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing.`,
+	}
+	exportHeadersChtFlag = cli.BoolFlag{
+		Name:  "cht",
+		Usage: "Include the CHT roots of the exported sections",
+	}
+	exportHeadersCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportHeaders),
+		Name:      "export-headers",
+		Usage:     "Export the header chain into a compact archive",
+		ArgsUsage: "<filename> [<blockNumFirst> <blockNumLast>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.LightModeFlag,
+			exportHeadersChtFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Writes the canonical headers into a compact binary archive, which can be used
+to distribute verified header chains out-of-band. Optional second and third
+arguments control the first and last header to write, the entire chain is
+exported otherwise.
+
+With --cht the CHT roots of the complete sections known to the node are
+appended, allowing light clients to serve and verify requests right away.`,
+	}
+	importHeadersCommand = cli.Command{
+		Action:    utils.MigrateFlags(importHeaders),
+		Name:      "import-headers",
+		Usage:     "Import a header chain archive",
+		ArgsUsage: "<filename> (<filename 2> ... <filename N>) ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.LightModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The import-headers command imports header archives written by export-headers.
+Every header is verified before being written, CHT roots are only imported in
+light mode, for the sections matching the imported chain.`,
+	}
+	exportSectionsCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportSections),
+		Name:      "export-sections",
+		Usage:     "Export CHT and bloom trie sections into an archive",
+		ArgsUsage: "<filename> [<sectionFirst> <sectionLast>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.LightModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+Writes the complete CHT and bloom trie sections known to the node, including
+all their trie nodes, into a portable archive. Optional second and third
+arguments control the first and last section to write, all sections are
+exported otherwise.`,
+	}
+	importSectionsCommand = cli.Command{
+		Action:    utils.MigrateFlags(importSections),
+		Name:      "import-sections",
+		Usage:     "Import a CHT and bloom trie section archive into a light node",
+		ArgsUsage: "<filename> (<filename 2> ... <filename N>) ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.LightModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The import-sections command imports section archives written by export-sections
+into the light client database, sparing the light client from retrieving the
+sections on demand. Every section trie is verified to be complete and to match
+its root, the locally known canonical headers and the trusted checkpoint.
+Requires --light.`,
 	}
 	copydbCommand = cli.Command{
 		Action:    utils.MigrateFlags(copyDb),
@@ -201,15 +293,27 @@ func importChain(ctx *cli.Context) error {
 		}
 	}()
 	// Import the chain
+	var checkpoint common.Hash
+	if ctx.IsSet(importCheckpointFlag.Name) {
+		if err := checkpoint.UnmarshalText([]byte(ctx.String(importCheckpointFlag.Name))); err != nil {
+			utils.Fatalf("Invalid checkpoint hash: %v", err)
+		}
+	}
+	importFile := func(fn string) error {
+		if ctx.Bool(importFastFlag.Name) || checkpoint != (common.Hash{}) {
+			return utils.BulkImportChain(chain, fn, checkpoint)
+		}
+		return utils.ImportChain(chain, fn)
+	}
 	start := time.Now()
 
 	if len(ctx.Args()) == 1 {
-		if err := utils.ImportChain(chain, ctx.Args().First()); err != nil {
+		if err := importFile(ctx.Args().First()); err != nil {
 			log.Error("Import error", "err", err)
 		}
 	} else {
 		for _, arg := range ctx.Args() {
-			if err := utils.ImportChain(chain, arg); err != nil {
+			if err := importFile(arg); err != nil {
 				log.Error("Import error", "file", arg, "err", err)
 			}
 		}
@@ -290,6 +394,110 @@ func exportChain(ctx *cli.Context) error {
 	return nil
 }
 
+func exportHeaders(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+	start := time.Now()
+
+	first, last := uint64(0), chain.CurrentHeader().Number.Uint64()
+	if len(ctx.Args()) >= 3 {
+		f, ferr := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+		l, lerr := strconv.ParseUint(ctx.Args().Get(2), 10, 64)
+		if ferr != nil || lerr != nil {
+			utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
+		}
+		if f > l || l > last {
+			utils.Fatalf("Export error: invalid header range [%d, %d], head is #%d\n", f, l, last)
+		}
+		first, last = f, l
+	}
+	if err := utils.ExportHeaders(chainDb, ctx.Args().First(), first, last, ctx.Bool(exportHeadersChtFlag.Name)); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
+func importHeaders(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+	start := time.Now()
+
+	for _, arg := range ctx.Args() {
+		if err := utils.ImportHeaders(chain, chainDb, arg, ctx.GlobalBool(utils.LightModeFlag.Name)); err != nil {
+			log.Error("Import error", "file", arg, "err", err)
+		}
+	}
+	chain.Stop()
+	fmt.Printf("Import done in %v\n", time.Since(start))
+	return nil
+}
+
+func exportSections(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+	start := time.Now()
+
+	first, last := uint64(0), (chain.CurrentHeader().Number.Uint64()+1)/light.CHTFrequencyClient
+	if last == 0 {
+		utils.Fatalf("Export error: no complete sections, head is #%d\n", chain.CurrentHeader().Number)
+	}
+	last--
+	if len(ctx.Args()) >= 3 {
+		f, ferr := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+		l, lerr := strconv.ParseUint(ctx.Args().Get(2), 10, 64)
+		if ferr != nil || lerr != nil {
+			utils.Fatalf("Export error in parsing parameters: section number not an integer\n")
+		}
+		if f > l || l > last {
+			utils.Fatalf("Export error: invalid section range [%d, %d], last complete section is %d\n", f, l, last)
+		}
+		first, last = f, l
+	}
+	if err := utils.ExportSections(chainDb, ctx.Args().First(), first, last); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
+func importSections(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	if !ctx.GlobalBool(utils.LightModeFlag.Name) {
+		utils.Fatalf("Sections can only be imported into light nodes (--light)")
+	}
+	stack := makeFullNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+	start := time.Now()
+
+	chtIndexer, bloomTrieIndexer := light.NewChtIndexer(chainDb, true), light.NewBloomTrieIndexer(chainDb, true)
+	defer chtIndexer.Close()
+	defer bloomTrieIndexer.Close()
+
+	for _, arg := range ctx.Args() {
+		if err := utils.ImportSections(chainDb, arg, chtIndexer, bloomTrieIndexer); err != nil {
+			log.Error("Import error", "file", arg, "err", err)
+		}
+	}
+	fmt.Printf("Import done in %v\n", time.Since(start))
+	return nil
+}
+
 func copyDb(ctx *cli.Context) error {
 	// Ensure we have a source chain directory to copy
 	if len(ctx.Args()) != 1 {
@@ -29,8 +29,10 @@ import (
 
 	"github.com/goola-team/goola/cmd/utils"
 	"github.com/goola-team/goola/goolabackend"
+	"github.com/goola-team/goola/multisig"
 	"github.com/goola-team/goola/node"
 	"github.com/goola-team/goola/params"
+	"github.com/goola-team/goola/payments"
 	whisper "github.com/goola-team/goola/whisper/whisperv5"
 	"github.com/naoina/toml"
 )
@@ -74,10 +76,13 @@ type ethstatsConfig struct {
 }
 
 type gethConfig struct {
-	goola      goolabackend.Config
+	Goola      goolabackend.Config
 	Shh        whisper.Config
 	Node       node.Config
 	GoolaStats ethstatsConfig
+	Multisig   multisig.Config
+	Payments   payments.Config
+	Networks   []goolabackend.NetworkConfig `toml:",omitempty"`
 }
 
 func loadConfig(file string, cfg *gethConfig) error {
@@ -98,7 +103,7 @@ func loadConfig(file string, cfg *gethConfig) error {
 func defaultNodeConfig() node.Config {
 	cfg := node.DefaultConfig
 	cfg.Name = clientIdentifier
-	cfg.Version = params.VersionWithCommit(gitCommit)
+	cfg.Version = params.VersionWithCommit(gitCommit, gitDate)
 	cfg.HTTPModules = append(cfg.HTTPModules, "goolabackend", "shh")
 	cfg.WSModules = append(cfg.WSModules, "goolabackend", "shh")
 	cfg.IPCPath = "goola.ipc"
@@ -108,9 +113,10 @@ func defaultNodeConfig() node.Config {
 func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	// Load defaults.
 	cfg := gethConfig{
-		goola: goolabackend.DefaultConfig,
-		Shh:   whisper.DefaultConfig,
-		Node:  defaultNodeConfig(),
+		Goola:    goolabackend.DefaultConfig,
+		Shh:      whisper.DefaultConfig,
+		Node:     defaultNodeConfig(),
+		Payments: payments.DefaultConfig,
 	}
 
 	// Load config file.
@@ -126,16 +132,31 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	if err != nil {
 		utils.Fatalf("Failed to create the protocol stack: %v", err)
 	}
-	utils.SetEthConfig(ctx, stack, &cfg.goola)
+	utils.SetEthConfig(ctx, stack, &cfg.Goola)
 	if ctx.GlobalIsSet(utils.EthStatsURLFlag.Name) {
 		cfg.GoolaStats.URL = ctx.GlobalString(utils.EthStatsURLFlag.Name)
 	}
 
 	utils.SetShhConfig(ctx, stack, &cfg.Shh)
+	utils.SetMultisigConfig(ctx, &cfg.Multisig)
+	utils.SetPaymentsConfig(ctx, &cfg.Payments)
 
 	return stack, cfg
 }
 
+// reloadEthConfig reassembles the Goola service configuration the same way as
+// on startup: defaults, overridden by the config file, overridden by the flags.
+func reloadEthConfig(ctx *cli.Context, stack *node.Node) (*goolabackend.Config, error) {
+	cfg := gethConfig{Goola: goolabackend.DefaultConfig}
+	if file := ctx.GlobalString(configFileFlag.Name); file != "" {
+		if err := loadConfig(file, &cfg); err != nil {
+			return nil, err
+		}
+	}
+	utils.SetEthConfig(ctx, stack, &cfg.Goola)
+	return &cfg.Goola, nil
+}
+
 // enableWhisper returns true in case one of the whisper flags is set.
 func enableWhisper(ctx *cli.Context) bool {
 	for _, flag := range whisperFlags {
@@ -149,8 +170,12 @@ func enableWhisper(ctx *cli.Context) bool {
 func makeFullNode(ctx *cli.Context) *node.Node {
 	stack, cfg := makeConfigNode(ctx)
 
-	utils.RegisterEthService(stack, &cfg.goola)
+	utils.RegisterEthService(stack, &cfg.Goola)
 
+	// Run any additional networks configured alongside the primary one
+	if len(cfg.Networks) > 0 {
+		utils.RegisterNetworksService(stack, cfg.Networks)
+	}
 
 	// Whisper must be explicitly enabled by specifying at least 1 whisper flag or in dev mode
 	shhEnabled := enableWhisper(ctx)
@@ -169,6 +194,14 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	if cfg.GoolaStats.URL != "" {
 		utils.RegisterEthStatsService(stack, cfg.GoolaStats.URL)
 	}
+	// Add the multisig wallet tracker if any wallets are configured.
+	if len(cfg.Multisig.Wallets) > 0 {
+		utils.RegisterMultisigService(stack, &cfg.Multisig)
+	}
+	// Add the payment watcher if requested.
+	if cfg.Payments.Enabled {
+		utils.RegisterPaymentsService(stack, &cfg.Payments)
+	}
 	return stack
 }
 
@@ -177,8 +210,8 @@ func dumpConfig(ctx *cli.Context) error {
 	_, cfg := makeConfigNode(ctx)
 	comment := ""
 
-	if cfg.goola.Genesis != nil {
-		cfg.goola.Genesis = nil
+	if cfg.Goola.Genesis != nil {
+		cfg.Goola.Genesis = nil
 		comment += "# Note: this config doesn't contain the genesis block.\n\n"
 	}
 
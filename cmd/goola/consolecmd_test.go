@@ -31,7 +31,7 @@ import (
 )
 
 const (
-	ipcAPIs  = "admin:1.0 debug:1.0 goolabackend:1.0 miner:1.0 net:1.0 personal:1.0 rpc:1.0 shh:1.0 txpool:1.0 goolajs:1.0"
+	ipcAPIs  = "admin:1.0 debug:1.0 dpos:1.0 goolabackend:1.0 miner:1.0 net:1.0 personal:1.0 rpc:1.0 shh:1.0 txpool:1.0 goolajs:1.0"
 	httpAPIs = "goolabackend:1.0 net:1.0 rpc:1.0 goolajs:1.0"
 )
 
@@ -20,9 +20,11 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/signal"
 	"runtime"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/goola-team/goola/accounts"
@@ -36,6 +38,7 @@ import (
 	"github.com/goola-team/goola/log"
 	"github.com/goola-team/goola/metrics"
 	"github.com/goola-team/goola/node"
+	"github.com/goola-team/goola/params"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -46,6 +49,8 @@ const (
 var (
 	// Git SHA1 commit hash of the release (set via linker flags)
 	gitCommit = ""
+	// Date of the release commit as YYYYMMDD (set via linker flags)
+	gitDate = ""
 	// Goola address of the goola release oracle.
 	relOracle = common.HexToAddress("0xfa7b9770ca4cb04296cac84f37736d4041251cdf")
 	// The app that holds all commands and flags.
@@ -61,6 +66,7 @@ var (
 		utils.DataDirFlag,
 		utils.KeyStoreDirFlag,
 		utils.NoUSBFlag,
+		utils.WatchOnlyFlag,
 		utils.DashboardEnabledFlag,
 		utils.EthashCacheDirFlag,
 		utils.TxPoolNoLocalsFlag,
@@ -77,13 +83,32 @@ var (
 		utils.LightModeFlag,
 		utils.SyncModeFlag,
 		utils.GCModeFlag,
+		utils.InternalTxIndexFlag,
+		utils.BalanceHistoryFlag,
+		utils.BalanceHistoryStepFlag,
+		utils.MaxReorgDepthFlag,
+		utils.ProfileFlag,
+		utils.RetainBlocksFlag,
+		utils.BlobRetentionFlag,
+		utils.ParallelExecutionFlag,
+		utils.ImportBufferFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
+		utils.LightPaymentsFlag,
+		utils.LightBudgetHourlyFlag,
+		utils.LightBudgetDailyFlag,
+		utils.LightVerifyRecentFlag,
+		utils.LightRelayFlag,
 		utils.LightKDFFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheGCFlag,
 		utils.TrieCacheGenFlag,
+		utils.MaintenanceWindowsFlag,
+		utils.MaintenanceCompactionFlag,
+		utils.SnapshotListenFlag,
+		utils.SnapshotURLFlag,
+		utils.SnapshotSignerFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
@@ -94,6 +119,15 @@ var (
 		utils.TargetGasLimitFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
+		utils.NTPServerFlag,
+		utils.QuarantineDirFlag,
+		utils.QueryLimitPeerFlag,
+		utils.QueryLimitGlobalFlag,
+		utils.QueryLimitStrikesFlag,
+		utils.TxPrivacyFlag,
+		utils.TxPrivacyFanoutFlag,
+		utils.TxPrivacyDelayFlag,
+		utils.TxPrivacyRelaysFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
 		utils.NodeKeyFileFlag,
@@ -103,11 +137,25 @@ var (
 		utils.TestnetFlag,
 		utils.RinkebyFlag,
 		utils.VMEnableDebugFlag,
+		utils.VMTracerPluginsFlag,
+		utils.VMTracerSidecarsFlag,
 		utils.NetworkIdFlag,
 		utils.RPCCORSDomainFlag,
 		utils.RPCVirtualHostsFlag,
+		utils.RPCSlowQueryFlag,
+		utils.ArchiveURLFlag,
+		utils.ArchiveRateFlag,
+		utils.CallCacheFlag,
+		utils.CallCacheTTLFlag,
 		utils.EthStatsURLFlag,
+		utils.AlertsURLFlag,
+		utils.AlertsSecretFlag,
+		utils.MultisigWalletsFlag,
+		utils.PaymentsFlag,
+		utils.PaymentsURLFlag,
+		utils.PaymentsSecretFlag,
 		utils.MetricsEnabledFlag,
+		utils.TracingEndpointFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
@@ -126,6 +174,11 @@ var (
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.WSNotifyBatchFlag,
+		utils.WSNotifyDelayFlag,
+		utils.RPCTLSCertFlag,
+		utils.RPCTLSKeyFlag,
+		utils.RPCTLSClientCAFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 	}
@@ -138,6 +191,9 @@ var (
 )
 
 func init() {
+	// Expose the build metadata to the node's APIs
+	params.GitCommit, params.GitDate = gitCommit, gitDate
+
 	// Initialize the CLI app and start goola
 	app.Action = goola
 	app.HideVersion = true // we have a command to print the version
@@ -147,6 +203,10 @@ func init() {
 		initCommand,
 		importCommand,
 		exportCommand,
+		exportHeadersCommand,
+		importHeadersCommand,
+		exportSectionsCommand,
+		importSectionsCommand,
 		copydbCommand,
 		removedbCommand,
 		dumpCommand,
@@ -164,6 +224,8 @@ func init() {
 		licenseCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See selftestcmd.go
+		selfTestCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -267,6 +329,25 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 			}
 		}
 	}()
+	// Reload the runtime safe configuration on SIGHUP (full nodes only)
+	var fullGoola *goolabackend.FullGoola
+	if err := stack.Service(&fullGoola); err == nil {
+		fullGoola.SetConfigLoader(func() (*goolabackend.Config, error) {
+			return reloadEthConfig(ctx, stack)
+		})
+		go func() {
+			sighup := make(chan os.Signal, 1)
+			signal.Notify(sighup, syscall.SIGHUP)
+			defer signal.Stop(sighup)
+
+			for range sighup {
+				log.Info("Got SIGHUP, reloading configuration...")
+				if _, err := fullGoola.ReloadConfig(); err != nil {
+					log.Error("Failed to reload configuration", "err", err)
+				}
+			}
+		}()
+	}
 	// Start auxiliary services if enabled
 	if ctx.GlobalBool(utils.MiningEnabledFlag.Name) || ctx.GlobalBool(utils.DeveloperFlag.Name) {
 		// Mining only makes sense if a full Goola node is running
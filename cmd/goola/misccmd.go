@@ -59,6 +59,9 @@ func version(ctx *cli.Context) error {
 	if gitCommit != "" {
 		fmt.Println("Git Commit:", gitCommit)
 	}
+	if gitDate != "" {
+		fmt.Println("Git Commit Date:", gitDate)
+	}
 	fmt.Println("Architecture:", runtime.GOARCH)
 	fmt.Println("Protocol Versions:", goolabackend.ProtocolVersions)
 	fmt.Println("Network Id:", goolabackend.DefaultConfig.NetworkId)
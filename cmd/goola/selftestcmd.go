@@ -0,0 +1,71 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/goola-team/goola/cmd/utils"
+	"github.com/goola-team/goola/goolabackend"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var selfTestCommand = cli.Command{
+	Action:    utils.MigrateFlags(selfTest),
+	Name:      "selftest",
+	Usage:     "Validate the node setup without starting it",
+	ArgsUsage: "",
+	Flags:     append(append(nodeFlags, rpcFlags...), whisperFlags...),
+	Category:  "MISCELLANEOUS COMMANDS",
+	Description: `
+The selftest command checks the database, the genesis and chain configuration,
+the availability of the sealing key when mining, the listening ports and the
+free disk space with the given flags, reporting all problems found. It exits
+with an error if any check fails.`,
+}
+
+// selfTest runs the self-test of the node configured by the flags and prints
+// its report.
+func selfTest(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	env := goolabackend.SelfTestEnv{
+		DataDir: stack.ResolvePath("chaindata"),
+		Mining:  ctx.GlobalBool(utils.MiningEnabledFlag.Name),
+	}
+	for _, addr := range []string{cfg.Node.P2P.ListenAddr, cfg.Node.HTTPEndpoint(), cfg.Node.WSEndpoint()} {
+		if addr != "" {
+			env.Listeners = append(env.Listeners, addr)
+		}
+	}
+	report := goolabackend.SelfTest(chainDb, &cfg.Goola, stack.AccountManager(), env)
+	for _, check := range report.Checks {
+		status := "ok"
+		if !check.Passed {
+			status = "FAIL"
+		}
+		fmt.Printf("%-4s  %-8s  %s\n", status, check.Name, check.Detail)
+	}
+	if !report.Passed {
+		return errors.New("self-test failed")
+	}
+	return nil
+}
@@ -71,15 +71,36 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.DataDirFlag,
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
+			utils.WatchOnlyFlag,
 			utils.NetworkIdFlag,
 			utils.TestnetFlag,
 			utils.RinkebyFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
+			utils.InternalTxIndexFlag,
+			utils.BalanceHistoryFlag,
+			utils.BalanceHistoryStepFlag,
+			utils.MaxReorgDepthFlag,
+			utils.ProfileFlag,
+			utils.RetainBlocksFlag,
+			utils.BlobRetentionFlag,
+			utils.ParallelExecutionFlag,
+			utils.ImportBufferFlag,
 			utils.EthStatsURLFlag,
+			utils.AlertsURLFlag,
+			utils.AlertsSecretFlag,
+			utils.MultisigWalletsFlag,
+			utils.PaymentsFlag,
+			utils.PaymentsURLFlag,
+			utils.PaymentsSecretFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
+			utils.LightPaymentsFlag,
+			utils.LightBudgetHourlyFlag,
+			utils.LightBudgetDailyFlag,
+			utils.LightVerifyRecentFlag,
+			utils.LightRelayFlag,
 			utils.LightKDFFlag,
 		},
 	},
@@ -127,6 +148,11 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
 			utils.TrieCacheGenFlag,
+			utils.MaintenanceWindowsFlag,
+			utils.MaintenanceCompactionFlag,
+			utils.SnapshotListenFlag,
+			utils.SnapshotURLFlag,
+			utils.SnapshotSignerFlag,
 		},
 	},
 	{
@@ -148,10 +174,20 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.WSNotifyBatchFlag,
+			utils.WSNotifyDelayFlag,
+			utils.RPCTLSCertFlag,
+			utils.RPCTLSKeyFlag,
+			utils.RPCTLSClientCAFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
 			utils.RPCVirtualHostsFlag,
+			utils.RPCSlowQueryFlag,
+			utils.ArchiveURLFlag,
+			utils.ArchiveRateFlag,
+			utils.CallCacheFlag,
+			utils.CallCacheTTLFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -168,6 +204,15 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MaxPendingPeersFlag,
 			utils.NATFlag,
 			utils.NoDiscoverFlag,
+			utils.NTPServerFlag,
+			utils.QuarantineDirFlag,
+			utils.QueryLimitPeerFlag,
+			utils.QueryLimitGlobalFlag,
+			utils.QueryLimitStrikesFlag,
+			utils.TxPrivacyFlag,
+			utils.TxPrivacyFanoutFlag,
+			utils.TxPrivacyDelayFlag,
+			utils.TxPrivacyRelaysFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
 			utils.NodeKeyFileFlag,
@@ -196,12 +241,15 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "VIRTUAL MACHINE",
 		Flags: []cli.Flag{
 			utils.VMEnableDebugFlag,
+			utils.VMTracerPluginsFlag,
+			utils.VMTracerSidecarsFlag,
 		},
 	},
 	{
 		Name: "LOGGING AND DEBUGGING",
 		Flags: append([]cli.Flag{
 			utils.MetricsEnabledFlag,
+			utils.TracingEndpointFlag,
 			utils.FakePoWFlag,
 			utils.NoCompactionFlag,
 		}, debug.Flags...),
@@ -26,9 +26,12 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/goola-team/goola/common"
 	"github.com/goola-team/goola/core"
 	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/gooladb"
 	"github.com/goola-team/goola/internal/debug"
+	"github.com/goola-team/goola/light"
 	"github.com/goola-team/goola/log"
 	"github.com/goola-team/goola/node"
 	"github.com/goola-team/goola/rlp"
@@ -179,6 +182,61 @@ func missingBlocks(chain *core.BlockChain, blocks []*types.Block) []*types.Block
 	return nil
 }
 
+// BulkImportChain imports a chain bundle through the parallel bulk import path,
+// optionally skipping the seal checks of the ancestors of a trusted checkpoint.
+func BulkImportChain(chain *core.BlockChain, fn string, checkpoint common.Hash) error {
+	// Watch for Ctrl-C while the import is running.
+	// If a signal is received, the import will stop at the next batch.
+	interrupt := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+	defer close(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info("Interrupted during import, stopping at next batch")
+		}
+		close(stop)
+	}()
+	log.Info("Bulk importing blockchain", "file", fn, "checkpoint", checkpoint)
+
+	config := core.DefaultBulkImportConfig
+	config.Checkpoint = checkpoint
+	config.Interrupt = stop
+
+	n, err := chain.BulkImport(func() (io.ReadCloser, error) { return openBundle(fn) }, config)
+	log.Info("Bulk imported blockchain", "file", fn, "blocks", n)
+	return err
+}
+
+// gzipBundle is a gzip compressed chain bundle, closing its file when closed.
+type gzipBundle struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (b *gzipBundle) Close() error {
+	b.Reader.Close()
+	return b.file.Close()
+}
+
+// openBundle opens a chain bundle, decompressing it if gzipped.
+func openBundle(fn string) (io.ReadCloser, error) {
+	fh, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(fn, ".gz") {
+		return fh, nil
+	}
+	reader, err := gzip.NewReader(fh)
+	if err != nil {
+		fh.Close()
+		return nil, err
+	}
+	return &gzipBundle{Reader: reader, file: fh}, nil
+}
+
 func ExportChain(blockchain *core.BlockChain, fn string) error {
 	log.Info("Exporting blockchain", "file", fn)
 	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
@@ -222,3 +280,230 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 	log.Info("Exported blockchain to", "file", fn)
 	return nil
 }
+
+// ExportHeaders writes the canonical headers first..last of the chain into a
+// header archive. If cht is set, the CHT roots of the exported sections known
+// to the database are appended too.
+func ExportHeaders(db gooladb.Database, fn string, first uint64, last uint64, cht bool) error {
+	log.Info("Exporting header chain", "file", fn, "first", first, "last", last)
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	archive, err := light.NewHeaderArchiveWriter(writer, core.GetCanonicalHash(db, 0))
+	if err != nil {
+		return err
+	}
+	for nr := first; nr <= last; nr++ {
+		hash := core.GetCanonicalHash(db, nr)
+		header := core.GetHeader(db, hash, nr)
+		if header == nil {
+			return fmt.Errorf("header #%d not found", nr)
+		}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+	}
+	if cht {
+		sections := 0
+		for section := first / light.CHTFrequencyClient; (section+1)*light.CHTFrequencyClient-1 <= last; section++ {
+			head := core.GetCanonicalHash(db, (section+1)*light.CHTFrequencyClient-1)
+			// Servers index LES/2 sized sections, clients LES/1 sized ones
+			root := light.GetChtV2Root(db, section, head)
+			if root == (common.Hash{}) {
+				root = light.GetChtRoot(db, section, head)
+			}
+			if root == (common.Hash{}) {
+				continue
+			}
+			if err := archive.WriteChtRoot(&light.ChtRootEntry{Section: section, Head: head, Root: root}); err != nil {
+				return err
+			}
+			sections++
+		}
+		log.Info("Exported CHT roots", "sections", sections)
+	}
+	if err := archive.Flush(); err != nil {
+		return err
+	}
+	log.Info("Exported header chain", "file", fn)
+	return nil
+}
+
+// ImportHeaders imports a header archive into the chain, verifying the seal of
+// every header. CHT roots are only imported into light client databases, and
+// only for sections whose head matches the imported canonical chain.
+func ImportHeaders(chain *core.BlockChain, db gooladb.Database, fn string, lightMode bool) error {
+	log.Info("Importing header chain", "file", fn)
+	fh, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return err
+		}
+	}
+	archive, err := light.NewHeaderArchiveReader(reader)
+	if err != nil {
+		return err
+	}
+	if genesis := chain.Genesis().Hash(); archive.Genesis() != genesis {
+		return fmt.Errorf("genesis mismatch: archive %x, local %x", archive.Genesis(), genesis)
+	}
+	var (
+		batch    = make([]*types.Header, 0, importBatchSize)
+		imported int
+		roots    int
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if i, err := chain.InsertHeaderChain(batch, 1); err != nil {
+			return fmt.Errorf("invalid header #%d: %v", batch[i].Number, err)
+		}
+		imported += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+	for {
+		entry, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("after %d headers: %v", imported+len(batch), err)
+		}
+		if header := entry.Header; header != nil {
+			// don't import the genesis header
+			if header.Number.Sign() == 0 {
+				continue
+			}
+			if batch = append(batch, header); len(batch) == importBatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+		if !lightMode {
+			continue
+		}
+		cht := entry.ChtRoot
+		if head := core.GetCanonicalHash(db, (cht.Section+1)*light.CHTFrequencyClient-1); head != cht.Head {
+			log.Warn("Skipping CHT root of non-canonical section", "section", cht.Section, "head", cht.Head)
+			continue
+		}
+		light.StoreChtRoot(db, cht.Section, cht.Head, cht.Root)
+		roots++
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	log.Info("Imported header chain", "file", fn, "headers", imported, "cht", roots)
+	return nil
+}
+
+// ExportSections writes the CHT and bloom trie sections first..last known to
+// the database into a section archive. Sections missing from the database are
+// skipped.
+func ExportSections(db gooladb.Database, fn string, first uint64, last uint64) error {
+	log.Info("Exporting helper trie sections", "file", fn, "first", first, "last", last)
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	if strings.HasSuffix(fn, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	archive, err := light.NewSectionArchiveWriter(writer, core.GetCanonicalHash(db, 0))
+	if err != nil {
+		return err
+	}
+	exported := 0
+	for section := first; section <= last; section++ {
+		for _, kind := range []uint8{light.SectionCht, light.SectionBloomTrie} {
+			sec, err := light.ReadHelperTrieSection(db, kind, section)
+			if err != nil {
+				return fmt.Errorf("section %d: %v", section, err)
+			}
+			if sec == nil {
+				log.Warn("Skipping unknown section", "kind", kind, "section", section)
+				continue
+			}
+			if err := archive.WriteSection(sec); err != nil {
+				return err
+			}
+			exported++
+		}
+	}
+	if err := archive.Flush(); err != nil {
+		return err
+	}
+	log.Info("Exported helper trie sections", "file", fn, "sections", exported)
+	return nil
+}
+
+// ImportSections imports a section archive into a light client database. Every
+// section is verified against the local header chain and trusted checkpoint
+// before being written, and marked as known by the indexer of its kind.
+func ImportSections(db gooladb.Database, fn string, chtIndexer, bloomTrieIndexer *core.ChainIndexer) error {
+	log.Info("Importing helper trie sections", "file", fn)
+	fh, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return err
+		}
+	}
+	archive, err := light.NewSectionArchiveReader(reader)
+	if err != nil {
+		return err
+	}
+	if genesis := core.GetCanonicalHash(db, 0); archive.Genesis() != genesis {
+		return fmt.Errorf("genesis mismatch: archive %x, local %x", archive.Genesis(), genesis)
+	}
+	var (
+		indexers = map[uint8]*core.ChainIndexer{light.SectionCht: chtIndexer, light.SectionBloomTrie: bloomTrieIndexer}
+		imported int
+	)
+	for {
+		sec, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("after %d sections: %v", imported, err)
+		}
+		if err := light.WriteHelperTrieSection(db, sec); err != nil {
+			return fmt.Errorf("section %d (kind %d): %v", sec.Section, sec.Kind, err)
+		}
+		if indexer := indexers[sec.Kind]; indexer != nil {
+			indexer.AddKnownSectionHead(sec.Section, sec.Head)
+		}
+		imported++
+	}
+	log.Info("Imported helper trie sections", "file", fn, "sections", imported)
+	return nil
+}
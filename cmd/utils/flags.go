@@ -41,11 +41,14 @@ import (
 	"github.com/goola-team/goola/consensus/dpos"
 	"github.com/goola-team/goola/goolabackend/downloader"
 	"github.com/goola-team/goola/goolabackend/gasprice"
+	"github.com/goola-team/goola/goolabackend/tracers"
 	"github.com/goola-team/goola/gooladb"
 	"github.com/goola-team/goola/goolastats"
+	"github.com/goola-team/goola/internal/ethapi"
 	"github.com/goola-team/goola/les"
 	"github.com/goola-team/goola/log"
 	"github.com/goola-team/goola/metrics"
+	"github.com/goola-team/goola/multisig"
 	"github.com/goola-team/goola/node"
 	"github.com/goola-team/goola/p2p"
 	"github.com/goola-team/goola/p2p/discover"
@@ -53,6 +56,7 @@ import (
 	"github.com/goola-team/goola/p2p/nat"
 	"github.com/goola-team/goola/p2p/netutil"
 	"github.com/goola-team/goola/params"
+	"github.com/goola-team/goola/payments"
 	whisper "github.com/goola-team/goola/whisper/whisperv5"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -124,6 +128,11 @@ var (
 		Name:  "nousb",
 		Usage: "Disables monitoring for and managing USB hardware wallets",
 	}
+	WatchOnlyFlag = cli.StringFlag{
+		Name:  "watchonly",
+		Usage: "Comma separated list of addresses to track as watch-only accounts",
+		Value: "",
+	}
 	NetworkIdFlag = cli.Uint64Flag{
 		Name:  "networkid",
 		Usage: "Network identifier (integer, 1=Frontier, 2=Morden (disused), 3=Ropsten, 4=Rinkeby)",
@@ -165,24 +174,83 @@ var (
 	defaultSyncMode = goolabackend.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "snap", or "light")`,
 		Value: &defaultSyncMode,
 	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
-		Usage: `Blockchain garbage collection mode ("full", "archive")`,
+		Usage: `Blockchain garbage collection mode ("full", "archive", "diff")`,
 		Value: "full",
 	}
+	InternalTxIndexFlag = cli.BoolFlag{
+		Name:  "index.internaltxs",
+		Usage: "Record the internal value transfers of imported blocks (goola_getInternalTransactions)",
+	}
+	BalanceHistoryFlag = cli.StringFlag{
+		Name:  "index.balances",
+		Usage: "Comma separated accounts whose balance history is recorded at import (goola_balanceHistory)",
+	}
+	BalanceHistoryStepFlag = cli.Uint64Flag{
+		Name:  "index.balances.step",
+		Usage: "Record the balance history at every Nth imported block only (0 = every block)",
+	}
+	MaxReorgDepthFlag = cli.Uint64Flag{
+		Name:  "reorg.maxdepth",
+		Usage: "Maximum number of blocks a chain reorg may drop, deeper ones are rejected (0 = unlimited)",
+	}
+	ProfileFlag = cli.StringFlag{
+		Name:  "profile",
+		Usage: `Node mode preset ("archive", "full", "light-server", "rpc-gateway")`,
+	}
+	RetainBlocksFlag = cli.Uint64Flag{
+		Name:  "history.retain",
+		Usage: "Number of recent blocks to retain bodies and receipts of, older ones keep headers only (0 = all)",
+	}
+	BlobRetentionFlag = cli.Uint64Flag{
+		Name:  "blobs.retain",
+		Usage: "Number of recent blocks to retain transaction data blobs of (0 = all)",
+		Value: goolabackend.DefaultConfig.BlobRetention,
+	}
+	ParallelExecutionFlag = cli.BoolFlag{
+		Name:  "exec.parallel",
+		Usage: "Execute the transactions of imported blocks in parallel, re-executing conflicting ones serially",
+	}
+	ImportBufferFlag = cli.IntFlag{
+		Name:  "import.buffer",
+		Usage: "Megabytes of memory to buffer fast sync imports in, backed by a write-ahead log (0 = disabled)",
+	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (0-90)",
 		Value: 0,
 	}
+	LightBudgetHourlyFlag = cli.IntFlag{
+		Name:  "lightbudget.hourly",
+		Usage: "Maximum LES traffic per hour in MB for light clients on metered connections (0 = unlimited)",
+		Value: 0,
+	}
+	LightBudgetDailyFlag = cli.IntFlag{
+		Name:  "lightbudget.daily",
+		Usage: "Maximum LES traffic per day in MB for light clients on metered connections (0 = unlimited)",
+		Value: 0,
+	}
+	LightVerifyRecentFlag = cli.Uint64Flag{
+		Name:  "lightsync.verifyrecent",
+		Usage: "Number of most recent headers fully verified by light sync, older ones are sampled (0 = verify all)",
+	}
+	LightRelayFlag = cli.StringFlag{
+		Name:  "lightrelay",
+		Usage: "Comma separated trusted JSON-RPC servers satisfying light client requests when no LES server can",
+	}
 	LightPeersFlag = cli.IntFlag{
 		Name:  "lightpeers",
 		Usage: "Maximum number of LES client peers",
 		Value: goolabackend.DefaultConfig.LightPeers,
 	}
+	LightPaymentsFlag = cli.BoolFlag{
+		Name:  "lightpayments",
+		Usage: "Serve LES clients with a prepaid balance with priority, charging their requests against it",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -269,6 +337,26 @@ var (
 		Usage: "Number of trie node generations to keep in memory",
 		Value: int(state.MaxTrieCacheGen),
 	}
+	MaintenanceWindowsFlag = cli.StringFlag{
+		Name:  "maintenance.windows",
+		Usage: "Comma separated daily UTC windows (HH:MM-HH:MM) to run heavy maintenance tasks in",
+	}
+	MaintenanceCompactionFlag = cli.DurationFlag{
+		Name:  "maintenance.compaction",
+		Usage: "Interval of the full database compactions (0 = disabled)",
+	}
+	SnapshotListenFlag = cli.StringFlag{
+		Name:  "snapshot.listen",
+		Usage: "HTTP listening address to seed chain snapshots on (empty = disabled)",
+	}
+	SnapshotURLFlag = cli.StringFlag{
+		Name:  "snapshot.url",
+		Usage: "Seeder URL to bootstrap an empty chain from before syncing",
+	}
+	SnapshotSignerFlag = cli.StringFlag{
+		Name:  "snapshot.signer",
+		Usage: "Node account (address of the node key) trusted to sign fetched snapshots",
+	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -314,15 +402,52 @@ var (
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	VMTracerPluginsFlag = DirectoryFlag{
+		Name:  "vmtrace.plugins",
+		Usage: "Directory of Go plugin tracers selectable as \"plugin:<name>\" in debug_trace calls",
+	}
+	VMTracerSidecarsFlag = cli.StringFlag{
+		Name:  "vmtrace.sidecars",
+		Usage: "Comma separated name=url tracer sidecars selectable as \"sidecar:<name>\" in debug_trace calls",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "goolastats",
 		Usage: "Reporting URL of a goolastats service (nodename:secret@host:port)",
 	}
+	AlertsURLFlag = cli.StringFlag{
+		Name:  "alerts.url",
+		Usage: "Webhook URL to POST chain health alerts to (deep reorgs, bad blocks, stalls, low peer count)",
+	}
+	AlertsSecretFlag = cli.StringFlag{
+		Name:  "alerts.secret",
+		Usage: "Secret key signing the alert payloads (HMAC-SHA256)",
+	}
+	MultisigWalletsFlag = cli.StringFlag{
+		Name:  "multisig",
+		Usage: "Comma separated list of multisig wallet contracts to track proposals of",
+		Value: "",
+	}
+	PaymentsFlag = cli.BoolFlag{
+		Name:  "payments",
+		Usage: "Watch imported blocks for value and token transfers to the keystore accounts",
+	}
+	PaymentsURLFlag = cli.StringFlag{
+		Name:  "payments.url",
+		Usage: "Webhook URL to POST incoming payments to the keystore accounts to",
+	}
+	PaymentsSecretFlag = cli.StringFlag{
+		Name:  "payments.secret",
+		Usage: "Secret key signing the payment notification payloads (HMAC-SHA256)",
+	}
 	MetricsEnabledFlag = cli.BoolFlag{
 		Name:  metrics.MetricsEnabledFlag,
 		Usage: "Enable metrics collection and reporting",
 	}
+	TracingEndpointFlag = cli.StringFlag{
+		Name:  "tracing.endpoint",
+		Usage: "OpenTelemetry collector endpoint to export traces to over OTLP/HTTP (e.g. http://localhost:4318/v1/traces)",
+	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -356,11 +481,39 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: "localhost",
 	}
+	RPCSlowQueryFlag = cli.DurationFlag{
+		Name:  "rpcslowquery",
+		Usage: "Minimum duration of RPC requests logged as slow queries (0 = disabled)",
+	}
+	ArchiveURLFlag = cli.StringFlag{
+		Name:  "archive.url",
+		Usage: "RPC endpoint of an archive node to forward calls failing on pruned data to",
+	}
+	ArchiveRateFlag = cli.IntFlag{
+		Name:  "archive.rate",
+		Usage: "Maximum number of calls forwarded to the archive node per second (0 = unlimited)",
+		Value: node.DefaultConfig.ArchiveRate,
+	}
 	RPCApiFlag = cli.StringFlag{
 		Name:  "rpcapi",
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCTLSCertFlag = cli.StringFlag{
+		Name:  "rpctlscert",
+		Usage: "PEM certificate file to serve the HTTP-RPC and WS-RPC interfaces over TLS",
+		Value: "",
+	}
+	RPCTLSKeyFlag = cli.StringFlag{
+		Name:  "rpctlskey",
+		Usage: "PEM private key file of the HTTP-RPC and WS-RPC TLS certificate",
+		Value: "",
+	}
+	RPCTLSClientCAFlag = cli.StringFlag{
+		Name:  "rpctlsclientca",
+		Usage: "PEM bundle of the authorities signing client certificates of privileged RPC tiers",
+		Value: "",
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -393,6 +546,16 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	WSNotifyBatchFlag = cli.IntFlag{
+		Name:  "wsnotifybatch",
+		Usage: "Maximum number of subscription notifications batched into one WS frame for opted-in clients (<2 = disabled)",
+		Value: node.DefaultConfig.WSNotifyBatchSize,
+	}
+	WSNotifyDelayFlag = cli.DurationFlag{
+		Name:  "wsnotifydelay",
+		Usage: "Maximum time a subscription notification is held back for batching",
+		Value: node.DefaultConfig.WSNotifyBatchDelay,
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -454,6 +617,47 @@ var (
 		Name:  "v5disc",
 		Usage: "Enables the experimental RLPx V5 (Topic Discovery) mechanism",
 	}
+	NTPServerFlag = cli.StringFlag{
+		Name:  "ntp.server",
+		Usage: "NTP server to estimate the local clock drift against, compensated for in block validation (e.g. pool.ntp.org, disabled by default)",
+	}
+	QuarantineDirFlag = DirectoryFlag{
+		Name:  "p2p.quarantine",
+		Usage: "Directory to persist malformed and protocol-violating peer messages into (empty disables)",
+	}
+	QueryLimitPeerFlag = cli.Uint64Flag{
+		Name:  "querylimit.peer",
+		Usage: "Data retrieval query budget refilled per second for each peer (0 = unlimited)",
+		Value: goolabackend.DefaultQueryLimits.PeerRate,
+	}
+	QueryLimitGlobalFlag = cli.Uint64Flag{
+		Name:  "querylimit.global",
+		Usage: "Data retrieval query budget refilled per second shared by all peers (0 = unlimited)",
+		Value: goolabackend.DefaultQueryLimits.GlobalRate,
+	}
+	QueryLimitStrikesFlag = cli.IntFlag{
+		Name:  "querylimit.strikes",
+		Usage: "Rate limited queries per minute tolerated before dropping a peer (0 = never drop)",
+		Value: goolabackend.DefaultQueryLimits.MaxStrikes,
+	}
+	TxPrivacyFlag = cli.BoolFlag{
+		Name:  "txprivacy",
+		Usage: "Forward local transactions to a few peers after a random delay instead of broadcasting them",
+	}
+	TxPrivacyFanoutFlag = cli.IntFlag{
+		Name:  "txprivacy.fanout",
+		Usage: "Random peers a local transaction is forwarded to",
+		Value: goolabackend.DefaultTxPrivacy.Fanout,
+	}
+	TxPrivacyDelayFlag = cli.DurationFlag{
+		Name:  "txprivacy.delay",
+		Usage: "Maximum random delay before forwarding a local transaction",
+		Value: goolabackend.DefaultTxPrivacy.MaxDelay,
+	}
+	TxPrivacyRelaysFlag = cli.StringFlag{
+		Name:  "txprivacy.relays",
+		Usage: "Comma separated enode URLs to forward local transactions to instead of random peers",
+	}
 	NetrestrictFlag = cli.StringFlag{
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
@@ -477,6 +681,16 @@ var (
 		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices",
 		Value: goolabackend.DefaultConfig.GPO.Percentile,
 	}
+	CallCacheFlag = cli.IntFlag{
+		Name:  "rpc.callcache",
+		Usage: "Number of call results on sealed blocks to cache (0 = disabled)",
+		Value: goolabackend.DefaultConfig.CallCache.Size,
+	}
+	CallCacheTTLFlag = cli.DurationFlag{
+		Name:  "rpc.callcachettl",
+		Usage: "Maximum time a call result is cached for (0 = unlimited)",
+		Value: goolabackend.DefaultConfig.CallCache.TTL,
+	}
 	WhisperEnabledFlag = cli.BoolFlag{
 		Name:  "shh",
 		Usage: "Enable Whisper",
@@ -653,6 +867,20 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
 }
 
+// setRPCTLS configures the TLS certificates of the HTTP and WebSocket RPC
+// interfaces from the set command line flags.
+func setRPCTLS(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCTLSCertFlag.Name) {
+		cfg.RPCTLSCert = ctx.GlobalString(RPCTLSCertFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTLSKeyFlag.Name) {
+		cfg.RPCTLSKey = ctx.GlobalString(RPCTLSKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTLSClientCAFlag.Name) {
+		cfg.RPCTLSClientCA = ctx.GlobalString(RPCTLSClientCAFlag.Name)
+	}
+}
+
 // setWS creates the WebSocket RPC listener interface string from the set
 // command line flags, returning empty if the HTTP endpoint is disabled.
 func setWS(ctx *cli.Context, cfg *node.Config) {
@@ -672,6 +900,12 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = splitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(WSNotifyBatchFlag.Name) {
+		cfg.WSNotifyBatchSize = ctx.GlobalInt(WSNotifyBatchFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSNotifyDelayFlag.Name) {
+		cfg.WSNotifyBatchDelay = ctx.GlobalDuration(WSNotifyDelayFlag.Name)
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -806,6 +1040,14 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		cfg.DiscoveryV5 = true
 	}
 
+	if ctx.GlobalIsSet(NTPServerFlag.Name) {
+		cfg.NTPServer = ctx.GlobalString(NTPServerFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(QuarantineDirFlag.Name) {
+		cfg.QuarantineDir = ctx.GlobalString(QuarantineDirFlag.Name)
+	}
+
 	if netrestrict := ctx.GlobalString(NetrestrictFlag.Name); netrestrict != "" {
 		list, err := netutil.ParseNetlist(netrestrict)
 		if err != nil {
@@ -829,8 +1071,22 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setRPCTLS(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
+	if ctx.GlobalIsSet(RPCSlowQueryFlag.Name) {
+		cfg.RPCSlowQueryThreshold = ctx.GlobalDuration(RPCSlowQueryFlag.Name)
+	}
+	if ctx.GlobalIsSet(TracingEndpointFlag.Name) {
+		cfg.TracingEndpoint = ctx.GlobalString(TracingEndpointFlag.Name)
+	}
+	if ctx.GlobalIsSet(ArchiveURLFlag.Name) {
+		cfg.ArchiveURL = ctx.GlobalString(ArchiveURLFlag.Name)
+	}
+	if ctx.GlobalIsSet(ArchiveRateFlag.Name) {
+		cfg.ArchiveRate = ctx.GlobalInt(ArchiveRateFlag.Name)
+	}
+
 	switch {
 	case ctx.GlobalIsSet(DataDirFlag.Name):
 		cfg.DataDir = ctx.GlobalString(DataDirFlag.Name)
@@ -851,6 +1107,14 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(NoUSBFlag.Name) {
 		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
 	}
+	if ctx.GlobalIsSet(WatchOnlyFlag.Name) {
+		for _, addr := range splitAndTrim(ctx.GlobalString(WatchOnlyFlag.Name)) {
+			if !common.IsHexAddress(addr) {
+				Fatalf("Invalid watch-only address: %s", addr)
+			}
+			cfg.WatchOnlyAccounts = append(cfg.WatchOnlyAccounts, common.HexToAddress(addr))
+		}
+	}
 }
 
 func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
@@ -862,6 +1126,36 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	}
 }
 
+// setCallCache applies the call result cache flags to the config.
+func setCallCache(ctx *cli.Context, cfg *ethapi.CallCacheConfig) {
+	if ctx.GlobalIsSet(CallCacheFlag.Name) {
+		cfg.Size = ctx.GlobalInt(CallCacheFlag.Name)
+	}
+	if ctx.GlobalIsSet(CallCacheTTLFlag.Name) {
+		cfg.TTL = ctx.GlobalDuration(CallCacheTTLFlag.Name)
+	}
+}
+
+// setExternalTracers applies the external VM tracer flags to the config.
+func setExternalTracers(ctx *cli.Context, cfg *tracers.ExternalConfig) {
+	if ctx.GlobalIsSet(VMTracerPluginsFlag.Name) {
+		cfg.PluginDir = ctx.GlobalString(VMTracerPluginsFlag.Name)
+	}
+	if ctx.GlobalIsSet(VMTracerSidecarsFlag.Name) {
+		cfg.Sidecars = make(map[string]string)
+		for _, entry := range strings.Split(ctx.GlobalString(VMTracerSidecarsFlag.Name), ",") {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			parts := strings.SplitN(entry, "=", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				Fatalf("Invalid tracer sidecar %q, expected name=url", entry)
+			}
+			cfg.Sidecars[parts[0]] = parts[1]
+		}
+	}
+}
+
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolNoLocalsFlag.Name) {
 		cfg.NoLocals = ctx.GlobalBool(TxPoolNoLocalsFlag.Name)
@@ -955,6 +1249,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *goolabackend.Config)
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
 	setGoolase(ctx, ks, cfg)
 	setGPO(ctx, &cfg.GPO)
+	setCallCache(ctx, &cfg.CallCache)
 	setTxPool(ctx, &cfg.TxPool)
 
 	switch {
@@ -965,12 +1260,30 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *goolabackend.Config)
 	case ctx.GlobalBool(LightModeFlag.Name):
 		cfg.SyncMode = downloader.LightSync
 	}
+	if ctx.GlobalIsSet(ProfileFlag.Name) {
+		cfg.Profile = goolabackend.Profile(ctx.GlobalString(ProfileFlag.Name))
+	}
 	if ctx.GlobalIsSet(LightServFlag.Name) {
 		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
 	}
 	if ctx.GlobalIsSet(LightPeersFlag.Name) {
 		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
 	}
+	if ctx.GlobalIsSet(LightPaymentsFlag.Name) {
+		cfg.LightPayments = ctx.GlobalBool(LightPaymentsFlag.Name)
+	}
+	if ctx.GlobalIsSet(LightBudgetHourlyFlag.Name) {
+		cfg.LightBudgetHourly = ctx.GlobalInt(LightBudgetHourlyFlag.Name)
+	}
+	if ctx.GlobalIsSet(LightBudgetDailyFlag.Name) {
+		cfg.LightBudgetDaily = ctx.GlobalInt(LightBudgetDailyFlag.Name)
+	}
+	if ctx.GlobalIsSet(LightVerifyRecentFlag.Name) {
+		cfg.LightVerifyRecent = ctx.GlobalUint64(LightVerifyRecentFlag.Name)
+	}
+	if ctx.GlobalIsSet(LightRelayFlag.Name) {
+		cfg.LightRelay = strings.Split(ctx.GlobalString(LightRelayFlag.Name), ",")
+	}
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -980,10 +1293,98 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *goolabackend.Config)
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
 
-	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
-		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" && gcmode != "diff" {
+		Fatalf("--%s must be either 'full', 'archive' or 'diff'", GCModeFlag.Name)
+	}
+	if ctx.GlobalIsSet(GCModeFlag.Name) || cfg.Profile == goolabackend.ProfileNone {
+		cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+		cfg.StateDiffs = ctx.GlobalString(GCModeFlag.Name) == "diff"
+	}
+
+	if ctx.GlobalIsSet(InternalTxIndexFlag.Name) {
+		cfg.InternalTxIndex = ctx.GlobalBool(InternalTxIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(BalanceHistoryFlag.Name) {
+		for _, addr := range splitAndTrim(ctx.GlobalString(BalanceHistoryFlag.Name)) {
+			if !common.IsHexAddress(addr) {
+				Fatalf("Invalid balance history address: %s", addr)
+			}
+			cfg.BalanceHistory = append(cfg.BalanceHistory, common.HexToAddress(addr))
+		}
+	}
+	if ctx.GlobalIsSet(BalanceHistoryStepFlag.Name) {
+		cfg.BalanceHistoryStep = ctx.GlobalUint64(BalanceHistoryStepFlag.Name)
+	}
+	if ctx.GlobalIsSet(MaxReorgDepthFlag.Name) {
+		cfg.MaxReorgDepth = ctx.GlobalUint64(MaxReorgDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(RetainBlocksFlag.Name) {
+		cfg.RetainBlocks = ctx.GlobalUint64(RetainBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(BlobRetentionFlag.Name) {
+		cfg.BlobRetention = ctx.GlobalUint64(BlobRetentionFlag.Name)
+	}
+	if ctx.GlobalIsSet(ParallelExecutionFlag.Name) {
+		cfg.ParallelExecution = ctx.GlobalBool(ParallelExecutionFlag.Name)
+	}
+	if ctx.GlobalIsSet(ImportBufferFlag.Name) {
+		cfg.ImportBuffer = ctx.GlobalInt(ImportBufferFlag.Name)
+	}
+	if ctx.GlobalIsSet(QueryLimitPeerFlag.Name) {
+		cfg.QueryLimits.PeerRate = ctx.GlobalUint64(QueryLimitPeerFlag.Name)
+	}
+	if ctx.GlobalIsSet(QueryLimitGlobalFlag.Name) {
+		cfg.QueryLimits.GlobalRate = ctx.GlobalUint64(QueryLimitGlobalFlag.Name)
+	}
+	if ctx.GlobalIsSet(QueryLimitStrikesFlag.Name) {
+		cfg.QueryLimits.MaxStrikes = ctx.GlobalInt(QueryLimitStrikesFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPrivacyFlag.Name) {
+		cfg.TxPrivacy.Enabled = ctx.GlobalBool(TxPrivacyFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPrivacyFanoutFlag.Name) {
+		cfg.TxPrivacy.Fanout = ctx.GlobalInt(TxPrivacyFanoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPrivacyDelayFlag.Name) {
+		cfg.TxPrivacy.MaxDelay = ctx.GlobalDuration(TxPrivacyDelayFlag.Name)
+	}
+	if ctx.GlobalIsSet(TxPrivacyRelaysFlag.Name) {
+		for _, url := range strings.Split(ctx.GlobalString(TxPrivacyRelaysFlag.Name), ",") {
+			node, err := discover.ParseNode(strings.TrimSpace(url))
+			if err != nil {
+				Fatalf("Invalid transaction relay %q: %v", url, err)
+			}
+			cfg.TxPrivacy.Relays = append(cfg.TxPrivacy.Relays, node)
+		}
+	}
+	if ctx.GlobalIsSet(AlertsURLFlag.Name) {
+		cfg.Alerts.URL = ctx.GlobalString(AlertsURLFlag.Name)
+	}
+	if ctx.GlobalIsSet(AlertsSecretFlag.Name) {
+		cfg.Alerts.Secret = ctx.GlobalString(AlertsSecretFlag.Name)
+	}
+	if ctx.GlobalIsSet(MaintenanceWindowsFlag.Name) {
+		cfg.Maintenance.Windows = strings.Split(ctx.GlobalString(MaintenanceWindowsFlag.Name), ",")
+	}
+	if ctx.GlobalIsSet(MaintenanceCompactionFlag.Name) {
+		cfg.Maintenance.Compaction = ctx.GlobalDuration(MaintenanceCompactionFlag.Name)
+	}
+	if ctx.GlobalIsSet(SnapshotListenFlag.Name) {
+		cfg.Snapshot.Listen = ctx.GlobalString(SnapshotListenFlag.Name)
+	}
+	if ctx.GlobalIsSet(SnapshotURLFlag.Name) {
+		cfg.Snapshot.URL = ctx.GlobalString(SnapshotURLFlag.Name)
+	}
+	if ctx.GlobalIsSet(SnapshotSignerFlag.Name) {
+		signer := ctx.GlobalString(SnapshotSignerFlag.Name)
+		if !common.IsHexAddress(signer) {
+			Fatalf("Invalid snapshot signer: %s", signer)
+		}
+		cfg.Snapshot.Signer = common.HexToAddress(signer)
+	}
+	if cfg.Snapshot.URL != "" && cfg.Snapshot.Signer == (common.Address{}) {
+		Fatalf("Snapshot bootstrapping requires a trusted signer (--%s)", SnapshotSignerFlag.Name)
 	}
-	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
@@ -1004,6 +1405,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *goolabackend.Config)
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	setExternalTracers(ctx, &cfg.ExternalTracers)
 
 	// Override any default configs for hard coded networks.
 	switch {
@@ -1045,6 +1447,10 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *goolabackend.Config)
 	if gen := ctx.GlobalInt(TrieCacheGenFlag.Name); gen > 0 {
 		state.MaxTrieCacheGen = uint16(gen)
 	}
+	// Expand the node profile last, so it's checked against all other settings
+	if err := cfg.ApplyProfile(); err != nil {
+		Fatalf("Invalid node profile: %v", err)
+	}
 }
 
 // RegisterEthService adds an Goola client to the stack.
@@ -1069,6 +1475,16 @@ func RegisterEthService(stack *node.Node, cfg *goolabackend.Config) {
 	}
 }
 
+// RegisterNetworksService adds full nodes of additional networks to the given
+// node, running side by side with the primary one.
+func RegisterNetworksService(stack *node.Node, cfgs []goolabackend.NetworkConfig) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		return goolabackend.NewNetworks(ctx, cfgs)
+	}); err != nil {
+		Fatalf("Failed to register the additional networks: %v", err)
+	}
+}
+
 // RegisterShhService configures Whisper and adds it to the given node.
 func RegisterShhService(stack *node.Node, cfg *whisper.Config) {
 	if err := stack.Register(func(n *node.ServiceContext) (node.Service, error) {
@@ -1095,6 +1511,68 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 	}
 }
 
+// SetMultisigConfig applies multisig wallet related command line flags to the config.
+func SetMultisigConfig(ctx *cli.Context, cfg *multisig.Config) {
+	if ctx.GlobalIsSet(MultisigWalletsFlag.Name) {
+		for _, addr := range splitAndTrim(ctx.GlobalString(MultisigWalletsFlag.Name)) {
+			if !common.IsHexAddress(addr) {
+				Fatalf("Invalid multisig wallet address: %s", addr)
+			}
+			cfg.Wallets = append(cfg.Wallets, multisig.WalletConfig{Address: common.HexToAddress(addr)})
+		}
+	}
+}
+
+// RegisterMultisigService configures the multisig wallet tracker and adds it to
+// the given node.
+func RegisterMultisigService(stack *node.Node, cfg *multisig.Config) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var ethServ *goolabackend.FullGoola
+		if err := ctx.Service(&ethServ); err != nil {
+			return nil, fmt.Errorf("multisig tracking requires a full node: %v", err)
+		}
+		return multisig.New(cfg, ethServ.BlockChain()), nil
+	}); err != nil {
+		Fatalf("Failed to register the multisig service: %v", err)
+	}
+}
+
+// SetPaymentsConfig applies payment watcher related command line flags to the config.
+func SetPaymentsConfig(ctx *cli.Context, cfg *payments.Config) {
+	if ctx.GlobalIsSet(PaymentsFlag.Name) {
+		cfg.Enabled = ctx.GlobalBool(PaymentsFlag.Name)
+	}
+	if ctx.GlobalIsSet(PaymentsURLFlag.Name) {
+		cfg.URL = ctx.GlobalString(PaymentsURLFlag.Name)
+	}
+	if ctx.GlobalIsSet(PaymentsSecretFlag.Name) {
+		cfg.Secret = ctx.GlobalString(PaymentsSecretFlag.Name)
+	}
+}
+
+// RegisterPaymentsService configures the watcher of payments to the keystore
+// accounts and adds it to the given node.
+func RegisterPaymentsService(stack *node.Node, cfg *payments.Config) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var ethServ *goolabackend.FullGoola
+		if err := ctx.Service(&ethServ); err != nil {
+			return nil, fmt.Errorf("payment watching requires a full node: %v", err)
+		}
+		accounts := func() []common.Address {
+			var addrs []common.Address
+			for _, backend := range ctx.AccountManager.Backends(keystore.KeyStoreType) {
+				for _, account := range backend.(*keystore.KeyStore).Accounts() {
+					addrs = append(addrs, account.Address)
+				}
+			}
+			return addrs
+		}
+		return payments.New(cfg, ethServ.BlockChain(), accounts), nil
+	}); err != nil {
+		Fatalf("Failed to register the payment watcher: %v", err)
+	}
+}
+
 // SetupNetwork configures the system for either the main net or some test network.
 func SetupNetwork(ctx *cli.Context) {
 	// TODO(fjl): move target gas limit into config
@@ -1142,15 +1620,14 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	}
 	var engine consensus.Engine
 	if !ctx.GlobalBool(FakePoWFlag.Name) {
-		engine = dpos.New(dpos.Config{
-
-		})
+		engine = dpos.New(dpos.Config{Scheduled: config.Ethash != nil && config.Ethash.Scheduled()}, chainDb)
 	}
-	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
-		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
+	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" && gcmode != "diff" {
+		Fatalf("--%s must be either 'full', 'archive' or 'diff'", GCModeFlag.Name)
 	}
 	cache := &core.CacheConfig{
 		Disabled:      ctx.GlobalString(GCModeFlag.Name) == "archive",
+		StateDiffs:    ctx.GlobalString(GCModeFlag.Name) == "diff",
 		TrieNodeLimit: goolabackend.DefaultConfig.TrieCache,
 		TrieTimeLimit: goolabackend.DefaultConfig.TrieTimeout,
 	}
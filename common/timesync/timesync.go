@@ -0,0 +1,148 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package timesync estimates the drift of the local clock from network time via
+// SNTP, providing a drift compensated wall clock for timestamp validation. The
+// drift measurement is shared with the clock sanity checks of node discovery.
+package timesync
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/log"
+)
+
+const (
+	// WarnThreshold is the drift beyond which the local clock is reported as off.
+	WarnThreshold = 10 * time.Second
+
+	// MaxCompensation caps the drift compensated for, so that a rogue time server
+	// cannot make the node accept arbitrarily timestamped blocks.
+	MaxCompensation = 5 * time.Minute
+
+	measureInterval = 10 * time.Minute // Period between two drift estimations
+	measurements    = 3                // Number of measurements per estimation
+)
+
+var (
+	lock     sync.RWMutex
+	drift    time.Duration // Estimated drift of the local clock (local - network)
+	measured time.Time     // Time of the last successful estimation
+)
+
+// Status is the current clock drift estimate.
+type Status struct {
+	Drift    time.Duration // Estimated drift of the local clock, positive if ahead
+	Measured time.Time     // Time of the estimation, zero if never measured
+	Warning  bool          // Whether the drift exceeds the warning threshold
+}
+
+// Drift returns the estimated drift of the local clock from network time,
+// positive if the local clock is ahead.
+func Drift() time.Duration {
+	lock.RLock()
+	defer lock.RUnlock()
+	return drift
+}
+
+// SetDrift overrides the estimated drift of the local clock.
+func SetDrift(d time.Duration) {
+	lock.Lock()
+	defer lock.Unlock()
+	drift, measured = d, time.Now()
+}
+
+// CurrentStatus returns the current drift estimate.
+func CurrentStatus() Status {
+	lock.RLock()
+	defer lock.RUnlock()
+	return Status{
+		Drift:    drift,
+		Measured: measured,
+		Warning:  drift < -WarnThreshold || drift > WarnThreshold,
+	}
+}
+
+// Now returns the local time corrected by the estimated drift, up to the
+// maximum compensation.
+func Now() time.Time {
+	d := Drift()
+	switch {
+	case d > MaxCompensation:
+		d = MaxCompensation
+	case d < -MaxCompensation:
+		d = -MaxCompensation
+	}
+	return time.Now().Add(-d)
+}
+
+// Monitor periodically estimates the drift of the local clock against an NTP
+// server.
+type Monitor struct {
+	server string
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewMonitor creates a clock drift monitor querying the given NTP server.
+func NewMonitor(server string) *Monitor {
+	return &Monitor{server: server, quit: make(chan struct{})}
+}
+
+// Start begins estimating the clock drift in the background.
+func (m *Monitor) Start() {
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Stop terminates the drift estimation.
+func (m *Monitor) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// loop estimates the drift on startup and then periodically.
+func (m *Monitor) loop() {
+	defer m.wg.Done()
+
+	for {
+		m.update()
+		select {
+		case <-time.After(measureInterval):
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// update runs a single drift estimation, warning the user if it's significant.
+func (m *Monitor) update() {
+	d, err := SNTPDrift(m.server, measurements)
+	if err != nil {
+		log.Debug("Failed to estimate clock drift", "server", m.server, "err", err)
+		return
+	}
+	SetDrift(d)
+
+	if d < -WarnThreshold || d > WarnThreshold {
+		log.Warn(fmt.Sprintf("System clock seems off by %v, compensating for block validation", d))
+		log.Warn("Please enable network time synchronisation in system settings.")
+	} else {
+		log.Debug("Estimated clock drift", "drift", d)
+	}
+}
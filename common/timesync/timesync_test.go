@@ -0,0 +1,51 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package timesync
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that the compensated clock corrects the estimated drift, capped at the
+// maximum compensation, and that large drifts are flagged.
+func TestCompensation(t *testing.T) {
+	defer SetDrift(0)
+
+	tests := []struct {
+		drift   time.Duration
+		offset  time.Duration
+		warning bool
+	}{
+		{0, 0, false},
+		{time.Second, -time.Second, false},
+		{-time.Minute, time.Minute, true},
+		{time.Hour, -MaxCompensation, true},
+		{-time.Hour, MaxCompensation, true},
+	}
+	for i, tt := range tests {
+		SetDrift(tt.drift)
+
+		offset := Now().Sub(time.Now())
+		if diff := offset - tt.offset; diff < -time.Second || diff > time.Second {
+			t.Errorf("test %d: clock offset mismatch: have %v, want %v", i, offset, tt.offset)
+		}
+		if status := CurrentStatus(); status.Drift != tt.drift || status.Warning != tt.warning || status.Measured.IsZero() {
+			t.Errorf("test %d: status mismatch: have %+v, want drift %v, warning %v", i, status, tt.drift, tt.warning)
+		}
+	}
+}
@@ -18,6 +18,8 @@
 package consensus
 
 import (
+	"math/big"
+
 	"github.com/goola-team/goola/common"
 	"github.com/goola-team/goola/core/state"
 	"github.com/goola-team/goola/core/types"
@@ -48,6 +50,10 @@ type ChainReader interface {
 }
 
 // Engine is an algorithm agnostic consensus engine.
+//
+// Blocks carry no uncles: headers commit to no uncle hash and block bodies only
+// hold transactions, so engines have no uncle inclusion policy to enforce and
+// miners can't produce blocks referencing uncles.
 type Engine interface {
 	// Author retrieves the Goola address of the account that minted the given
 	// block, which may be different from the header's coinbase if a consensus
@@ -90,6 +96,21 @@ type Engine interface {
 	APIs(chain ChainReader) []rpc.API
 }
 
+// Weigher is implemented by engines assigning weights to blocks, the chain with
+// the highest total weight being the canonical one.
+type Weigher interface {
+	// Weight returns the fork choice weight of a block.
+	Weight(chain ChainReader, header *types.Header) *big.Int
+}
+
+// Finality is implemented by engines whose blocks become irreversible once a
+// supermajority of the block producers built upon them.
+type Finality interface {
+	// Justified returns the highest ancestor of a header that more than two
+	// thirds of the block producers built upon, or nil if there is none yet.
+	Justified(chain ChainReader, header *types.Header) (*types.Header, error)
+}
+
 // PoW is a consensus engine based on proof-of-work.
 type PoW interface {
 	Engine
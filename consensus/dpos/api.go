@@ -0,0 +1,211 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"errors"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/consensus"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/params"
+	"github.com/goola-team/goola/rpc"
+)
+
+// errNoState is returned when inspecting the delegate registry through a chain
+// not giving access to its states.
+var errNoState = errors.New("chain state unavailable")
+
+// stateReader is implemented by chains giving access to their states, needed to
+// inspect the delegate registry.
+type stateReader interface {
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// API is a user facing RPC API to inspect the delegates producing the blocks of
+// the dpos epochs and the votes electing them.
+type API struct {
+	chain consensus.ChainReader
+	dpos  *dops
+}
+
+// GetSnapshot retrieves the snapshot of the epoch of the given block.
+func (api *API) GetSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
+	// Retrieve the requested block number (or current if none requested)
+	return api.snapshot(api.header(number))
+}
+
+// GetSnapshotAtHash retrieves the snapshot of the epoch of the given block.
+func (api *API) GetSnapshotAtHash(hash common.Hash) (*Snapshot, error) {
+	return api.snapshot(api.chain.GetHeaderByHash(hash))
+}
+
+// snapshot retrieves the snapshot of the epoch of a block, looking it up from its
+// parent. The first epoch starts after the genesis block.
+func (api *API) snapshot(header *types.Header) (*Snapshot, error) {
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	if header.Number.Sign() == 0 {
+		return api.dpos.snapshot(api.chain, header, nil)
+	}
+	parent := api.chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	return api.dpos.snapshot(api.chain, parent, nil)
+}
+
+// Delegates lists the validators of an epoch, and the ones the votes cast so far
+// would elect for the next one.
+type Delegates struct {
+	Number  uint64           `json:"number"`
+	Hash    common.Hash      `json:"hash"`
+	Current []common.Address `json:"current"`
+	Pending []common.Address `json:"pending"`
+}
+
+// GetDelegates retrieves the current and pending delegates as of the given block.
+func (api *API) GetDelegates(number *rpc.BlockNumber) (*Delegates, error) {
+	header := api.header(number)
+	snap, err := api.snapshot(header)
+	if err != nil {
+		return nil, err
+	}
+	reg, err := api.registry(header)
+	if err != nil {
+		return nil, err
+	}
+	config := api.epochConfig(snap)
+	suspended := reg.suspended(config, epochOf(config, header.Number.Uint64()), snap.Validators)
+
+	return &Delegates{
+		Number:  header.Number.Uint64(),
+		Hash:    header.Hash(),
+		Current: snap.Validators,
+		Pending: reg.elect(config, snap.Validators, suspended),
+	}, nil
+}
+
+// Votes describes the registry entries of an account: its candidacy with the
+// vote weight backing it, and the delegate it votes for.
+type Votes struct {
+	Candidate bool           `json:"candidate"`
+	Weight    *hexutil.Big   `json:"weight"`
+	Delegate  common.Address `json:"delegate"` // Zero if not voting
+}
+
+// GetVotes retrieves the registry entries of an account as of the given block.
+func (api *API) GetVotes(addr common.Address, number *rpc.BlockNumber) (*Votes, error) {
+	reg, err := api.registry(api.header(number))
+	if err != nil {
+		return nil, err
+	}
+	votes := &Votes{
+		Candidate: reg.contains(candidatesSet, addr),
+		Weight:    new(hexutil.Big),
+		Delegate:  reg.vote(addr),
+	}
+	if _, weights := reg.tally(); weights[addr] != nil {
+		votes.Weight = (*hexutil.Big)(weights[addr])
+	}
+	return votes, nil
+}
+
+// header retrieves the header of a block by number, the current one if none is
+// requested.
+func (api *API) header(number *rpc.BlockNumber) *types.Header {
+	if number == nil || *number == rpc.LatestBlockNumber {
+		return api.chain.CurrentHeader()
+	}
+	return api.chain.GetHeaderByNumber(uint64(number.Int64()))
+}
+
+// epochConfig returns the engine configuration with the validators of an epoch.
+func (api *API) epochConfig(snap *Snapshot) *params.EthashConfig {
+	var config params.EthashConfig
+	if api.chain.Config().Ethash != nil {
+		config = *api.chain.Config().Ethash
+	}
+	config.Validators = snap.Validators
+	return &config
+}
+
+// registry opens the delegate registry in the state of a block.
+func (api *API) registry(header *types.Header) (*registry, error) {
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	chain, ok := api.chain.(stateReader)
+	if !ok {
+		return nil, errNoState
+	}
+	statedb, err := chain.StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	return &registry{state: statedb}, nil
+}
+
+// Liveness is the production record of a validator or standby.
+type Liveness struct {
+	Produced    uint64 `json:"produced"`    // Blocks produced
+	Missed      uint64 `json:"missed"`      // Slots missed as the scheduled validator
+	EpochMissed uint64 `json:"epochMissed"` // Slots missed in the epoch of the block
+	Withheld    uint64 `json:"withheld"`    // Percentage of the block reward withheld if producing next
+	Suspended   bool   `json:"suspended"`   // Whether left out of the next election
+}
+
+// GetLiveness retrieves the production records of the validators and standbys
+// of the epoch of the given block, as of that block.
+func (api *API) GetLiveness(number *rpc.BlockNumber) (map[common.Address]*Liveness, error) {
+	header := api.header(number)
+	snap, err := api.snapshot(header)
+	if err != nil {
+		return nil, err
+	}
+	reg, err := api.registry(header)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		config    = api.epochConfig(snap)
+		epoch     = epochOf(config, header.Number.Uint64())
+		suspended = reg.suspended(config, epoch, snap.Validators)
+	)
+
+	liveness := make(map[common.Address]*Liveness)
+	for _, addr := range append(append([]common.Address{}, snap.Validators...), config.Standbys...) {
+		missed := reg.epochMissed(epoch, addr)
+		liveness[addr] = &Liveness{
+			Produced:    reg.counter(producedCounter, addr[:]),
+			Missed:      reg.counter(missedCounter, addr[:]),
+			EpochMissed: missed,
+			Withheld:    withheld(config, missed),
+			Suspended:   suspended[addr],
+		}
+	}
+	return liveness, nil
+}
+
+// GetDrifts retrieves the timestamp drift records of the blocks received from
+// every validator seen since the node started.
+func (api *API) GetDrifts() map[common.Address]*Drift {
+	return api.dpos.driftTracker().drifts()
+}
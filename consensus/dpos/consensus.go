@@ -45,12 +45,17 @@ var (
 	errZeroBlockTime     = errors.New("timestamp equals parent's")
 	errNonceOutOfRange   = errors.New("nonce out of range")
 	errInvalidPoW        = errors.New("invalid proof-of-work")
+	errInvalidDifficulty = errors.New("invalid difficulty")
 )
 
-// Author implements consensus.Engine, returning the header's coinbase as the
-// proof-of-work verified author of the block.
+// Author implements consensus.Engine, returning the account that sealed the
+// header on scheduled chains and the header's coinbase otherwise, headers of
+// unscheduled chains not being sealed.
 func (ethash *dops) Author(header *types.Header) (common.Address, error) {
-	return header.Coinbase, nil
+	if !ethash.config.Scheduled || header.Number.Sign() == 0 {
+		return header.Coinbase, nil
+	}
+	return ethash.signer(header)
 }
 
 // VerifyHeader checks whether a header conforms to the consensus rules of the
@@ -66,7 +71,11 @@ func (ethash *dops) VerifyHeader(chain consensus.ChainReader, header *types.Head
 		return consensus.ErrUnknownAncestor
 	}
 	// Sanity checks passed, do a proper verification
-	return ethash.verifyHeader(chain, header, parent,  seal)
+	if err := ethash.verifyHeader(chain, header, parent, nil, seal); err != nil {
+		return err
+	}
+	ethash.recordDrift(header)
+	return nil
 }
 
 // VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers
@@ -147,7 +156,11 @@ func (ethash *dops) verifyHeaderWorker(chain consensus.ChainReader, headers []*t
 	if chain.GetHeader(headers[index].Hash(), headers[index].Number.Uint64()) != nil {
 		return nil // known block
 	}
-	return ethash.verifyHeader(chain, headers[index], parent, seals[index])
+	if err := ethash.verifyHeader(chain, headers[index], parent, headers[:index], seals[index]); err != nil {
+		return err
+	}
+	ethash.recordDrift(headers[index])
+	return nil
 }
 
 
@@ -155,19 +168,48 @@ func (ethash *dops) verifyHeaderWorker(chain consensus.ChainReader, headers []*t
 // verifyHeader checks whether a header conforms to the consensus rules of the
 // stock Goola dpos engine.
 // See YP section 4.3.4. "Block Header Validity"
-func (ethash *dops) verifyHeader(chain consensus.ChainReader, header, parent *types.Header, seal bool) error {
-	// Ensure that the header's extra-data section is of a reasonable size
-	if uint64(len(header.Extra)) > params.MaximumExtraDataSize {
+func (ethash *dops) verifyHeader(chain consensus.ChainReader, header, parent *types.Header, parents []*types.Header, seal bool) error {
+	// Ensure that the header's extra-data section is of a reasonable size, or on
+	// checkpoints, that it commits to the next epoch's validators
+	config := chain.Config().Ethash
+	if IsCheckpoint(config, header.Number.Uint64()) {
+		if err := verifyCheckpoint(header); err != nil {
+			return err
+		}
+	} else if config != nil && config.Scheduled() {
+		if len(header.Extra) < extraSeal {
+			return errMissingSignature
+		}
+		if uint64(len(header.Extra)-extraSeal) > params.MaximumExtraDataSize {
+			return fmt.Errorf("extra-data too long: %d > %d", len(header.Extra)-extraSeal, params.MaximumExtraDataSize)
+		}
+	} else if uint64(len(header.Extra)) > params.MaximumExtraDataSize {
 		return fmt.Errorf("extra-data too long: %d > %d", len(header.Extra), params.MaximumExtraDataSize)
 	}
 	// Verify the header's timestamp
 
-		if header.Time.Cmp(big.NewInt(time.Now().Add(allowedFutureBlockTime).Unix())) > 0 {
+		if header.Time.Cmp(big.NewInt(ethash.now().Add(allowedFutureBlockTime).Unix())) > 0 {
 			return consensus.ErrFutureBlock
 		}
 	if header.Time.Cmp(parent.Time) <= 0 {
 		return errZeroBlockTime
 	}
+	// Verify that the header was produced by the validator or standby of its slot.
+	// The producer is the coinbase, which only the seal authenticates: headers
+	// whose seal isn't checked are trusted to be linked to ones whose seal is, and
+	// skip the costly signature recovery.
+	if config != nil && config.Scheduled() {
+		epoch, err := ethash.epochConfig(chain, parent, parents)
+		if err != nil {
+			return err
+		}
+		if err := verifyProducer(epoch, header, parent, header.Coinbase); err != nil {
+			return err
+		}
+		if header.Nonce.Uint64() != CalcDifficulty(epoch, header.Time.Uint64(), header.Coinbase) {
+			return errInvalidDifficulty
+		}
+	}
 
 
 
@@ -217,66 +259,109 @@ var (
 	big2999999    = big.NewInt(2999999)
 )
 
-// VerifySeal implements consensus.Engine, checking whether the given block satisfies
-// the PoW difficulty requirements.
+// VerifySeal implements consensus.Engine, checking that the header of a scheduled
+// chain is signed by its coinbase. Headers of unscheduled chains carry no seal.
+// Whether the coinbase may produce in the header's slot depends on the parent,
+// which may not be in the chain yet, and is checked by verifyHeader.
 func (ethash *dops) VerifySeal(chain consensus.ChainReader, header *types.Header) error {
-	// If we're running a fake PoW, accept any seal as valid
+	// If we're running a fake engine, accept any seal as valid
+	if ethash.mode != ModeNormal {
 		time.Sleep(ethash.fakeDelay)
 		if ethash.fakeFail == header.Number.Uint64() {
 			return errInvalidPoW
 		}
 		return nil
-
-	//// If we're running a shared PoW, delegate verification to it
-	//if dpos.shared != nil {
-	//	return dpos.shared.VerifySeal(chain, header)
-	//}
-	// Sanity check that the block number is below the lookup table size (60M blocks)
-	//number := header.Number.Uint64()
-	//if number/epochLength >= maxEpoch {
-	//	// Go < 1.7 cannot calculate new cache/dataset sizes (no fast prime check)
-	//	return errNonceOutOfRange
-	//}
-	//// Ensure that we have a valid difficulty for the block
-	//if header.Difficulty.Sign() <= 0 {
-	//	return errInvalidDifficulty
-	//}
-
-	// Recompute the digest and PoW value and verify against the header
-	//cache := dpos.cache(number)
-	//size := datasetSize(number)
-	//if dpos.config.PowMode == ModeTest {
-	//	size = 32 * 1024
-	//}
-	//digest, result := hashimotoLight(size, cache.cache, header.HashNoNonce().Bytes(), header.Nonce.Uint64())
-	//// Caches are unmapped in a finalizer. Ensure that the cache stays live
-	//// until after the call to hashimotoLight so it's not unmapped while being used.
-	//runtime.KeepAlive(cache)
-	//
-	//if !bytes.Equal(header.MixDigest[:], digest) {
-	//	return errInvalidMixDigest
-	//}
-	//target := new(big.Int).Div(maxUint256, header.Difficulty)
-	//if new(big.Int).SetBytes(result).Cmp(target) > 0 {
-	//	return errInvalidPoW
-	//}
+	}
+	config := chain.Config().Ethash
+	if config == nil || !config.Scheduled() {
+		return nil
+	}
+	signer, err := ethash.signer(header)
+	if err != nil {
+		return err
+	}
+	if signer != header.Coinbase {
+		return errInvalidSigner
+	}
 	return nil
 }
 
-// Prepare implements consensus.Engine, initializing the difficulty field of a
-// header to conform to the dpos protocol. The changes are done inline.
+// Prepare implements consensus.Engine, initializing the header to conform to the
+// dpos protocol. If block production is scheduled, the timestamp is moved to the
+// next slot the producer may produce in, space is reserved for the seal and the
+// otherwise unused nonce is set to the block's difficulty. The validators of the
+// next epoch are only appended to the extra-data of checkpoints once elected, by
+// Finalize. The changes are done inline.
 func (ethash *dops) Prepare(chain consensus.ChainReader, header *types.Header) error {
 	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
 	if parent == nil {
 		return consensus.ErrUnknownAncestor
 	}
+	config := chain.Config().Ethash
+	if config == nil || !config.Scheduled() {
+		return nil
+	}
+	epoch, err := ethash.epochConfig(chain, parent, nil)
+	if err != nil {
+		return err
+	}
+	if header.Coinbase != (common.Address{}) {
+		if time, ok := nextProductionTime(epoch, parent.Time.Uint64(), header.Time.Uint64(), header.Coinbase); ok {
+			header.Time = new(big.Int).SetUint64(time)
+		}
+	}
+	if IsCheckpoint(config, header.Number.Uint64()) {
+		header.Extra = checkpointExtra(header.Extra, nil)
+	} else {
+		header.Extra = append(common.CopyBytes(header.Extra), make([]byte, extraSeal)...)
+	}
+	header.Nonce = types.EncodeNonce(CalcDifficulty(epoch, header.Time.Uint64(), header.Coinbase))
 	return nil
 }
 
-// Finalize implements consensus.Engine, accumulating the block ,
-// setting the final state and assembling the block.
+// Weight implements consensus.Weigher, scoring blocks by their difficulty, which
+// on scheduled chains is verified to be the one of their producer and slot.
+// Chains are compared by the sum of their blocks' weights.
+func (ethash *dops) Weight(chain consensus.ChainReader, header *types.Header) *big.Int {
+	if config := chain.Config().Ethash; config == nil || !config.Scheduled() {
+		_, standby := config.Weights()
+		return new(big.Int).SetUint64(standby)
+	}
+	return new(big.Int).SetUint64(header.Nonce.Uint64())
+}
+
+// Finalize implements consensus.Engine, executing the delegate registry actions
+// of the block's vote transactions, accounting the slots missed since the parent,
+// electing the validators of the next epoch on checkpoints, accumulating the
+// block rewards, setting the final state and assembling the block. Checkpoints
+// not committing to their validators yet are made to commit to the elected ones,
+// others must already commit to them.
 func (ethash *dops) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, receipts []*types.Receipt) (*types.Block, error) {
-	accumulateRewards(chain.Config(), state, header)
+	var penalty uint64
+	if config := chain.Config().Ethash; config != nil && config.Scheduled() && config.Epoch > 0 {
+		applyVotes(chain.Config(), header, state, txs, receipts)
+
+		number := header.Number.Uint64()
+		parent := chain.GetHeader(header.ParentHash, number-1)
+		if parent == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		epoch, err := ethash.epochConfig(chain, parent, nil)
+		if err != nil {
+			return nil, err
+		}
+		reg := &registry{state: state}
+		reg.recordSlots(epoch, header, parent)
+		penalty = withheld(config, reg.epochMissed(epochOf(config, number), header.Coinbase))
+
+		if IsCheckpoint(config, number) {
+			suspended := reg.suspended(config, epochOf(config, number), epoch.Validators)
+			if err := verifyElection(header, reg.elect(config, epoch.Validators, suspended)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	accumulateRewards(chain.Config(), state, header, penalty)
 	header.Root = state.IntermediateRoot(true)
 
 	// Header seems complete, assemble into a block and return
@@ -285,20 +370,58 @@ func (ethash *dops) Finalize(chain consensus.ChainReader, header *types.Header,
 
 // Some weird constants to avoid constant memory allocs for them.
 var (
-	big8  = big.NewInt(8)
-	big32 = big.NewInt(32)
+	big8   = big.NewInt(8)
+	big32  = big.NewInt(32)
+	big100 = big.NewInt(100)
 )
 
 // AccumulateRewards credits the coinbase of the given block with the mining
-// reward. The total reward consists of the static block reward and rewards for
-func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header) {
+// reward. The total reward consists of the static or scheduled block reward and
+// rewards for its producer, less the treasury's share and the given percentage
+// withheld for the slots it missed.
+func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, withheld uint64) {
 	// Select the correct block reward based on chain progression
 	blockReward := FrontierBlockReward
 	if config.IsByzantium(header.Number) {
 		blockReward = ByzantiumBlockReward
 	}
+	var schedule *params.RewardSchedule
+	if config.Ethash != nil {
+		schedule = config.Ethash.Rewards
+	}
+	if schedule != nil {
+		blockReward = schedule.Reward(header.Number, blockReward)
+
+		// Pay the treasury its share of the full reward, withheld or not
+		if schedule.TreasuryPercent > 0 {
+			share := new(big.Int).Mul(blockReward, new(big.Int).SetUint64(schedule.TreasuryPercent))
+			share.Div(share, big100)
+
+			state.AddBalance(schedule.Treasury, share)
+			blockReward = new(big.Int).Sub(blockReward, share)
+		}
+	}
 	// Accumulate the rewards for the miner 
-	reward := new(big.Int).Set(blockReward)
+	reward := new(big.Int).Mul(blockReward, new(big.Int).SetUint64(100-withheld))
+	reward.Div(reward, big100)
 
-	state.AddBalance(header.Coinbase, reward)
+	if config.Ethash == nil || len(config.Ethash.Payouts) == 0 {
+		state.AddBalance(header.Coinbase, reward)
+		return
+	}
+	// Split the reward among the configured recipients, any rounding dust going
+	// to the coinbase
+	remainder := new(big.Int).Set(reward)
+	for _, split := range config.Ethash.Payouts {
+		share := new(big.Int).Mul(reward, new(big.Int).SetUint64(split.Percent))
+		share.Div(share, big100)
+
+		recipient := split.Address
+		if recipient == (common.Address{}) {
+			recipient = header.Coinbase
+		}
+		state.AddBalance(recipient, share)
+		remainder.Sub(remainder, share)
+	}
+	state.AddBalance(header.Coinbase, remainder)
 }
@@ -17,11 +17,19 @@
 package dpos
 
 import (
+	"crypto/ecdsa"
 	"encoding/json"
 	"math/big"
+	"testing"
 
+	"github.com/goola-team/goola/accounts"
+	"github.com/goola-team/goola/common"
 	"github.com/goola-team/goola/common/math"
-
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
 )
 
 type diffTest struct {
@@ -50,3 +58,242 @@ func (d *diffTest) UnmarshalJSON(b []byte) (err error) {
 }
 
 
+
+// Tests that block rewards are split among the configured payout recipients,
+// with the zero address standing for the coinbase.
+func TestAccumulateRewardsSplit(t *testing.T) {
+	var (
+		coinbase = common.Address{0x01}
+		fund     = common.Address{0x02}
+		header   = &types.Header{Number: big.NewInt(1), Coinbase: coinbase}
+	)
+	db, _ := gooladb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	config := &params.ChainConfig{Ethash: &params.EthashConfig{Payouts: []params.PayoutSplit{
+		{Address: common.Address{}, Percent: 67},
+		{Address: fund, Percent: 33},
+	}}}
+	if err := config.Ethash.CheckPayouts(); err != nil {
+		t.Fatalf("valid payouts rejected: %v", err)
+	}
+	accumulateRewards(config, statedb, header, 0)
+
+	wantFund := new(big.Int).Div(new(big.Int).Mul(FrontierBlockReward, big.NewInt(33)), big100)
+	if have := statedb.GetBalance(fund); have.Cmp(wantFund) != 0 {
+		t.Errorf("fund balance mismatch: have %v, want %v", have, wantFund)
+	}
+	wantCoinbase := new(big.Int).Sub(FrontierBlockReward, wantFund)
+	if have := statedb.GetBalance(coinbase); have.Cmp(wantCoinbase) != 0 {
+		t.Errorf("coinbase balance mismatch: have %v, want %v", have, wantCoinbase)
+	}
+	// Shares must be non-zero and sum up to exactly 100%
+	for _, payouts := range [][]params.PayoutSplit{
+		{{Address: fund, Percent: 99}},
+		{{Address: fund, Percent: 100}, {Address: coinbase, Percent: 0}},
+	} {
+		if err := (&params.EthashConfig{Payouts: payouts}).CheckPayouts(); err == nil {
+			t.Errorf("invalid payouts %v accepted", payouts)
+		}
+	}
+}
+
+// Tests that configured reward schedules replace the default block reward, the
+// treasury being paid its share before any reward is withheld.
+func TestAccumulateRewardsSchedule(t *testing.T) {
+	var (
+		coinbase = common.Address{0x01}
+		treasury = common.Address{0x02}
+		header   = &types.Header{Number: big.NewInt(150), Coinbase: coinbase}
+	)
+	db, _ := gooladb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	config := &params.ChainConfig{Ethash: &params.EthashConfig{Rewards: &params.RewardSchedule{
+		Eras:            []params.RewardEra{{Block: big.NewInt(100), Reward: big.NewInt(1e18)}},
+		HalvingInterval: 50,
+		Treasury:        treasury,
+		TreasuryPercent: 10,
+	}}}
+	accumulateRewards(config, statedb, header, 50)
+
+	if have, want := statedb.GetBalance(treasury), big.NewInt(5e16); have.Cmp(want) != 0 {
+		t.Errorf("treasury balance mismatch: have %v, want %v", have, want)
+	}
+	if have, want := statedb.GetBalance(coinbase), big.NewInt(225e15); have.Cmp(want) != 0 {
+		t.Errorf("coinbase balance mismatch: have %v, want %v", have, want)
+	}
+}
+
+// Tests that sealed headers of scheduled chains are only accepted if signed by
+// their coinbase, being the producer scheduled for their slot, and declaring the
+// difficulty of that producer.
+func TestVerifySeal(t *testing.T) {
+	var (
+		keyA, _ = crypto.GenerateKey()
+		keyB, _ = crypto.GenerateKey()
+		addrA   = crypto.PubkeyToAddress(keyA.PublicKey)
+		addrB   = crypto.PubkeyToAddress(keyB.PublicKey)
+	)
+	signer := func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, keyA)
+	}
+	config := &params.EthashConfig{Period: 10, Validators: []common.Address{addrA, addrB}}
+	parent := &types.Header{Number: big.NewInt(1), Time: big.NewInt(15), GasLimit: params.GenesisGasLimit}
+	chain := &testChain{
+		config:  &params.ChainConfig{Ethash: config},
+		headers: []*types.Header{{Number: big.NewInt(0), Time: big.NewInt(0)}, parent},
+	}
+	seal := func(engine *dops, coinbase common.Address) (*types.Header, error) {
+		header := &types.Header{ParentHash: parent.Hash(), Number: big.NewInt(2), Time: big.NewInt(20), GasLimit: parent.GasLimit, Coinbase: coinbase}
+		if err := engine.Prepare(chain, header); err != nil {
+			return nil, err
+		}
+		block, err := engine.Seal(chain, types.NewBlockWithHeader(header), nil)
+		if err != nil {
+			return nil, err
+		}
+		return block.Header(), nil
+	}
+	engine := New(Config{Scheduled: true}, nil)
+	if _, err := seal(engine, addrA); err != errUnauthorizedSealer {
+		t.Fatalf("unauthorized sealing error mismatch: have %v, want %v", err, errUnauthorizedSealer)
+	}
+	engine.Authorize(addrA, signer)
+
+	header, err := seal(engine, addrA)
+	if err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	if err := engine.VerifyHeader(chain, header, true); err != nil {
+		t.Errorf("valid header rejected: %v", err)
+	}
+	if author, err := engine.Author(header); err != nil || author != addrA {
+		t.Errorf("author mismatch: have %x/%v, want %x", author, err, addrA)
+	}
+	// Headers must declare the difficulty of their producer and slot
+	forged := types.CopyHeader(header)
+	forged.Nonce = types.EncodeNonce(1)
+	sig, _ := crypto.Sign(sealHash(forged).Bytes(), keyA)
+	copy(forged.Extra[len(forged.Extra)-extraSeal:], sig)
+	if err := engine.VerifyHeader(chain, forged, true); err != errInvalidDifficulty {
+		t.Errorf("invalid difficulty error mismatch: have %v, want %v", err, errInvalidDifficulty)
+	}
+	// Seals by other accounts than the coinbase must be rejected
+	forged = types.CopyHeader(header)
+	forged.Coinbase = addrB
+	if err := engine.VerifySeal(chain, forged); err != errInvalidSigner {
+		t.Errorf("forged seal error mismatch: have %v, want %v", err, errInvalidSigner)
+	}
+	// Validators producing outside their slots must be rejected
+	sig, _ = crypto.Sign(sealHash(forged).Bytes(), keyB)
+	copy(forged.Extra[len(forged.Extra)-extraSeal:], sig)
+	if err := engine.VerifyHeader(chain, forged, true); err != errUnauthorizedProducer {
+		t.Errorf("out of slot seal error mismatch: have %v, want %v", err, errUnauthorizedProducer)
+	}
+	// Unsealed headers are only accepted by fake engines
+	header.Extra = make([]byte, extraSeal)
+	if err := engine.VerifySeal(chain, header); err == nil {
+		t.Errorf("unsealed header accepted")
+	}
+	if err := NewFaker().VerifySeal(chain, header); err != nil {
+		t.Errorf("fake engine rejected unsealed header: %v", err)
+	}
+}
+
+// Tests that batches of sealed headers of scheduled chains are accepted, even
+// though the parents of all but the first are not yet in the chain.
+func TestVerifyHeadersBatch(t *testing.T) {
+	var (
+		keyA, _ = crypto.GenerateKey()
+		keyB, _ = crypto.GenerateKey()
+		addrA   = crypto.PubkeyToAddress(keyA.PublicKey)
+		addrB   = crypto.PubkeyToAddress(keyB.PublicKey)
+	)
+	config := &params.EthashConfig{Period: 10, Validators: []common.Address{addrA, addrB}}
+	genesis := &types.Header{Number: big.NewInt(0), Time: big.NewInt(0), GasLimit: params.GenesisGasLimit}
+	chain := &testChain{
+		config:  &params.ChainConfig{Ethash: config},
+		headers: []*types.Header{genesis},
+	}
+	// Produce a few blocks with the validators sealing in turn
+	var (
+		keys    = map[common.Address]*ecdsa.PrivateKey{addrA: keyA, addrB: keyB}
+		headers []*types.Header
+		parent  = genesis
+	)
+	for i := 1; i <= 4; i++ {
+		time := uint64(i) * config.Period
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i)),
+			Time:       new(big.Int).SetUint64(time),
+			GasLimit:   parent.GasLimit,
+			Coinbase:   config.Validators[(time/config.Period)%uint64(len(config.Validators))],
+			Extra:      make([]byte, extraSeal),
+		}
+		header.Nonce = types.EncodeNonce(CalcDifficulty(config, time, header.Coinbase))
+		sig, _ := crypto.Sign(sealHash(header).Bytes(), keys[header.Coinbase])
+		copy(header.Extra, sig)
+
+		headers = append(headers, header)
+		parent = header
+	}
+	engine := New(Config{Scheduled: true}, nil)
+	for _, seal := range []bool{false, true} {
+		seals := make([]bool, len(headers))
+		for i := range seals {
+			seals[i] = seal
+		}
+		abort, results := engine.VerifyHeaders(chain, headers, seals)
+		for i := range headers {
+			if err := <-results; err != nil {
+				t.Errorf("header %d (seal %v) rejected: %v", i, seal, err)
+			}
+		}
+		close(abort)
+	}
+	// Headers not sampled for seal verification skip the signature recovery, so
+	// an unsealed one is only caught if sampled
+	unsealed := append([]*types.Header{}, headers...)
+	unsealed[3] = types.CopyHeader(headers[3])
+	unsealed[3].Extra = make([]byte, extraSeal)
+
+	for _, seal := range []bool{false, true} {
+		abort, results := engine.VerifyHeaders(chain, unsealed, []bool{false, false, false, seal})
+		for i := 0; i < 3; i++ {
+			if err := <-results; err != nil {
+				t.Errorf("header %d rejected: %v", i, err)
+			}
+		}
+		if err := <-results; (err != nil) != seal {
+			t.Errorf("unsealed header (seal %v) error mismatch: have %v, want error %v", seal, err, seal)
+		}
+		close(abort)
+	}
+}
+
+// Tests that the author of headers of unscheduled chains is their coinbase, such
+// headers not being sealed, whatever their extra-data holds.
+func TestAuthorUnscheduled(t *testing.T) {
+	var (
+		key, _   = crypto.GenerateKey()
+		coinbase = common.Address{0xc0}
+		engine   = New(Config{}, nil)
+	)
+	header := &types.Header{Number: big.NewInt(1), Time: big.NewInt(10), Coinbase: coinbase}
+	for _, extra := range [][]byte{nil, make([]byte, extraSeal), make([]byte, extraVanity+extraSeal)} {
+		header.Extra = extra
+		if len(extra) >= extraSeal {
+			sig, _ := crypto.Sign(sealHash(header).Bytes(), key)
+			copy(extra[len(extra)-extraSeal:], sig)
+		}
+		if author, err := engine.Author(header); err != nil || author != coinbase {
+			t.Errorf("extra %d bytes: author mismatch: have %x/%v, want %x", len(extra), author, err, coinbase)
+		}
+	}
+	// Scheduled engines authenticate the sealer instead
+	if author, err := New(Config{Scheduled: true}, nil).Author(header); err != nil || author != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Errorf("scheduled author mismatch: have %x/%v, want %x", author, err, crypto.PubkeyToAddress(key.PublicKey))
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"sort"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/params"
+)
+
+// RegistryAddress is the system account holding the delegate registry in its
+// storage. Vote transactions sent to it register and unregister delegates, and
+// cast and withdraw the votes of their senders.
+var RegistryAddress = common.HexToAddress("0x000000000000000000000000000000000000d905")
+
+// Actions of the vote transactions sent to the registry, given by the first byte
+// of their data.
+const (
+	actionRegister   = 0x01 // Registers the sender as a delegate candidate
+	actionUnregister = 0x02 // Withdraws the sender's candidacy
+	actionVote       = 0x03 // Votes for the delegate following the action byte
+	actionUnvote     = 0x04 // Withdraws the sender's vote
+)
+
+// VoteData returns the data of a vote transaction backing the given delegate.
+func VoteData(delegate common.Address) []byte {
+	return append([]byte{actionVote}, delegate[:]...)
+}
+
+// UnvoteData returns the data of a vote transaction withdrawing its sender's vote.
+func UnvoteData() []byte {
+	return []byte{actionUnvote}
+}
+
+// Names of the address sets kept in the registry's storage.
+const (
+	candidatesSet = "dpos.candidates"
+	votersSet     = "dpos.voters"
+)
+
+// registry is the delegate registry kept in the storage of the registry account.
+// Every voter backs a single delegate with a weight equal to its balance at the
+// time of the election.
+type registry struct {
+	state *state.StateDB
+}
+
+// slot returns the storage slot of a named registry entry with the given keys.
+func slot(name string, keys ...[]byte) common.Hash {
+	return crypto.Keccak256Hash(append([][]byte{[]byte(name)}, keys...)...)
+}
+
+// index encodes a position in an address set as a storage key.
+func index(i uint64) []byte {
+	var enc [8]byte
+	binary.BigEndian.PutUint64(enc[:], i)
+	return enc[:]
+}
+
+// size returns the number of addresses in a set.
+func (r *registry) size(set string) uint64 {
+	return r.state.GetState(RegistryAddress, slot(set)).Big().Uint64()
+}
+
+// members returns the addresses in a set.
+func (r *registry) members(set string) []common.Address {
+	addrs := make([]common.Address, r.size(set))
+	for i := range addrs {
+		addrs[i] = common.BytesToAddress(r.state.GetState(RegistryAddress, slot(set, index(uint64(i)))).Bytes())
+	}
+	return addrs
+}
+
+// contains returns whether an address is in a set.
+func (r *registry) contains(set string, addr common.Address) bool {
+	return r.state.GetState(RegistryAddress, slot(set, addr[:])) != (common.Hash{})
+}
+
+// add inserts an address into a set, tracking its position to allow removal.
+func (r *registry) add(set string, addr common.Address) {
+	if r.contains(set, addr) {
+		return
+	}
+	size := r.size(set)
+	r.state.SetState(RegistryAddress, slot(set, index(size)), addr.Hash())
+	r.state.SetState(RegistryAddress, slot(set, addr[:]), common.BigToHash(new(big.Int).SetUint64(size+1)))
+	r.state.SetState(RegistryAddress, slot(set), common.BigToHash(new(big.Int).SetUint64(size+1)))
+}
+
+// remove deletes an address from a set, moving the last one into its position.
+func (r *registry) remove(set string, addr common.Address) {
+	pos := r.state.GetState(RegistryAddress, slot(set, addr[:])).Big().Uint64()
+	if pos == 0 {
+		return
+	}
+	last := r.size(set) - 1
+	if pos-1 != last {
+		moved := r.state.GetState(RegistryAddress, slot(set, index(last)))
+		r.state.SetState(RegistryAddress, slot(set, index(pos-1)), moved)
+		r.state.SetState(RegistryAddress, slot(set, common.BytesToAddress(moved.Bytes()).Bytes()), common.BigToHash(new(big.Int).SetUint64(pos)))
+	}
+	r.state.SetState(RegistryAddress, slot(set, index(last)), common.Hash{})
+	r.state.SetState(RegistryAddress, slot(set, addr[:]), common.Hash{})
+	r.state.SetState(RegistryAddress, slot(set), common.BigToHash(new(big.Int).SetUint64(last)))
+}
+
+// vote returns the delegate a voter backs, the zero address if none.
+func (r *registry) vote(voter common.Address) common.Address {
+	return common.BytesToAddress(r.state.GetState(RegistryAddress, slot("dpos.vote", voter[:])).Bytes())
+}
+
+// setVote records the delegate a voter backs, withdrawing its vote if zero.
+func (r *registry) setVote(voter, delegate common.Address) {
+	r.state.SetState(RegistryAddress, slot("dpos.vote", voter[:]), delegate.Hash())
+	if delegate == (common.Address{}) {
+		r.remove(votersSet, voter)
+	} else {
+		r.add(votersSet, voter)
+	}
+}
+
+// keep prevents the registry from being swept away as an empty account.
+func (r *registry) keep() {
+	if r.state.GetNonce(RegistryAddress) == 0 {
+		r.state.SetNonce(RegistryAddress, 1)
+	}
+}
+
+// apply executes the registry action of a vote transaction sent by the given
+// account. Malformed actions are ignored.
+func (r *registry) apply(sender common.Address, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	r.keep()
+
+	switch data[0] {
+	case actionRegister:
+		r.add(candidatesSet, sender)
+	case actionUnregister:
+		r.remove(candidatesSet, sender)
+	case actionVote:
+		if len(data) == 1+common.AddressLength {
+			r.setVote(sender, common.BytesToAddress(data[1:]))
+		}
+	case actionUnvote:
+		r.setVote(sender, common.Address{})
+	}
+}
+
+// tally returns the registered candidates with the total balance of the voters
+// backing them, ordered by decreasing weight and increasing address. Candidates
+// without votes are left out.
+func (r *registry) tally() ([]common.Address, map[common.Address]*big.Int) {
+	weights := make(map[common.Address]*big.Int)
+	for _, voter := range r.members(votersSet) {
+		delegate := r.vote(voter)
+		if !r.contains(candidatesSet, delegate) {
+			continue
+		}
+		if weights[delegate] == nil {
+			weights[delegate] = new(big.Int)
+		}
+		weights[delegate].Add(weights[delegate], r.state.GetBalance(voter))
+	}
+	delegates := make([]common.Address, 0, len(weights))
+	for delegate, weight := range weights {
+		if weight.Sign() > 0 {
+			delegates = append(delegates, delegate)
+		}
+	}
+	sort.Slice(delegates, func(i, j int) bool {
+		if cmp := weights[delegates[i]].Cmp(weights[delegates[j]]); cmp != 0 {
+			return cmp > 0
+		}
+		return bytes.Compare(delegates[i][:], delegates[j][:]) < 0
+	})
+	return delegates, weights
+}
+
+// elect returns the validators of the next epoch: the delegates with the most
+// vote weight, up to the configured number, leaving the suspended ones out. The
+// validators of the current epoch not suspended are kept if nobody eligible has
+// been voted for, all of them if all are suspended.
+func (r *registry) elect(config *params.EthashConfig, current []common.Address, suspended map[common.Address]bool) []common.Address {
+	delegates, _ := r.tally()
+	if delegates = eligible(delegates, suspended); len(delegates) == 0 {
+		if delegates = eligible(current, suspended); len(delegates) == 0 {
+			return current
+		}
+		return delegates
+	}
+	seats := int(config.Delegates)
+	if seats == 0 {
+		seats = len(config.Validators)
+	}
+	if len(delegates) > seats {
+		delegates = delegates[:seats]
+	}
+	// Sort the elected delegates for a schedule independent of vote fluctuations
+	sort.Slice(delegates, func(i, j int) bool { return bytes.Compare(delegates[i][:], delegates[j][:]) < 0 })
+	return delegates
+}
+
+// eligible returns the addresses not suspended, in their original order.
+func eligible(addrs []common.Address, suspended map[common.Address]bool) []common.Address {
+	var result []common.Address
+	for _, addr := range addrs {
+		if !suspended[addr] {
+			result = append(result, addr)
+		}
+	}
+	return result
+}
+
+// applyVotes executes the registry actions of the successful vote transactions
+// of a block.
+func applyVotes(config *params.ChainConfig, header *types.Header, statedb *state.StateDB, txs []*types.Transaction, receipts []*types.Receipt) {
+	var (
+		signer = types.MakeSigner(config, header.Number)
+		reg    = &registry{state: statedb}
+	)
+	for i, tx := range txs {
+		if tx.Type() != types.TxTypeVote || tx.To() == nil || *tx.To() != RegistryAddress {
+			continue
+		}
+		if i < len(receipts) && len(receipts[i].PostState) == 0 && receipts[i].Status == types.ReceiptStatusFailed {
+			continue
+		}
+		sender, err := types.Sender(signer, tx)
+		if err != nil {
+			continue
+		}
+		reg.apply(sender, tx.Data())
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+)
+
+// Tests that delegates are elected by the balance of their voters, registered
+// candidates only, and that checkpoints are made to commit to them.
+func TestDelegateElection(t *testing.T) {
+	chain := newEpochChain(4)
+	chain.config.ChainId = big.NewInt(1)
+	config := chain.config.Ethash
+
+	var (
+		keys     = make([]*ecdsa.PrivateKey, 3)
+		accounts = make([]common.Address, 3)
+	)
+	db, _ := gooladb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		accounts[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		statedb.AddBalance(accounts[i], big.NewInt(int64(100-i*25)))
+	}
+	signer := types.NewEIP155Signer(chain.config.ChainId)
+	action := func(sender int, data ...byte) *types.Transaction {
+		tx := types.NewTransaction(statedb.GetNonce(accounts[sender]), RegistryAddress, new(big.Int), 50000, new(big.Int), types.TxTypeVote, data)
+		tx, _ = types.SignTx(tx, signer, keys[sender])
+		statedb.SetNonce(accounts[sender], tx.Nonce()+1)
+		return tx
+	}
+	vote := func(sender, delegate int) *types.Transaction {
+		return action(sender, append([]byte{actionVote}, accounts[delegate][:]...)...)
+	}
+	header := &types.Header{Number: big.NewInt(1)}
+	applyVotes(chain.config, header, statedb, []*types.Transaction{
+		action(0, actionRegister),
+		action(1, actionRegister),
+		action(2, actionRegister),
+		vote(0, 1), // 100 for account 1
+		vote(1, 0), // 75 for account 0
+		vote(2, 2), // 50 for account 2
+		action(2, actionUnregister),
+	}, nil)
+
+	reg := &registry{state: statedb}
+	if candidates := reg.members(candidatesSet); len(candidates) != 2 || !reg.contains(candidatesSet, accounts[0]) || !reg.contains(candidatesSet, accounts[1]) {
+		t.Fatalf("candidates mismatch: %x", candidates)
+	}
+	delegates, weights := reg.tally()
+	if want := []common.Address{accounts[1], accounts[0]}; !reflect.DeepEqual(delegates, want) {
+		t.Fatalf("tally mismatch: have %x, want %x", delegates, want)
+	}
+	if weights[accounts[1]].Int64() != 100 || weights[accounts[0]].Int64() != 75 {
+		t.Errorf("weights mismatch: %v", weights)
+	}
+	// Failed vote transactions must be ignored
+	applyVotes(chain.config, header, statedb, []*types.Transaction{action(0, actionUnvote)}, []*types.Receipt{{Status: types.ReceiptStatusFailed}})
+	if reg.vote(accounts[0]) != accounts[1] {
+		t.Errorf("failed unvote applied")
+	}
+	config.Delegates = 1
+	if elected := reg.elect(config, config.Validators, nil); !reflect.DeepEqual(elected, []common.Address{accounts[1]}) {
+		t.Errorf("elected validators mismatch: have %x, want [%x]", elected, accounts[1])
+	}
+	config.Delegates = 0
+
+	// Checkpoints commit to the elected validators, sorted by address
+	elected := []common.Address{accounts[0], accounts[1]}
+	if accounts[1].Big().Cmp(accounts[0].Big()) < 0 {
+		elected[0], elected[1] = elected[1], elected[0]
+	}
+	checkpoint := &types.Header{ParentHash: chain.headers[3].Hash(), Number: big.NewInt(4), Time: big.NewInt(int64(4 * config.Period)), Extra: checkpointExtra(nil, nil)}
+	if _, err := NewFaker().Finalize(chain, checkpoint, statedb, nil, nil); err != nil {
+		t.Fatalf("failed to finalize checkpoint: %v", err)
+	}
+	if validators, err := CheckpointValidators(checkpoint); err != nil || !reflect.DeepEqual(validators, elected) {
+		t.Errorf("checkpoint validators mismatch: have %x/%v, want %x", validators, err, elected)
+	}
+	checkpoint.Extra = checkpointExtra(nil, config.Validators)
+	if _, err := NewFaker().Finalize(chain, checkpoint, statedb, nil, nil); err != errMismatchingCheckpointValidators {
+		t.Errorf("checkpoint with unelected validators error mismatch: have %v, want %v", err, errMismatchingCheckpointValidators)
+	}
+	// Without votes, the current validators stay in office
+	applyVotes(chain.config, header, statedb, []*types.Transaction{action(0, actionUnvote), action(1, actionUnvote)}, nil)
+	if elected := reg.elect(config, config.Validators, nil); !reflect.DeepEqual(elected, config.Validators) {
+		t.Errorf("validators without votes mismatch: have %x, want %x", elected, config.Validators)
+	}
+	if voters := reg.members(votersSet); len(voters) != 1 || voters[0] != accounts[2] {
+		t.Errorf("voters mismatch: %x", voters)
+	}
+}
+
+// Tests that the registry isn't swept away as an empty account.
+func TestRegistryPersistence(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	reg := &registry{state: statedb}
+	reg.apply(common.Address{0x01}, []byte{actionRegister})
+	statedb.Finalise(true)
+
+	if !reg.contains(candidatesSet, common.Address{0x01}) {
+		t.Errorf("registry lost")
+	}
+}
+
+// stateChain is a test chain giving access to the states of its blocks.
+type stateChain struct {
+	*testChain
+	db state.Database
+}
+
+func (c *stateChain) StateAt(root common.Hash) (*state.StateDB, error) {
+	return state.New(root, c.db)
+}
+
+// Tests that the API reports the current and pending delegates along with the
+// votes backing them.
+func TestDelegatesAPI(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	chain := &stateChain{testChain: newEpochChain(6), db: state.NewDatabase(db)}
+	config := chain.config.Ethash
+
+	statedb, _ := state.New(common.Hash{}, chain.db)
+	statedb.AddBalance(common.Address{0x10}, big.NewInt(100))
+
+	reg := &registry{state: statedb}
+	reg.apply(outsider, []byte{actionRegister})
+	reg.apply(common.Address{0x10}, VoteData(outsider))
+	root, err := statedb.Commit(true)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	chain.CurrentHeader().Root = root
+
+	api := &API{chain: chain, dpos: New(Config{}, nil)}
+	delegates, err := api.GetDelegates(nil)
+	if err != nil {
+		t.Fatalf("failed to get delegates: %v", err)
+	}
+	if !reflect.DeepEqual(delegates.Current, config.Validators) {
+		t.Errorf("current delegates mismatch: have %x, want %x", delegates.Current, config.Validators)
+	}
+	if want := []common.Address{outsider}; !reflect.DeepEqual(delegates.Pending, want) {
+		t.Errorf("pending delegates mismatch: have %x, want %x", delegates.Pending, want)
+	}
+	votes, err := api.GetVotes(outsider, nil)
+	if err != nil {
+		t.Fatalf("failed to get candidate votes: %v", err)
+	}
+	if !votes.Candidate || votes.Weight.ToInt().Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("candidate votes mismatch: have %v/%v, want true/100", votes.Candidate, votes.Weight)
+	}
+	if votes, _ = api.GetVotes(common.Address{0x10}, nil); votes.Delegate != outsider || votes.Weight.ToInt().Sign() != 0 {
+		t.Errorf("voter votes mismatch: have %x/%v, want %x/0", votes.Delegate, votes.Weight, outsider)
+	}
+	// Chains without state access can't serve the registry
+	api.chain = chain.testChain
+	if _, err := api.GetDelegates(nil); err != errNoState {
+		t.Errorf("stateless chain error mismatch: have %v, want %v", err, errNoState)
+	}
+}
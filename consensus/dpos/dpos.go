@@ -23,8 +23,12 @@ import (
 	"math/rand"
 	"sync"
 	"time"
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/timesync"
 	"github.com/goola-team/goola/consensus"
+	"github.com/goola-team/goola/gooladb"
 	"github.com/goola-team/goola/rpc"
+	"github.com/hashicorp/golang-lru"
 
 )
 
@@ -62,34 +66,64 @@ const (
 	ModeFullFake
 )
 
+// Clock is the source of time used to validate block timestamps and to wait for
+// production slots. It allows simulations to run the engine on a virtual clock.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the drift compensated wall clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return timesync.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
 // Config are the configuration parameters of the ethash.
 type Config struct {
-
+	Scheduled bool // Whether block production is scheduled, headers being sealed by their producer
 }
 
 // dops is a consensus engine based on proot-of-work implementing the dpos
 // algorithm.
 type dops struct {
 	config Config
+	mode   Mode
+	db     gooladb.Database // Database to persist the epoch snapshots into, if any
 
 	// Mining related fields
 	rand     *rand.Rand    // Properly seeded random source for nonces
 	threads  int           // Number of threads to mine on if mining
 	update   chan struct{} // Notification channel to update mining parameters
 
+	signers     *consensus.SignerCache // Producers recovered from headers, created on first use
+	signersOnce sync.Once              // Ensures the signer cache is only created once
+
+	schedules     *lru.Cache // Epoch snapshots of the children of recent headers, created on first use
+	schedulesOnce sync.Once  // Ensures the schedule cache is only created once
+
+	drifts     *driftTracker // Timestamp drifts of the blocks received, created on first use
+	driftsOnce sync.Once     // Ensures the drift tracker is only created once
+
+	sealer common.Address // Goola address of the signing key sealing blocks
+	signFn SignerFn       // Signer function to authorize hashes with
+
 	// The fields below are hooks for testing
 	shared    *dops         // Shared PoW verifier to avoid cache regeneration
 	fakeFail  uint64        // Block number which fails PoW check even in fake mode
 	fakeDelay time.Duration // Time delay to sleep for before returning from verify
+	clock     Clock         // Source of time, the system clock if nil
 
 	lock sync.Mutex // Ensures thread safety for the in-memory caches and mining fields
 }
 
 
-// New creates a full sized ethash PoW scheme.
-func New(config Config) *dops {
+// New creates a dpos consensus engine persisting the epoch snapshots into the
+// given database.
+func New(config Config, db gooladb.Database) *dops {
 	return &dops{
 		config:   config,
+		db:       db,
 		update:   make(chan struct{}),
 	}
 }
@@ -100,6 +134,7 @@ func New(config Config) *dops {
 // consensus rules.
 func NewFaker() *dops {
 	return &dops{
+		mode: ModeFake,
 	}
 }
 
@@ -108,6 +143,7 @@ func NewFaker() *dops {
 // still have to conform to the Goola consensus rules.
 func NewFakeFailer(fail uint64) *dops {
 	return &dops{
+		mode:     ModeFake,
 		fakeFail: fail,
 	}
 }
@@ -117,6 +153,7 @@ func NewFakeFailer(fail uint64) *dops {
 // they still have to conform to the Goola consensus rules.
 func NewFakeDelayer(delay time.Duration) *dops {
 	return &dops{
+		mode:      ModeFake,
 		fakeDelay: delay,
 	}
 }
@@ -125,7 +162,7 @@ func NewFakeDelayer(delay time.Duration) *dops {
 // accepts all blocks as valid, without checking any consensus rules whatsoever.
 func NewFullFaker() *dops {
 	return &dops{
-
+		mode: ModeFullFake,
 	}
 }
 
@@ -139,8 +176,35 @@ func NewShared() *dops {
 
 
 
-// APIs implements consensus.Engine, returning the user facing RPC APIs. Currently
-// that is empty.
+// SetClock replaces the source of time of the engine. It's meant to be used by
+// simulations before the engine is put to use.
+func (ethash *dops) SetClock(clock Clock) {
+	ethash.clock = clock
+}
+
+// now returns the current time according to the engine's clock.
+func (ethash *dops) now() time.Time {
+	if ethash.clock == nil {
+		return systemClock{}.Now()
+	}
+	return ethash.clock.Now()
+}
+
+// after waits for the duration to elapse on the engine's clock.
+func (ethash *dops) after(d time.Duration) <-chan time.Time {
+	if ethash.clock == nil {
+		return systemClock{}.After(d)
+	}
+	return ethash.clock.After(d)
+}
+
+// APIs implements consensus.Engine, returning the user facing RPC API to inspect
+// the epoch snapshots.
 func (ethash *dops) APIs(chain consensus.ChainReader) []rpc.API {
-	return nil
+	return []rpc.API{{
+		Namespace: "dpos",
+		Version:   "1.0",
+		Service:   &API{chain: chain, dpos: ethash},
+		Public:    false,
+	}}
 }
@@ -0,0 +1,213 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/metrics"
+	"github.com/hashicorp/golang-lru"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+const (
+	driftWindow       = time.Minute     // Maximum drift of a header received live, older ones being synced
+	driftSamples      = 32              // Number of most recent drifts kept per validator
+	driftAlertSamples = 8               // Minimum number of recent drifts of a validator to alert on
+	driftTolerance    = 2 * time.Second // Maximum mean drift of the recent blocks of a validator
+	driftSeenLimit    = 1024            // Number of recorded header hashes to skip verifying again
+)
+
+var (
+	driftHistogram  = metrics.NewHistogram("consensus/dpos/drift")
+	driftEarlyMeter = metrics.NewMeter("consensus/dpos/drift/early")
+	driftLateMeter  = metrics.NewMeter("consensus/dpos/drift/late")
+)
+
+// validatorDrift is the record of the timestamp drifts of the blocks produced
+// by a single validator.
+type validatorDrift struct {
+	recent    []time.Duration     // Ring buffer of the most recent drifts
+	next      int                 // Index of the next drift to overwrite in the ring
+	blocks    uint64              // Number of blocks recorded in total
+	early     uint64              // Number of blocks received before their timestamp by more than the tolerance
+	late      uint64              // Number of blocks received after their timestamp by more than the tolerance
+	alert     int                 // Direction of the raised alert (-1 early, 1 late, 0 none)
+	histogram gometrics.Histogram // Distribution of the drifts of the validator
+}
+
+// mean returns the mean of the most recent drifts of the validator.
+func (v *validatorDrift) mean() time.Duration {
+	if len(v.recent) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, drift := range v.recent {
+		total += drift
+	}
+	return total / time.Duration(len(v.recent))
+}
+
+// driftTracker records the drift between the local time blocks are received at
+// and their header timestamps, alerting when a validator consistently produces
+// early or late blocks, a sign of a misconfigured clock.
+type driftTracker struct {
+	seen       *lru.Cache // Hashes of the headers already recorded
+	validators map[common.Address]*validatorDrift
+	lock       sync.Mutex
+}
+
+// newDriftTracker creates an empty block timestamp drift tracker.
+func newDriftTracker() *driftTracker {
+	seen, _ := lru.New(driftSeenLimit)
+	return &driftTracker{
+		seen:       seen,
+		validators: make(map[common.Address]*validatorDrift),
+	}
+}
+
+// record accounts the drift of a block produced by a validator. Blocks already
+// recorded, and blocks too old to have been received live, are ignored.
+func (t *driftTracker) record(validator common.Address, header *types.Header, received time.Time) {
+	drift := received.Sub(time.Unix(header.Time.Int64(), 0))
+	if drift > driftWindow {
+		return
+	}
+	if seen, _ := t.seen.ContainsOrAdd(header.Hash(), true); seen {
+		return
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	v := t.validators[validator]
+	if v == nil {
+		v = &validatorDrift{histogram: metrics.NewHistogram("consensus/dpos/drift/" + validator.Hex())}
+		t.validators[validator] = v
+	}
+	if len(v.recent) < driftSamples {
+		v.recent = append(v.recent, drift)
+	} else {
+		v.recent[v.next] = drift
+	}
+	v.next = (v.next + 1) % driftSamples
+	v.blocks++
+
+	switch {
+	case drift < -driftTolerance:
+		v.early++
+		driftEarlyMeter.Mark(1)
+	case drift > driftTolerance:
+		v.late++
+		driftLateMeter.Mark(1)
+	}
+	driftHistogram.Update(int64(drift / time.Millisecond))
+	v.histogram.Update(int64(drift / time.Millisecond))
+
+	// Raise or clear the alert of the validator if its recent blocks warrant it
+	if len(v.recent) < driftAlertSamples {
+		return
+	}
+	alert, mean := 0, v.mean()
+	switch {
+	case mean < -driftTolerance:
+		alert = -1
+	case mean > driftTolerance:
+		alert = 1
+	}
+	if alert == v.alert {
+		return
+	}
+	v.alert = alert
+
+	switch alert {
+	case -1:
+		log.Warn("Validator producing blocks early, check its clock", "validator", validator, "drift", common.PrettyDuration(mean))
+	case 1:
+		log.Warn("Validator producing blocks late, check its clock", "validator", validator, "drift", common.PrettyDuration(mean))
+	default:
+		log.Info("Validator block timing back to normal", "validator", validator, "drift", common.PrettyDuration(mean))
+	}
+}
+
+// driftTracker returns the block timestamp drift tracker of the engine.
+func (ethash *dops) driftTracker() *driftTracker {
+	ethash.driftsOnce.Do(func() {
+		ethash.drifts = newDriftTracker()
+	})
+	return ethash.drifts
+}
+
+// recordDrift accounts the timestamp drift of a header verified upon receipt
+// against the current time.
+func (ethash *dops) recordDrift(header *types.Header) {
+	received := ethash.now()
+	producer, err := ethash.signer(header)
+	if err != nil {
+		return
+	}
+	ethash.driftTracker().record(producer, header, received)
+}
+
+// Drift is the record of the timestamp drifts of the blocks of a validator, the
+// durations being in milliseconds, positive if the blocks were received after
+// their timestamps.
+type Drift struct {
+	Blocks uint64 `json:"blocks"` // Blocks recorded in total
+	Mean   int64  `json:"mean"`   // Mean drift of the most recent blocks
+	Min    int64  `json:"min"`    // Minimum drift of the most recent blocks
+	Max    int64  `json:"max"`    // Maximum drift of the most recent blocks
+	Early  uint64 `json:"early"`  // Blocks received before their timestamp by more than the tolerance
+	Late   uint64 `json:"late"`   // Blocks received after their timestamp by more than the tolerance
+	Alert  string `json:"alert"`  // Raised alert on the recent blocks ("early", "late" or empty)
+}
+
+// drifts returns the timestamp drift records of every validator seen.
+func (t *driftTracker) drifts() map[common.Address]*Drift {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	drifts := make(map[common.Address]*Drift)
+	for validator, v := range t.validators {
+		drift := &Drift{
+			Blocks: v.blocks,
+			Mean:   int64(v.mean() / time.Millisecond),
+			Min:    int64(v.recent[0] / time.Millisecond),
+			Max:    int64(v.recent[0] / time.Millisecond),
+			Early:  v.early,
+			Late:   v.late,
+		}
+		for _, recent := range v.recent[1:] {
+			if ms := int64(recent / time.Millisecond); ms < drift.Min {
+				drift.Min = ms
+			} else if ms > drift.Max {
+				drift.Max = ms
+			}
+		}
+		switch v.alert {
+		case -1:
+			drift.Alert = "early"
+		case 1:
+			drift.Alert = "late"
+		}
+		drifts[validator] = drift
+	}
+	return drifts
+}
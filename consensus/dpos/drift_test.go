@@ -0,0 +1,64 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+)
+
+// Tests that the timestamp drifts of received blocks are recorded per producer,
+// and that producers with consistently early or late blocks are flagged.
+func TestDriftTracking(t *testing.T) {
+	var (
+		early, punctual = common.Address{0x0a}, common.Address{0x0b}
+		tracker         = newDriftTracker()
+		now             = time.Unix(1000000, 0)
+	)
+	for i := 0; i < driftAlertSamples; i++ {
+		// A producer 3 seconds ahead, and one received half a second late
+		header := &types.Header{Number: big.NewInt(int64(2 * i)), Time: big.NewInt(now.Unix() + 3)}
+		tracker.record(early, header, now)
+		tracker.record(early, header, now) // verified again upon import
+
+		header = &types.Header{Number: big.NewInt(int64(2*i + 1)), Time: big.NewInt(now.Unix())}
+		tracker.record(punctual, header, now.Add(500*time.Millisecond))
+	}
+	// Blocks synced long after their production are not received live
+	old := &types.Header{Number: big.NewInt(100), Time: big.NewInt(now.Unix() - 3600)}
+	tracker.record(punctual, old, now)
+
+	drifts := tracker.drifts()
+	if have := drifts[early]; have == nil || have.Blocks != driftAlertSamples || have.Mean != -3000 || have.Early != driftAlertSamples || have.Alert != "early" {
+		t.Errorf("early producer drift mismatch: have %+v", have)
+	}
+	if have := drifts[punctual]; have == nil || have.Blocks != driftAlertSamples || have.Mean != 500 || have.Min != 500 || have.Max != 500 || have.Alert != "" {
+		t.Errorf("punctual producer drift mismatch: have %+v", have)
+	}
+	// The alert is cleared once the producer's clock is fixed
+	for i := 0; i < driftSamples; i++ {
+		header := &types.Header{Number: big.NewInt(int64(200 + i)), Time: big.NewInt(now.Unix())}
+		tracker.record(early, header, now)
+	}
+	if have := tracker.drifts()[early]; have.Alert != "" || have.Mean != 0 || have.Blocks != driftAlertSamples+driftSamples {
+		t.Errorf("fixed producer drift mismatch: have %+v", have)
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/consensus"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/params"
+)
+
+const (
+	// extraVanity is the number of extra-data prefix bytes reserved for producer
+	// vanity on checkpoint blocks, followed by the next epoch's validators.
+	extraVanity = 32
+
+	// extraSeal is the number of extra-data suffix bytes reserved for the
+	// producer's signature on blocks of scheduled chains.
+	extraSeal = 65
+
+	// maxEpochProofHeaders is the maximum number of headers in an epoch proof.
+	maxEpochProofHeaders = 1024
+)
+
+var (
+	// errInvalidCheckpointValidators is returned if a checkpoint block's extra-data
+	// doesn't hold a well formed list of validators.
+	errInvalidCheckpointValidators = errors.New("invalid validator list on checkpoint block")
+
+	// errMismatchingCheckpointValidators is returned if a checkpoint block commits
+	// to a validator set different from the one elected for the next epoch.
+	errMismatchingCheckpointValidators = errors.New("mismatching validator list on checkpoint block")
+
+	// errEpochNotFinal is returned when proving an epoch transition that hasn't
+	// been built upon by enough of the previous validators yet.
+	errEpochNotFinal = errors.New("epoch transition not final")
+
+	// errInvalidEpochProof is returned if an epoch proof doesn't prove the
+	// transition it's supposed to.
+	errInvalidEpochProof = errors.New("invalid epoch proof")
+
+	// errUnsealedEpochProof is returned when proving an epoch transition of a
+	// chain without a validator schedule, its headers not being sealed.
+	errUnsealedEpochProof = errors.New("epoch proof of unsealed chain")
+)
+
+// IsCheckpoint reports whether the block with the given number is the last one
+// of an epoch, committing to the validators of the next.
+func IsCheckpoint(config *params.EthashConfig, number uint64) bool {
+	return config != nil && config.Scheduled() && config.Epoch > 0 && number > 0 && number%config.Epoch == 0
+}
+
+// CheckpointValidators returns the validators a checkpoint header commits to.
+func CheckpointValidators(header *types.Header) ([]common.Address, error) {
+	if len(header.Extra) < extraVanity+extraSeal || (len(header.Extra)-extraVanity-extraSeal)%common.AddressLength != 0 {
+		return nil, errInvalidCheckpointValidators
+	}
+	validators := make([]common.Address, (len(header.Extra)-extraVanity-extraSeal)/common.AddressLength)
+	for i := range validators {
+		copy(validators[i][:], header.Extra[extraVanity+i*common.AddressLength:])
+	}
+	if len(validators) == 0 {
+		return nil, errInvalidCheckpointValidators
+	}
+	return validators, nil
+}
+
+// checkpointExtra returns the extra-data of a checkpoint block, the vanity padded
+// or truncated to its reserved size followed by the validators and the space
+// reserved for the seal.
+func checkpointExtra(vanity []byte, validators []common.Address) []byte {
+	extra := make([]byte, extraVanity, extraVanity+len(validators)*common.AddressLength+extraSeal)
+	copy(extra, vanity)
+	for _, validator := range validators {
+		extra = append(extra, validator[:]...)
+	}
+	return append(extra, make([]byte, extraSeal)...)
+}
+
+// verifyCheckpoint checks that a checkpoint header commits to a well formed
+// validator set. Whether it's the elected one can only be checked against the
+// state, when the block is finalized.
+func verifyCheckpoint(header *types.Header) error {
+	_, err := CheckpointValidators(header)
+	return err
+}
+
+// verifyElection checks that a checkpoint header commits to the elected
+// validators, or makes it commit to them if it doesn't commit to any yet.
+func verifyElection(header *types.Header, elected []common.Address) error {
+	validators, err := CheckpointValidators(header)
+	if err != nil {
+		header.Extra = checkpointExtra(header.Extra, elected)
+		return nil
+	}
+	if len(validators) != len(elected) {
+		return errMismatchingCheckpointValidators
+	}
+	for i := range validators {
+		if validators[i] != elected[i] {
+			return errMismatchingCheckpointValidators
+		}
+	}
+	return nil
+}
+
+// epochConfig returns the production schedule of the children of a header: the
+// configuration with the validators of their epoch, being the configured ones in
+// the first epoch and those the checkpoint ending the previous epoch commits to
+// afterwards. Ancestors not yet in the chain may be given, oldest first.
+func (ethash *dops) epochConfig(chain consensus.ChainReader, parent *types.Header, parents []*types.Header) (*params.EthashConfig, error) {
+	snap, err := ethash.snapshot(chain, parent, parents)
+	if err != nil {
+		return nil, err
+	}
+	config := *chain.Config().Ethash
+	config.Validators = snap.Validators
+	return &config, nil
+}
+
+// producer returns the account that produced a header.
+func producer(header *types.Header) (common.Address, error) {
+	return header.Coinbase, nil
+}
+
+// signer returns the account that produced a header, going through the signer
+// cache shared by header verification and fork choice. Unless faking, that is
+// the account recovered from the header's seal.
+func (ethash *dops) signer(header *types.Header) (common.Address, error) {
+	ethash.signersOnce.Do(func() {
+		if ethash.mode == ModeNormal {
+			ethash.signers = consensus.NewSignerCache(ecrecover)
+		} else {
+			ethash.signers = consensus.NewSignerCache(producer)
+		}
+	})
+	return ethash.signers.Signer(header)
+}
+
+// finalityThreshold returns the number of distinct validators of a set that
+// need to build upon a block for it to become irreversible.
+func finalityThreshold(validators int) int {
+	return validators*2/3 + 1
+}
+
+// EpochProof returns the proof of the validator set transition into the given
+// epoch: the checkpoint header ending the previous epoch and its descendants up
+// to the one completing the confirmations of more than two thirds of the
+// previous epoch's validators.
+func EpochProof(chain consensus.ChainReader, epoch uint64) ([]*types.Header, error) {
+	config := chain.Config().Ethash
+	if !config.Scheduled() {
+		return nil, errUnsealedEpochProof
+	}
+	if epoch == 0 || !IsCheckpoint(config, epoch*config.Epoch) {
+		return nil, fmt.Errorf("no transition into epoch %d", epoch)
+	}
+	previous := config.Validators
+	if epoch > 1 {
+		header := chain.GetHeaderByNumber((epoch - 1) * config.Epoch)
+		if header == nil {
+			return nil, errEpochNotFinal
+		}
+		validators, err := CheckpointValidators(header)
+		if err != nil {
+			return nil, err
+		}
+		previous = validators
+	}
+	var (
+		headers   []*types.Header
+		confirmed = make(map[common.Address]bool)
+		members   = make(map[common.Address]bool)
+	)
+	for _, validator := range previous {
+		members[validator] = true
+	}
+	for number := epoch * config.Epoch; len(headers) < maxEpochProofHeaders; number++ {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			break
+		}
+		headers = append(headers, header)
+
+		if signer, err := sealedProducer(header); err == nil && members[signer] {
+			if confirmed[signer] = true; len(confirmed) >= finalityThreshold(len(previous)) {
+				return headers, nil
+			}
+		}
+	}
+	return nil, errEpochNotFinal
+}
+
+// VerifyEpochProof checks that a list of headers proves the transition into the
+// given epoch, starting with the checkpoint ending the previous epoch, linked
+// together and sealed by more than two thirds of the previous validators. The
+// validators of the new epoch are returned.
+func VerifyEpochProof(config *params.EthashConfig, epoch uint64, previous []common.Address, headers []*types.Header) ([]common.Address, error) {
+	if !config.Scheduled() {
+		return nil, errUnsealedEpochProof
+	}
+	if epoch == 0 || !IsCheckpoint(config, epoch*config.Epoch) {
+		return nil, fmt.Errorf("no transition into epoch %d", epoch)
+	}
+	if len(headers) == 0 || len(headers) > maxEpochProofHeaders || headers[0].Number.Uint64() != epoch*config.Epoch {
+		return nil, errInvalidEpochProof
+	}
+	members := make(map[common.Address]bool)
+	for _, validator := range previous {
+		members[validator] = true
+	}
+	confirmed := make(map[common.Address]bool)
+	for i, header := range headers {
+		if i > 0 && (header.ParentHash != headers[i-1].Hash() || header.Number.Uint64() != headers[i-1].Number.Uint64()+1) {
+			return nil, errInvalidEpochProof
+		}
+		signer, err := sealedProducer(header)
+		if err != nil {
+			return nil, err
+		}
+		if members[signer] {
+			confirmed[signer] = true
+		}
+	}
+	if len(confirmed) < finalityThreshold(len(previous)) {
+		return nil, errEpochNotFinal
+	}
+	return CheckpointValidators(headers[0])
+}
+
+// sealedProducer returns the producer of a header, authenticated by its seal.
+// Unlike the coinbase alone, it can't be claimed by anyone but the producer.
+func sealedProducer(header *types.Header) (common.Address, error) {
+	signer, err := ecrecover(header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if signer != header.Coinbase {
+		return common.Address{}, errInvalidSigner
+	}
+	return signer, nil
+}
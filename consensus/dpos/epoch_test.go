@@ -0,0 +1,232 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/params"
+)
+
+// testChain is a canonical header chain for epoch proof tests.
+type testChain struct {
+	config  *params.ChainConfig
+	headers []*types.Header
+}
+
+func (c *testChain) Config() *params.ChainConfig  { return c.config }
+func (c *testChain) CurrentHeader() *types.Header { return c.headers[len(c.headers)-1] }
+func (c *testChain) GetBlock(common.Hash, uint64) *types.Block {
+	return nil
+}
+func (c *testChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+		return header
+	}
+	return nil
+}
+func (c *testChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range c.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number < uint64(len(c.headers)) {
+		return c.headers[number]
+	}
+	return nil
+}
+
+// newEpochChain creates a chain of the given length with the validators of the
+// test schedule producing in turn, and checkpoints every four blocks.
+func newEpochChain(length int) *testChain {
+	config := *testSchedule
+	return makeEpochChain(&config, length, nil)
+}
+
+// newSealedEpochChain creates a chain like newEpochChain, but with validators
+// backed by keys, sealing the headers they produce.
+func newSealedEpochChain(length int) *testChain {
+	config := *testSchedule
+	config.Validators = nil
+
+	keys := make(map[common.Address]*ecdsa.PrivateKey)
+	for i := 0; i < len(testSchedule.Validators); i++ {
+		key, _ := crypto.GenerateKey()
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+
+		keys[addr] = key
+		config.Validators = append(config.Validators, addr)
+	}
+	return makeEpochChain(&config, length, keys)
+}
+
+// makeEpochChain creates a chain of the given length with the validators of the
+// config producing in turn, sealing the headers of those with a key.
+func makeEpochChain(config *params.EthashConfig, length int, keys map[common.Address]*ecdsa.PrivateKey) *testChain {
+	config.Epoch = 4
+
+	chain := &testChain{config: &params.ChainConfig{Ethash: config}}
+	parent := &types.Header{Number: big.NewInt(0), Time: big.NewInt(0)}
+	chain.headers = append(chain.headers, parent)
+	for i := 1; i < length; i++ {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     big.NewInt(int64(i)),
+			Time:       big.NewInt(int64(i) * int64(config.Period)),
+			Coinbase:   config.Validators[i%len(config.Validators)],
+			Extra:      []byte("vanity"),
+		}
+		if IsCheckpoint(config, uint64(i)) {
+			header.Extra = checkpointExtra(header.Extra, config.Validators)
+		} else if keys != nil {
+			header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+		}
+		if key := keys[header.Coinbase]; key != nil {
+			sig, _ := crypto.Sign(sealHash(header).Bytes(), key)
+			copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		}
+		chain.headers = append(chain.headers, header)
+		parent = header
+	}
+	return chain
+}
+
+// Tests that checkpoints commit to the validators of the next epoch.
+func TestCheckpointValidators(t *testing.T) {
+	chain := newEpochChain(9)
+	config := chain.config.Ethash
+
+	for _, header := range chain.headers {
+		if !IsCheckpoint(config, header.Number.Uint64()) {
+			continue
+		}
+		if err := verifyCheckpoint(header); err != nil {
+			t.Errorf("checkpoint %d: %v", header.Number, err)
+		}
+		if err := verifyElection(header, config.Validators); err != nil {
+			t.Errorf("checkpoint %d: %v", header.Number, err)
+		}
+	}
+	header := types.CopyHeader(chain.headers[4])
+	if err := verifyElection(header, []common.Address{outsider}); err != errMismatchingCheckpointValidators {
+		t.Error("checkpoint with foreign validators accepted")
+	}
+	if header.Extra = header.Extra[:extraVanity+5]; verifyCheckpoint(header) != errInvalidCheckpointValidators {
+		t.Error("checkpoint with malformed validators accepted")
+	}
+	// Checkpoints not committing to any validators yet commit to the elected ones
+	if header.Extra = checkpointExtra(nil, nil); verifyElection(header, []common.Address{outsider}) != nil {
+		t.Fatal("failed to commit to elected validators")
+	}
+	if validators, err := CheckpointValidators(header); err != nil || len(validators) != 1 || validators[0] != outsider {
+		t.Errorf("committed validators mismatch: have %x/%v, want [%x]", validators, err, outsider)
+	}
+}
+
+// Tests that the validators of an epoch are the ones the checkpoint ending the
+// previous epoch commits to.
+func TestEpochConfig(t *testing.T) {
+	chain := newEpochChain(9)
+	chain.headers[4].Extra = checkpointExtra(nil, []common.Address{outsider})
+	for i := 5; i < len(chain.headers); i++ {
+		chain.headers[i].ParentHash = chain.headers[i-1].Hash()
+	}
+	engine := NewFaker()
+	for i, want := range [][]common.Address{chain.config.Ethash.Validators, {outsider}, {outsider}} {
+		parent := chain.headers[[]int{3, 4, 7}[i]]
+		epoch, err := engine.epochConfig(chain, parent, nil)
+		if err != nil {
+			t.Fatalf("test %d: failed to get schedule: %v", i, err)
+		}
+		if !reflect.DeepEqual(epoch.Validators, want) {
+			t.Errorf("test %d: validators mismatch: have %x, want %x", i, epoch.Validators, want)
+		}
+	}
+}
+
+// Tests that epoch proofs are built once the checkpoint is confirmed by enough
+// validators, and that tampered proofs are rejected.
+func TestEpochProof(t *testing.T) {
+	chain := newSealedEpochChain(9)
+	config := chain.config.Ethash
+
+	// With two validators, both must have produced on top of the checkpoint
+	unconfirmed := &testChain{config: chain.config, headers: chain.headers[:5]}
+	if _, err := EpochProof(unconfirmed, 1); err != errEpochNotFinal {
+		t.Fatalf("unconfirmed epoch proof error mismatch: have %v, want %v", err, errEpochNotFinal)
+	}
+	proof, err := EpochProof(chain, 1)
+	if err != nil {
+		t.Fatalf("failed to create epoch proof: %v", err)
+	}
+	if len(proof) != 2 || proof[0].Number.Uint64() != 4 {
+		t.Fatalf("epoch proof mismatch: %d headers", len(proof))
+	}
+	validators, err := VerifyEpochProof(config, 1, config.Validators, proof)
+	if err != nil {
+		t.Fatalf("failed to verify epoch proof: %v", err)
+	}
+	if !reflect.DeepEqual(validators, config.Validators) {
+		t.Errorf("proven validators mismatch: have %x, want %x", validators, config.Validators)
+	}
+	if _, err := VerifyEpochProof(config, 2, config.Validators, proof); err != errInvalidEpochProof {
+		t.Errorf("proof of another epoch accepted: %v", err)
+	}
+	if _, err := VerifyEpochProof(config, 1, []common.Address{config.Validators[0], outsider}, proof); err != errEpochNotFinal {
+		t.Errorf("proof by untrusted validators accepted: %v", err)
+	}
+	forged := []*types.Header{proof[0], types.CopyHeader(proof[1])}
+	forged[1].ParentHash = common.Hash{}
+	if _, err := VerifyEpochProof(config, 1, config.Validators, forged); err != errInvalidEpochProof {
+		t.Errorf("unlinked proof accepted: %v", err)
+	}
+	// Claiming a validator's coinbase without its seal must not confirm anything
+	forged = []*types.Header{proof[0], types.CopyHeader(proof[1])}
+	forged[1].Coinbase = config.Validators[0]
+	if _, err := VerifyEpochProof(config, 1, config.Validators, forged); err != errInvalidSigner {
+		t.Errorf("proof with forged coinbase error mismatch: have %v, want %v", err, errInvalidSigner)
+	}
+	forged[1].Extra = []byte("vanity")
+	if _, err := VerifyEpochProof(config, 1, config.Validators, forged); err != errMissingSignature {
+		t.Errorf("proof with unsealed header error mismatch: have %v, want %v", err, errMissingSignature)
+	}
+	// Forged headers on the chain must not count towards finality either
+	tampered := &testChain{config: chain.config, headers: append([]*types.Header{}, chain.headers[:5]...)}
+	tampered.headers = append(tampered.headers, forged[1])
+	if _, err := EpochProof(tampered, 1); err != errEpochNotFinal {
+		t.Errorf("epoch proof over forged header error mismatch: have %v, want %v", err, errEpochNotFinal)
+	}
+	// Chains without seals can't prove anything
+	unsealed := newEpochChain(9)
+	unsealed.config.Ethash.Period = 0
+	if _, err := EpochProof(unsealed, 1); err != errUnsealedEpochProof {
+		t.Errorf("unsealed chain epoch proof error mismatch: have %v, want %v", err, errUnsealedEpochProof)
+	}
+	if _, err := VerifyEpochProof(unsealed.config.Ethash, 1, config.Validators, proof); err != errUnsealedEpochProof {
+		t.Errorf("unsealed chain proof error mismatch: have %v, want %v", err, errUnsealedEpochProof)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/consensus"
+	"github.com/goola-team/goola/core/types"
+)
+
+// maxFinalityDepth is the maximum number of ancestors walked looking for the
+// justified block of a header.
+const maxFinalityDepth = 1024
+
+// Justified returns the highest ancestor of a header that more than two thirds
+// of the validators of its epoch built upon, counting the distinct producers of
+// its descendants up to the header. The genesis block is always justified. On
+// unscheduled chains no block is.
+func (ethash *dops) Justified(chain consensus.ChainReader, header *types.Header) (*types.Header, error) {
+	if config := chain.Config().Ethash; config == nil || !config.Scheduled() {
+		return nil, nil
+	}
+	confirmed := make(map[common.Address]bool)
+	for depth := 0; depth < maxFinalityDepth; depth++ {
+		number := header.Number.Uint64()
+		if number == 0 {
+			return header, nil
+		}
+		parent := chain.GetHeader(header.ParentHash, number-1)
+		if parent == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		config, err := ethash.epochConfig(chain, parent, nil)
+		if err != nil {
+			return nil, err
+		}
+		confirmations := 0
+		for _, validator := range config.Validators {
+			if confirmed[validator] {
+				confirmations++
+			}
+		}
+		if confirmations >= finalityThreshold(len(config.Validators)) {
+			return header, nil
+		}
+		signer, err := ethash.signer(header)
+		if err != nil {
+			return nil, err
+		}
+		confirmed[signer] = true
+		header = parent
+	}
+	return nil, nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"testing"
+
+	"github.com/goola-team/goola/params"
+)
+
+// Tests that blocks are justified once more than two thirds of the validators
+// produced on top of them, walking back from the given header.
+func TestJustified(t *testing.T) {
+	chain := newEpochChain(9)
+	engine := NewFaker()
+
+	// With two validators, both must have produced a descendant
+	for i, want := range map[int]int{8: 6, 6: 4, 2: 0, 1: 0, 0: 0} {
+		justified, err := engine.Justified(chain, chain.headers[i])
+		if err != nil {
+			t.Fatalf("block %d: failed to justify: %v", i, err)
+		}
+		if justified == nil || justified.Hash() != chain.headers[want].Hash() {
+			t.Errorf("block %d: justified mismatch: have %v, want %d", i, justified, want)
+		}
+	}
+	// Unscheduled chains provide no finality
+	chain.config = &params.ChainConfig{Ethash: new(params.EthashConfig)}
+	if justified, err := engine.Justified(chain, chain.headers[8]); justified != nil || err != nil {
+		t.Errorf("unscheduled chain justified: have %v/%v, want nil", justified, err)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/params"
+)
+
+// Names of the liveness counters kept in the registry's storage.
+const (
+	producedCounter    = "dpos.produced"    // Blocks produced, per producer
+	missedCounter      = "dpos.missed"      // Slots missed, per validator
+	epochMissedCounter = "dpos.epochMissed" // Slots missed, per epoch and validator
+)
+
+// counter returns the value of a named registry counter with the given keys.
+func (r *registry) counter(name string, keys ...[]byte) uint64 {
+	return r.state.GetState(RegistryAddress, slot(name, keys...)).Big().Uint64()
+}
+
+// increment adds to a named registry counter with the given keys.
+func (r *registry) increment(name string, n uint64, keys ...[]byte) {
+	value := new(big.Int).SetUint64(r.counter(name, keys...) + n)
+	r.state.SetState(RegistryAddress, slot(name, keys...), common.BigToHash(value))
+}
+
+// epochOf returns the index of the epoch a block belongs to, checkpoints being
+// the last blocks of their epochs. Without epochs, all blocks belong to the first.
+func epochOf(config *params.EthashConfig, number uint64) uint64 {
+	if number == 0 || config.Epoch == 0 {
+		return 0
+	}
+	return (number - 1) / config.Epoch
+}
+
+// recordSlots accounts the slots elapsed since the parent of a block: the slots
+// left empty are missed by their scheduled validators, and so is the block's own
+// slot unless its scheduled validator produced it.
+func (r *registry) recordSlots(config *params.EthashConfig, header, parent *types.Header) {
+	r.keep()
+
+	var (
+		validators = uint64(len(config.Validators))
+		first      = parent.Time.Uint64()/config.Period + 1
+		slot       = header.Time.Uint64() / config.Period
+		epoch      = index(epochOf(config, header.Number.Uint64()))
+	)
+	miss := func(validator common.Address, n uint64) {
+		r.increment(missedCounter, n, validator[:])
+		r.increment(epochMissedCounter, n, epoch, validator[:])
+	}
+	if slot > first {
+		// Every validator misses its turns among the empty slots
+		empty := slot - first
+		for i := uint64(0); i < validators && i < empty; i++ {
+			turns := empty / validators
+			if i < empty%validators {
+				turns++
+			}
+			miss(config.Validators[(first+i)%validators], turns)
+		}
+	}
+	if scheduled := config.Validators[slot%validators]; scheduled != header.Coinbase {
+		miss(scheduled, 1)
+	}
+	r.increment(producedCounter, 1, header.Coinbase[:])
+}
+
+// epochMissed returns the number of slots a validator missed in an epoch.
+func (r *registry) epochMissed(epoch uint64, validator common.Address) uint64 {
+	return r.counter(epochMissedCounter, index(epoch), validator[:])
+}
+
+// suspended returns the validators of an epoch that missed too many of their
+// slots to be elected for the next one.
+func (r *registry) suspended(config *params.EthashConfig, epoch uint64, validators []common.Address) map[common.Address]bool {
+	suspended := make(map[common.Address]bool)
+	if config.MissLimit == 0 {
+		return suspended
+	}
+	for _, validator := range validators {
+		if r.epochMissed(epoch, validator) >= config.MissLimit {
+			suspended[validator] = true
+		}
+	}
+	return suspended
+}
+
+// withheld returns the percentage of the block reward withheld from a producer
+// that missed the given number of its slots in the epoch.
+func withheld(config *params.EthashConfig, missed uint64) uint64 {
+	if config.MissPenalty == 0 || missed == 0 {
+		return 0
+	}
+	if missed >= (100+config.MissPenalty-1)/config.MissPenalty {
+		return 100
+	}
+	return missed * config.MissPenalty
+}
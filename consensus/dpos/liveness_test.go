@@ -0,0 +1,85 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
+)
+
+// Tests that producers are accounted the slots they miss, and penalised with
+// withheld rewards and suspension from the next epoch.
+func TestMissedSlots(t *testing.T) {
+	var (
+		a, b, c = common.Address{0x0a}, common.Address{0x0b}, common.Address{0x0c}
+		standby = common.Address{0x05}
+		config  = &params.EthashConfig{Period: 10, Validators: []common.Address{a, b, c}, Epoch: 100, MissPenalty: 30, MissLimit: 2}
+	)
+	db, _ := gooladb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	reg := &registry{state: statedb}
+
+	// Slots 2 to 5 left empty, slot 6 taken over by a standby
+	parent := &types.Header{Number: big.NewInt(4), Time: big.NewInt(10)}
+	header := &types.Header{Number: big.NewInt(5), Time: big.NewInt(65), Coinbase: standby}
+	reg.recordSlots(config, header, parent)
+
+	for addr, want := range map[common.Address]uint64{a: 2, b: 1, c: 2, standby: 0} {
+		if missed := reg.epochMissed(0, addr); missed != want {
+			t.Errorf("%x: missed slots mismatch: have %d, want %d", addr, missed, want)
+		}
+	}
+	if produced := reg.counter(producedCounter, standby[:]); produced != 1 {
+		t.Errorf("produced blocks mismatch: have %d, want 1", produced)
+	}
+	// Missed slots of the next epoch are counted afresh
+	next := &types.Header{Number: big.NewInt(101), Time: big.NewInt(80), Coinbase: c}
+	reg.recordSlots(config, next, header)
+	if missed := reg.epochMissed(1, b); missed != 1 {
+		t.Errorf("next epoch missed slots mismatch: have %d, want 1", missed)
+	}
+	if missed := reg.counter(missedCounter, b[:]); missed != 2 {
+		t.Errorf("total missed slots mismatch: have %d, want 2", missed)
+	}
+	// Rewards are withheld in proportion, validators missing too much suspended
+	for missed, want := range map[uint64]uint64{0: 0, 1: 30, 3: 90, 4: 100} {
+		if have := withheld(config, missed); have != want {
+			t.Errorf("withheld reward for %d missed slots mismatch: have %d%%, want %d%%", missed, have, want)
+		}
+	}
+	suspended := reg.suspended(config, 0, config.Validators)
+	if want := map[common.Address]bool{a: true, c: true}; !reflect.DeepEqual(suspended, want) {
+		t.Errorf("suspended validators mismatch: have %v, want %v", suspended, want)
+	}
+	if elected := reg.elect(config, config.Validators, suspended); !reflect.DeepEqual(elected, []common.Address{b}) {
+		t.Errorf("elected validators mismatch: have %x, want [%x]", elected, b)
+	}
+	if elected := reg.elect(config, config.Validators, map[common.Address]bool{a: true, b: true, c: true}); !reflect.DeepEqual(elected, config.Validators) {
+		t.Errorf("validators all suspended mismatch: have %x, want %x", elected, config.Validators)
+	}
+	accumulateRewards(&params.ChainConfig{Ethash: config}, statedb, header, 30)
+	if have, want := statedb.GetBalance(standby), new(big.Int).Div(new(big.Int).Mul(FrontierBlockReward, big.NewInt(70)), big100); have.Cmp(want) != 0 {
+		t.Errorf("penalised reward mismatch: have %v, want %v", have, want)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"errors"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/params"
+)
+
+var (
+	// errUnauthorizedProducer is returned if a header is produced by an address
+	// that is neither the scheduled validator nor a standby of its slot.
+	errUnauthorizedProducer = errors.New("unauthorized producer")
+
+	// errEarlyStandby is returned if a standby produced a header before its turn
+	// to take over the slot came.
+	errEarlyStandby = errors.New("standby produced before its turn")
+
+	// errSlotTaken is returned if a header is in the same slot as its parent.
+	errSlotTaken = errors.New("slot already produced")
+)
+
+// producerDelay returns the number of seconds into a slot after which the given
+// address may produce its block: zero for the scheduled validator, and for the
+// standbys increasing multiples of the standby delay, in an order rotating with
+// the slots. False is returned if the address may not produce in the slot.
+func producerDelay(config *params.EthashConfig, slot uint64, producer common.Address) (uint64, bool) {
+	if config.Validators[slot%uint64(len(config.Validators))] == producer {
+		return 0, true
+	}
+	if config.StandbyDelay == 0 {
+		return 0, false
+	}
+	standbys := uint64(len(config.Standbys))
+	for rank := uint64(0); rank < standbys; rank++ {
+		delay := (rank + 1) * config.StandbyDelay
+		if delay >= config.Period {
+			break
+		}
+		if config.Standbys[(slot+rank)%standbys] == producer {
+			return delay, true
+		}
+	}
+	return 0, false
+}
+
+// nextProductionTime returns the earliest timestamp not before now at which the
+// producer may produce a child of a block with the given timestamp. For standbys
+// this is the time their turn comes in case the scheduled validator misses its
+// slot. False is returned if the producer is not scheduled at all.
+func nextProductionTime(config *params.EthashConfig, parentTime, now uint64, producer common.Address) (uint64, bool) {
+	if now <= parentTime {
+		now = parentTime + 1
+	}
+	slot := now / config.Period
+	if parent := parentTime / config.Period; slot <= parent {
+		slot = parent + 1
+	}
+	// Every producer gets a turn within a full rotation of both lists
+	rotation := uint64(len(config.Validators) * (len(config.Standbys) + 1))
+	for end := slot + rotation; slot < end; slot++ {
+		delay, ok := producerDelay(config, slot, producer)
+		if !ok {
+			continue
+		}
+		time := slot*config.Period + delay
+		if time < now {
+			time = now
+		}
+		if time < (slot+1)*config.Period {
+			return time, true
+		}
+	}
+	return 0, false
+}
+
+// verifyProducer checks that a header was produced in its own slot, either by
+// the scheduled validator or by a standby whose turn has come.
+func verifyProducer(config *params.EthashConfig, header, parent *types.Header, producer common.Address) error {
+	if header.Time.Uint64()/config.Period <= parent.Time.Uint64()/config.Period {
+		return errSlotTaken
+	}
+	return verifySlot(config, header, producer)
+}
+
+// verifySlot checks that the producer of a header is the scheduled validator of
+// the slot its timestamp falls into, or a standby whose turn has come.
+func verifySlot(config *params.EthashConfig, header *types.Header, producer common.Address) error {
+	time := header.Time.Uint64()
+
+	delay, ok := producerDelay(config, time/config.Period, producer)
+	if !ok {
+		return errUnauthorizedProducer
+	}
+	if time%config.Period < delay {
+		return errEarlyStandby
+	}
+	return nil
+}
+
+// CalcDifficulty is the difficulty adjustment algorithm of dpos. It returns the
+// difficulty of a block produced by the given account at the given time: the
+// in-turn weight for the scheduled validator of the slot, the standby weight for
+// anyone else or without a production schedule. Chains are scored by the sum of
+// their blocks' difficulties, so a chain of missed slots loses against one
+// produced as scheduled.
+func CalcDifficulty(config *params.EthashConfig, time uint64, producer common.Address) uint64 {
+	inTurn, standby := config.Weights()
+	if config == nil || !config.Scheduled() {
+		return standby
+	}
+	if delay, ok := producerDelay(config, time/config.Period, producer); ok && delay == 0 {
+		return inTurn
+	}
+	return standby
+}
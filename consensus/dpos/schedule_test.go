@@ -0,0 +1,133 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/params"
+)
+
+var (
+	validatorA = common.HexToAddress("0xa")
+	validatorB = common.HexToAddress("0xb")
+	standbyX   = common.HexToAddress("0x1")
+	standbyY   = common.HexToAddress("0x2")
+	outsider   = common.HexToAddress("0xf")
+
+	testSchedule = &params.EthashConfig{
+		Period:       10,
+		Validators:   []common.Address{validatorA, validatorB},
+		Standbys:     []common.Address{standbyX, standbyY},
+		StandbyDelay: 3,
+	}
+)
+
+// Tests that headers are only accepted from the scheduled validator of their slot
+// or, delayed according to their rank, from its standbys.
+func TestVerifyProducer(t *testing.T) {
+	parent := &types.Header{Time: big.NewInt(15)} // slot 1
+	tests := []struct {
+		time     uint64
+		producer common.Address
+		err      error
+	}{
+		{20, validatorA, nil},                     // slot 2 belongs to A
+		{29, validatorA, nil},                     // anywhere within its slot
+		{20, validatorB, errUnauthorizedProducer}, // not B's slot
+		{19, validatorB, errSlotTaken},            // slot 1 already produced
+		{22, standbyX, errEarlyStandby},           // first standby of slot 2 after 3s
+		{23, standbyX, nil},
+		{25, standbyY, errEarlyStandby}, // second standby of slot 2 after 6s
+		{26, standbyY, nil},
+		{33, standbyY, nil}, // standbys rotate: Y first in slot 3
+		{33, standbyX, errEarlyStandby},
+		{36, standbyX, nil},
+		{20, outsider, errUnauthorizedProducer},
+	}
+	for i, tt := range tests {
+		header := &types.Header{Time: new(big.Int).SetUint64(tt.time), Coinbase: tt.producer}
+		if err := verifyProducer(testSchedule, header, parent, tt.producer); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that producers are given the earliest time they may produce at, which
+// for standbys is the time they take over a missed slot.
+func TestNextProductionTime(t *testing.T) {
+	tests := []struct {
+		parent, now uint64
+		producer    common.Address
+		time        uint64
+		ok          bool
+	}{
+		{15, 16, validatorA, 20, true}, // A's next slot starts at 20
+		{15, 24, validatorA, 24, true}, // already within A's slot
+		{15, 16, validatorB, 30, true}, // B skips slot 2
+		{15, 16, standbyX, 23, true},   // X takes over slot 2 if A misses it
+		{15, 24, standbyX, 24, true},
+		{15, 16, standbyY, 26, true},
+		{25, 26, validatorA, 40, true}, // slot 2 is taken, next A slot is 4
+		{15, 16, outsider, 0, false},
+	}
+	for i, tt := range tests {
+		time, ok := nextProductionTime(testSchedule, tt.parent, tt.now, tt.producer)
+		if time != tt.time || ok != tt.ok {
+			t.Errorf("test %d: production time mismatch: have %d/%v, want %d/%v", i, time, ok, tt.time, tt.ok)
+		}
+		if !ok {
+			continue
+		}
+		parent := &types.Header{Time: new(big.Int).SetUint64(tt.parent)}
+		header := &types.Header{Time: new(big.Int).SetUint64(time), Coinbase: tt.producer}
+		if err := verifyProducer(testSchedule, header, parent, tt.producer); err != nil {
+			t.Errorf("test %d: production time rejected: %v", i, err)
+		}
+	}
+}
+
+// Tests that blocks produced in turn outweigh those of standbys, using the
+// configured weights if any.
+func TestCalcDifficulty(t *testing.T) {
+	weighted := *testSchedule
+	weighted.InTurnWeight, weighted.StandbyWeight = 10, 3
+
+	tests := []struct {
+		config     *params.EthashConfig
+		time       uint64
+		producer   common.Address
+		difficulty uint64
+	}{
+		{testSchedule, 20, validatorA, 2},
+		{testSchedule, 30, validatorB, 2},
+		{testSchedule, 23, standbyX, 1},
+		{testSchedule, 26, standbyY, 1},
+		{&weighted, 20, validatorA, 10},
+		{&weighted, 23, standbyX, 3},
+		{new(params.EthashConfig), 20, outsider, 1},
+		{nil, 20, outsider, 1},
+	}
+	for i, tt := range tests {
+		if difficulty := CalcDifficulty(tt.config, tt.time, tt.producer); difficulty != tt.difficulty {
+			t.Errorf("test %d: difficulty mismatch: have %d, want %d", i, difficulty, tt.difficulty)
+		}
+	}
+}
@@ -17,20 +17,134 @@
 package dpos
 
 import (
+	"errors"
+	"time"
 
-
+	"github.com/goola-team/goola/accounts"
+	"github.com/goola-team/goola/common"
 	"github.com/goola-team/goola/consensus"
 	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/crypto/sha3"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rlp"
+)
+
+var (
+	// errMissingSignature is returned if a header of a scheduled chain doesn't
+	// have enough extra-data to hold its seal.
+	errMissingSignature = errors.New("extra-data 65 byte seal missing")
+
+	// errInvalidSigner is returned if a header's seal was signed by an account
+	// other than its coinbase.
+	errInvalidSigner = errors.New("seal not signed by the coinbase")
+
+	// errUnauthorizedSealer is returned when sealing a block of a scheduled chain
+	// without a signing key for its coinbase.
+	errUnauthorizedSealer = errors.New("unauthorized sealer")
 )
 
+// SignerFn is a signer callback function to request a hash to be signed by a
+// backing account.
+type SignerFn func(accounts.Account, []byte) ([]byte, error)
+
+// Authorize injects a private key into the consensus engine to seal new blocks
+// with.
+func (ethash *dops) Authorize(signer common.Address, signFn SignerFn) {
+	ethash.lock.Lock()
+	defer ethash.lock.Unlock()
+
+	ethash.sealer = signer
+	ethash.signFn = signFn
+}
 
+// sealHash returns the hash of a header prior to it being sealed, the extra-data
+// stripped of the seal.
+func sealHash(header *types.Header) (hash common.Hash) {
+	hasher := sha3.NewKeccak256()
+
+	rlp.Encode(hasher, []interface{}{
+		header.ParentHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra[:len(header.Extra)-extraSeal],
+		header.Nonce,
+	})
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// ecrecover extracts the account that signed the seal of a header.
+func ecrecover(header *types.Header) (common.Address, error) {
+	if len(header.Extra) < extraSeal {
+		return common.Address{}, errMissingSignature
+	}
+	signature := header.Extra[len(header.Extra)-extraSeal:]
+
+	pubkey, err := crypto.Ecrecover(sealHash(header).Bytes(), signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var signer common.Address
+	copy(signer[:], crypto.Keccak256(pubkey[1:])[12:])
+	return signer, nil
+}
+
+// Seal implements consensus.Engine, waiting for the producer's slot on scheduled
+// chains and signing the block with the authorized key.
 func (ethash *dops) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
 	var (
 		header  = block.Header()
 	)
-	var result *types.Block
+	// If production is scheduled, make sure it's our turn and wait for our slot.
+	// Standbys get aborted by the new chain head if the validator produces it.
+	if config := chain.Config().Ethash; config != nil && config.Scheduled() {
+		parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+		if parent == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		epoch, err := ethash.epochConfig(chain, parent, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := verifyProducer(epoch, header, parent, header.Coinbase); err != nil {
+			return nil, err
+		}
+		delay := time.Unix(header.Time.Int64(), 0).Sub(ethash.now())
+		if delay > 0 {
+			log.Trace("Waiting for production slot", "number", header.Number, "producer", header.Coinbase, "delay", delay)
+		}
+		select {
+		case <-stop:
+			return nil, nil
+		case <-ethash.after(delay):
+		}
+	}
 	header = types.CopyHeader(header)
-	result = block.WithSeal(header);
-	return result, nil
+	if config := chain.Config().Ethash; config != nil && config.Scheduled() && ethash.mode == ModeNormal {
+		ethash.lock.Lock()
+		sealer, signFn := ethash.sealer, ethash.signFn
+		ethash.lock.Unlock()
+
+		if signFn == nil || sealer != header.Coinbase {
+			return nil, errUnauthorizedSealer
+		}
+		if len(header.Extra) < extraSeal {
+			return nil, errMissingSignature
+		}
+		sighash, err := signFn(accounts.Account{Address: sealer}, sealHash(header).Bytes())
+		if err != nil {
+			return nil, err
+		}
+		copy(header.Extra[len(header.Extra)-extraSeal:], sighash)
+	}
+	return block.WithSeal(header), nil
 }
 
@@ -0,0 +1,141 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/consensus"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/hashicorp/golang-lru"
+)
+
+// scheduleCacheLimit is the number of headers to keep the snapshot of the epoch
+// of their children cached for.
+const scheduleCacheLimit = 4096
+
+// errUnknownBlock is returned when the snapshot of a block is requested that is
+// not part of the local blockchain.
+var errUnknownBlock = errors.New("unknown block")
+
+// Snapshot is the delegate set producing the blocks of an epoch, as committed to
+// by the checkpoint ending the previous epoch. Snapshots are persisted, so that
+// the schedule survives restarts without looking the checkpoints up again.
+type Snapshot struct {
+	Number     uint64           `json:"number"`     // Number of the checkpoint the epoch starts after
+	Hash       common.Hash      `json:"hash"`       // Hash of the checkpoint the epoch starts after
+	Validators []common.Address `json:"validators"` // Validators producing the slots of the epoch
+}
+
+// loadSnapshot loads the snapshot of the epoch following a checkpoint from the
+// database.
+func loadSnapshot(db gooladb.Database, hash common.Hash) (*Snapshot, error) {
+	blob, err := db.Get(append([]byte("dpos-"), hash[:]...))
+	if err != nil {
+		return nil, err
+	}
+	snap := new(Snapshot)
+	if err := json.Unmarshal(blob, snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// store inserts the snapshot into the database.
+func (s *Snapshot) store(db gooladb.Database) error {
+	blob, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return db.Put(append([]byte("dpos-"), s.Hash[:]...), blob)
+}
+
+// checkpointSnapshot returns the snapshot of the epoch following a checkpoint,
+// loading it from the database if persisted, or persisting it otherwise.
+func (ethash *dops) checkpointSnapshot(header *types.Header) (*Snapshot, error) {
+	hash := header.Hash()
+	if ethash.db != nil {
+		if snap, err := loadSnapshot(ethash.db, hash); err == nil {
+			return snap, nil
+		}
+	}
+	validators, err := CheckpointValidators(header)
+	if err != nil {
+		return nil, err
+	}
+	snap := &Snapshot{Number: header.Number.Uint64(), Hash: hash, Validators: validators}
+	if ethash.db != nil {
+		if err := snap.store(ethash.db); err != nil {
+			return nil, err
+		}
+	}
+	return snap, nil
+}
+
+// snapshot returns the snapshot of the epoch of the children of a header. It's
+// looked up among the recently used ones, or walking back to the checkpoint the
+// epoch starts after. Ancestors not yet in the chain may be given, oldest first.
+func (ethash *dops) snapshot(chain consensus.ChainReader, parent *types.Header, parents []*types.Header) (*Snapshot, error) {
+	config := chain.Config().Ethash
+	if config == nil {
+		return new(Snapshot), nil
+	}
+	if !config.Scheduled() || config.Epoch == 0 || parent.Number.Uint64() < config.Epoch {
+		return &Snapshot{Validators: config.Validators}, nil
+	}
+	ethash.schedulesOnce.Do(func() {
+		ethash.schedules, _ = lru.New(scheduleCacheLimit)
+	})
+	var (
+		header = parent
+		walked []common.Hash
+		snap   *Snapshot
+	)
+	for {
+		if cached, ok := ethash.schedules.Get(header.Hash()); ok {
+			snap = cached.(*Snapshot)
+			break
+		}
+		walked = append(walked, header.Hash())
+		if IsCheckpoint(config, header.Number.Uint64()) {
+			var err error
+			if snap, err = ethash.checkpointSnapshot(header); err != nil {
+				return nil, err
+			}
+			break
+		}
+		number, hash := header.Number.Uint64()-1, header.ParentHash
+		if header = chain.GetHeader(hash, number); header == nil {
+			for i := len(parents) - 1; i >= 0; i-- {
+				if parents[i].Hash() == hash {
+					header = parents[i]
+					break
+				}
+			}
+			if header == nil {
+				return nil, consensus.ErrUnknownAncestor
+			}
+		}
+	}
+	for _, hash := range walked {
+		ethash.schedules.Add(hash, snap)
+	}
+	return snap, nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/rpc"
+)
+
+// Tests that epoch snapshots are persisted at their checkpoints, and served by
+// the API for the blocks of their epoch.
+func TestSnapshotPersistence(t *testing.T) {
+	chain := newEpochChain(9)
+	chain.headers[4].Extra = checkpointExtra(nil, []common.Address{outsider})
+	for i := 5; i < len(chain.headers); i++ {
+		chain.headers[i].ParentHash = chain.headers[i-1].Hash()
+	}
+	db, _ := gooladb.NewMemDatabase()
+	if _, err := New(Config{}, db).epochConfig(chain, chain.headers[6], nil); err != nil {
+		t.Fatalf("failed to get schedule: %v", err)
+	}
+	snap, err := loadSnapshot(db, chain.headers[4].Hash())
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	if snap.Number != 4 || !reflect.DeepEqual(snap.Validators, []common.Address{outsider}) {
+		t.Errorf("persisted snapshot mismatch: %+v", snap)
+	}
+	api := &API{chain: chain, dpos: New(Config{}, db)}
+	for number, want := range map[rpc.BlockNumber][]common.Address{
+		0: chain.config.Ethash.Validators,
+		4: chain.config.Ethash.Validators,
+		5: {outsider},
+		8: {outsider},
+	} {
+		number := number
+		snap, err := api.GetSnapshot(&number)
+		if err != nil {
+			t.Fatalf("block %d: failed to get snapshot: %v", number, err)
+		}
+		if !reflect.DeepEqual(snap.Validators, want) {
+			t.Errorf("block %d: validators mismatch: have %x, want %x", number, snap.Validators, want)
+		}
+	}
+	if _, err := api.GetSnapshotAtHash(common.Hash{}); err != errUnknownBlock {
+		t.Errorf("unknown block error mismatch: have %v, want %v", err, errUnknownBlock)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/metrics"
+	"github.com/hashicorp/golang-lru"
+)
+
+// signerCacheLimit is the number of recovered header signers to keep cached.
+const signerCacheLimit = 4096
+
+var (
+	signerCacheHitMeter  = metrics.NewMeter("consensus/signers/hit")
+	signerCacheMissMeter = metrics.NewMeter("consensus/signers/miss")
+)
+
+// SignerCache is an engine agnostic LRU cache of the accounts that signed block
+// headers, keyed by header hash. It spares the header verification workers and
+// the fork choice of an engine the repeated recovery of the same signatures.
+type SignerCache struct {
+	recover func(header *types.Header) (common.Address, error)
+	cache   *lru.Cache
+}
+
+// NewSignerCache creates a signer cache recovering the signers of the headers
+// missing from it with the given engine specific function.
+func NewSignerCache(recover func(header *types.Header) (common.Address, error)) *SignerCache {
+	cache, _ := lru.New(signerCacheLimit)
+	return &SignerCache{
+		recover: recover,
+		cache:   cache,
+	}
+}
+
+// Signer returns the account that signed a header, recovering and caching it if
+// it's not cached yet. Failed recoveries are not cached.
+func (c *SignerCache) Signer(header *types.Header) (common.Address, error) {
+	hash := header.Hash()
+	if signer, ok := c.cache.Get(hash); ok {
+		signerCacheHitMeter.Mark(1)
+		return signer.(common.Address), nil
+	}
+	signerCacheMissMeter.Mark(1)
+
+	signer, err := c.recover(header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	c.cache.Add(hash, signer)
+	return signer, nil
+}
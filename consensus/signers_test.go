@@ -0,0 +1,59 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package consensus
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+)
+
+// Tests that header signers are only recovered once, unless recovery fails.
+func TestSignerCache(t *testing.T) {
+	var (
+		recoveries int
+		errBadSig  = errors.New("bad signature")
+	)
+	cache := NewSignerCache(func(header *types.Header) (common.Address, error) {
+		recoveries++
+		if header.Number.Sign() == 0 {
+			return common.Address{}, errBadSig
+		}
+		return header.Coinbase, nil
+	})
+	header := &types.Header{Number: big.NewInt(1), Coinbase: common.Address{0x01}}
+	for i := 0; i < 3; i++ {
+		if signer, err := cache.Signer(header); err != nil || signer != header.Coinbase {
+			t.Fatalf("lookup %d: signer mismatch: have %x/%v, want %x", i, signer, err, header.Coinbase)
+		}
+	}
+	if recoveries != 1 {
+		t.Errorf("cached signer recovered %d times", recoveries)
+	}
+	invalid := &types.Header{Number: big.NewInt(0)}
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Signer(invalid); err != errBadSig {
+			t.Fatalf("lookup %d: error mismatch: have %v, want %v", i, err, errBadSig)
+		}
+	}
+	if recoveries != 3 {
+		t.Errorf("failed recovery cached: have %d recoveries, want 3", recoveries)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package simulation
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// VirtualClock is a manually advanced clock. Time only passes when the clock
+// is advanced, firing the pending timers in deadline order.
+type VirtualClock struct {
+	now    time.Time
+	timers []*virtualTimer
+	lock   sync.Mutex
+}
+
+// virtualTimer is a pending wait on the virtual clock.
+type virtualTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewVirtualClock creates a virtual clock starting at the given time.
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+// Now returns the current virtual time.
+func (c *VirtualClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+// After returns a channel that receives the virtual time once the clock was
+// advanced by at least the given duration. Non-positive durations fire at once.
+func (c *VirtualClock) After(d time.Duration) <-chan time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	timer := &virtualTimer{at: c.now.Add(d), ch: ch}
+	i := sort.Search(len(c.timers), func(i int) bool { return c.timers[i].at.After(timer.at) })
+	c.timers = append(c.timers, nil)
+	copy(c.timers[i+1:], c.timers[i:])
+	c.timers[i] = timer
+	return ch
+}
+
+// Advance moves the clock forward by the given duration, firing every timer
+// that expires in the meantime.
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].at.After(c.now) {
+		c.timers[0].ch <- c.timers[0].at
+		c.timers = c.timers[1:]
+	}
+}
+
+// Pending returns the number of timers waiting to fire.
+func (c *VirtualClock) Pending() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return len(c.timers)
+}
@@ -0,0 +1,60 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package simulation
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that virtual timers only fire when the clock is advanced past them, in
+// deadline order.
+func TestVirtualClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clock := NewVirtualClock(start)
+
+	late, early := clock.After(3*time.Second), clock.After(time.Second)
+	select {
+	case <-clock.After(0):
+	default:
+		t.Fatal("zero timer didn't fire immediately")
+	}
+	clock.Advance(2 * time.Second)
+	select {
+	case at := <-early:
+		if want := start.Add(time.Second); !at.Equal(want) {
+			t.Errorf("fire time mismatch: have %v, want %v", at, want)
+		}
+	default:
+		t.Fatal("expired timer didn't fire")
+	}
+	select {
+	case <-late:
+		t.Fatal("pending timer fired early")
+	default:
+	}
+	if pending := clock.Pending(); pending != 1 {
+		t.Errorf("pending timer count mismatch: have %d, want %d", pending, 1)
+	}
+	clock.Advance(time.Second)
+	if _, ok := <-late; !ok || clock.Pending() != 0 {
+		t.Error("timer didn't fire at its deadline")
+	}
+	if now := clock.Now(); !now.Equal(start.Add(3 * time.Second)) {
+		t.Errorf("time mismatch: have %v, want %v", now, start.Add(3*time.Second))
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package simulation runs multiple in-process consensus engines over an in-memory
+// network on a virtual clock, allowing slot timing, failover and fork choice to
+// be tested deterministically.
+package simulation
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/consensus"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/params"
+)
+
+// Config is the setup of a simulated network.
+type Config struct {
+	Chain     *params.ChainConfig // Chain configuration shared by all nodes
+	Producers []common.Address    // Block producer address of each node
+	Start     time.Time           // Virtual time of the genesis block
+
+	// Engine creates the consensus engine of a node. The engine must take its
+	// time from the given clock, otherwise the simulation is not deterministic.
+	Engine func(clock *VirtualClock) consensus.Engine
+}
+
+// Network is a set of simulated nodes connected by in-memory links. Blocks are
+// relayed synchronously, so the state of the network is fully determined by the
+// sequence of steps taken.
+type Network struct {
+	clock *VirtualClock
+	nodes []*Node
+	links map[[2]int]bool
+}
+
+// Node is a single simulated block producer with its own chain and engine.
+type Node struct {
+	index   int
+	address common.Address
+	engine  consensus.Engine
+	chain   *core.BlockChain
+	online  bool
+	stop    chan struct{}
+}
+
+// NewNetwork creates a fully connected network with a node for every producer.
+func NewNetwork(config Config) (*Network, error) {
+	if config.Chain == nil || config.Engine == nil {
+		return nil, errors.New("simulation needs a chain config and an engine")
+	}
+	n := &Network{
+		clock: NewVirtualClock(config.Start),
+		links: make(map[[2]int]bool),
+	}
+	genesis := &core.Genesis{
+		Config:    config.Chain,
+		Timestamp: uint64(config.Start.Unix()),
+		GasLimit:  params.GenesisGasLimit,
+	}
+	for i, addr := range config.Producers {
+		db, _ := gooladb.NewMemDatabase()
+		genesis.MustCommit(db)
+
+		engine := config.Engine(n.clock)
+		chain, err := core.NewBlockChain(db, nil, config.Chain, engine, vm.Config{})
+		if err != nil {
+			n.Stop()
+			return nil, err
+		}
+		n.nodes = append(n.nodes, &Node{
+			index:   i,
+			address: addr,
+			engine:  engine,
+			chain:   chain,
+			online:  true,
+			stop:    make(chan struct{}),
+		})
+	}
+	n.Heal()
+	return n, nil
+}
+
+// Stop terminates all the nodes of the network.
+func (n *Network) Stop() {
+	for _, node := range n.nodes {
+		close(node.stop)
+		node.chain.Stop()
+	}
+}
+
+// Clock returns the virtual clock of the network.
+func (n *Network) Clock() *VirtualClock {
+	return n.clock
+}
+
+// Nodes returns the nodes of the network, in the order of their producers.
+func (n *Network) Nodes() []*Node {
+	return n.nodes
+}
+
+// Node returns the i-th node of the network.
+func (n *Network) Node(i int) *Node {
+	return n.nodes[i]
+}
+
+// Connect links two nodes and synchronises their chains.
+func (n *Network) Connect(a, b *Node) {
+	if a == b || n.connected(a, b) {
+		return
+	}
+	n.links[linkKey(a, b)] = true
+	n.sync(a, b)
+}
+
+// Disconnect removes the link between two nodes.
+func (n *Network) Disconnect(a, b *Node) {
+	delete(n.links, linkKey(a, b))
+}
+
+// Partition splits the network into the given groups, removing every link
+// between nodes of different groups. Nodes not in any group are isolated.
+func (n *Network) Partition(groups ...[]*Node) {
+	group := make(map[*Node]int)
+	for i, nodes := range groups {
+		for _, node := range nodes {
+			group[node] = i + 1
+		}
+	}
+	for _, a := range n.nodes {
+		for _, b := range n.nodes {
+			if a.index < b.index && (group[a] == 0 || group[a] != group[b]) {
+				n.Disconnect(a, b)
+			}
+		}
+	}
+}
+
+// Heal connects every pair of nodes, synchronising their chains.
+func (n *Network) Heal() {
+	for _, a := range n.nodes {
+		for _, b := range n.nodes {
+			if a.index < b.index {
+				n.Connect(a, b)
+			}
+		}
+	}
+}
+
+// SetOnline brings a node on or off line. Offline nodes neither produce nor
+// relay blocks, and catch up with their peers when coming back.
+func (n *Network) SetOnline(node *Node, online bool) {
+	if node.online == online {
+		return
+	}
+	node.online = online
+	if online {
+		for _, peer := range n.peers(node) {
+			n.sync(node, peer)
+		}
+	}
+}
+
+// Step advances the virtual clock by a second and lets every online node, in
+// order, produce a block if its engine allows it to at the new time. The blocks
+// produced are relayed before the next node gets its turn.
+func (n *Network) Step() []*types.Block {
+	n.clock.Advance(time.Second)
+
+	var blocks []*types.Block
+	for _, node := range n.nodes {
+		if !node.online {
+			continue
+		}
+		if block := node.produce(uint64(n.clock.Now().Unix())); block != nil {
+			blocks = append(blocks, block)
+			n.propagate(node, block)
+		}
+	}
+	return blocks
+}
+
+// Run steps the network until the given amount of virtual time passes, returning
+// all the blocks produced.
+func (n *Network) Run(d time.Duration) []*types.Block {
+	var blocks []*types.Block
+	for end := n.clock.Now().Add(d); n.clock.Now().Before(end); {
+		blocks = append(blocks, n.Step()...)
+	}
+	return blocks
+}
+
+// connected reports whether two nodes are linked.
+func (n *Network) connected(a, b *Node) bool {
+	return n.links[linkKey(a, b)]
+}
+
+// peers returns the nodes linked to the given one, in order.
+func (n *Network) peers(node *Node) []*Node {
+	var peers []*Node
+	for _, peer := range n.nodes {
+		if peer != node && n.connected(node, peer) {
+			peers = append(peers, peer)
+		}
+	}
+	return peers
+}
+
+// propagate relays a block from the node that has it to every node reachable
+// through online peers.
+func (n *Network) propagate(origin *Node, block *types.Block) {
+	queue := []*Node{origin}
+	for len(queue) > 0 {
+		src := queue[0]
+		queue = queue[1:]
+
+		for _, dst := range n.peers(src) {
+			if dst.receive(src, block) {
+				queue = append(queue, dst)
+			}
+		}
+	}
+}
+
+// sync exchanges the chain heads of two linked nodes, relaying them further if
+// they were new to the receiver.
+func (n *Network) sync(a, b *Node) {
+	headA, headB := a.Head(), b.Head()
+	if b.receive(a, headA) {
+		n.propagate(b, headA)
+	}
+	if a.receive(b, headB) {
+		n.propagate(a, headB)
+	}
+}
+
+// linkKey returns the identifier of the link between two nodes.
+func linkKey(a, b *Node) [2]int {
+	if a.index > b.index {
+		a, b = b, a
+	}
+	return [2]int{a.index, b.index}
+}
+
+// Address returns the block producer address of the node.
+func (node *Node) Address() common.Address {
+	return node.address
+}
+
+// Chain returns the local chain of the node.
+func (node *Node) Chain() *core.BlockChain {
+	return node.chain
+}
+
+// Head returns the current head block of the node.
+func (node *Node) Head() *types.Block {
+	return node.chain.CurrentBlock()
+}
+
+// Online reports whether the node is participating in the network.
+func (node *Node) Online() bool {
+	return node.online
+}
+
+// produce creates, seals and imports a block on top of the node's head, if the
+// engine allows the node to produce at the given time.
+func (node *Node) produce(now uint64) *types.Block {
+	parent := node.chain.CurrentBlock()
+	if now <= parent.Time().Uint64() {
+		return nil
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   parent.GasLimit(),
+		Time:       new(big.Int).SetUint64(now),
+		Coinbase:   node.address,
+	}
+	// Engines move the timestamp to the producer's turn if it's not now
+	if err := node.engine.Prepare(node.chain, header); err != nil || header.Time.Uint64() != now {
+		return nil
+	}
+	statedb, err := node.chain.StateAt(parent.Root())
+	if err != nil {
+		log.Error("Simulated node missing state", "node", node.index, "root", parent.Root(), "err", err)
+		return nil
+	}
+	block, err := node.engine.Finalize(node.chain, header, statedb, nil, nil)
+	if err != nil {
+		return nil
+	}
+	if block, err = node.engine.Seal(node.chain, block, node.stop); block == nil || err != nil {
+		return nil
+	}
+	if _, err := node.chain.InsertChain(types.Blocks{block}); err != nil {
+		log.Error("Simulated node rejected own block", "node", node.index, "number", block.Number(), "err", err)
+		return nil
+	}
+	return block
+}
+
+// receive imports a block relayed by a peer, together with any ancestors the
+// node is missing. It reports whether the block was newly imported.
+func (node *Node) receive(from *Node, block *types.Block) bool {
+	if !node.online || node.chain.HasBlock(block.Hash(), block.NumberU64()) {
+		return false
+	}
+	blocks := types.Blocks{block}
+	for parent := block; parent.NumberU64() > 0 && !node.chain.HasBlock(parent.ParentHash(), parent.NumberU64()-1); {
+		if parent = from.chain.GetBlock(parent.ParentHash(), parent.NumberU64()-1); parent == nil {
+			return false
+		}
+		blocks = append(types.Blocks{parent}, blocks...)
+	}
+	if _, err := node.chain.InsertChain(blocks); err != nil {
+		log.Debug("Simulated node rejected block", "node", node.index, "number", block.Number(), "hash", block.Hash(), "err", err)
+		return false
+	}
+	return true
+}
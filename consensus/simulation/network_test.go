@@ -0,0 +1,169 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package simulation
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/consensus"
+	"github.com/goola-team/goola/consensus/dpos"
+	"github.com/goola-team/goola/params"
+)
+
+var (
+	validatorA = common.HexToAddress("0xa")
+	validatorB = common.HexToAddress("0xb")
+	standby    = common.HexToAddress("0x1")
+
+	// genesisTime is the start of a slot, so the first slots are predictable
+	genesisTime = time.Unix(1500000000, 0)
+)
+
+// newTestNetwork creates a network of two validators and a standby taking over
+// two seconds into the five second slots.
+func newTestNetwork(t *testing.T) *Network {
+	config := &params.ChainConfig{
+		ChainId:        big.NewInt(1),
+		ByzantiumBlock: big.NewInt(0),
+		Ethash: &params.EthashConfig{
+			Period:       5,
+			Validators:   []common.Address{validatorA, validatorB},
+			Standbys:     []common.Address{standby},
+			StandbyDelay: 2,
+		},
+	}
+	network, err := NewNetwork(Config{
+		Chain:     config,
+		Producers: []common.Address{validatorA, validatorB, standby},
+		Start:     genesisTime,
+		Engine: func(clock *VirtualClock) consensus.Engine {
+			engine := dpos.NewFaker()
+			engine.SetClock(clock)
+			return engine
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create network: %v", err)
+	}
+	return network
+}
+
+// Tests that validators produce their slots in turn and at the slot start.
+func TestSlotTiming(t *testing.T) {
+	network := newTestNetwork(t)
+	defer network.Stop()
+
+	blocks := network.Run(30 * time.Second)
+	if len(blocks) != 6 {
+		t.Fatalf("produced block count mismatch: have %d, want %d", len(blocks), 6)
+	}
+	for i, block := range blocks {
+		slot := uint64(genesisTime.Unix())/5 + uint64(i) + 1
+		if want := []common.Address{validatorA, validatorB}[slot%2]; block.Coinbase() != want {
+			t.Errorf("block %d: producer mismatch: have %x, want %x", i, block.Coinbase(), want)
+		}
+		if want := slot * 5; block.Time().Uint64() != want {
+			t.Errorf("block %d: timestamp mismatch: have %d, want %d", i, block.Time(), want)
+		}
+	}
+	for i, node := range network.Nodes() {
+		if head := node.Head().Hash(); head != blocks[len(blocks)-1].Hash() {
+			t.Errorf("node %d: head mismatch: have %x, want %x", i, head, blocks[len(blocks)-1].Hash())
+		}
+	}
+}
+
+// Tests that the standby takes over the slots of an offline validator, delayed
+// by the standby delay, and that the validator catches up when coming back.
+func TestMissedSlotFailover(t *testing.T) {
+	network := newTestNetwork(t)
+	defer network.Stop()
+
+	network.SetOnline(network.Node(0), false)
+	blocks := network.Run(22 * time.Second)
+	if len(blocks) != 4 {
+		t.Fatalf("produced block count mismatch: have %d, want %d", len(blocks), 4)
+	}
+	for i, block := range blocks {
+		slot := block.Time().Uint64() / 5
+		switch slot % 2 {
+		case 0:
+			if block.Coinbase() != standby || block.Time().Uint64() != slot*5+2 {
+				t.Errorf("block %d: missed slot not taken over: producer %x, time %d", i, block.Coinbase(), block.Time())
+			}
+		case 1:
+			if block.Coinbase() != validatorB || block.Time().Uint64() != slot*5 {
+				t.Errorf("block %d: slot not produced by validator: producer %x, time %d", i, block.Coinbase(), block.Time())
+			}
+		}
+	}
+	network.SetOnline(network.Node(0), true)
+	if head := network.Node(0).Head().Hash(); head != blocks[len(blocks)-1].Hash() {
+		t.Errorf("returning validator not synced: have %x, want %x", head, blocks[len(blocks)-1].Hash())
+	}
+	if blocks := network.Run(10 * time.Second); len(blocks) != 2 || blocks[0].Coinbase() == standby || blocks[1].Coinbase() == standby {
+		t.Errorf("validator didn't resume production: %d blocks", len(blocks))
+	}
+}
+
+// Tests that both sides of a partition keep producing and that healing the
+// network makes every node converge on the heavier branch, deterministically.
+func TestPartitionedFork(t *testing.T) {
+	var heads []common.Hash
+	for run := 0; run < 2; run++ {
+		network := newTestNetwork(t)
+
+		network.Partition([]*Node{network.Node(0)}, []*Node{network.Node(1), network.Node(2)})
+		blocks := network.Run(20 * time.Second)
+
+		var branchA, branchB []common.Hash
+		for _, block := range blocks {
+			if block.Coinbase() == validatorA {
+				branchA = append(branchA, block.Hash())
+			} else {
+				branchB = append(branchB, block.Hash())
+			}
+		}
+		if len(branchA) == 0 || len(branchB) == 0 {
+			t.Fatalf("run %d: partition didn't fork: %d and %d blocks", run, len(branchA), len(branchB))
+		}
+		if network.Node(0).Chain().GetBlockByHash(branchB[0]) != nil {
+			t.Fatalf("run %d: block crossed the partition", run)
+		}
+		network.Heal()
+		for i, node := range network.Nodes() {
+			for _, hash := range append(branchA, branchB...) {
+				if node.Chain().GetBlockByHash(hash) == nil {
+					t.Errorf("run %d: node %d: missing block %x after heal", run, i, hash)
+				}
+			}
+			if head := node.Head().Hash(); head != branchB[len(branchB)-1] {
+				t.Errorf("run %d: node %d: head mismatch: have %x, want %x", run, i, head, branchB[len(branchB)-1])
+			}
+			heads = append(heads, node.Head().Hash())
+		}
+		network.Stop()
+	}
+	for i := 0; i < len(heads)/2; i++ {
+		if heads[i] != heads[len(heads)/2+i] {
+			t.Errorf("node %d: head differs between runs: %x != %x", i, heads[i], heads[len(heads)/2+i])
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rlp"
+)
+
+// BalanceRecord is the balance of a watched account at a block, as recorded by
+// the balance history index. The hash of the block is kept to tell records of
+// blocks reorganised out of the canonical chain apart.
+type BalanceRecord struct {
+	Hash    common.Hash
+	Balance *big.Int
+}
+
+// balanceHistKey = balanceHistPrefix + address + num (uint64 big endian)
+func balanceHistKey(addr common.Address, number uint64) []byte {
+	return append(append(append([]byte{}, balanceHistPrefix...), addr.Bytes()...), encodeBlockNumber(number)...)
+}
+
+// GetBalanceRecord retrieves the indexed balance of an account at the block with
+// the given number.
+func GetBalanceRecord(db DatabaseReader, addr common.Address, number uint64) *BalanceRecord {
+	data, _ := db.Get(balanceHistKey(addr, number))
+	if len(data) == 0 {
+		return nil
+	}
+	record := new(BalanceRecord)
+	if err := rlp.DecodeBytes(data, record); err != nil {
+		log.Error("Invalid balance record RLP", "address", addr, "number", number, "err", err)
+		return nil
+	}
+	return record
+}
+
+// WriteBalanceRecord stores the indexed balance of an account at the block with
+// the given number, replacing any record of a block reorganised out.
+func WriteBalanceRecord(db gooladb.Putter, addr common.Address, number uint64, record *BalanceRecord) error {
+	data, err := rlp.EncodeToBytes(record)
+	if err != nil {
+		return err
+	}
+	return db.Put(balanceHistKey(addr, number), data)
+}
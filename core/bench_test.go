@@ -80,7 +80,7 @@ func genValueTx(nbytes int) func(int, *BlockGen) {
 	return func(i int, gen *BlockGen) {
 		toaddr := common.Address{}
 		data := make([]byte, nbytes)
-		gas, _ := IntrinsicGas(data, false)
+		gas, _ := IntrinsicGas(data, false, params.DefaultDataRules)
 		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(benchRootAddr), toaddr, big.NewInt(1), gas, nil, types.TxTypeTransfer,data), types.EIP155Signer{}, benchRootKey)
 		gen.AddTx(tx)
 	}
@@ -0,0 +1,241 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/params"
+)
+
+// minBlobRetention is the minimum number of recent blocks whose blobs are
+// retained, keeping them available across shallow reorgs.
+const minBlobRetention = triesInMemory
+
+// CalcBlobFee returns the fee per blob of a block, given the number of blobs
+// carried by its parent. The fee doubles for every blob the parent carried above
+// the target, pricing blob space in a fee market of its own, separate from gas.
+func CalcBlobFee(parentBlobs int) *big.Int {
+	fee := new(big.Int).SetUint64(params.MinBlobFee)
+	if excess := parentBlobs - params.TargetBlobsPerBlock; excess > 0 {
+		fee.Lsh(fee, uint(excess))
+	}
+	return fee
+}
+
+// CountBlobs returns the number of blobs carried by the transactions.
+func CountBlobs(txs types.Transactions) int {
+	blobs := 0
+	for _, tx := range txs {
+		blobs += tx.BlobCount()
+	}
+	return blobs
+}
+
+// BlockReader is implemented by chains able to retrieve entire blocks.
+type BlockReader interface {
+	GetBlock(hash common.Hash, number uint64) *types.Block
+}
+
+// BlobFee returns the fee per blob of the block with the given header, derived
+// from the blobs carried by its parent. The body of the parent is required, the
+// fee is unknown without it.
+func BlobFee(chain BlockReader, header *types.Header) (*big.Int, error) {
+	if header.Number.Sign() == 0 {
+		return CalcBlobFee(0), nil
+	}
+	parent := chain.GetBlock(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return nil, ErrUnknownBlobFee
+	}
+	return CalcBlobFee(CountBlobs(parent.Transactions())), nil
+}
+
+// blockBlobFee returns the fee per blob of a block carrying blob transactions
+// once they're activated, or nil if there are none to pay it.
+func blockBlobFee(config *params.ChainConfig, chain BlockReader, block *types.Block) (*big.Int, error) {
+	if !config.IsBlob(block.Number()) {
+		return nil, nil
+	}
+	for _, tx := range block.Transactions() {
+		if tx.Type() == types.TxTypeBlob {
+			return BlobFee(chain, block.Header())
+		}
+	}
+	return nil, nil
+}
+
+// isBlobMessage returns whether a message is a blob transaction.
+func isBlobMessage(msg Message) bool {
+	typed, ok := msg.(interface{ Type() uint })
+	return ok && typed.Type() == types.TxTypeBlob
+}
+
+// validateBlobs checks the blob transactions of a block once they're activated,
+// ensuring they're well formed and don't exceed the blob limit of the block.
+func validateBlobs(config *params.ChainConfig, block *types.Block) error {
+	if !config.IsBlob(block.Number()) {
+		return nil
+	}
+	blobs := 0
+	for i, tx := range block.Transactions() {
+		if tx.Type() != types.TxTypeBlob {
+			continue
+		}
+		blob, err := tx.BlobTxData()
+		if err != nil {
+			return fmt.Errorf("invalid blob transaction %d: %v", i, err)
+		}
+		blobs += len(blob.BlobHashes)
+	}
+	if blobs > params.MaxBlobsPerBlock {
+		return ErrTooManyBlockBlobs
+	}
+	return nil
+}
+
+// GetBlob retrieves a data blob by its hash, or nil if it's unknown or pruned.
+func GetBlob(db DatabaseReader, hash common.Hash) []byte {
+	data, _ := db.Get(append(blobPrefix, hash[:]...))
+	return data
+}
+
+// HasBlob reports whether a data blob is stored locally.
+func HasBlob(db DatabaseReader, hash common.Hash) bool {
+	return len(GetBlob(db, hash)) > 0
+}
+
+// WriteBlob stores a data blob, keyed by its hash.
+func WriteBlob(db gooladb.Putter, blob []byte) common.Hash {
+	hash := types.BlobHash(blob)
+	if err := db.Put(append(blobPrefix, hash[:]...), blob); err != nil {
+		log.Crit("Failed to store data blob", "err", err)
+	}
+	return hash
+}
+
+// DeleteBlob removes a data blob.
+func DeleteBlob(db DatabaseDeleter, hash common.Hash) {
+	db.Delete(append(blobPrefix, hash[:]...))
+}
+
+// deleteTxBlobs removes the data blobs of the blob transactions.
+func deleteTxBlobs(db DatabaseDeleter, txs types.Transactions) {
+	for _, tx := range txs {
+		if blob, err := tx.BlobTxData(); err == nil {
+			for _, hash := range blob.BlobHashes {
+				DeleteBlob(db, hash)
+			}
+		}
+	}
+}
+
+// GetBlob retrieves a data blob by its hash, or nil if it's unknown or pruned.
+func (bc *BlockChain) GetBlob(hash common.Hash) []byte {
+	return GetBlob(bc.db, hash)
+}
+
+// HasBlob reports whether a data blob is stored locally.
+func (bc *BlockChain) HasBlob(hash common.Hash) bool {
+	return HasBlob(bc.db, hash)
+}
+
+// WriteBlob stores a data blob, returning its hash.
+func (bc *BlockChain) WriteBlob(blob []byte) common.Hash {
+	return WriteBlob(bc.db, blob)
+}
+
+// HasBlobs reports whether all the data blobs of a blob transaction are stored
+// locally.
+func (bc *BlockChain) HasBlobs(tx *types.Transaction) bool {
+	blob, err := tx.BlobTxData()
+	if err != nil {
+		return false
+	}
+	for _, hash := range blob.BlobHashes {
+		if !bc.HasBlob(hash) {
+			return false
+		}
+	}
+	return true
+}
+
+// GetBlobTail retrieves the number of the oldest block whose blobs are retained.
+func GetBlobTail(db DatabaseReader) uint64 {
+	data, _ := db.Get(blobTailKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteBlobTail stores the number of the oldest block whose blobs are retained.
+func WriteBlobTail(db gooladb.Putter, number uint64) {
+	if err := db.Put(blobTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store blob tail", "err", err)
+	}
+}
+
+// SetBlobRetention limits the number of recent canonical blocks whose data blobs
+// are retained, older ones being pruned as the chain progresses. Zero retains
+// all blobs.
+func (bc *BlockChain) SetBlobRetention(blocks uint64) {
+	if blocks > 0 && blocks < minBlobRetention {
+		log.Warn("Sanitizing blob retention", "provided", blocks, "updated", minBlobRetention)
+		blocks = minBlobRetention
+	}
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.blobRetention = blocks
+}
+
+// pruneBlobs deletes the data blobs of the canonical blocks which fell out of
+// the blob retention window after importing the given head.
+//
+// Note, this method assumes that the chain manager mutex is held!
+func (bc *BlockChain) pruneBlobs(head uint64) {
+	if bc.blobRetention == 0 || head <= bc.blobRetention {
+		return
+	}
+	tail, cutoff := GetBlobTail(bc.db), head-bc.blobRetention+1
+	if tail == 0 {
+		tail = 1
+	}
+	if tail >= cutoff {
+		return
+	}
+	if cutoff-tail > historyPruneLimit {
+		cutoff = tail + historyPruneLimit
+	}
+	for number := tail; number < cutoff; number++ {
+		hash := GetCanonicalHash(bc.db, number)
+		if hash == (common.Hash{}) {
+			continue
+		}
+		if body := GetBody(bc.db, hash, number); body != nil {
+			deleteTxBlobs(bc.db, body.Transactions)
+		}
+	}
+	WriteBlobTail(bc.db, cutoff)
+	log.Debug("Pruned data blobs", "from", tail, "to", cutoff-1)
+}
@@ -0,0 +1,161 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
+)
+
+// Tests that the blob fee stays at its minimum up to the target blob count and
+// doubles for every blob above it.
+func TestCalcBlobFee(t *testing.T) {
+	tests := []struct {
+		blobs int
+		fee   uint64
+	}{
+		{0, params.MinBlobFee},
+		{params.TargetBlobsPerBlock, params.MinBlobFee},
+		{params.TargetBlobsPerBlock + 1, 2 * params.MinBlobFee},
+		{params.MaxBlobsPerBlock, params.MinBlobFee << uint(params.MaxBlobsPerBlock-params.TargetBlobsPerBlock)},
+	}
+	for i, tt := range tests {
+		if fee := CalcBlobFee(tt.blobs); fee.Uint64() != tt.fee {
+			t.Errorf("test %d: fee mismatch: have %v, want %d", i, fee, tt.fee)
+		}
+	}
+}
+
+// blockMap is a block store for blob fee tests.
+type blockMap map[common.Hash]*types.Block
+
+func (m blockMap) GetBlock(hash common.Hash, number uint64) *types.Block { return m[hash] }
+
+// Tests that the blob fee is derived from the blobs of the parent block, and is
+// unknown without the parent's body.
+func TestBlobFee(t *testing.T) {
+	config := *params.TestChainConfig
+	config.BlobBlock = big.NewInt(1)
+
+	var txs []*types.Transaction
+	for i := 0; i < params.TargetBlobsPerBlock/params.MaxBlobsPerTx+1; i++ {
+		tx, err := types.NewBlobTransaction(uint64(i), common.Address{}, nil, 50000, big.NewInt(1), CalcBlobFee(0), make([][]byte, params.MaxBlobsPerTx), nil)
+		if err != nil {
+			t.Fatalf("failed to create blob transaction: %v", err)
+		}
+		txs = append(txs, tx)
+	}
+	var (
+		blocks = make(blockMap)
+		parent = types.NewBlock(&types.Header{Number: big.NewInt(1)}, txs, nil)
+		child  = types.NewBlock(&types.Header{Number: big.NewInt(2), ParentHash: parent.Hash()}, txs[:1], nil)
+		empty  = types.NewBlock(&types.Header{Number: big.NewInt(2), ParentHash: parent.Hash()}, nil, nil)
+	)
+	if _, err := BlobFee(blocks, child.Header()); err != ErrUnknownBlobFee {
+		t.Fatalf("missing parent error mismatch: have %v, want %v", err, ErrUnknownBlobFee)
+	}
+	if _, err := blockBlobFee(&config, blocks, child); err != ErrUnknownBlobFee {
+		t.Fatalf("missing parent block error mismatch: have %v, want %v", err, ErrUnknownBlobFee)
+	}
+	if fee, err := blockBlobFee(&config, blocks, empty); fee != nil || err != nil {
+		t.Fatalf("fee of block without blobs mismatch: have %v, %v, want nil", fee, err)
+	}
+	blocks[parent.Hash()] = parent
+
+	want := CalcBlobFee(CountBlobs(txs))
+	if fee, err := blockBlobFee(&config, blocks, child); err != nil || fee.Cmp(want) != 0 {
+		t.Fatalf("blob fee mismatch: have %v, %v, want %v", fee, err, want)
+	}
+	if fee, err := BlobFee(blocks, &types.Header{Number: big.NewInt(0)}); err != nil || fee.Uint64() != params.MinBlobFee {
+		t.Fatalf("genesis blob fee mismatch: have %v, %v, want %d", fee, err, params.MinBlobFee)
+	}
+}
+
+// Tests that blocks carrying more blobs than allowed are rejected once the blob
+// fork is active.
+func TestValidateBlobs(t *testing.T) {
+	config := *params.TestChainConfig
+	config.BlobBlock = big.NewInt(1)
+
+	var txs []*types.Transaction
+	for i := 0; i <= params.MaxBlobsPerBlock/params.MaxBlobsPerTx; i++ {
+		tx, err := types.NewBlobTransaction(uint64(i), common.Address{}, nil, 50000, big.NewInt(1), CalcBlobFee(0), make([][]byte, params.MaxBlobsPerTx), nil)
+		if err != nil {
+			t.Fatalf("failed to create blob transaction: %v", err)
+		}
+		txs = append(txs, tx)
+	}
+	full := len(txs) - 1
+	tests := []struct {
+		number int64
+		txs    []*types.Transaction
+		err    error
+	}{
+		{1, txs[:full], nil},
+		{1, txs, ErrTooManyBlockBlobs},
+		{0, txs, nil}, // before the fork
+	}
+	for i, tt := range tests {
+		block := types.NewBlock(&types.Header{Number: big.NewInt(tt.number)}, tt.txs, nil)
+		if err := validateBlobs(&config, block); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that blob pruning deletes the blobs of blocks outside the retention
+// window, keeping the rest.
+func TestBlobPruning(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	bc := &BlockChain{db: db}
+
+	var blocks []*types.Block
+	for i := int64(0); i < 300; i++ {
+		blob := []byte{byte(i), byte(i >> 8)}
+		tx, err := types.NewBlobTransaction(uint64(i), common.Address{}, nil, 50000, big.NewInt(1), CalcBlobFee(0), [][]byte{blob}, nil)
+		if err != nil {
+			t.Fatalf("failed to create blob transaction: %v", err)
+		}
+		block := types.NewBlock(&types.Header{Number: big.NewInt(i)}, []*types.Transaction{tx}, nil)
+		WriteBlock(db, block)
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		WriteBlob(db, blob)
+		blocks = append(blocks, block)
+	}
+	bc.SetBlobRetention(10)
+	if bc.blobRetention != minBlobRetention {
+		t.Fatalf("retention not sanitized: have %d, want %d", bc.blobRetention, minBlobRetention)
+	}
+	bc.pruneBlobs(299)
+
+	tail := uint64(299 - minBlobRetention + 1)
+	if have := GetBlobTail(db); have != tail {
+		t.Fatalf("blob tail mismatch: have %d, want %d", have, tail)
+	}
+	for _, block := range blocks {
+		number := block.NumberU64()
+		pruned := number > 0 && number < tail
+		if bc.HasBlobs(block.Transactions()[0]) == pruned {
+			t.Errorf("block #%d: blob pruned mismatch, want %v", number, pruned)
+		}
+	}
+}
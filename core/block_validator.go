@@ -66,6 +66,24 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
 		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
 	}
+	if err := validateTxSizes(v.config, block); err != nil {
+		return err
+	}
+	return validateBlobs(v.config, block)
+}
+
+// validateTxSizes checks that the transactions of a block fit the size limit of
+// the data policy in force, if any.
+func validateTxSizes(config *params.ChainConfig, block *types.Block) error {
+	rules := config.DataRules(block.Number())
+	if !rules.Enforced {
+		return nil
+	}
+	for i, tx := range block.Transactions() {
+		if size := uint64(tx.Size()); size > rules.MaxTxSize {
+			return fmt.Errorf("transaction %d: %v: %d bytes, limit %d", i, ErrOversizedData, size, rules.MaxTxSize)
+		}
+	}
 	return nil
 }
 
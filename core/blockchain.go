@@ -18,16 +18,20 @@
 package core
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/goola-team/goola/common"
 	"github.com/goola-team/goola/common/mclock"
+	"github.com/goola-team/goola/common/timesync"
 	"github.com/goola-team/goola/consensus"
 	"github.com/goola-team/goola/core/state"
 	"github.com/goola-team/goola/core/types"
@@ -39,6 +43,7 @@ import (
 	"github.com/goola-team/goola/metrics"
 	"github.com/goola-team/goola/params"
 	"github.com/goola-team/goola/rlp"
+	"github.com/goola-team/goola/tracing"
 	"github.com/goola-team/goola/trie"
 	"github.com/hashicorp/golang-lru"
 	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
@@ -48,6 +53,14 @@ var (
 	blockInsertTimer = metrics.NewTimer("chain/inserts")
 
 	ErrNoGenesis = errors.New("Genesis not found in chain")
+
+	// ErrReorgTooDeep is returned if importing a block would reorganise the
+	// chain deeper than the configured maximum depth.
+	ErrReorgTooDeep = errors.New("reorg exceeds maximum depth")
+
+	// ErrChainFrozen is returned if blocks are attempted to be written into a
+	// chain frozen in emergency read-only mode.
+	ErrChainFrozen = errors.New("chain frozen")
 )
 
 const (
@@ -56,6 +69,8 @@ const (
 	maxFutureBlocks     = 256
 	maxTimeFutureBlocks = 30
 	badBlockLimit       = 10
+	rejectedReorgLimit  = 16
+	touchedCacheLimit   = 256
 	triesInMemory       = 128
 
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
@@ -68,6 +83,8 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+	StateDiffs    bool          // Whether to record reverse state diffs to reconstruct pruned states
+	ImportBuffer  int           // Memory (MB) to buffer fast sync imports in before committing them (0 = disabled)
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -98,6 +115,8 @@ type BlockChain struct {
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
 	logsFeed      event.Feed
+	reorgRejFeed  event.Feed
+	touchFeed     event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
@@ -114,19 +133,37 @@ type BlockChain struct {
 	bodyRLPCache *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
 	blockCache   *lru.Cache     // Cache for the most recent entire blocks
 	futureBlocks *lru.Cache     // future blocks are blocks added for later processing
+	touchedCache *lru.Cache     // Accounts and storage slots modified by the most recent blocks
 
 	quit    chan struct{} // blockchain quit channel
 	running int32         // running must be called atomically
 	// procInterrupt must be atomically called
-	procInterrupt int32          // interrupt signaler for block processing
-	wg            sync.WaitGroup // chain processing wait group for shutting down
+	procInterrupt  int32          // interrupt signaler for block processing
+	frozen         int32          // Whether block writes are refused (atomic)
+	bulkStateLimit int32          // Memory allowance (MB) of uncommitted state during bulk imports, 0 if none (atomic)
+	wg             sync.WaitGroup // chain processing wait group for shutting down
 
 	engine    consensus.Engine
 	processor Processor // block processor interface
 	validator Validator // block and state validator interface
 	vmConfig  vm.Config
 
-	badBlocks *lru.Cache // Bad block cache
+	badBlocks *lru.Cache    // Bad block cache
+	imports   *importBuffer // Write-ahead buffer of fast sync imports, nil if disabled
+
+	indexInternalTxs bool // Whether to record the internal transactions of imported blocks
+	parallelTxs      bool // Whether to execute the transactions of imported blocks in parallel
+
+	maxReorgDepth  uint64               // Maximum number of canonical blocks a reorg may drop (0 = unlimited)
+	retainBlocks   uint64               // Number of recent blocks to retain the bodies and receipts of (0 = all)
+	blobRetention  uint64               // Number of recent blocks to retain the data blobs of (0 = all)
+	rejectedReorgs []ReorgRejectedEvent // Most recent rejected reorgs, protected by mu
+
+	justified *types.Header // Highest justified canonical block, protected by mu
+	finalized *types.Header // Highest finalized canonical block, protected by mu
+
+	hooks     []namedImportHook // Plugins notified of canonical block imports
+	hooksLock sync.RWMutex
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -144,6 +181,7 @@ func NewBlockChain(db gooladb.Database, cacheConfig *CacheConfig, chainConfig *p
 	blockCache, _ := lru.New(blockCacheLimit)
 	futureBlocks, _ := lru.New(maxFutureBlocks)
 	badBlocks, _ := lru.New(badBlockLimit)
+	touchedCache, _ := lru.New(touchedCacheLimit)
 
 	bc := &BlockChain{
 		chainConfig:  chainConfig,
@@ -156,6 +194,7 @@ func NewBlockChain(db gooladb.Database, cacheConfig *CacheConfig, chainConfig *p
 		bodyRLPCache: bodyRLPCache,
 		blockCache:   blockCache,
 		futureBlocks: futureBlocks,
+		touchedCache: touchedCache,
 		engine:       engine,
 		vmConfig:     vmConfig,
 		badBlocks:    badBlocks,
@@ -172,9 +211,27 @@ func NewBlockChain(db gooladb.Database, cacheConfig *CacheConfig, chainConfig *p
 	if bc.genesisBlock == nil {
 		return nil, ErrNoGenesis
 	}
+	// Recover any fast sync data buffered when the node went down
+	if err := replayImportLog(db); err != nil {
+		return nil, err
+	}
+	if cacheConfig.ImportBuffer > 0 {
+		if bc.imports, err = newImportBuffer(db, cacheConfig.ImportBuffer*1024*1024, bc.setFastHead); err != nil {
+			return nil, err
+		}
+	}
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	// If the node crashed last time, make sure the head wasn't left half written
+	if started := ReadDirtyShutdown(db); started != 0 {
+		log.Warn("Unclean shutdown detected, checking chain consistency", "started", time.Unix(int64(started), 0))
+		if err := bc.recoverHead(); err != nil {
+			return nil, err
+		}
+	}
+	WriteDirtyShutdown(db, uint64(time.Now().Unix()))
+
 	// Check the current state of the block hashes and make sure that we do not have any of the bad blocks in our chain
 	for hash := range BadHashes {
 		if header := bc.GetHeaderByHash(hash); header != nil {
@@ -241,7 +298,8 @@ func (bc *BlockChain) loadLastState() error {
 			bc.currentFastBlock = block
 		}
 	}
-	
+	bc.loadFinality()
+
 
 	log.Info("Loaded most recent local header", "number", currentHeader.Number, "hash", currentHeader.Hash())
 	log.Info("Loaded most recent local full block", "number", bc.currentBlock.Number(), "hash", bc.currentBlock.Hash())
@@ -257,9 +315,16 @@ func (bc *BlockChain) loadLastState() error {
 func (bc *BlockChain) SetHead(head uint64) error {
 	log.Warn("Rewinding blockchain", "target", head)
 
+	bc.commitImports()
+
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
 
+	// Finalized blocks are irreversible, refuse to drop them
+	if bc.finalized != nil && head < bc.finalized.Number.Uint64() {
+		log.Error("Refused rewinding below finalized block", "target", head, "finalized", bc.finalized.Number)
+		return ErrRewindFinalized
+	}
 	// Rewind the header chain, deleting all block bodies until then
 	delFn := func(hash common.Hash, num uint64) {
 		DeleteBody(bc.db, hash, num)
@@ -272,6 +337,7 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	bc.bodyRLPCache.Purge()
 	bc.blockCache.Purge()
 	bc.futureBlocks.Purge()
+	bc.touchedCache.Purge()
 
 	// Rewind the block chain, ensuring we don't end up with a stateless head block
 	if bc.currentBlock != nil && currentHeader.Number.Uint64() < bc.currentBlock.NumberU64() {
@@ -306,6 +372,8 @@ func (bc *BlockChain) SetHead(head uint64) error {
 // FastSyncCommitHead sets the current head block to the one defined by the hash
 // irrelevant what the chain contents were prior.
 func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
+	bc.commitImports()
+
 	// Make sure that both the block as well at its state trie exists
 	block := bc.GetBlockByHash(hash)
 	if block == nil {
@@ -363,6 +431,56 @@ func (bc *BlockChain) SetValidator(validator Validator) {
 	bc.validator = validator
 }
 
+// SetForkChoice replaces the rule selecting the canonical chain, which defaults
+// to the one of the consensus engine.
+func (bc *BlockChain) SetForkChoice(forkChoice ForkChoice) {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.hc.SetForkChoice(forkChoice)
+}
+
+// SetInternalTxIndexing enables or disables recording the internal value
+// transfers of blocks as they are imported.
+func (bc *BlockChain) SetInternalTxIndexing(enabled bool) {
+	bc.procmu.Lock()
+	defer bc.procmu.Unlock()
+	bc.indexInternalTxs = enabled
+}
+
+// SetParallelExecution enables or disables optimistically executing the
+// transactions of imported blocks in parallel, re-executing the ones conflicting
+// with earlier transactions of the block serially.
+func (bc *BlockChain) SetParallelExecution(enabled bool) {
+	bc.procmu.Lock()
+	defer bc.procmu.Unlock()
+	bc.parallelTxs = enabled
+}
+
+// SetMaxReorgDepth limits the number of canonical blocks a chain reorganisation
+// may drop. Imports exceeding it are rejected with ErrReorgTooDeep. Blocks of
+// forks deeper than that are stored as side chain without consulting the fork
+// choice rule. Zero lifts the limit.
+func (bc *BlockChain) SetMaxReorgDepth(depth uint64) {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.maxReorgDepth = depth
+}
+
+// SetHistoryRetention limits the number of recent canonical blocks the bodies
+// and receipts of are retained, older ones being pruned as the chain progresses
+// while their headers are kept. Zero retains the entire history.
+func (bc *BlockChain) SetHistoryRetention(blocks uint64) {
+	if blocks > 0 && blocks < minRetainBlocks {
+		log.Warn("Sanitizing history retention", "provided", blocks, "updated", minRetainBlocks)
+		blocks = minRetainBlocks
+	}
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.retainBlocks = blocks
+}
+
 // Validator returns the current validator.
 func (bc *BlockChain) Validator() Validator {
 	bc.procmu.RLock()
@@ -377,6 +495,25 @@ func (bc *BlockChain) Processor() Processor {
 	return bc.processor
 }
 
+// internalTxIndexing reports whether internal transactions are being recorded.
+func (bc *BlockChain) internalTxIndexing() bool {
+	bc.procmu.RLock()
+	defer bc.procmu.RUnlock()
+	return bc.indexInternalTxs
+}
+
+// parallelExecution reports whether block transactions are executed in parallel.
+func (bc *BlockChain) parallelExecution() bool {
+	bc.procmu.RLock()
+	defer bc.procmu.RUnlock()
+	return bc.parallelTxs
+}
+
+// GetInternalTxs retrieves the internal transactions recorded for a block.
+func (bc *BlockChain) GetInternalTxs(hash common.Hash, number uint64) []*InternalTx {
+	return GetInternalTxs(bc.db, hash, number)
+}
+
 // State returns a new mutable state based on the current HEAD block.
 func (bc *BlockChain) State() (*state.StateDB, error) {
 	return bc.StateAt(bc.CurrentBlock().Root())
@@ -629,6 +766,11 @@ func (bc *BlockChain) Stop() {
 
 	bc.wg.Wait()
 
+	if bc.imports != nil {
+		if err := bc.imports.close(); err != nil {
+			log.Error("Failed to commit buffered imports", "err", err)
+		}
+	}
 	// Ensure the state of a recent block is also stored to disk before exiting.
 	// It is fine if this state does not exist (fast start/stop cycle), but it is
 	// advisable to leave an N block gap from the head so 1) a restart loads up
@@ -655,6 +797,9 @@ func (bc *BlockChain) Stop() {
 			log.Error("Dangling trie nodes after full cleanup")
 		}
 	}
+	// Everything was flushed, mark the shutdown as clean
+	DeleteDirtyShutdown(bc.db)
+
 	log.Info("Blockchain manager stopped")
 }
 
@@ -687,6 +832,8 @@ const (
 // Rollback is designed to remove a chain of links from the database that aren't
 // certain enough to be valid.
 func (bc *BlockChain) Rollback(chain []common.Hash) {
+	bc.commitImports()
+
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
 
@@ -742,9 +889,27 @@ func SetReceiptsData(config *params.ChainConfig, block *types.Block, receipts ty
 	}
 }
 
+// SetFrozen freezes or unfreezes the chain. A frozen chain keeps serving reads,
+// but refuses importing or writing any blocks with ErrChainFrozen.
+func (bc *BlockChain) SetFrozen(frozen bool) {
+	if frozen {
+		atomic.StoreInt32(&bc.frozen, 1)
+	} else {
+		atomic.StoreInt32(&bc.frozen, 0)
+	}
+}
+
+// Frozen reports whether the chain is frozen in read-only mode.
+func (bc *BlockChain) Frozen() bool {
+	return atomic.LoadInt32(&bc.frozen) == 1
+}
+
 // InsertReceiptChain attempts to complete an already existing header chain with
 // transaction and receipt data.
 func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain []types.Receipts) (int, error) {
+	if bc.Frozen() {
+		return 0, ErrChainFrozen
+	}
 	bc.wg.Add(1)
 	defer bc.wg.Done()
 
@@ -763,7 +928,16 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		start = time.Now()
 		bytes = 0
 		batch = bc.db.NewBatch()
+		head  = blockChain[len(blockChain)-1]
+
+		writer  gooladb.Putter = batch
+		segment *importSegment
 	)
+	// If imports are buffered, collect the entire chain into a single segment
+	if bc.imports != nil {
+		segment = new(importSegment)
+		writer = segment
+	}
 	for i, block := range blockChain {
 		receipts := receiptChain[i]
 		// Short circuit insertion if shutting down or processing failed
@@ -782,18 +956,18 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		// Compute all the non-consensus fields of the receipts
 		SetReceiptsData(bc.chainConfig, block, receipts)
 		// Write all the data out into the database
-		if err := WriteBody(batch, block.Hash(), block.NumberU64(), block.Body()); err != nil {
+		if err := WriteBody(writer, block.Hash(), block.NumberU64(), block.Body()); err != nil {
 			return i, fmt.Errorf("failed to write block body: %v", err)
 		}
-		if err := WriteBlockReceipts(batch, block.Hash(), block.NumberU64(), receipts); err != nil {
+		if err := WriteBlockReceipts(writer, block.Hash(), block.NumberU64(), receipts); err != nil {
 			return i, fmt.Errorf("failed to write block receipts: %v", err)
 		}
-		if err := WriteTxLookupEntries(batch, block); err != nil {
+		if err := WriteTxLookupEntries(writer, block); err != nil {
 			return i, fmt.Errorf("failed to write lookup metadata: %v", err)
 		}
 		stats.processed++
 
-		if batch.ValueSize() >= gooladb.IdealBatchSize {
+		if segment == nil && batch.ValueSize() >= gooladb.IdealBatchSize {
 			if err := batch.Write(); err != nil {
 				return 0, err
 			}
@@ -801,18 +975,26 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 			batch.Reset()
 		}
 	}
+	if segment != nil {
+		// Persist the fast head along with the data, so a replayed segment is complete
+		if err := WriteHeadFastBlockHash(segment, head.Hash()); err != nil {
+			return 0, err
+		}
+		// The fast head only advances once the buffer is committed
+		bytes = segment.size
+		if err := bc.imports.add(segment, head); err != nil {
+			return 0, err
+		}
+	}
 	if batch.ValueSize() > 0 {
 		bytes += batch.ValueSize()
 		if err := batch.Write(); err != nil {
 			return 0, err
 		}
 	}
-
-	// Update the head fast sync block if better
-	bc.mu.Lock()
-	head := blockChain[len(blockChain)-1]
-	bc.currentFastBlock = head
-	bc.mu.Unlock()
+	if segment == nil {
+		bc.setFastHead(head)
+	}
 
 	log.Info("Imported new block receipts",
 		"count", stats.processed,
@@ -839,19 +1021,64 @@ func (bc *BlockChain) WriteBlockWithoutState(block *types.Block) (err error) {
 
 // WriteBlockWithState writes the block and all associated state to the database.
 func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.Receipt, state *state.StateDB) (status WriteStatus, err error) {
+	if bc.Frozen() {
+		return NonStatTy, ErrChainFrozen
+	}
 	bc.wg.Add(1)
 	defer bc.wg.Done()
 	// Make sure no inconsistent state is leaked during insertion
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
 
-
-
+	// Let the fork choice rule decide whether the block becomes the new head. A
+	// block forking off deeper than the maximum reorg depth can never become the
+	// head, so the fork choice isn't made to walk its whole fork. It's taken to
+	// win only if it outgrew the canonical chain, for the refusal to be reported.
+	var reorg, deep bool
+	if bc.maxReorgDepth > 0 && block.ParentHash() != bc.currentBlock.Hash() {
+		deep = bc.reorgDepth(block, bc.maxReorgDepth) > bc.maxReorgDepth
+	}
+	if deep {
+		reorg = block.NumberU64() > bc.currentBlock.NumberU64()
+	} else {
+		var err error
+		if reorg, err = bc.hc.forkChoice.ReorgNeeded(bc, bc.currentBlock.Header(), block.Header()); err != nil {
+			return NonStatTy, err
+		}
+	}
+	// Refuse to rewrite finalized or more history than allowed. Blocks staying
+	// on a side chain rewrite nothing and are never refused.
+	if reorg && bc.belowFinalized(block) {
+		log.Error("Rejected chain reorg below finalized block", "number", block.Number(), "hash", block.Hash(),
+			"head", bc.currentBlock.Number(), "finalized", bc.finalized.Number)
+		return NonStatTy, ErrReorgFinalized
+	}
+	if reorg && deep {
+		depth := bc.reorgDepth(block, 0)
+		ev := ReorgRejectedEvent{Block: block, Head: bc.currentBlock, Depth: depth}
+		if bc.rejectedReorgs = append(bc.rejectedReorgs, ev); len(bc.rejectedReorgs) > rejectedReorgLimit {
+			bc.rejectedReorgs = bc.rejectedReorgs[len(bc.rejectedReorgs)-rejectedReorgLimit:]
+		}
+		log.Error("Rejected deep chain reorg", "number", block.Number(), "hash", block.Hash(),
+			"head", bc.currentBlock.Number(), "depth", depth, "limit", bc.maxReorgDepth)
+		go bc.reorgRejFeed.Send(ev)
+		return NonStatTy, ErrReorgTooDeep
+	}
 	// Write other block data using a batch.
 	batch := bc.db.NewBatch()
 	if err := WriteBlock(batch, block); err != nil {
 		return NonStatTy, err
 	}
+	// Record the state modified by the block before committing clears it
+	touched := newTouchedState(state)
+	bc.touchedCache.Add(block.Hash(), touched)
+
+	if bc.cacheConfig.StateDiffs {
+		if err := bc.writeStateDiff(batch, block, state, touched); err != nil {
+			return NonStatTy, err
+		}
+	}
+
 	root, err := state.Commit(true)
 	if err != nil {
 		return NonStatTy, err
@@ -878,8 +1105,13 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 			var (
 				size  = triedb.Size()
 				limit = common.StorageSize(bc.cacheConfig.TrieNodeLimit) * 1024 * 1024
+				stale = bc.gcproc > bc.cacheConfig.TrieTimeLimit
 			)
-			if size > limit || bc.gcproc > bc.cacheConfig.TrieTimeLimit {
+			// Bulk imports only commit once their own memory allowance is used up
+			if bulk := atomic.LoadInt32(&bc.bulkStateLimit); bulk > 0 {
+				limit, stale = common.StorageSize(bulk)*1024*1024, false
+			}
+			if size > limit || stale {
 				// If we're exceeding limits but haven't reached a large enough memory gap,
 				// warn the user that the system is becoming unstable.
 				if chosen < lastWrite+triesInMemory {
@@ -912,8 +1144,6 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 		return NonStatTy, err
 	}
 
-	reorg := true
-
 	if reorg {
 		// Reorganise the chain if the parent is not the head block
 		if block.ParentHash() != bc.currentBlock.Hash() {
@@ -940,6 +1170,9 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	// Set new head.
 	if status == CanonStatTy {
 		bc.insert(block)
+		bc.updateFinality(block.Header())
+		bc.pruneBlobs(block.NumberU64())
+		bc.pruneHistory(block.NumberU64())
 	}
 	bc.futureBlocks.Remove(block.Hash())
 	return status, nil
@@ -952,15 +1185,19 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 //
 // After insertion is done, all accumulated events will be fired.
 func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
-	n, events, logs, err := bc.insertChain(chain)
+	n, events, logs, err := bc.insertChain(chain, true)
 	bc.PostChainEvents(events, logs)
 	return n, err
 }
 
 // insertChain will execute the actual chain insertion and event aggregation. The
 // only reason this method exists as a separate one is to make locking cleaner
-// with deferred statements.
-func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*types.Log, error) {
+// with deferred statements. Seal verification may be skipped for blocks already
+// authenticated otherwise.
+func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []interface{}, []*types.Log, error) {
+	if bc.Frozen() {
+		return 0, nil, nil, ErrChainFrozen
+	}
 	// Do a sanity check that the provided chain is actually ordered and linked
 	for i := 1; i < len(chain); i++ {
 		if chain[i].NumberU64() != chain[i-1].NumberU64()+1 || chain[i].ParentHash() != chain[i-1].Hash() {
@@ -973,12 +1210,17 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		}
 	}
 	// Pre-checks passed, start the full block imports
+	ctx, span := tracing.Start(context.Background(), "chain.insert", "blocks", len(chain))
+	defer span.End()
+
 	bc.wg.Add(1)
 	defer bc.wg.Done()
 
 	bc.chainmu.Lock()
 	defer bc.chainmu.Unlock()
 
+	bc.commitImports()
+
 	// A queued approach to delivering events. This is generally
 	// faster than direct delivery and requires much less mutex
 	// acquiring.
@@ -994,7 +1236,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 
 	for i, block := range chain {
 		headers[i] = block.Header()
-		seals[i] = true
+		seals[i] = verifySeals
 	}
 	abort, results := bc.engine.VerifyHeaders(bc, headers, seals)
 	defer close(abort)
@@ -1014,10 +1256,13 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		// Wait for the block's verification to complete
 		bstart := time.Now()
 
+		_, vspan := tracing.Start(ctx, "chain.verify", "number", block.NumberU64())
 		err := <-results
 		if err == nil {
 			err = bc.Validator().ValidateBody(block)
 		}
+		vspan.End()
+
 		switch {
 		case err == ErrKnownBlock:
 			// Block and state both already known. However if the current block is below
@@ -1030,7 +1275,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		case err == consensus.ErrFutureBlock:
 			// Allow up to MaxFuture second in the future blocks. If this limit is exceeded
 			// the chain is discarded and processed at a later time if given.
-			max := big.NewInt(time.Now().Unix() + maxTimeFutureBlocks)
+			max := big.NewInt(timesync.Now().Unix() + maxTimeFutureBlocks)
 			if block.Time().Cmp(max) > 0 {
 				return i, events, coalescedLogs, fmt.Errorf("future block: %v > %v", block.Time(), max)
 			}
@@ -1065,7 +1310,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 			}
 			// Import all the pruned blocks to make the state available
 			bc.chainmu.Unlock()
-			_, evs, logs, err := bc.insertChain(winner)
+			_, evs, logs, err := bc.insertChain(winner, true)
 			bc.chainmu.Lock()
 			events, coalescedLogs = evs, logs
 
@@ -1074,6 +1319,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 			}
 
 		case err != nil:
+			span.SetError(err)
 			bc.reportBlock(block, nil, err)
 			return i, events, coalescedLogs, err
 		}
@@ -1090,24 +1336,46 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 			return i, events, coalescedLogs, err
 		}
 		// Process block using the parent state as reference point.
-		receipts, logs, usedGas, err := bc.processor.Process(block, state, bc.vmConfig)
+		vmConfig, itxs := bc.vmConfig, (*internalTxTracer)(nil)
+		if bc.internalTxIndexing() && !vmConfig.Debug {
+			itxs = newInternalTxTracer()
+			vmConfig.Debug, vmConfig.Tracer = true, itxs
+		}
+		_, pspan := tracing.Start(ctx, "chain.process", "number", block.NumberU64(), "txs", len(block.Transactions()))
+		receipts, logs, usedGas, err := bc.processor.Process(block, state, vmConfig)
+		pspan.SetError(err)
+		pspan.End()
 		if err != nil {
+			span.SetError(err)
 			bc.reportBlock(block, receipts, err)
 			return i, events, coalescedLogs, err
 		}
 		// Validate the state using the default validator
+		_, sspan := tracing.Start(ctx, "chain.validate", "number", block.NumberU64())
 		err = bc.Validator().ValidateState(block, parent, state, receipts, usedGas)
+		sspan.SetError(err)
+		sspan.End()
 		if err != nil {
+			span.SetError(err)
 			bc.reportBlock(block, receipts, err)
 			return i, events, coalescedLogs, err
 		}
 		proctime := time.Since(bstart)
 
 		// Write the block to the chain and get the status.
+		_, wspan := tracing.Start(ctx, "chain.write", "number", block.NumberU64())
 		status, err := bc.WriteBlockWithState(block, receipts, state)
+		wspan.SetError(err)
+		wspan.End()
 		if err != nil {
+			span.SetError(err)
 			return i, events, coalescedLogs, err
 		}
+		if itxs != nil {
+			if err := WriteInternalTxs(bc.db, block.Hash(), block.NumberU64(), itxs.result(block, receipts)); err != nil {
+				log.Error("Failed to store internal transactions", "number", block.Number(), "hash", block.Hash(), "err", err)
+			}
+		}
 		switch status {
 		case CanonStatTy:
 			log.Debug("Inserted new block", "number", block.Number(), "hash", block.Hash(),
@@ -1116,6 +1384,10 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 			coalescedLogs = append(coalescedLogs, logs...)
 			blockInsertTimer.UpdateSince(bstart)
 			events = append(events, ChainEvent{block, block.Hash(), logs})
+			if touched := bc.TouchedState(block.Hash()); touched != nil {
+				events = append(events, StateTouchEvent{block, touched})
+			}
+			bc.runImportHooks(block, receipts)
 			lastCanon = block
 
 			// Only count canonical blocks for GC processing time
@@ -1189,6 +1461,75 @@ func countTransactions(chain []*types.Block) (c int) {
 	return c
 }
 
+// reorgDepth returns the number of canonical blocks that importing the given
+// block would drop. Walking the fork back to the canonical chain stops as soon
+// as more than limit blocks would be dropped, limit+1 being returned then. Zero
+// lifts the limit. The caller must hold the chain mutex.
+func (bc *BlockChain) reorgDepth(block *types.Block, limit uint64) uint64 {
+	head := bc.currentBlock.NumberU64()
+
+	header := block.Header()
+	for header != nil && GetCanonicalHash(bc.db, header.Number.Uint64()) != header.Hash() {
+		if limit > 0 && header.Number.Uint64()+limit <= head {
+			return limit + 1
+		}
+		header = bc.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	if header == nil || header.Number.Uint64() >= head {
+		return 0
+	}
+	return head - header.Number.Uint64()
+}
+
+// RejectedReorgs returns the most recent imports refused for exceeding the
+// maximum reorg depth, oldest first.
+func (bc *BlockChain) RejectedReorgs() []ReorgRejectedEvent {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return append([]ReorgRejectedEvent{}, bc.rejectedReorgs...)
+}
+
+// TouchedState is the set of accounts and storage slots modified by a block.
+type TouchedState struct {
+	Accounts []common.Address                 // Accounts modified, sorted
+	Storage  map[common.Address][]common.Hash // Storage slots written per account, sorted
+}
+
+// newTouchedState collects the accounts and storage slots modified in a state
+// since its last commit.
+func newTouchedState(statedb *state.StateDB) *TouchedState {
+	touched := &TouchedState{
+		Accounts: statedb.DirtyAccounts(),
+		Storage:  statedb.DirtyStorage(),
+	}
+	sort.Slice(touched.Accounts, func(i, j int) bool {
+		return bytes.Compare(touched.Accounts[i][:], touched.Accounts[j][:]) < 0
+	})
+	for _, slots := range touched.Storage {
+		sort.Slice(slots, func(i, j int) bool { return bytes.Compare(slots[i][:], slots[j][:]) < 0 })
+	}
+	return touched
+}
+
+// TouchedState returns the accounts and storage slots modified by a recently
+// imported block, or nil if the block is unknown or was imported too long ago.
+func (bc *BlockChain) TouchedState(hash common.Hash) *TouchedState {
+	if touched, ok := bc.touchedCache.Get(hash); ok {
+		return touched.(*TouchedState)
+	}
+	return nil
+}
+
+// TouchedAccounts returns the accounts modified by a recently imported block, or
+// nil if the block is unknown or was imported too long ago.
+func (bc *BlockChain) TouchedAccounts(hash common.Hash) []common.Address {
+	if touched := bc.TouchedState(hash); touched != nil {
+		return touched.Accounts
+	}
+	return nil
+}
+
 // reorgs takes two blocks, an old chain and a new chain and will reconstruct the blocks and inserts them
 // to be part of the new canonical chain and accumulates potential missing transactions and post an
 // event about them
@@ -1317,6 +1658,9 @@ func (bc *BlockChain) PostChainEvents(events []interface{}, logs []*types.Log) {
 
 		case ChainSideEvent:
 			bc.chainSideFeed.Send(ev)
+
+		case StateTouchEvent:
+			bc.touchFeed.Send(ev)
 		}
 	}
 }
@@ -1387,6 +1731,9 @@ Error: %v
 // of the header retrieval mechanisms already need to verify nonces, as well as
 // because nonces can be verified sparsely, not needing to check each.
 func (bc *BlockChain) InsertHeaderChain(chain []*types.Header, checkFreq int) (int, error) {
+	if bc.Frozen() {
+		return 0, ErrChainFrozen
+	}
 	start := time.Now()
 	if i, err := bc.hc.ValidateHeaderChain(chain, checkFreq); err != nil {
 		return i, err
@@ -1496,6 +1843,16 @@ func (bc *BlockChain) SubscribeChainSideEvent(ch chan<- ChainSideEvent) event.Su
 	return bc.scope.Track(bc.chainSideFeed.Subscribe(ch))
 }
 
+// SubscribeReorgRejectedEvent registers a subscription of ReorgRejectedEvent.
+func (bc *BlockChain) SubscribeReorgRejectedEvent(ch chan<- ReorgRejectedEvent) event.Subscription {
+	return bc.scope.Track(bc.reorgRejFeed.Subscribe(ch))
+}
+
+// SubscribeStateTouchEvent registers a subscription of StateTouchEvent.
+func (bc *BlockChain) SubscribeStateTouchEvent(ch chan<- StateTouchEvent) event.Subscription {
+	return bc.scope.Track(bc.touchFeed.Subscribe(ch))
+}
+
 // SubscribeLogsEvent registers a subscription of []*types.Log.
 func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
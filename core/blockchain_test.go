@@ -1009,3 +1009,105 @@ func TestLargeReorgTrieGC(t *testing.T) {
 		}
 	}
 }
+
+// Tests that a frozen chain refuses all block and header imports, and accepts
+// them again once unfrozen.
+func TestFrozenChain(t *testing.T) {
+	db, blockchain, err := newCanonical(dpos.NewFaker(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	blocks := makeBlockChain(blockchain.CurrentBlock(), 4, dpos.NewFaker(), db, 0)
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	blockchain.SetFrozen(true)
+	if _, err := blockchain.InsertChain(blocks); err != ErrChainFrozen {
+		t.Fatalf("block import error mismatch: have %v, want %v", err, ErrChainFrozen)
+	}
+	if _, err := blockchain.InsertHeaderChain(headers, 1); err != ErrChainFrozen {
+		t.Fatalf("header import error mismatch: have %v, want %v", err, ErrChainFrozen)
+	}
+	if head := blockchain.CurrentBlock().NumberU64(); head != 0 {
+		t.Fatalf("frozen chain advanced: head #%d", head)
+	}
+	blockchain.SetFrozen(false)
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import blocks after unfreezing: %v", err)
+	}
+	if head := blockchain.CurrentBlock().NumberU64(); head != uint64(len(blocks)) {
+		t.Errorf("head mismatch: have #%d, want #%d", head, len(blocks))
+	}
+}
+
+// Tests that the maximum reorg depth only refuses blocks that would reorganise
+// the chain, side chain blocks forking off deeper being stored regardless.
+func TestReorgDepthLimit(t *testing.T) {
+	db, blockchain, err := newCanonical(dpos.NewFaker(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+	blockchain.SetMaxReorgDepth(3)
+
+	blocks := makeBlockChain(blockchain.CurrentBlock(), 10, dpos.NewFaker(), db, 0)
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// A shorter fork from deep below the head stays a side chain and is accepted
+	side := makeBlockChain(blocks[4], 3, dpos.NewFaker(), db, 1)
+	if _, err := blockchain.InsertChain(side); err != nil {
+		t.Fatalf("failed to insert side chain: %v", err)
+	}
+	if rejected := blockchain.RejectedReorgs(); len(rejected) != 0 {
+		t.Fatalf("side chain reported as rejected reorg: %v", rejected)
+	}
+	if head := blockchain.CurrentBlock(); head.Hash() != blocks[len(blocks)-1].Hash() {
+		t.Fatalf("head changed by side chain: have #%d", head.NumberU64())
+	}
+	// Walking a deep fork back to the canonical chain stops past the limit
+	if depth := blockchain.reorgDepth(side[len(side)-1], 3); depth != 4 {
+		t.Errorf("capped reorg depth mismatch: have %d, want 4", depth)
+	}
+	if depth := blockchain.reorgDepth(side[len(side)-1], 0); depth != 5 {
+		t.Errorf("reorg depth mismatch: have %d, want 5", depth)
+	}
+	// A heavier fork from there would drop too many blocks and is refused
+	alerts := make(chan ReorgRejectedEvent, 1)
+	sub := blockchain.SubscribeReorgRejectedEvent(alerts)
+	defer sub.Unsubscribe()
+
+	fork := makeBlockChain(blocks[4], 10, dpos.NewFaker(), db, 2)
+	if _, err := blockchain.InsertChain(fork); err != ErrReorgTooDeep {
+		t.Fatalf("deep reorg error mismatch: have %v, want %v", err, ErrReorgTooDeep)
+	}
+	if rejected := blockchain.RejectedReorgs(); len(rejected) != 1 || rejected[0].Depth != 5 {
+		t.Fatalf("rejected reorgs mismatch: have %v, want one of depth 5", rejected)
+	}
+	if head := blockchain.CurrentBlock(); head.Hash() != blocks[len(blocks)-1].Hash() {
+		t.Errorf("head changed by refused reorg: have #%d", head.NumberU64())
+	}
+	select {
+	case ev := <-alerts:
+		if ev.Block.Hash() != fork[5].Hash() || ev.Head.Hash() != blocks[len(blocks)-1].Hash() || ev.Depth != 5 {
+			t.Errorf("reorg alert mismatch: have #%d over #%d depth %d, want #%d over #%d depth 5",
+				ev.Block.NumberU64(), ev.Head.NumberU64(), ev.Depth, fork[5].NumberU64(), len(blocks))
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no alert for refused reorg")
+	}
+	// A heavier fork within the limit reorganises the chain
+	fork = makeBlockChain(blocks[7], 4, dpos.NewFaker(), db, 3)
+	if _, err := blockchain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert shallow fork: %v", err)
+	}
+	if head := blockchain.CurrentBlock(); head.Hash() != fork[len(fork)-1].Hash() {
+		t.Errorf("head mismatch after shallow reorg: have #%d, want #%d", head.NumberU64(), fork[len(fork)-1].NumberU64())
+	}
+	if rejected := blockchain.RejectedReorgs(); len(rejected) != 1 {
+		t.Errorf("shallow reorg reported as rejected: %v", rejected)
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rlp"
+)
+
+var (
+	// errCheckpointMissing is returned if the trusted checkpoint of a bulk import
+	// is not contained in the imported bundle.
+	errCheckpointMissing = errors.New("trusted checkpoint not in bundle")
+
+	// errBulkImportInterrupted is returned if a bulk import was interrupted.
+	errBulkImportInterrupted = errors.New("interrupted")
+)
+
+// BulkImportConfig are the settings of a bulk chain import.
+type BulkImportConfig struct {
+	BatchSize  int             // Number of blocks inserted at once
+	StateLimit int             // Memory allowance (MB) of uncommitted state, deferring commits into large batches
+	Checkpoint common.Hash     // Trusted hash of a bundle block, whose ancestors skip seal verification (zero = verify all)
+	Interrupt  <-chan struct{} // Aborts the import after the current batch when closed
+}
+
+// DefaultBulkImportConfig contains the default settings of bulk imports.
+var DefaultBulkImportConfig = BulkImportConfig{
+	BatchSize:  2500,
+	StateLimit: 1024,
+}
+
+// bulkBatch is a batch of consecutive blocks flowing through the bulk import
+// pipeline.
+type bulkBatch struct {
+	blocks  types.Blocks
+	trusted bool  // Whether the blocks are ancestors of the trusted checkpoint
+	err     error // Failure reading the bundle, terminating the pipeline
+}
+
+// BulkImport imports a bundle of consecutive RLP encoded blocks, as written by
+// Export, into the chain. The bundle is read by open, which is called twice if a
+// trusted checkpoint is configured.
+//
+// Compared to InsertChain, the bundle is decoded and the transaction senders are
+// recovered in parallel pipelines ahead of block processing, state commits are
+// deferred until the configured memory allowance is used up, and the seals of
+// the blocks proven to be ancestors of the trusted checkpoint are not verified.
+// Blocks already in the chain are skipped. It returns the number of blocks
+// inserted.
+func (bc *BlockChain) BulkImport(open func() (io.ReadCloser, error), config BulkImportConfig) (int, error) {
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBulkImportConfig.BatchSize
+	}
+	// If a checkpoint is trusted, index the batches proven to be its ancestors
+	var (
+		trustedTo uint64
+		trusted   map[uint64]common.Hash
+	)
+	if config.Checkpoint != (common.Hash{}) {
+		r, err := open()
+		if err != nil {
+			return 0, err
+		}
+		trustedTo, trusted, err = scanBundle(r, config.Checkpoint, uint64(config.BatchSize))
+		r.Close()
+		if err != nil {
+			return 0, err
+		}
+		log.Info("Verified bundle against trusted checkpoint", "number", trustedTo, "hash", config.Checkpoint)
+	}
+	r, err := open()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	// Defer state commits for the duration of the import
+	if config.StateLimit > 0 {
+		atomic.StoreInt32(&bc.bulkStateLimit, int32(config.StateLimit))
+		defer atomic.StoreInt32(&bc.bulkStateLimit, 0)
+	}
+	// Start the decoding and sender recovery pipelines
+	var (
+		quit      = make(chan struct{})
+		decoded   = make(chan *bulkBatch, 2)
+		recovered = make(chan *bulkBatch, 2)
+	)
+	defer close(quit)
+
+	go readBundle(r, uint64(config.BatchSize), trustedTo, trusted, decoded, quit)
+	go bc.recoverBundleSenders(decoded, recovered, quit)
+
+	// Insert the pre-verified batches as they arrive
+	imported := 0
+	for batch := range recovered {
+		if batch.err != nil {
+			return imported, batch.err
+		}
+		select {
+		case <-config.Interrupt:
+			return imported, errBulkImportInterrupted
+		default:
+		}
+		missing := bc.missingBlocks(batch.blocks)
+		if len(missing) == 0 {
+			continue
+		}
+		n, events, logs, err := bc.insertChain(missing, !batch.trusted)
+		bc.PostChainEvents(events, logs)
+		if err != nil {
+			return imported + n, fmt.Errorf("invalid block #%d: %v", missing[n].NumberU64(), err)
+		}
+		imported += len(missing)
+		last := missing[len(missing)-1]
+		log.Info("Imported bundle batch", "blocks", len(missing), "number", last.NumberU64(), "hash", last.Hash(), "trusted", batch.trusted)
+	}
+	return imported, nil
+}
+
+// scanBundle checks that the blocks of a bundle up to the trusted checkpoint are
+// contiguous and linked, returning the number of the checkpoint and the hashes
+// of its ancestors ending import batches. Only the headers are decoded.
+func scanBundle(r io.Reader, checkpoint common.Hash, batchSize uint64) (uint64, map[uint64]common.Hash, error) {
+	var (
+		stream = rlp.NewStream(r, 0)
+		hashes = make(map[uint64]common.Hash)
+		parent *types.Header
+	)
+	for {
+		raw, err := stream.Raw()
+		if err == io.EOF {
+			return 0, nil, errCheckpointMissing
+		} else if err != nil {
+			return 0, nil, err
+		}
+		header, err := decodeBundleHeader(raw)
+		if err != nil {
+			return 0, nil, err
+		}
+		number, hash := header.Number.Uint64(), header.Hash()
+		if parent != nil && (number != parent.Number.Uint64()+1 || header.ParentHash != parent.Hash()) {
+			return 0, nil, fmt.Errorf("non contiguous bundle at block #%d", number)
+		}
+		parent = header
+
+		if number%batchSize == 0 || hash == checkpoint {
+			hashes[number] = hash
+		}
+		if hash == checkpoint {
+			return number, hashes, nil
+		}
+	}
+}
+
+// decodeBundleHeader decodes the header of an RLP encoded block, skipping the
+// decoding of its body.
+func decodeBundleHeader(raw []byte) (*types.Header, error) {
+	content, _, err := rlp.SplitList(raw)
+	if err != nil {
+		return nil, err
+	}
+	_, _, rest, err := rlp.Split(content)
+	if err != nil {
+		return nil, err
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(content[:len(content)-len(rest)], header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// readBundle decodes the blocks of a bundle into batches, cut at the multiples
+// of the batch size and at the trusted checkpoint, marking the batches proven to
+// be ancestors of the checkpoint trusted. The genesis block is skipped.
+func readBundle(r io.Reader, batchSize uint64, trustedTo uint64, trusted map[uint64]common.Hash, out chan<- *bulkBatch, quit chan struct{}) {
+	defer close(out)
+
+	deliver := func(batch *bulkBatch) bool {
+		select {
+		case out <- batch:
+			return true
+		case <-quit:
+			return false
+		}
+	}
+	var (
+		stream = rlp.NewStream(r, 0)
+		blocks types.Blocks
+	)
+	for {
+		block := new(types.Block)
+		if err := stream.Decode(block); err == io.EOF {
+			break
+		} else if err != nil {
+			deliver(&bulkBatch{err: fmt.Errorf("block %d of bundle: %v", len(blocks), err)})
+			return
+		}
+		number := block.NumberU64()
+		if number == 0 {
+			continue
+		}
+		blocks = append(blocks, block)
+		if number%batchSize == 0 || uint64(len(blocks)) == batchSize || (trusted != nil && number == trustedTo) {
+			hash, ok := trusted[number]
+			if !deliver(&bulkBatch{blocks: blocks, trusted: ok && number <= trustedTo && hash == block.Hash()}) {
+				return
+			}
+			blocks = nil
+		}
+	}
+	if len(blocks) > 0 {
+		deliver(&bulkBatch{blocks: blocks})
+	}
+}
+
+// recoverBundleSenders recovers the senders of the transactions in the decoded
+// batches in parallel, caching them in the transactions for block processing.
+func (bc *BlockChain) recoverBundleSenders(in <-chan *bulkBatch, out chan<- *bulkBatch, quit chan struct{}) {
+	defer close(out)
+
+	for batch := range in {
+		if batch.err == nil {
+			var (
+				txs     = make(chan *types.Transaction, runtime.NumCPU())
+				pending sync.WaitGroup
+			)
+			signer := types.MakeSigner(bc.chainConfig, batch.blocks[0].Number())
+			for i := 0; i < runtime.NumCPU(); i++ {
+				pending.Add(1)
+				go func() {
+					defer pending.Done()
+					for tx := range txs {
+						types.Sender(signer, tx)
+					}
+				}()
+			}
+			for _, block := range batch.blocks {
+				for _, tx := range block.Transactions() {
+					txs <- tx
+				}
+			}
+			close(txs)
+			pending.Wait()
+		}
+		select {
+		case out <- batch:
+		case <-quit:
+			return
+		}
+	}
+}
+
+// missingBlocks returns the blocks of a batch starting at the first one missing
+// from the chain. State availability is required for blocks above the head.
+func (bc *BlockChain) missingBlocks(blocks types.Blocks) types.Blocks {
+	head := bc.CurrentBlock().NumberU64()
+	for i, block := range blocks {
+		if head > block.NumberU64() {
+			if !bc.HasBlock(block.Hash(), block.NumberU64()) {
+				return blocks[i:]
+			}
+			continue
+		}
+		if !bc.HasBlockAndState(block.Hash(), block.NumberU64()) {
+			return blocks[i:]
+		}
+	}
+	return nil
+}
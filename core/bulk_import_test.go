@@ -0,0 +1,73 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/goola-team/goola/consensus/dpos"
+)
+
+// Tests that bulk imports insert exported bundles, and skip the seal checks of
+// the blocks proven to be ancestors of a trusted checkpoint only.
+func TestBulkImport(t *testing.T) {
+	_, source, err := newCanonical(dpos.NewFaker(), 10, true)
+	if err != nil {
+		t.Fatalf("failed to create source chain: %v", err)
+	}
+	defer source.Stop()
+
+	bundle := new(bytes.Buffer)
+	if err := source.Export(bundle); err != nil {
+		t.Fatalf("failed to export source chain: %v", err)
+	}
+	open := func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(bundle.Bytes())), nil
+	}
+	config := BulkImportConfig{BatchSize: 4, StateLimit: 16}
+
+	// Import into a chain rejecting the seal of block #3, failing without a checkpoint
+	_, failing, _ := newCanonical(dpos.NewFakeFailer(3), 0, true)
+	defer failing.Stop()
+
+	if n, err := failing.BulkImport(open, config); err == nil || n != 2 {
+		t.Fatalf("untrusted import mismatch: have %d blocks, err %v; want 2 blocks, seal error", n, err)
+	}
+	// Trust block #6 and ensure the import skips the failing seal check
+	_, trusted, _ := newCanonical(dpos.NewFakeFailer(3), 0, true)
+	defer trusted.Stop()
+
+	config.Checkpoint = source.GetBlockByNumber(6).Hash()
+	if n, err := trusted.BulkImport(open, config); err != nil || n != 10 {
+		t.Fatalf("trusted import mismatch: have %d blocks, err %v; want 10 blocks", n, err)
+	}
+	if head := trusted.CurrentBlock().Hash(); head != source.CurrentBlock().Hash() {
+		t.Errorf("head mismatch: have %x, want %x", head, source.CurrentBlock().Hash())
+	}
+	// Reimporting must skip all the known blocks
+	if n, err := trusted.BulkImport(open, config); err != nil || n != 0 {
+		t.Errorf("reimport mismatch: have %d blocks, err %v; want none", n, err)
+	}
+	// A checkpoint missing from the bundle must be rejected
+	config.Checkpoint[0]++
+	if _, err := trusted.BulkImport(open, config); err != errCheckpointMissing {
+		t.Errorf("missing checkpoint error mismatch: have %v, want %v", err, errCheckpointMissing)
+	}
+}
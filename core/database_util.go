@@ -42,9 +42,14 @@ type DatabaseDeleter interface {
 }
 
 var (
-	headHeaderKey = []byte("LastHeader")
-	headBlockKey  = []byte("LastBlock")
-	headFastKey   = []byte("LastFast")
+	headHeaderKey  = []byte("LastHeader")
+	headBlockKey   = []byte("LastBlock")
+	headFastKey    = []byte("LastFast")
+	historyTailKey = []byte("HistoryTail")
+	blobTailKey    = []byte("BlobTail")
+	finalizedKey   = []byte("LastFinalized")
+
+	dirtyShutdownKey = []byte("DirtyShutdown")
 
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`).
 	headerPrefix        = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
@@ -55,6 +60,10 @@ var (
 	blockReceiptsPrefix = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 	lookupPrefix        = []byte("l") // lookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix     = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	internalTxsPrefix   = []byte("c") // internalTxsPrefix + num (uint64 big endian) + hash -> internal value transfers
+	stateDiffPrefix     = []byte("d") // stateDiffPrefix + num (uint64 big endian) + hash -> reverse state diff
+	blobPrefix          = []byte("o") // blobPrefix + hash -> data blob of a blob transaction
+	balanceHistPrefix   = []byte("a") // balanceHistPrefix + address + num (uint64 big endian) -> indexed balance
 
 	preimagePrefix = "secure-key-"              // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
@@ -556,6 +565,13 @@ func WriteChainConfig(db gooladb.Putter, hash common.Hash, cfg *params.ChainConf
 	return db.Put(append(configPrefix, hash[:]...), jsonChainConfig)
 }
 
+// GetChainConfigJSON retrieves the JSON encoded network settings stored for the
+// given genesis hash, or nil if none are stored.
+func GetChainConfigJSON(db DatabaseReader, hash common.Hash) []byte {
+	data, _ := db.Get(append(configPrefix, hash[:]...))
+	return data
+}
+
 // GetChainConfig will fetch the network settings based on the given hash.
 func GetChainConfig(db DatabaseReader, hash common.Hash) (*params.ChainConfig, error) {
 	jsonChainConfig, _ := db.Get(append(configPrefix, hash[:]...))
@@ -32,4 +32,16 @@ var (
 	// ErrNonceTooHigh is returned if the nonce of a transaction is higher than the
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
+
+	// ErrBlobFeeTooLow is returned if the blob fee cap of a blob transaction is
+	// below the blob fee of the block it's included in.
+	ErrBlobFeeTooLow = errors.New("blob fee cap too low")
+
+	// ErrUnknownBlobFee is returned if the blob fee of a block can't be derived,
+	// the body of its parent being unavailable.
+	ErrUnknownBlobFee = errors.New("unknown blob fee")
+
+	// ErrTooManyBlockBlobs is returned if the transactions of a block carry more
+	// data blobs than allowed.
+	ErrTooManyBlockBlobs = errors.New("too many blobs in block")
 )
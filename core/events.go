@@ -22,7 +22,26 @@ import (
 )
 
 // TxPreEvent is posted when a transaction enters the transaction pool.
-type TxPreEvent struct{ Tx *types.Transaction }
+type TxPreEvent struct {
+	Tx    *types.Transaction
+	Local bool // Whether the transaction was sent from a local account
+}
+
+// Reasons for dropping a transaction from the transaction pool.
+const (
+	DropUnderpriced = "underpriced" // Priced below the pool minimum, or evicted by better priced ones when full
+	DropReplaced    = "replaced"    // Replaced by a transaction with the same nonce and a higher price
+	DropExpired     = "expired"     // Queued for longer than the pool lifetime
+	DropPoolFull    = "pool-full"   // Evicted to enforce the slot allowances of the pool
+	DropUnpayable   = "unpayable"   // Sender can no longer cover the cost of the transaction
+)
+
+// TxDropEvent is posted when a transaction is evicted from the transaction pool
+// without having been included in a block.
+type TxDropEvent struct {
+	Tx     *types.Transaction
+	Reason string
+}
 
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
 type PendingLogsEvent struct {
@@ -52,3 +71,18 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// ReorgRejectedEvent is posted when a block is refused for reorganising the
+// chain deeper than the configured maximum depth.
+type ReorgRejectedEvent struct {
+	Block *types.Block // Block whose import was rejected
+	Head  *types.Block // Canonical head at the time of the rejection
+	Depth uint64       // Number of canonical blocks the import would have dropped
+}
+
+// StateTouchEvent is posted when a block becomes canonical on import, carrying
+// the accounts and storage slots modified by it.
+type StateTouchEvent struct {
+	Block   *types.Block
+	Touched *TouchedState
+}
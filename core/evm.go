@@ -35,8 +35,19 @@ type ChainContext interface {
 	GetHeader(common.Hash, uint64) *types.Header
 }
 
-// NewEVMContext creates a new context for use in the EVM.
+// NewEVMContext creates a new context for use in the EVM. The blob fee of blob
+// messages is derived from the parent block if the chain can retrieve it, such
+// messages fail otherwise.
 func NewEVMContext(msg Message, header *types.Header, chain ChainContext, author *common.Address) vm.Context {
+	ctx := newEVMContext(msg, header, chain, author)
+	if reader, ok := chain.(BlockReader); ok && isBlobMessage(msg) {
+		ctx.BlobFee, _ = BlobFee(reader, header)
+	}
+	return ctx
+}
+
+// newEVMContext creates a new context for use in the EVM, without a blob fee.
+func newEVMContext(msg Message, header *types.Header, chain ChainContext, author *common.Address) vm.Context {
 	// If we don't have an explicit author (i.e. not mining), extract from the header
 	var beneficiary common.Address
 	if author == nil {
@@ -0,0 +1,139 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/consensus"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/log"
+)
+
+var (
+	// ErrReorgFinalized is returned if importing a block would reorganise the
+	// chain below its finalized block.
+	ErrReorgFinalized = errors.New("reorg below finalized block")
+
+	// ErrRewindFinalized is returned if the chain is attempted to be rewound
+	// below its finalized block.
+	ErrRewindFinalized = errors.New("rewind below finalized block")
+)
+
+// GetFinalizedBlockHash retrieves the hash of the highest finalized block of the
+// canonical chain.
+func GetFinalizedBlockHash(db DatabaseReader) common.Hash {
+	data, _ := db.Get(finalizedKey)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteFinalizedBlockHash stores the hash of the highest finalized block of the
+// canonical chain.
+func WriteFinalizedBlockHash(db gooladb.Putter, hash common.Hash) error {
+	if err := db.Put(finalizedKey, hash.Bytes()); err != nil {
+		log.Crit("Failed to store finalized block's hash", "err", err)
+	}
+	return nil
+}
+
+// CurrentJustified returns the highest justified block of the canonical chain,
+// or nil if the consensus engine provides no finality or none is justified yet.
+func (bc *BlockChain) CurrentJustified() *types.Header {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.justified
+}
+
+// CurrentFinalized returns the highest finalized block of the canonical chain,
+// or nil if the consensus engine provides no finality or none is finalized yet.
+// The chain is never reorganised or rewound below it.
+func (bc *BlockChain) CurrentFinalized() *types.Header {
+	bc.mu.RLock()
+	defer bc.mu.RUnlock()
+
+	return bc.finalized
+}
+
+// loadFinality restores the finalized block from the database, dropping it if
+// it's no longer part of the canonical chain.
+//
+// Note, this method assumes that the chain manager mutex is held!
+func (bc *BlockChain) loadFinality() {
+	bc.justified, bc.finalized = nil, nil
+
+	hash := GetFinalizedBlockHash(bc.db)
+	if hash == (common.Hash{}) {
+		return
+	}
+	header := bc.GetHeaderByHash(hash)
+	if header == nil || GetCanonicalHash(bc.db, header.Number.Uint64()) != hash || header.Number.Uint64() > bc.currentBlock.NumberU64() {
+		return
+	}
+	bc.finalized = header
+}
+
+// updateFinality advances the justified and finalized blocks after importing a
+// new head. A block is finalized once a justified block built upon it by more
+// than two thirds of the producers is justified itself. The finalized block
+// never moves backwards.
+//
+// Note, this method assumes that the chain manager mutex is held!
+func (bc *BlockChain) updateFinality(head *types.Header) {
+	finality, ok := bc.engine.(consensus.Finality)
+	if !ok {
+		return
+	}
+	justified, err := finality.Justified(bc, head)
+	if err != nil || justified == nil {
+		if err != nil {
+			log.Debug("Failed to justify block", "number", head.Number, "hash", head.Hash(), "err", err)
+		}
+		return
+	}
+	bc.justified = justified
+
+	finalized, err := finality.Justified(bc, justified)
+	if err != nil || finalized == nil {
+		return
+	}
+	if bc.finalized != nil && finalized.Number.Cmp(bc.finalized.Number) <= 0 {
+		return
+	}
+	bc.finalized = finalized
+	WriteFinalizedBlockHash(bc.db, finalized.Hash())
+
+	log.Debug("Finalized block", "number", finalized.Number, "hash", finalized.Hash(), "justified", justified.Number)
+}
+
+// belowFinalized returns whether importing the given block would reorganise the
+// chain below its finalized block.
+//
+// Note, this method assumes that the chain manager mutex is held!
+func (bc *BlockChain) belowFinalized(block *types.Block) bool {
+	if bc.finalized == nil || block.ParentHash() == bc.currentBlock.Hash() {
+		return false
+	}
+	// Only walk the fork as deep as the finalized block
+	limit := bc.currentBlock.NumberU64() - bc.finalized.Number.Uint64()
+	return bc.reorgDepth(block, limit+1) > limit
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/goola-team/goola/consensus"
+	"github.com/goola-team/goola/consensus/dpos"
+	"github.com/goola-team/goola/core/types"
+)
+
+// finalityEngine is a fake consensus engine justifying the canonical ancestor a
+// fixed number of blocks below any header.
+type finalityEngine struct {
+	consensus.Engine
+	lag uint64
+}
+
+func (e *finalityEngine) Justified(chain consensus.ChainReader, header *types.Header) (*types.Header, error) {
+	if header.Number.Uint64() < e.lag {
+		return nil, nil
+	}
+	return chain.GetHeaderByNumber(header.Number.Uint64() - e.lag), nil
+}
+
+// Tests that the finalized block advances with the chain, survives restarts and
+// that neither reorgs nor rewinds below it are accepted.
+func TestFinality(t *testing.T) {
+	engine := &finalityEngine{Engine: dpos.NewFaker(), lag: 2}
+	db, blockchain, err := newCanonical(engine, 0, true)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	blocks := makeBlockChain(blockchain.CurrentBlock(), 10, engine, db, 0)
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if justified := blockchain.CurrentJustified(); justified == nil || justified.Number.Uint64() != 8 {
+		t.Fatalf("justified block mismatch: have %v, want 8", justified)
+	}
+	if finalized := blockchain.CurrentFinalized(); finalized == nil || finalized.Hash() != blocks[5].Hash() {
+		t.Fatalf("finalized block mismatch: have %v, want 6", finalized)
+	}
+	// Forks from below the justified block never become canonical, longer or not
+	fork := makeBlockChain(blocks[3], 10, engine, db, 1)
+	if _, err := blockchain.InsertChain(fork); err != nil && err != ErrReorgFinalized {
+		t.Errorf("failed to insert side chain: %v", err)
+	}
+	if head := blockchain.CurrentBlock(); head.Hash() != blocks[9].Hash() {
+		t.Errorf("head mismatch after fork below finalized block: have %v, want 10", head.Number())
+	}
+	if err := blockchain.SetHead(5); err != ErrRewindFinalized {
+		t.Errorf("rewind below finalized block error mismatch: have %v, want %v", err, ErrRewindFinalized)
+	}
+	// Rewinding down to the finalized block keeps it
+	if err := blockchain.SetHead(6); err != nil {
+		t.Fatalf("failed to rewind to finalized block: %v", err)
+	}
+	if finalized := blockchain.CurrentFinalized(); finalized == nil || finalized.Hash() != blocks[5].Hash() {
+		t.Errorf("finalized block lost on rewind: have %v, want 6", finalized)
+	}
+	// Forks from the finalized block are accepted
+	fork = makeBlockChain(blocks[5], 10, engine, db, 2)
+	if _, err := blockchain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to reorg above finalized block: %v", err)
+	}
+	if finalized := blockchain.CurrentFinalized(); finalized == nil || finalized.Hash() != fork[5].Hash() {
+		t.Errorf("finalized block mismatch after reorg: have %v, want 12", finalized)
+	}
+}
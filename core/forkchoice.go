@@ -0,0 +1,150 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/goola-team/goola/consensus"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/params"
+)
+
+// ForkChoice is the rule selecting the canonical chain among competing branches.
+// It's consulted whenever a block or header is imported that is not already the
+// head of the chain.
+type ForkChoice interface {
+	// ReorgNeeded reports whether the chain ending in header should replace the
+	// canonical chain ending in current. Ties must keep the current chain.
+	ReorgNeeded(chain consensus.ChainReader, current, header *types.Header) (bool, error)
+}
+
+// NewForkChoice returns the fork choice rule of the consensus engine configured
+// for the chain: the heaviest chain as weighed by the engine, which with engines
+// providing finality can't revert justified blocks, or the longest chain for
+// clique.
+func NewForkChoice(config *params.ChainConfig, engine consensus.Engine) ForkChoice {
+	if config.Clique != nil {
+		return LongestChain{}
+	}
+	if finality, ok := engine.(consensus.Finality); ok {
+		return NewIrreversibleChain(NewHeaviestChain(engine), finality)
+	}
+	return NewHeaviestChain(engine)
+}
+
+// HeaviestChain selects the chain with the highest total weight, the sum of the
+// weights the engine assigns to its blocks. Engines not weighing blocks count
+// every block as one. This is the total difficulty rule of proof-of-work.
+type HeaviestChain struct {
+	weigher consensus.Weigher
+}
+
+// NewHeaviestChain creates a heaviest chain rule using the weights of the engine.
+func NewHeaviestChain(engine consensus.Engine) *HeaviestChain {
+	weigher, _ := engine.(consensus.Weigher)
+	return &HeaviestChain{weigher: weigher}
+}
+
+// ReorgNeeded implements ForkChoice, comparing the weights of the two branches
+// since their common ancestor.
+func (c *HeaviestChain) ReorgNeeded(chain consensus.ChainReader, current, header *types.Header) (bool, error) {
+	oldBranch, newBranch, _, err := forkBranches(chain, current, header)
+	if err != nil {
+		return false, err
+	}
+	return c.weigh(chain, newBranch).Cmp(c.weigh(chain, oldBranch)) > 0, nil
+}
+
+// weigh returns the total weight of a list of headers.
+func (c *HeaviestChain) weigh(chain consensus.ChainReader, headers []*types.Header) *big.Int {
+	if c.weigher == nil {
+		return big.NewInt(int64(len(headers)))
+	}
+	total := new(big.Int)
+	for _, header := range headers {
+		total.Add(total, c.weigher.Weight(chain, header))
+	}
+	return total
+}
+
+// LongestChain selects the chain with the most blocks.
+type LongestChain struct{}
+
+// ReorgNeeded implements ForkChoice.
+func (LongestChain) ReorgNeeded(chain consensus.ChainReader, current, header *types.Header) (bool, error) {
+	return header.Number.Cmp(current.Number) > 0, nil
+}
+
+// IrreversibleChain wraps a fork choice rule, refusing to revert the blocks that
+// the engine's finality considers justified, i.e. built upon by more than two
+// thirds of the validators of their epoch.
+type IrreversibleChain struct {
+	base     ForkChoice
+	finality consensus.Finality
+}
+
+// NewIrreversibleChain creates an irreversibility aware rule on top of a base one,
+// using the justification of the given finality engine.
+func NewIrreversibleChain(base ForkChoice, finality consensus.Finality) *IrreversibleChain {
+	return &IrreversibleChain{base: base, finality: finality}
+}
+
+// ReorgNeeded implements ForkChoice, rejecting branches forking off before the
+// last irreversible block of the current chain.
+func (c *IrreversibleChain) ReorgNeeded(chain consensus.ChainReader, current, header *types.Header) (bool, error) {
+	if header.ParentHash != current.Hash() {
+		_, _, ancestor, err := forkBranches(chain, current, header)
+		if err != nil {
+			return false, err
+		}
+		lib, err := c.Irreversible(chain, current)
+		if err != nil {
+			return false, err
+		}
+		if lib != nil && ancestor.Number.Cmp(lib.Number) < 0 {
+			return false, nil
+		}
+	}
+	return c.base.ReorgNeeded(chain, current, header)
+}
+
+// Irreversible returns the last irreversible block of the chain ending in head,
+// its highest justified ancestor, or nil if there is none.
+func (c *IrreversibleChain) Irreversible(chain consensus.ChainReader, head *types.Header) (*types.Header, error) {
+	return c.finality.Justified(chain, head)
+}
+
+// forkBranches walks two chains back to their common ancestor, returning the
+// headers of both branches since, newest first, and the ancestor itself.
+func forkBranches(chain consensus.ChainReader, a, b *types.Header) ([]*types.Header, []*types.Header, *types.Header, error) {
+	var branchA, branchB []*types.Header
+	for a.Hash() != b.Hash() {
+		if a.Number.Cmp(b.Number) >= 0 {
+			branchA = append(branchA, a)
+			if a = chain.GetHeader(a.ParentHash, a.Number.Uint64()-1); a == nil {
+				return nil, nil, nil, consensus.ErrUnknownAncestor
+			}
+		} else {
+			branchB = append(branchB, b)
+			if b = chain.GetHeader(b.ParentHash, b.Number.Uint64()-1); b == nil {
+				return nil, nil, nil, consensus.ErrUnknownAncestor
+			}
+		}
+	}
+	return branchA, branchB, a, nil
+}
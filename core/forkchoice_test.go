@@ -0,0 +1,133 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/consensus"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/params"
+)
+
+// forkChoiceReader is a header store for fork choice tests.
+type forkChoiceReader map[common.Hash]*types.Header
+
+func (r forkChoiceReader) Config() *params.ChainConfig                 { return params.TestChainConfig }
+func (r forkChoiceReader) CurrentHeader() *types.Header                { return nil }
+func (r forkChoiceReader) GetHeaderByNumber(uint64) *types.Header      { return nil }
+func (r forkChoiceReader) GetHeaderByHash(h common.Hash) *types.Header { return r[h] }
+func (r forkChoiceReader) GetBlock(common.Hash, uint64) *types.Block   { return nil }
+func (r forkChoiceReader) GetHeader(h common.Hash, n uint64) *types.Header {
+	return r[h]
+}
+
+// extend adds a branch of headers produced by the given addresses on top of the
+// parent, returning its head.
+func (r forkChoiceReader) extend(parent *types.Header, producers ...common.Address) *types.Header {
+	for _, producer := range producers {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, common.Big1),
+			Time:       new(big.Int).Add(parent.Time, common.Big1),
+			Coinbase:   producer,
+		}
+		r[header.Hash()] = header
+		parent = header
+	}
+	return parent
+}
+
+// weighedEngine weighs blocks by their producer.
+type weighedEngine struct {
+	consensus.Engine
+	weights map[common.Address]int64
+}
+
+func (e *weighedEngine) Weight(chain consensus.ChainReader, header *types.Header) *big.Int {
+	return big.NewInt(e.weights[header.Coinbase])
+}
+
+// producerFinality justifies the blocks built upon by a number of distinct
+// producers, counting their descendants by coinbase.
+type producerFinality int
+
+func (f producerFinality) Justified(chain consensus.ChainReader, header *types.Header) (*types.Header, error) {
+	producers := make(map[common.Address]bool)
+	for header.Number.Sign() > 0 && len(producers) < int(f) {
+		producers[header.Coinbase] = true
+		if header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1); header == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+	}
+	return header, nil
+}
+
+func TestForkChoice(t *testing.T) {
+	var (
+		a, b, c = common.HexToAddress("0xa"), common.HexToAddress("0xb"), common.HexToAddress("0xc")
+		reader  = make(forkChoiceReader)
+		genesis = &types.Header{Number: big.NewInt(0), Time: big.NewInt(0)}
+	)
+	reader[genesis.Hash()] = genesis
+
+	// Two light blocks of a versus a single heavy block of b forking off block 1
+	base := reader.extend(genesis, c)
+	long := reader.extend(base, a, a)
+	heavy := reader.extend(base, b)
+
+	longest := LongestChain{}
+	if reorg, _ := longest.ReorgNeeded(reader, heavy, long); !reorg {
+		t.Error("longest chain: longer branch not chosen")
+	}
+	if reorg, _ := longest.ReorgNeeded(reader, long, heavy); reorg {
+		t.Error("longest chain: shorter branch chosen")
+	}
+	heaviest := NewHeaviestChain(&weighedEngine{weights: map[common.Address]int64{a: 1, b: 3, c: 1}})
+	if reorg, err := heaviest.ReorgNeeded(reader, long, heavy); !reorg || err != nil {
+		t.Errorf("heaviest chain: heavier branch not chosen: %v", err)
+	}
+	if reorg, _ := heaviest.ReorgNeeded(reader, heavy, long); reorg {
+		t.Error("heaviest chain: lighter branch chosen")
+	}
+	if reorg, _ := heaviest.ReorgNeeded(reader, long, long); reorg {
+		t.Error("heaviest chain: tie replaced current chain")
+	}
+	if _, err := heaviest.ReorgNeeded(reader, long, &types.Header{Number: big.NewInt(5), ParentHash: common.HexToHash("0xdead")}); err != consensus.ErrUnknownAncestor {
+		t.Errorf("heaviest chain: unknown ancestor error mismatch: have %v", err)
+	}
+	// With three validators, blocks built upon by a, b and c are irreversible
+	irreversible := NewIrreversibleChain(LongestChain{}, producerFinality(3))
+
+	final := reader.extend(base, a, b, c, a)
+	if lib, err := irreversible.Irreversible(reader, final); err != nil || lib == nil || lib.Number.Uint64() != 2 {
+		t.Fatalf("irreversible block mismatch: have %v, want 2: %v", lib, err)
+	}
+	if lib, _ := irreversible.Irreversible(reader, long); lib == nil || lib.Number.Sign() != 0 {
+		t.Errorf("irreversible block found without enough producers: %v", lib)
+	}
+	longer := reader.extend(base, c, c, c, c)
+	if reorg, _ := irreversible.ReorgNeeded(reader, final, longer); reorg {
+		t.Error("irreversible block reverted")
+	}
+	extension := reader.extend(reader[final.ParentHash], a, a, a)
+	if reorg, _ := irreversible.ReorgNeeded(reader, final, extension); !reorg {
+		t.Error("reorg above the irreversible block refused")
+	}
+}
@@ -148,8 +148,11 @@ func (e *GenesisMismatchError) Error() string {
 //
 // The returned chain configuration is never nil.
 func SetupGenesisBlock(db gooladb.Database, genesis *Genesis) (*params.ChainConfig, common.Hash, error) {
-	if genesis != nil && genesis.Config == nil {
-		return params.AllEthashProtocolChanges, common.Hash{}, errGenesisNoConfig
+	if err := genesis.verify(); err != nil {
+		if err == errGenesisNoConfig {
+			return params.AllEthashProtocolChanges, common.Hash{}, err
+		}
+		return genesis.Config, common.Hash{}, err
 	}
 
 	// Just commit the new block if there is no stored genesis block.
@@ -204,6 +207,71 @@ func SetupGenesisBlock(db gooladb.Database, genesis *Genesis) (*params.ChainConf
 	return newcfg, stored, WriteChainConfig(db, stored, newcfg)
 }
 
+// CheckGenesisBlock validates the genesis block and chain configuration against
+// the database like SetupGenesisBlock, without writing anything. It returns the
+// chain configuration and genesis hash the node would set up.
+func CheckGenesisBlock(db gooladb.Database, genesis *Genesis) (*params.ChainConfig, common.Hash, error) {
+	if err := genesis.verify(); err != nil {
+		return nil, common.Hash{}, err
+	}
+	stored := GetCanonicalHash(db, 0)
+	if (stored == common.Hash{}) {
+		if genesis == nil {
+			genesis = DefaultGenesisBlock()
+		}
+		return genesis.Config, genesis.ToBlock(nil).Hash(), nil
+	}
+	if genesis != nil {
+		if hash := genesis.ToBlock(nil).Hash(); hash != stored {
+			return genesis.Config, hash, &GenesisMismatchError{stored, hash}
+		}
+	}
+	newcfg := genesis.configOrDefault(stored)
+	storedcfg, err := GetChainConfig(db, stored)
+	if err == ErrChainConfigNotFound {
+		// Interrupted genesis write, the config is written on setup
+		return newcfg, stored, nil
+	} else if err != nil {
+		return newcfg, stored, err
+	}
+	if genesis == nil && stored != params.MainnetGenesisHash {
+		return storedcfg, stored, nil
+	}
+	height := GetBlockNumber(db, GetHeadHeaderHash(db))
+	if height == missingNumber {
+		return newcfg, stored, fmt.Errorf("missing block number for head header hash")
+	}
+	if compatErr := storedcfg.CheckCompatible(newcfg, height); compatErr != nil && height != 0 && compatErr.RewindTo != 0 {
+		return newcfg, stored, compatErr
+	}
+	return newcfg, stored, nil
+}
+
+// verify checks the sanity of a user supplied genesis, nil standing for the
+// default one.
+func (g *Genesis) verify() error {
+	if g == nil {
+		return nil
+	}
+	if g.Config == nil {
+		return errGenesisNoConfig
+	}
+	if g.Config.Ethash != nil {
+		if err := g.Config.Ethash.CheckPayouts(); err != nil {
+			return fmt.Errorf("invalid block reward payouts: %v", err)
+		}
+		if err := g.Config.Ethash.CheckWeights(); err != nil {
+			return fmt.Errorf("invalid block weights: %v", err)
+		}
+		if g.Config.Ethash.Rewards != nil {
+			if err := g.Config.Ethash.Rewards.Check(); err != nil {
+				return fmt.Errorf("invalid block reward schedule: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
 func (g *Genesis) configOrDefault(ghash common.Hash) *params.ChainConfig {
 	switch {
 	case g != nil:
@@ -60,8 +60,9 @@ type HeaderChain struct {
 
 	procInterrupt func() bool
 
-	rand   *mrand.Rand
-	engine consensus.Engine
+	rand       *mrand.Rand
+	engine     consensus.Engine
+	forkChoice ForkChoice
 }
 
 // NewHeaderChain creates a new HeaderChain structure.
@@ -88,6 +89,7 @@ func NewHeaderChain(chainDb gooladb.Database, config *params.ChainConfig, engine
 		procInterrupt: procInterrupt,
 		rand:          mrand.New(mrand.NewSource(seed.Int64())),
 		engine:        engine,
+		forkChoice:    NewForkChoice(config, engine),
 	}
 
 	hc.genesisHeader = hc.GetHeaderByNumber(0)
@@ -138,9 +140,12 @@ func (hc *HeaderChain) WriteHeader(header *types.Header) (status WriteStatus, er
 	if err := WriteHeader(hc.chainDb, header); err != nil {
 		log.Crit("Failed to write header content", "err", err)
 	}
-	// Second clause in the if statement reduces the vulnerability to selfish mining.
-	// Please refer to http://www.cs.cornell.edu/~ie53/publications/btcProcFC.pdf
-	if mrand.Float64() < 0.5 {
+	// Let the fork choice rule decide whether the header becomes the new head
+	reorg, err := hc.forkChoice.ReorgNeeded(hc, hc.currentHeader, header)
+	if err != nil {
+		return NonStatTy, err
+	}
+	if reorg {
 		// Delete any canonical number assignments above the new head
 		for i := number + 1; ; i++ {
 			hash := GetCanonicalHash(hc.chainDb, i)
@@ -402,6 +407,11 @@ func (hc *HeaderChain) SetGenesis(head *types.Header) {
 	hc.genesisHeader = head
 }
 
+// SetForkChoice replaces the rule selecting the canonical chain.
+func (hc *HeaderChain) SetForkChoice(forkChoice ForkChoice) {
+	hc.forkChoice = forkChoice
+}
+
 // Config retrieves the header chain's chain configuration.
 func (hc *HeaderChain) Config() *params.ChainConfig { return hc.config }
 
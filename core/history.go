@@ -0,0 +1,136 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rpc"
+)
+
+const (
+	// minRetainBlocks is the minimum number of recent blocks whose bodies and
+	// receipts are retained, keeping shallow reorgs possible.
+	minRetainBlocks = triesInMemory
+
+	// historyPruneLimit is the maximum number of blocks pruned at once, so that
+	// enabling retention on an existing database doesn't stall block imports.
+	historyPruneLimit = 1024
+)
+
+// PrunedHistoryError is returned when the body or receipts of a block pruned
+// by the history retention policy are requested.
+type PrunedHistoryError struct {
+	Number uint64 // Number of the requested block
+	Tail   uint64 // Number of the oldest block with retained history
+}
+
+func (e *PrunedHistoryError) Error() string {
+	return fmt.Sprintf("history of block #%d pruned, retained since #%d", e.Number, e.Tail)
+}
+
+// Pruned marks the error as caused by locally pruned data.
+func (e *PrunedHistoryError) Pruned() bool { return true }
+
+// ErrorCode implements rpc.Error.
+func (e *PrunedHistoryError) ErrorCode() int { return rpc.ErrCodePruned }
+
+// ErrorData implements rpc.DataError, reporting the oldest block with history.
+func (e *PrunedHistoryError) ErrorData() interface{} {
+	return map[string]interface{}{"block": hexutil.Uint64(e.Number), "tail": hexutil.Uint64(e.Tail)}
+}
+
+// GetHistoryTail retrieves the number of the oldest block whose body and
+// receipts are retained, apart from the genesis block.
+func GetHistoryTail(db DatabaseReader) uint64 {
+	data, _ := db.Get(historyTailKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteHistoryTail stores the number of the oldest block whose body and receipts
+// are retained.
+func WriteHistoryTail(db gooladb.Putter, number uint64) error {
+	if err := db.Put(historyTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store history tail", "err", err)
+	}
+	return nil
+}
+
+// HistoryTail returns the number of the oldest block whose body and receipts
+// are retained, apart from the genesis block.
+func (bc *BlockChain) HistoryTail() uint64 {
+	return GetHistoryTail(bc.db)
+}
+
+// CheckHistory returns a *PrunedHistoryError if the body and receipts of the
+// given block were pruned by the history retention policy, nil otherwise.
+func (bc *BlockChain) CheckHistory(number uint64) error {
+	if tail := bc.HistoryTail(); number > 0 && number < tail {
+		return &PrunedHistoryError{Number: number, Tail: tail}
+	}
+	return nil
+}
+
+// pruneHistory deletes the bodies, receipts and transaction lookups of the
+// canonical blocks which fell out of the retention window after importing the
+// given head. The genesis block and side chains are left intact.
+//
+// Note, this method assumes that the chain manager mutex is held!
+func (bc *BlockChain) pruneHistory(head uint64) {
+	if bc.retainBlocks == 0 || head <= bc.retainBlocks {
+		return
+	}
+	tail, cutoff := GetHistoryTail(bc.db), head-bc.retainBlocks+1
+	if tail == 0 {
+		tail = 1
+	}
+	if tail >= cutoff {
+		return
+	}
+	if cutoff-tail > historyPruneLimit {
+		cutoff = tail + historyPruneLimit
+	}
+	for number := tail; number < cutoff; number++ {
+		hash := GetCanonicalHash(bc.db, number)
+		if hash == (common.Hash{}) {
+			continue
+		}
+		if body := GetBody(bc.db, hash, number); body != nil {
+			for _, tx := range body.Transactions {
+				DeleteTxLookupEntry(bc.db, tx.Hash())
+			}
+			// Blobs can't be found without the body, drop them too
+			deleteTxBlobs(bc.db, body.Transactions)
+		}
+		DeleteBody(bc.db, hash, number)
+		DeleteBlockReceipts(bc.db, hash, number)
+		bc.bodyCache.Remove(hash)
+		bc.bodyRLPCache.Remove(hash)
+		bc.blockCache.Remove(hash)
+	}
+	// Pruning is idempotent, so a crash before the tail is updated is harmless
+	WriteHistoryTail(bc.db, cutoff)
+	log.Debug("Pruned chain history", "from", tail, "to", cutoff-1)
+}
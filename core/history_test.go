@@ -0,0 +1,79 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/gooladb"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// Tests that history pruning drops the bodies, receipts and transaction lookups
+// of blocks outside the retention window while keeping their headers.
+func TestHistoryPruning(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	bodyCache, _ := lru.New(bodyCacheLimit)
+	bodyRLPCache, _ := lru.New(bodyCacheLimit)
+	blockCache, _ := lru.New(blockCacheLimit)
+	bc := &BlockChain{db: db, bodyCache: bodyCache, bodyRLPCache: bodyRLPCache, blockCache: blockCache}
+
+	var blocks []*types.Block
+	for i := int64(0); i < 300; i++ {
+		tx := types.NewTransaction(uint64(i), common.Address{0x01}, big.NewInt(1), 21000, big.NewInt(1), types.TxTypeTransfer, nil)
+		block := types.NewBlock(&types.Header{Number: big.NewInt(i)}, []*types.Transaction{tx}, nil)
+		WriteBlock(db, block)
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		WriteBlockReceipts(db, block.Hash(), block.NumberU64(), types.Receipts{new(types.Receipt)})
+		WriteTxLookupEntries(db, block)
+		blocks = append(blocks, block)
+	}
+	bc.SetHistoryRetention(10)
+	if bc.retainBlocks != minRetainBlocks {
+		t.Fatalf("retention not sanitized: have %d, want %d", bc.retainBlocks, minRetainBlocks)
+	}
+	bc.pruneHistory(299)
+
+	tail := uint64(299 - minRetainBlocks + 1)
+	if have := bc.HistoryTail(); have != tail {
+		t.Fatalf("history tail mismatch: have %d, want %d", have, tail)
+	}
+	for _, block := range blocks {
+		hash, number := block.Hash(), block.NumberU64()
+		pruned := number > 0 && number < tail
+
+		if GetHeader(db, hash, number) == nil {
+			t.Errorf("block #%d: header missing", number)
+		}
+		if (GetBody(db, hash, number) == nil) != pruned {
+			t.Errorf("block #%d: body pruned mismatch, want %v", number, pruned)
+		}
+		if (GetBlockReceipts(db, hash, number) == nil) != pruned {
+			t.Errorf("block #%d: receipts pruned mismatch, want %v", number, pruned)
+		}
+		if tx, _, _, _ := GetTransaction(db, block.Transactions()[0].Hash()); (tx == nil) != pruned {
+			t.Errorf("block #%d: transaction lookup pruned mismatch, want %v", number, pruned)
+		}
+		err := bc.CheckHistory(number)
+		if _, ok := err.(*PrunedHistoryError); ok != pruned {
+			t.Errorf("block #%d: history check mismatch: %v", number, err)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rlp"
+)
+
+// importBufferInterval is the maximum time fast sync data is buffered before
+// being committed to the database.
+const importBufferInterval = time.Minute
+
+var errCorruptImportRecord = errors.New("corrupt import log record")
+
+// importEntry is a single database write buffered by the import buffer.
+type importEntry struct {
+	Key   []byte
+	Value []byte
+}
+
+// importSegment collects the database writes of a single receipt chain import,
+// which are buffered and committed atomically.
+type importSegment struct {
+	entries []importEntry
+	size    int
+}
+
+// Put implements gooladb.Putter, recording a write into the segment.
+func (s *importSegment) Put(key []byte, value []byte) error {
+	s.entries = append(s.entries, importEntry{Key: key, Value: value})
+	s.size += len(key) + len(value)
+	return nil
+}
+
+// importBuffer accumulates the data written by fast sync imports in memory,
+// committing it to the database in large atomic batches instead of many small
+// ones, which considerably speeds up imports on spinning disks.
+//
+// To avoid losing the buffered data on a crash, every segment is appended to a
+// write-ahead log before being buffered. The log is truncated once the buffered
+// data is committed, and replayed into the database on startup if the node went
+// down with data still buffered. Memory backed databases are buffered without
+// a log.
+//
+// The buffered data is invisible to the readers of the database, so the fast
+// sync head it carries is only reported once committed.
+type importBuffer struct {
+	db      gooladb.Database
+	batch   gooladb.Batch
+	limit   int       // Size of the buffered data at which to commit it
+	flushed time.Time // Time the buffered data was last committed
+
+	head     *types.Block       // Fast sync head of the buffered data, nil if none
+	onCommit func(*types.Block) // Callback notified of the fast sync head once committed
+
+	path string   // Location of the write-ahead log, empty if none
+	wal  *os.File // Write-ahead log of the buffered segments
+
+	lock sync.Mutex
+}
+
+// importLogPath returns the location of the write-ahead log of the imports into
+// db, or an empty string if db is not disk backed.
+func importLogPath(db gooladb.Database) string {
+	if ldb, ok := db.(interface{ Path() string }); ok {
+		return ldb.Path() + ".wal"
+	}
+	return ""
+}
+
+// newImportBuffer creates an import buffer committing data to db whenever limit
+// bytes are buffered, notifying onCommit of the fast sync head of the committed
+// data.
+func newImportBuffer(db gooladb.Database, limit int, onCommit func(*types.Block)) (*importBuffer, error) {
+	buf := &importBuffer{
+		db:       db,
+		batch:    db.NewBatch(),
+		limit:    limit,
+		flushed:  time.Now(),
+		onCommit: onCommit,
+		path:     importLogPath(db),
+	}
+	if buf.path != "" {
+		wal, err := os.OpenFile(buf.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return nil, err
+		}
+		buf.wal = wal
+	}
+	return buf, nil
+}
+
+// replayImportLog commits the segments found in the write-ahead log of db, left
+// behind if the node went down with import data still buffered. A torn record
+// at the end of the log, left by a crash while appending, is ignored along with
+// anything following it.
+func replayImportLog(db gooladb.Database) error {
+	path := importLogPath(db)
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		r        = bufio.NewReader(f)
+		batch    = db.NewBatch()
+		segments int
+	)
+	for {
+		entries, err := readImportRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Warn("Discarding torn import log record", "path", path, "segments", segments, "err", err)
+			break
+		}
+		for _, entry := range entries {
+			if err := batch.Put(entry.Key, entry.Value); err != nil {
+				return err
+			}
+		}
+		segments++
+	}
+	if segments == 0 {
+		return nil
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Replayed import write-ahead log", "segments", segments, "size", common.StorageSize(batch.ValueSize()))
+	return nil
+}
+
+// readImportRecord reads a length prefixed, checksummed segment from the log.
+func readImportRecord(r io.Reader) ([]importEntry, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errCorruptImportRecord
+		}
+		return nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header[:4]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, errCorruptImportRecord
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, errCorruptImportRecord
+	}
+	var entries []importEntry
+	if err := rlp.DecodeBytes(payload, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// add logs a segment importing the chain up to head and buffers its writes,
+// committing the buffered data if the size or time limit is reached.
+func (buf *importBuffer) add(segment *importSegment, head *types.Block) error {
+	buf.lock.Lock()
+	defer buf.lock.Unlock()
+
+	if buf.wal != nil {
+		payload, err := rlp.EncodeToBytes(segment.entries)
+		if err != nil {
+			return err
+		}
+		record := make([]byte, 8+len(payload))
+		binary.BigEndian.PutUint32(record[:4], uint32(len(payload)))
+		binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+		copy(record[8:], payload)
+
+		if _, err := buf.wal.Write(record); err != nil {
+			return err
+		}
+		if err := buf.wal.Sync(); err != nil {
+			return err
+		}
+	}
+	for _, entry := range segment.entries {
+		if err := buf.batch.Put(entry.Key, entry.Value); err != nil {
+			return err
+		}
+	}
+	buf.head = head
+
+	if buf.batch.ValueSize() >= buf.limit || time.Since(buf.flushed) >= importBufferInterval {
+		return buf.flush()
+	}
+	return nil
+}
+
+// commit commits all the buffered data into the database.
+func (buf *importBuffer) commit() error {
+	buf.lock.Lock()
+	defer buf.lock.Unlock()
+
+	return buf.flush()
+}
+
+// flush commits the buffered data and truncates the write-ahead log, reporting
+// the fast sync head it carried. The lock must be held.
+func (buf *importBuffer) flush() error {
+	buf.flushed = time.Now()
+	if buf.batch.ValueSize() == 0 {
+		return nil
+	}
+	size := buf.batch.ValueSize()
+	if err := buf.batch.Write(); err != nil {
+		return err
+	}
+	buf.batch.Reset()
+
+	if buf.wal != nil {
+		if err := buf.wal.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := buf.wal.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+	log.Debug("Committed buffered import data", "size", common.StorageSize(size))
+
+	if head := buf.head; head != nil {
+		buf.head = nil
+		if buf.onCommit != nil {
+			buf.onCommit(head)
+		}
+	}
+	return nil
+}
+
+// close commits the buffered data and removes the write-ahead log.
+func (buf *importBuffer) close() error {
+	buf.lock.Lock()
+	defer buf.lock.Unlock()
+
+	if err := buf.flush(); err != nil {
+		return err
+	}
+	if buf.wal != nil {
+		buf.wal.Close()
+		buf.wal = nil
+		return os.Remove(buf.path)
+	}
+	return nil
+}
+
+// commitImports commits any buffered fast sync data into the database, making it
+// visible to the readers of the chain and advancing the fast sync head over it.
+//
+// Note, this method must not be called with the chain manager mutex held!
+func (bc *BlockChain) commitImports() {
+	if bc.imports == nil {
+		return
+	}
+	if err := bc.imports.commit(); err != nil {
+		log.Crit("Failed to commit buffered imports", "err", err)
+	}
+}
+
+// setFastHead advances the head of the fast sync chain once the data imported
+// up to it was committed.
+func (bc *BlockChain) setFastHead(head *types.Block) {
+	bc.mu.Lock()
+	bc.currentFastBlock = head
+	bc.mu.Unlock()
+}
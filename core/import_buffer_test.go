@@ -0,0 +1,127 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/consensus/dpos"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
+)
+
+// Tests that data buffered by the import buffer is not committed before the
+// limit is reached, and that it's recovered from the write-ahead log if the
+// node goes down before committing it, ignoring a torn trailing record.
+func TestImportBufferReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "import-buffer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := gooladb.NewLDBDatabase(dir, 16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	buf, err := newImportBuffer(db, 1024*1024, nil)
+	if err != nil {
+		t.Fatalf("failed to create import buffer: %v", err)
+	}
+	for i := byte(0); i < 3; i++ {
+		segment := new(importSegment)
+		segment.Put([]byte{'k', i}, bytes.Repeat([]byte{i}, 32))
+		if err := buf.add(segment, nil); err != nil {
+			t.Fatalf("failed to buffer segment %d: %v", i, err)
+		}
+	}
+	if ok, _ := db.Has([]byte{'k', 0}); ok {
+		t.Fatalf("buffered data committed before reaching the limit")
+	}
+	// Simulate a crash in the middle of appending a record and replay the log
+	wal, _ := os.OpenFile(buf.path, os.O_WRONLY|os.O_APPEND, 0644)
+	wal.Write([]byte{0x00, 0x00, 0x01, 0x00, 0xde, 0xad})
+	wal.Close()
+
+	if err := replayImportLog(db); err != nil {
+		t.Fatalf("failed to replay import log: %v", err)
+	}
+	for i := byte(0); i < 3; i++ {
+		if have, _ := db.Get([]byte{'k', i}); !bytes.Equal(have, bytes.Repeat([]byte{i}, 32)) {
+			t.Errorf("segment %d: replayed value mismatch: have %x", i, have)
+		}
+	}
+	// Closing the buffer must commit the data and remove the log
+	if err := buf.close(); err != nil {
+		t.Fatalf("failed to close import buffer: %v", err)
+	}
+	if _, err := os.Stat(buf.path); !os.IsNotExist(err) {
+		t.Fatalf("write-ahead log not removed: %v", err)
+	}
+}
+
+// Tests that the fast sync head doesn't advance over receipt chains still held
+// in the import buffer, only once they're committed and readable.
+func TestImportBufferFastHead(t *testing.T) {
+	var (
+		gendb, _ = gooladb.NewMemDatabase()
+		gspec    = &Genesis{Config: params.TestChainConfig}
+		genesis  = gspec.MustCommit(gendb)
+	)
+	blocks, receipts := GenerateChain(gspec.Config, genesis, dpos.NewFaker(), gendb, 8, nil)
+
+	db, _ := gooladb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	cacheConfig := &CacheConfig{TrieNodeLimit: 256, TrieTimeLimit: 5 * time.Minute, ImportBuffer: 1}
+	chain, err := NewBlockChain(db, cacheConfig, gspec.Config, dpos.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if n, err := chain.InsertHeaderChain(headers, 1); err != nil {
+		t.Fatalf("failed to insert header %d: %v", n, err)
+	}
+	if n, err := chain.InsertReceiptChain(blocks, receipts); err != nil {
+		t.Fatalf("failed to insert receipt %d: %v", n, err)
+	}
+	if head := chain.CurrentFastBlock(); head.NumberU64() != 0 {
+		t.Fatalf("fast head advanced over buffered data: have #%d, want #0", head.NumberU64())
+	}
+	chain.commitImports()
+
+	head := chain.CurrentFastBlock()
+	if head.Hash() != blocks[len(blocks)-1].Hash() {
+		t.Fatalf("fast head mismatch after commit: have #%d, want #%d", head.NumberU64(), len(blocks))
+	}
+	if chain.GetBlock(head.Hash(), head.NumberU64()) == nil || chain.GetReceiptsByHash(head.Hash()) == nil {
+		t.Fatalf("fast head data unavailable after commit")
+	}
+}
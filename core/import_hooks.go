@@ -0,0 +1,117 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/metrics"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+var importHookPanicCounter = metrics.NewCounter("chain/hooks/panics")
+
+// ImportHook is a plugin notified synchronously of every block imported into
+// the canonical chain. Hooks run on the import path, so any lengthy processing
+// should be handed off to a background routine.
+type ImportHook interface {
+	// BlockImported is called with the block, its receipts and the accounts and
+	// storage slots it modified, right after it became the canonical head.
+	BlockImported(block *types.Block, receipts types.Receipts, touched *TouchedState)
+}
+
+// ImportHookFunc is an adapter allowing an ordinary function to be used as an
+// import hook.
+type ImportHookFunc func(block *types.Block, receipts types.Receipts, touched *TouchedState)
+
+// BlockImported implements ImportHook, calling f.
+func (f ImportHookFunc) BlockImported(block *types.Block, receipts types.Receipts, touched *TouchedState) {
+	f(block, receipts, touched)
+}
+
+// namedImportHook is a registered import hook along with its latency timer.
+type namedImportHook struct {
+	name  string
+	hook  ImportHook
+	timer gometrics.Timer
+}
+
+// AddImportHook registers a block import hook under the given name, replacing
+// any previous one with the same name. Hooks are run in registration order.
+func (bc *BlockChain) AddImportHook(name string, hook ImportHook) {
+	bc.hooksLock.Lock()
+	defer bc.hooksLock.Unlock()
+
+	// The hook list is copied on write, allowing imports to iterate it unlocked
+	hooks := make([]namedImportHook, 0, len(bc.hooks)+1)
+	for _, h := range bc.hooks {
+		if h.name != name {
+			hooks = append(hooks, h)
+		}
+	}
+	bc.hooks = append(hooks, namedImportHook{name: name, hook: hook, timer: metrics.NewTimer("chain/hooks/" + name)})
+	log.Info("Registered block import hook", "name", name)
+}
+
+// RemoveImportHook unregisters the block import hook with the given name.
+func (bc *BlockChain) RemoveImportHook(name string) error {
+	bc.hooksLock.Lock()
+	defer bc.hooksLock.Unlock()
+
+	for i, h := range bc.hooks {
+		if h.name == name {
+			hooks := make([]namedImportHook, 0, len(bc.hooks)-1)
+			bc.hooks = append(append(hooks, bc.hooks[:i]...), bc.hooks[i+1:]...)
+			log.Info("Unregistered block import hook", "name", name)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown import hook %q", name)
+}
+
+// runImportHooks invokes all registered import hooks for a canonical block. A
+// panicking hook is logged and skipped, it never aborts the import.
+func (bc *BlockChain) runImportHooks(block *types.Block, receipts types.Receipts) {
+	bc.hooksLock.RLock()
+	hooks := bc.hooks
+	bc.hooksLock.RUnlock()
+
+	if len(hooks) == 0 {
+		return
+	}
+	touched := bc.TouchedState(block.Hash())
+	for _, h := range hooks {
+		start := time.Now()
+		runImportHook(h, block, receipts, touched)
+		h.timer.UpdateSince(start)
+	}
+}
+
+// runImportHook invokes a single import hook, recovering from any panic.
+func runImportHook(h namedImportHook, block *types.Block, receipts types.Receipts, touched *TouchedState) {
+	defer func() {
+		if r := recover(); r != nil {
+			importHookPanicCounter.Inc(1)
+			log.Error("Block import hook panicked", "name", h.name, "number", block.Number(), "hash", block.Hash(), "err", r, "stack", string(debug.Stack()))
+		}
+	}()
+	h.hook.BlockImported(block, receipts, touched)
+}
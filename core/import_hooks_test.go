@@ -0,0 +1,64 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// Tests that import hooks are invoked in order with the block's modifications,
+// and that a panicking hook does not prevent the others from running.
+func TestImportHooks(t *testing.T) {
+	touchedCache, _ := lru.New(touchedCacheLimit)
+	bc := &BlockChain{touchedCache: touchedCache}
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})
+	touched := &TouchedState{Accounts: []common.Address{{0x01}}}
+	bc.touchedCache.Add(block.Hash(), touched)
+
+	var calls []string
+	bc.AddImportHook("panicking", ImportHookFunc(func(*types.Block, types.Receipts, *TouchedState) {
+		calls = append(calls, "panicking")
+		panic("boom")
+	}))
+	bc.AddImportHook("indexer", ImportHookFunc(func(b *types.Block, receipts types.Receipts, diff *TouchedState) {
+		if b != block || diff != touched || len(receipts) != 1 {
+			t.Errorf("hook invoked with wrong import details")
+		}
+		calls = append(calls, "indexer")
+	}))
+	bc.runImportHooks(block, types.Receipts{new(types.Receipt)})
+	if len(calls) != 2 || calls[0] != "panicking" || calls[1] != "indexer" {
+		t.Fatalf("hook invocations mismatch: %v", calls)
+	}
+	if err := bc.RemoveImportHook("panicking"); err != nil {
+		t.Fatalf("failed to remove hook: %v", err)
+	}
+	if err := bc.RemoveImportHook("panicking"); err == nil {
+		t.Fatalf("removed unknown hook")
+	}
+	calls = nil
+	bc.runImportHooks(block, types.Receipts{new(types.Receipt)})
+	if len(calls) != 1 || calls[0] != "indexer" {
+		t.Fatalf("hook invocations after removal mismatch: %v", calls)
+	}
+}
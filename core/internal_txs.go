@@ -0,0 +1,202 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rlp"
+)
+
+// InternalTx is a value transfer made by a contract during the execution of a
+// transaction, i.e. a message call or contract creation that does not show up
+// in the block body itself.
+type InternalTx struct {
+	TxHash  common.Hash
+	TxIndex uint
+	Type    string
+	From    common.Address
+	To      common.Address
+	Value   *big.Int
+	Depth   uint
+	Failed  bool
+}
+
+// internalTxTracer is a lightweight vm.Tracer which only inspects the opcodes
+// transferring value between accounts, collecting the internal transactions of
+// a whole block. It relies on the state database to tell transactions apart.
+type internalTxTracer struct {
+	txs     []*InternalTx
+	pending []pendingInternalTx // Calls waiting for their result in the caller frame
+}
+
+// pendingInternalTx is an internal call whose outcome isn't known yet.
+type pendingInternalTx struct {
+	index int // Index of the call within the collected transfers
+	depth int // Depth of the calling frame
+}
+
+// newInternalTxTracer creates a tracer to collect the internal transactions
+// of a single block.
+func newInternalTxTracer() *internalTxTracer {
+	return new(internalTxTracer)
+}
+
+func (t *internalTxTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState records value transferring opcodes, and resolves the outcome of
+// previously recorded calls once execution returns into their calling frame.
+func (t *internalTxTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	// Resolve any calls that have returned into this frame
+	for len(t.pending) > 0 {
+		last := t.pending[len(t.pending)-1]
+		if last.depth < depth {
+			break
+		}
+		t.pending = t.pending[:len(t.pending)-1]
+		if last.depth == depth && len(stack.Data()) > 0 && stack.Back(0).Sign() != 0 {
+			continue
+		}
+		// The call failed or its frame was unwound, everything below is reverted
+		for _, tx := range t.txs[last.index:] {
+			tx.Failed = true
+		}
+	}
+	var (
+		kind  string
+		to    common.Address
+		value *big.Int
+	)
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		if stack.Back(2).Sign() == 0 {
+			return nil
+		}
+		kind, to, value = op.String(), common.BigToAddress(stack.Back(1)), new(big.Int).Set(stack.Back(2))
+	case vm.CREATE:
+		if stack.Back(0).Sign() == 0 {
+			return nil
+		}
+		kind, value = op.String(), new(big.Int).Set(stack.Back(0))
+	case vm.SELFDESTRUCT:
+		balance := env.StateDB.GetBalance(contract.Address())
+		if balance.Sign() == 0 {
+			return nil
+		}
+		kind, to, value = op.String(), common.BigToAddress(stack.Back(0)), new(big.Int).Set(balance)
+	default:
+		return nil
+	}
+	index := 0
+	if indexer, ok := env.StateDB.(interface{ TxIndex() int }); ok {
+		index = indexer.TxIndex()
+	}
+	t.txs = append(t.txs, &InternalTx{
+		TxIndex: uint(index),
+		Type:    kind,
+		From:    contract.Address(),
+		To:      to,
+		Value:   value,
+		Depth:   uint(depth),
+	})
+	if op != vm.SELFDESTRUCT {
+		t.pending = append(t.pending, pendingInternalTx{index: len(t.txs) - 1, depth: depth})
+	}
+	return nil
+}
+
+func (t *internalTxTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+func (t *internalTxTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// result finalises the internal transactions collected while processing the
+// given block, filling in the transaction hashes and reverting the transfers
+// of failed transactions.
+func (t *internalTxTracer) result(block *types.Block, receipts types.Receipts) []*InternalTx {
+	txs := block.Transactions()
+	for _, itx := range t.txs {
+		if int(itx.TxIndex) >= len(txs) {
+			continue
+		}
+		itx.TxHash = txs[itx.TxIndex].Hash()
+		if int(itx.TxIndex) < len(receipts) && receipts[itx.TxIndex].Status == types.ReceiptStatusFailed {
+			itx.Failed = true
+		}
+	}
+	return t.txs
+}
+
+// internalTxRLP is the consensus independent storage encoding of an InternalTx.
+type internalTxRLP struct {
+	TxHash  common.Hash
+	TxIndex uint
+	Type    string
+	From    common.Address
+	To      common.Address
+	Value   *big.Int
+	Depth   uint
+	Failed  bool
+}
+
+// GetInternalTxs retrieves the internal transactions recorded while importing
+// the block with the given hash and number.
+func GetInternalTxs(db DatabaseReader, hash common.Hash, number uint64) []*InternalTx {
+	data, _ := db.Get(append(append(internalTxsPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+	if len(data) == 0 {
+		return nil
+	}
+	var stored []*internalTxRLP
+	if err := rlp.DecodeBytes(data, &stored); err != nil {
+		log.Error("Invalid internal transactions RLP", "hash", hash, "err", err)
+		return nil
+	}
+	txs := make([]*InternalTx, len(stored))
+	for i, tx := range stored {
+		txs[i] = (*InternalTx)(tx)
+	}
+	return txs
+}
+
+// WriteInternalTxs stores the internal transactions of a block.
+func WriteInternalTxs(db gooladb.Putter, hash common.Hash, number uint64, txs []*InternalTx) error {
+	stored := make([]*internalTxRLP, len(txs))
+	for i, tx := range txs {
+		stored[i] = (*internalTxRLP)(tx)
+	}
+	data, err := rlp.EncodeToBytes(stored)
+	if err != nil {
+		return err
+	}
+	return db.Put(append(append(internalTxsPrefix, encodeBlockNumber(number)...), hash.Bytes()...), data)
+}
+
+// DeleteInternalTxs removes the internal transactions of a block.
+func DeleteInternalTxs(db DatabaseDeleter, hash common.Hash, number uint64) {
+	db.Delete(append(append(internalTxsPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+}
@@ -0,0 +1,50 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/gooladb"
+)
+
+// Tests internal transaction storage and retrieval operations.
+func TestInternalTxStorage(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+
+	hash, number := common.Hash{0x01}, uint64(42)
+	if entry := GetInternalTxs(db, hash, number); entry != nil {
+		t.Fatalf("non existent internal transactions returned: %v", entry)
+	}
+	txs := []*InternalTx{
+		{TxHash: common.Hash{0x11}, TxIndex: 0, Type: "CALL", From: common.Address{0x01}, To: common.Address{0x02}, Value: big.NewInt(1000), Depth: 1},
+		{TxHash: common.Hash{0x22}, TxIndex: 3, Type: "CREATE", From: common.Address{0x03}, Value: big.NewInt(1), Depth: 2, Failed: true},
+	}
+	if err := WriteInternalTxs(db, hash, number, txs); err != nil {
+		t.Fatalf("failed to write internal transactions: %v", err)
+	}
+	if entry := GetInternalTxs(db, hash, number); !reflect.DeepEqual(entry, txs) {
+		t.Fatalf("retrieved internal transactions mismatch: have %v, want %v", entry, txs)
+	}
+	DeleteInternalTxs(db, hash, number)
+	if entry := GetInternalTxs(db, hash, number); entry != nil {
+		t.Fatalf("deleted internal transactions returned: %v", entry)
+	}
+}
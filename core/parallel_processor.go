@@ -0,0 +1,397 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"runtime"
+	"sync"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/log"
+)
+
+// accessRecorder is a view of a state database recording the accounts and
+// storage slots a transaction reads and writes, used to detect conflicts between
+// transactions executed in parallel.
+//
+// Balance increases of accounts otherwise not written (e.g. fees paid to the
+// coinbase) commute with other transactions and are recorded separately as
+// deltas, so that they don't cause conflicts by themselves.
+type accessRecorder struct {
+	*state.StateDB
+
+	reads      map[common.Address]struct{}                 // Accounts whose fields were read
+	writes     map[common.Address]struct{}                 // Accounts whose fields were written
+	created    map[common.Address]struct{}                 // Accounts (re)created, resetting their storage
+	slotReads  map[common.Address]map[common.Hash]struct{} // Storage slots read
+	slotWrites map[common.Address]map[common.Hash]struct{} // Storage slots written
+	scans      map[common.Address]struct{}                 // Accounts whose entire storage was iterated
+	deltas     map[common.Address]accountBase              // Accounts credited, with their state before
+}
+
+// accountBase is the state of a credited account before the transaction.
+type accountBase struct {
+	exists  bool
+	balance *big.Int
+}
+
+func newAccessRecorder(statedb *state.StateDB) *accessRecorder {
+	return &accessRecorder{
+		StateDB:    statedb,
+		reads:      make(map[common.Address]struct{}),
+		writes:     make(map[common.Address]struct{}),
+		created:    make(map[common.Address]struct{}),
+		slotReads:  make(map[common.Address]map[common.Hash]struct{}),
+		slotWrites: make(map[common.Address]map[common.Hash]struct{}),
+		scans:      make(map[common.Address]struct{}),
+		deltas:     make(map[common.Address]accountBase),
+	}
+}
+
+func (r *accessRecorder) read(addr common.Address) {
+	r.reads[addr] = struct{}{}
+}
+
+// write records an account as written. Written accounts are merged by copying
+// all their fields, so they are also considered read.
+func (r *accessRecorder) write(addr common.Address) {
+	r.reads[addr] = struct{}{}
+	r.writes[addr] = struct{}{}
+}
+
+// credit records a balance increase (or a zero value touch) of an account.
+func (r *accessRecorder) credit(addr common.Address) {
+	if _, ok := r.deltas[addr]; !ok {
+		r.deltas[addr] = accountBase{exists: r.StateDB.Exist(addr), balance: r.StateDB.GetBalance(addr)}
+	}
+}
+
+func recordSlot(slots map[common.Address]map[common.Hash]struct{}, addr common.Address, key common.Hash) {
+	if slots[addr] == nil {
+		slots[addr] = make(map[common.Hash]struct{})
+	}
+	slots[addr][key] = struct{}{}
+}
+
+func (r *accessRecorder) CreateAccount(addr common.Address) {
+	r.write(addr)
+	r.created[addr] = struct{}{}
+	r.StateDB.CreateAccount(addr)
+}
+
+func (r *accessRecorder) SubBalance(addr common.Address, amount *big.Int) {
+	if amount.Sign() == 0 {
+		r.credit(addr)
+	} else {
+		r.write(addr)
+	}
+	r.StateDB.SubBalance(addr, amount)
+}
+
+func (r *accessRecorder) AddBalance(addr common.Address, amount *big.Int) {
+	r.credit(addr)
+	r.StateDB.AddBalance(addr, amount)
+}
+
+func (r *accessRecorder) GetBalance(addr common.Address) *big.Int {
+	r.read(addr)
+	return r.StateDB.GetBalance(addr)
+}
+
+func (r *accessRecorder) GetNonce(addr common.Address) uint64 {
+	r.read(addr)
+	return r.StateDB.GetNonce(addr)
+}
+
+func (r *accessRecorder) SetNonce(addr common.Address, nonce uint64) {
+	r.write(addr)
+	r.StateDB.SetNonce(addr, nonce)
+}
+
+func (r *accessRecorder) GetCodeHash(addr common.Address) common.Hash {
+	r.read(addr)
+	return r.StateDB.GetCodeHash(addr)
+}
+
+func (r *accessRecorder) GetCode(addr common.Address) []byte {
+	r.read(addr)
+	return r.StateDB.GetCode(addr)
+}
+
+func (r *accessRecorder) SetCode(addr common.Address, code []byte) {
+	r.write(addr)
+	r.StateDB.SetCode(addr, code)
+}
+
+func (r *accessRecorder) GetCodeSize(addr common.Address) int {
+	r.read(addr)
+	return r.StateDB.GetCodeSize(addr)
+}
+
+func (r *accessRecorder) GetState(addr common.Address, key common.Hash) common.Hash {
+	recordSlot(r.slotReads, addr, key)
+	return r.StateDB.GetState(addr, key)
+}
+
+func (r *accessRecorder) SetState(addr common.Address, key common.Hash, value common.Hash) {
+	recordSlot(r.slotWrites, addr, key)
+	r.StateDB.SetState(addr, key, value)
+}
+
+func (r *accessRecorder) Suicide(addr common.Address) bool {
+	r.write(addr)
+	return r.StateDB.Suicide(addr)
+}
+
+func (r *accessRecorder) HasSuicided(addr common.Address) bool {
+	r.read(addr)
+	return r.StateDB.HasSuicided(addr)
+}
+
+func (r *accessRecorder) Exist(addr common.Address) bool {
+	r.read(addr)
+	return r.StateDB.Exist(addr)
+}
+
+func (r *accessRecorder) Empty(addr common.Address) bool {
+	r.read(addr)
+	return r.StateDB.Empty(addr)
+}
+
+func (r *accessRecorder) ForEachStorage(addr common.Address, cb func(key, value common.Hash) bool) {
+	r.scans[addr] = struct{}{}
+	r.StateDB.ForEachStorage(addr, cb)
+}
+
+// blockAccesses accumulates the state modified by the transactions of a block
+// merged so far.
+type blockAccesses struct {
+	accounts map[common.Address]struct{}                 // Accounts modified, including credits
+	slots    map[common.Address]map[common.Hash]struct{} // Storage slots modified
+	resets   map[common.Address]struct{}                 // Accounts created or deleted
+}
+
+func newBlockAccesses() *blockAccesses {
+	return &blockAccesses{
+		accounts: make(map[common.Address]struct{}),
+		slots:    make(map[common.Address]map[common.Hash]struct{}),
+		resets:   make(map[common.Address]struct{}),
+	}
+}
+
+// conflicts reports whether a transaction executed on the state before the block
+// accessed any state modified by the transactions merged so far, which would
+// make its execution differ from a serial one.
+func (b *blockAccesses) conflicts(r *accessRecorder) bool {
+	for addr := range r.reads {
+		if _, ok := b.accounts[addr]; ok {
+			return true
+		}
+		if _, ok := b.resets[addr]; ok {
+			return true
+		}
+	}
+	for addr, keys := range r.slotReads {
+		if _, ok := b.resets[addr]; ok {
+			return true
+		}
+		for key := range keys {
+			if _, ok := b.slots[addr][key]; ok {
+				return true
+			}
+		}
+	}
+	for addr := range r.slotWrites {
+		if _, ok := b.resets[addr]; ok {
+			return true
+		}
+	}
+	for addr := range r.scans {
+		if _, ok := b.resets[addr]; ok || len(b.slots[addr]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// add records the state modified by a transaction merged into statedb.
+func (b *blockAccesses) add(r *accessRecorder, statedb *state.StateDB) {
+	for addr := range r.writes {
+		b.accounts[addr] = struct{}{}
+		if !statedb.Exist(addr) {
+			b.resets[addr] = struct{}{}
+		}
+	}
+	for addr := range r.deltas {
+		b.accounts[addr] = struct{}{}
+		if !statedb.Exist(addr) {
+			b.resets[addr] = struct{}{}
+		}
+	}
+	for addr := range r.created {
+		b.resets[addr] = struct{}{}
+	}
+	for addr, keys := range r.slotWrites {
+		for key := range keys {
+			recordSlot(b.slots, addr, key)
+		}
+	}
+}
+
+// parallelResult is the outcome of a transaction executed on its own copy of
+// the state before the block.
+type parallelResult struct {
+	statedb  *state.StateDB
+	accesses *accessRecorder
+	receipt  *types.Receipt
+	err      error
+}
+
+// processParallel executes the transactions of a block optimistically in
+// parallel, each on its own copy of the state before the block, then merges the
+// results into statedb in block order. Transactions which accessed state
+// modified by an earlier transaction of the block, or which failed, are
+// re-executed serially on the merged state, so the outcome is identical to a
+// serial execution.
+func (p *StateProcessor) processParallel(block *types.Block, statedb *state.StateDB, gp *GasPool, usedGas *uint64, blobFee *big.Int, cfg vm.Config) (types.Receipts, []*types.Log, error) {
+	var (
+		header  = block.Header()
+		txs     = block.Transactions()
+		results = make([]parallelResult, len(txs))
+	)
+	author, _ := p.engine.Author(header) // Ignore error, we're past header validation
+	// Execute all the transactions on the pre state concurrently
+	var (
+		pre     = statedb.Copy()
+		pending = make(chan int, len(txs))
+		wg      sync.WaitGroup
+	)
+	for i := range txs {
+		pending <- i
+	}
+	close(pending)
+
+	workers := runtime.NumCPU()
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pending {
+				res := &results[i]
+				res.statedb = pre.Copy()
+				res.statedb.Prepare(txs[i].Hash(), block.Hash(), i)
+				res.accesses = newAccessRecorder(res.statedb)
+
+				used := uint64(0)
+				txgp := new(GasPool).AddGas(block.GasLimit())
+				res.receipt, _, res.err = applyTransaction(p.config, p.bc, &author, txgp, res.statedb, res.accesses, header, txs[i], &used, blobFee, cfg)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Merge the results in order, re-executing the conflicting transactions
+	var (
+		receipts = make(types.Receipts, 0, len(txs))
+		allLogs  []*types.Log
+		modified = newBlockAccesses()
+		serial   int
+	)
+	for i, tx := range txs {
+		res := &results[i]
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+
+		var (
+			receipt *types.Receipt
+			err     error
+		)
+		if res.err != nil || gp.Gas() < tx.Gas() || modified.conflicts(res.accesses) {
+			accesses := newAccessRecorder(statedb)
+			if receipt, _, err = applyTransaction(p.config, p.bc, &author, gp, statedb, accesses, header, tx, usedGas, blobFee, cfg); err != nil {
+				return nil, nil, err
+			}
+			modified.add(accesses, statedb)
+			serial++
+		} else {
+			receipt = res.receipt
+			mergeTransaction(statedb, res.statedb, res.accesses, tx.Hash())
+			gp.SubGas(receipt.GasUsed)
+			*usedGas += receipt.GasUsed
+
+			receipt.CumulativeGasUsed = *usedGas
+			receipt.Logs = statedb.GetLogs(tx.Hash())
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			modified.add(res.accesses, statedb)
+		}
+		receipts = append(receipts, receipt)
+		allLogs = append(allLogs, receipt.Logs...)
+	}
+	log.Debug("Executed block transactions in parallel", "number", block.Number(), "hash", block.Hash(), "txs", len(txs), "reexecuted", serial)
+	return receipts, allLogs, nil
+}
+
+// mergeTransaction applies the modifications made by a transaction executed on
+// its own copy of the state onto statedb, which must not have been modified in
+// any way the transaction depended on.
+func mergeTransaction(statedb, executed *state.StateDB, accesses *accessRecorder, hash common.Hash) {
+	for addr := range accesses.writes {
+		if !executed.Exist(addr) {
+			statedb.Suicide(addr)
+			continue
+		}
+		if _, ok := accesses.created[addr]; ok {
+			statedb.CreateAccount(addr)
+		}
+		statedb.SetBalance(addr, executed.GetBalance(addr))
+		statedb.SetNonce(addr, executed.GetNonce(addr))
+		if executed.GetCodeHash(addr) != statedb.GetCodeHash(addr) {
+			statedb.SetCode(addr, executed.GetCode(addr))
+		}
+	}
+	for addr, keys := range accesses.slotWrites {
+		if !executed.Exist(addr) {
+			continue
+		}
+		for key := range keys {
+			statedb.SetState(addr, key, executed.GetState(addr, key))
+		}
+	}
+	// Credits commute, apply them as deltas unless the account was written anyway
+	for addr, base := range accesses.deltas {
+		if _, ok := accesses.writes[addr]; ok {
+			continue
+		}
+		delta := new(big.Int).Sub(executed.GetBalance(addr), base.balance)
+		if delta.Sign() != 0 || (base.exists && !executed.Exist(addr)) {
+			statedb.AddBalance(addr, delta)
+		}
+	}
+	for _, l := range executed.GetLogs(hash) {
+		statedb.AddLog(l)
+	}
+	for hash, preimage := range executed.Preimages() {
+		statedb.AddPreimage(hash, preimage)
+	}
+	statedb.Finalise(true)
+}
@@ -0,0 +1,131 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/consensus/dpos"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
+)
+
+// Tests that executing blocks in parallel yields exactly the same state and
+// receipts as executing them serially, both for independent transactions and
+// for ones conflicting on balances, nonces, storage slots and destructions.
+func TestParallelExecutionEquivalence(t *testing.T) {
+	var (
+		keys  = make([]*ecdsa.PrivateKey, 8)
+		addrs = make([]common.Address, len(keys))
+		alloc = GenesisAlloc{
+			// SSTORE(0, SLOAD(0)+1): every call conflicts with the previous one
+			common.Address{0xc0}: {Balance: new(big.Int), Code: common.FromHex("0x60005460010160005500")},
+			// SSTORE(CALLER, CALLVALUE): calls by different senders don't conflict
+			common.Address{0xc1}: {Balance: new(big.Int), Code: common.FromHex("0x34335500")},
+			// LOG0 then SELFDESTRUCT(CALLER)
+			common.Address{0xc2}: {Balance: big.NewInt(1000), Code: common.FromHex("0x60006000a033ff")},
+		}
+		config = &params.ChainConfig{ChainId: big.NewInt(1)}
+		signer = types.NewEIP155Signer(config.ChainId)
+		price  = big.NewInt(1)
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		alloc[addrs[i]] = GenesisAccount{Balance: big.NewInt(1000000000)}
+	}
+	gspec := &Genesis{Config: config, GasLimit: 10000000, Alloc: alloc}
+
+	db, _ := gooladb.NewMemDatabase()
+	genesis := gspec.MustCommit(db)
+
+	blocks, receipts := GenerateChain(gspec.Config, genesis, dpos.NewFaker(), db, 4, func(i int, gen *BlockGen) {
+		send := func(from int, to *common.Address, value int64, gas uint64, data []byte) {
+			var tx *types.Transaction
+			if to == nil {
+				tx = types.NewContractCreation(gen.TxNonce(addrs[from]), big.NewInt(value), gas, price, data)
+			} else {
+				tx = types.NewTransaction(gen.TxNonce(addrs[from]), *to, big.NewInt(value), gas, price, types.TxTypeTransfer, data)
+			}
+			tx, _ = types.SignTx(tx, signer, keys[from])
+			gen.AddTx(tx)
+		}
+		counter, mapping, destroy := common.Address{0xc0}, common.Address{0xc1}, common.Address{0xc2}
+		switch i {
+		case 0:
+			// Independent transfers and storage writes
+			for j := 0; j < 4; j++ {
+				send(j, &addrs[j+4], 1000, params.TxGas, nil)
+			}
+			for j := 4; j < 8; j++ {
+				send(j, &mapping, int64(j), 100000, nil)
+			}
+		case 1:
+			// Conflicting transfers: shared recipients and repeated senders
+			send(0, &addrs[1], 1000, params.TxGas, nil)
+			send(1, &addrs[2], 1000, params.TxGas, nil)
+			send(0, &addrs[2], 1000, params.TxGas, nil)
+			send(3, &addrs[2], 1000, params.TxGas, nil)
+			send(4, &addrs[0], 1000, params.TxGas, nil)
+		case 2:
+			// Conflicting storage updates mixed with independent ones
+			for j := 0; j < 4; j++ {
+				send(j, &counter, 0, 100000, nil)
+				send(j+4, &mapping, int64(j+1), 100000, nil)
+			}
+		case 3:
+			// Destruction followed by accesses to the destructed contract
+			send(0, &destroy, 0, 100000, nil)
+			send(1, &destroy, 10, 100000, nil)
+			send(2, &addrs[3], 1000, params.TxGas, nil)
+			send(3, nil, 0, 100000, common.FromHex("0x60006000a000"))
+			send(4, &counter, 0, 100000, nil)
+		}
+	})
+	// Import the chain both serially and in parallel, checking the results
+	for _, parallel := range []bool{false, true} {
+		db, _ := gooladb.NewMemDatabase()
+		gspec.MustCommit(db)
+
+		chain, _ := NewBlockChain(db, nil, gspec.Config, dpos.NewFaker(), vm.Config{})
+		chain.SetParallelExecution(parallel)
+
+		if n, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("parallel %v: failed to insert block %d: %v", parallel, n, err)
+		}
+		for i, block := range blocks {
+			have, want := chain.GetReceiptsByHash(block.Hash()), receipts[i]
+			if len(have) != len(want) {
+				t.Fatalf("parallel %v: block %d receipt count mismatch: have %d, want %d", parallel, i, len(have), len(want))
+			}
+			for j := range have {
+				if have[j].TxHash != want[j].TxHash || have[j].Status != want[j].Status || have[j].GasUsed != want[j].GasUsed ||
+					have[j].CumulativeGasUsed != want[j].CumulativeGasUsed || have[j].ContractAddress != want[j].ContractAddress ||
+					have[j].Bloom != want[j].Bloom || len(have[j].Logs) != len(want[j].Logs) {
+					t.Errorf("parallel %v: block %d receipt %d mismatch: have %+v, want %+v", parallel, i, j, have[j], want[j])
+				}
+			}
+		}
+		chain.Stop()
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/log"
+)
+
+// recoveryCheckNodes is the number of state trie nodes of a head block walked
+// after an unclean shutdown, to detect states only partially flushed to disk.
+const recoveryCheckNodes = 4096
+
+// ReadDirtyShutdown retrieves the unix time the chain was last opened at if it
+// wasn't closed cleanly since, zero otherwise.
+func ReadDirtyShutdown(db DatabaseReader) uint64 {
+	data, _ := db.Get(dirtyShutdownKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteDirtyShutdown marks the chain as opened at the given unix time, to be
+// cleared when it's closed cleanly.
+func WriteDirtyShutdown(db gooladb.Putter, started uint64) {
+	if err := db.Put(dirtyShutdownKey, encodeBlockNumber(started)); err != nil {
+		log.Crit("Failed to store dirty shutdown marker", "err", err)
+	}
+}
+
+// DeleteDirtyShutdown clears the unclean shutdown marker.
+func DeleteDirtyShutdown(db DatabaseDeleter) {
+	if err := db.Delete(dirtyShutdownKey); err != nil {
+		log.Crit("Failed to delete dirty shutdown marker", "err", err)
+	}
+}
+
+// recoverHead checks the consistency of the head block after an unclean shutdown,
+// rewinding the chain to the last block whose data was fully committed.
+func (bc *BlockChain) recoverHead() error {
+	head := bc.CurrentBlock()
+	for head.NumberU64() > 0 {
+		err := bc.checkCommitted(head)
+		if err == nil {
+			break
+		}
+		log.Warn("Discarding partially committed block", "number", head.Number(), "hash", head.Hash(), "err", err)
+		if head = bc.GetBlock(head.ParentHash(), head.NumberU64()-1); head == nil {
+			return errors.New("no fully committed ancestor of the head block")
+		}
+	}
+	if head.Hash() == bc.CurrentBlock().Hash() {
+		log.Info("Chain consistent after unclean shutdown", "number", head.Number(), "hash", head.Hash())
+		return nil
+	}
+	log.Warn("Rolling back to last committed block", "number", head.Number(), "hash", head.Hash())
+	return bc.SetHead(head.NumberU64())
+}
+
+// checkCommitted verifies that the body, receipts and state of a block are all
+// available on disk. The state trie is only walked partially, as that suffices
+// to catch the commits interrupted midway.
+func (bc *BlockChain) checkCommitted(block *types.Block) error {
+	hash, number := block.Hash(), block.NumberU64()
+	if GetBody(bc.db, hash, number) == nil {
+		return errors.New("missing body")
+	}
+	if len(block.Transactions()) > 0 && GetBlockReceipts(bc.db, hash, number) == nil {
+		return errors.New("missing receipts")
+	}
+	tr, err := bc.stateCache.OpenTrie(block.Root())
+	if err != nil {
+		return fmt.Errorf("missing state: %v", err)
+	}
+	it := tr.NodeIterator(nil)
+	for nodes := 0; nodes < recoveryCheckNodes && it.Next(true); nodes++ {
+	}
+	if err := it.Error(); err != nil {
+		return fmt.Errorf("incomplete state: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// Tests that the unclean shutdown marker round trips through the database.
+func TestDirtyShutdownMarker(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	if started := ReadDirtyShutdown(db); started != 0 {
+		t.Fatalf("fresh database marked dirty: %d", started)
+	}
+	WriteDirtyShutdown(db, 1234)
+	if started := ReadDirtyShutdown(db); started != 1234 {
+		t.Fatalf("dirty marker mismatch: have %d, want 1234", started)
+	}
+	DeleteDirtyShutdown(db)
+	if started := ReadDirtyShutdown(db); started != 0 {
+		t.Fatalf("cleared database marked dirty: %d", started)
+	}
+}
+
+// Tests that blocks with states only partially flushed to disk are detected as
+// not fully committed.
+func TestCommittedCheck(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	bodyCache, _ := lru.New(bodyCacheLimit)
+	bodyRLPCache, _ := lru.New(bodyCacheLimit)
+	blockCache, _ := lru.New(blockCacheLimit)
+	bc := &BlockChain{db: db, stateCache: state.NewDatabase(db), bodyCache: bodyCache, bodyRLPCache: bodyRLPCache, blockCache: blockCache}
+
+	// Create a state with enough accounts to span multiple trie nodes
+	statedb, _ := state.New(common.Hash{}, bc.stateCache)
+	for i := 0; i < 64; i++ {
+		statedb.SetBalance(common.BytesToAddress([]byte{byte(i + 1)}), big.NewInt(int64(i+1)))
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := bc.stateCache.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1), Root: root}, nil, nil)
+	if err := bc.checkCommitted(block); err == nil {
+		t.Fatalf("block without body reported committed")
+	}
+	WriteBlock(db, block)
+	if err := bc.checkCommitted(block); err != nil {
+		t.Fatalf("committed block reported inconsistent: %v", err)
+	}
+	// Drop an inner trie node, keeping the root, and ensure it's detected
+	for _, key := range db.Keys() {
+		if len(key) == common.HashLength && common.BytesToHash(key) != root {
+			if value, _ := db.Get(key); crypto.Keccak256Hash(value) == common.BytesToHash(key) {
+				db.Delete(key)
+				break
+			}
+		}
+	}
+	bc.stateCache = state.NewDatabase(db)
+	if err := bc.checkCommitted(block); err == nil {
+		t.Fatalf("partially flushed state reported committed")
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"io"
+	"math/big"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/rlp"
+)
+
+// accountRLP is the encoding of an account in the trie, and so part of the state
+// root consensus is reached on. RLP has no signed integers, so the score is stored
+// as its two's complement byte: non-negative scores encode like the unsigned
+// integer of the same value, while negative ones take the bytes 0x80-0xff.
+//
+// Changing this encoding changes every state root and so forks the network: all
+// nodes have to switch at the same block, and existing databases be resynced.
+//
+// Introducing it needed neither a fork nor a migration. Before, encoding the
+// signed score failed on the first state commit, so no database holds accounts,
+// nor did any network agree on a state root, in another format.
+type accountRLP struct {
+	Nonce      uint64
+	Balance    *big.Int
+	Root       common.Hash
+	CodeHash   []byte
+	Homepage   string
+	Score      uint8
+	Goodsurl   common.Hash
+	Historyurl common.Hash
+	Ordersurl  common.Hash
+}
+
+// EncodeRLP implements rlp.Encoder.
+func (a Account) EncodeRLP(w io.Writer) error {
+	return rlp.Encode(w, &accountRLP{a.Nonce, a.Balance, a.Root, a.CodeHash, a.Homepage, uint8(a.Score), a.Goodsurl, a.Historyurl, a.Ordersurl})
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (a *Account) DecodeRLP(s *rlp.Stream) error {
+	var enc accountRLP
+	if err := s.Decode(&enc); err != nil {
+		return err
+	}
+	*a = Account{enc.Nonce, enc.Balance, enc.Root, enc.CodeHash, enc.Homepage, int8(enc.Score), enc.Goodsurl, enc.Historyurl, enc.Ordersurl}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/rlp"
+)
+
+// Tests that account scores, negative ones included, survive the trie encoding
+// and are stored as their two's complement byte.
+func TestAccountScoreEncoding(t *testing.T) {
+	for _, score := range []int8{0, 1, 127, -1, -128} {
+		account := Account{Balance: big.NewInt(1), CodeHash: emptyCodeHash, Score: score}
+		blob, err := rlp.EncodeToBytes(account)
+		if err != nil {
+			t.Fatalf("score %d: failed to encode account: %v", score, err)
+		}
+		want, _ := rlp.EncodeToBytes(uint8(score))
+		if !bytes.Contains(blob, want) {
+			t.Errorf("score %d: encoding %x lacks two's complement byte %x", score, blob, want)
+		}
+		var decoded Account
+		if err := rlp.DecodeBytes(blob, &decoded); err != nil {
+			t.Fatalf("score %d: failed to decode account: %v", score, err)
+		}
+		if decoded.Score != score {
+			t.Errorf("score mismatch: have %d, want %d", decoded.Score, score)
+		}
+	}
+	// Negative scores must also survive committing and reopening the state
+	db, _ := gooladb.NewMemDatabase()
+	sdb := NewDatabase(db)
+	state, _ := New(common.Hash{}, sdb)
+
+	addr := toAddr([]byte{0x01})
+	state.GetOrNewStateObject(addr).SetScore(-42)
+	root, err := state.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	state, _ = New(root, NewDatabase(db))
+	if obj := state.getStateObject(addr); obj == nil || obj.Score() != -42 {
+		t.Errorf("score lost on commit: have %v, want -42", obj)
+	}
+}
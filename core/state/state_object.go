@@ -211,6 +211,7 @@ func (self *stateObject) SetState(db Database, key, value common.Hash) {
 func (self *stateObject) setState(key, value common.Hash) {
 	self.cachedStorage[key] = value
 	self.dirtyStorage[key] = value
+	self.db.markStorageDirty(self.address, key)
 
 	if self.onDirty != nil {
 		self.onDirty(self.Address())
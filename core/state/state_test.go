@@ -53,7 +53,7 @@ func (s *StateSuite) TestDump(c *checker.C) {
 	// check that dump contains the state objects that are in trie
 	got := string(s.state.Dump())
 	want := `{
-    "root": "71edff0130dd2385947095001c73d9e28d862fc286fca2b922ca6f6f3cddfdd2",
+    "root": "202ddffae3a053d0a7b92e2f480a68075d933d4d92a31c4b26df22b4daf5a72a",
     "accounts": {
         "0000000000000000000000000000000000000001": {
             "balance": "22",
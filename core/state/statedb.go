@@ -26,6 +26,7 @@ import (
 	"github.com/goola-team/goola/common"
 	"github.com/goola-team/goola/core/types"
 	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
 	"github.com/goola-team/goola/log"
 	"github.com/goola-team/goola/rlp"
 	"github.com/goola-team/goola/trie"
@@ -57,6 +58,10 @@ type StateDB struct {
 	stateObjects      map[common.Address]*stateObject
 	stateObjectsDirty map[common.Address]struct{}
 
+	// Storage slots written since the last commit, tracked across transactions
+	// (unlike the objects' dirty storage) to report the slots a block touched.
+	storageDirty map[common.Address]map[common.Hash]struct{}
+
 	// DB error.
 	// State objects are used by the consensus core and VM which are
 	// unable to deal with database-level errors. Any error that occurs
@@ -94,6 +99,7 @@ func New(root common.Hash, db Database) (*StateDB, error) {
 		trie:              tr,
 		stateObjects:      make(map[common.Address]*stateObject),
 		stateObjectsDirty: make(map[common.Address]struct{}),
+		storageDirty:      make(map[common.Address]map[common.Hash]struct{}),
 		logs:              make(map[common.Hash][]*types.Log),
 		preimages:         make(map[common.Hash][]byte),
 	}, nil
@@ -120,6 +126,7 @@ func (self *StateDB) Reset(root common.Hash) error {
 	self.trie = tr
 	self.stateObjects = make(map[common.Address]*stateObject)
 	self.stateObjectsDirty = make(map[common.Address]struct{})
+	self.storageDirty = make(map[common.Address]map[common.Hash]struct{})
 	self.thash = common.Hash{}
 	self.bhash = common.Hash{}
 	self.txIndex = 0
@@ -259,6 +266,12 @@ func (self *StateDB) StorageTrie(a common.Address) Trie {
 	return cpy.updateTrie(self.db)
 }
 
+// ProveAccount writes the Merkle proof of an account's entry in the state trie
+// into proofDb. If the account doesn't exist, the proof of its absence is written.
+func (self *StateDB) ProveAccount(addr common.Address, proofDb gooladb.Putter) error {
+	return self.trie.Prove(crypto.Keccak256(addr[:]), 0, proofDb)
+}
+
 func (self *StateDB) HasSuicided(addr common.Address) bool {
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
@@ -403,6 +416,40 @@ func (self *StateDB) MarkStateObjectDirty(addr common.Address) {
 	self.stateObjectsDirty[addr] = struct{}{}
 }
 
+// DirtyAccounts returns the addresses of the accounts modified since the last
+// commit of the state.
+func (self *StateDB) DirtyAccounts() []common.Address {
+	addrs := make([]common.Address, 0, len(self.stateObjectsDirty))
+	for addr := range self.stateObjectsDirty {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// DirtyStorage returns the storage slots written since the last commit of the
+// state, grouped by account.
+func (self *StateDB) DirtyStorage() map[common.Address][]common.Hash {
+	storage := make(map[common.Address][]common.Hash, len(self.storageDirty))
+	for addr, slots := range self.storageDirty {
+		keys := make([]common.Hash, 0, len(slots))
+		for slot := range slots {
+			keys = append(keys, slot)
+		}
+		storage[addr] = keys
+	}
+	return storage
+}
+
+// markStorageDirty records a storage slot as written.
+func (self *StateDB) markStorageDirty(addr common.Address, slot common.Hash) {
+	slots := self.storageDirty[addr]
+	if slots == nil {
+		slots = make(map[common.Hash]struct{})
+		self.storageDirty[addr] = slots
+	}
+	slots[slot] = struct{}{}
+}
+
 // createObject creates a new state object. If there is an existing account with
 // the given address, it is overwritten and returned as the second return value.
 func (self *StateDB) createObject(addr common.Address) (newobj, prev *stateObject) {
@@ -468,6 +515,7 @@ func (self *StateDB) Copy() *StateDB {
 		trie:              self.db.CopyTrie(self.trie),
 		stateObjects:      make(map[common.Address]*stateObject, len(self.stateObjectsDirty)),
 		stateObjectsDirty: make(map[common.Address]struct{}, len(self.stateObjectsDirty)),
+		storageDirty:      make(map[common.Address]map[common.Hash]struct{}, len(self.storageDirty)),
 		refund:            self.refund,
 		logs:              make(map[common.Hash][]*types.Log, len(self.logs)),
 		logSize:           self.logSize,
@@ -478,6 +526,12 @@ func (self *StateDB) Copy() *StateDB {
 		state.stateObjects[addr] = self.stateObjects[addr].deepCopy(state, state.MarkStateObjectDirty)
 		state.stateObjectsDirty[addr] = struct{}{}
 	}
+	for addr, slots := range self.storageDirty {
+		state.storageDirty[addr] = make(map[common.Hash]struct{}, len(slots))
+		for slot := range slots {
+			state.storageDirty[addr][slot] = struct{}{}
+		}
+	}
 	for hash, logs := range self.logs {
 		state.logs[hash] = make([]*types.Log, len(logs))
 		copy(state.logs[hash], logs)
@@ -554,6 +608,12 @@ func (self *StateDB) Prepare(thash, bhash common.Hash, ti int) {
 	self.txIndex = ti
 }
 
+// TxIndex returns the index of the transaction currently being processed, as
+// set by the last call to Prepare.
+func (self *StateDB) TxIndex() int {
+	return self.txIndex
+}
+
 // DeleteSuicides flags the suicided objects for deletion so that it
 // won't be referenced again when called / queried up on.
 //
@@ -585,6 +645,8 @@ func (s *StateDB) clearJournalAndRefund() {
 func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error) {
 	defer s.clearJournalAndRefund()
 
+	s.storageDirty = make(map[common.Address]map[common.Hash]struct{})
+
 	// Commit objects to the trie.
 	for addr, stateObject := range s.stateObjects {
 		_, isDirty := s.stateObjectsDirty[addr]
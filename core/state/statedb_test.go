@@ -168,6 +168,38 @@ func TestCopy(t *testing.T) {
 	}
 }
 
+// Tests that the written storage slots are tracked even if reverted, and that
+// they are carried over into copies.
+func TestDirtyStorage(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	state, _ := New(common.Hash{}, NewDatabase(db))
+
+	var (
+		addr1 = common.BytesToAddress([]byte{0x01})
+		addr2 = common.BytesToAddress([]byte{0x02})
+	)
+	state.SetState(addr1, common.Hash{0x01}, common.Hash{0xff})
+
+	// Reverted writes still count as touched
+	snap := state.Snapshot()
+	state.SetState(addr1, common.Hash{0x02}, common.Hash{0xff})
+	state.SetState(addr2, common.Hash{0x03}, common.Hash{0xff})
+	state.RevertToSnapshot(snap)
+
+	want := map[common.Address]int{addr1: 2, addr2: 1}
+	for _, st := range []*StateDB{state, state.Copy()} {
+		dirty := st.DirtyStorage()
+		if len(dirty) != len(want) {
+			t.Fatalf("dirty account count mismatch: have %d, want %d", len(dirty), len(want))
+		}
+		for addr, slots := range want {
+			if len(dirty[addr]) != slots {
+				t.Errorf("dirty slot count mismatch for %x: have %d, want %d", addr, len(dirty[addr]), slots)
+			}
+		}
+	}
+}
+
 func TestSnapshotRandom(t *testing.T) {
 	config := &quick.Config{MaxCount: 1000}
 	err := quick.Check((*snapshotTest).run, config)
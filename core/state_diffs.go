@@ -0,0 +1,238 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/consensus"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rlp"
+	"github.com/goola-team/goola/rpc"
+	"github.com/goola-team/goola/trie"
+)
+
+var errMissingStateDiff = errors.New("missing reverse state diff")
+
+// PrunedStateError is returned when the state of a block was pruned and can't
+// be reconstructed from reverse state diffs.
+type PrunedStateError struct {
+	Number uint64 // Number of the block the state was requested of
+	Err    error  // Reason the state can't be reconstructed
+}
+
+func (e *PrunedStateError) Error() string {
+	return fmt.Sprintf("state of block #%d unavailable: %v", e.Number, e.Err)
+}
+
+// Pruned marks the error as caused by locally pruned data.
+func (e *PrunedStateError) Pruned() bool { return true }
+
+// ErrorCode implements rpc.Error.
+func (e *PrunedStateError) ErrorCode() int { return rpc.ErrCodePruned }
+
+// ErrorData implements rpc.DataError.
+func (e *PrunedStateError) ErrorData() interface{} {
+	return map[string]interface{}{"block": hexutil.Uint64(e.Number)}
+}
+
+// StateDiff is the reverse state diff of a block: the values the accounts and
+// storage slots modified by the block held before it was applied. Applying the
+// diff of a block onto its post state yields the state of its parent.
+type StateDiff struct {
+	Accounts []*StateDiffAccount
+}
+
+// StateDiffAccount is the state of a single account before a block.
+type StateDiffAccount struct {
+	Address common.Address
+	Exists  bool // Whether the account existed before the block
+	Nonce   uint64
+	Balance *big.Int
+
+	// Reset is set if the account was destructed or recreated by the block, in
+	// which case Code holds its original code and Storage its entire original
+	// storage. Otherwise Storage only holds the slots modified by the block.
+	Reset   bool
+	Code    []byte
+	Storage []StateDiffSlot
+}
+
+// StateDiffSlot is the value of a storage slot before a block.
+type StateDiffSlot struct {
+	Key   common.Hash
+	Value common.Hash
+}
+
+// newStateDiff creates the reverse diff of the modifications touched made to
+// the pre state, resulting in the post state.
+func newStateDiff(pre, post *state.StateDB, touched *TouchedState) (*StateDiff, error) {
+	diff := &StateDiff{Accounts: make([]*StateDiffAccount, 0, len(touched.Accounts))}
+	for _, addr := range touched.Accounts {
+		account := &StateDiffAccount{Address: addr, Balance: new(big.Int)}
+		diff.Accounts = append(diff.Accounts, account)
+
+		if account.Exists = pre.Exist(addr); !account.Exists {
+			continue
+		}
+		account.Nonce = pre.GetNonce(addr)
+		account.Balance = pre.GetBalance(addr)
+
+		// Without CREATE2, an account can only lose its code by being destructed
+		account.Reset = !post.Exist(addr) || post.GetCodeHash(addr) != pre.GetCodeHash(addr)
+		if !account.Reset {
+			for _, key := range touched.Storage[addr] {
+				account.Storage = append(account.Storage, StateDiffSlot{Key: key, Value: pre.GetState(addr, key)})
+			}
+			continue
+		}
+		account.Code = pre.GetCode(addr)
+
+		storage := pre.StorageTrie(addr)
+		for it := trie.NewIterator(storage.NodeIterator(nil)); it.Next(); {
+			key := storage.GetKey(it.Key)
+			if key == nil {
+				return nil, fmt.Errorf("missing storage key preimage %x of %x", it.Key, addr)
+			}
+			_, content, _, err := rlp.Split(it.Value)
+			if err != nil {
+				return nil, err
+			}
+			account.Storage = append(account.Storage, StateDiffSlot{Key: common.BytesToHash(key), Value: common.BytesToHash(content)})
+		}
+	}
+	return diff, nil
+}
+
+// applyStateDiff reverts the modifications of a block in its post state.
+func applyStateDiff(statedb *state.StateDB, diff *StateDiff) {
+	for _, account := range diff.Accounts {
+		if !account.Exists {
+			statedb.Suicide(account.Address)
+			continue
+		}
+		if account.Reset {
+			statedb.CreateAccount(account.Address)
+			if len(account.Code) > 0 {
+				statedb.SetCode(account.Address, account.Code)
+			}
+		}
+		statedb.SetNonce(account.Address, account.Nonce)
+		statedb.SetBalance(account.Address, account.Balance)
+		for _, slot := range account.Storage {
+			statedb.SetState(account.Address, slot.Key, slot.Value)
+		}
+	}
+	statedb.Finalise(false)
+}
+
+// GetStateDiff retrieves the reverse state diff recorded for a block.
+func GetStateDiff(db DatabaseReader, hash common.Hash, number uint64) *StateDiff {
+	data, _ := db.Get(append(append(stateDiffPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+	if len(data) == 0 {
+		return nil
+	}
+	diff := new(StateDiff)
+	if err := rlp.DecodeBytes(data, diff); err != nil {
+		log.Error("Invalid reverse state diff RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return diff
+}
+
+// WriteStateDiff stores the reverse state diff of a block.
+func WriteStateDiff(db gooladb.Putter, hash common.Hash, number uint64, diff *StateDiff) error {
+	data, err := rlp.EncodeToBytes(diff)
+	if err != nil {
+		return err
+	}
+	return db.Put(append(append(stateDiffPrefix, encodeBlockNumber(number)...), hash.Bytes()...), data)
+}
+
+// DeleteStateDiff removes the reverse state diff of a block.
+func DeleteStateDiff(db DatabaseDeleter, hash common.Hash, number uint64) {
+	db.Delete(append(append(stateDiffPrefix, encodeBlockNumber(number)...), hash.Bytes()...))
+}
+
+// writeStateDiff records the reverse diff of a processed block, given its post
+// state and the modifications it made.
+func (bc *BlockChain) writeStateDiff(db gooladb.Putter, block *types.Block, post *state.StateDB, touched *TouchedState) error {
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	pre, err := state.New(parent.Root, bc.stateCache)
+	if err != nil {
+		return err
+	}
+	diff, err := newStateDiff(pre, post, touched)
+	if err != nil {
+		return err
+	}
+	return WriteStateDiff(db, block.Hash(), block.NumberU64(), diff)
+}
+
+// HistoricState returns the state after the given canonical block. If the state
+// was pruned and reverse state diffs are recorded, it is reconstructed by
+// reverting the diffs of the subsequent blocks, starting from the nearest later
+// state still available.
+func (bc *BlockChain) HistoricState(header *types.Header) (*state.StateDB, error) {
+	statedb, err := bc.StateAt(header.Root)
+	if err == nil {
+		return statedb, nil
+	}
+	number := header.Number.Uint64()
+	if !bc.cacheConfig.StateDiffs {
+		return nil, &PrunedStateError{Number: number, Err: err}
+	}
+	if GetCanonicalHash(bc.db, number) != header.Hash() {
+		return nil, &PrunedStateError{Number: number, Err: errors.New("non-canonical block")}
+	}
+	// Find the nearest later state which was not pruned
+	var (
+		head = bc.CurrentBlock().NumberU64()
+		base uint64
+	)
+	for base = number + 1; base <= head; base++ {
+		if statedb, err = bc.StateAt(bc.GetHeaderByNumber(base).Root); err == nil {
+			break
+		}
+	}
+	if statedb == nil {
+		return nil, &PrunedStateError{Number: number, Err: errors.New("no later state available")}
+	}
+	// Revert the blocks one by one down to the requested one
+	for n := base; n > number; n-- {
+		diff := GetStateDiff(bc.db, GetCanonicalHash(bc.db, n), n)
+		if diff == nil {
+			return nil, &PrunedStateError{Number: number, Err: fmt.Errorf("%v of block #%d", errMissingStateDiff, n)}
+		}
+		applyStateDiff(statedb, diff)
+	}
+	if root := statedb.IntermediateRoot(false); root != header.Root {
+		return nil, fmt.Errorf("reconstructed state root mismatch: have %x, want %x", root, header.Root)
+	}
+	log.Debug("Reconstructed historic state", "number", number, "base", base)
+	return statedb, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/gooladb"
+)
+
+// Tests that applying the reverse diff of a set of modifications onto the post
+// state reproduces the pre state exactly, including destructed contracts.
+func TestStateDiffReversal(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	sdb := state.NewDatabase(db)
+
+	var (
+		plain     = common.Address{0x01}
+		contract  = common.Address{0x02}
+		destroyed = common.Address{0x03}
+		created   = common.Address{0x04}
+	)
+	pre, _ := state.New(common.Hash{}, sdb)
+	pre.SetBalance(plain, big.NewInt(1000))
+	pre.SetNonce(contract, 1)
+	pre.SetCode(contract, []byte{0x60, 0x00})
+	pre.SetState(contract, common.Hash{0x01}, common.Hash{0x11})
+	pre.SetState(contract, common.Hash{0x02}, common.Hash{0x22})
+	pre.SetNonce(destroyed, 1)
+	pre.SetCode(destroyed, []byte{0x60, 0x01})
+	pre.SetState(destroyed, common.Hash{0x01}, common.Hash{0x33})
+	preRoot, _ := pre.Commit(false)
+	sdb.TrieDB().Commit(preRoot, false)
+
+	// Modify every kind of account and record the diff
+	pre, _ = state.New(preRoot, sdb)
+	post, _ := state.New(preRoot, sdb)
+	post.SetBalance(plain, big.NewInt(10))
+	post.SetNonce(plain, 5)
+	post.SetState(contract, common.Hash{0x01}, common.Hash{})
+	post.SetState(contract, common.Hash{0x03}, common.Hash{0x44})
+	post.Suicide(destroyed)
+	post.SetBalance(created, big.NewInt(7))
+	post.IntermediateRoot(true)
+
+	diff, err := newStateDiff(pre, post, newTouchedState(post))
+	if err != nil {
+		t.Fatalf("failed to create diff: %v", err)
+	}
+	postRoot, _ := post.Commit(true)
+	sdb.TrieDB().Commit(postRoot, false)
+
+	// Round trip the diff through the database and revert the post state
+	WriteStateDiff(db, common.Hash{0xff}, 1, diff)
+	if diff = GetStateDiff(db, common.Hash{0xff}, 1); diff == nil {
+		t.Fatalf("failed to retrieve stored diff")
+	}
+	reverted, _ := state.New(postRoot, sdb)
+	applyStateDiff(reverted, diff)
+	if root := reverted.IntermediateRoot(false); root != preRoot {
+		t.Fatalf("reverted state root mismatch: have %x, want %x", root, preRoot)
+	}
+	DeleteStateDiff(db, common.Hash{0xff}, 1)
+	if GetStateDiff(db, common.Hash{0xff}, 1) != nil {
+		t.Fatalf("deleted diff still retrievable")
+	}
+}
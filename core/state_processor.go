@@ -17,6 +17,8 @@
 package core
 
 import (
+	"math/big"
+
 	"github.com/goola-team/goola/common"
 	"github.com/goola-team/goola/consensus"
 	"github.com/goola-team/goola/core/state"
@@ -60,10 +62,31 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		allLogs  []*types.Log
 		gp       = new(GasPool).AddGas(block.GasLimit())
 	)
+	// Derive the blob fee once for all the blob transactions of the block
+	var blobFee *big.Int
+	if p.bc != nil {
+		fee, err := blockBlobFee(p.config, p.bc, block)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		blobFee = fee
+	}
+	// Execute the transactions optimistically in parallel if enabled, unless
+	// they need to be traced one after the other
+	if p.bc != nil && p.bc.parallelExecution() && !cfg.Debug && len(block.Transactions()) > 1 {
+		receipts, allLogs, err := p.processParallel(block, statedb, gp, usedGas, blobFee, cfg)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if _, err := p.engine.Finalize(p.bc, header, statedb, block.Transactions(), receipts); err != nil {
+			return nil, nil, 0, err
+		}
+		return receipts, allLogs, *usedGas, nil
+	}
 	// Iterate over and process the individual transactions
 	for i, tx := range block.Transactions() {
 		statedb.Prepare(tx.Hash(), block.Hash(), i)
-		receipt, _, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg)
+		receipt, _, err := applyTransaction(p.config, p.bc, nil, gp, statedb, statedb, header, tx, usedGas, blobFee, cfg)
 		if err != nil {
 			return nil, nil, 0, err
 		}
@@ -71,7 +94,9 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		allLogs = append(allLogs, receipt.Logs...)
 	}
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
-	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), receipts)
+	if _, err := p.engine.Finalize(p.bc, header, statedb, block.Transactions(), receipts); err != nil {
+		return nil, nil, 0, err
+	}
 
 	return receipts, allLogs, *usedGas, nil
 }
@@ -81,15 +106,31 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 // for the transaction, gas used and an error if the transaction failed,
 // indicating the block was invalid.
 func ApplyTransaction(config *params.ChainConfig, bc *BlockChain, author *common.Address, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, uint64, error) {
+	var blobFee *big.Int
+	if bc != nil && tx.Type() == types.TxTypeBlob && config.IsBlob(header.Number) {
+		fee, err := BlobFee(bc, header)
+		if err != nil {
+			return nil, 0, err
+		}
+		blobFee = fee
+	}
+	return applyTransaction(config, bc, author, gp, statedb, statedb, header, tx, usedGas, blobFee, cfg)
+}
+
+// applyTransaction is ApplyTransaction running the EVM on top of vmdb, a view of
+// statedb which may e.g. record the state accessed by the transaction, charging
+// blob transactions the given blob fee.
+func applyTransaction(config *params.ChainConfig, bc *BlockChain, author *common.Address, gp *GasPool, statedb *state.StateDB, vmdb vm.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, blobFee *big.Int, cfg vm.Config) (*types.Receipt, uint64, error) {
 	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number))
 	if err != nil {
 		return nil, 0, err
 	}
 	// Create a new context to be used in the EVM environment
-	context := NewEVMContext(msg, header, bc, author)
+	context := newEVMContext(msg, header, bc, author)
+	context.BlobFee = blobFee
 	// Create a new environment which holds all relevant information
 	// about the transaction and calling mechanisms.
-	vmenv := vm.NewEVM(context, statedb, config, cfg)
+	vmenv := vm.NewEVM(context, vmdb, config, cfg)
 	// Apply the transaction to the current state (included in the env)
 	_, gas, failed, err := ApplyMessage(vmenv, msg, gp)
 	if err != nil {
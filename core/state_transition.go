@@ -22,6 +22,7 @@ import (
 	"math/big"
 
 	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
 	"github.com/goola-team/goola/core/vm"
 	"github.com/goola-team/goola/log"
 	"github.com/goola-team/goola/params"
@@ -56,6 +57,7 @@ type StateTransition struct {
 	initialGas uint64
 	value      *big.Int
 	data       []byte
+	blobCost   *big.Int // Fee for the carried data blobs, burned (nil = no blobs)
 	state      vm.StateDB
 	evm        *vm.EVM
 }
@@ -75,8 +77,9 @@ type Message interface {
 	Data() []byte
 }
 
-// IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
-func IntrinsicGas(data []byte, contractCreation bool) (uint64, error) {
+// IntrinsicGas computes the 'intrinsic gas' for a message with the given data,
+// pricing it by the given data rules.
+func IntrinsicGas(data []byte, contractCreation bool, rules params.DataRules) (uint64, error) {
 	// Set the starting gas for the raw transaction
 	var gas uint64
 	if contractCreation{
@@ -94,16 +97,16 @@ func IntrinsicGas(data []byte, contractCreation bool) (uint64, error) {
 			}
 		}
 		// Make sure we don't exceed uint64 for all data combinations
-		if (math.MaxUint64-gas)/params.TxDataNonZeroGas < nz {
+		if (math.MaxUint64-gas)/rules.TxDataNonZeroGas < nz {
 			return 0, vm.ErrOutOfGas
 		}
-		gas += nz * params.TxDataNonZeroGas
+		gas += nz * rules.TxDataNonZeroGas
 
 		z := uint64(len(data)) - nz
-		if (math.MaxUint64-gas)/params.TxDataZeroGas < z {
+		if (math.MaxUint64-gas)/rules.TxDataZeroGas < z {
 			return 0, vm.ErrOutOfGas
 		}
-		gas += z * params.TxDataZeroGas
+		gas += z * rules.TxDataZeroGas
 	}
 	return gas, nil
 }
@@ -171,7 +174,11 @@ func (st *StateTransition) buyGas() error {
 		sender = st.from()
 	)
 	mgval := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.gasPrice)
-	if state.GetBalance(sender.Address()).Cmp(mgval) < 0 {
+	cost := mgval
+	if st.blobCost != nil {
+		cost = new(big.Int).Add(mgval, st.blobCost)
+	}
+	if state.GetBalance(sender.Address()).Cmp(cost) < 0 {
 		return errInsufficientBalanceForGas
 	}
 	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
@@ -180,7 +187,30 @@ func (st *StateTransition) buyGas() error {
 	st.gas += st.msg.Gas()
 
 	st.initialGas = st.msg.Gas()
-	state.SubBalance(sender.Address(), mgval)
+	state.SubBalance(sender.Address(), cost)
+	return nil
+}
+
+// prepareBlobs unpacks the payload of blob transactions once they're activated,
+// checking the blob fee cap and computing the blob fee to burn. The call data of
+// the transaction replaces the payload.
+func (st *StateTransition) prepareBlobs() error {
+	if !isBlobMessage(st.msg) || !st.evm.ChainConfig().IsBlob(st.evm.BlockNumber) {
+		return nil
+	}
+	blob, err := types.DecodeBlobTxData(st.msg.Data())
+	if err != nil {
+		return err
+	}
+	fee := st.evm.BlobFee
+	if fee == nil {
+		return ErrUnknownBlobFee
+	}
+	if blob.MaxBlobFee.Cmp(fee) < 0 {
+		return ErrBlobFeeTooLow
+	}
+	st.data = blob.Data
+	st.blobCost = new(big.Int).Mul(fee, big.NewInt(int64(len(blob.BlobHashes))))
 	return nil
 }
 
@@ -197,6 +227,9 @@ func (st *StateTransition) preCheck() error {
 			return ErrNonceTooLow
 		}
 	}
+	if err := st.prepareBlobs(); err != nil {
+		return err
+	}
 	return st.buyGas()
 }
 
@@ -213,7 +246,7 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 	contractCreation := msg.To() == nil
 
 	// Pay intrinsic gas
-	gas, err := IntrinsicGas(st.data, contractCreation)
+	gas, err := IntrinsicGas(st.data, contractCreation, st.evm.ChainConfig().DataRules(st.evm.BlockNumber))
 	if err != nil {
 		return nil, 0, false, err
 	}
@@ -0,0 +1,90 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/log"
+)
+
+// TxPoolState is the read only view of the pool's head state made available to
+// admission validators.
+type TxPoolState interface {
+	GetBalance(addr common.Address) *big.Int
+	GetNonce(addr common.Address) uint64
+	GetCode(addr common.Address) []byte
+	GetState(addr common.Address, key common.Hash) common.Hash
+}
+
+// TxValidator is an external admission check run on every transaction entering
+// the pool, after it passed the built-in validity checks. Returning an error
+// rejects the transaction with that error.
+type TxValidator func(tx *types.Transaction, from common.Address, state TxPoolState) error
+
+// namedTxValidator is a registered admission validator.
+type namedTxValidator struct {
+	name     string
+	validate TxValidator
+}
+
+// AddValidator registers an admission validator under the given name, replacing
+// any previous one with the same name. Validators only apply to transactions
+// added after registration; already pooled transactions are not re-checked.
+func (pool *TxPool) AddValidator(name string, validate TxValidator) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for i, v := range pool.validators {
+		if v.name == name {
+			pool.validators[i].validate = validate
+			return
+		}
+	}
+	pool.validators = append(pool.validators, namedTxValidator{name: name, validate: validate})
+	log.Info("Registered transaction admission validator", "name", name)
+}
+
+// RemoveValidator unregisters the admission validator with the given name.
+func (pool *TxPool) RemoveValidator(name string) error {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for i, v := range pool.validators {
+		if v.name == name {
+			pool.validators = append(pool.validators[:i], pool.validators[i+1:]...)
+			log.Info("Unregistered transaction admission validator", "name", name)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown validator %q", name)
+}
+
+// runValidators runs all registered admission validators in registration
+// order, returning the first rejection. The pool lock must be held.
+func (pool *TxPool) runValidators(tx *types.Transaction, from common.Address) error {
+	for _, v := range pool.validators {
+		if err := v.validate(tx, from, pool.currentState); err != nil {
+			log.Trace("Transaction rejected by validator", "hash", tx.Hash(), "validator", v.name, "err", err)
+			return err
+		}
+	}
+	return nil
+}
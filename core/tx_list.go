@@ -389,6 +389,8 @@ type txPricedList struct {
 	all    *map[common.Hash]*types.Transaction // Pointer to the map of all transactions
 	items  *priceHeap                          // Heap of prices of all the stored transactions
 	stales int                                 // Number of stale price points to (re-heap trigger)
+
+	exempt func(*types.Transaction) bool // Optional filter of transactions never dropped for their price
 }
 
 // newTxPricedList creates a new price-sorted transaction heap.
@@ -441,8 +443,8 @@ func (l *txPricedList) Cap(threshold *big.Int, local *accountSet) types.Transact
 			save = append(save, tx)
 			break
 		}
-		// Non stale transaction found, discard unless local
-		if local.containsTx(tx) {
+		// Non stale transaction found, discard unless local or exempt
+		if local.containsTx(tx) || l.isExempt(tx) {
 			save = append(save, tx)
 		} else {
 			drop = append(drop, tx)
@@ -457,8 +459,8 @@ func (l *txPricedList) Cap(threshold *big.Int, local *accountSet) types.Transact
 // Underpriced checks whether a transaction is cheaper than (or as cheap as) the
 // lowest priced transaction currently being tracked.
 func (l *txPricedList) Underpriced(tx *types.Transaction, local *accountSet) bool {
-	// Local transactions cannot be underpriced
-	if local.containsTx(tx) {
+	// Local and exempt transactions cannot be underpriced
+	if local.containsTx(tx) || l.isExempt(tx) {
 		return false
 	}
 	// Discard stale price points if found at the heap start
@@ -493,8 +495,8 @@ func (l *txPricedList) Discard(count int, local *accountSet) types.Transactions
 			l.stales--
 			continue
 		}
-		// Non stale transaction found, discard unless local
-		if local.containsTx(tx) {
+		// Non stale transaction found, discard unless local or exempt
+		if local.containsTx(tx) || l.isExempt(tx) {
 			save = append(save, tx)
 		} else {
 			drop = append(drop, tx)
@@ -506,3 +508,9 @@ func (l *txPricedList) Discard(count int, local *accountSet) types.Transactions
 	}
 	return drop
 }
+
+// isExempt checks whether a transaction is exempt from being dropped for its
+// price.
+func (l *txPricedList) isExempt(tx *types.Transaction) bool {
+	return l.exempt != nil && l.exempt(tx)
+}
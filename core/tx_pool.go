@@ -23,6 +23,7 @@ import (
 	"math/big"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/goola-team/goola/common"
@@ -40,6 +41,9 @@ const (
 	chainHeadChanSize = 10
 	// rmTxChanSize is the size of channel listening to RemovedTransactionEvent.
 	rmTxChanSize = 10
+	// maxQueuedDrops is the number of undelivered drop events kept for slow
+	// subscribers, beyond which the oldest ones are discarded.
+	maxQueuedDrops = 4096
 )
 
 var (
@@ -78,6 +82,10 @@ var (
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
 	ErrOversizedData = errors.New("oversized data")
+
+	// ErrTxPoolFrozen is returned if transactions are attempted to be added to
+	// a pool frozen in emergency read-only mode.
+	ErrTxPoolFrozen = errors.New("transaction pool frozen")
 )
 
 var (
@@ -99,8 +107,9 @@ var (
 	queuedNofundsCounter   = metrics.NewCounter("txpool/queued/nofunds")   // Dropped due to out-of-funds
 
 	// General tx metrics
-	invalidTxCounter     = metrics.NewCounter("txpool/invalid")
-	underpricedTxCounter = metrics.NewCounter("txpool/underpriced")
+	invalidTxCounter        = metrics.NewCounter("txpool/invalid")
+	underpricedTxCounter    = metrics.NewCounter("txpool/underpriced")
+	dropEventDiscardCounter = metrics.NewCounter("txpool/dropevents/discard") // Undelivered to slow subscribers
 )
 
 // TxStatus is the current status of a transaction as seen by the pool.
@@ -189,11 +198,13 @@ type TxPool struct {
 	chain        blockChain
 	gasPrice     *big.Int
 	txFeed       event.Feed
+	dropFeed     event.Feed
 	scope        event.SubscriptionScope
 	chainHeadCh  chan ChainHeadEvent
 	chainHeadSub event.Subscription
 	signer       types.Signer
 	mu           sync.RWMutex
+	frozen       int32 // Whether new transactions are refused (atomic)
 
 	currentState  *state.StateDB      // Current state in the blockchain head
 	pendingState  *state.ManagedState // Pending state tracking virtual nonces
@@ -208,6 +219,13 @@ type TxPool struct {
 	all     map[common.Hash]*types.Transaction // All transactions to allow lookups
 	priced  *txPricedList                      // All transactions sorted by price
 
+	validators []namedTxValidator // External admission checks run on new transactions
+
+	drops     []TxDropEvent // Evictions awaiting delivery, in eviction order
+	dropsLock sync.Mutex    // Protects the undelivered evictions
+	dropReq   chan struct{} // Wakes the drop event loop on new evictions
+	quit      chan struct{} // Terminates the drop event loop
+
 	wg sync.WaitGroup // for shutdown sync
 }
 
@@ -229,9 +247,14 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		all:         make(map[common.Hash]*types.Transaction),
 		chainHeadCh: make(chan ChainHeadEvent, chainHeadChanSize),
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
+		dropReq:     make(chan struct{}, 1),
+		quit:        make(chan struct{}),
 	}
 	pool.locals = newAccountSet(pool.signer)
 	pool.priced = newTxPricedList(&pool.all)
+	if chainconfig.GasFree != nil {
+		pool.priced.exempt = pool.gasFreeSender
+	}
 	pool.reset(nil, chain.CurrentBlock().Header())
 
 	// If local transactions and journaling is enabled, load from disk
@@ -248,9 +271,10 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 	// Subscribe events from blockchain
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
 
-	// Start the event loop and return
-	pool.wg.Add(1)
+	// Start the event loops and return
+	pool.wg.Add(2)
 	go pool.loop()
+	go pool.dropLoop()
 
 	return pool
 }
@@ -315,6 +339,7 @@ func (pool *TxPool) loop() {
 				// Any non-locals old enough should be removed
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					for _, tx := range pool.queue[addr].Flatten() {
+						pool.dropped(tx, DropExpired)
 						pool.removeTx(tx.Hash())
 					}
 				}
@@ -433,6 +458,7 @@ func (pool *TxPool) Stop() {
 
 	// Unsubscribe subscriptions registered from blockchain
 	pool.chainHeadSub.Unsubscribe()
+	close(pool.quit)
 	pool.wg.Wait()
 
 	if pool.journal != nil {
@@ -447,6 +473,60 @@ func (pool *TxPool) SubscribeTxPreEvent(ch chan<- TxPreEvent) event.Subscription
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeTxDropEvent registers a subscription of TxDropEvent and starts
+// sending event to the given channel.
+func (pool *TxPool) SubscribeTxDropEvent(ch chan<- TxDropEvent) event.Subscription {
+	return pool.scope.Track(pool.dropFeed.Subscribe(ch))
+}
+
+// dropped queues the notification of a transaction evicted from the pool for the
+// drop event loop. It's called with the pool lock held, so the notifications are
+// delivered in eviction order. If the subscribers fall too far behind, the oldest
+// undelivered notifications are discarded.
+func (pool *TxPool) dropped(tx *types.Transaction, reason string) {
+	pool.dropsLock.Lock()
+	if len(pool.drops) >= maxQueuedDrops {
+		pool.drops = pool.drops[1:]
+		dropEventDiscardCounter.Inc(1)
+	}
+	pool.drops = append(pool.drops, TxDropEvent{Tx: tx, Reason: reason})
+	pool.dropsLock.Unlock()
+
+	select {
+	case pool.dropReq <- struct{}{}:
+	default:
+	}
+}
+
+// dropLoop delivers the queued eviction notifications to the subscribers, all
+// the ones accumulated since the last delivery at once, in order.
+func (pool *TxPool) dropLoop() {
+	defer pool.wg.Done()
+
+	for {
+		select {
+		case <-pool.dropReq:
+			pool.dropsLock.Lock()
+			drops := pool.drops
+			pool.drops = nil
+			pool.dropsLock.Unlock()
+
+			// A blocked send is released by Stop closing the subscriptions,
+			// after which the rest of the batch is abandoned
+			for _, ev := range drops {
+				select {
+				case <-pool.quit:
+					return
+				default:
+				}
+				pool.dropFeed.Send(ev)
+			}
+		case <-pool.quit:
+			return
+		}
+	}
+}
+
 // GasPrice returns the current gas price enforced by the transaction pool.
 func (pool *TxPool) GasPrice() *big.Int {
 	pool.mu.RLock()
@@ -463,11 +543,47 @@ func (pool *TxPool) SetGasPrice(price *big.Int) {
 
 	pool.gasPrice = price
 	for _, tx := range pool.priced.Cap(price, pool.locals) {
+		pool.dropped(tx, DropUnderpriced)
 		pool.removeTx(tx.Hash())
 	}
 	log.Info("Transaction pool price threshold updated", "price", price)
 }
 
+// gasFreeSender checks whether a transaction is sent by an account of the gas-free
+// lane, never dropped for its price. Calls to the gas-free targets are accepted
+// regardless of their price too, but anyone may send them, so they are evicted
+// like any other transaction to keep the pool from being filled with them.
+func (pool *TxPool) gasFreeSender(tx *types.Transaction) bool {
+	from, err := types.Sender(pool.signer, tx)
+	if err != nil {
+		return false
+	}
+	return pool.chainconfig.IsGasFreeSender(from)
+}
+
+// SetLimits updates the runtime adjustable limits of the transaction pool: the
+// price bump, the slot and queue allowances and the lifetime of queued
+// transactions. Any transactions exceeding the new allowances are evicted.
+func (pool *TxPool) SetLimits(config TxPoolConfig) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.config.PriceBump = config.PriceBump
+	pool.config.AccountSlots = config.AccountSlots
+	pool.config.GlobalSlots = config.GlobalSlots
+	pool.config.AccountQueue = config.AccountQueue
+	pool.config.GlobalQueue = config.GlobalQueue
+	pool.config.Lifetime = config.Lifetime
+	pool.config = pool.config.sanitize()
+
+	// Enforce the new allowances on the current pool contents
+	pool.promoteExecutables(nil)
+
+	log.Info("Transaction pool limits updated", "pricebump", pool.config.PriceBump,
+		"accountslots", pool.config.AccountSlots, "globalslots", pool.config.GlobalSlots,
+		"accountqueue", pool.config.AccountQueue, "globalqueue", pool.config.GlobalQueue, "lifetime", pool.config.Lifetime)
+}
+
 // State returns the virtual managed state of the transaction pool.
 func (pool *TxPool) State() *state.ManagedState {
 	pool.mu.RLock()
@@ -549,8 +665,11 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
-	// Heuristic limit, reject transactions over 32KB to prevent DOS attacks
-	if tx.Size() > 32*1024 {
+	// Reject transactions over the size limit of the next block to prevent DOS
+	// attacks, the limit defaulting to a 32KB heuristic without a data policy
+	next := new(big.Int).Add(pool.chain.CurrentBlock().Number(), common.Big1)
+	rules := pool.chainconfig.DataRules(next)
+	if uint64(tx.Size()) > rules.MaxTxSize {
 		return ErrOversizedData
 	}
 	// Transactions can't be negative. This may never happen using RLP decoded
@@ -569,26 +688,36 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	}
 	// Drop non-local transactions under our own minimal accepted gas price
 	local = local || pool.locals.contains(from) // account may be local even if the transaction arrived from the network
-	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
+	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 && !pool.chainconfig.IsGasFree(from, tx.To()) {
 		return ErrUnderpriced
 	}
 	// Ensure the transaction adheres to nonce ordering
 	if pool.currentState.GetNonce(from) > tx.Nonce() {
 		return ErrNonceTooLow
 	}
+	// Unpack blob transactions once activated, their fee caps adding to the costs
+	cost, data := tx.Cost(), tx.Data()
+	if tx.Type() == types.TxTypeBlob && pool.chainconfig.IsBlob(next) {
+		blob, err := tx.BlobTxData()
+		if err != nil {
+			return err
+		}
+		cost = new(big.Int).Add(cost, new(big.Int).Mul(blob.MaxBlobFee, big.NewInt(int64(len(blob.BlobHashes)))))
+		data = blob.Data
+	}
 	// Transactor should have enough funds to cover the costs
-	// cost == V + GP * GL
-	if pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
+	// cost == V + GP * GL (+ blob fee cap * blobs)
+	if pool.currentState.GetBalance(from).Cmp(cost) < 0 {
 		return ErrInsufficientFunds
 	}
-	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil)
+	intrGas, err := IntrinsicGas(data, tx.To() == nil, rules)
 	if err != nil {
 		return err
 	}
 	if tx.Gas() < intrGas {
 		return ErrIntrinsicGas
 	}
-	return nil
+	return pool.runValidators(tx, from)
 }
 
 // add validates a transaction and inserts it into the non-executable queue for
@@ -600,6 +729,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 // whitelisted, preventing any associated transaction from being dropped out of
 // the pool due to pricing constraints.
 func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
+	// Refuse all new transactions while frozen
+	if atomic.LoadInt32(&pool.frozen) == 1 {
+		return false, ErrTxPoolFrozen
+	}
 	// If the transaction is already known, discard it
 	hash := tx.Hash()
 	if pool.all[hash] != nil {
@@ -625,6 +758,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "price", tx.GasPrice())
 			underpricedTxCounter.Inc(1)
+			pool.dropped(tx, DropUnderpriced)
 			pool.removeTx(tx.Hash())
 		}
 	}
@@ -642,6 +776,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 			delete(pool.all, old.Hash())
 			pool.priced.Removed()
 			pendingReplaceCounter.Inc(1)
+			pool.dropped(old, DropReplaced)
 		}
 		pool.all[tx.Hash()] = tx
 		pool.priced.Put(tx)
@@ -650,7 +785,7 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
 
 		// We've directly injected a replacement transaction, notify subsystems
-		go pool.txFeed.Send(TxPreEvent{tx})
+		go pool.txFeed.Send(TxPreEvent{Tx: tx, Local: local || pool.locals.contains(from)})
 
 		return old != nil, nil
 	}
@@ -689,6 +824,7 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 		delete(pool.all, old.Hash())
 		pool.priced.Removed()
 		queuedReplaceCounter.Inc(1)
+		pool.dropped(old, DropReplaced)
 	}
 	pool.all[hash] = tx
 	pool.priced.Put(tx)
@@ -724,6 +860,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingDiscardCounter.Inc(1)
+		pool.dropped(tx, DropUnderpriced)
 		return
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -732,6 +869,7 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 		pool.priced.Removed()
 
 		pendingReplaceCounter.Inc(1)
+		pool.dropped(old, DropReplaced)
 	}
 	// Failsafe to work around direct pending inserts (tests)
 	if pool.all[hash] == nil {
@@ -742,7 +880,17 @@ func (pool *TxPool) promoteTx(addr common.Address, hash common.Hash, tx *types.T
 	pool.beats[addr] = time.Now()
 	pool.pendingState.SetNonce(addr, tx.Nonce()+1)
 
-	go pool.txFeed.Send(TxPreEvent{tx})
+	go pool.txFeed.Send(TxPreEvent{Tx: tx, Local: pool.locals.contains(addr)})
+}
+
+// SetFrozen freezes or unfreezes the pool. A frozen pool keeps its contents, but
+// refuses adding any new transactions with ErrTxPoolFrozen.
+func (pool *TxPool) SetFrozen(frozen bool) {
+	if frozen {
+		atomic.StoreInt32(&pool.frozen, 1)
+	} else {
+		atomic.StoreInt32(&pool.frozen, 0)
+	}
 }
 
 // AddLocal enqueues a single transaction into the pool if it is valid, marking
@@ -930,6 +1078,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			delete(pool.all, hash)
 			pool.priced.Removed()
 			queuedNofundsCounter.Inc(1)
+			pool.dropped(tx, DropUnpayable)
 		}
 		// Gather all executable transactions and promote them
 		for _, tx := range list.Ready(pool.pendingState.GetNonce(addr)) {
@@ -944,6 +1093,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 				delete(pool.all, hash)
 				pool.priced.Removed()
 				queuedRateLimitCounter.Inc(1)
+				pool.dropped(tx, DropPoolFull)
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 		}
@@ -988,6 +1138,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 							hash := tx.Hash()
 							delete(pool.all, hash)
 							pool.priced.Removed()
+							pool.dropped(tx, DropPoolFull)
 
 							// Update the account nonce to the dropped transaction
 							if nonce := tx.Nonce(); pool.pendingState.GetNonce(offenders[i]) > nonce {
@@ -1010,6 +1161,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 						hash := tx.Hash()
 						delete(pool.all, hash)
 						pool.priced.Removed()
+						pool.dropped(tx, DropPoolFull)
 
 						// Update the account nonce to the dropped transaction
 						if nonce := tx.Nonce(); pool.pendingState.GetNonce(addr) > nonce {
@@ -1048,6 +1200,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Drop all transactions if they are less than the overflow
 			if size := uint64(list.Len()); size <= drop {
 				for _, tx := range list.Flatten() {
+					pool.dropped(tx, DropPoolFull)
 					pool.removeTx(tx.Hash())
 				}
 				drop -= size
@@ -1057,6 +1210,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 			// Otherwise drop only last few transactions
 			txs := list.Flatten()
 			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+				pool.dropped(txs[i], DropPoolFull)
 				pool.removeTx(txs[i].Hash())
 				drop--
 				queuedRateLimitCounter.Inc(1)
@@ -1088,6 +1242,7 @@ func (pool *TxPool) demoteUnexecutables() {
 			delete(pool.all, hash)
 			pool.priced.Removed()
 			pendingNofundsCounter.Inc(1)
+			pool.dropped(tx, DropUnpayable)
 		}
 		for _, tx := range invalids {
 			hash := tx.Hash()
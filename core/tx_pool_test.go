@@ -18,6 +18,7 @@ package core
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -261,6 +262,47 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that registered admission validators are consulted after the built-in
+// checks and can be removed again.
+func TestTransactionValidators(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	sign := func(nonce uint64, gaslimit uint64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(100), gaslimit, big.NewInt(1), types.TxTypeTransfer, nil), pool.signer, key)
+		return tx
+	}
+	tx := sign(0, 100000)
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(0xffffffffffffff))
+
+	errNotAllowed := errors.New("sender not allowed")
+	pool.AddValidator("allowlist", func(tx *types.Transaction, sender common.Address, state TxPoolState) error {
+		if sender != from || state.GetBalance(sender).Sign() == 0 {
+			t.Errorf("validator invoked with wrong context")
+		}
+		return errNotAllowed
+	})
+	if err := pool.AddRemote(tx); err != errNotAllowed {
+		t.Fatalf("validator rejection mismatch: have %v, want %v", err, errNotAllowed)
+	}
+	// Built-in checks must run before any validator
+	if err := pool.AddRemote(sign(1, 100)); err != ErrIntrinsicGas {
+		t.Fatalf("built-in rejection mismatch: have %v, want %v", err, ErrIntrinsicGas)
+	}
+	if err := pool.RemoveValidator("allowlist"); err != nil {
+		t.Fatalf("failed to remove validator: %v", err)
+	}
+	if err := pool.RemoveValidator("allowlist"); err == nil {
+		t.Fatalf("removed unknown validator")
+	}
+	if err := pool.AddRemote(tx); err != nil {
+		t.Fatalf("failed to add transaction after removing validator: %v", err)
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
@@ -1369,6 +1411,75 @@ func TestTransactionPoolUnderpricing(t *testing.T) {
 	}
 }
 
+// Tests that the transactions of the gas-free lane are accepted regardless of
+// their price, but that only those of the allowlisted senders are protected from
+// eviction, calls to the allowlisted targets being sendable by anyone.
+func TestTransactionPoolGasFree(t *testing.T) {
+	t.Parallel()
+
+	var (
+		sender, _   = crypto.GenerateKey()
+		caller, _   = crypto.GenerateKey()
+		spammer, _  = crypto.GenerateKey()
+		payer, _    = crypto.GenerateKey()
+		target      = common.Address{0xaa}
+		chainconfig = *params.TestChainConfig
+	)
+	chainconfig.GasFree = &params.GasFreeConfig{
+		Senders: []common.Address{crypto.PubkeyToAddress(sender.PublicKey)},
+		Targets: []common.Address{target},
+	}
+	db, _ := gooladb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.GlobalSlots = 2
+	config.GlobalQueue = 2
+
+	pool := NewTxPool(config, &chainconfig, blockchain)
+	defer pool.Stop()
+
+	for _, key := range []*ecdsa.PrivateKey{sender, caller, spammer, payer} {
+		pool.currentState.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+	}
+	transfer := func(nonce uint64, to common.Address, price int64, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, to, big.NewInt(1), 21000, big.NewInt(price), types.TxTypeTransfer, nil), pool.signer, key)
+		return tx
+	}
+	// Free transactions are only accepted from the senders or to the targets
+	free := transfer(0, common.Address{}, 0, sender)
+	if err := pool.AddRemote(free); err != nil {
+		t.Fatalf("failed to add gas-free sender transaction: %v", err)
+	}
+	freeCall := transfer(0, target, 0, caller)
+	if err := pool.AddRemote(freeCall); err != nil {
+		t.Fatalf("failed to add gas-free target call: %v", err)
+	}
+	if err := pool.AddRemote(transfer(0, common.Address{0xbb}, 0, spammer)); err != ErrUnderpriced {
+		t.Fatalf("free transaction outside the lane error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	// Fill up the pool with paying transactions, evicting the free target call
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		if err := pool.AddRemote(transfer(nonce, common.Address{}, 2, payer)); err != nil {
+			t.Fatalf("failed to add paying transaction %d: %v", nonce, err)
+		}
+	}
+	if pool.Get(free.Hash()) == nil {
+		t.Errorf("gas-free sender transaction evicted")
+	}
+	if pool.Get(freeCall.Hash()) != nil {
+		t.Errorf("gas-free target call not evicted")
+	}
+	// Further free target calls can't push paying transactions out of a full pool
+	if err := pool.AddRemote(transfer(0, target, 0, spammer)); err != ErrUnderpriced {
+		t.Errorf("free target call into full pool error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Tests that the pool rejects replacement transactions that don't meet the minimum
 // price bump required.
 func TestTransactionReplacement(t *testing.T) {
@@ -1716,3 +1827,76 @@ func benchmarkPoolBatchInsert(b *testing.B, size int) {
 		pool.AddRemotes(batch)
 	}
 }
+
+// Tests that eviction notifications are delivered in eviction order, even when
+// many transactions are dropped at once.
+func TestTransactionDropEventOrder(t *testing.T) {
+	t.Parallel()
+
+	pool, _ := setupTxPool()
+	defer pool.Stop()
+
+	drops := make(chan TxDropEvent)
+	sub := pool.SubscribeTxDropEvent(drops)
+	defer sub.Unsubscribe()
+
+	var txs []*types.Transaction
+	pool.mu.Lock()
+	for i := uint64(0); i < 64; i++ {
+		tx := types.NewTransaction(i, common.Address{}, big.NewInt(100), 100000, big.NewInt(1), types.TxTypeTransfer, nil)
+		txs = append(txs, tx)
+		pool.dropped(tx, DropPoolFull)
+	}
+	pool.mu.Unlock()
+
+	for i, tx := range txs {
+		select {
+		case ev := <-drops:
+			if ev.Tx.Hash() != tx.Hash() || ev.Reason != DropPoolFull {
+				t.Fatalf("drop event %d mismatch: have nonce %d (%s), want nonce %d", i, ev.Tx.Nonce(), ev.Reason, tx.Nonce())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("drop event %d not delivered", i)
+		}
+	}
+}
+
+// Tests that drop events pile up only to a limit for subscribers not keeping up,
+// discarding the oldest ones, and that such subscribers don't block stopping.
+func TestTransactionDropEventSlowSubscriber(t *testing.T) {
+	t.Parallel()
+
+	pool, _ := setupTxPool()
+
+	drops := make(chan TxDropEvent) // never read
+	pool.SubscribeTxDropEvent(drops)
+
+	var txs []*types.Transaction
+	pool.mu.Lock()
+	for i := uint64(0); i < 2*maxQueuedDrops; i++ {
+		tx := types.NewTransaction(i, common.Address{}, big.NewInt(100), 100000, big.NewInt(1), types.TxTypeTransfer, nil)
+		txs = append(txs, tx)
+		pool.dropped(tx, DropPoolFull)
+	}
+	pool.mu.Unlock()
+
+	pool.dropsLock.Lock()
+	queued := pool.drops
+	pool.dropsLock.Unlock()
+	if len(queued) > maxQueuedDrops {
+		t.Fatalf("queued drop events mismatch: have %d, want at most %d", len(queued), maxQueuedDrops)
+	}
+	if last := queued[len(queued)-1]; last.Tx != txs[len(txs)-1] {
+		t.Fatalf("last queued drop event mismatch: have nonce %d, want %d", last.Tx.Nonce(), txs[len(txs)-1].Nonce())
+	}
+	stopped := make(chan struct{})
+	go func() {
+		pool.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("pool stop blocked by slow drop event subscriber")
+	}
+}
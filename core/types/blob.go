@@ -0,0 +1,120 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/params"
+	"github.com/goola-team/goola/rlp"
+)
+
+var (
+	ErrNotBlobTx     = errors.New("not a blob transaction")
+	ErrNoBlobs       = errors.New("blob transaction without blobs")
+	ErrTooManyBlobs  = errors.New("too many blobs in transaction")
+	ErrBlobTooLarge  = errors.New("blob too large")
+	ErrBlobMismatch  = errors.New("blob doesn't match its commitment")
+	errBlobFeeNeeded = errors.New("blob transaction without fee cap")
+)
+
+// BlobTxData is the payload of a blob transaction. The transaction commits to
+// the hashes of the blobs it carries, which travel and are stored separately
+// from the chain, and are pruned after a retention window.
+type BlobTxData struct {
+	MaxBlobFee *big.Int      // Maximum fee per blob the sender is willing to pay
+	BlobHashes []common.Hash // Keccak256 hashes of the carried blobs
+	Data       []byte        // Call data of the transaction
+}
+
+// NewBlobTransaction creates a transaction carrying the given blobs, committing
+// to their hashes.
+func NewBlobTransaction(nonce uint64, to common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, maxBlobFee *big.Int, blobs [][]byte, data []byte) (*Transaction, error) {
+	payload := &BlobTxData{MaxBlobFee: maxBlobFee, Data: data}
+	for _, blob := range blobs {
+		payload.BlobHashes = append(payload.BlobHashes, BlobHash(blob))
+	}
+	enc, err := rlp.EncodeToBytes(payload)
+	if err != nil {
+		return nil, err
+	}
+	return NewTransaction(nonce, to, amount, gasLimit, gasPrice, TxTypeBlob, enc), nil
+}
+
+// DecodeBlobTxData parses and sanity checks the payload of a blob transaction.
+func DecodeBlobTxData(payload []byte) (*BlobTxData, error) {
+	blob := new(BlobTxData)
+	if err := rlp.DecodeBytes(payload, blob); err != nil {
+		return nil, err
+	}
+	if blob.MaxBlobFee == nil {
+		return nil, errBlobFeeNeeded
+	}
+	if len(blob.BlobHashes) == 0 {
+		return nil, ErrNoBlobs
+	}
+	if len(blob.BlobHashes) > params.MaxBlobsPerTx {
+		return nil, ErrTooManyBlobs
+	}
+	return blob, nil
+}
+
+// BlobTxData returns the parsed payload of a blob transaction.
+func (tx *Transaction) BlobTxData() (*BlobTxData, error) {
+	if tx.data.TxType != TxTypeBlob {
+		return nil, ErrNotBlobTx
+	}
+	return DecodeBlobTxData(tx.data.Payload)
+}
+
+// BlobCount returns the number of blobs a transaction carries, zero for non-blob
+// and malformed blob transactions.
+func (tx *Transaction) BlobCount() int {
+	if tx.data.TxType != TxTypeBlob {
+		return 0
+	}
+	blob, err := DecodeBlobTxData(tx.data.Payload)
+	if err != nil {
+		return 0
+	}
+	return len(blob.BlobHashes)
+}
+
+// BlobHash returns the hash a transaction commits to a blob with.
+func BlobHash(blob []byte) common.Hash {
+	return crypto.Keccak256Hash(blob)
+}
+
+// VerifyBlobs checks that the given blobs are exactly the ones the transaction
+// payload commits to, in order.
+func (blob *BlobTxData) VerifyBlobs(blobs [][]byte) error {
+	if len(blobs) != len(blob.BlobHashes) {
+		return ErrBlobMismatch
+	}
+	for i, data := range blobs {
+		if len(data) > params.MaxBlobSize {
+			return ErrBlobTooLarge
+		}
+		if BlobHash(data) != blob.BlobHashes[i] {
+			return ErrBlobMismatch
+		}
+	}
+	return nil
+}
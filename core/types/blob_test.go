@@ -0,0 +1,71 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/params"
+)
+
+// Tests that blob transactions commit to their blobs and only accept the exact
+// blobs committed to.
+func TestBlobTransaction(t *testing.T) {
+	blobs := [][]byte{[]byte("first blob"), []byte("second blob")}
+	tx, err := NewBlobTransaction(0, common.Address{0x01}, big.NewInt(1), 50000, big.NewInt(1), new(big.Int).SetUint64(params.MinBlobFee), blobs, []byte{0xca, 0xfe})
+	if err != nil {
+		t.Fatalf("failed to create blob transaction: %v", err)
+	}
+	if count := tx.BlobCount(); count != len(blobs) {
+		t.Fatalf("blob count mismatch: have %d, want %d", count, len(blobs))
+	}
+	data, err := tx.BlobTxData()
+	if err != nil {
+		t.Fatalf("failed to decode blob payload: %v", err)
+	}
+	if data.MaxBlobFee.Uint64() != params.MinBlobFee || string(data.Data) != "\xca\xfe" {
+		t.Fatalf("payload mismatch: have fee %v, data %x", data.MaxBlobFee, data.Data)
+	}
+	if err := data.VerifyBlobs(blobs); err != nil {
+		t.Errorf("committed blobs rejected: %v", err)
+	}
+	if err := data.VerifyBlobs([][]byte{blobs[1], blobs[0]}); err != ErrBlobMismatch {
+		t.Errorf("reordered blobs: have %v, want %v", err, ErrBlobMismatch)
+	}
+	if err := data.VerifyBlobs(blobs[:1]); err != ErrBlobMismatch {
+		t.Errorf("missing blob: have %v, want %v", err, ErrBlobMismatch)
+	}
+	oversized, err := NewBlobTransaction(0, common.Address{}, nil, 0, nil, big.NewInt(1), make([][]byte, params.MaxBlobsPerTx+1), nil)
+	if err != nil {
+		t.Fatalf("failed to create oversized transaction: %v", err)
+	}
+	if _, err := oversized.BlobTxData(); err != ErrTooManyBlobs {
+		t.Errorf("oversized transaction: have %v, want %v", err, ErrTooManyBlobs)
+	}
+	if oversized.BlobCount() != 0 {
+		t.Errorf("oversized transaction counted as carrying blobs")
+	}
+	plain := NewTransaction(0, common.Address{}, nil, 0, nil, TxTypeTransfer, nil)
+	if _, err := plain.BlobTxData(); err != ErrNotBlobTx {
+		t.Errorf("plain transaction: have %v, want %v", err, ErrNotBlobTx)
+	}
+	if plain.BlobCount() != 0 {
+		t.Errorf("plain transaction carries blobs")
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/metrics"
+	"github.com/hashicorp/golang-lru"
+)
+
+// senderCacheLimit is the number of recovered transaction senders retained.
+const senderCacheLimit = 65536
+
+var (
+	senderCacheHitMeter  = metrics.NewMeter("types/sendercache/hit")
+	senderCacheMissMeter = metrics.NewMeter("types/sendercache/miss")
+)
+
+// senderCache is a process wide cache of the senders recovered from transaction
+// signatures, keyed by transaction hash. Contrary to the cache embedded in the
+// transactions, it is shared between the distinct instances of the same
+// transaction decoded by the pool, the miner, block import and the RPC APIs,
+// so the signature of a transaction is only recovered once.
+var senderCache, _ = lru.New(senderCacheLimit)
+
+// cachedSender retrieves the sender of a transaction recovered earlier with an
+// equal signer.
+func cachedSender(signer Signer, hash common.Hash) (common.Address, bool) {
+	if cached, ok := senderCache.Get(hash); ok {
+		if sc := cached.(sigCache); sc.signer.Equal(signer) {
+			senderCacheHitMeter.Mark(1)
+			return sc.from, true
+		}
+	}
+	senderCacheMissMeter.Mark(1)
+	return common.Address{}, false
+}
+
+// cacheSender stores the sender recovered from the signature of a transaction.
+func cacheSender(signer Signer, hash common.Hash, from common.Address) {
+	senderCache.Add(hash, sigCache{signer: signer, from: from})
+}
@@ -48,6 +48,7 @@ const (
 	TxTypeVote
 	TxTypeContract
 	TxTypeWitnessa
+	TxTypeBlob // Carries large data blobs, see BlobTxData
 )
 
 
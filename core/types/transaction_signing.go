@@ -61,7 +61,9 @@ func SignTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, err
 //
 // Sender may cache the address, allowing it to be used regardless of
 // signing method. The cache is invalidated if the cached signer does
-// not match the signer used in the current call.
+// not match the signer used in the current call. Senders are also cached
+// process wide by transaction hash, so other instances of the same
+// transaction don't need to recover the signature again.
 func Sender(signer Signer, tx *Transaction) (common.Address, error) {
 	if sc := tx.from.Load(); sc != nil {
 		sigCache := sc.(sigCache)
@@ -72,12 +74,17 @@ func Sender(signer Signer, tx *Transaction) (common.Address, error) {
 			return sigCache.from, nil
 		}
 	}
-
+	hash := tx.Hash()
+	if addr, ok := cachedSender(signer, hash); ok {
+		tx.from.Store(sigCache{signer: signer, from: addr})
+		return addr, nil
+	}
 	addr, err := signer.Sender(tx)
 	if err != nil {
 		return common.Address{}, err
 	}
 	tx.from.Store(sigCache{signer: signer, from: addr})
+	cacheSender(signer, hash, addr)
 	return addr, nil
 }
 
@@ -129,3 +129,36 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+func TestSenderCacheShared(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	signer := NewEIP155Signer(big.NewInt(18))
+	tx, err := SignTx(NewTransaction(0, addr, new(big.Int), 0, new(big.Int), TxTypeTransfer, nil), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Sender(signer, tx); err != nil {
+		t.Fatal(err)
+	}
+	if !senderCache.Contains(tx.Hash()) {
+		t.Fatalf("recovered sender not cached")
+	}
+	// A distinct instance of the same transaction must use the shared cache
+	enc, _ := rlp.EncodeToBytes(tx)
+	decoded := new(Transaction)
+	if err := rlp.DecodeBytes(enc, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if from, ok := cachedSender(signer, decoded.Hash()); !ok || from != addr {
+		t.Fatalf("shared sender mismatch: have %x (cached %v), want %x", from, ok, addr)
+	}
+	if from, err := Sender(signer, decoded); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have %x (%v), want %x", from, err, addr)
+	}
+	// A different signer must not be served from the cache
+	if _, err := Sender(NewEIP155Signer(big.NewInt(19)), decoded); err != ErrInvalidChainId {
+		t.Fatalf("foreign signer error mismatch: have %v, want %v", err, ErrInvalidChainId)
+	}
+}
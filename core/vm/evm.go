@@ -69,6 +69,7 @@ type Context struct {
 	GasLimit    uint64         // Provides information for GASLIMIT
 	BlockNumber *big.Int       // Provides information for NUMBER
 	Time        *big.Int       // Provides information for TIME
+	BlobFee     *big.Int       // Fee per data blob, only set for blob transactions
 }
 
 // EVM is the Goola Virtual Machine base object and provides
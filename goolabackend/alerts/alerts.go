@@ -0,0 +1,242 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package alerts implements a webhook notifier of chain health incidents.
+package alerts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/event"
+	"github.com/goola-team/goola/log"
+)
+
+// Alert types reported to the webhook.
+const (
+	DeepReorg     = "deepReorg"     // The canonical chain was reorganised deeper than the threshold
+	RejectedReorg = "rejectedReorg" // A reorg was refused for exceeding the maximum depth
+	BadBlock      = "badBlock"      // A block failed consensus validation
+	FinalityStall = "finalityStall" // No new chain head arrived for too long
+	LowPeerCount  = "lowPeerCount"  // The peer count dropped below the threshold
+)
+
+// checkInterval is the period of the polled health checks.
+const checkInterval = 10 * time.Second
+
+// Config are the configuration parameters of the alert webhook.
+type Config struct {
+	URL          string        `toml:",omitempty"` // Endpoint the alerts are POSTed to (empty = disabled)
+	Secret       string        `toml:",omitempty"` // Key of the HMAC-SHA256 signature of the payloads
+	Retries      int           // Number of times a failed delivery is retried
+	ReorgDepth   uint64        // Minimum depth of the reorgs alerted about
+	StallTimeout time.Duration // Time without a new chain head alerted about as a stall
+	MinPeers     int           // Peer count below which an alert is raised
+}
+
+// DefaultConfig contains the default alerting thresholds.
+var DefaultConfig = Config{
+	Retries:      3,
+	ReorgDepth:   8,
+	StallTimeout: 5 * time.Minute,
+	MinPeers:     3,
+}
+
+// Chain is the blockchain access needed to monitor its health.
+type Chain interface {
+	CurrentBlock() *types.Block
+	GetBlock(hash common.Hash, number uint64) *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	BadBlocks() ([]core.BadBlockArgs, error)
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+	SubscribeReorgRejectedEvent(ch chan<- core.ReorgRejectedEvent) event.Subscription
+}
+
+// Notifier monitors the chain and the network, posting alerts about incidents
+// to the configured webhook.
+type Notifier struct {
+	config *Config
+	chain  Chain
+	peers  func() int
+
+	hook     *Webhook
+	interval time.Duration
+
+	head     *types.Block         // Last chain head seen, to measure reorgs
+	headTime time.Time            // Time the last chain head was seen
+	stalled  bool                 // Whether the current stall was already reported
+	peersOK  bool                 // Whether the peer count was above the threshold
+	seenBad  map[common.Hash]bool // Bad blocks already reported
+
+	quit chan struct{}
+	done chan struct{}
+}
+
+// New creates an alert notifier monitoring the given chain and peer count.
+func New(config *Config, chain Chain, peers func() int) *Notifier {
+	return &Notifier{
+		config:   config,
+		chain:    chain,
+		peers:    peers,
+		hook:     NewWebhook(config.URL, config.Secret, config.Retries, time.Second),
+		interval: checkInterval,
+		seenBad:  make(map[common.Hash]bool),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start begins monitoring and delivering alerts.
+func (n *Notifier) Start() {
+	n.hook.Start()
+	go n.loop()
+
+	log.Info("Started alert webhook", "url", n.config.URL)
+}
+
+// Stop terminates monitoring, dropping any undelivered alerts.
+func (n *Notifier) Stop() {
+	close(n.quit)
+	<-n.done
+	n.hook.Stop()
+
+	log.Info("Stopped alert webhook")
+}
+
+// loop processes the chain events and runs the periodic health checks.
+func (n *Notifier) loop() {
+	defer close(n.done)
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	headSub := n.chain.SubscribeChainHeadEvent(heads)
+	defer headSub.Unsubscribe()
+
+	rejects := make(chan core.ReorgRejectedEvent, 16)
+	rejectSub := n.chain.SubscribeReorgRejectedEvent(rejects)
+	defer rejectSub.Unsubscribe()
+
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	n.head, n.headTime = n.chain.CurrentBlock(), time.Now()
+	for {
+		select {
+		case ev := <-heads:
+			n.newHead(ev.Block)
+
+		case ev := <-rejects:
+			n.alert(RejectedReorg, fmt.Sprintf("Rejected reorg of depth %d at block #%d", ev.Depth, ev.Block.NumberU64()), map[string]interface{}{
+				"number":     ev.Block.NumberU64(),
+				"hash":       ev.Block.Hash(),
+				"headNumber": ev.Head.NumberU64(),
+				"headHash":   ev.Head.Hash(),
+				"depth":      ev.Depth,
+			})
+
+		case <-ticker.C:
+			n.check()
+
+		case <-headSub.Err():
+			return
+		case <-rejectSub.Err():
+			return
+		case <-n.quit:
+			return
+		}
+	}
+}
+
+// newHead measures the depth of the reorg leading to a new chain head, if any,
+// and alerts about it if deep enough.
+func (n *Notifier) newHead(head *types.Block) {
+	old := n.head
+	n.head, n.headTime, n.stalled = head, time.Now(), false
+	if old == nil {
+		return
+	}
+	// Walk back the old head until reaching the canonical chain
+	ancestor := old
+	for ancestor != nil {
+		if canon := n.chain.GetBlockByNumber(ancestor.NumberU64()); canon != nil && canon.Hash() == ancestor.Hash() {
+			break
+		}
+		if ancestor.NumberU64() == 0 {
+			return
+		}
+		ancestor = n.chain.GetBlock(ancestor.ParentHash(), ancestor.NumberU64()-1)
+	}
+	if ancestor == nil {
+		return
+	}
+	if depth := old.NumberU64() - ancestor.NumberU64(); depth > 0 && depth >= n.config.ReorgDepth {
+		n.alert(DeepReorg, fmt.Sprintf("Chain reorg of depth %d at block #%d", depth, ancestor.NumberU64()), map[string]interface{}{
+			"ancestorNumber": ancestor.NumberU64(),
+			"ancestorHash":   ancestor.Hash(),
+			"oldHeadHash":    old.Hash(),
+			"newHeadNumber":  head.NumberU64(),
+			"newHeadHash":    head.Hash(),
+			"depth":          depth,
+		})
+	}
+}
+
+// check runs the periodic health checks: bad blocks, stalls and peer count.
+func (n *Notifier) check() {
+	if bad, err := n.chain.BadBlocks(); err == nil {
+		for _, block := range bad {
+			if n.seenBad[block.Hash] {
+				continue
+			}
+			n.seenBad[block.Hash] = true
+			n.alert(BadBlock, fmt.Sprintf("Bad block #%d received", block.Header.Number.Uint64()), map[string]interface{}{
+				"number": block.Header.Number.Uint64(),
+				"hash":   block.Hash,
+			})
+		}
+	}
+	if n.config.StallTimeout > 0 && !n.stalled {
+		if elapsed := time.Since(n.headTime); elapsed > n.config.StallTimeout {
+			n.stalled = true
+			n.alert(FinalityStall, fmt.Sprintf("No new block for %v", common.PrettyDuration(elapsed)), map[string]interface{}{
+				"number": n.head.NumberU64(),
+				"hash":   n.head.Hash(),
+			})
+		}
+	}
+	// Only alert about losing peers, not about not having found any yet
+	if n.config.MinPeers > 0 && n.peers != nil {
+		peers := n.peers()
+		switch {
+		case peers >= n.config.MinPeers:
+			n.peersOK = true
+		case n.peersOK:
+			n.peersOK = false
+			n.alert(LowPeerCount, fmt.Sprintf("Peer count dropped to %d", peers), map[string]interface{}{
+				"peers":     peers,
+				"threshold": n.config.MinPeers,
+			})
+		}
+	}
+}
+
+// alert queues an alert for delivery to the webhook.
+func (n *Notifier) alert(typ string, message string, details interface{}) {
+	log.Warn("Raising alert", "type", typ, "message", message)
+	n.hook.Send(&Alert{Type: typ, Time: time.Now().Unix(), Message: message, Details: details})
+}
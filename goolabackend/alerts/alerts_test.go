@@ -0,0 +1,156 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package alerts
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/event"
+)
+
+// testChain is a block tree with a designated canonical branch.
+type testChain struct {
+	blocks map[common.Hash]*types.Block
+	canon  []*types.Block
+	bad    []core.BadBlockArgs
+}
+
+func (c *testChain) CurrentBlock() *types.Block { return c.canon[len(c.canon)-1] }
+
+func (c *testChain) GetBlock(hash common.Hash, number uint64) *types.Block { return c.blocks[hash] }
+
+func (c *testChain) GetBlockByNumber(number uint64) *types.Block {
+	if number < uint64(len(c.canon)) {
+		return c.canon[number]
+	}
+	return nil
+}
+
+func (c *testChain) BadBlocks() ([]core.BadBlockArgs, error) { return c.bad, nil }
+
+func (c *testChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return new(event.Feed).Subscribe(ch)
+}
+
+func (c *testChain) SubscribeReorgRejectedEvent(ch chan<- core.ReorgRejectedEvent) event.Subscription {
+	return new(event.Feed).Subscribe(ch)
+}
+
+// extend creates n blocks on top of parent, tagged to make forks distinct, and
+// makes them canonical.
+func (c *testChain) extend(parent *types.Block, n int, tag byte) {
+	c.canon = c.canon[:parent.NumberU64()+1]
+	for i := 0; i < n; i++ {
+		block := types.NewBlockWithHeader(&types.Header{
+			Number:     new(big.Int).SetUint64(parent.NumberU64() + 1),
+			ParentHash: parent.Hash(),
+			Extra:      []byte{tag},
+		})
+		c.blocks[block.Hash()] = block
+		c.canon = append(c.canon, block)
+		parent = block
+	}
+}
+
+// Tests that deep reorgs and lost peers are alerted about, that payloads are
+// signed, and that failed deliveries are retried.
+func TestNotifierAlerts(t *testing.T) {
+	var (
+		secret   = "s3cr3t"
+		attempts = 0
+		received = make(chan *Alert, 10)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts++; attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		payload, _ := ioutil.ReadAll(r.Body)
+		if have, want := r.Header.Get(SignatureHeader), "sha256="+Sign([]byte(secret), payload); have != want {
+			t.Errorf("signature mismatch: have %s, want %s", have, want)
+		}
+		alert := new(Alert)
+		if err := json.Unmarshal(payload, alert); err != nil {
+			t.Errorf("invalid alert payload: %v", err)
+		}
+		received <- alert
+	}))
+	defer server.Close()
+
+	genesis := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0)})
+	chain := &testChain{blocks: map[common.Hash]*types.Block{genesis.Hash(): genesis}, canon: []*types.Block{genesis}}
+	chain.extend(genesis, 10, 0)
+
+	peers := 5
+	config := &Config{URL: server.URL, Secret: secret, ReorgDepth: 3, MinPeers: 3}
+	notifier := New(config, chain, func() int { return peers })
+	notifier.hook = NewWebhook(config.URL, config.Secret, 2, time.Millisecond)
+	notifier.hook.Start()
+	defer notifier.hook.Stop()
+
+	notifier.head = chain.CurrentBlock()
+
+	// A shallow reorg must be tolerated, a deep one reported
+	chain.extend(chain.canon[8], 3, 1)
+	notifier.newHead(chain.CurrentBlock())
+
+	chain.extend(chain.canon[5], 8, 2)
+	notifier.newHead(chain.CurrentBlock())
+
+	select {
+	case alert := <-received:
+		if alert.Type != DeepReorg {
+			t.Fatalf("alert type mismatch: have %s, want %s", alert.Type, DeepReorg)
+		}
+		if depth := alert.Details.(map[string]interface{})["depth"]; depth != float64(6) {
+			t.Errorf("reorg depth mismatch: have %v, want 6", depth)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("deep reorg not alerted")
+	}
+	if attempts != 2 {
+		t.Errorf("delivery attempts mismatch: have %d, want 2", attempts)
+	}
+	// Losing peers must be reported once
+	notifier.check()
+	peers = 1
+	notifier.check()
+	notifier.check()
+
+	select {
+	case alert := <-received:
+		if alert.Type != LowPeerCount {
+			t.Fatalf("alert type mismatch: have %s, want %s", alert.Type, LowPeerCount)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("low peer count not alerted")
+	}
+	select {
+	case alert := <-received:
+		t.Fatalf("unexpected alert: %+v", alert)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
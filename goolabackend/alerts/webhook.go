@@ -0,0 +1,166 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package alerts
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goola-team/goola/log"
+)
+
+const (
+	// SignatureHeader is the HTTP header carrying the HMAC-SHA256 signature of
+	// the alert payload, hex encoded and prefixed with "sha256=".
+	SignatureHeader = "X-Goola-Signature"
+
+	alertQueueSize = 64               // Maximum number of alerts waiting for delivery
+	requestTimeout = 10 * time.Second // Timeout of a single delivery attempt
+)
+
+// Alert is the JSON payload POSTed to the webhook.
+type Alert struct {
+	Type    string      `json:"type"`
+	Time    int64       `json:"time"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// Webhook delivers alerts to an HTTP endpoint, retrying failed attempts with an
+// exponential backoff.
+type Webhook struct {
+	url     string
+	secret  []byte
+	retries int
+	backoff time.Duration // Delay before the first retry, doubled after each one
+
+	client *http.Client
+	queue  chan *Alert
+	quit   chan struct{}
+	done   chan struct{}
+}
+
+// NewWebhook creates an alert deliverer to the given endpoint, waiting backoff
+// before retrying a failed delivery for the first time.
+func NewWebhook(url, secret string, retries int, backoff time.Duration) *Webhook {
+	return &Webhook{
+		url:     url,
+		secret:  []byte(secret),
+		retries: retries,
+		backoff: backoff,
+		client:  &http.Client{Timeout: requestTimeout},
+		queue:   make(chan *Alert, alertQueueSize),
+		quit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Send queues an alert for delivery, dropping it if the queue is full.
+func (w *Webhook) Send(alert *Alert) {
+	select {
+	case w.queue <- alert:
+	default:
+		log.Warn("Alert queue full, dropping alert", "type", alert.Type)
+	}
+}
+
+// Start begins delivering the queued alerts.
+func (w *Webhook) Start() {
+	go w.loop()
+}
+
+// loop delivers the queued alerts in order until stopped.
+func (w *Webhook) loop() {
+	defer close(w.done)
+
+	for {
+		select {
+		case alert := <-w.queue:
+			w.deliver(alert)
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// Stop terminates the delivery loop, dropping any undelivered alerts.
+func (w *Webhook) Stop() {
+	close(w.quit)
+	<-w.done
+}
+
+// deliver POSTs an alert to the webhook, retrying on failure.
+func (w *Webhook) deliver(alert *Alert) {
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		log.Error("Failed to encode alert", "type", alert.Type, "err", err)
+		return
+	}
+	delay := w.backoff
+	for attempt := 0; ; attempt++ {
+		if err = w.post(payload); err == nil {
+			return
+		}
+		if attempt >= w.retries {
+			break
+		}
+		log.Debug("Alert delivery failed, retrying", "type", alert.Type, "attempt", attempt+1, "err", err)
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-w.quit:
+			return
+		}
+	}
+	log.Warn("Failed to deliver alert", "type", alert.Type, "url", w.url, "err", err)
+}
+
+// post makes a single signed delivery attempt of a payload.
+func (w *Webhook) post(payload []byte) error {
+	req, err := http.NewRequest("POST", w.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(w.secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(w.secret, payload))
+	}
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", res.Status)
+	}
+	return nil
+}
+
+// Sign computes the hex encoded HMAC-SHA256 of a payload, as carried by the
+// signature header of the webhook requests.
+func Sign(secret, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
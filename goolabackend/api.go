@@ -17,12 +17,14 @@
 package goolabackend
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
 	"math/big"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/goola-team/goola/common"
@@ -31,6 +33,7 @@ import (
 	"github.com/goola-team/goola/core/state"
 	"github.com/goola-team/goola/core/types"
 	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/miner"
 	"github.com/goola-team/goola/params"
 	"github.com/goola-team/goola/rlp"
 	"github.com/goola-team/goola/rpc"
@@ -58,8 +61,16 @@ func (api *PublicEthereumAPI) Coinbase() (common.Address, error) {
 	return api.Goolase()
 }
 
+// PublicGoolaAPI provides an API to access Goola specific extensions that have
+// no counterpart in the standard Goola RPC namespace.
+type PublicGoolaAPI struct {
+	e *FullGoola
+}
 
-
+// NewPublicGoolaAPI creates a new API for the Goola specific extensions.
+func NewPublicGoolaAPI(e *FullGoola) *PublicGoolaAPI {
+	return &PublicGoolaAPI{e}
+}
 
 // PrivateMinerAPI provides private RPC methods to control the miner.
 // These methods can be abused by external users and must be considered insecure for use by untrusted users.
@@ -123,6 +134,16 @@ func (api *PrivateMinerAPI) SetExtra(extra string) (bool, error) {
 	return true, nil
 }
 
+// SetExtraTemplate sets a template rendered into the extra data of every block
+// this miner seals. Besides the {{blockNumber}}, {{timestamp}} and {{nodeName}}
+// built-ins, the template may reference any of the supplied custom variables.
+func (api *PrivateMinerAPI) SetExtraTemplate(template string, vars map[string]string) (bool, error) {
+	if err := api.e.Miner().SetExtraTemplate(template, vars); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // SetGasPrice sets the minimum accepted gas price for the miner.
 func (api *PrivateMinerAPI) SetGasPrice(gasPrice hexutil.Big) bool {
 	api.e.lock.Lock()
@@ -139,6 +160,19 @@ func (api *PrivateMinerAPI) SetGoolase(etherbase common.Address) bool {
 	return true
 }
 
+// SetNextCoinbase sets the fee recipient of the next block sealed by this miner
+// only, routing its rewards without changing the etherbase.
+func (api *PrivateMinerAPI) SetNextCoinbase(coinbase common.Address) bool {
+	api.e.Miner().SetNextCoinbase(coinbase)
+	return true
+}
+
+// LastPackingReport returns the number of transactions considered, included and
+// skipped by reason when packing the last block sealed by this miner.
+func (api *PrivateMinerAPI) LastPackingReport() *miner.PackingReport {
+	return api.e.Miner().LastPackingReport()
+}
+
 // PrivateAdminAPI is the collection of Goola full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -262,7 +296,7 @@ func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error
 	if block == nil {
 		return state.Dump{}, fmt.Errorf("block #%d not found", blockNr)
 	}
-	stateDb, err := api.fullGoola.BlockChain().StateAt(block.Root())
+	stateDb, err := api.fullGoola.BlockChain().HistoricState(block.Header())
 	if err != nil {
 		return state.Dump{}, err
 	}
@@ -343,7 +377,7 @@ func storageRangeAt(st state.Trie, start []byte, maxResult int) (StorageRangeRes
 	return result, nil
 }
 
-// GetModifiedAccountsByumber returns all accounts that have changed between the
+// GetModifiedAccountsByNumber returns all accounts that have changed between the
 // two blocks specified. A change is defined as a difference in nonce, balance,
 // code hash, or storage hash.
 //
@@ -398,10 +432,17 @@ func (api *PrivateDebugAPI) GetModifiedAccountsByHash(startHash common.Hash, end
 	return api.getModifiedAccounts(startBlock, endBlock)
 }
 
+// getModifiedAccounts collects the accounts modified between two blocks. If the
+// reverse state diffs of all blocks in the range are recorded, the accounts are
+// collected from those, which also works for pruned states and includes deleted
+// accounts. Otherwise the state tries of the two blocks are compared.
 func (api *PrivateDebugAPI) getModifiedAccounts(startBlock, endBlock *types.Block) ([]common.Address, error) {
 	if startBlock.Number().Uint64() >= endBlock.Number().Uint64() {
 		return nil, fmt.Errorf("start block height (%d) must be less than end block height (%d)", startBlock.Number().Uint64(), endBlock.Number().Uint64())
 	}
+	if dirty := api.getModifiedAccountsFromDiffs(startBlock, endBlock); dirty != nil {
+		return dirty, nil
+	}
 
 	oldTrie, err := trie.NewSecure(startBlock.Root(), trie.NewDatabase(api.fullGoola.chainDb), 0)
 	if err != nil {
@@ -425,3 +466,43 @@ func (api *PrivateDebugAPI) getModifiedAccounts(startBlock, endBlock *types.Bloc
 	}
 	return dirty, nil
 }
+
+// getModifiedAccountsFromDiffs collects the accounts touched by the blocks after
+// startBlock up to and including endBlock from their reverse state diffs, sorted
+// by address. Nil is returned if any of the diffs is missing.
+func (api *PrivateDebugAPI) getModifiedAccountsFromDiffs(startBlock, endBlock *types.Block) []common.Address {
+	var (
+		seen   = make(map[common.Address]struct{})
+		hash   = endBlock.Hash()
+		number = endBlock.NumberU64()
+	)
+	for ; number > startBlock.NumberU64(); number-- {
+		diff := core.GetStateDiff(api.fullGoola.chainDb, hash, number)
+		if diff == nil {
+			return nil
+		}
+		for _, account := range diff.Accounts {
+			seen[account.Address] = struct{}{}
+		}
+		header := api.fullGoola.blockchain.GetHeader(hash, number)
+		if header == nil {
+			return nil
+		}
+		hash = header.ParentHash
+	}
+	if hash != startBlock.Hash() {
+		return nil
+	}
+	dirty := make([]common.Address, 0, len(seen))
+	for addr := range seen {
+		dirty = append(dirty, addr)
+	}
+	sort.Slice(dirty, func(i, j int) bool { return bytes.Compare(dirty[i][:], dirty[j][:]) < 0 })
+	return dirty
+}
+
+// ReloadConfig reloads the node configuration, applying the changes to the
+// runtime safe fields and reporting those requiring a restart.
+func (api *PrivateAdminAPI) ReloadConfig() (*ReloadReport, error) {
+	return api.fullGoola.ReloadConfig()
+}
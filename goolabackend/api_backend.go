@@ -32,8 +32,10 @@ import (
 	"github.com/goola-team/goola/goolabackend/gasprice"
 	"github.com/goola-team/goola/gooladb"
 	"github.com/goola-team/goola/event"
+	"github.com/goola-team/goola/internal/ethapi"
 	"github.com/goola-team/goola/params"
 	"github.com/goola-team/goola/rpc"
+	"github.com/goola-team/goola/tracing"
 )
 
 // GoolaApiBackend implements ethapi.Backend for full nodes
@@ -56,6 +58,9 @@ func (b *GoolaApiBackend) SetHead(number uint64) {
 }
 
 func (b *GoolaApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	_, span := tracing.Start(ctx, "backend.HeaderByNumber", "number", int64(blockNr))
+	defer span.End()
+
 	// Pending block is only known by the miner
 	if blockNr == rpc.PendingBlockNumber {
 		block := b.goola.miner.PendingBlock()
@@ -69,6 +74,9 @@ func (b *GoolaApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockN
 }
 
 func (b *GoolaApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	_, span := tracing.Start(ctx, "backend.BlockByNumber", "number", int64(blockNr))
+	defer span.End()
+
 	// Pending block is only known by the miner
 	if blockNr == rpc.PendingBlockNumber {
 		block := b.goola.miner.PendingBlock()
@@ -78,10 +86,16 @@ func (b *GoolaApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNu
 	if blockNr == rpc.LatestBlockNumber {
 		return b.goola.blockchain.CurrentBlock(), nil
 	}
-	return b.goola.blockchain.GetBlockByNumber(uint64(blockNr)), nil
+	if block := b.goola.blockchain.GetBlockByNumber(uint64(blockNr)); block != nil {
+		return block, nil
+	}
+	return nil, b.goola.blockchain.CheckHistory(uint64(blockNr))
 }
 
 func (b *GoolaApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	ctx, span := tracing.Start(ctx, "backend.StateAndHeaderByNumber", "number", int64(blockNr))
+	defer span.End()
+
 	// Pending state is only known by the miner
 	if blockNr == rpc.PendingBlockNumber {
 		block, state := b.goola.miner.Pending()
@@ -92,16 +106,38 @@ func (b *GoolaApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rp
 	if header == nil || err != nil {
 		return nil, nil, err
 	}
-	stateDb, err := b.goola.BlockChain().StateAt(header.Root)
+	stateDb, err := b.goola.BlockChain().HistoricState(header)
 	return stateDb, header, err
 }
 
 func (b *GoolaApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
-	return b.goola.blockchain.GetBlockByHash(blockHash), nil
+	_, span := tracing.Start(ctx, "backend.GetBlock", "hash", blockHash)
+	defer span.End()
+
+	if block := b.goola.blockchain.GetBlockByHash(blockHash); block != nil {
+		return block, nil
+	}
+	return nil, b.checkHistory(blockHash)
 }
 
 func (b *GoolaApiBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
-	return core.GetBlockReceipts(b.goola.chainDb, blockHash, core.GetBlockNumber(b.goola.chainDb, blockHash)), nil
+	_, span := tracing.Start(ctx, "backend.GetReceipts", "hash", blockHash)
+	defer span.End()
+
+	if receipts := core.GetBlockReceipts(b.goola.chainDb, blockHash, core.GetBlockNumber(b.goola.chainDb, blockHash)); receipts != nil {
+		return receipts, nil
+	}
+	return nil, b.checkHistory(blockHash)
+}
+
+// checkHistory returns a *core.PrunedHistoryError if the history of a known
+// canonical block was pruned, nil otherwise.
+func (b *GoolaApiBackend) checkHistory(blockHash common.Hash) error {
+	number := core.GetBlockNumber(b.goola.chainDb, blockHash)
+	if core.GetCanonicalHash(b.goola.chainDb, number) != blockHash {
+		return nil
+	}
+	return b.goola.blockchain.CheckHistory(number)
 }
 
 
@@ -134,6 +170,9 @@ func (b *GoolaApiBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subsc
 }
 
 func (b *GoolaApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	_, span := tracing.Start(ctx, "backend.SendTx", "tx", signedTx.Hash())
+	defer span.End()
+
 	return b.goola.txPool.AddLocal(signedTx)
 }
 
@@ -154,6 +193,9 @@ func (b *GoolaApiBackend) GetPoolTransaction(hash common.Hash) *types.Transactio
 }
 
 func (b *GoolaApiBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	_, span := tracing.Start(ctx, "backend.GetPoolNonce", "address", addr)
+	defer span.End()
+
 	return b.goola.txPool.State().GetNonce(addr), nil
 }
 
@@ -169,6 +211,10 @@ func (b *GoolaApiBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.S
 	return b.goola.TxPool().SubscribeTxPreEvent(ch)
 }
 
+func (b *GoolaApiBackend) SubscribeTxDropEvent(ch chan<- core.TxDropEvent) event.Subscription {
+	return b.goola.TxPool().SubscribeTxDropEvent(ch)
+}
+
 func (b *GoolaApiBackend) Downloader() *downloader.Downloader {
 	return b.goola.Downloader()
 }
@@ -178,6 +224,9 @@ func (b *GoolaApiBackend) ProtocolVersion() int {
 }
 
 func (b *GoolaApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	ctx, span := tracing.Start(ctx, "backend.SuggestPrice")
+	defer span.End()
+
 	return b.gpo.SuggestPrice(ctx)
 }
 
@@ -193,6 +242,10 @@ func (b *GoolaApiBackend) AccountManager() *accounts.Manager {
 	return b.goola.AccountManager()
 }
 
+func (b *GoolaApiBackend) CallCacheConfig() ethapi.CallCacheConfig {
+	return b.goola.config.CallCache
+}
+
 func (b *GoolaApiBackend) BloomStatus() (uint64, uint64) {
 	sections, _, _ := b.goola.bloomIndexer.Sections()
 	return params.BloomBitsBlocks, sections
@@ -0,0 +1,188 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rpc"
+)
+
+const (
+	// maxWatchedAccounts is the maximum number of accounts a single balance
+	// change subscription may watch.
+	maxWatchedAccounts = 1024
+
+	// maxBalanceWalkback is the maximum number of newly canonical blocks checked
+	// for balance changes on a single chain head update.
+	maxBalanceWalkback = 256
+)
+
+// BalanceChange is the notification of a watched account's balance or nonce
+// being modified by a new canonical block.
+type BalanceChange struct {
+	Address         common.Address `json:"address"`
+	BlockNumber     hexutil.Uint64 `json:"blockNumber"`
+	BlockHash       common.Hash    `json:"blockHash"`
+	Balance         *hexutil.Big   `json:"balance"`
+	Nonce           hexutil.Uint64 `json:"nonce"`
+	PreviousBalance *hexutil.Big   `json:"previousBalance"`
+	PreviousNonce   hexutil.Uint64 `json:"previousNonce"`
+}
+
+// BalanceChanges creates a subscription that fires whenever the balance or the
+// nonce of one of the given accounts changes in a new canonical block. Changes
+// are detected from the accounts modified during block import, so blocks that
+// were not imported by this node (e.g. fast synced) are not reported.
+func (api *PublicGoolaAPI) BalanceChanges(ctx context.Context, accounts []common.Address) (*rpc.Subscription, error) {
+	if len(accounts) == 0 {
+		return nil, errors.New("no accounts to watch")
+	}
+	if len(accounts) > maxWatchedAccounts {
+		return nil, fmt.Errorf("too many accounts to watch: %d > %d", len(accounts), maxWatchedAccounts)
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	watched := make(map[common.Address]bool, len(accounts))
+	for _, addr := range accounts {
+		watched[addr] = true
+	}
+	// Subscribe right away not to miss blocks imported before the goroutine runs
+	var (
+		chain = api.e.BlockChain()
+		heads = make(chan core.ChainHeadEvent, 16)
+		sub   = chain.SubscribeChainHeadEvent(heads)
+		last  = chain.CurrentBlock()
+	)
+	go func() {
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-heads:
+				for _, block := range newCanonicalBlocks(chain, last, ev.Block) {
+					changes, err := balanceChanges(chain, block, watched)
+					if err != nil {
+						log.Debug("Failed to compute balance changes", "number", block.NumberU64(), "hash", block.Hash(), "err", err)
+						continue
+					}
+					for _, change := range changes {
+						notifier.Notify(rpcSub.ID, change)
+					}
+				}
+				last = ev.Block
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// newCanonicalBlocks returns the blocks that became canonical when the chain head
+// moved from old to head, oldest first, limited to the most recent ones.
+func newCanonicalBlocks(chain *core.BlockChain, old, head *types.Block) []*types.Block {
+	var blocks []*types.Block
+	for head != nil && len(blocks) < maxBalanceWalkback {
+		// Stop as soon as the new branch joins the old one
+		for old != nil && old.NumberU64() > head.NumberU64() {
+			old = chain.GetBlock(old.ParentHash(), old.NumberU64()-1)
+		}
+		if old != nil && old.Hash() == head.Hash() {
+			break
+		}
+		blocks = append(blocks, head)
+		if head.NumberU64() == 0 {
+			break
+		}
+		head = chain.GetBlock(head.ParentHash(), head.NumberU64()-1)
+	}
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+	return blocks
+}
+
+// balanceChanges returns the changes of the watched accounts' balances and nonces
+// made by the given block.
+func balanceChanges(chain *core.BlockChain, block *types.Block, watched map[common.Address]bool) ([]*BalanceChange, error) {
+	var touched []common.Address
+	for _, addr := range chain.TouchedAccounts(block.Hash()) {
+		if watched[addr] {
+			touched = append(touched, addr)
+		}
+	}
+	if len(touched) == 0 || block.NumberU64() == 0 {
+		return nil, nil
+	}
+	parent := chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %x not found", block.ParentHash())
+	}
+	prev, err := chain.HistoricState(parent.Header())
+	if err != nil {
+		return nil, err
+	}
+	post, err := chain.HistoricState(block.Header())
+	if err != nil {
+		return nil, err
+	}
+	var changes []*BalanceChange
+	for _, addr := range touched {
+		if change := balanceChange(prev, post, addr); change != nil {
+			change.BlockNumber = hexutil.Uint64(block.NumberU64())
+			change.BlockHash = block.Hash()
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// balanceChange compares an account's balance and nonce between two states,
+// returning nil if neither changed.
+func balanceChange(prev, post *state.StateDB, addr common.Address) *BalanceChange {
+	var (
+		prevBalance, balance = prev.GetBalance(addr), post.GetBalance(addr)
+		prevNonce, nonce     = prev.GetNonce(addr), post.GetNonce(addr)
+	)
+	if prevBalance.Cmp(balance) == 0 && prevNonce == nonce {
+		return nil
+	}
+	return &BalanceChange{
+		Address:         addr,
+		Balance:         (*hexutil.Big)(balance),
+		Nonce:           hexutil.Uint64(nonce),
+		PreviousBalance: (*hexutil.Big)(prevBalance),
+		PreviousNonce:   hexutil.Uint64(prevNonce),
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/consensus/dpos"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
+	"github.com/goola-team/goola/rpc"
+)
+
+// Tests that balance change notifications are sent for the watched accounts
+// modified by newly imported blocks only.
+func TestBalanceChanges(t *testing.T) {
+	var (
+		db, _   = gooladb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+		watched = common.Address{0x01}
+	)
+	blockchain, _ := core.NewBlockChain(db, nil, gspec.Config, dpos.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("goola", NewPublicGoolaAPI(&FullGoola{blockchain: blockchain})); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	changes := make(chan *BalanceChange, 4)
+	if _, err := client.Subscribe(context.Background(), "goola", changes, "balanceChanges", []common.Address{}); err == nil {
+		t.Fatalf("subscription without accounts accepted")
+	}
+	sub, err := client.Subscribe(context.Background(), "goola", changes, "balanceChanges", []common.Address{watched})
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Pay the watched account in the first and third blocks, another in the second
+	payees := []common.Address{watched, {0x02}, watched}
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, dpos.NewFaker(), db, len(payees), func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(testBank), payees[i], big.NewInt(int64(1000*(i+1))), 21000, big.NewInt(1), types.TxTypeTransfer, nil), signer, testBankKey)
+		b.AddTx(tx)
+	})
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	want := []BalanceChange{
+		{Address: watched, BlockNumber: 1, BlockHash: blocks[0].Hash(), Balance: (*hexutil.Big)(big.NewInt(1000)), PreviousBalance: (*hexutil.Big)(new(big.Int))},
+		{Address: watched, BlockNumber: 3, BlockHash: blocks[2].Hash(), Balance: (*hexutil.Big)(big.NewInt(4000)), PreviousBalance: (*hexutil.Big)(big.NewInt(1000))},
+	}
+	for i, want := range want {
+		select {
+		case have := <-changes:
+			if have.Address != want.Address || have.BlockNumber != want.BlockNumber || have.BlockHash != want.BlockHash ||
+				have.Balance.ToInt().Cmp(want.Balance.ToInt()) != 0 || have.PreviousBalance.ToInt().Cmp(want.PreviousBalance.ToInt()) != 0 {
+				t.Errorf("change %d mismatch: have %+v, want %+v", i, have, want)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(time.Second):
+			t.Fatalf("change %d not notified", i)
+		}
+	}
+	select {
+	case have := <-changes:
+		t.Errorf("unexpected change: %+v", have)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// Tests that only the blocks of the new branch are checked after a reorg.
+func TestNewCanonicalBlocks(t *testing.T) {
+	var (
+		db, _   = gooladb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blockchain, _ := core.NewBlockChain(db, nil, gspec.Config, dpos.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, dpos.NewFaker(), db, 3, nil)
+	fork, _ := core.GenerateChain(gspec.Config, blocks[0], dpos.NewFaker(), db, 3, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+	})
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if _, err := blockchain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	canonical := newCanonicalBlocks(blockchain, blocks[2], fork[2])
+	if len(canonical) != len(fork) {
+		t.Fatalf("new canonical block count mismatch: have %d, want %d", len(canonical), len(fork))
+	}
+	for i, block := range canonical {
+		if block.Hash() != fork[i].Hash() {
+			t.Errorf("block %d mismatch: have #%d %x, want #%d %x", i, block.NumberU64(), block.Hash(), fork[i].NumberU64(), fork[i].Hash())
+		}
+	}
+	if extended := newCanonicalBlocks(blockchain, fork[1], fork[2]); len(extended) != 1 || extended[0].Hash() != fork[2].Hash() {
+		t.Errorf("chain extension mismatch: have %d blocks, want #%d", len(extended), fork[2].NumberU64())
+	}
+}
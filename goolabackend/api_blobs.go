@@ -0,0 +1,75 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"fmt"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/internal/ethapi"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rlp"
+)
+
+// SendBlobTransaction submits a signed blob transaction together with its data
+// blobs. The blobs are verified against the hashes committed to by the
+// transaction and stored locally before it enters the pool, so the node can
+// serve them to its peers and the miner can include the transaction.
+func (api *PublicGoolaAPI) SendBlobTransaction(encodedTx hexutil.Bytes, blobs []hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	data, err := tx.BlobTxData()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	raw := make([][]byte, len(blobs))
+	for i, blob := range blobs {
+		raw[i] = blob
+	}
+	if err := data.VerifyBlobs(raw); err != nil {
+		return common.Hash{}, err
+	}
+	for _, blob := range raw {
+		api.e.blockchain.WriteBlob(blob)
+	}
+	if err := api.e.txPool.AddLocal(tx); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted blob transaction", "fullhash", tx.Hash().Hex(), "blobs", len(raw))
+	return tx.Hash(), nil
+}
+
+// GetBlob returns a data blob held by the node. Blobs are pruned once their
+// block falls out of the retention window.
+func (api *PublicGoolaAPI) GetBlob(hash common.Hash) (hexutil.Bytes, error) {
+	blob := api.e.blockchain.GetBlob(hash)
+	if blob == nil {
+		return nil, &ethapi.NotFoundError{Kind: "blob", Message: fmt.Sprintf("blob %x not found", hash)}
+	}
+	return blob, nil
+}
+
+// BlobFee returns the fee per blob a transaction included in the next block
+// would be charged.
+func (api *PublicGoolaAPI) BlobFee() *hexutil.Big {
+	return (*hexutil.Big)(core.CalcBlobFee(core.CountBlobs(api.e.blockchain.CurrentBlock().Transactions())))
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/params"
+)
+
+// errBloomRebuildRunning is returned if a bloombits index rebuild is requested
+// while another one is still in progress.
+var errBloomRebuildRunning = errors.New("bloom index rebuild already in progress")
+
+// VerifyBloomIndex checks every section of the bloombits index against the
+// canonical chain, reporting the status of each.
+func (api *PrivateDebugAPI) VerifyBloomIndex(ctx context.Context) ([]*BloomSectionStatus, error) {
+	sections, _, _ := api.fullGoola.bloomIndexer.Sections()
+
+	result := make([]*BloomSectionStatus, 0, sections)
+	for section := uint64(0); section < sections; section++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		status, err := verifyBloomSection(api.fullGoola.chainDb, params.BloomBitsBlocks, section)
+		if err != nil {
+			return nil, fmt.Errorf("section %d: %v", section, err)
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
+
+// RebuildBloomIndex regenerates the given range of sections of the bloombits
+// index from the canonical headers in the background, repairing damaged data
+// without resyncing.
+func (api *PrivateDebugAPI) RebuildBloomIndex(first, last hexutil.Uint64) error {
+	if first > last {
+		return fmt.Errorf("invalid section range %d-%d", first, last)
+	}
+	if sections, _, _ := api.fullGoola.bloomIndexer.Sections(); uint64(last) >= sections {
+		return fmt.Errorf("section %d not indexed yet, %d available", last, sections)
+	}
+	goola := api.fullGoola
+	if !atomic.CompareAndSwapInt32(&goola.bloomRebuilding, 0, 1) {
+		return errBloomRebuildRunning
+	}
+	go func() {
+		defer atomic.StoreInt32(&goola.bloomRebuilding, 0)
+
+		log.Info("Rebuilding bloom index", "first", uint64(first), "last", uint64(last))
+		for section := uint64(first); section <= uint64(last); section++ {
+			select {
+			case <-goola.shutdownChan:
+				log.Warn("Bloom index rebuild aborted", "section", section)
+				return
+			default:
+			}
+			if err := rebuildBloomSection(goola.chainDb, params.BloomBitsBlocks, section); err != nil {
+				log.Error("Failed to rebuild bloom index section", "section", section, "err", err)
+				return
+			}
+			log.Debug("Rebuilt bloom index section", "section", section)
+		}
+		log.Info("Bloom index rebuilt", "first", uint64(first), "last", uint64(last))
+	}()
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+)
+
+// RPCFinality is the RPC representation of the irreversible part of the chain.
+type RPCFinality struct {
+	Number          hexutil.Uint64 `json:"number"`
+	Hash            common.Hash    `json:"hash"`
+	JustifiedNumber hexutil.Uint64 `json:"justifiedNumber"`
+	JustifiedHash   common.Hash    `json:"justifiedHash"`
+}
+
+// FinalizedBlock returns the highest finalized block of the canonical chain
+// along with the highest justified one, or nil if the consensus engine provides
+// no finality or no block is finalized yet.
+func (api *PublicEthereumAPI) FinalizedBlock() *RPCFinality {
+	chain := api.e.BlockChain()
+
+	finalized := chain.CurrentFinalized()
+	if finalized == nil {
+		return nil
+	}
+	result := &RPCFinality{
+		Number: hexutil.Uint64(finalized.Number.Uint64()),
+		Hash:   finalized.Hash(),
+	}
+	if justified := chain.CurrentJustified(); justified != nil {
+		result.JustifiedNumber = hexutil.Uint64(justified.Number.Uint64())
+		result.JustifiedHash = justified.Hash()
+	}
+	return result
+}
@@ -0,0 +1,166 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/params"
+)
+
+// blockIntervalSamples is the number of recent blocks averaged to estimate the
+// block interval of chains without a fixed production period.
+const blockIntervalSamples = 128
+
+// RPCFork is the RPC representation of a fork of the chain config.
+type RPCFork struct {
+	Name      string          `json:"name"`
+	Block     hexutil.Uint64  `json:"block"`
+	Activated bool            `json:"activated"`
+	Remaining hexutil.Uint64  `json:"remaining"`     // Blocks left until activation
+	ETA       *hexutil.Uint64 `json:"eta,omitempty"` // Estimated unix time of activation, nil if unknown
+	Supported bool            `json:"supported"`     // Whether this node version implements the fork
+}
+
+// blockInterval estimates the seconds between blocks, either from the
+// configured production period or the recent chain history. Zero is returned
+// if no estimate can be made.
+func (s *FullGoola) blockInterval() float64 {
+	if dpos := s.chainConfig.Ethash; dpos != nil && dpos.Scheduled() {
+		return float64(dpos.Period)
+	}
+	head := s.blockchain.CurrentHeader()
+	if head.Number.Uint64() == 0 {
+		return 0
+	}
+	samples := uint64(blockIntervalSamples)
+	if number := head.Number.Uint64(); number < samples {
+		samples = number
+	}
+	past := s.blockchain.GetHeaderByNumber(head.Number.Uint64() - samples)
+	if past == nil {
+		return 0
+	}
+	return float64(head.Time.Uint64()-past.Time.Uint64()) / float64(samples)
+}
+
+// unsupportedForks returns the forks scheduled in the stored chain config which
+// this version of the node does not implement.
+func (s *FullGoola) unsupportedForks() []params.Fork {
+	config := core.GetChainConfigJSON(s.chainDb, s.blockchain.Genesis().Hash())
+	if config == nil {
+		return nil
+	}
+	forks, err := params.UnsupportedForks(config)
+	if err != nil {
+		log.Warn("Failed to parse stored chain config", "err", err)
+		return nil
+	}
+	return forks
+}
+
+// forkSchedule returns all the scheduled forks of the chain, ordered by
+// activation block, with their countdowns estimated from the chain head.
+func (s *FullGoola) forkSchedule() []*RPCFork {
+	var (
+		head     = s.blockchain.CurrentHeader()
+		interval = s.blockInterval()
+		schedule []*RPCFork
+	)
+	add := func(fork params.Fork, supported bool) {
+		if fork.Block == nil {
+			return
+		}
+		rpcFork := &RPCFork{
+			Name:      fork.Name,
+			Block:     hexutil.Uint64(fork.Block.Uint64()),
+			Activated: fork.Block.Cmp(head.Number) <= 0,
+			Supported: supported,
+		}
+		if !rpcFork.Activated {
+			remaining := fork.Block.Uint64() - head.Number.Uint64()
+			rpcFork.Remaining = hexutil.Uint64(remaining)
+			if interval > 0 {
+				eta := hexutil.Uint64(head.Time.Uint64() + uint64(float64(remaining)*interval))
+				rpcFork.ETA = &eta
+			}
+		}
+		schedule = append(schedule, rpcFork)
+	}
+	for _, fork := range s.chainConfig.Forks() {
+		add(fork, true)
+	}
+	for _, fork := range s.unsupportedForks() {
+		add(fork, false)
+	}
+	sort.SliceStable(schedule, func(i, j int) bool { return schedule[i].Block < schedule[j].Block })
+	return schedule
+}
+
+// forkReadiness returns a warning for every scheduled fork this node version
+// cannot follow the chain across.
+func (s *FullGoola) forkReadiness() []string {
+	var warnings []string
+	for _, fork := range s.forkSchedule() {
+		if fork.Supported {
+			continue
+		}
+		if fork.Activated {
+			warnings = append(warnings, fmt.Sprintf("fork %s activated at block %d is not supported by this node version", fork.Name, fork.Block))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("fork %s scheduled at block %d (in %d blocks) is not supported by this node version", fork.Name, fork.Block, fork.Remaining))
+		}
+	}
+	return warnings
+}
+
+// ForkSchedule returns the forks of the chain config ordered by activation
+// block, with the estimated activation times of the upcoming ones. Forks not
+// supported by this node version are included, flagged as such.
+func (api *PublicGoolaAPI) ForkSchedule() []*RPCFork {
+	return api.e.forkSchedule()
+}
+
+// NodeHealth is the result of the node health checks.
+type NodeHealth struct {
+	Healthy  bool     `json:"healthy"`
+	Warnings []string `json:"warnings"`
+}
+
+// PublicNodeHealthAPI provides the health checks of the node.
+type PublicNodeHealthAPI struct {
+	e *FullGoola
+}
+
+// NewPublicNodeHealthAPI creates a new node health check API.
+func NewPublicNodeHealthAPI(e *FullGoola) *PublicNodeHealthAPI {
+	return &PublicNodeHealthAPI{e}
+}
+
+// Health runs the node health checks, currently whether the node version can
+// follow the chain across all of its scheduled forks.
+func (api *PublicNodeHealthAPI) Health() *NodeHealth {
+	warnings := api.e.forkReadiness()
+	if warnings == nil {
+		warnings = []string{}
+	}
+	return &NodeHealth{Healthy: len(warnings) == 0, Warnings: warnings}
+}
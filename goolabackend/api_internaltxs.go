@@ -0,0 +1,114 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/rpc"
+)
+
+// errInternalTxsDisabled is returned if internal transactions are requested
+// but the node isn't configured to index them.
+var errInternalTxsDisabled = errors.New("internal transaction indexing disabled")
+
+// InternalTxQuery selects the internal transactions to retrieve, either those of
+// a whole block by number, or those of a single transaction by hash.
+type InternalTxQuery struct {
+	TxHash      *common.Hash
+	BlockNumber rpc.BlockNumber
+}
+
+// UnmarshalJSON parses either a 32 byte transaction hash or a block number.
+func (q *InternalTxQuery) UnmarshalJSON(data []byte) error {
+	input := strings.Trim(strings.TrimSpace(string(data)), `"`)
+	if len(input) == 2+2*common.HashLength && strings.HasPrefix(input, "0x") {
+		hash, err := hexutil.Decode(input)
+		if err != nil {
+			return err
+		}
+		txHash := common.BytesToHash(hash)
+		q.TxHash = &txHash
+		return nil
+	}
+	return q.BlockNumber.UnmarshalJSON(data)
+}
+
+// RPCInternalTx is the RPC representation of an internal transaction.
+type RPCInternalTx struct {
+	BlockHash   common.Hash    `json:"blockHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	TxIndex     hexutil.Uint   `json:"transactionIndex"`
+	Type        string         `json:"type"`
+	From        common.Address `json:"from"`
+	To          common.Address `json:"to"`
+	Value       *hexutil.Big   `json:"value"`
+	Depth       hexutil.Uint   `json:"depth"`
+	Failed      bool           `json:"failed"`
+}
+
+// GetInternalTransactions returns the value transfers made by contracts while
+// executing either a whole block or a single transaction.
+func (api *PublicGoolaAPI) GetInternalTransactions(ctx context.Context, query InternalTxQuery) ([]*RPCInternalTx, error) {
+	if !api.e.config.InternalTxIndex {
+		return nil, errInternalTxsDisabled
+	}
+	var (
+		chain = api.e.BlockChain()
+		hash  common.Hash
+		num   uint64
+	)
+	if query.TxHash != nil {
+		blockHash, blockNumber, _ := core.GetTxLookupEntry(api.e.ChainDb(), *query.TxHash)
+		if blockHash == (common.Hash{}) {
+			return nil, fmt.Errorf("transaction %#x not found", *query.TxHash)
+		}
+		hash, num = blockHash, blockNumber
+	} else {
+		header, err := api.e.ApiBackend.HeaderByNumber(ctx, query.BlockNumber)
+		if header == nil || err != nil {
+			return nil, fmt.Errorf("block #%d not found", query.BlockNumber)
+		}
+		hash, num = header.Hash(), header.Number.Uint64()
+	}
+	result := make([]*RPCInternalTx, 0)
+	for _, itx := range chain.GetInternalTxs(hash, num) {
+		if query.TxHash != nil && itx.TxHash != *query.TxHash {
+			continue
+		}
+		result = append(result, &RPCInternalTx{
+			BlockHash:   hash,
+			BlockNumber: hexutil.Uint64(num),
+			TxHash:      itx.TxHash,
+			TxIndex:     hexutil.Uint(itx.TxIndex),
+			Type:        itx.Type,
+			From:        itx.From,
+			To:          itx.To,
+			Value:       (*hexutil.Big)(itx.Value),
+			Depth:       hexutil.Uint(itx.Depth),
+			Failed:      itx.Failed,
+		})
+	}
+	return result, nil
+}
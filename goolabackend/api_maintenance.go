@@ -0,0 +1,127 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/goolabackend/maintenance"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/params"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Names of the maintenance tasks run by the full node.
+const (
+	compactionTask = "compaction" // Full compaction of the chain database
+	bloomCheckTask = "bloomcheck" // Verification and repair of the bloombits index
+)
+
+// errMaintenanceAborted is returned by the maintenance tasks interrupted by a
+// node shutdown.
+var errMaintenanceAborted = errors.New("aborted")
+
+// newMaintenanceScheduler creates the scheduler of the heavy background tasks
+// of a full node, measuring its load by the sync status and the pool size.
+func newMaintenanceScheduler(goola *FullGoola, config *maintenance.Config) (*maintenance.Scheduler, error) {
+	scheduler, err := maintenance.New(config, func() maintenance.Load {
+		pending, queued := goola.txPool.Stats()
+		return maintenance.Load{
+			Syncing:    goola.protocolManager.downloader.Synchronising(),
+			PendingTxs: pending + queued,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	scheduler.Register(compactionTask, config.Compaction, goola.compactDatabase)
+	scheduler.Register(bloomCheckTask, config.BloomCheck, goola.repairBloomIndex)
+	return scheduler, nil
+}
+
+// compactDatabase compacts the entire key range of the chain database.
+func (fullGoola *FullGoola) compactDatabase(quit <-chan struct{}) error {
+	ldb, ok := fullGoola.chainDb.(*gooladb.LDBDatabase)
+	if !ok {
+		return errors.New("compaction not supported by the database")
+	}
+	for b := byte(0); b < 255; b++ {
+		select {
+		case <-quit:
+			return errMaintenanceAborted
+		default:
+		}
+		log.Debug("Compacting chain database", "range", fmt.Sprintf("0x%0.2X-0x%0.2X", b, b+1))
+		if err := ldb.LDB().CompactRange(util.Range{Start: []byte{b}, Limit: []byte{b + 1}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repairBloomIndex verifies every section of the bloombits index, regenerating
+// the damaged ones from the canonical headers.
+func (fullGoola *FullGoola) repairBloomIndex(quit <-chan struct{}) error {
+	if !atomic.CompareAndSwapInt32(&fullGoola.bloomRebuilding, 0, 1) {
+		return errBloomRebuildRunning
+	}
+	defer atomic.StoreInt32(&fullGoola.bloomRebuilding, 0)
+
+	sections, _, _ := fullGoola.bloomIndexer.Sections()
+	for section := uint64(0); section < sections; section++ {
+		select {
+		case <-quit:
+			return errMaintenanceAborted
+		default:
+		}
+		status, err := verifyBloomSection(fullGoola.chainDb, params.BloomBitsBlocks, section)
+		if err != nil {
+			return fmt.Errorf("section %d: %v", section, err)
+		}
+		if status.Valid {
+			continue
+		}
+		log.Warn("Repairing damaged bloom index section", "section", section)
+		if err := rebuildBloomSection(fullGoola.chainDb, params.BloomBitsBlocks, section); err != nil {
+			return fmt.Errorf("section %d: %v", section, err)
+		}
+	}
+	return nil
+}
+
+// MaintenanceSchedule returns the maintenance windows and the state of the
+// background maintenance tasks.
+func (api *PrivateAdminAPI) MaintenanceSchedule() *maintenance.Schedule {
+	return api.fullGoola.scheduler.Schedule()
+}
+
+// RunMaintenance runs a maintenance task right away, regardless of its schedule
+// and the maintenance windows.
+func (api *PrivateAdminAPI) RunMaintenance(task string) error {
+	return api.fullGoola.scheduler.Run(task)
+}
+
+// SuspendMaintenance postpones the scheduled maintenance tasks for the given
+// number of seconds, zero resuming them.
+func (api *PrivateAdminAPI) SuspendMaintenance(seconds hexutil.Uint64) {
+	api.fullGoola.scheduler.Suspend(time.Duration(seconds) * time.Second)
+}
@@ -0,0 +1,214 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"runtime"
+	"sync/atomic"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
+)
+
+// NodeConfig is the effective configuration of a node, reported to diagnose
+// misconfigurations remotely. Secrets are deliberately left out.
+type NodeConfig struct {
+	Build    BuildInfo      `json:"build"`
+	Chain    ChainInfo      `json:"chain"`
+	Sync     SyncConfig     `json:"sync"`
+	Database DatabaseConfig `json:"database"`
+	TxPool   TxPoolConfig   `json:"txpool"`
+	GasPrice GasPriceConfig `json:"gasPrice"`
+	Mining   MiningConfig   `json:"mining"`
+	Light    LightConfig    `json:"light"`
+	Indexes  IndexesConfig  `json:"indexes"`
+	Alerts   AlertsConfig   `json:"alerts"`
+}
+
+// BuildInfo identifies the running binary.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"` // Empty if not set at build time
+	Date      string `json:"date"`   // Commit date as YYYYMMDD, empty if not set at build time
+	GoVersion string `json:"goVersion"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// ChainInfo identifies the chain and fork rules the node follows.
+type ChainInfo struct {
+	NetworkId  uint64              `json:"networkId"`
+	Genesis    common.Hash         `json:"genesis"`
+	Config     *params.ChainConfig `json:"config"`
+	HeadNumber hexutil.Uint64      `json:"headNumber"`
+	HeadHash   common.Hash         `json:"headHash"`
+}
+
+// SyncConfig is the synchronisation and pruning configuration.
+type SyncConfig struct {
+	Mode          string `json:"mode"`
+	FastSyncing   bool   `json:"fastSyncing"` // Whether fast sync is still in progress
+	NoPruning     bool   `json:"noPruning"`
+	MaxReorgDepth uint64 `json:"maxReorgDepth"`
+}
+
+// DatabaseConfig is the storage configuration.
+type DatabaseConfig struct {
+	Engine      string `json:"engine"`
+	Cache       int    `json:"cache"`
+	Handles     int    `json:"handles"`
+	TrieCache   int    `json:"trieCache"`
+	TrieTimeout string `json:"trieTimeout"`
+}
+
+// TxPoolConfig is the transaction pool configuration.
+type TxPoolConfig struct {
+	NoLocals     bool   `json:"noLocals"`
+	Journal      string `json:"journal"`
+	Rejournal    string `json:"rejournal"`
+	PriceLimit   uint64 `json:"priceLimit"`
+	PriceBump    uint64 `json:"priceBump"`
+	AccountSlots uint64 `json:"accountSlots"`
+	GlobalSlots  uint64 `json:"globalSlots"`
+	AccountQueue uint64 `json:"accountQueue"`
+	GlobalQueue  uint64 `json:"globalQueue"`
+	Lifetime     string `json:"lifetime"`
+}
+
+// GasPriceConfig is the gas price oracle configuration.
+type GasPriceConfig struct {
+	Blocks     int          `json:"blocks"`
+	Percentile int          `json:"percentile"`
+	Default    *hexutil.Big `json:"default"`
+}
+
+// MiningConfig is the block production configuration.
+type MiningConfig struct {
+	Etherbase common.Address `json:"etherbase"`
+	Threads   int            `json:"threads"`
+	ExtraData hexutil.Bytes  `json:"extraData"`
+	GasPrice  *hexutil.Big   `json:"gasPrice"`
+}
+
+// LightConfig is the light client serving configuration.
+type LightConfig struct {
+	Serve        int `json:"serve"`
+	Peers        int `json:"peers"`
+	BudgetHourly int `json:"budgetHourly"`
+	BudgetDaily  int `json:"budgetDaily"`
+}
+
+// IndexesConfig lists the optional indexes maintained by the node.
+type IndexesConfig struct {
+	InternalTxs bool `json:"internalTxs"`
+	Preimages   bool `json:"preimages"`
+}
+
+// AlertsConfig is the alert webhook configuration, without its endpoint and
+// signing secret.
+type AlertsConfig struct {
+	Enabled      bool   `json:"enabled"`
+	Signed       bool   `json:"signed"`
+	ReorgDepth   uint64 `json:"reorgDepth"`
+	StallTimeout string `json:"stallTimeout"`
+	MinPeers     int    `json:"minPeers"`
+}
+
+// NodeConfig returns the effective configuration of the node.
+func (api *PublicGoolaAPI) NodeConfig() *NodeConfig {
+	var (
+		e      = api.e
+		config = e.config
+		head   = e.blockchain.CurrentBlock()
+	)
+	engine := "memory"
+	if _, ok := e.chainDb.(*gooladb.LDBDatabase); ok {
+		engine = "leveldb"
+	}
+	return &NodeConfig{
+		Build: BuildInfo{
+			Version:   params.Version,
+			Commit:    params.GitCommit,
+			Date:      params.GitDate,
+			GoVersion: runtime.Version(),
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+		},
+		Chain: ChainInfo{
+			NetworkId:  e.networkId,
+			Genesis:    e.blockchain.Genesis().Hash(),
+			Config:     e.chainConfig,
+			HeadNumber: hexutil.Uint64(head.NumberU64()),
+			HeadHash:   head.Hash(),
+		},
+		Sync: SyncConfig{
+			Mode:          config.SyncMode.String(),
+			FastSyncing:   atomic.LoadUint32(&e.protocolManager.fastSync) == 1,
+			NoPruning:     config.NoPruning,
+			MaxReorgDepth: config.MaxReorgDepth,
+		},
+		Database: DatabaseConfig{
+			Engine:      engine,
+			Cache:       config.DatabaseCache,
+			Handles:     config.DatabaseHandles,
+			TrieCache:   config.TrieCache,
+			TrieTimeout: config.TrieTimeout.String(),
+		},
+		TxPool: TxPoolConfig{
+			NoLocals:     config.TxPool.NoLocals,
+			Journal:      config.TxPool.Journal,
+			Rejournal:    config.TxPool.Rejournal.String(),
+			PriceLimit:   config.TxPool.PriceLimit,
+			PriceBump:    config.TxPool.PriceBump,
+			AccountSlots: config.TxPool.AccountSlots,
+			GlobalSlots:  config.TxPool.GlobalSlots,
+			AccountQueue: config.TxPool.AccountQueue,
+			GlobalQueue:  config.TxPool.GlobalQueue,
+			Lifetime:     config.TxPool.Lifetime.String(),
+		},
+		GasPrice: GasPriceConfig{
+			Blocks:     config.GPO.Blocks,
+			Percentile: config.GPO.Percentile,
+			Default:    (*hexutil.Big)(config.GPO.Default),
+		},
+		Mining: MiningConfig{
+			Etherbase: config.Etherbase,
+			Threads:   config.MinerThreads,
+			ExtraData: config.ExtraData,
+			GasPrice:  (*hexutil.Big)(config.GasPrice),
+		},
+		Light: LightConfig{
+			Serve:        config.LightServ,
+			Peers:        config.LightPeers,
+			BudgetHourly: config.LightBudgetHourly,
+			BudgetDaily:  config.LightBudgetDaily,
+		},
+		Indexes: IndexesConfig{
+			InternalTxs: config.InternalTxIndex,
+			Preimages:   config.EnablePreimageRecording,
+		},
+		Alerts: AlertsConfig{
+			Enabled:      config.Alerts.URL != "",
+			Signed:       config.Alerts.Secret != "",
+			ReorgDepth:   config.Alerts.ReorgDepth,
+			StallTimeout: config.Alerts.StallTimeout.String(),
+			MinPeers:     config.Alerts.MinPeers,
+		},
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/goolabackend/downloader"
+	"github.com/goola-team/goola/params"
+)
+
+// Tests the structure of the reported node configuration, including the build
+// metadata of the binary.
+func TestNodeConfig(t *testing.T) {
+	defer func(commit, date string) { params.GitCommit, params.GitDate = commit, date }(params.GitCommit, params.GitDate)
+	params.GitCommit, params.GitDate = "0123456789abcdef0123456789abcdef01234567", "20181017"
+
+	pm, db := newTestProtocolManagerMust(t, downloader.FullSync, 4, nil, nil)
+	defer pm.Stop()
+
+	config := DefaultConfig
+	goola := &FullGoola{
+		config:          &config,
+		chainConfig:     params.TestChainConfig,
+		chainDb:         db,
+		blockchain:      pm.blockchain,
+		protocolManager: pm,
+		networkId:       config.NetworkId,
+	}
+	blob, err := json.Marshal(NewPublicGoolaAPI(goola).NodeConfig())
+	if err != nil {
+		t.Fatalf("failed to encode node config: %v", err)
+	}
+	var fields map[string]map[string]interface{}
+	if err := json.Unmarshal(blob, &fields); err != nil {
+		t.Fatalf("failed to decode node config: %v", err)
+	}
+	var sections []string
+	for section := range fields {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+	want := []string{"alerts", "build", "chain", "database", "gasPrice", "indexes", "light", "mining", "sync", "txpool"}
+	if !reflect.DeepEqual(sections, want) {
+		t.Fatalf("sections mismatch: have %v, want %v", sections, want)
+	}
+	build := fields["build"]
+	for key, value := range map[string]string{"version": params.Version, "commit": params.GitCommit, "date": params.GitDate} {
+		if build[key] != value {
+			t.Errorf("build %s mismatch: have %v, want %v", key, build[key], value)
+		}
+	}
+	if goVersion, _ := build["goVersion"].(string); !strings.HasPrefix(goVersion, "go") {
+		t.Errorf("go version mismatch: have %v", build["goVersion"])
+	}
+	if have := fields["chain"]["headNumber"]; have != "0x4" {
+		t.Errorf("head number mismatch: have %v, want 0x4", have)
+	}
+	if have := fields["database"]["engine"]; have != "memory" {
+		t.Errorf("database engine mismatch: have %v, want memory", have)
+	}
+}
+
+// Tests that the reported node configuration reflects the settings the node
+// actually runs with, leaving out the alert endpoint and secret.
+func TestNodeConfigSettings(t *testing.T) {
+	pm, db := newTestProtocolManagerMust(t, downloader.FastSync, 0, nil, nil)
+	defer pm.Stop()
+
+	config := DefaultConfig
+	config.SyncMode = downloader.FastSync
+	config.MaxReorgDepth = 64
+	config.DatabaseCache = 1024
+	config.TxPool.PriceLimit = 7
+	config.TxPool.Lifetime = 90 * time.Minute
+	config.GPO.Percentile = 42
+	config.Etherbase = common.Address{0x01}
+	config.LightServ, config.LightPeers = 50, 25
+	config.InternalTxIndex = true
+	config.Alerts.URL, config.Alerts.Secret = "https://alerts.example.org/hook", "s3cr3t"
+
+	goola := &FullGoola{
+		config:          &config,
+		chainConfig:     params.TestChainConfig,
+		chainDb:         db,
+		blockchain:      pm.blockchain,
+		protocolManager: pm,
+		networkId:       1234,
+	}
+	have := NewPublicGoolaAPI(goola).NodeConfig()
+
+	if have.Chain.NetworkId != 1234 || have.Chain.Genesis != pm.blockchain.Genesis().Hash() {
+		t.Errorf("chain mismatch: have network %d genesis %x", have.Chain.NetworkId, have.Chain.Genesis)
+	}
+	if want := (SyncConfig{Mode: "fast", FastSyncing: true, MaxReorgDepth: 64}); have.Sync != want {
+		t.Errorf("sync config mismatch: have %+v, want %+v", have.Sync, want)
+	}
+	if have.Database.Cache != 1024 || have.Database.Handles != config.DatabaseHandles {
+		t.Errorf("database config mismatch: have %+v", have.Database)
+	}
+	if have.TxPool.PriceLimit != 7 || have.TxPool.Lifetime != "1h30m0s" || have.TxPool.GlobalSlots != config.TxPool.GlobalSlots {
+		t.Errorf("txpool config mismatch: have %+v", have.TxPool)
+	}
+	if have.GasPrice.Percentile != 42 || have.GasPrice.Default.ToInt().Cmp(config.GPO.Default) != 0 {
+		t.Errorf("gas price config mismatch: have %+v", have.GasPrice)
+	}
+	if have.Mining.Etherbase != config.Etherbase || have.Mining.GasPrice.ToInt().Cmp(config.GasPrice) != 0 {
+		t.Errorf("mining config mismatch: have %+v", have.Mining)
+	}
+	if have.Light.Serve != 50 || have.Light.Peers != 25 {
+		t.Errorf("light config mismatch: have %+v", have.Light)
+	}
+	if !have.Indexes.InternalTxs || have.Indexes.Preimages {
+		t.Errorf("indexes mismatch: have %+v", have.Indexes)
+	}
+	if !have.Alerts.Enabled || !have.Alerts.Signed {
+		t.Errorf("alerts config mismatch: have %+v", have.Alerts)
+	}
+	blob, _ := json.Marshal(have)
+	for _, secret := range []string{config.Alerts.URL, config.Alerts.Secret} {
+		if strings.Contains(string(blob), secret) {
+			t.Errorf("node config leaks %q", secret)
+		}
+	}
+}
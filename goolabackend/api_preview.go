@@ -0,0 +1,93 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"math/big"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core/types"
+)
+
+// RPCPreviewTx is a transaction of a previewed block, in packing order.
+type RPCPreviewTx struct {
+	Hash              common.Hash     `json:"hash"`
+	From              common.Address  `json:"from"`
+	To                *common.Address `json:"to"`
+	Nonce             hexutil.Uint64  `json:"nonce"`
+	GasPrice          *hexutil.Big    `json:"gasPrice"`
+	GasUsed           hexutil.Uint64  `json:"gasUsed"`
+	CumulativeGasUsed hexutil.Uint64  `json:"cumulativeGasUsed"`
+	Fee               *hexutil.Big    `json:"fee"`
+}
+
+// RPCBlockPreview is the RPC representation of the block the miner would
+// produce next.
+type RPCBlockPreview struct {
+	Number       hexutil.Uint64  `json:"number"`
+	ParentHash   common.Hash     `json:"parentHash"`
+	Coinbase     common.Address  `json:"coinbase"`
+	Timestamp    hexutil.Uint64  `json:"timestamp"`
+	GasLimit     hexutil.Uint64  `json:"gasLimit"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	Fees         *hexutil.Big    `json:"fees"`
+	Transactions []*RPCPreviewTx `json:"transactions"`
+}
+
+// PendingBlockPreview runs the miner's transaction packing against the current
+// pool, returning the transactions the next block would include in order, the
+// gas they would use and the fee income they would yield. Nothing is sealed.
+func (api *PublicGoolaAPI) PendingBlockPreview() (*RPCBlockPreview, error) {
+	preview, err := api.e.Miner().Preview()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		block  = preview.Block
+		signer = types.NewEIP155Signer(api.e.chainConfig.ChainId)
+		txs    = make([]*RPCPreviewTx, len(block.Transactions()))
+	)
+	for i, tx := range block.Transactions() {
+		from, _ := types.Sender(signer, tx)
+		receipt := preview.Receipts[i]
+
+		fee := new(big.Int).SetUint64(receipt.GasUsed)
+		fee.Mul(fee, tx.GasPrice())
+
+		txs[i] = &RPCPreviewTx{
+			Hash:              tx.Hash(),
+			From:              from,
+			To:                tx.To(),
+			Nonce:             hexutil.Uint64(tx.Nonce()),
+			GasPrice:          (*hexutil.Big)(tx.GasPrice()),
+			GasUsed:           hexutil.Uint64(receipt.GasUsed),
+			CumulativeGasUsed: hexutil.Uint64(receipt.CumulativeGasUsed),
+			Fee:               (*hexutil.Big)(fee),
+		}
+	}
+	return &RPCBlockPreview{
+		Number:       hexutil.Uint64(block.NumberU64()),
+		ParentHash:   block.ParentHash(),
+		Coinbase:     block.Coinbase(),
+		Timestamp:    hexutil.Uint64(block.Time().Uint64()),
+		GasLimit:     hexutil.Uint64(block.GasLimit()),
+		GasUsed:      hexutil.Uint64(block.GasUsed()),
+		Fees:         (*hexutil.Big)(preview.Fees),
+		Transactions: txs,
+	}, nil
+}
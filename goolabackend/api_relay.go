@@ -0,0 +1,105 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/light"
+	"github.com/goola-team/goola/rlp"
+)
+
+// maxRelayProofs is the maximum number of trie entries that can be proven by a
+// single relay request, mirroring the LES limit on proof requests.
+const maxRelayProofs = 256
+
+// RelayProofQuery selects a trie entry to prove by its hashed key. An empty
+// account key selects the account trie, otherwise the storage trie of the
+// account with the given hashed address.
+type RelayProofQuery struct {
+	AccKey hexutil.Bytes `json:"accKey"`
+	Key    hexutil.Bytes `json:"key"`
+}
+
+// GetTrieProofs returns the merged Merkle proofs of the requested account and
+// storage trie entries at the state of the given block. The queries address
+// entries by hashed key, allowing light clients relaying their on-demand
+// requests to retrieve the same proofs an LES server would serve.
+func (api *PublicDebugAPI) GetTrieProofs(ctx context.Context, hash common.Hash, queries []RelayProofQuery) ([]hexutil.Bytes, error) {
+	if len(queries) > maxRelayProofs {
+		return nil, fmt.Errorf("too many proof queries: %d > %d", len(queries), maxRelayProofs)
+	}
+	chain := api.fullGoola.BlockChain()
+
+	header := chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, fmt.Errorf("block %x not found", hash)
+	}
+	statedb, err := chain.StateAt(header.Root)
+	if err != nil {
+		return nil, err
+	}
+	nodes := light.NewNodeSet()
+	for _, query := range queries {
+		tr, err := statedb.Database().OpenTrie(header.Root)
+		if err != nil {
+			return nil, err
+		}
+		if len(query.AccKey) > 0 {
+			blob, err := tr.TryGet(query.AccKey)
+			if err != nil {
+				return nil, err
+			}
+			if blob == nil {
+				return nil, fmt.Errorf("account %x not found", []byte(query.AccKey))
+			}
+			var account state.Account
+			if err := rlp.DecodeBytes(blob, &account); err != nil {
+				return nil, err
+			}
+			if tr, err = statedb.Database().OpenStorageTrie(common.BytesToHash(query.AccKey), account.Root); err != nil {
+				return nil, err
+			}
+		}
+		if err := tr.Prove(query.Key, 0, nodes); err != nil {
+			return nil, err
+		}
+	}
+	proof := make([]hexutil.Bytes, 0, nodes.KeyCount())
+	for _, node := range nodes.NodeList() {
+		proof = append(proof, hexutil.Bytes(node))
+	}
+	return proof, nil
+}
+
+// GetCodeByHash returns the contract code with the given hash.
+func (api *PublicDebugAPI) GetCodeByHash(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
+	statedb, err := api.fullGoola.BlockChain().State()
+	if err != nil {
+		return nil, err
+	}
+	code, err := statedb.Database().TrieDB().Node(hash)
+	if err != nil || crypto.Keccak256Hash(code) != hash {
+		return nil, fmt.Errorf("code %x not found", hash)
+	}
+	return code, nil
+}
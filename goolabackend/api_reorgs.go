@@ -0,0 +1,91 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"context"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/rpc"
+)
+
+// RPCRejectedReorg is the RPC representation of a block import refused for
+// reorganising the chain deeper than allowed.
+type RPCRejectedReorg struct {
+	Number     hexutil.Uint64 `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	HeadNumber hexutil.Uint64 `json:"headNumber"`
+	HeadHash   common.Hash    `json:"headHash"`
+	Depth      hexutil.Uint64 `json:"depth"`
+}
+
+// newRPCRejectedReorg converts a reorg rejection event into its RPC representation.
+func newRPCRejectedReorg(ev core.ReorgRejectedEvent) *RPCRejectedReorg {
+	return &RPCRejectedReorg{
+		Number:     hexutil.Uint64(ev.Block.NumberU64()),
+		Hash:       ev.Block.Hash(),
+		HeadNumber: hexutil.Uint64(ev.Head.NumberU64()),
+		HeadHash:   ev.Head.Hash(),
+		Depth:      hexutil.Uint64(ev.Depth),
+	}
+}
+
+// RejectedReorgs returns the most recent block imports refused for exceeding
+// the maximum reorg depth, oldest first.
+func (api *PublicGoolaAPI) RejectedReorgs() []*RPCRejectedReorg {
+	rejected := api.e.BlockChain().RejectedReorgs()
+
+	result := make([]*RPCRejectedReorg, len(rejected))
+	for i, ev := range rejected {
+		result[i] = newRPCRejectedReorg(ev)
+	}
+	return result
+}
+
+// ReorgAlerts creates a subscription that fires whenever a block import is
+// refused for exceeding the maximum reorg depth.
+func (api *PublicGoolaAPI) ReorgAlerts(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	// Subscribe right away not to miss rejections before the goroutine runs
+	rejected := make(chan core.ReorgRejectedEvent)
+	sub := api.e.BlockChain().SubscribeReorgRejectedEvent(rejected)
+
+	go func() {
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-rejected:
+				notifier.Notify(rpcSub.ID, newRPCRejectedReorg(ev))
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/consensus/dpos"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
+	"github.com/goola-team/goola/rpc"
+)
+
+// Tests that imports refused for reorganising too deep are listed and alerted
+// over RPC.
+func TestReorgAlerts(t *testing.T) {
+	var (
+		db, _   = gooladb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blockchain, _ := core.NewBlockChain(db, nil, gspec.Config, dpos.NewFaker(), vm.Config{})
+	defer blockchain.Stop()
+	blockchain.SetMaxReorgDepth(2)
+
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, dpos.NewFaker(), db, 5, nil)
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("goola", NewPublicGoolaAPI(&FullGoola{blockchain: blockchain})); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	alerts := make(chan *RPCRejectedReorg, 1)
+	sub, err := client.Subscribe(context.Background(), "goola", alerts, "reorgAlerts")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Import a heavier fork dropping all but the first canonical block
+	fork, _ := core.GenerateChain(gspec.Config, blocks[0], dpos.NewFaker(), db, 5, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{1})
+	})
+	if _, err := blockchain.InsertChain(fork); err != core.ErrReorgTooDeep {
+		t.Fatalf("deep reorg error mismatch: have %v, want %v", err, core.ErrReorgTooDeep)
+	}
+	want := RPCRejectedReorg{
+		Number:     hexutil.Uint64(fork[4].NumberU64()),
+		Hash:       fork[4].Hash(),
+		HeadNumber: hexutil.Uint64(blocks[4].NumberU64()),
+		HeadHash:   blocks[4].Hash(),
+		Depth:      4,
+	}
+	select {
+	case alert := <-alerts:
+		if *alert != want {
+			t.Errorf("reorg alert mismatch: have %+v, want %+v", alert, want)
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(time.Second):
+		t.Fatalf("no alert for refused reorg")
+	}
+	var rejected []*RPCRejectedReorg
+	if err := client.Call(&rejected, "goola_rejectedReorgs"); err != nil {
+		t.Fatalf("failed to list rejected reorgs: %v", err)
+	}
+	if len(rejected) != 1 || *rejected[0] != want {
+		t.Errorf("rejected reorgs mismatch: have %v, want [%+v]", rejected, want)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"context"
+	"errors"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/consensus"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rpc"
+)
+
+// maxWatchedSlots is the maximum number of storage slots a single subscription
+// may watch.
+const maxWatchedSlots = 1024
+
+var errTooManySlots = errors.New("too many watched storage slots")
+
+// StorageWatchCriteria selects the storage slots of a contract to watch. An
+// empty slot list watches the entire storage of the contract.
+type StorageWatchCriteria struct {
+	Address common.Address `json:"address"`
+	Slots   []common.Hash  `json:"slots"`
+}
+
+// RPCStorageChange is the notification of watched storage slots changed by a
+// canonical block, carrying their new values.
+type RPCStorageChange struct {
+	BlockNumber hexutil.Uint64              `json:"blockNumber"`
+	BlockHash   common.Hash                 `json:"blockHash"`
+	Address     common.Address              `json:"address"`
+	Changes     map[common.Hash]common.Hash `json:"changes"`
+}
+
+// watchedSlots returns the slots in the written set which are watched, or all
+// of them if every slot is watched (nil set).
+func watchedSlots(written []common.Hash, watched map[common.Hash]struct{}) []common.Hash {
+	if watched == nil {
+		return written
+	}
+	var slots []common.Hash
+	for _, slot := range written {
+		if _, ok := watched[slot]; ok {
+			slots = append(slots, slot)
+		}
+	}
+	return slots
+}
+
+// storageChanges returns the new values of the given slots of a contract which
+// were changed by the block, skipping slots rewritten with their old value.
+func (api *PublicEthereumAPI) storageChanges(block *types.Block, addr common.Address, slots []common.Hash) (map[common.Hash]common.Hash, error) {
+	chain := api.e.BlockChain()
+
+	post, err := chain.StateAt(block.Root())
+	if err != nil {
+		return nil, err
+	}
+	parent := chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	pre, err := chain.StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(map[common.Hash]common.Hash)
+	for _, slot := range slots {
+		if value := post.GetState(addr, slot); value != pre.GetState(addr, slot) {
+			changes[slot] = value
+		}
+	}
+	return changes, nil
+}
+
+// Storage creates a subscription that fires whenever a block imported as the
+// new canonical head changes any of the watched storage slots of a contract.
+func (api *PublicEthereumAPI) Storage(ctx context.Context, crit StorageWatchCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if len(crit.Slots) > maxWatchedSlots {
+		return nil, errTooManySlots
+	}
+	var watched map[common.Hash]struct{}
+	if len(crit.Slots) > 0 {
+		watched = make(map[common.Hash]struct{}, len(crit.Slots))
+		for _, slot := range crit.Slots {
+			watched[slot] = struct{}{}
+		}
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		touches := make(chan core.StateTouchEvent, 16)
+		sub := api.e.BlockChain().SubscribeStateTouchEvent(touches)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-touches:
+				slots := watchedSlots(ev.Touched.Storage[crit.Address], watched)
+				if len(slots) == 0 {
+					continue
+				}
+				changes, err := api.storageChanges(ev.Block, crit.Address, slots)
+				if err != nil {
+					log.Warn("Failed to compute storage changes", "block", ev.Block.Number(), "address", crit.Address, "err", err)
+					continue
+				}
+				if len(changes) > 0 {
+					notifier.Notify(rpcSub.ID, &RPCStorageChange{
+						BlockNumber: hexutil.Uint64(ev.Block.NumberU64()),
+						BlockHash:   ev.Block.Hash(),
+						Address:     crit.Address,
+						Changes:     changes,
+					})
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -17,13 +17,21 @@
 package goolabackend
 
 import (
+	"bytes"
+	"math/big"
 	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/consensus/dpos"
+	"github.com/goola-team/goola/core"
 	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/core/vm"
 	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
 )
 
 var dumper = spew.ConfigState{Indent: "    "}
@@ -89,3 +97,72 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that only the watched slots of a written set are selected, with a nil
+// watch set selecting them all.
+func TestWatchedSlots(t *testing.T) {
+	written := []common.Hash{{0x01}, {0x02}, {0x03}}
+
+	if slots := watchedSlots(written, nil); !reflect.DeepEqual(slots, written) {
+		t.Errorf("unfiltered slots mismatch: have %x, want %x", slots, written)
+	}
+	watched := map[common.Hash]struct{}{{0x02}: {}, {0x04}: {}}
+	if slots := watchedSlots(written, watched); !reflect.DeepEqual(slots, []common.Hash{{0x02}}) {
+		t.Errorf("filtered slots mismatch: have %x, want [02..]", slots)
+	}
+	if slots := watchedSlots(nil, watched); len(slots) != 0 {
+		t.Errorf("slots selected from empty write set: %x", slots)
+	}
+}
+
+// Tests that the accounts modified between blocks are collected from the reverse
+// state diffs if recorded, matching the ones found by comparing the state tries.
+func TestGetModifiedAccounts(t *testing.T) {
+	var (
+		gspec   = &core.Genesis{Config: params.TestChainConfig, Alloc: core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000000)}}}
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+		payees  = []common.Address{{0x01}, {0x02}}
+		db, _   = gooladb.NewMemDatabase()
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, dpos.NewFaker(), db, len(payees), func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(testBank), payees[i], big.NewInt(1000), 21000, big.NewInt(1), types.TxTypeTransfer, nil), signer, testBankKey)
+		b.AddTx(tx)
+	})
+	// Import the chain into archive nodes with and without reverse state diffs
+	modified := func(diffs bool, start uint64, end uint64) []common.Address {
+		db, _ := gooladb.NewMemDatabase()
+		gspec.MustCommit(db)
+
+		blockchain, _ := core.NewBlockChain(db, &core.CacheConfig{Disabled: true, StateDiffs: diffs}, gspec.Config, dpos.NewFaker(), vm.Config{})
+		defer blockchain.Stop()
+		if _, err := blockchain.InsertChain(blocks); err != nil {
+			t.Fatalf("failed to insert chain: %v", err)
+		}
+		api := NewPrivateDebugAPI(gspec.Config, &FullGoola{chainDb: db, blockchain: blockchain})
+		if diffs && api.getModifiedAccountsFromDiffs(blockchain.GetBlockByNumber(start), blockchain.GetBlockByNumber(end)) == nil {
+			t.Fatalf("modified accounts not collected from state diffs")
+		}
+		dirty, err := api.GetModifiedAccountsByNumber(start, &end)
+		if err != nil {
+			t.Fatalf("failed to collect modified accounts: %v", err)
+		}
+		sort.Slice(dirty, func(i, j int) bool { return bytes.Compare(dirty[i][:], dirty[j][:]) < 0 })
+		return dirty
+	}
+	for _, span := range [][2]uint64{{0, 1}, {1, 2}, {0, 2}} {
+		have, want := modified(true, span[0], span[1]), modified(false, span[0], span[1])
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("blocks %d-%d: modified accounts mismatch: have %x, want %x", span[0], span[1], have, want)
+		}
+		for i, payee := range payees {
+			found := false
+			for _, addr := range have {
+				found = found || addr == payee
+			}
+			if paid := uint64(i) >= span[0] && uint64(i) < span[1]; found != paid {
+				t.Errorf("blocks %d-%d: payee %x reported %v, want %v", span[0], span[1], payee, found, paid)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/rpc"
+)
+
+// RPCTouchedState is the RPC representation of the accounts and storage slots
+// modified by a block.
+type RPCTouchedState struct {
+	BlockNumber hexutil.Uint64                   `json:"blockNumber"`
+	BlockHash   common.Hash                      `json:"blockHash"`
+	Accounts    []common.Address                 `json:"accounts"`
+	Storage     map[common.Address][]common.Hash `json:"storage"`
+}
+
+// newRPCTouchedState converts the state modified by a block into its RPC
+// representation.
+func newRPCTouchedState(block *types.Block, touched *core.TouchedState) *RPCTouchedState {
+	return &RPCTouchedState{
+		BlockNumber: hexutil.Uint64(block.NumberU64()),
+		BlockHash:   block.Hash(),
+		Accounts:    touched.Accounts,
+		Storage:     touched.Storage,
+	}
+}
+
+// GetTouchedState returns the accounts and storage slots modified by a block.
+// The record is only available for blocks recently imported by this node.
+func (api *PrivateDebugAPI) GetTouchedState(ctx context.Context, hash common.Hash) (*RPCTouchedState, error) {
+	chain := api.fullGoola.BlockChain()
+
+	block := chain.GetBlockByHash(hash)
+	if block == nil {
+		return nil, fmt.Errorf("block %x not found", hash)
+	}
+	touched := chain.TouchedState(hash)
+	if touched == nil {
+		return nil, fmt.Errorf("no touched state recorded for block %x", hash)
+	}
+	return newRPCTouchedState(block, touched), nil
+}
+
+// StateTouches creates a subscription that fires for every block imported as
+// the new canonical head, with the accounts and storage slots it modified.
+func (api *PrivateDebugAPI) StateTouches(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		touches := make(chan core.StateTouchEvent, 16)
+		sub := api.fullGoola.BlockChain().SubscribeStateTouchEvent(touches)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-touches:
+				notifier.Notify(rpcSub.ID, newRPCTouchedState(ev.Block, ev.Touched))
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
@@ -567,15 +567,29 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 				return nil, err
 			}
 		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer); err != nil {
-			return nil, err
+		// Constuct the external or JavaScript tracer to execute with
+		var stoppable interface{ Stop(error) }
+		if tracers.IsExternal(*config.Tracer) {
+			external, err := api.fullGoola.config.ExternalTracers.NewExternal(*config.Tracer)
+			if err != nil {
+				return nil, err
+			}
+			tracer, stoppable = external, external
+		} else {
+			js, err := tracers.New(*config.Tracer)
+			if err != nil {
+				return nil, err
+			}
+			tracer, stoppable = js, js
 		}
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
+			if deadlineCtx.Err() == context.DeadlineExceeded {
+				rpc.RequestLogger(ctx).Debug("Transaction trace timed out", "timeout", timeout)
+			}
+			stoppable.Stop(errors.New("execution timeout"))
 		}()
 		defer cancel()
 
@@ -605,6 +619,9 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	case *tracers.Tracer:
 		return tracer.GetResult()
 
+	case tracers.ExternalTracer:
+		return tracer.GetResult()
+
 	default:
 		panic(fmt.Sprintf("bad tracer type %T", tracer))
 	}
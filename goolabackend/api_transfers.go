@@ -0,0 +1,125 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/goolabackend/filters"
+	"github.com/goola-team/goola/internal/ethapi"
+	"github.com/goola-team/goola/rpc"
+)
+
+// RPCTokenTransfers is the number of transfers of a token within a block.
+type RPCTokenTransfers struct {
+	Token     common.Address `json:"token"`
+	Transfers hexutil.Uint   `json:"transfers"`
+}
+
+// RPCTransferSummary is the summary of the value moved by the transactions of a
+// block, computed from its receipts.
+type RPCTransferSummary struct {
+	BlockHash    common.Hash          `json:"blockHash"`
+	BlockNumber  hexutil.Uint64       `json:"blockNumber"`
+	Transactions hexutil.Uint         `json:"transactions"`
+	Failed       hexutil.Uint         `json:"failed"`  // Transactions whose execution failed
+	Value        *hexutil.Big         `json:"value"`   // Native value moved by the successful transactions
+	Fees         *hexutil.Big         `json:"fees"`    // Gas fees paid by all transactions
+	GasUsed      hexutil.Uint64       `json:"gasUsed"` // Gas used by all transactions
+	Tokens       []*RPCTokenTransfers `json:"tokens"`  // Transfers of the tokens with registered ABIs
+}
+
+// BlockTransferSummary summarizes the native value moved, the fees paid and the
+// token transfers made by the transactions of a block. The receipts are verified
+// against the receipt root of the block header before being summarized. Token
+// transfers are counted for the contracts with a registered ABI defining a
+// Transfer event.
+func (api *PublicGoolaAPI) BlockTransferSummary(ctx context.Context, blockNr rpc.BlockNumber) (*RPCTransferSummary, error) {
+	block, err := api.e.ApiBackend.BlockByNumber(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, &ethapi.NotFoundError{Kind: "block", Message: fmt.Sprintf("block #%d not found", blockNr)}
+	}
+	receipts, err := api.e.ApiBackend.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) != len(block.Transactions()) {
+		return nil, fmt.Errorf("receipts of block #%d unavailable", block.NumberU64())
+	}
+	if root := types.DeriveSha(receipts); root != block.ReceiptHash() {
+		return nil, fmt.Errorf("receipt root mismatch of block #%d: have %x, want %x", block.NumberU64(), root, block.ReceiptHash())
+	}
+	return summarizeTransfers(block, receipts, filters.NewABIRegistry(api.e.ChainDb())), nil
+}
+
+// summarizeTransfers summarizes the value moved by the transactions of a block
+// from their receipts, decoding the token transfers with the ABI registry.
+func summarizeTransfers(block *types.Block, receipts types.Receipts, registry *filters.ABIRegistry) *RPCTransferSummary {
+	var (
+		value   = new(big.Int)
+		fees    = new(big.Int)
+		gasUsed uint64
+		failed  uint
+		logs    []*types.Log
+	)
+	for i, tx := range block.Transactions() {
+		receipt := receipts[i]
+
+		gasUsed += receipt.GasUsed
+		fees.Add(fees, new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), tx.GasPrice()))
+
+		// Receipts predating the status field carry the post state root instead
+		if len(receipt.PostState) == 0 && receipt.Status == types.ReceiptStatusFailed {
+			failed++
+			continue
+		}
+		value.Add(value, tx.Value())
+		logs = append(logs, receipt.Logs...)
+	}
+	transfers := make(map[common.Address]uint)
+	for _, log := range registry.Decode(logs) {
+		if log.Event != nil && log.Event.Name == "Transfer" {
+			transfers[log.Log.Address]++
+		}
+	}
+	tokens := make([]*RPCTokenTransfers, 0, len(transfers))
+	for token, count := range transfers {
+		tokens = append(tokens, &RPCTokenTransfers{Token: token, Transfers: hexutil.Uint(count)})
+	}
+	sort.Slice(tokens, func(i, j int) bool { return bytes.Compare(tokens[i].Token[:], tokens[j].Token[:]) < 0 })
+
+	return &RPCTransferSummary{
+		BlockHash:    block.Hash(),
+		BlockNumber:  hexutil.Uint64(block.NumberU64()),
+		Transactions: hexutil.Uint(len(block.Transactions())),
+		Failed:       hexutil.Uint(failed),
+		Value:        (*hexutil.Big)(value),
+		Fees:         (*hexutil.Big)(fees),
+		GasUsed:      hexutil.Uint64(gasUsed),
+		Tokens:       tokens,
+	}
+}
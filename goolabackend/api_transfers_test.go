@@ -0,0 +1,83 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/goolabackend/filters"
+	"github.com/goola-team/goola/gooladb"
+)
+
+// Tests that transfer summaries account for the value of successful transactions
+// only, the fees of all of them and the token transfers of registered contracts.
+func TestSummarizeTransfers(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	registry := filters.NewABIRegistry(db)
+
+	token := common.Address{0xaa}
+	if err := registry.Register(token, `[{"type":"event","name":"Transfer","anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"value","type":"uint256"}]}]`); err != nil {
+		t.Fatalf("failed to register ABI: %v", err)
+	}
+	transfer := &types.Log{
+		Address: token,
+		Topics: []common.Hash{
+			crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")),
+			common.BytesToHash(common.Address{0x01}.Bytes()),
+			common.BytesToHash(common.Address{0x02}.Bytes()),
+		},
+		Data: common.BigToHash(big.NewInt(1000)).Bytes(),
+	}
+	unknown := &types.Log{Address: common.Address{0xbb}, Topics: transfer.Topics, Data: transfer.Data}
+
+	txs := []*types.Transaction{
+		types.NewTransaction(0, common.Address{0x01}, big.NewInt(100), 21000, big.NewInt(2), types.TxTypeTransfer, nil),
+		types.NewTransaction(1, token, big.NewInt(0), 50000, big.NewInt(3), types.TxTypeTransfer, nil),
+		types.NewTransaction(2, common.Address{0x02}, big.NewInt(500), 21000, big.NewInt(1), types.TxTypeTransfer, nil),
+	}
+	receipts := types.Receipts{
+		types.NewReceipt(nil, false, 21000),
+		types.NewReceipt(nil, false, 61000),
+		types.NewReceipt(nil, true, 82000),
+	}
+	receipts[0].GasUsed, receipts[1].GasUsed, receipts[2].GasUsed = 21000, 40000, 21000
+	receipts[1].Logs = []*types.Log{transfer, unknown, transfer}
+	receipts[2].Logs = []*types.Log{transfer}
+
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, txs, receipts)
+	summary := summarizeTransfers(block, receipts, registry)
+
+	if summary.Transactions != 3 || summary.Failed != 1 {
+		t.Errorf("transaction counts mismatch: have %d/%d, want 3/1", summary.Transactions, summary.Failed)
+	}
+	if value := summary.Value.ToInt(); value.Int64() != 100 {
+		t.Errorf("value mismatch: have %v, want 100", value)
+	}
+	if fees := summary.Fees.ToInt(); fees.Int64() != 21000*2+40000*3+21000*1 {
+		t.Errorf("fees mismatch: have %v, want %d", fees, 21000*2+40000*3+21000*1)
+	}
+	if summary.GasUsed != 82000 {
+		t.Errorf("gas used mismatch: have %d, want 82000", summary.GasUsed)
+	}
+	if len(summary.Tokens) != 1 || summary.Tokens[0].Token != token || summary.Tokens[0].Transfers != 2 {
+		t.Errorf("token transfers mismatch: %+v", summary.Tokens)
+	}
+}
@@ -18,6 +18,7 @@
 package goolabackend
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math/big"
@@ -36,7 +37,10 @@ import (
 	"github.com/goola-team/goola/core/vm"
 	"github.com/goola-team/goola/goolabackend/downloader"
 	"github.com/goola-team/goola/goolabackend/filters"
+	"github.com/goola-team/goola/goolabackend/alerts"
 	"github.com/goola-team/goola/goolabackend/gasprice"
+	"github.com/goola-team/goola/goolabackend/maintenance"
+	"github.com/goola-team/goola/goolabackend/snapshot"
 	"github.com/goola-team/goola/gooladb"
 	"github.com/goola-team/goola/event"
 	"github.com/goola-team/goola/internal/ethapi"
@@ -49,11 +53,16 @@ import (
 	"github.com/goola-team/goola/rpc"
 )
 
+// errGoolaseMissing is returned if no goolase is set and none can be derived
+// from the local accounts.
+var errGoolaseMissing = &ethapi.UnavailableError{Feature: "goolase", Message: "goolase must be explicitly specified"}
+
 type LesServer interface {
 	Start(srvr *p2p.Server)
 	Stop()
 	Protocols() []p2p.Protocol
 	SetBloomBitsIndexer(bbIndexer *core.ChainIndexer)
+	APIs() []rpc.API
 }
 
 // FullGoola implements the FullGoola full node service.
@@ -73,13 +82,17 @@ type FullGoola struct {
 
 	// DB interfaces
 	chainDb gooladb.Database // Block chain database
+	dataDir string           // Directory of the block chain database, empty if ephemeral
 
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
 	accountManager *accounts.Manager
 
-	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
-	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
+	bloomRequests   chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
+	bloomIndexer    *core.ChainIndexer             // Bloom indexer operating during block imports
+	bloomRebuilding int32                          // Whether a bloom index rebuild is in progress (atomic)
+	blockStats      *blockStats                    // Import time aggregator of the recent blocks' gas usage
+	balanceHistory  *balanceHistory                // Import time index of the watched accounts' balances (nil = disabled)
 
 	ApiBackend *GoolaApiBackend
 
@@ -90,6 +103,17 @@ type FullGoola struct {
 	networkId     uint64
 	netRPCService *ethapi.PublicNetAPI
 
+	alerter   *alerts.Notifier       // Alert webhook notifier, nil if not configured
+	scheduler *maintenance.Scheduler // Runner of the heavy background maintenance tasks
+	seeder    *snapshot.Server       // Chain snapshot HTTP seeder, nil if not configured
+
+	configLoader func() (*Config, error) // Source of reloaded configurations, nil if unsupported
+	reloadLock   sync.Mutex              // Serializes configuration reloads
+
+	frozen       bool       // Whether the node is in emergency read-only mode
+	frozenMining bool       // Whether the node was mining when frozen
+	freezeLock   sync.Mutex // Serializes freezing and unfreezing the node
+
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and goolase)
 }
 
@@ -101,6 +125,9 @@ func (fullGoola *FullGoola) AddLesServer(ls LesServer) {
 // New creates a new FullGoola object (including the
 // initialisation of the common FullGoola object)
 func New(ctx *node.ServiceContext, config *Config) (*FullGoola, error) {
+	if err := config.ApplyProfile(); err != nil {
+		return nil, err
+	}
 	if config.SyncMode == downloader.LightSync {
 		return nil, errors.New("can't run goolabackend.goola in light sync mode, use les.LightGoola")
 	}
@@ -121,6 +148,7 @@ func New(ctx *node.ServiceContext, config *Config) (*FullGoola, error) {
 	fullGoola := &FullGoola{
 		config:         config,
 		chainDb:        chainDb,
+		dataDir:        ctx.ResolvePath("chaindata"),
 		chainConfig:    chainConfig,
 		eventMux:       ctx.EventMux,
 		accountManager: ctx.AccountManager,
@@ -145,12 +173,22 @@ func New(ctx *node.ServiceContext, config *Config) (*FullGoola, error) {
 	}
 	var (
 		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout, StateDiffs: config.StateDiffs, ImportBuffer: config.ImportBuffer}
 	)
 	fullGoola.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, fullGoola.chainConfig, fullGoola.engine, vmConfig)
 	if err != nil {
 		return nil, err
 	}
+	fullGoola.blockchain.SetInternalTxIndexing(config.InternalTxIndex)
+	fullGoola.blockchain.SetMaxReorgDepth(config.MaxReorgDepth)
+	fullGoola.blockchain.SetHistoryRetention(config.RetainBlocks)
+	fullGoola.blockStats = newBlockStats(fullGoola.blockchain)
+	if len(config.BalanceHistory) > 0 {
+		fullGoola.balanceHistory = newBalanceHistory(fullGoola.blockchain, chainDb, config.BalanceHistory, config.BalanceHistoryStep)
+	}
+	fullGoola.blockchain.SetBlobRetention(config.BlobRetention)
+	fullGoola.blockchain.SetParallelExecution(config.ParallelExecution)
+
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -159,6 +197,11 @@ func New(ctx *node.ServiceContext, config *Config) (*FullGoola, error) {
 	}
 	fullGoola.bloomIndexer.Start(fullGoola.blockchain)
 
+	// Warn if the chain is scheduled to fork in ways this version can't follow
+	for _, warning := range fullGoola.forkReadiness() {
+		log.Warn("Node not ready for scheduled fork, upgrade required", "reason", warning)
+	}
+
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
@@ -167,8 +210,19 @@ func New(ctx *node.ServiceContext, config *Config) (*FullGoola, error) {
 	if fullGoola.protocolManager, err = NewProtocolManager(fullGoola.chainConfig, config.SyncMode, config.NetworkId, fullGoola.eventMux, fullGoola.txPool, fullGoola.engine, fullGoola.blockchain, chainDb); err != nil {
 		return nil, err
 	}
+	fullGoola.protocolManager.SetQueryLimits(config.QueryLimits)
+	fullGoola.protocolManager.SetTxPrivacy(config.TxPrivacy)
+
 	fullGoola.miner = miner.New(fullGoola, fullGoola.chainConfig, fullGoola.EventMux(), fullGoola.engine)
-	fullGoola.miner.SetExtra(makeExtraData(config.ExtraData))
+	fullGoola.miner.SetNodeName(ctx.Identity())
+	if bytes.Contains(config.ExtraData, []byte("{{")) {
+		if err := fullGoola.miner.SetExtraTemplate(string(config.ExtraData), nil); err != nil {
+			log.Warn("Invalid miner extra template", "template", string(config.ExtraData), "err", err)
+			fullGoola.miner.SetExtra(makeExtraData(nil))
+		}
+	} else {
+		fullGoola.miner.SetExtra(makeExtraData(config.ExtraData))
+	}
 
 	fullGoola.ApiBackend = &GoolaApiBackend{fullGoola, nil}
 	gpoParams := config.GPO
@@ -177,6 +231,9 @@ func New(ctx *node.ServiceContext, config *Config) (*FullGoola, error) {
 	}
 	fullGoola.ApiBackend.gpo = gasprice.NewOracle(fullGoola.ApiBackend, gpoParams)
 
+	if fullGoola.scheduler, err = newMaintenanceScheduler(fullGoola, &config.Maintenance); err != nil {
+		return nil, err
+	}
 	return fullGoola, nil
 }
 
@@ -211,10 +268,10 @@ func CreateDB(ctx *node.ServiceContext, config *Config, name string) (gooladb.Da
 
 // CreateConsensusEngine creates the required type of consensus engine instance for an Goola service
 func CreateConsensusEngine(ctx *node.ServiceContext, chainConfig *params.ChainConfig, db gooladb.Database) consensus.Engine {
-
-	engine := dpos.New(dpos.Config{
-	})
-	return engine
+	config := dpos.Config{
+		Scheduled: chainConfig.Ethash != nil && chainConfig.Ethash.Scheduled(),
+	}
+	return dpos.New(config, db)
 }
 
 // APIs returns the collection of RPC services the Goola package offers.
@@ -225,6 +282,11 @@ func (fullGoola *FullGoola) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, fullGoola.engine.APIs(fullGoola.BlockChain())...)
 
+	// Append the APIs of the light server, if running
+	if fullGoola.lesServer != nil {
+		apis = append(apis, fullGoola.lesServer.APIs()...)
+	}
+
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -232,6 +294,16 @@ func (fullGoola *FullGoola) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicEthereumAPI(fullGoola),
 			Public:    true,
+		}, {
+			Namespace: "goola",
+			Version:   "1.0",
+			Service:   NewPublicGoolaAPI(fullGoola),
+			Public:    true,
+		}, {
+			Namespace: "node",
+			Version:   "1.0",
+			Service:   NewPublicNodeHealthAPI(fullGoola),
+			Public:    true,
 		}, {
 			Namespace: "goolabackend",
 			Version:   "1.0",
@@ -251,6 +323,10 @@ func (fullGoola *FullGoola) APIs() []rpc.API {
 			Namespace: "admin",
 			Version:   "1.0",
 			Service:   NewPrivateAdminAPI(fullGoola),
+		}, {
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   filters.NewPrivateABIRegistryAPI(filters.NewABIRegistry(fullGoola.chainDb)),
 		}, {
 			Namespace: "debug",
 			Version:   "1.0",
@@ -293,7 +369,7 @@ func (fullGoola *FullGoola) Goolase() (eb common.Address, err error) {
 			return goolase, nil
 		}
 	}
-	return common.Address{}, fmt.Errorf("goolase must be explicitly specified")
+	return common.Address{}, errGoolaseMissing
 }
 
 // set in js console via admin interface or wrapper from cli flags
@@ -306,19 +382,26 @@ func (fullGoola *FullGoola) SetEtherbase(goolase common.Address) {
 }
 
 func (fullGoola *FullGoola) StartMining(local bool) error {
+	if fullGoola.Frozen() {
+		return errNodeFrozen
+	}
 	eb, err := fullGoola.Goolase()
 	if err != nil {
 		log.Error("Cannot start mining without goolase", "err", err)
-		return fmt.Errorf("etherbase missing: %v", err)
-	}
-	//if clique, ok := fullGoola.engine.(*clique.Clique); ok {
-	//	wallet, err := fullGoola.accountManager.Find(accounts.Account{Address: eb})
-	//	if wallet == nil || err != nil {
-	//		log.Error("Goolase account unavailable locally", "err", err)
-	//		return fmt.Errorf("signer missing: %v", err)
-	//	}
-	//	clique.Authorize(eb, wallet.SignHash)
-	//}
+		return err
+	}
+	// Blocks of scheduled chains are signed by their producer, authorize it
+	engine, ok := fullGoola.engine.(interface {
+		Authorize(common.Address, dpos.SignerFn)
+	})
+	if config := fullGoola.blockchain.Config().Ethash; ok && config != nil && config.Scheduled() {
+		wallet, err := fullGoola.accountManager.Find(accounts.Account{Address: eb})
+		if wallet == nil || err != nil {
+			log.Error("Goolase account unavailable locally", "err", err)
+			return fmt.Errorf("signer missing: %v", err)
+		}
+		engine.Authorize(eb, wallet.SignHash)
+	}
 	if local {
 		// If local (CPU) mining is started, we can disable the transaction rejection
 		// mechanism introduced to speed sync times. CPU mining on mainnet is ludicrous
@@ -371,11 +454,45 @@ func (fullGoola *FullGoola) Start(srvr *p2p.Server) error {
 		}
 		maxPeers -= fullGoola.config.LightPeers
 	}
+	// Bootstrap an empty chain from a trusted snapshot seeder if requested
+	if fullGoola.config.Snapshot.URL != "" {
+		imported, err := snapshot.Bootstrap(fullGoola.blockchain, fullGoola.config.Snapshot.URL, fullGoola.config.Snapshot.Signer)
+		if err != nil {
+			log.Error("Snapshot bootstrap failed", "url", fullGoola.config.Snapshot.URL, "err", err)
+		}
+		if imported > 0 {
+			// Blocks were imported, fast sync can no longer be used
+			atomic.StoreUint32(&fullGoola.protocolManager.fastSync, 0)
+		}
+	}
+	// Keep connected to the relays of the local transactions
+	if fullGoola.config.TxPrivacy.Enabled {
+		for _, node := range fullGoola.config.TxPrivacy.Relays {
+			srvr.AddPeer(node)
+		}
+	}
 	// Start the networking layer and the light server if requested
 	fullGoola.protocolManager.Start(maxPeers)
 	if fullGoola.lesServer != nil {
 		fullGoola.lesServer.Start(srvr)
 	}
+	// Start reporting chain health incidents if requested
+	if fullGoola.config.Alerts.URL != "" {
+		fullGoola.alerter = alerts.New(&fullGoola.config.Alerts, fullGoola.blockchain, srvr.PeerCount)
+		fullGoola.alerter.Start()
+	}
+	// Start seeding the local chain snapshot if requested
+	if fullGoola.config.Snapshot.Listen != "" {
+		fullGoola.seeder = snapshot.NewServer(fullGoola.blockchain, srvr.PrivateKey)
+		if err := fullGoola.seeder.Start(fullGoola.config.Snapshot.Listen); err != nil {
+			return err
+		}
+	}
+	fullGoola.blockStats.Start()
+	if fullGoola.balanceHistory != nil {
+		fullGoola.balanceHistory.Start()
+	}
+	fullGoola.scheduler.Start()
 	return nil
 }
 
@@ -385,6 +502,17 @@ func (fullGoola *FullGoola) Stop() error {
 	if fullGoola.stopDbUpgrade != nil {
 		fullGoola.stopDbUpgrade()
 	}
+	if fullGoola.alerter != nil {
+		fullGoola.alerter.Stop()
+	}
+	if fullGoola.seeder != nil {
+		fullGoola.seeder.Stop()
+	}
+	fullGoola.scheduler.Stop()
+	fullGoola.blockStats.Stop()
+	if fullGoola.balanceHistory != nil {
+		fullGoola.balanceHistory.Stop()
+	}
 	fullGoola.bloomIndexer.Close()
 	fullGoola.blockchain.Stop()
 	fullGoola.protocolManager.Stop()
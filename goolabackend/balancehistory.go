@@ -0,0 +1,223 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/event"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rpc"
+)
+
+const (
+	// balanceHistoryWalkback is the maximum number of blocks indexed on a single
+	// chain head update. States older than the recent ones kept in memory are
+	// usually pruned, so going further back is pointless on a non-archive node.
+	balanceHistoryWalkback = 128
+
+	// maxBalanceHistoryPoints is the maximum number of balances returned by a
+	// single history query.
+	maxBalanceHistoryPoints = 4096
+
+	// balanceHistoryChanSize is the size of channel listening to ChainHeadEvent.
+	balanceHistoryChanSize = 10
+)
+
+var errBalanceHistoryDisabled = errors.New("balance history index disabled")
+
+// balanceHistoryChain is the chain access needed to index account balances.
+type balanceHistoryChain interface {
+	CurrentBlock() *types.Block
+	GetBlock(hash common.Hash, number uint64) *types.Block
+	StateAt(root common.Hash) (*state.StateDB, error)
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// balanceHistory records the balances of a configured set of accounts at every
+// step-th canonical block as it is imported, so charting them doesn't require
+// repeated archive state queries.
+type balanceHistory struct {
+	chain    balanceHistoryChain
+	db       gooladb.Database
+	accounts []common.Address
+	watched  map[common.Address]bool
+	step     uint64
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newBalanceHistory(chain balanceHistoryChain, db gooladb.Database, accounts []common.Address, step uint64) *balanceHistory {
+	if step == 0 {
+		step = 1
+	}
+	watched := make(map[common.Address]bool, len(accounts))
+	for _, addr := range accounts {
+		watched[addr] = true
+	}
+	return &balanceHistory{
+		chain:    chain,
+		db:       db,
+		accounts: accounts,
+		watched:  watched,
+		step:     step,
+		quit:     make(chan struct{}),
+	}
+}
+
+// Start indexes the recent blocks and starts following the chain head.
+func (h *balanceHistory) Start() {
+	h.wg.Add(1)
+	go h.loop()
+}
+
+// Stop terminates following the chain head.
+func (h *balanceHistory) Stop() {
+	close(h.quit)
+	h.wg.Wait()
+}
+
+func (h *balanceHistory) loop() {
+	defer h.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, balanceHistoryChanSize)
+	sub := h.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	h.update(h.chain.CurrentBlock())
+	for {
+		select {
+		case ev := <-heads:
+			h.update(ev.Block)
+		case <-sub.Err():
+			return
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// indexed reports whether the balances at a block are already recorded.
+func (h *balanceHistory) indexed(block *types.Block) bool {
+	record := core.GetBalanceRecord(h.db, h.accounts[0], block.NumberU64())
+	return record != nil && record.Hash == block.Hash()
+}
+
+// update records the balances at a new head block along with its ancestors not
+// indexed yet, either missed or replacing the blocks of a reorganised chain.
+func (h *balanceHistory) update(head *types.Block) {
+	if head == nil {
+		return
+	}
+	var blocks []*types.Block
+	for block, depth := head, 0; block != nil && depth < balanceHistoryWalkback; depth++ {
+		if block.NumberU64()%h.step == 0 {
+			if h.indexed(block) {
+				break
+			}
+			blocks = append(blocks, block)
+		}
+		if block.NumberU64() == 0 {
+			break
+		}
+		block = h.chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	}
+	batch := h.db.NewBatch()
+	for i := len(blocks) - 1; i >= 0; i-- {
+		statedb, err := h.chain.StateAt(blocks[i].Root())
+		if err != nil {
+			log.Debug("Balance history state unavailable", "number", blocks[i].Number(), "hash", blocks[i].Hash(), "err", err)
+			continue
+		}
+		for _, addr := range h.accounts {
+			core.WriteBalanceRecord(batch, addr, blocks[i].NumberU64(), &core.BalanceRecord{
+				Hash:    blocks[i].Hash(),
+				Balance: statedb.GetBalance(addr),
+			})
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to write balance history", "err", err)
+	}
+}
+
+// RPCBalancePoint is the balance of an account at a block.
+type RPCBalancePoint struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	Balance     *hexutil.Big   `json:"balance"`
+}
+
+// history returns the indexed balances of an account at every step-th block of
+// the given range, oldest first. The step is rounded up to a multiple of the
+// indexing step, and blocks without a canonical record are left out.
+func (h *balanceHistory) history(addr common.Address, from, to, step uint64) ([]*RPCBalancePoint, error) {
+	if !h.watched[addr] {
+		return nil, fmt.Errorf("balance history of %x not indexed", addr)
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid range [%d, %d]", from, to)
+	}
+	if step < h.step {
+		step = h.step
+	}
+	step = (step + h.step - 1) / h.step * h.step
+	from = (from + h.step - 1) / h.step * h.step
+
+	if from <= to && (to-from)/step >= maxBalanceHistoryPoints {
+		return nil, fmt.Errorf("too many balance points: range [%d, %d] with step %d exceeds %d", from, to, step, maxBalanceHistoryPoints)
+	}
+	var result []*RPCBalancePoint
+	for number := from; number <= to; number += step {
+		record := core.GetBalanceRecord(h.db, addr, number)
+		if record == nil || record.Hash != core.GetCanonicalHash(h.db, number) {
+			continue
+		}
+		result = append(result, &RPCBalancePoint{
+			BlockNumber: hexutil.Uint64(number),
+			BlockHash:   record.Hash,
+			Balance:     (*hexutil.Big)(record.Balance),
+		})
+	}
+	return result, nil
+}
+
+// BalanceHistory returns the balances of a watched account at every step-th
+// block between the given ones, as indexed at import. Only the accounts
+// configured for indexing are available.
+func (api *PublicGoolaAPI) BalanceHistory(addr common.Address, fromBlock, toBlock rpc.BlockNumber, step hexutil.Uint64) ([]*RPCBalancePoint, error) {
+	if api.e.balanceHistory == nil {
+		return nil, errBalanceHistoryDisabled
+	}
+	head := api.e.BlockChain().CurrentBlock().NumberU64()
+	resolve := func(number rpc.BlockNumber) uint64 {
+		if number < 0 || uint64(number) > head {
+			return head
+		}
+		return uint64(number)
+	}
+	return api.e.balanceHistory.history(addr, resolve(fromBlock), resolve(toBlock), uint64(step))
+}
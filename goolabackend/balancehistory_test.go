@@ -0,0 +1,118 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/event"
+	"github.com/goola-team/goola/gooladb"
+)
+
+// testBalanceChain is a block and state store feeding the balance history index.
+type testBalanceChain struct {
+	db     gooladb.Database
+	states state.Database
+	blocks map[common.Hash]*types.Block
+	head   *types.Block
+	feed   event.Feed
+}
+
+func (c *testBalanceChain) CurrentBlock() *types.Block { return c.head }
+func (c *testBalanceChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return c.blocks[hash]
+}
+func (c *testBalanceChain) StateAt(root common.Hash) (*state.StateDB, error) {
+	return state.New(root, c.states)
+}
+func (c *testBalanceChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+// extend appends a canonical block to the chain crediting the account with the
+// given balance, and returns the new head.
+func (c *testBalanceChain) extend(parent *types.Block, addr common.Address, balance int64) *types.Block {
+	header := &types.Header{Number: new(big.Int)}
+	statedb, _ := state.New(common.Hash{}, c.states)
+	if parent != nil {
+		header.ParentHash = parent.Hash()
+		header.Number.Add(parent.Number(), common.Big1)
+		statedb, _ = state.New(parent.Root(), c.states)
+	}
+	statedb.SetBalance(addr, big.NewInt(balance))
+	header.Root, _ = statedb.Commit(false)
+
+	block := types.NewBlockWithHeader(header)
+	c.blocks[block.Hash()] = block
+	core.WriteCanonicalHash(c.db, block.Hash(), block.NumberU64())
+	return block
+}
+
+// Tests that the balances of the watched accounts are recorded at every step-th
+// block, and rewritten on reorganisations.
+func TestBalanceHistory(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	chain := &testBalanceChain{db: db, states: state.NewDatabase(db), blocks: make(map[common.Hash]*types.Block)}
+
+	addr := common.Address{1}
+	for i := int64(0); i <= 5; i++ {
+		chain.head = chain.extend(chain.head, addr, 10*i)
+	}
+	history := newBalanceHistory(chain, db, []common.Address{addr}, 2)
+	history.update(chain.head)
+
+	points, err := history.history(addr, 0, 5, 1)
+	if err != nil {
+		t.Fatalf("failed to query history: %v", err)
+	}
+	want := []int64{0, 20, 40}
+	if len(points) != len(want) {
+		t.Fatalf("point count mismatch: have %d, want %d", len(points), len(want))
+	}
+	for i, point := range points {
+		if uint64(point.BlockNumber) != uint64(2*i) || point.Balance.ToInt().Int64() != want[i] {
+			t.Errorf("point %d: have #%d balance %v, want #%d balance %d", i, point.BlockNumber, point.Balance, 2*i, want[i])
+		}
+	}
+	// Steps are rounded up to the indexing step
+	if points, _ = history.history(addr, 1, 5, 3); len(points) != 1 || points[0].BlockNumber != 2 {
+		t.Errorf("rounded step mismatch: have %v, want block #2 only", points)
+	}
+	// Reorganise the last two blocks away, the stale record being skipped until
+	// the replacement is indexed
+	block3 := chain.blocks[chain.blocks[chain.head.ParentHash()].ParentHash()]
+	chain.head = chain.extend(block3, addr, 99)
+
+	if points, _ = history.history(addr, 4, 4, 1); len(points) != 0 {
+		t.Errorf("stale record returned: %v", points)
+	}
+	history.update(chain.head)
+	if points, _ = history.history(addr, 4, 4, 1); len(points) != 1 || points[0].BlockHash != chain.head.Hash() || points[0].Balance.ToInt().Int64() != 99 {
+		t.Errorf("reorged record mismatch: have %v", points)
+	}
+	if _, err := history.history(common.Address{2}, 0, 5, 1); err == nil {
+		t.Errorf("unwatched account accepted")
+	}
+	if _, err := history.history(addr, 0, 2*maxBalanceHistoryPoints, 1); err == nil {
+		t.Errorf("oversized range accepted")
+	}
+}
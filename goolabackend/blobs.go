@@ -0,0 +1,100 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/p2p"
+	"github.com/goola-team/goola/params"
+)
+
+// maxBlobsFetch is the maximum number of data blobs requested or served in a
+// single message.
+const maxBlobsFetch = 16
+
+// RequestBlobs fetches a batch of data blobs by their hashes from the peer.
+func (p *peer) RequestBlobs(hashes []common.Hash) error {
+	for _, hash := range hashes {
+		p.blobs.Add(hash)
+	}
+	p.Log().Debug("Fetching batch of blobs", "count", len(hashes))
+	return p2p.Send(p.rw, GetBlobsMsg, hashes)
+}
+
+// SendBlobs sends a batch of data blobs to the peer.
+func (p *peer) SendBlobs(blobs [][]byte) error {
+	return p2p.Send(p.rw, BlobsMsg, blobs)
+}
+
+// requestMissingBlobs asks the peer that sent some blob transactions for their
+// blobs not yet stored locally.
+func (pm *ProtocolManager) requestMissingBlobs(p *peer, txs []*types.Transaction) {
+	if !p.Supports(CapBlobs) {
+		return
+	}
+	var missing []common.Hash
+	for _, tx := range txs {
+		blob, err := tx.BlobTxData()
+		if err != nil {
+			continue
+		}
+		for _, hash := range blob.BlobHashes {
+			if !p.blobs.Has(hash) && !pm.blockchain.HasBlob(hash) {
+				missing = append(missing, hash)
+			}
+		}
+	}
+	for len(missing) > 0 {
+		batch := missing
+		if len(batch) > maxBlobsFetch {
+			batch = batch[:maxBlobsFetch]
+		}
+		if err := p.RequestBlobs(batch); err != nil {
+			return
+		}
+		missing = missing[len(batch):]
+	}
+}
+
+// serveBlobs gathers the requested data blobs available locally.
+func (pm *ProtocolManager) serveBlobs(hashes []common.Hash) [][]byte {
+	var blobs [][]byte
+	for _, hash := range hashes {
+		if len(blobs) >= maxBlobsFetch {
+			break
+		}
+		if blob := pm.blockchain.GetBlob(hash); blob != nil {
+			blobs = append(blobs, blob)
+		}
+	}
+	return blobs
+}
+
+// deliverBlobs stores the data blobs delivered by a peer, dropping any that
+// weren't requested from it.
+func (pm *ProtocolManager) deliverBlobs(p *peer, blobs [][]byte) {
+	for _, blob := range blobs {
+		hash := types.BlobHash(blob)
+		if !p.blobs.Has(hash) || len(blob) > params.MaxBlobSize {
+			p.Log().Debug("Discarding unrequested blob", "hash", hash)
+			continue
+		}
+		p.blobs.Remove(hash)
+		pm.blockchain.WriteBlob(blob)
+	}
+}
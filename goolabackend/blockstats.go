@@ -0,0 +1,232 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/event"
+)
+
+const (
+	// blockStatsLimit is the number of recent canonical blocks whose statistics
+	// are retained for aggregation.
+	blockStatsLimit = 8192
+
+	// blockStatsChanSize is the size of channel listening to ChainHeadEvent.
+	blockStatsChanSize = 10
+)
+
+// blockStatsChain is the chain access needed to aggregate block statistics.
+type blockStatsChain interface {
+	CurrentBlock() *types.Block
+	GetBlock(hash common.Hash, number uint64) *types.Block
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// blockStat is the gas usage of a single block.
+type blockStat struct {
+	number   uint64
+	hash     common.Hash
+	gasUsed  uint64
+	gasLimit uint64
+	txs      uint64
+	prices   *big.Int // Sum of the gas prices of the transactions
+}
+
+func newBlockStat(block *types.Block) *blockStat {
+	stat := &blockStat{
+		number:   block.NumberU64(),
+		hash:     block.Hash(),
+		gasUsed:  block.GasUsed(),
+		gasLimit: block.GasLimit(),
+		txs:      uint64(len(block.Transactions())),
+		prices:   new(big.Int),
+	}
+	for _, tx := range block.Transactions() {
+		stat.prices.Add(stat.prices, tx.GasPrice())
+	}
+	return stat
+}
+
+// blockStats aggregates the gas usage of the recent canonical blocks as they are
+// imported, so charting it doesn't require scanning the chain.
+type blockStats struct {
+	chain blockStatsChain
+
+	stats []*blockStat // Statistics of the recent blocks, indexed by number modulo the limit
+	head  uint64       // Number of the current head block
+	lock  sync.RWMutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newBlockStats(chain blockStatsChain) *blockStats {
+	return &blockStats{
+		chain: chain,
+		stats: make([]*blockStat, blockStatsLimit),
+		quit:  make(chan struct{}),
+	}
+}
+
+// Start backfills the statistics of the recent blocks and starts following the
+// chain head.
+func (s *blockStats) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop terminates following the chain head.
+func (s *blockStats) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+func (s *blockStats) loop() {
+	defer s.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, blockStatsChanSize)
+	sub := s.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	s.update(s.chain.CurrentBlock())
+	for {
+		select {
+		case ev := <-heads:
+			s.update(ev.Block)
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// known reports whether the statistics of a block are already aggregated.
+func (s *blockStats) known(number uint64, hash common.Hash) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	stat := s.stats[number%blockStatsLimit]
+	return stat != nil && stat.number == number && stat.hash == hash
+}
+
+// update aggregates a new head block along with its ancestors not aggregated
+// yet, either missed or replacing the blocks of a reorganised chain.
+func (s *blockStats) update(head *types.Block) {
+	if head == nil {
+		return
+	}
+	var stats []*blockStat
+	for block := head; block != nil && len(stats) < blockStatsLimit; {
+		if s.known(block.NumberU64(), block.Hash()) {
+			break
+		}
+		stats = append(stats, newBlockStat(block))
+		if block.NumberU64() == 0 {
+			break
+		}
+		block = s.chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, stat := range stats {
+		s.stats[stat.number%blockStatsLimit] = stat
+	}
+	s.head = head.NumberU64()
+}
+
+// RPCBlockStats is the aggregated gas usage of an interval of blocks.
+type RPCBlockStats struct {
+	From        hexutil.Uint64 `json:"from"`
+	To          hexutil.Uint64 `json:"to"`
+	Blocks      hexutil.Uint64 `json:"blocks"` // Blocks aggregated, the ones unavailable being left out
+	Txs         hexutil.Uint64 `json:"txs"`
+	GasUsed     hexutil.Uint64 `json:"gasUsed"`
+	GasLimit    hexutil.Uint64 `json:"gasLimit"`
+	Fullness    float64        `json:"fullness"`    // Gas used in percent of the gas limit
+	AvgGasPrice *hexutil.Big   `json:"avgGasPrice"` // Mean gas price of the transactions, nil if none
+}
+
+// aggregate returns the statistics of the given number of most recent blocks,
+// aggregated over intervals of blocks, oldest first. The newest interval is cut
+// short at the head if the range isn't a multiple of the interval.
+func (s *blockStats) aggregate(blocks, interval uint64) ([]*RPCBlockStats, error) {
+	if blocks == 0 || blocks > blockStatsLimit {
+		return nil, fmt.Errorf("invalid range %d: must be within [1, %d]", blocks, blockStatsLimit)
+	}
+	if interval == 0 || interval > blocks {
+		return nil, fmt.Errorf("invalid interval %d: must be within [1, %d]", interval, blocks)
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if blocks > s.head+1 {
+		blocks = s.head + 1
+	}
+	var result []*RPCBlockStats
+	for from := s.head + 1 - blocks; from <= s.head; from += interval {
+		to := from + interval - 1
+		if to > s.head {
+			to = s.head
+		}
+		result = append(result, s.interval(from, to))
+	}
+	return result, nil
+}
+
+// interval aggregates the statistics of an interval of blocks. The lock must be
+// held.
+func (s *blockStats) interval(from, to uint64) *RPCBlockStats {
+	var (
+		result = &RPCBlockStats{From: hexutil.Uint64(from), To: hexutil.Uint64(to)}
+		prices = new(big.Int)
+	)
+	for number := from; number <= to; number++ {
+		stat := s.stats[number%blockStatsLimit]
+		if stat == nil || stat.number != number {
+			continue
+		}
+		result.Blocks++
+		result.Txs += hexutil.Uint64(stat.txs)
+		result.GasUsed += hexutil.Uint64(stat.gasUsed)
+		result.GasLimit += hexutil.Uint64(stat.gasLimit)
+		prices.Add(prices, stat.prices)
+	}
+	if result.GasLimit > 0 {
+		result.Fullness = 100 * float64(result.GasUsed) / float64(result.GasLimit)
+	}
+	if result.Txs > 0 {
+		result.AvgGasPrice = (*hexutil.Big)(prices.Div(prices, new(big.Int).SetUint64(uint64(result.Txs))))
+	}
+	return result
+}
+
+// BlockStats returns the gas usage, fullness, transaction count and average gas
+// price of the given number of most recent blocks, aggregated over intervals of
+// blocks, oldest first.
+func (api *PublicGoolaAPI) BlockStats(blocks hexutil.Uint64, interval hexutil.Uint64) ([]*RPCBlockStats, error) {
+	return api.e.blockStats.aggregate(uint64(blocks), uint64(interval))
+}
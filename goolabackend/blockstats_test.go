@@ -0,0 +1,111 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/event"
+)
+
+// testStatsChain is a block store feeding the block statistics aggregator.
+type testStatsChain struct {
+	blocks map[common.Hash]*types.Block
+	head   *types.Block
+	feed   event.Feed
+}
+
+func (c *testStatsChain) CurrentBlock() *types.Block { return c.head }
+func (c *testStatsChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return c.blocks[hash]
+}
+func (c *testStatsChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+// extend appends blocks using the given gas to the chain, each with a single
+// transaction paying the given price, and returns the new head.
+func (c *testStatsChain) extend(parent *types.Block, gas []uint64, price int64) *types.Block {
+	for _, used := range gas {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number(), common.Big1),
+			GasLimit:   100,
+			GasUsed:    used,
+		}
+		tx := types.NewTransaction(0, common.Address{}, new(big.Int), used, big.NewInt(price), types.TxTypeTransfer, nil)
+		parent = types.NewBlock(header, []*types.Transaction{tx}, nil)
+		c.blocks[parent.Hash()] = parent
+	}
+	return parent
+}
+
+// Tests that block statistics are aggregated over intervals, and rewritten on
+// reorganisations.
+func TestBlockStats(t *testing.T) {
+	genesis := types.NewBlockWithHeader(&types.Header{Number: new(big.Int), GasLimit: 100})
+	chain := &testStatsChain{blocks: map[common.Hash]*types.Block{genesis.Hash(): genesis}}
+	chain.head = chain.extend(genesis, []uint64{10, 20, 30, 40, 50}, 1)
+
+	stats := newBlockStats(chain)
+	stats.update(chain.head)
+
+	result, err := stats.aggregate(5, 2)
+	if err != nil {
+		t.Fatalf("failed to aggregate: %v", err)
+	}
+	want := []struct {
+		from, to, gas uint64
+		fullness      float64
+	}{{1, 2, 30, 15}, {3, 4, 70, 35}, {5, 5, 50, 50}}
+	if len(result) != len(want) {
+		t.Fatalf("interval count mismatch: have %d, want %d", len(result), len(want))
+	}
+	for i, w := range want {
+		r := result[i]
+		if uint64(r.From) != w.from || uint64(r.To) != w.to || uint64(r.GasUsed) != w.gas || r.Fullness != w.fullness {
+			t.Errorf("interval %d: have [%d, %d] gas %d fullness %v, want [%d, %d] gas %d fullness %v", i, r.From, r.To, r.GasUsed, r.Fullness, w.from, w.to, w.gas, w.fullness)
+		}
+		if r.AvgGasPrice.ToInt().Cmp(common.Big1) != 0 {
+			t.Errorf("interval %d: average gas price mismatch: have %v, want 1", i, r.AvgGasPrice)
+		}
+	}
+	// Reorganise the last three blocks away, the replacements being aggregated
+	fork := chain.head
+	for i := 0; i < 3; i++ {
+		fork = chain.blocks[fork.ParentHash()]
+	}
+	stats.update(chain.extend(fork, []uint64{100, 100}, 3))
+
+	if result, _ = stats.aggregate(4, 4); len(result) != 1 {
+		t.Fatalf("interval count mismatch: have %d, want 1", len(result))
+	}
+	r := result[0]
+	if r.From != 1 || r.To != 4 || r.Blocks != 4 || r.GasUsed != 230 || r.Txs != 4 || r.AvgGasPrice.ToInt().Int64() != 2 {
+		t.Errorf("reorged interval mismatch: have %+v", r)
+	}
+	if _, err := stats.aggregate(blockStatsLimit+1, 1); err == nil {
+		t.Errorf("oversized range accepted")
+	}
+	if _, err := stats.aggregate(4, 5); err == nil {
+		t.Errorf("interval over the range accepted")
+	}
+}
@@ -17,10 +17,13 @@
 package goolabackend
 
 import (
+	"bytes"
+	"fmt"
 	"time"
 
 	"github.com/goola-team/goola/common"
 	"github.com/goola-team/goola/common/bitutil"
+	"github.com/goola-team/goola/common/hexutil"
 	"github.com/goola-team/goola/core"
 	"github.com/goola-team/goola/core/bloombits"
 	"github.com/goola-team/goola/core/types"
@@ -141,3 +144,82 @@ func (b *BloomIndexer) Commit() error {
 	}
 	return batch.Write()
 }
+
+// BloomSectionStatus is the outcome of verifying a section of the bloombits
+// index against the canonical chain.
+type BloomSectionStatus struct {
+	Section hexutil.Uint64 `json:"section"`
+	Head    common.Hash    `json:"head"`
+	Valid   bool           `json:"valid"`
+
+	MissingBits []uint           `json:"missingBits,omitempty"` // Bit vectors absent from the database
+	CorruptBits []uint           `json:"corruptBits,omitempty"` // Bit vectors not matching the header blooms
+	BadBlooms   []hexutil.Uint64 `json:"badBlooms,omitempty"`   // Blocks whose header bloom doesn't match their receipts
+}
+
+// canonicalHeader retrieves the header of the canonical block with the given number.
+func canonicalHeader(db gooladb.Database, number uint64) (*types.Header, error) {
+	header := core.GetHeader(db, core.GetCanonicalHash(db, number), number)
+	if header == nil {
+		return nil, fmt.Errorf("missing canonical header #%d", number)
+	}
+	return header, nil
+}
+
+// verifyBloomSection checks the stored bloombits of a section against the blooms
+// of its canonical headers, and the header blooms against the block receipts
+// where those are available.
+func verifyBloomSection(db gooladb.Database, size, section uint64) (*BloomSectionStatus, error) {
+	gen, err := bloombits.NewGenerator(uint(size))
+	if err != nil {
+		return nil, err
+	}
+	status := &BloomSectionStatus{Section: hexutil.Uint64(section)}
+	for number := section * size; number < (section+1)*size; number++ {
+		header, err := canonicalHeader(db, number)
+		if err != nil {
+			return nil, err
+		}
+		gen.AddBloom(uint(number-section*size), header.Bloom)
+		status.Head = header.Hash()
+
+		if receipts := core.GetBlockReceipts(db, status.Head, number); receipts != nil || header.TxHash == types.EmptyRootHash {
+			if types.CreateBloom(receipts) != header.Bloom {
+				status.BadBlooms = append(status.BadBlooms, hexutil.Uint64(number))
+			}
+		}
+	}
+	for i := 0; i < types.BloomBitLength; i++ {
+		want, err := gen.Bitset(uint(i))
+		if err != nil {
+			return nil, err
+		}
+		comp, err := core.GetBloomBits(db, uint(i), section, status.Head)
+		if err != nil {
+			status.MissingBits = append(status.MissingBits, uint(i))
+			continue
+		}
+		if have, err := bitutil.DecompressBytes(comp, int(size)/8); err != nil || !bytes.Equal(have, want) {
+			status.CorruptBits = append(status.CorruptBits, uint(i))
+		}
+	}
+	status.Valid = len(status.MissingBits) == 0 && len(status.CorruptBits) == 0 && len(status.BadBlooms) == 0
+	return status, nil
+}
+
+// rebuildBloomSection regenerates the bloombits of a section from the blooms of
+// its canonical headers, overwriting any data stored previously.
+func rebuildBloomSection(db gooladb.Database, size, section uint64) error {
+	indexer := &BloomIndexer{db: db, size: size}
+	if err := indexer.Reset(section, common.Hash{}); err != nil {
+		return err
+	}
+	for number := section * size; number < (section+1)*size; number++ {
+		header, err := canonicalHeader(db, number)
+		if err != nil {
+			return err
+		}
+		indexer.Process(header)
+	}
+	return indexer.Commit()
+}
@@ -0,0 +1,72 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/bitutil"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/gooladb"
+)
+
+// Tests that damaged bloombits sections are detected by the verifier and are
+// repaired by rebuilding them from the canonical headers.
+func TestBloomIndexVerifyRebuild(t *testing.T) {
+	const size = 2048
+
+	db, _ := gooladb.NewMemDatabase()
+	for i := uint64(0); i < size; i++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(i), TxHash: types.EmptyRootHash, Extra: []byte("bloom")}
+		if i%7 == 0 {
+			header.Bloom = types.BytesToBloom(common.BigToHash(new(big.Int).SetUint64(i)).Bytes())
+			header.TxHash = common.Hash{0x01}
+		}
+		core.WriteHeader(db, header)
+		core.WriteCanonicalHash(db, header.Hash(), i)
+	}
+	// A missing index must be detected and rebuilt
+	if status, err := verifyBloomSection(db, size, 0); err != nil || status.Valid || len(status.MissingBits) != types.BloomBitLength {
+		t.Fatalf("unindexed section: have %+v (%v), want all bits missing", status, err)
+	}
+	if err := rebuildBloomSection(db, size, 0); err != nil {
+		t.Fatalf("failed to rebuild section: %v", err)
+	}
+	status, err := verifyBloomSection(db, size, 0)
+	if err != nil || !status.Valid {
+		t.Fatalf("rebuilt section invalid: %+v (%v)", status, err)
+	}
+	// Corrupted bit vectors must be detected and repaired
+	core.WriteBloomBits(db, 3, 0, status.Head, bitutil.CompressBytes(make([]byte, size/8)))
+	core.WriteBloomBits(db, 5, 0, status.Head, []byte{0xff})
+	if status, _ = verifyBloomSection(db, size, 0); status.Valid || len(status.CorruptBits) != 2 || status.CorruptBits[0] != 3 || status.CorruptBits[1] != 5 {
+		t.Fatalf("corrupt section: have %+v, want bits 3 and 5 corrupt", status)
+	}
+	rebuildBloomSection(db, size, 0)
+	if status, _ = verifyBloomSection(db, size, 0); !status.Valid {
+		t.Fatalf("repaired section invalid: %+v", status)
+	}
+	// Header blooms not matching the receipts must be reported
+	hash := core.GetCanonicalHash(db, 14)
+	core.WriteBlockReceipts(db, hash, 14, types.Receipts{{Logs: []*types.Log{}}})
+	if status, _ = verifyBloomSection(db, size, 0); status.Valid || len(status.BadBlooms) != 1 || status.BadBlooms[0] != 14 {
+		t.Fatalf("bad header bloom: have %+v, want block 14 reported", status)
+	}
+}
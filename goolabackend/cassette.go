@@ -0,0 +1,274 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/p2p"
+	"github.com/goola-team/goola/p2p/discover"
+	"github.com/goola-team/goola/rlp"
+)
+
+// cassetteVersion is the version of the recorded message stream format.
+const cassetteVersion = 1
+
+// errUnknownRecording is returned if a recording to stop was never requested.
+var errUnknownRecording = errors.New("no recording requested for peer")
+
+// cassetteHeader is the first record of a cassette, identifying the recorded
+// protocol session.
+type cassetteHeader struct {
+	Format  uint
+	Peer    discover.NodeID
+	Version uint
+	Time    uint64
+}
+
+// cassetteEntry is an inbound message of a recorded session.
+type cassetteEntry struct {
+	Offset  uint64 // Nanoseconds elapsed since the session start
+	Code    uint64
+	Payload []byte
+}
+
+// cassetteRecorder is a message stream that writes every message read from the
+// wrapped stream to a cassette file.
+type cassetteRecorder struct {
+	p2p.MsgReadWriter
+
+	file  *os.File
+	out   *bufio.Writer
+	start time.Time
+	lock  sync.Mutex
+}
+
+// newCassetteRecorder creates a cassette at the given path and starts recording
+// the inbound messages of a protocol session into it.
+func newCassetteRecorder(path string, id discover.NodeID, version int, rw p2p.MsgReadWriter) (*cassetteRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	rec := &cassetteRecorder{
+		MsgReadWriter: rw,
+		file:          file,
+		out:           bufio.NewWriter(file),
+		start:         time.Now(),
+	}
+	header := &cassetteHeader{Format: cassetteVersion, Peer: id, Version: uint(version), Time: uint64(rec.start.Unix())}
+	if err := rlp.Encode(rec.out, header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return rec, nil
+}
+
+// ReadMsg implements p2p.MsgReader, recording the message before returning it.
+func (rec *cassetteRecorder) ReadMsg() (p2p.Msg, error) {
+	msg, err := rec.MsgReadWriter.ReadMsg()
+	if err != nil {
+		return msg, err
+	}
+	payload, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return msg, err
+	}
+	msg.Payload = bytes.NewReader(payload)
+
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+
+	if rec.out != nil {
+		entry := &cassetteEntry{Offset: uint64(time.Since(rec.start)), Code: msg.Code, Payload: payload}
+		if err := rlp.Encode(rec.out, entry); err != nil {
+			log.Warn("Failed to record message", "file", rec.file.Name(), "err", err)
+		}
+	}
+	return msg, nil
+}
+
+// Close flushes the recorded messages to disk and closes the cassette.
+func (rec *cassetteRecorder) Close() error {
+	rec.lock.Lock()
+	defer rec.lock.Unlock()
+
+	if rec.out == nil {
+		return nil
+	}
+	err := rec.out.Flush()
+	if cerr := rec.file.Close(); err == nil {
+		err = cerr
+	}
+	rec.out = nil
+	return err
+}
+
+// cassettePlayer is a message stream returning the messages of a cassette,
+// paced as they were recorded, and discarding everything written into it.
+type cassettePlayer struct {
+	stream *rlp.Stream
+	file   *os.File
+	start  time.Time
+	quit   chan struct{}
+}
+
+// openCassette opens a recorded session for replaying.
+func openCassette(path string) (*cassettePlayer, *cassetteHeader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	stream := rlp.NewStream(bufio.NewReader(file), 0)
+
+	header := new(cassetteHeader)
+	if err := stream.Decode(header); err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("invalid cassette header: %v", err)
+	}
+	if header.Format != cassetteVersion {
+		file.Close()
+		return nil, nil, fmt.Errorf("unsupported cassette format %d", header.Format)
+	}
+	return &cassettePlayer{stream: stream, file: file, start: time.Now(), quit: make(chan struct{})}, header, nil
+}
+
+// ReadMsg implements p2p.MsgReader, returning the next recorded message once
+// its recorded time is reached.
+func (p *cassettePlayer) ReadMsg() (p2p.Msg, error) {
+	entry := new(cassetteEntry)
+	if err := p.stream.Decode(entry); err != nil {
+		return p2p.Msg{}, err
+	}
+	if wait := time.Duration(entry.Offset) - time.Since(p.start); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-p.quit:
+			return p2p.Msg{}, io.EOF
+		}
+	}
+	return p2p.Msg{
+		Code:       entry.Code,
+		Size:       uint32(len(entry.Payload)),
+		Payload:    bytes.NewReader(entry.Payload),
+		ReceivedAt: time.Now(),
+	}, nil
+}
+
+// WriteMsg implements p2p.MsgWriter, discarding the message.
+func (p *cassettePlayer) WriteMsg(msg p2p.Msg) error {
+	_, err := io.Copy(ioutil.Discard, msg.Payload)
+	return err
+}
+
+// Close aborts the replay and closes the cassette.
+func (p *cassettePlayer) Close() error {
+	close(p.quit)
+	return p.file.Close()
+}
+
+// startRecording wraps the message stream of a connecting peer into a cassette
+// recorder if a recording of it was requested, returning nil otherwise.
+func (pm *ProtocolManager) startRecording(p *p2p.Peer, version int, rw p2p.MsgReadWriter) *cassetteRecorder {
+	pm.cassetteLock.Lock()
+	defer pm.cassetteLock.Unlock()
+
+	id := p.ID().String()
+	for prefix, path := range pm.cassettes {
+		if !strings.HasPrefix(id, prefix) {
+			continue
+		}
+		delete(pm.cassettes, prefix)
+
+		rec, err := newCassetteRecorder(path, p.ID(), version, rw)
+		if err != nil {
+			log.Error("Failed to create cassette", "peer", id, "file", path, "err", err)
+			return nil
+		}
+		log.Info("Recording peer messages", "peer", id, "file", path)
+		return rec
+	}
+	return nil
+}
+
+// ReplayCassette feeds a recorded session into the protocol manager as if it
+// arrived from the recorded peer, blocking until all messages are consumed.
+func (pm *ProtocolManager) ReplayCassette(path string) error {
+	player, header, err := openCassette(path)
+	if err != nil {
+		return err
+	}
+	defer player.Close()
+
+	log.Info("Replaying recorded peer session", "peer", header.Peer, "file", path)
+	peer := pm.newPeer(int(header.Version), p2p.NewPeer(header.Peer, "cassette", nil), player)
+	select {
+	case pm.newPeerCh <- peer:
+		pm.wg.Add(1)
+		defer pm.wg.Done()
+
+		if err := pm.handle(peer); err != io.EOF {
+			return err
+		}
+		log.Info("Replayed recorded peer session", "peer", header.Peer, "file", path)
+		return nil
+	case <-pm.quitSync:
+		return p2p.DiscQuitting
+	}
+}
+
+// RecordPeer requests recording the inbound messages of the next connection of
+// the peer whose node ID starts with the given prefix into a file.
+func (api *PrivateDebugAPI) RecordPeer(id string, file string) {
+	pm := api.fullGoola.protocolManager
+
+	pm.cassetteLock.Lock()
+	defer pm.cassetteLock.Unlock()
+
+	pm.cassettes[strings.TrimPrefix(id, "0x")] = file
+}
+
+// CancelRecording withdraws a recording request of a peer not yet connected.
+func (api *PrivateDebugAPI) CancelRecording(id string) error {
+	pm := api.fullGoola.protocolManager
+
+	pm.cassetteLock.Lock()
+	defer pm.cassetteLock.Unlock()
+
+	id = strings.TrimPrefix(id, "0x")
+	if _, ok := pm.cassettes[id]; !ok {
+		return errUnknownRecording
+	}
+	delete(pm.cassettes, id)
+	return nil
+}
+
+// ReplayCassette feeds a recorded peer session into the node, blocking until it
+// is fully replayed. The node should be on the chain it was recorded on.
+func (api *PrivateDebugAPI) ReplayCassette(file string) error {
+	return api.fullGoola.protocolManager.ReplayCassette(file)
+}
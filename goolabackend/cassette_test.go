@@ -0,0 +1,104 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goola-team/goola/p2p"
+	"github.com/goola-team/goola/p2p/discover"
+)
+
+// Tests that the messages read through a cassette recorder are played back in
+// the same order with the same contents.
+func TestCassetteRoundtrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "session.rlp")
+
+	// Record a few messages sent over a pipe
+	local, remote := p2p.MsgPipe()
+	defer remote.Close()
+
+	id := discover.NodeID{0x01, 0x02}
+	rec, err := newCassetteRecorder(path, id, eth63, local)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	sent := []struct {
+		code uint64
+		data interface{}
+	}{
+		{StatusMsg, []uint{1, 2, 3}},
+		{NewBlockHashesMsg, "hello"},
+		{TxMsg, []uint{}},
+	}
+	go func() {
+		for _, msg := range sent {
+			p2p.Send(remote, msg.code, msg.data)
+		}
+	}()
+	for i, want := range sent {
+		msg, err := rec.ReadMsg()
+		if err != nil {
+			t.Fatalf("message %d: failed to read: %v", i, err)
+		}
+		if msg.Code != want.code {
+			t.Fatalf("message %d: code mismatch: have %d, want %d", i, msg.Code, want.code)
+		}
+		msg.Discard()
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("failed to close recorder: %v", err)
+	}
+	// Play the recording back and ensure it's identical
+	player, header, err := openCassette(path)
+	if err != nil {
+		t.Fatalf("failed to open cassette: %v", err)
+	}
+	defer player.Close()
+
+	if header.Peer != id || header.Version != eth63 {
+		t.Errorf("header mismatch: have %x/%d, want %x/%d", header.Peer[:2], header.Version, id[:2], eth63)
+	}
+	for i, want := range sent {
+		msg, err := player.ReadMsg()
+		if err != nil {
+			t.Fatalf("message %d: failed to replay: %v", i, err)
+		}
+		if err := p2p.ExpectMsg(&replayReader{msg}, want.code, want.data); err != nil {
+			t.Errorf("message %d: %v", i, err)
+		}
+	}
+	if _, err := player.ReadMsg(); err != io.EOF {
+		t.Errorf("replay end mismatch: have %v, want %v", err, io.EOF)
+	}
+}
+
+// replayReader returns a single pre-read message.
+type replayReader struct {
+	msg p2p.Msg
+}
+
+func (r *replayReader) ReadMsg() (p2p.Msg, error) { return r.msg, nil }
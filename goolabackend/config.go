@@ -26,7 +26,12 @@ import (
 	"github.com/goola-team/goola/common/hexutil"
 	"github.com/goola-team/goola/core"
 	"github.com/goola-team/goola/goolabackend/downloader"
+	"github.com/goola-team/goola/goolabackend/alerts"
 	"github.com/goola-team/goola/goolabackend/gasprice"
+	"github.com/goola-team/goola/goolabackend/maintenance"
+	"github.com/goola-team/goola/goolabackend/snapshot"
+	"github.com/goola-team/goola/goolabackend/tracers"
+	"github.com/goola-team/goola/internal/ethapi"
 	"github.com/goola-team/goola/params"
 )
 
@@ -39,12 +44,18 @@ var DefaultConfig = Config{
 	TrieCache:     256,
 	TrieTimeout:   5 * time.Minute,
 	GasPrice:      big.NewInt(18 * params.Shannon),
+	BlobRetention: 28800,
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
 		Blocks:     20,
 		Percentile: 60,
 	},
+	Alerts:      alerts.DefaultConfig,
+	Maintenance: maintenance.DefaultConfig,
+	QueryLimits: DefaultQueryLimits,
+	TxPrivacy:   DefaultTxPrivacy,
+	CallCache:   ethapi.DefaultCallCacheConfig,
 }
 
 func init() {
@@ -63,15 +74,69 @@ type Config struct {
 	// If nil, the Goola main net block is used.
 	Genesis *core.Genesis `toml:",omitempty"`
 
+	// Profile is the node mode preset expanding into consistent settings,
+	// overriding the defaults of the settings it covers.
+	Profile Profile `toml:",omitempty"`
+
 	// Protocol options
 	NetworkId uint64 // Network ID to use for selecting peers to connect to
 	SyncMode  downloader.SyncMode
 	NoPruning bool
 
+	// StateDiffs enables recording the reverse state diff of every block, so that
+	// pruned historical states can be reconstructed on demand.
+	StateDiffs bool `toml:",omitempty"`
+
+	// InternalTxIndex enables recording the internal value transfers of
+	// imported blocks, at the cost of tracing every executed transaction.
+	InternalTxIndex bool `toml:",omitempty"`
+
+	// BalanceHistory is the set of accounts whose balances are recorded at every
+	// BalanceHistoryStep-th imported block (0 = every block).
+	BalanceHistory     []common.Address `toml:",omitempty"`
+	BalanceHistoryStep uint64           `toml:",omitempty"`
+
+	// MaxReorgDepth is the maximum number of canonical blocks a chain reorg may
+	// drop. Deeper reorgs are rejected and reported instead (0 = unlimited).
+	MaxReorgDepth uint64 `toml:",omitempty"`
+
+	// RetainBlocks is the number of recent blocks whose bodies and receipts are
+	// retained, older ones being pruned while their headers are kept. Zero
+	// retains the entire chain history.
+	RetainBlocks uint64 `toml:",omitempty"`
+
+	// BlobRetention is the number of recent blocks whose transaction data blobs
+	// are retained, older ones being pruned. Zero retains all blobs.
+	BlobRetention uint64 `toml:",omitempty"`
+
+	// ParallelExecution enables executing the transactions of imported blocks
+	// optimistically in parallel, re-executing conflicting ones serially.
+	ParallelExecution bool `toml:",omitempty"`
+
+	// ImportBuffer is the memory allowance (MB) to buffer fast sync imports in,
+	// committing them in large batches backed by a write-ahead log (0 = disabled).
+	ImportBuffer int `toml:",omitempty"`
+
 	// Light client options
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 
+	// LightPayments enables serving LES clients with a prepaid balance with
+	// priority, charging their requests against it.
+	LightPayments bool `toml:",omitempty"`
+
+	// Bandwidth budget of light clients on metered connections, in MB (0 = unlimited)
+	LightBudgetHourly int `toml:",omitempty"`
+	LightBudgetDaily  int `toml:",omitempty"`
+
+	// LightVerifyRecent is the number of most recent headers whose seals are all
+	// verified during light sync, older ones being only sampled (0 = verify all).
+	LightVerifyRecent uint64 `toml:",omitempty"`
+
+	// LightRelay is the list of trusted JSON-RPC servers used by light clients to
+	// satisfy on-demand requests when no LES server is able to serve them.
+	LightRelay []string `toml:",omitempty"`
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
@@ -92,9 +157,30 @@ type Config struct {
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
+	// Cache of the call results executed on sealed blocks
+	CallCache ethapi.CallCacheConfig
+
+	// Alert webhook options
+	Alerts alerts.Config
+
+	// Scheduling of the heavy background maintenance tasks
+	Maintenance maintenance.Config
+
+	// Chain snapshot seeding and bootstrapping options
+	Snapshot snapshot.Config
+
+	// Rate limits of the data retrieval queries served to peers
+	QueryLimits QueryLimitConfig
+
+	// Broadcast policy of the transactions sent from local accounts
+	TxPrivacy TxPrivacyConfig
+
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// External VM tracers selectable by the debug_trace* calls
+	ExternalTracers tracers.ExternalConfig
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 }
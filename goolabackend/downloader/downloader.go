@@ -18,6 +18,7 @@
 package downloader
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -31,6 +32,7 @@ import (
 	"github.com/goola-team/goola/event"
 	"github.com/goola-team/goola/log"
 	"github.com/goola-team/goola/params"
+	"github.com/goola-team/goola/tracing"
 	"github.com/rcrowley/go-metrics"
 )
 
@@ -63,6 +65,8 @@ var (
 	fsHeaderForceVerify    = 24              // Number of headers to verify before and after the pivot to accept it
 	fsHeaderContCheck      = 3 * time.Second // Time interval to check for header continuations during state download
 	fsMinFullBlocks        = 64              // Number of blocks to retrieve fully even in fast sync
+
+	checkpointCheckFrequency = maxHeadersProcess // Verification frequency of the downloaded headers below a trusted checkpoint
 )
 
 var (
@@ -110,6 +114,12 @@ type Downloader struct {
 	lightchain LightChain
 	blockchain BlockChain
 
+	lightVerifyRecent uint64 // Number of most recent headers fully verified during light sync (0 = all)
+
+	snapSyncer SnapSyncer // Retriever of state ranges used in snap sync mode (nil = trie nodes only)
+
+	checkpoint *params.TrustedCheckpoint // Trusted checkpoint the synced chain must pass through (nil = none)
+
 	// Callbacks
 	dropPeer peerDropFn // Drops a peer for misbehaving
 
@@ -192,6 +202,14 @@ type BlockChain interface {
 	InsertReceiptChain(types.Blocks, []types.Receipts) (int, error)
 }
 
+// SnapSyncer retrieves the state of a block as contiguous ranges of accounts and
+// storage slots instead of individual trie nodes.
+type SnapSyncer interface {
+	// Sync retrieves the state with the given root, blocking until done, failed
+	// or cancelled.
+	Sync(root common.Hash, cancel <-chan struct{}) error
+}
+
 // New creates a new downloader to fetch hashes and blocks from remote peers.
 func New(mode SyncMode, stateDb gooladb.Database, mux *event.TypeMux, chain BlockChain, lightchain LightChain, dropPeer peerDropFn) *Downloader {
 	if lightchain == nil {
@@ -241,7 +259,7 @@ func (d *Downloader) Progress() goola.SyncProgress {
 	switch d.mode {
 	case FullSync:
 		current = d.blockchain.CurrentBlock().NumberU64()
-	case FastSync:
+	case FastSync, SnapSync:
 		current = d.blockchain.CurrentFastBlock().NumberU64()
 	case LightSync:
 		current = d.lightchain.CurrentHeader().Number.Uint64()
@@ -255,6 +273,45 @@ func (d *Downloader) Progress() goola.SyncProgress {
 	}
 }
 
+// SetLightHeaderSampling sets the number of most recent headers whose seals are
+// all verified during light sync. Seals of older headers are only verified for a
+// random sample of them, trading some trust in the serving peers for sync speed.
+// Zero verifies every header.
+func (d *Downloader) SetLightHeaderSampling(recent uint64) {
+	atomic.StoreUint64(&d.lightVerifyRecent, recent)
+}
+
+// SetSnapSyncer sets the retriever of state ranges used in snap sync mode. Any
+// state left missing by it is healed by retrieving individual trie nodes, which
+// is also the fallback if no snap syncer is set.
+func (d *Downloader) SetSnapSyncer(syncer SnapSyncer) {
+	d.snapSyncer = syncer
+}
+
+// SetCheckpoint sets the trusted checkpoint of the chain. Peers whose chain
+// doesn't pass through it are dropped, and the seals of the headers below it are
+// only verified sparsely, as their hashes are committed to by the checkpoint.
+func (d *Downloader) SetCheckpoint(checkpoint *params.TrustedCheckpoint) {
+	d.checkpoint = checkpoint
+}
+
+// lightCheckFrequency returns the seal verification frequency of a chunk of
+// headers imported during light sync.
+func (d *Downloader) lightCheckFrequency(chunk []*types.Header) int {
+	recent := atomic.LoadUint64(&d.lightVerifyRecent)
+	if recent == 0 {
+		return 1
+	}
+	d.syncStatsLock.RLock()
+	height := d.syncStatsChainHeight
+	d.syncStatsLock.RUnlock()
+
+	if chunk[len(chunk)-1].Number.Uint64()+recent > height {
+		return 1
+	}
+	return fsHeaderCheckFrequency
+}
+
 // Synchronising returns whether the downloader is currently retrieving blocks.
 func (d *Downloader) Synchronising() bool {
 	return atomic.LoadInt32(&d.synchronising) > 0
@@ -412,17 +469,30 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash) (err erro
 		log.Debug("Synchronisation terminated", "elapsed", time.Since(start))
 	}(time.Now())
 
+	ctx, span := tracing.Start(context.Background(), "downloader.sync", "peer", p.id, "mode", d.mode)
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
+
 	// Look up the sync boundaries: the common ancestor and the target block
-	latest, err := d.fetchHeight(p)
-	if err != nil {
+	var latest *types.Header
+	if err := traceStage(ctx, "fetchHeight", func() (err error) {
+		latest, err = d.fetchHeight(p)
+		return err
+	})(); err != nil {
 		return err
 	}
 	height := latest.Number.Uint64()
 
-	origin, err := d.findAncestor(p, height)
-	if err != nil {
+	var origin uint64
+	if err := traceStage(ctx, "findAncestor", func() (err error) {
+		origin, err = d.findAncestor(p, height)
+		return err
+	})(); err != nil {
 		return err
 	}
+	span.SetAttributes("origin", origin, "height", height)
 	d.syncStatsLock.Lock()
 	if d.syncStatsChainHeight <= origin || d.syncStatsChainOrigin > origin {
 		d.syncStatsChainOrigin = origin
@@ -432,7 +502,7 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash) (err erro
 
 	// Ensure our origin point is below any fast sync pivot point
 	pivot := uint64(0)
-	if d.mode == FastSync {
+	if d.mode == FastSync || d.mode == SnapSync {
 		if height <= uint64(fsMinFullBlocks) {
 			origin = 0
 		} else {
@@ -443,9 +513,17 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash) (err erro
 		}
 	}
 	d.committed = 1
-	if d.mode == FastSync && pivot != 0 {
+	if (d.mode == FastSync || d.mode == SnapSync) && pivot != 0 {
 		d.committed = 0
 	}
+	// Ensure the remote chain passes through the trusted checkpoint
+	var checkpoint uint64
+	if err := traceStage(ctx, "verifyCheckpoint", func() (err error) {
+		checkpoint, err = d.verifyCheckpoint(p, origin, height)
+		return err
+	})(); err != nil {
+		return err
+	}
 	// Initiate the sync using a concurrent header and content retrieval algorithm
 	d.queue.Prepare(origin+1, d.mode)
 	if d.syncInitHook != nil {
@@ -453,19 +531,30 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash) (err erro
 	}
 
 	fetchers := []func() error{
-		func() error { return d.fetchHeaders(p, origin+1, pivot) }, // Headers are always retrieved
-		func() error { return d.fetchBodies(origin + 1) },          // Bodies are retrieved during normal and fast sync
-		func() error { return d.fetchReceipts(origin + 1) },        // Receipts are retrieved during fast sync
-		func() error { return d.processHeaders(origin+1, pivot) },
+		traceStage(ctx, "fetchHeaders", func() error { return d.fetchHeaders(p, origin+1, pivot) }), // Headers are always retrieved
+		traceStage(ctx, "fetchBodies", func() error { return d.fetchBodies(origin + 1) }),           // Bodies are retrieved during normal and fast sync
+		traceStage(ctx, "fetchReceipts", func() error { return d.fetchReceipts(origin + 1) }),       // Receipts are retrieved during fast sync
+		traceStage(ctx, "processHeaders", func() error { return d.processHeaders(origin+1, pivot, checkpoint, height) }),
 	}
-	if d.mode == FastSync {
-		fetchers = append(fetchers, func() error { return d.processFastSyncContent(latest) })
+	if d.mode == FastSync || d.mode == SnapSync {
+		fetchers = append(fetchers, traceStage(ctx, "processFastSyncContent", func() error { return d.processFastSyncContent(latest) }))
 	} else if d.mode == FullSync {
-		fetchers = append(fetchers, d.processFullSyncContent)
+		fetchers = append(fetchers, traceStage(ctx, "processFullSyncContent", d.processFullSyncContent))
 	}
 	return d.spawnSync(fetchers)
 }
 
+// traceStage wraps a stage of a sync run into a span of the run's trace.
+func traceStage(ctx context.Context, name string, stage func() error) func() error {
+	return func() error {
+		_, span := tracing.Start(ctx, "downloader."+name)
+		err := stage()
+		span.SetError(err)
+		span.End()
+		return err
+	}
+}
+
 // spawnSync runs d.process and all given fetcher functions to completion in
 // separate goroutines, returning the first error that appears.
 func (d *Downloader) spawnSync(fetchers []func() error) error {
@@ -570,6 +659,56 @@ func (d *Downloader) fetchHeight(p *peerConnection) (*types.Header, error) {
 	}
 }
 
+// verifyCheckpoint ensures that the chain of the remote peer passes through the
+// trusted checkpoint if the sync crosses it. It returns the number of the
+// checkpoint header, or zero if the checkpoint doesn't apply to this sync.
+func (d *Downloader) verifyCheckpoint(p *peerConnection, origin uint64, height uint64) (uint64, error) {
+	if d.checkpoint == nil {
+		return 0, nil
+	}
+	number := d.checkpoint.HeadNumber()
+	if number <= origin || number > height {
+		return 0, nil
+	}
+	p.log.Debug("Verifying trusted checkpoint", "number", number, "hash", d.checkpoint.SectionHead)
+	go p.peer.RequestHeadersByNumber(number, 1, 0, false)
+
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return 0, errCancelHeaderFetch
+
+		case packet := <-d.headerCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				log.Debug("Received headers from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			// Make sure the peer actually gave something valid
+			headers := packet.(*headerPack).headers
+			if len(headers) != 1 {
+				p.log.Debug("Multiple headers for single request", "headers", len(headers))
+				return 0, errBadPeer
+			}
+			if header := headers[0]; header.Number.Uint64() != number || header.Hash() != d.checkpoint.SectionHead {
+				p.log.Warn("Trusted checkpoint mismatch", "number", header.Number, "hash", header.Hash(), "want", d.checkpoint.SectionHead)
+				return 0, errInvalidChain
+			}
+			return number, nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for checkpoint header timed out", "elapsed", ttl)
+			return 0, errTimeout
+
+		case <-d.bodyCh:
+		case <-d.receiptCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
+
 // findAncestor tries to locate the common ancestor link of the local chain and
 // a remote peers blockchain. In the general case when our node was in sync and
 // on the correct chain, checking the top N links should already get us a match.
@@ -581,7 +720,7 @@ func (d *Downloader) findAncestor(p *peerConnection, height uint64) (uint64, err
 
 	if d.mode == FullSync {
 		ceil = d.blockchain.CurrentBlock().NumberU64()
-	} else if d.mode == FastSync {
+	} else if d.mode == FastSync || d.mode == SnapSync {
 		ceil = d.blockchain.CurrentFastBlock().NumberU64()
 	}
 	if ceil >= MaxForkAncestry {
@@ -1142,8 +1281,9 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 
 // processHeaders takes batches of retrieved headers from an input channel and
 // keeps processing and scheduling them into the header chain and downloader's
-// queue until the stream ends or a failure occurs.
-func (d *Downloader) processHeaders(origin uint64, pivot uint64) error {
+// queue until the stream ends or a failure occurs. A stream ending before the
+// head the peer advertised at the given height is considered a stall.
+func (d *Downloader) processHeaders(origin uint64, pivot uint64, checkpoint uint64, height uint64) error {
 	// Keep a count of uncertain headers to roll back
 	rollback := []*types.Header{}
 	defer func() {
@@ -1188,6 +1328,12 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64) error {
 					case <-d.cancelCh:
 					}
 				}
+				// If the peer stopped short of the head it advertised, it withheld
+				// its promised chain. The only exception is if the headers were
+				// already imported by other means (e.g. fetcher).
+				if origin <= height && d.lightchain.CurrentHeader().Number.Uint64() < height {
+					return errStallingPeer
+				}
 				// Disable any rollback and return
 				rollback = nil
 				return nil
@@ -1208,8 +1354,17 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64) error {
 				}
 				chunk := headers[:limit]
 
+				// Make sure the chunk passes through the trusted checkpoint
+				first, last := chunk[0].Number.Uint64(), chunk[len(chunk)-1].Number.Uint64()
+				if checkpoint != 0 && first <= checkpoint && checkpoint <= last {
+					if header := chunk[checkpoint-first]; header.Hash() != d.checkpoint.SectionHead {
+						log.Debug("Trusted checkpoint mismatch", "number", header.Number, "hash", header.Hash(), "want", d.checkpoint.SectionHead)
+						return errInvalidChain
+					}
+				}
+
 				// In case of header only syncing, validate the chunk immediately
-				if d.mode == FastSync || d.mode == LightSync {
+				if d.mode == FastSync || d.mode == SnapSync || d.mode == LightSync {
 					// Collect the yet unknown headers to mark them as uncertain
 					unknown := make([]*types.Header, 0, len(headers))
 					for _, header := range chunk {
@@ -1222,6 +1377,13 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64) error {
 					if chunk[len(chunk)-1].Number.Uint64()+uint64(fsHeaderForceVerify) > pivot {
 						frequency = 1
 					}
+					if d.mode == LightSync {
+						frequency = d.lightCheckFrequency(chunk)
+					}
+					// Headers up to a verified checkpoint are committed to by its hash
+					if last <= checkpoint {
+						frequency = checkpointCheckFrequency
+					}
 					if n, err := d.lightchain.InsertHeaderChain(chunk, frequency); err != nil {
 						// If some headers were inserted, add them too to the rollback list
 						if n > 0 {
@@ -1237,7 +1399,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64) error {
 					}
 				}
 				// Unless we're doing light chains, schedule the headers for associated content retrieval
-				if d.mode == FullSync || d.mode == FastSync {
+				if d.mode == FullSync || d.mode == FastSync || d.mode == SnapSync {
 					// If we've reached the allowed number of pending headers, stall a bit
 					for d.queue.PendingBlocks() >= maxQueuedHeaders || d.queue.PendingReceipts() >= maxQueuedHeaders {
 						select {
@@ -213,6 +213,9 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 type stateSync struct {
 	d *Downloader // Downloader instance to access and manage current peerset
 
+	root common.Hash // State root being retrieved
+	snap SnapSyncer  // Retriever of state ranges preceding the node sync (nil = node sync only)
+
 	sched  *trie.TrieSync             // State trie sync scheduler defining the tasks
 	keccak hash.Hash                  // Keccak256 hasher to verify deliveries with
 	tasks  map[common.Hash]*stateTask // Set of tasks currently queued for retrieval
@@ -236,8 +239,14 @@ type stateTask struct {
 // newStateSync creates a new state trie download scheduler. This method does not
 // yet start the sync. The user needs to call run to initiate.
 func newStateSync(d *Downloader, root common.Hash) *stateSync {
+	var snap SnapSyncer
+	if d.mode == SnapSync {
+		snap = d.snapSyncer
+	}
 	return &stateSync{
 		d:       d,
+		root:    root,
+		snap:    snap,
 		sched:   state.NewStateSync(root, d.stateDB),
 		keccak:  sha3.NewKeccak256(),
 		tasks:   make(map[common.Hash]*stateTask),
@@ -249,8 +258,20 @@ func newStateSync(d *Downloader, root common.Hash) *stateSync {
 
 // run starts the task assignment and response processing loop, blocking until
 // it finishes, and finally notifying any goroutines waiting for the loop to
-// finish.
+// finish. In snap sync mode the state is first retrieved as ranges, the node
+// sync only healing whatever is still missing afterwards.
 func (s *stateSync) run() {
+	if s.snap != nil {
+		if err := s.snap.Sync(s.root, s.cancel); err != nil {
+			select {
+			case <-s.cancel:
+			default:
+				log.Warn("Snap sync failed, healing state via trie nodes", "root", s.root, "err", err)
+			}
+		}
+		// Reschedule the node sync to skip the tries retrieved meanwhile
+		s.sched = state.NewStateSync(s.root, s.d.stateDB)
+	}
 	s.err = s.loop()
 	close(s.done)
 }
@@ -28,6 +28,7 @@ import (
 	ethereum "github.com/goola-team/goola"
 	"github.com/goola-team/goola/common"
 	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core"
 	"github.com/goola-team/goola/core/types"
 	"github.com/goola-team/goola/gooladb"
 	"github.com/goola-team/goola/event"
@@ -57,6 +58,7 @@ type PublicFilterAPI struct {
 	quit      chan struct{}
 	chainDb   gooladb.Database
 	events    *EventSystem
+	registry  *ABIRegistry
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
 }
@@ -64,11 +66,12 @@ type PublicFilterAPI struct {
 // NewPublicFilterAPI returns a new PublicFilterAPI instance.
 func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
 	api := &PublicFilterAPI{
-		backend: backend,
-		mux:     backend.EventMux(),
-		chainDb: backend.ChainDb(),
-		events:  NewEventSystem(backend.EventMux(), backend, lightMode),
-		filters: make(map[rpc.ID]*filter),
+		backend:  backend,
+		mux:      backend.EventMux(),
+		chainDb:  backend.ChainDb(),
+		events:   NewEventSystem(backend.EventMux(), backend, lightMode),
+		registry: NewABIRegistry(backend.ChainDb()),
+		filters:  make(map[rpc.ID]*filter),
 	}
 	go api.timeoutLoop()
 
@@ -164,6 +167,45 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// DroppedTransaction is the notification of a transaction evicted from the
+// transaction pool.
+type DroppedTransaction struct {
+	Hash   common.Hash `json:"hash"`
+	Reason string      `json:"reason"`
+}
+
+// DroppedTransactions creates a subscription that is triggered each time a
+// transaction is evicted from the transaction pool without being included in a
+// block, e.g. for being underpriced, replaced, expired or to make room.
+func (api *PublicFilterAPI) DroppedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		drops := make(chan core.TxDropEvent)
+		droppedTxSub := api.events.SubscribeDroppedTxEvents(drops)
+
+		for {
+			select {
+			case ev := <-drops:
+				notifier.Notify(rpcSub.ID, &DroppedTransaction{Hash: ev.Tx.Hash(), Reason: ev.Reason})
+			case <-rpcSub.Err():
+				droppedTxSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				droppedTxSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with gla_getFilterChanges.
 //
@@ -241,7 +283,7 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 		matchedLogs = make(chan []*types.Log)
 	)
 
-	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery(crit), matchedLogs)
+	logsSub, err := api.events.SubscribeLogs(crit.query(), matchedLogs)
 	if err != nil {
 		return nil, err
 	}
@@ -251,6 +293,12 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 		for {
 			select {
 			case logs := <-matchedLogs:
+				if crit.Decode {
+					for _, log := range api.registry.Decode(logs) {
+						notifier.Notify(rpcSub.ID, log)
+					}
+					continue
+				}
 				for _, log := range logs {
 					notifier.Notify(rpcSub.ID, &log)
 				}
@@ -275,6 +323,17 @@ type FilterCriteria struct {
 	ToBlock   *big.Int
 	Addresses []common.Address
 	Topics    [][]common.Hash
+	Decode    bool // Whether to decode the events of contracts with registered ABIs
+}
+
+// query converts the criteria into the filter query of the event system.
+func (crit FilterCriteria) query() ethereum.FilterQuery {
+	return ethereum.FilterQuery{
+		FromBlock: crit.FromBlock,
+		ToBlock:   crit.ToBlock,
+		Addresses: crit.Addresses,
+		Topics:    crit.Topics,
+	}
 }
 
 // NewFilter creates a new filter and returns the filter id. It can be
@@ -292,7 +351,7 @@ type FilterCriteria struct {
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#gla_newfilter
 func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 	logs := make(chan []*types.Log)
-	logsSub, err := api.events.SubscribeLogs(ethereum.FilterQuery(crit), logs)
+	logsSub, err := api.events.SubscribeLogs(crit.query(), logs)
 	if err != nil {
 		return rpc.ID(""), err
 	}
@@ -325,7 +384,7 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 // GetLogs returns logs matching the given argument that are stored within the state.
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#gla_getlogs
-func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
+func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) (interface{}, error) {
 	// Convert the RPC block numbers into internal representations
 	if crit.FromBlock == nil {
 		crit.FromBlock = big.NewInt(rpc.LatestBlockNumber.Int64())
@@ -340,7 +399,7 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 	if err != nil {
 		return nil, err
 	}
-	return returnLogs(logs), err
+	return api.returnLogs(logs, crit.Decode), err
 }
 
 // UninstallFilter removes the filter with the given filter id.
@@ -364,7 +423,7 @@ func (api *PublicFilterAPI) UninstallFilter(id rpc.ID) bool {
 // If the filter could not be found an empty array of logs is returned.
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#gla_getfilterlogs
-func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*types.Log, error) {
+func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) (interface{}, error) {
 	api.filtersMu.Lock()
 	f, found := api.filters[id]
 	api.filtersMu.Unlock()
@@ -388,7 +447,7 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 	if err != nil {
 		return nil, err
 	}
-	return returnLogs(logs), nil
+	return api.returnLogs(logs, f.crit.Decode), nil
 }
 
 // GetFilterChanges returns the logs for the filter with the given id since
@@ -418,7 +477,7 @@ func (api *PublicFilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
 		case LogsSubscription:
 			logs := f.logs
 			f.logs = nil
-			return returnLogs(logs), nil
+			return api.returnLogs(logs, f.crit.Decode), nil
 		}
 	}
 
@@ -443,6 +502,15 @@ func returnLogs(logs []*types.Log) []*types.Log {
 	return logs
 }
 
+// returnLogs is a helper that will decode the events of the logs if requested,
+// otherwise returning them as is.
+func (api *PublicFilterAPI) returnLogs(logs []*types.Log, decode bool) interface{} {
+	if decode {
+		return api.registry.Decode(logs)
+	}
+	return returnLogs(logs)
+}
+
 // UnmarshalJSON sets *args fields with given data.
 func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 	type input struct {
@@ -450,6 +518,7 @@ func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 		ToBlock   *rpc.BlockNumber `json:"toBlock"`
 		Addresses interface{}      `json:"address"`
 		Topics    []interface{}    `json:"topics"`
+		Decode    bool             `json:"decode"`
 	}
 
 	var raw input
@@ -465,6 +534,7 @@ func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 		args.ToBlock = big.NewInt(raw.ToBlock.Int64())
 	}
 
+	args.Decode = raw.Decode
 	args.Addresses = []common.Address{}
 
 	if raw.Addresses != nil {
@@ -42,6 +42,7 @@ type testBackend struct {
 	db         gooladb.Database
 	sections   uint64
 	txFeed     *event.Feed
+	dropFeed   *event.Feed
 	rmLogsFeed *event.Feed
 	logsFeed   *event.Feed
 	chainFeed  *event.Feed
@@ -77,6 +78,10 @@ func (b *testBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Subsc
 	return b.txFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeTxDropEvent(ch chan<- core.TxDropEvent) event.Subscription {
+	return b.dropFeed.Subscribe(ch)
+}
+
 func (b *testBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return b.rmLogsFeed.Subscribe(ch)
 }
@@ -135,7 +140,7 @@ func TestBlockSubscription(t *testing.T) {
 		rmLogsFeed  = new(event.Feed)
 		logsFeed    = new(event.Feed)
 		chainFeed   = new(event.Feed)
-		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend     = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed}
 		api         = NewPublicFilterAPI(backend, false)
 		genesis     = new(core.Genesis).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, dpos.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {})
@@ -192,7 +197,7 @@ func TestPendingTxFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed}
 		api        = NewPublicFilterAPI(backend, false)
 
 		transactions = []*types.Transaction{
@@ -245,6 +250,47 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestDroppedTxSubscription tests whether dropped transaction subscriptions
+// receive the evicted transactions along with the reason of their eviction.
+func TestDroppedTxSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux       = new(event.TypeMux)
+		db, _     = gooladb.NewMemDatabase()
+		dropFeed  = new(event.Feed)
+		backend   = &testBackend{mux, db, 0, new(event.Feed), dropFeed, new(event.Feed), new(event.Feed), new(event.Feed)}
+		api       = NewPublicFilterAPI(backend, false)
+		recipient = common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268")
+
+		events = []core.TxDropEvent{
+			{Tx: types.NewTransaction(0, recipient, new(big.Int), 0, new(big.Int), types.TxTypeTransfer, nil), Reason: core.DropUnderpriced},
+			{Tx: types.NewTransaction(1, recipient, new(big.Int), 0, new(big.Int), types.TxTypeTransfer, nil), Reason: core.DropReplaced},
+			{Tx: types.NewTransaction(2, recipient, new(big.Int), 0, new(big.Int), types.TxTypeTransfer, nil), Reason: core.DropExpired},
+			{Tx: types.NewTransaction(3, recipient, new(big.Int), 0, new(big.Int), types.TxTypeTransfer, nil), Reason: core.DropPoolFull},
+		}
+	)
+	drops := make(chan core.TxDropEvent)
+	sub := api.events.SubscribeDroppedTxEvents(drops)
+	defer sub.Unsubscribe()
+
+	go func() {
+		for _, ev := range events {
+			dropFeed.Send(ev)
+		}
+	}()
+	for i, want := range events {
+		select {
+		case have := <-drops:
+			if have.Tx.Hash() != want.Tx.Hash() || have.Reason != want.Reason {
+				t.Errorf("drop %d: have %x (%s), want %x (%s)", i, have.Tx.Hash(), have.Reason, want.Tx.Hash(), want.Reason)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("drop %d: timeout", i)
+		}
+	}
+}
+
 // TestLogFilterCreation test whether a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
@@ -255,7 +301,7 @@ func TestLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed}
 		api        = NewPublicFilterAPI(backend, false)
 
 		testCases = []struct {
@@ -304,7 +350,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed}
 		api        = NewPublicFilterAPI(backend, false)
 	)
 
@@ -334,7 +380,7 @@ func TestLogFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
@@ -453,7 +499,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, new(event.Feed), rmLogsFeed, logsFeed, chainFeed}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
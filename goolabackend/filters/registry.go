@@ -0,0 +1,291 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/goola-team/goola/accounts/abi"
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/rlp"
+)
+
+var (
+	abiPrefix   = []byte("contract-abi-")      // abiPrefix + address -> contract ABI definition
+	abiIndexKey = []byte("contract-abi-index") // abiIndexKey -> rlp list of addresses with registered ABIs
+)
+
+// errNoEvents is returned if an ABI without any events is registered.
+var errNoEvents = errors.New("contract ABI defines no events")
+
+// ABIRegistry stores contract ABIs in the database, used to decode the events
+// emitted by the registered contracts.
+type ABIRegistry struct {
+	db   gooladb.Database
+	lock sync.Mutex // Serializes updates of the address index
+}
+
+// NewABIRegistry creates a contract ABI registry persisted in the given database.
+func NewABIRegistry(db gooladb.Database) *ABIRegistry {
+	return &ABIRegistry{db: db}
+}
+
+// Register validates and stores the ABI definition of a contract, replacing any
+// previously registered one.
+func (r *ABIRegistry) Register(addr common.Address, definition string) error {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		return err
+	}
+	if len(parsed.Events) == 0 {
+		return errNoEvents
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.db.Put(abiKey(addr), []byte(definition)); err != nil {
+		return err
+	}
+	addrs := r.contracts()
+	for _, known := range addrs {
+		if known == addr {
+			return nil
+		}
+	}
+	return r.storeIndex(append(addrs, addr))
+}
+
+// Unregister drops the ABI definition of a contract, returning whether one was
+// registered.
+func (r *ABIRegistry) Unregister(addr common.Address) (bool, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	addrs := r.contracts()
+	for i, known := range addrs {
+		if known == addr {
+			if err := r.db.Delete(abiKey(addr)); err != nil {
+				return false, err
+			}
+			return true, r.storeIndex(append(addrs[:i], addrs[i+1:]...))
+		}
+	}
+	return false, nil
+}
+
+// Contracts returns the addresses of all contracts with registered ABIs.
+func (r *ABIRegistry) Contracts() []common.Address {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.contracts()
+}
+
+// Definition returns the raw ABI definition registered for a contract, or an
+// empty string if none is.
+func (r *ABIRegistry) Definition(addr common.Address) string {
+	blob, _ := r.db.Get(abiKey(addr))
+	return string(blob)
+}
+
+// abiKey returns the database key of a contract's ABI definition.
+func abiKey(addr common.Address) []byte {
+	return append(append([]byte{}, abiPrefix...), addr.Bytes()...)
+}
+
+// contracts reads the address index. The caller must hold the lock.
+func (r *ABIRegistry) contracts() []common.Address {
+	blob, err := r.db.Get(abiIndexKey)
+	if err != nil || len(blob) == 0 {
+		return nil
+	}
+	var addrs []common.Address
+	if err := rlp.DecodeBytes(blob, &addrs); err != nil {
+		return nil
+	}
+	return addrs
+}
+
+// storeIndex persists the address index. The caller must hold the lock.
+func (r *ABIRegistry) storeIndex(addrs []common.Address) error {
+	blob, err := rlp.EncodeToBytes(addrs)
+	if err != nil {
+		return err
+	}
+	return r.db.Put(abiIndexKey, blob)
+}
+
+// PrivateABIRegistryAPI offers the administrative methods to manage the contract
+// ABIs used to decode events.
+type PrivateABIRegistryAPI struct {
+	registry *ABIRegistry
+}
+
+// NewPrivateABIRegistryAPI creates a new contract ABI registry API.
+func NewPrivateABIRegistryAPI(registry *ABIRegistry) *PrivateABIRegistryAPI {
+	return &PrivateABIRegistryAPI{registry}
+}
+
+// RegisterContractABI stores the JSON ABI definition of a contract, enabling the
+// decoding of its events.
+func (api *PrivateABIRegistryAPI) RegisterContractABI(addr common.Address, definition string) error {
+	return api.registry.Register(addr, definition)
+}
+
+// UnregisterContractABI drops the ABI definition of a contract, returning whether
+// one was registered.
+func (api *PrivateABIRegistryAPI) UnregisterContractABI(addr common.Address) (bool, error) {
+	return api.registry.Unregister(addr)
+}
+
+// ContractABIs returns the registered ABI definitions keyed by contract address.
+func (api *PrivateABIRegistryAPI) ContractABIs() map[common.Address]string {
+	definitions := make(map[common.Address]string)
+	for _, addr := range api.registry.Contracts() {
+		definitions[addr] = api.registry.Definition(addr)
+	}
+	return definitions
+}
+
+// DecodedEvent is the event name and parameters decoded from a log.
+type DecodedEvent struct {
+	Name   string                 `json:"name"`
+	Params map[string]interface{} `json:"params"`
+}
+
+// DecodedLog is a log along with its decoded event, if the emitting contract
+// has a registered ABI defining it.
+type DecodedLog struct {
+	Log   *types.Log
+	Event *DecodedEvent
+}
+
+// MarshalJSON marshals the log as usual, extended with the decoded event.
+func (l *DecodedLog) MarshalJSON() ([]byte, error) {
+	blob, err := json.Marshal(l.Log)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(blob, &fields); err != nil {
+		return nil, err
+	}
+	if fields["decoded"], err = json.Marshal(l.Event); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// Decode annotates the logs with their decoded events. Logs of contracts with no
+// registered ABI, or matching no event in it, are returned undecoded.
+func (r *ABIRegistry) Decode(logs []*types.Log) []*DecodedLog {
+	parsed := make(map[common.Address]*abi.ABI)
+
+	decoded := make([]*DecodedLog, len(logs))
+	for i, log := range logs {
+		decoded[i] = &DecodedLog{Log: log}
+
+		contract, ok := parsed[log.Address]
+		if !ok {
+			if definition := r.Definition(log.Address); definition != "" {
+				if parsedABI, err := abi.JSON(strings.NewReader(definition)); err == nil {
+					contract = &parsedABI
+				}
+			}
+			parsed[log.Address] = contract
+		}
+		if contract != nil {
+			decoded[i].Event = decodeEvent(contract, log)
+		}
+	}
+	return decoded
+}
+
+// decodeEvent decodes a log according to the matching event of a contract ABI.
+// Anonymous events cannot be identified and are never decoded.
+func decodeEvent(contract *abi.ABI, log *types.Log) *DecodedEvent {
+	if len(log.Topics) == 0 {
+		return nil
+	}
+	for _, event := range contract.Events {
+		if event.Anonymous || event.Id() != log.Topics[0] {
+			continue
+		}
+		values, err := event.Inputs.UnpackValues(log.Data)
+		if err != nil {
+			return nil
+		}
+		var (
+			params  = make(map[string]interface{})
+			topics  = log.Topics[1:]
+			nonIdx  = 0
+			indexed = 0
+		)
+		for _, arg := range event.Inputs {
+			if !arg.Indexed {
+				params[arg.Name] = formatParam(values[nonIdx])
+				nonIdx++
+				continue
+			}
+			if indexed >= len(topics) {
+				return nil
+			}
+			params[arg.Name] = decodeIndexedParam(arg.Type, topics[indexed])
+			indexed++
+		}
+		return &DecodedEvent{Name: event.Name, Params: params}
+	}
+	return nil
+}
+
+// decodeIndexedParam decodes an indexed event parameter. Dynamic types are stored
+// as the hash of their content, which is returned as is.
+func decodeIndexedParam(typ abi.Type, topic common.Hash) interface{} {
+	switch typ.T {
+	case abi.IntTy, abi.UintTy, abi.BoolTy, abi.AddressTy, abi.FixedBytesTy:
+		values, err := abi.Arguments{{Type: typ}}.UnpackValues(topic.Bytes())
+		if err == nil && len(values) == 1 {
+			return formatParam(values[0])
+		}
+	}
+	return topic
+}
+
+// formatParam converts decoded values into their usual RPC representation, hex
+// encoding big integers and byte blobs.
+func formatParam(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *big.Int:
+		return (*hexutil.Big)(v)
+	case []byte:
+		return hexutil.Bytes(v)
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Array && rv.Type().Name() == "" && rv.Type().Elem().Kind() == reflect.Uint8 {
+		blob := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(blob), rv)
+		return hexutil.Bytes(blob)
+	}
+	return value
+}
@@ -0,0 +1,98 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package filters
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+)
+
+const transferABI = `[{"type":"event","name":"Transfer","anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"address"},{"indexed":true,"name":"to","type":"address"},{"indexed":false,"name":"value","type":"uint256"}]}]`
+
+// Tests that registered ABIs are persisted and used to decode the events of
+// their contracts, leaving other logs undecoded.
+func TestABIRegistryDecode(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+
+	var (
+		token = common.Address{0xaa}
+		from  = common.Address{0x01}
+		to    = common.Address{0x02}
+	)
+	if err := NewABIRegistry(db).Register(token, `[{"type":"function","name":"f","inputs":[]}]`); err != errNoEvents {
+		t.Fatalf("event-less ABI registration error mismatch: have %v, want %v", err, errNoEvents)
+	}
+	if err := NewABIRegistry(db).Register(token, transferABI); err != nil {
+		t.Fatalf("failed to register ABI: %v", err)
+	}
+	registry := NewABIRegistry(db)
+	if contracts := registry.Contracts(); len(contracts) != 1 || contracts[0] != token {
+		t.Fatalf("registered contracts mismatch: %v", contracts)
+	}
+	transfer := &types.Log{
+		Address: token,
+		Topics: []common.Hash{
+			crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")),
+			common.BytesToHash(from.Bytes()),
+			common.BytesToHash(to.Bytes()),
+		},
+		Data: common.BigToHash(big.NewInt(1000)).Bytes(),
+	}
+	other := &types.Log{Address: common.Address{0xbb}, Topics: transfer.Topics, Data: transfer.Data}
+
+	decoded := registry.Decode([]*types.Log{transfer, other})
+	if decoded[1].Event != nil {
+		t.Errorf("unregistered contract log decoded: %+v", decoded[1].Event)
+	}
+	event := decoded[0].Event
+	if event == nil || event.Name != "Transfer" {
+		t.Fatalf("event decoding mismatch: %+v", event)
+	}
+	if event.Params["from"] != from || event.Params["to"] != to {
+		t.Errorf("indexed parameters mismatch: %v", event.Params)
+	}
+	if value, ok := event.Params["value"].(*hexutil.Big); !ok || value.ToInt().Int64() != 1000 {
+		t.Errorf("value parameter mismatch: %v", event.Params["value"])
+	}
+	blob, err := json.Marshal(decoded[0])
+	if err != nil {
+		t.Fatalf("failed to marshal decoded log: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(blob, &fields); err != nil {
+		t.Fatalf("failed to unmarshal decoded log: %v", err)
+	}
+	if _, ok := fields["topics"]; !ok {
+		t.Errorf("raw topics missing from decoded log: %s", blob)
+	}
+	if _, ok := fields["decoded"]; !ok {
+		t.Errorf("decoded event missing from log: %s", blob)
+	}
+	if ok, err := registry.Unregister(token); !ok || err != nil {
+		t.Fatalf("failed to unregister ABI: %v, %v", ok, err)
+	}
+	if decoded := registry.Decode([]*types.Log{transfer}); decoded[0].Event != nil {
+		t.Errorf("unregistered ABI still decoding: %+v", decoded[0].Event)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"errors"
+
+	"github.com/goola-team/goola/log"
+)
+
+// errNodeFrozen is returned if mining is attempted to be started on a node in
+// emergency read-only mode.
+var errNodeFrozen = errors.New("node frozen")
+
+// Freeze puts the node into emergency read-only mode: mining is stopped, any
+// chain synchronisation is aborted, and no new blocks or transactions are
+// accepted until unfrozen. The chain, state and pool contents remain available
+// to reads. It returns false if the node was already frozen.
+func (fullGoola *FullGoola) Freeze() bool {
+	fullGoola.freezeLock.Lock()
+	defer fullGoola.freezeLock.Unlock()
+
+	if fullGoola.frozen {
+		return false
+	}
+	fullGoola.frozen = true
+	fullGoola.frozenMining = fullGoola.IsMining()
+
+	fullGoola.blockchain.SetFrozen(true)
+	fullGoola.txPool.SetFrozen(true)
+	fullGoola.miner.Stop()
+	fullGoola.protocolManager.downloader.Cancel()
+
+	log.Warn("Node frozen in read-only mode", "number", fullGoola.blockchain.CurrentBlock().NumberU64(), "mining", fullGoola.frozenMining)
+	return true
+}
+
+// Unfreeze returns the node from emergency read-only mode into normal operation,
+// resuming mining if it was interrupted by the freeze. It returns false if the
+// node was not frozen.
+func (fullGoola *FullGoola) Unfreeze() (bool, error) {
+	fullGoola.freezeLock.Lock()
+	defer fullGoola.freezeLock.Unlock()
+
+	if !fullGoola.frozen {
+		return false, nil
+	}
+	fullGoola.frozen = false
+	fullGoola.blockchain.SetFrozen(false)
+	fullGoola.txPool.SetFrozen(false)
+
+	log.Warn("Node unfrozen", "number", fullGoola.blockchain.CurrentBlock().NumberU64(), "mining", fullGoola.frozenMining)
+	if fullGoola.frozenMining {
+		fullGoola.frozenMining = false
+		if err := fullGoola.StartMining(true); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// Frozen reports whether the node is in emergency read-only mode.
+func (fullGoola *FullGoola) Frozen() bool {
+	return fullGoola.blockchain.Frozen()
+}
+
+// Freeze puts the node into emergency read-only mode, refusing new blocks and
+// transactions and stopping mining, while keeping all reads available. It
+// returns false if the node was already frozen.
+func (api *PrivateAdminAPI) Freeze() bool {
+	return api.fullGoola.Freeze()
+}
+
+// Unfreeze returns the node from emergency read-only mode into normal operation.
+// It returns false if the node was not frozen.
+func (api *PrivateAdminAPI) Unfreeze() (bool, error) {
+	return api.fullGoola.Unfreeze()
+}
+
+// Frozen reports whether the node is in emergency read-only mode.
+func (api *PrivateAdminAPI) Frozen() bool {
+	return api.fullGoola.Frozen()
+}
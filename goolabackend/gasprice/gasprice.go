@@ -50,8 +50,29 @@ type Oracle struct {
 	percentile                       int
 }
 
-// NewOracle returns a new oracle.
+// NewOracle returns a new oracle, warmed up with the block prices sampled for
+// the last suggestion before the node was restarted.
 func NewOracle(backend ethapi.Backend, params Config) *Oracle {
+	gpo := &Oracle{
+		backend:   backend,
+		lastPrice: params.Default,
+	}
+	gpo.setParams(params)
+	gpo.loadSummary()
+	return gpo
+}
+
+// SetParams updates the sampling parameters of the oracle. The new parameters
+// take effect from the next chain head on.
+func (gpo *Oracle) SetParams(params Config) {
+	gpo.fetchLock.Lock()
+	defer gpo.fetchLock.Unlock()
+
+	gpo.setParams(params)
+}
+
+// setParams sanitizes and sets the sampling parameters of the oracle.
+func (gpo *Oracle) setParams(params Config) {
 	blocks := params.Blocks
 	if blocks < 1 {
 		blocks = 1
@@ -63,14 +84,10 @@ func NewOracle(backend ethapi.Backend, params Config) *Oracle {
 	if percent > 100 {
 		percent = 100
 	}
-	return &Oracle{
-		backend:     backend,
-		lastPrice:   params.Default,
-		checkBlocks: blocks,
-		maxEmpty:    blocks / 2,
-		maxBlocks:   blocks * 5,
-		percentile:  percent,
-	}
+	gpo.checkBlocks = blocks
+	gpo.maxEmpty = blocks / 2
+	gpo.maxBlocks = blocks * 5
+	gpo.percentile = percent
 }
 
 // SuggestPrice returns the recommended gas price.
@@ -135,6 +152,7 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	if len(blockPrices) > 0 {
 		sort.Sort(bigIntArray(blockPrices))
 		price = blockPrices[(len(blockPrices)-1)*gpo.percentile/100]
+		gpo.storeSummary(headHash, blockPrices)
 	}
 	if price.Cmp(maxPrice) > 0 {
 		price = new(big.Int).Set(maxPrice)
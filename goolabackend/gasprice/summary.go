@@ -0,0 +1,73 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rlp"
+)
+
+// priceSummaryKey is the database key the summary of the last price suggestion
+// is stored under, warming up the oracle across restarts.
+var priceSummaryKey = []byte("gasprice-summary")
+
+// priceSummary is a compact summary of the block prices sampled for the last
+// price suggestion.
+type priceSummary struct {
+	Head   common.Hash // Chain head the prices were sampled at
+	Prices []*big.Int  // Lowest non-miner price of every sampled block, sorted
+}
+
+// loadSummary reloads the prices sampled before the last shutdown, so sensible
+// suggestions can be served before the recent blocks are sampled again.
+func (gpo *Oracle) loadSummary() {
+	blob, err := gpo.backend.ChainDb().Get(priceSummaryKey)
+	if err != nil || len(blob) == 0 {
+		return
+	}
+	var summary priceSummary
+	if err := rlp.DecodeBytes(blob, &summary); err != nil {
+		log.Warn("Failed to decode gas price summary", "err", err)
+		return
+	}
+	if len(summary.Prices) == 0 {
+		return
+	}
+	sort.Sort(bigIntArray(summary.Prices))
+	price := summary.Prices[(len(summary.Prices)-1)*gpo.percentile/100]
+	if price.Cmp(maxPrice) > 0 {
+		price = new(big.Int).Set(maxPrice)
+	}
+	gpo.lastHead, gpo.lastPrice = summary.Head, price
+	log.Debug("Loaded gas price summary", "head", summary.Head, "samples", len(summary.Prices), "price", price)
+}
+
+// storeSummary persists the sorted prices sampled for a price suggestion.
+func (gpo *Oracle) storeSummary(head common.Hash, prices []*big.Int) {
+	blob, err := rlp.EncodeToBytes(&priceSummary{Head: head, Prices: prices})
+	if err != nil {
+		log.Warn("Failed to encode gas price summary", "err", err)
+		return
+	}
+	if err := gpo.backend.ChainDb().Put(priceSummaryKey, blob); err != nil {
+		log.Warn("Failed to store gas price summary", "err", err)
+	}
+}
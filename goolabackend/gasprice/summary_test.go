@@ -0,0 +1,106 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/internal/ethapi"
+	"github.com/goola-team/goola/params"
+	"github.com/goola-team/goola/rpc"
+)
+
+// testBackend is a backend serving a fixed chain, any other call panics.
+type testBackend struct {
+	ethapi.Backend
+	db      gooladb.Database
+	blocks  []*types.Block
+	fetches int32 // Number of blocks retrieved for sampling
+}
+
+func (b *testBackend) ChainDb() gooladb.Database        { return b.db }
+func (b *testBackend) ChainConfig() *params.ChainConfig { return params.TestChainConfig }
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	return b.blocks[len(b.blocks)-1].Header(), nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	atomic.AddInt32(&b.fetches, 1)
+	return b.blocks[number], nil
+}
+
+// newTestBackend creates a chain whose every block holds a single transaction
+// paying ten times the block number as gas price.
+func newTestBackend(t *testing.T, db gooladb.Database) *testBackend {
+	key, _ := crypto.GenerateKey()
+	signer := types.NewEIP155Signer(params.TestChainConfig.ChainId)
+
+	blocks := []*types.Block{types.NewBlockWithHeader(&types.Header{Number: new(big.Int)})}
+	for i := int64(1); i <= 10; i++ {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), common.Address{}, new(big.Int), 21000, big.NewInt(10*i), types.TxTypeTransfer, nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		header := &types.Header{ParentHash: blocks[i-1].Hash(), Number: big.NewInt(i)}
+		blocks = append(blocks, types.NewBlock(header, []*types.Transaction{tx}, nil))
+	}
+	return &testBackend{db: db, blocks: blocks}
+}
+
+// Tests that the prices sampled for a suggestion are persisted and warm up the
+// oracle after a restart, without sampling the chain again.
+func TestPriceSummaryWarmup(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	config := Config{Blocks: 5, Percentile: 60, Default: big.NewInt(1)}
+
+	backend := newTestBackend(t, db)
+	price, err := NewOracle(backend, config).SuggestPrice(context.Background())
+	if err != nil {
+		t.Fatalf("failed to suggest price: %v", err)
+	}
+	if price.Cmp(big.NewInt(80)) != 0 {
+		t.Fatalf("sampled price mismatch: have %v, want 80", price)
+	}
+	// A restarted oracle suggests the same price without sampling
+	restarted := &testBackend{db: db, blocks: backend.blocks}
+
+	price, err = NewOracle(restarted, config).SuggestPrice(context.Background())
+	if err != nil {
+		t.Fatalf("failed to suggest price after restart: %v", err)
+	}
+	if price.Cmp(big.NewInt(80)) != 0 || restarted.fetches != 0 {
+		t.Errorf("warmed up price mismatch: have %v after %d fetches, want 80 without any", price, restarted.fetches)
+	}
+	// The persisted samples are reevaluated with the new percentile
+	config.Percentile = 0
+	if gpo := NewOracle(restarted, config); gpo.lastPrice.Cmp(big.NewInt(60)) != 0 {
+		t.Errorf("reevaluated price mismatch: have %v, want 60", gpo.lastPrice)
+	}
+	// A corrupt summary falls back to the default price
+	db.Put(priceSummaryKey, []byte{0xff})
+	if gpo := NewOracle(restarted, config); gpo.lastPrice.Cmp(config.Default) != 0 || gpo.lastHead != (common.Hash{}) {
+		t.Errorf("price with corrupt summary mismatch: have %v, want %v", gpo.lastPrice, config.Default)
+	}
+}
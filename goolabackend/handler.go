@@ -31,6 +31,7 @@ import (
 	"github.com/goola-team/goola/core/types"
 	"github.com/goola-team/goola/goolabackend/downloader"
 	"github.com/goola-team/goola/goolabackend/fetcher"
+	"github.com/goola-team/goola/goolabackend/snap"
 	"github.com/goola-team/goola/gooladb"
 	"github.com/goola-team/goola/event"
 	"github.com/goola-team/goola/log"
@@ -57,14 +58,33 @@ var (
 // not compatible (low protocol version restrictions and high requirements).
 var errIncompatibleConfig = errors.New("incompatible configuration")
 
+// protoError is an error caused by a remote peer, tagged with its error code.
+type protoError struct {
+	code errCode
+	msg  string
+}
+
+func (e *protoError) Error() string { return e.msg }
+
 func errResp(code errCode, format string, v ...interface{}) error {
-	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+	return &protoError{code: code, msg: fmt.Sprintf("%v - %v", code, fmt.Sprintf(format, v...))}
+}
+
+// reportViolation reports the last message received from a peer to the p2p
+// layer if the error was caused by it being malformed or violating the protocol.
+func reportViolation(p *peer, err error) {
+	if perr, ok := err.(*protoError); ok {
+		if violation, ok := errorToViolation[perr.code]; ok {
+			p.ReportViolation(ProtocolName, violation, err)
+		}
+	}
 }
 
 type ProtocolManager struct {
 	networkId uint64
 
 	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
+	snapSync  bool   // Flag whether fast sync retrieves the state as ranges over the snap protocol
 	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
 
 	txpool      txPool
@@ -72,9 +92,17 @@ type ProtocolManager struct {
 	chainconfig *params.ChainConfig
 	maxPeers    int
 
-	downloader *downloader.Downloader
-	fetcher    *fetcher.Fetcher
-	peers      *peerSet
+	downloader   *downloader.Downloader
+	fetcher      *fetcher.Fetcher
+	peers        *peerSet
+	snapSyncer   *snap.Syncer  // Retriever of state ranges from the snap peers
+	queryLimiter *queryLimiter // Rate limiter of the data retrieval queries, nil if unlimited
+	txPrivacy    *txPrivacy    // Private forwarder of the local transactions, nil if broadcast openly
+	propagation  *propagationTracker
+	capabilities Capabilities // Optional protocol extensions supported locally
+
+	cassettes    map[string]string // Files to record the sessions of peers into, keyed by ID prefix
+	cassetteLock sync.Mutex
 
 	SubProtocols []p2p.Protocol
 
@@ -99,30 +127,34 @@ type ProtocolManager struct {
 func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, networkId uint64, mux *event.TypeMux, txpool txPool, engine consensus.Engine, blockchain *core.BlockChain, chaindb gooladb.Database) (*ProtocolManager, error) {
 	// Create the protocol manager with the base fields
 	manager := &ProtocolManager{
-		networkId:   networkId,
-		eventMux:    mux,
-		txpool:      txpool,
-		blockchain:  blockchain,
-		chainconfig: config,
-		peers:       newPeerSet(),
-		newPeerCh:   make(chan *peer),
-		noMorePeers: make(chan struct{}),
-		txsyncCh:    make(chan *txsync),
-		quitSync:    make(chan struct{}),
+		networkId:    networkId,
+		eventMux:     mux,
+		txpool:       txpool,
+		blockchain:   blockchain,
+		chainconfig:  config,
+		peers:        newPeerSet(),
+		newPeerCh:    make(chan *peer),
+		noMorePeers:  make(chan struct{}),
+		txsyncCh:     make(chan *txsync),
+		quitSync:     make(chan struct{}),
+		propagation:  newPropagationTracker(),
+		cassettes:    make(map[string]string),
+		capabilities: CapBlobs,
 	}
 	// Figure out whether to allow fast sync or not
-	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
+	if (mode == downloader.FastSync || mode == downloader.SnapSync) && blockchain.CurrentBlock().NumberU64() > 0 {
 		log.Warn("Blockchain not empty, fast sync disabled")
 		mode = downloader.FullSync
 	}
-	if mode == downloader.FastSync {
+	if mode == downloader.FastSync || mode == downloader.SnapSync {
 		manager.fastSync = uint32(1)
 	}
+	manager.snapSync = mode == downloader.SnapSync
 	// Initiate a sub-protocol for every implemented version we can handle
 	manager.SubProtocols = make([]p2p.Protocol, 0, len(ProtocolVersions))
 	for i, version := range ProtocolVersions {
 		// Skip protocol version if incompatible with the mode of operation
-		if mode == downloader.FastSync && version < eth63 {
+		if (mode == downloader.FastSync || mode == downloader.SnapSync) && version < eth63 {
 			continue
 		}
 		// Compatible; initialise the sub-protocol
@@ -132,6 +164,10 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 			Version: version,
 			Length:  ProtocolLengths[i],
 			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				if rec := manager.startRecording(p, int(version), rw); rec != nil {
+					defer rec.Close()
+					rw = rec
+				}
 				peer := manager.newPeer(int(version), p, rw)
 				select {
 				case manager.newPeerCh <- peer:
@@ -156,8 +192,26 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 	if len(manager.SubProtocols) == 0 {
 		return nil, errIncompatibleConfig
 	}
+	// Serve the local state as ranges, retrieving them too during snap sync
+	statedb, err := blockchain.State()
+	if err != nil {
+		return nil, err
+	}
+	manager.snapSyncer = snap.NewSyncer(chaindb)
+	manager.SubProtocols = append(manager.SubProtocols, p2p.Protocol{
+		Name:    snap.ProtocolName,
+		Version: snap.ProtocolVersion,
+		Length:  snap.ProtocolLength,
+		Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+			manager.wg.Add(1)
+			defer manager.wg.Done()
+			return snap.Handle(statedb.Database(), manager.snapSyncer, snap.NewPeer(p, rw))
+		},
+	})
 	// Construct the different synchronisation mechanisms
 	manager.downloader = downloader.New(mode, chaindb, manager.eventMux, blockchain, nil, manager.removePeer)
+	manager.downloader.SetSnapSyncer(manager.snapSyncer)
+	manager.downloader.SetCheckpoint(params.CheckpointOf(networkId, blockchain.Genesis().Hash()))
 
 	validator := func(header *types.Header) error {
 		return engine.VerifyHeader(blockchain, header, true)
@@ -189,9 +243,13 @@ func (pm *ProtocolManager) removePeer(id string) {
 
 	// Unregister the peer from the downloader and Goola peer set
 	pm.downloader.UnregisterPeer(id)
+	pm.propagation.dropPeer(id)
 	if err := pm.peers.Unregister(id); err != nil {
 		log.Error("Peer removal failed", "peer", id, "err", err)
 	}
+	if pm.queryLimiter != nil {
+		pm.queryLimiter.forget(id)
+	}
 	// Hard disconnect at the networking layer
 	if peer != nil {
 		peer.Peer.Disconnect(p2p.DiscUselessPeer)
@@ -258,8 +316,9 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		head    = pm.blockchain.CurrentHeader()
 		hash    = head.Hash()
 	)
-	if err := p.Handshake(pm.networkId, hash, genesis.Hash()); err != nil {
+	if err := p.Handshake(pm.networkId, hash, genesis.Hash(), pm.capabilities); err != nil {
 		p.Log().Debug("Ethereum handshake failed", "err", err)
+		reportViolation(p, err)
 		return err
 	}
 	if rw, ok := p.rw.(*meteredMsgReadWriter); ok {
@@ -283,6 +342,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	for {
 		if err := pm.handleMsg(p); err != nil {
 			p.Log().Debug("Ethereum message handling failed", "err", err)
+			reportViolation(p, err)
 			return err
 		}
 	}
@@ -314,7 +374,19 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&query); err != nil {
 			return errResp(ErrDecode, "%v: %v", msg, err)
 		}
+		amount := query.Amount
+		if amount > uint64(downloader.MaxHeaderFetch) {
+			amount = uint64(downloader.MaxHeaderFetch)
+		}
+		if allowed, err := pm.limitQuery(p, headerQueryCost*amount); err != nil {
+			return err
+		} else if !allowed {
+			return p.SendBlockHeaders(nil)
+		}
 		hashMode := query.Origin.Hash != (common.Hash{})
+		if hashMode {
+			pm.propagation.requested(query.Origin.Hash, msg.ReceivedAt)
+		}
 
 		// Gather headers until the fetch or network limits is reached
 		var (
@@ -406,6 +478,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 
 	case msg.Code == GetBlockBodiesMsg:
+		if allowed, err := pm.limitQuery(p, bodyQueryCost*hashQueryItems(msg, downloader.MaxBlockFetch)); err != nil {
+			return err
+		} else if !allowed {
+			return p.SendBlockBodiesRLP(nil)
+		}
 		// Decode the retrieval message
 		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
 		if _, err := msgStream.List(); err != nil {
@@ -457,6 +534,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 
 	case p.version >= eth63 && msg.Code == GetNodeDataMsg:
+		if allowed, err := pm.limitQuery(p, nodeDataQueryCost*hashQueryItems(msg, downloader.MaxStateFetch)); err != nil {
+			return err
+		} else if !allowed {
+			return p.SendNodeData(nil)
+		}
 		// Decode the retrieval message
 		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
 		if _, err := msgStream.List(); err != nil {
@@ -495,6 +577,11 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 
 	case p.version >= eth63 && msg.Code == GetReceiptsMsg:
+		if allowed, err := pm.limitQuery(p, receiptQueryCost*hashQueryItems(msg, downloader.MaxReceiptFetch)); err != nil {
+			return err
+		} else if !allowed {
+			return p.SendReceiptsRLP(nil)
+		}
 		// Decode the retrieval message
 		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
 		if _, err := msgStream.List(); err != nil {
@@ -549,6 +636,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		// Mark the hashes as present at the remote node
 		for _, block := range announces {
 			p.MarkBlock(block.Hash)
+			pm.propagation.announced(p.id, block.Hash, msg.ReceivedAt)
 		}
 		// Schedule all the unknown hashes for retrieval
 		unknown := make(newBlockHashesData, 0, len(announces))
@@ -572,6 +660,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 
 		// Mark the peer as owning the block and schedule it for import
 		p.MarkBlock(request.Block.Hash())
+		pm.propagation.received(p.id, request.Block, msg.ReceivedAt)
 		pm.fetcher.Enqueue(p.id, request.Block)
 
 
@@ -595,6 +684,26 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			p.MarkTransaction(tx.Hash())
 		}
 		pm.txpool.AddRemotes(txs)
+		pm.requestMissingBlobs(p, txs)
+
+	case p.Supports(CapBlobs) && msg.Code == GetBlobsMsg:
+		if allowed, err := pm.limitQuery(p, blobQueryCost*hashQueryItems(msg, maxBlobsFetch)); err != nil {
+			return err
+		} else if !allowed {
+			return p.SendBlobs(nil)
+		}
+		var hashes []common.Hash
+		if err := msg.Decode(&hashes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		return p.SendBlobs(pm.serveBlobs(hashes))
+
+	case p.Supports(CapBlobs) && msg.Code == BlobsMsg:
+		var blobs [][]byte
+		if err := msg.Decode(&blobs); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		pm.deliverBlobs(p, blobs)
 
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
@@ -646,6 +755,7 @@ func (self *ProtocolManager) minedBroadcastLoop() {
 	for obj := range self.minedBlockSub.Chan() {
 		switch ev := obj.Data.(type) {
 		case core.NewMinedBlockEvent:
+			self.propagation.sealedLocally(ev.Block.Hash(), time.Now())
 			self.BroadcastBlock(ev.Block, true)  // First propagate block to peers
 			self.BroadcastBlock(ev.Block, false) // Only then announce to the rest
 		}
@@ -656,6 +766,10 @@ func (self *ProtocolManager) txBroadcastLoop() {
 	for {
 		select {
 		case event := <-self.txCh:
+			if event.Local && self.txPrivacy != nil {
+				self.relayTx(event.Tx)
+				continue
+			}
 			self.BroadcastTx(event.Tx.Hash(), event.Tx)
 
 		// Err() channel will be closed when unsubscribing.
@@ -0,0 +1,365 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package maintenance implements a scheduler running heavy background tasks
+// only within maintenance windows or while the node is idle.
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/log"
+)
+
+// checkInterval is the period of checking whether any task is due.
+const checkInterval = time.Minute
+
+var (
+	errUnknownTask = errors.New("unknown maintenance task")
+	errTaskRunning = errors.New("maintenance task already running")
+)
+
+// Config are the configuration parameters of the maintenance scheduler.
+type Config struct {
+	// Windows are the daily UTC time ranges ("HH:MM-HH:MM") heavy tasks are
+	// allowed to run in regardless of the node load.
+	Windows []string `toml:",omitempty"`
+
+	// MaxPendingTxs is the transaction pool size below which the node counts as
+	// idle, allowing tasks to run outside the windows unless syncing (0 = never).
+	MaxPendingTxs int `toml:",omitempty"`
+
+	// Intervals of the periodic tasks (0 = disabled)
+	Compaction time.Duration `toml:",omitempty"` // Full database compaction
+	BloomCheck time.Duration `toml:",omitempty"` // Bloom index verification and repair
+}
+
+// DefaultConfig contains the default maintenance settings.
+var DefaultConfig = Config{
+	MaxPendingTxs: 64,
+}
+
+// Load is a snapshot of the node activity deciding whether it's idle.
+type Load struct {
+	Syncing    bool // Whether the node is synchronising with the network
+	PendingTxs int  // Number of transactions in the pool
+}
+
+// Func is a maintenance task body, which should return early if quit is closed.
+type Func func(quit <-chan struct{}) error
+
+// window is a daily time range, in minutes since midnight UTC. Windows with the
+// start after the end wrap around midnight.
+type window struct {
+	start, end int
+}
+
+// parseWindow parses a "HH:MM-HH:MM" daily time range.
+func parseWindow(spec string) (window, error) {
+	var sh, sm, eh, em int
+	if _, err := fmt.Sscanf(spec, "%d:%d-%d:%d", &sh, &sm, &eh, &em); err != nil {
+		return window{}, fmt.Errorf("invalid maintenance window %q: %v", spec, err)
+	}
+	if sh < 0 || sh > 23 || sm < 0 || sm > 59 || eh < 0 || em < 0 || em > 59 || eh*60+em > 24*60 {
+		return window{}, fmt.Errorf("invalid maintenance window %q: time out of range", spec)
+	}
+	return window{start: sh*60 + sm, end: eh*60 + em}, nil
+}
+
+// contains checks whether the given time falls within the window.
+func (w window) contains(t time.Time) bool {
+	t = t.UTC()
+	now := t.Hour()*60 + t.Minute()
+	if w.start <= w.end {
+		return now >= w.start && now < w.end
+	}
+	return now >= w.start || now < w.end
+}
+
+// task is a registered maintenance job along with its run history.
+type task struct {
+	name     string
+	interval time.Duration // Minimum time between runs, zero if only run on demand
+	run      Func
+
+	running bool      // Whether the task is currently executing
+	forced  bool      // Whether the task was requested to run regardless of the schedule
+	lastRun time.Time // Time the last run finished, zero if never run
+	lastErr error     // Failure of the last run, if any
+	nextRun time.Time // Earliest time of the next scheduled run
+}
+
+// TaskStatus is the schedule of a maintenance task.
+type TaskStatus struct {
+	Name     string     `json:"name"`
+	Interval string     `json:"interval"`
+	Running  bool       `json:"running"`
+	Forced   bool       `json:"forced"`
+	LastRun  *time.Time `json:"lastRun,omitempty"`
+	NextRun  *time.Time `json:"nextRun,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// Schedule is the state of the maintenance scheduler.
+type Schedule struct {
+	Windows        []string      `json:"windows"`
+	InWindow       bool          `json:"inWindow"`
+	Idle           bool          `json:"idle"`
+	SuspendedUntil *time.Time    `json:"suspendedUntil,omitempty"`
+	Tasks          []*TaskStatus `json:"tasks"`
+}
+
+// Scheduler runs the registered maintenance tasks when they are due, but only
+// within the maintenance windows or while the node is idle, one at a time.
+type Scheduler struct {
+	config  *Config
+	windows []window
+	load    func() Load
+
+	tasks     map[string]*task
+	suspended time.Time // Time until which scheduled runs are suspended
+	busy      bool      // Whether any task is running
+	lock      sync.Mutex
+
+	now  func() time.Time // Clock, overridable in tests
+	wake chan struct{}
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a maintenance scheduler measuring the node activity with the
+// given load function.
+func New(config *Config, load func() Load) (*Scheduler, error) {
+	windows := make([]window, 0, len(config.Windows))
+	for _, spec := range config.Windows {
+		w, err := parseWindow(spec)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return &Scheduler{
+		config:  config,
+		windows: windows,
+		load:    load,
+		tasks:   make(map[string]*task),
+		now:     time.Now,
+		wake:    make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+	}, nil
+}
+
+// Register adds a maintenance task, run at most once per interval. Tasks with
+// a zero interval are only run when explicitly requested.
+func (s *Scheduler) Register(name string, interval time.Duration, run Func) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Don't run the tasks right away on startup, wait a full interval first
+	s.tasks[name] = &task{name: name, interval: interval, run: run, nextRun: s.now().Add(interval)}
+}
+
+// Start begins running the due tasks in the background.
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop terminates the scheduler, signalling any running task to abort.
+func (s *Scheduler) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// Run requests a task to be executed as soon as possible, bypassing both the
+// interval and the windows.
+func (s *Scheduler) Run(name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	t, ok := s.tasks[name]
+	if !ok {
+		return errUnknownTask
+	}
+	if t.running {
+		return errTaskRunning
+	}
+	t.forced = true
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Suspend postpones all scheduled (not forced) runs for the given duration. A
+// zero duration resumes the schedule.
+func (s *Scheduler) Suspend(d time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.suspended = s.now().Add(d)
+}
+
+// Schedule returns the current state of the scheduler and its tasks.
+func (s *Scheduler) Schedule() *Schedule {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	schedule := &Schedule{
+		Windows:  append([]string{}, s.config.Windows...),
+		InWindow: s.inWindow(now),
+		Idle:     s.idle(),
+		Tasks:    make([]*TaskStatus, 0, len(s.tasks)),
+	}
+	if s.suspended.After(now) {
+		until := s.suspended
+		schedule.SuspendedUntil = &until
+	}
+	for _, t := range s.tasks {
+		status := &TaskStatus{
+			Name:     t.name,
+			Interval: t.interval.String(),
+			Running:  t.running,
+			Forced:   t.forced,
+		}
+		if !t.lastRun.IsZero() {
+			last := t.lastRun
+			status.LastRun = &last
+		}
+		if t.interval > 0 {
+			next := t.nextRun
+			status.NextRun = &next
+		}
+		if t.lastErr != nil {
+			status.Error = t.lastErr.Error()
+		}
+		schedule.Tasks = append(schedule.Tasks, status)
+	}
+	sort.Slice(schedule.Tasks, func(i, j int) bool { return schedule.Tasks[i].Name < schedule.Tasks[j].Name })
+	return schedule
+}
+
+// loop periodically starts the tasks that became due.
+func (s *Scheduler) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		s.dispatch()
+
+		select {
+		case <-ticker.C:
+		case <-s.wake:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// dispatch picks the next task to run, if any, and executes it in the background.
+func (s *Scheduler) dispatch() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.busy {
+		return
+	}
+	t := s.next(s.now())
+	if t == nil {
+		return
+	}
+	t.running, t.forced, s.busy = true, false, true
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		log.Info("Running maintenance task", "task", t.name)
+		start := time.Now()
+		err := t.run(s.quit)
+
+		s.lock.Lock()
+		t.running, s.busy = false, false
+		t.lastRun, t.lastErr = s.now(), err
+		t.nextRun = t.lastRun.Add(t.interval)
+		s.lock.Unlock()
+
+		if err != nil {
+			log.Error("Maintenance task failed", "task", t.name, "err", err)
+		} else {
+			log.Info("Maintenance task finished", "task", t.name, "elapsed", time.Since(start))
+		}
+		// Check whether another task is waiting
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}()
+}
+
+// next returns the task that should run now: any forced one first, otherwise
+// the most overdue task if the schedule permits running it.
+//
+// The caller needs to hold the scheduler lock.
+func (s *Scheduler) next(now time.Time) *task {
+	var due *task
+	for _, t := range s.tasks {
+		if t.forced {
+			return t
+		}
+		if t.interval == 0 || now.Before(t.nextRun) {
+			continue
+		}
+		if due == nil || t.nextRun.Before(due.nextRun) {
+			due = t
+		}
+	}
+	if due == nil || now.Before(s.suspended) {
+		return nil
+	}
+	if !s.inWindow(now) && !s.idle() {
+		return nil
+	}
+	return due
+}
+
+// inWindow checks whether the given time is within any maintenance window.
+func (s *Scheduler) inWindow(now time.Time) bool {
+	for _, w := range s.windows {
+		if w.contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// idle checks whether the node load is below the configured thresholds.
+func (s *Scheduler) idle() bool {
+	if s.config.MaxPendingTxs == 0 {
+		return false
+	}
+	load := s.load()
+	return !load.Syncing && load.PendingTxs < s.config.MaxPendingTxs
+}
@@ -0,0 +1,103 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that maintenance windows are parsed and matched correctly, including
+// the ones wrapping around midnight.
+func TestWindows(t *testing.T) {
+	tests := []struct {
+		spec  string
+		time  string
+		match bool
+	}{
+		{"02:00-04:30", "02:00", true},
+		{"02:00-04:30", "04:29", true},
+		{"02:00-04:30", "04:30", false},
+		{"02:00-04:30", "01:59", false},
+		{"23:00-01:00", "23:30", true},
+		{"23:00-01:00", "00:30", true},
+		{"23:00-01:00", "12:00", false},
+		{"22:00-24:00", "23:59", true},
+	}
+	for i, tt := range tests {
+		w, err := parseWindow(tt.spec)
+		if err != nil {
+			t.Fatalf("test %d: failed to parse window %q: %v", i, tt.spec, err)
+		}
+		at, _ := time.Parse("15:04", tt.time)
+		if match := w.contains(at); match != tt.match {
+			t.Errorf("test %d: window %q at %s: have %v, want %v", i, tt.spec, tt.time, match, tt.match)
+		}
+	}
+	for _, spec := range []string{"", "2-4", "25:00-01:00", "01:00-24:30", "01:60-02:00"} {
+		if _, err := parseWindow(spec); err == nil {
+			t.Errorf("invalid window %q accepted", spec)
+		}
+	}
+}
+
+// Tests that due tasks are only picked outside the windows if the node is idle,
+// and that forced and suspended runs override the schedule.
+func TestScheduling(t *testing.T) {
+	load := Load{Syncing: true}
+	s, err := New(&Config{Windows: []string{"02:00-04:00"}, MaxPendingTxs: 10}, func() Load { return load })
+	if err != nil {
+		t.Fatalf("failed to create scheduler: %v", err)
+	}
+	now := time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	s.Register("hourly", time.Hour, func(<-chan struct{}) error { return nil })
+	s.Register("manual", 0, func(<-chan struct{}) error { return nil })
+
+	if task := s.next(now); task != nil {
+		t.Fatalf("task %q picked before due", task.name)
+	}
+	// Make the periodic task due, but keep the node busy outside the window
+	now = now.Add(time.Hour)
+	if task := s.next(now); task != nil {
+		t.Fatalf("task %q picked while busy outside the window", task.name)
+	}
+	load = Load{PendingTxs: 5}
+	if task := s.next(now); task == nil || task.name != "hourly" {
+		t.Fatalf("due task not picked while idle: %v", task)
+	}
+	load = Load{Syncing: true}
+	if task := s.next(time.Date(2018, 1, 2, 3, 0, 0, 0, time.UTC)); task == nil || task.name != "hourly" {
+		t.Fatalf("due task not picked within the window: %v", task)
+	}
+	// Suspend the schedule and ensure only forced tasks are picked
+	load = Load{}
+	s.Suspend(time.Hour)
+	if task := s.next(now); task != nil {
+		t.Fatalf("task %q picked while suspended", task.name)
+	}
+	if err := s.Run("missing"); err != errUnknownTask {
+		t.Fatalf("unknown task run error mismatch: have %v, want %v", err, errUnknownTask)
+	}
+	if err := s.Run("manual"); err != nil {
+		t.Fatalf("failed to force task: %v", err)
+	}
+	if task := s.next(now); task == nil || task.name != "manual" {
+		t.Fatalf("forced task not picked: %v", task)
+	}
+}
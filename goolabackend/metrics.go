@@ -54,6 +54,10 @@ var (
 	miscInTrafficMeter        = metrics.NewMeter("goolabackend/misc/in/traffic")
 	miscOutPacketsMeter       = metrics.NewMeter("goolabackend/misc/out/packets")
 	miscOutTrafficMeter       = metrics.NewMeter("goolabackend/misc/out/traffic")
+
+	propLatencyAnnounceTimer = metrics.NewTimer("goolabackend/prop/latency/announce")
+	propLatencyReceiptTimer  = metrics.NewTimer("goolabackend/prop/latency/receipt")
+	propLatencyAckTimer      = metrics.NewTimer("goolabackend/prop/latency/ack")
 )
 
 // meteredMsgReadWriter is a wrapper around a p2p.MsgReadWriter, capable of
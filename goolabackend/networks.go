@@ -0,0 +1,192 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"fmt"
+
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/node"
+	"github.com/goola-team/goola/p2p"
+	"github.com/goola-team/goola/p2p/discover"
+	"github.com/goola-team/goola/rpc"
+)
+
+// NetworkConfig is the configuration of an additional network.
+type NetworkConfig struct {
+	Goola Config // Full node settings of the network
+
+	// ListenAddr is the address the network's p2p server listens on, which must
+	// differ from the one of the primary network. Empty disables listening.
+	ListenAddr string
+
+	// BootstrapNodes are used to discover the other nodes of the network.
+	BootstrapNodes []*discover.Node `toml:",omitempty"`
+}
+
+// Networks runs additional Goola networks side by side with the primary one in
+// the same node. Each network keeps its chain in its own data directory, and is
+// served by its own p2p server speaking the unchanged wire protocols, so it can
+// peer with the ordinary nodes of the network. The RPC API namespaces are
+// distinguished by the network ID (e.g. goolabackend5_blockNumber for network 5).
+type Networks struct {
+	networks []*network
+}
+
+// network is a full node of an additional network with its own p2p server.
+type network struct {
+	goola  *FullGoola
+	config *NetworkConfig
+	nodedb string // Node database of the network's p2p server
+	server *p2p.Server
+}
+
+// NewNetworks creates a full node for each of the given network configurations.
+func NewNetworks(ctx *node.ServiceContext, configs []NetworkConfig) (*Networks, error) {
+	seen := make(map[uint64]bool)
+
+	var primary *FullGoola
+	if err := ctx.Service(&primary); err == nil {
+		seen[primary.NetVersion()] = true
+	}
+	n := new(Networks)
+	for i := range configs {
+		netconf := configs[i]
+		config := &netconf.Goola
+		if seen[config.NetworkId] {
+			return nil, fmt.Errorf("duplicate network %d", config.NetworkId)
+		}
+		seen[config.NetworkId] = true
+
+		// Fill in the settings left out from the network's config section
+		if config.DatabaseCache == 0 {
+			config.DatabaseCache = DefaultConfig.DatabaseCache
+		}
+		if config.TrieCache == 0 {
+			config.TrieCache = DefaultConfig.TrieCache
+		}
+		if config.TrieTimeout == 0 {
+			config.TrieTimeout = DefaultConfig.TrieTimeout
+		}
+		if config.GasPrice == nil {
+			config.GasPrice = DefaultConfig.GasPrice
+		}
+		// Secondary networks don't serve light clients
+		config.LightServ, config.LightPeers = 0, 0
+
+		subctx := ctx.Subcontext(fmt.Sprintf("network%d", config.NetworkId))
+		goola, err := New(subctx, config)
+		if err != nil {
+			return nil, fmt.Errorf("network %d: %v", config.NetworkId, err)
+		}
+		n.networks = append(n.networks, &network{
+			goola:  goola,
+			config: &netconf,
+			nodedb: subctx.ResolvePath("nodes"),
+		})
+	}
+	return n, nil
+}
+
+// Networks returns the full nodes of the additional networks.
+func (n *Networks) Networks() []*FullGoola {
+	networks := make([]*FullGoola, len(n.networks))
+	for i, network := range n.networks {
+		networks[i] = network.goola
+	}
+	return networks
+}
+
+// Servers returns the p2p servers of the additional networks, nil before start.
+func (n *Networks) Servers() []*p2p.Server {
+	servers := make([]*p2p.Server, len(n.networks))
+	for i, network := range n.networks {
+		servers[i] = network.server
+	}
+	return servers
+}
+
+// Protocols implements node.Service. The protocols of the additional networks
+// run on their own p2p servers instead of the primary one.
+func (n *Networks) Protocols() []p2p.Protocol {
+	return nil
+}
+
+// APIs implements node.Service, returning the RPC APIs of all the networks,
+// moved into their network specific namespaces.
+func (n *Networks) APIs() []rpc.API {
+	var apis []rpc.API
+	for _, network := range n.networks {
+		for _, api := range network.goola.APIs() {
+			api.Namespace += fmt.Sprintf("%d", network.goola.NetVersion())
+			apis = append(apis, api)
+		}
+	}
+	return apis
+}
+
+// Start implements node.Service, starting the p2p servers and full nodes of all
+// the networks. The servers share the node key and peer limits of the primary
+// one, but have their own listening address, bootstrap nodes and node database.
+func (n *Networks) Start(srvr *p2p.Server) error {
+	for i, network := range n.networks {
+		if err := network.start(srvr.Config); err != nil {
+			for _, started := range n.networks[:i] {
+				started.stop()
+			}
+			return fmt.Errorf("network %d: %v", network.goola.NetVersion(), err)
+		}
+		log.Info("Started additional network", "network", network.goola.NetVersion(), "listen", network.config.ListenAddr)
+	}
+	return nil
+}
+
+// Stop implements node.Service, terminating all the networks.
+func (n *Networks) Stop() error {
+	for _, network := range n.networks {
+		network.stop()
+	}
+	return nil
+}
+
+// start starts the network's p2p server, derived from the primary server's
+// configuration, and its full node on top.
+func (n *network) start(config p2p.Config) error {
+	config.ListenAddr = n.config.ListenAddr
+	config.BootstrapNodes = n.config.BootstrapNodes
+	config.BootstrapNodesV5, config.DiscoveryV5 = nil, false
+	config.StaticNodes, config.TrustedNodes = nil, nil
+	config.NodeDatabase = n.nodedb
+	config.QuarantineDir, config.PeerHistoryDatabase = "", ""
+	config.Protocols = n.goola.Protocols()
+
+	n.server = &p2p.Server{Config: config}
+	if err := n.server.Start(); err != nil {
+		return err
+	}
+	if err := n.goola.Start(n.server); err != nil {
+		n.server.Stop()
+		return err
+	}
+	return nil
+}
+
+// stop terminates the network's full node and p2p server.
+func (n *network) stop() {
+	n.goola.Stop()
+	n.server.Stop()
+}
@@ -0,0 +1,93 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/node"
+	"github.com/goola-team/goola/p2p"
+)
+
+// Tests that an additional network run side by side with the primary one peers
+// with the ordinary nodes of that network.
+func TestNetworksConnect(t *testing.T) {
+	genesis := core.DeveloperGenesisBlock(15, common.Address{})
+	p2pConfig := p2p.Config{ListenAddr: "127.0.0.1:0", NoDiscovery: true, MaxPeers: 10}
+
+	// Start an ordinary node of the network
+	ordinary, err := node.New(&node.Config{Name: "ordinary", P2P: p2pConfig})
+	if err != nil {
+		t.Fatalf("failed to create ordinary node: %v", err)
+	}
+	if err := ordinary.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		return New(ctx, &Config{Genesis: genesis, NetworkId: 5})
+	}); err != nil {
+		t.Fatalf("failed to register ordinary full node: %v", err)
+	}
+	if err := ordinary.Start(); err != nil {
+		t.Fatalf("failed to start ordinary node: %v", err)
+	}
+	defer ordinary.Stop()
+
+	// Start a node running the same network as an additional one
+	multi, err := node.New(&node.Config{Name: "multi", P2P: p2pConfig})
+	if err != nil {
+		t.Fatalf("failed to create multi-network node: %v", err)
+	}
+	if err := multi.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		return NewNetworks(ctx, []NetworkConfig{{
+			Goola:      Config{Genesis: genesis, NetworkId: 5},
+			ListenAddr: "127.0.0.1:0",
+		}})
+	}); err != nil {
+		t.Fatalf("failed to register additional networks: %v", err)
+	}
+	if err := multi.Start(); err != nil {
+		t.Fatalf("failed to start multi-network node: %v", err)
+	}
+	defer multi.Stop()
+
+	var (
+		networks *Networks
+		goola    *FullGoola
+	)
+	if err := multi.Service(&networks); err != nil {
+		t.Fatalf("failed to retrieve additional networks: %v", err)
+	}
+	if err := ordinary.Service(&goola); err != nil {
+		t.Fatalf("failed to retrieve ordinary full node: %v", err)
+	}
+	// Connect the additional network to the ordinary node and wait for the
+	// protocol handshake to complete on both sides
+	networks.Servers()[0].AddPeer(ordinary.Server().Self())
+
+	timeout := time.After(5 * time.Second)
+	for goola.protocolManager.peers.Len() == 0 || networks.Networks()[0].protocolManager.peers.Len() == 0 {
+		select {
+		case <-timeout:
+			t.Fatalf("networks didn't connect")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if peers := multi.Server().PeerCount(); peers != 0 {
+		t.Errorf("primary server peered with the additional network: %d peers", peers)
+	}
+}
@@ -44,8 +44,9 @@ const (
 // PeerInfo represents a short summary of the Goola sub-protocol metadata known
 // about a connected peer.
 type PeerInfo struct {
-	Version    int      `json:"version"`    // Goola protocol version negotiated
-	Head       string   `json:"head"`       // SHA3 hash of the peer's best owned block
+	Version      int      `json:"version"`                // Goola protocol version negotiated
+	Head         string   `json:"head"`                   // SHA3 hash of the peer's best owned block
+	Capabilities []string `json:"capabilities,omitempty"` // Optional protocol extensions negotiated
 }
 
 type peer struct {
@@ -54,14 +55,16 @@ type peer struct {
 	*p2p.Peer
 	rw p2p.MsgReadWriter
 
-	version  int         // Protocol version negotiated
-	forkDrop *time.Timer // Timed connection dropper if forks aren't validated in time
+	version  int          // Protocol version negotiated
+	caps     Capabilities // Optional protocol extensions supported by both sides
+	forkDrop *time.Timer  // Timed connection dropper if forks aren't validated in time
 
 	head common.Hash
 	lock sync.RWMutex
 
 	knownTxs    *set.Set // Set of transaction hashes known to be known by this peer
 	knownBlocks *set.Set // Set of block hashes known to be known by this peer
+	blobs       *set.Set // Set of blob hashes requested from this peer and not yet delivered
 }
 
 func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
@@ -74,6 +77,7 @@ func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 		id:          fmt.Sprintf("%x", id[:8]),
 		knownTxs:    set.New(),
 		knownBlocks: set.New(),
+		blobs:       set.New(),
 	}
 }
 
@@ -82,11 +86,18 @@ func (p *peer) Info() *PeerInfo {
 	hash := p.Head()
 
 	return &PeerInfo{
-		Version:    p.version,
-		Head:       hash.Hex(),
+		Version:      p.version,
+		Head:         hash.Hex(),
+		Capabilities: p.caps.Names(),
 	}
 }
 
+// Supports reports whether the given optional protocol extensions were
+// negotiated with the peer.
+func (p *peer) Supports(caps Capabilities) bool {
+	return p.caps.Has(caps)
+}
+
 // Head retrieves a copy of the current head hash
 func (p *peer) Head() (hash common.Hash) {
 	p.lock.RLock()
@@ -223,18 +234,29 @@ func (p *peer) RequestReceipts(hashes []common.Hash) error {
 }
 
 // Handshake executes the goola protocol handshake, negotiating version number,
-// network IDs, difficulties, head and genesis blocks.
-func (p *peer) Handshake(network uint64, head common.Hash, genesis common.Hash) error {
+// network IDs, difficulties, head and genesis blocks, as well as the optional
+// protocol extensions supported by both sides from goolabackend/64 on.
+func (p *peer) Handshake(network uint64, head common.Hash, genesis common.Hash, caps Capabilities) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
-	var status statusData // safe to read after two values have been received from errc
+	var status statusData64 // safe to read after two values have been received from errc
 
 	go func() {
-		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
+		if p.version < eth64 {
+			errc <- p2p.Send(p.rw, StatusMsg, &statusData{
+				ProtocolVersion: uint32(p.version),
+				NetworkId:       network,
+				CurrentBlock:    head,
+				GenesisBlock:    genesis,
+			})
+			return
+		}
+		errc <- p2p.Send(p.rw, StatusMsg, &statusData64{
 			ProtocolVersion: uint32(p.version),
 			NetworkId:       network,
 			CurrentBlock:    head,
 			GenesisBlock:    genesis,
+			Capabilities:    caps,
 		})
 	}()
 	go func() {
@@ -252,11 +274,12 @@ func (p *peer) Handshake(network uint64, head common.Hash, genesis common.Hash)
 			return p2p.DiscReadTimeout
 		}
 	}
-	 p.head = status.CurrentBlock
+	p.head = status.CurrentBlock
+	p.caps = status.Capabilities & caps
 	return nil
 }
 
-func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash) (err error) {
+func (p *peer) readStatus(network uint64, status *statusData64, genesis common.Hash) (err error) {
 	msg, err := p.rw.ReadMsg()
 	if err != nil {
 		return err
@@ -268,7 +291,19 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
 	}
 	// Decode the handshake and make sure everything matches
-	if err := msg.Decode(&status); err != nil {
+	if p.version < eth64 {
+		var legacy statusData
+		if err := msg.Decode(&legacy); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		*status = statusData64{
+			ProtocolVersion: legacy.ProtocolVersion,
+			NetworkId:       legacy.NetworkId,
+			TD:              legacy.TD,
+			CurrentBlock:    legacy.CurrentBlock,
+			GenesisBlock:    legacy.GenesisBlock,
+		}
+	} else if err := msg.Decode(status); err != nil {
 		return errResp(ErrDecode, "msg %v: %v", msg, err)
 	}
 	if status.GenesisBlock != genesis {
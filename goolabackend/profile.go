@@ -0,0 +1,141 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"fmt"
+
+	"github.com/goola-team/goola/goolabackend/downloader"
+)
+
+// Profile is a node mode preset, expanding into a consistent bundle of settings.
+type Profile string
+
+const (
+	ProfileNone        Profile = ""             // No preset, settings are used as configured
+	ProfileArchive     Profile = "archive"      // Full sync, all states, blocks and blobs retained
+	ProfileFull        Profile = "full"         // Fast sync with state pruning
+	ProfileLightServer Profile = "light-server" // Full node serving light clients the entire history
+	ProfileRPCGateway  Profile = "rpc-gateway"  // Full node tuned for serving RPC queries
+)
+
+const (
+	// archiveDatabaseCache is the minimum database cache (MB) of archive nodes.
+	archiveDatabaseCache = 2048
+
+	// lightServerDatabaseCache is the minimum database cache (MB) of light servers.
+	lightServerDatabaseCache = 1024
+
+	// lightServerServ is the percentage of time light servers serve LES requests
+	// if not configured.
+	lightServerServ = 50
+
+	// gatewayDatabaseCache and gatewayTrieCache are the minimum caches (MB) of
+	// RPC gateways.
+	gatewayDatabaseCache = 2048
+	gatewayTrieCache     = 512
+
+	// gatewayCallCache is the minimum number of call results cached by RPC
+	// gateways.
+	gatewayCallCache = 16384
+)
+
+// ProfileConflictError is returned if an explicitly configured setting
+// contradicts the configured node profile.
+type ProfileConflictError struct {
+	Profile Profile
+	Setting string
+	Value   interface{}
+}
+
+func (err *ProfileConflictError) Error() string {
+	return fmt.Sprintf("profile %s conflicts with %s = %v", err.Profile, err.Setting, err.Value)
+}
+
+// ApplyProfile expands the configured profile into its settings. Settings left
+// at their defaults are overridden, while explicitly configured ones are only
+// checked to be consistent with the profile, returning a ProfileConflictError
+// otherwise. Applying a profile multiple times is harmless.
+func (c *Config) ApplyProfile() error {
+	conflict := func(setting string, value interface{}) error {
+		return &ProfileConflictError{Profile: c.Profile, Setting: setting, Value: value}
+	}
+	if c.Profile != ProfileNone && c.SyncMode == downloader.LightSync {
+		return conflict("SyncMode", c.SyncMode)
+	}
+	switch c.Profile {
+	case ProfileNone:
+		return nil
+
+	case ProfileArchive:
+		if c.StateDiffs {
+			return conflict("StateDiffs", c.StateDiffs)
+		}
+		if c.RetainBlocks != 0 {
+			return conflict("RetainBlocks", c.RetainBlocks)
+		}
+		if c.BlobRetention != 0 && c.BlobRetention != DefaultConfig.BlobRetention {
+			return conflict("BlobRetention", c.BlobRetention)
+		}
+		c.SyncMode = downloader.FullSync
+		c.NoPruning = true
+		c.BlobRetention = 0
+		c.InternalTxIndex = true
+		c.DatabaseCache = atLeast(c.DatabaseCache, archiveDatabaseCache)
+
+	case ProfileFull:
+		if c.NoPruning {
+			return conflict("NoPruning", c.NoPruning)
+		}
+
+	case ProfileLightServer:
+		if c.RetainBlocks != 0 {
+			return conflict("RetainBlocks", c.RetainBlocks)
+		}
+		if c.LightServ == 0 {
+			c.LightServ = lightServerServ
+		}
+		if c.LightPeers == 0 {
+			c.LightPeers = DefaultConfig.LightPeers
+		}
+		c.DatabaseCache = atLeast(c.DatabaseCache, lightServerDatabaseCache)
+
+	case ProfileRPCGateway:
+		if c.LightServ != 0 {
+			return conflict("LightServ", c.LightServ)
+		}
+		if c.CallCache.Size == 0 {
+			return conflict("CallCache.Size", c.CallCache.Size)
+		}
+		c.InternalTxIndex = true
+		c.DatabaseCache = atLeast(c.DatabaseCache, gatewayDatabaseCache)
+		c.TrieCache = atLeast(c.TrieCache, gatewayTrieCache)
+		c.CallCache.Size = atLeast(c.CallCache.Size, gatewayCallCache)
+
+	default:
+		return fmt.Errorf("unknown node profile %q", c.Profile)
+	}
+	return nil
+}
+
+// atLeast returns the configured value, raised to the given minimum.
+func atLeast(value, min int) int {
+	if value < min {
+		return min
+	}
+	return value
+}
@@ -0,0 +1,96 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"testing"
+
+	"github.com/goola-team/goola/goolabackend/downloader"
+)
+
+// Tests that node profiles expand into their settings and reject conflicting
+// explicit ones.
+func TestApplyProfile(t *testing.T) {
+	tests := []struct {
+		profile  Profile
+		tweak    func(*Config)
+		conflict string
+		check    func(*Config) bool
+	}{
+		{
+			profile: ProfileArchive,
+			check: func(c *Config) bool {
+				return c.SyncMode == downloader.FullSync && c.NoPruning && c.BlobRetention == 0 && c.InternalTxIndex && c.DatabaseCache == archiveDatabaseCache
+			},
+		},
+		{profile: ProfileArchive, tweak: func(c *Config) { c.RetainBlocks = 1024 }, conflict: "RetainBlocks"},
+		{profile: ProfileArchive, tweak: func(c *Config) { c.StateDiffs = true }, conflict: "StateDiffs"},
+		{profile: ProfileArchive, tweak: func(c *Config) { c.SyncMode = downloader.LightSync }, conflict: "SyncMode"},
+		{
+			profile: ProfileFull,
+			check: func(c *Config) bool {
+				return c.SyncMode == downloader.FastSync && !c.NoPruning
+			},
+		},
+		{profile: ProfileFull, tweak: func(c *Config) { c.NoPruning = true }, conflict: "NoPruning"},
+		{
+			profile: ProfileLightServer,
+			tweak:   func(c *Config) { c.DatabaseCache = 4096 },
+			check: func(c *Config) bool {
+				return c.LightServ == lightServerServ && c.DatabaseCache == 4096
+			},
+		},
+		{profile: ProfileLightServer, tweak: func(c *Config) { c.RetainBlocks = 1024 }, conflict: "RetainBlocks"},
+		{
+			profile: ProfileRPCGateway,
+			check: func(c *Config) bool {
+				return c.LightServ == 0 && c.InternalTxIndex && c.CallCache.Size == gatewayCallCache && c.TrieCache == gatewayTrieCache
+			},
+		},
+		{profile: ProfileRPCGateway, tweak: func(c *Config) { c.LightServ = 25 }, conflict: "LightServ"},
+	}
+	for i, tt := range tests {
+		config := DefaultConfig
+		config.Profile = tt.profile
+		if tt.tweak != nil {
+			tt.tweak(&config)
+		}
+		err := config.ApplyProfile()
+		if tt.conflict != "" {
+			if cerr, ok := err.(*ProfileConflictError); !ok || cerr.Setting != tt.conflict {
+				t.Errorf("test %d: error mismatch: have %v, want conflict on %s", i, err, tt.conflict)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to apply profile: %v", i, err)
+			continue
+		}
+		if !tt.check(&config) {
+			t.Errorf("test %d: settings mismatch: %+v", i, config)
+		}
+		// Reapplying the expanded profile must be harmless
+		if err := config.ApplyProfile(); err != nil {
+			t.Errorf("test %d: failed to reapply profile: %v", i, err)
+		}
+	}
+	config := DefaultConfig
+	config.Profile = "validator"
+	if err := config.ApplyProfile(); err == nil {
+		t.Errorf("unknown profile accepted")
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/rcrowley/go-metrics"
+)
+
+const (
+	// propagationCacheSize is the number of recent blocks whose first sighting
+	// and local seal times are retained for latency measurements.
+	propagationCacheSize = 1024
+
+	// propagationSampleSize is the reservoir size of each latency distribution.
+	propagationSampleSize = 1028
+)
+
+// propagationTracker measures how quickly blocks travel across the network:
+// how far behind the first announcement each peer announces or delivers a
+// block, and how long locally sealed blocks take to be acknowledged by a peer.
+type propagationTracker struct {
+	firstSeen *lru.Cache // First time a block hash was heard of from any peer
+	sealed    *lru.Cache // Local seal times of blocks not yet acknowledged
+
+	announce metrics.Histogram // Peer announcement lag behind the first sighting
+	receipt  metrics.Histogram // Full block arrival delay after the first sighting
+	ack      metrics.Histogram // Delay between local seal and first peer acknowledgement
+
+	peers map[string]*peerPropagation
+	lock  sync.Mutex
+}
+
+// peerPropagation is the latency distribution of a single peer.
+type peerPropagation struct {
+	announce metrics.Histogram
+	receipt  metrics.Histogram
+}
+
+func newPropagationHistogram() metrics.Histogram {
+	return metrics.NewHistogram(metrics.NewExpDecaySample(propagationSampleSize, 0.015))
+}
+
+func newPropagationTracker() *propagationTracker {
+	firstSeen, _ := lru.New(propagationCacheSize)
+	sealed, _ := lru.New(propagationCacheSize)
+
+	return &propagationTracker{
+		firstSeen: firstSeen,
+		sealed:    sealed,
+		announce:  newPropagationHistogram(),
+		receipt:   newPropagationHistogram(),
+		ack:       newPropagationHistogram(),
+		peers:     make(map[string]*peerPropagation),
+	}
+}
+
+// sighting returns the time the block was first heard of, recording now if it
+// is the first sighting.
+func (t *propagationTracker) sighting(hash common.Hash, now time.Time) time.Time {
+	if first, ok := t.firstSeen.Get(hash); ok {
+		return first.(time.Time)
+	}
+	t.firstSeen.Add(hash, now)
+	return now
+}
+
+// peer returns the latency distributions of the given peer, creating them if
+// needed. The lock must be held.
+func (t *propagationTracker) peer(id string) *peerPropagation {
+	stats := t.peers[id]
+	if stats == nil {
+		stats = &peerPropagation{announce: newPropagationHistogram(), receipt: newPropagationHistogram()}
+		t.peers[id] = stats
+	}
+	return stats
+}
+
+// announced records a block hash announcement from a peer.
+func (t *propagationTracker) announced(peer string, hash common.Hash, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.acknowledged(hash, now)
+
+	lag := now.Sub(t.sighting(hash, now))
+	t.announce.Update(int64(lag))
+	t.peer(peer).announce.Update(int64(lag))
+	propLatencyAnnounceTimer.Update(lag)
+}
+
+// received records a full block propagated by a peer. The delivery delay is
+// only measured if the block was announced before it arrived.
+func (t *propagationTracker) received(peer string, block *types.Block, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.acknowledged(block.Hash(), now)
+	t.acknowledged(block.ParentHash(), now)
+
+	first := t.sighting(block.Hash(), now)
+	if first.Equal(now) {
+		return
+	}
+	delay := now.Sub(first)
+	t.receipt.Update(int64(delay))
+	t.peer(peer).receipt.Update(int64(delay))
+	propLatencyReceiptTimer.Update(delay)
+}
+
+// requested records a peer asking for a block by hash.
+func (t *propagationTracker) requested(hash common.Hash, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.acknowledged(hash, now)
+}
+
+// sealedLocally records the broadcast of a locally sealed block.
+func (t *propagationTracker) sealedLocally(hash common.Hash, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.firstSeen.Add(hash, now)
+	t.sealed.Add(hash, now)
+}
+
+// acknowledged records the first evidence of a peer holding a locally sealed
+// block: it asked for it, relayed it or built on top of it. The lock must be
+// held.
+func (t *propagationTracker) acknowledged(hash common.Hash, now time.Time) {
+	sealed, ok := t.sealed.Get(hash)
+	if !ok {
+		return
+	}
+	t.sealed.Remove(hash)
+
+	delay := now.Sub(sealed.(time.Time))
+	t.ack.Update(int64(delay))
+	propLatencyAckTimer.Update(delay)
+}
+
+// dropPeer discards the latency distributions of a disconnected peer.
+func (t *propagationTracker) dropPeer(id string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.peers, id)
+}
+
+// PropagationLatency summarises a latency distribution in milliseconds.
+type PropagationLatency struct {
+	Count int64   `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+// PeerPropagationStats is the latency summary of a single peer.
+type PeerPropagationStats struct {
+	Announce PropagationLatency `json:"announce"`
+	Receipt  PropagationLatency `json:"receipt"`
+}
+
+// PropagationStats is the block propagation latency report of the node.
+type PropagationStats struct {
+	Announce PropagationLatency              `json:"announce"`
+	Receipt  PropagationLatency              `json:"receipt"`
+	Ack      PropagationLatency              `json:"ack"`
+	Peers    map[string]PeerPropagationStats `json:"peers"`
+}
+
+func newPropagationLatency(h metrics.Histogram) PropagationLatency {
+	snap := h.Snapshot()
+	ps := snap.Percentiles([]float64{0.5, 0.9, 0.99})
+
+	ms := func(ns float64) float64 { return ns / float64(time.Millisecond) }
+	return PropagationLatency{
+		Count: snap.Count(),
+		Mean:  ms(snap.Mean()),
+		P50:   ms(ps[0]),
+		P90:   ms(ps[1]),
+		P99:   ms(ps[2]),
+		Max:   ms(float64(snap.Max())),
+	}
+}
+
+// stats assembles the current latency report.
+func (t *propagationTracker) stats() *PropagationStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	stats := &PropagationStats{
+		Announce: newPropagationLatency(t.announce),
+		Receipt:  newPropagationLatency(t.receipt),
+		Ack:      newPropagationLatency(t.ack),
+		Peers:    make(map[string]PeerPropagationStats, len(t.peers)),
+	}
+	for id, peer := range t.peers {
+		stats.Peers[id] = PeerPropagationStats{
+			Announce: newPropagationLatency(peer.announce),
+			Receipt:  newPropagationLatency(peer.receipt),
+		}
+	}
+	return stats
+}
+
+// PropagationStats returns block propagation latency percentiles, both network
+// wide and per connected peer.
+func (api *PrivateAdminAPI) PropagationStats() *PropagationStats {
+	return api.fullGoola.protocolManager.propagation.stats()
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/core/types"
+)
+
+// Tests that propagation latencies are measured relative to the first sighting
+// of a block and that locally sealed blocks are acknowledged once.
+func TestPropagationTracker(t *testing.T) {
+	tracker := newPropagationTracker()
+	start := time.Unix(1000, 0)
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)})
+	tracker.announced("a", block.Hash(), start)
+	tracker.announced("b", block.Hash(), start.Add(100*time.Millisecond))
+	tracker.received("b", block, start.Add(300*time.Millisecond))
+
+	// A block pushed without prior announcement has no measurable delay
+	other := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2)})
+	tracker.received("a", other, start)
+
+	// Locally sealed blocks are acknowledged by the first peer building on them
+	sealed := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(3)})
+	tracker.sealedLocally(sealed.Hash(), start)
+	child := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(4), ParentHash: sealed.Hash()})
+	tracker.received("a", child, start.Add(500*time.Millisecond))
+	tracker.requested(sealed.Hash(), start.Add(time.Second))
+
+	stats := tracker.stats()
+	if stats.Announce.Count != 2 || stats.Announce.Max != 100 {
+		t.Errorf("announce stats mismatch: %+v", stats.Announce)
+	}
+	if stats.Receipt.Count != 1 || stats.Receipt.Max != 300 {
+		t.Errorf("receipt stats mismatch: %+v", stats.Receipt)
+	}
+	if stats.Ack.Count != 1 || stats.Ack.Max != 500 {
+		t.Errorf("ack stats mismatch: %+v", stats.Ack)
+	}
+	if peer := stats.Peers["b"]; peer.Announce.P50 != 100 || peer.Receipt.Count != 1 {
+		t.Errorf("peer stats mismatch: %+v", peer)
+	}
+	tracker.dropPeer("b")
+	if _, ok := tracker.stats().Peers["b"]; ok {
+		t.Errorf("dropped peer still reported")
+	}
+}
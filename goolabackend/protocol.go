@@ -32,16 +32,17 @@ import (
 const (
 	eth62 = 62
 	eth63 = 63
+	eth64 = 64
 )
 
 // Official short name of the protocol used during capability negotiation.
 var ProtocolName = "goolabackend"
 
 // Supported versions of the goola protocol (first is primary).
-var ProtocolVersions = []uint{eth63, eth62}
+var ProtocolVersions = []uint{eth64, eth63, eth62}
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{17, 8}
+var ProtocolLengths = []uint64{19, 17, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -62,6 +63,11 @@ const (
 	NodeDataMsg    = 0x0e
 	GetReceiptsMsg = 0x0f
 	ReceiptsMsg    = 0x10
+
+	// Protocol messages belonging to goolabackend/64, only exchanged with peers
+	// supporting the corresponding extension
+	GetBlobsMsg = 0x11
+	BlobsMsg    = 0x12
 )
 
 type errCode int
@@ -76,8 +82,20 @@ const (
 	ErrNoStatusMsg
 	ErrExtraStatusMsg
 	ErrSuspendedPeer
+	ErrRateLimited
 )
 
+// errorToViolation maps the error codes caused by malformed or protocol
+// violating messages to the violation types reported to the p2p layer.
+var errorToViolation = map[errCode]string{
+	ErrMsgTooLarge:    "msg-too-large",
+	ErrDecode:         "decode",
+	ErrInvalidMsgCode: "invalid-msg-code",
+	ErrNoStatusMsg:    "no-status",
+	ErrExtraStatusMsg: "extra-status",
+	ErrRateLimited:    "rate-limited",
+}
+
 func (e errCode) String() string {
 	return errorToString[int(e)]
 }
@@ -93,6 +111,7 @@ var errorToString = map[int]string{
 	ErrNoStatusMsg:             "No status message",
 	ErrExtraStatusMsg:          "Extra status message",
 	ErrSuspendedPeer:           "Suspended peer",
+	ErrRateLimited:             "Query rate limit exceeded",
 }
 
 type txPool interface {
@@ -117,6 +136,56 @@ type statusData struct {
 	GenesisBlock    common.Hash
 }
 
+// statusData64 is the network packet for the status message of goolabackend/64,
+// extended with the optional protocol extensions supported by the sender. Any
+// trailing fields appended by future versions are ignored, so extensions can be
+// introduced without bumping the protocol version.
+type statusData64 struct {
+	ProtocolVersion uint32
+	NetworkId       uint64
+	TD              *big.Int
+	CurrentBlock    common.Hash
+	GenesisBlock    common.Hash
+	Capabilities    Capabilities
+	Rest            []rlp.RawValue `rlp:"tail"`
+}
+
+// Capabilities is a bitfield of optional protocol extensions, exchanged in the
+// goolabackend/64 handshake. An extension is only used with a peer if both sides
+// advertise it.
+type Capabilities uint64
+
+const (
+	CapTxAnnounce  Capabilities = 1 << iota // Announces transactions by hash instead of broadcasting them
+	CapSnapshot                             // Serves state snapshots
+	CapCompression                          // Compresses large message payloads
+	CapBlobs                                // Exchanges the data blobs of blob transactions
+)
+
+var capabilityNames = []string{"txannounce", "snapshot", "compression", "blobs"}
+
+// Has reports whether all the given extensions are in the set.
+func (c Capabilities) Has(caps Capabilities) bool {
+	return c&caps == caps
+}
+
+// Names returns the names of the extensions in the set, along with the bit
+// numbers of the ones unknown locally.
+func (c Capabilities) Names() []string {
+	var names []string
+	for i := uint(0); i < 64; i++ {
+		if c&(1<<i) == 0 {
+			continue
+		}
+		if i < uint(len(capabilityNames)) {
+			names = append(names, capabilityNames[i])
+		} else {
+			names = append(names, fmt.Sprintf("bit%d", i))
+		}
+	}
+	return names
+}
+
 // newBlockHashesData is the network packet for the block announcements.
 type newBlockHashesData []struct {
 	Hash   common.Hash // Hash of one particular block being announced
@@ -27,6 +27,7 @@ import (
 	"github.com/goola-team/goola/crypto"
 	"github.com/goola-team/goola/goolabackend/downloader"
 	"github.com/goola-team/goola/p2p"
+	"github.com/goola-team/goola/p2p/discover"
 	"github.com/goola-team/goola/rlp"
 )
 
@@ -45,7 +46,7 @@ func testStatusMsgErrors(t *testing.T, protocol int) {
 	var (
 		genesis = pm.blockchain.Genesis()
 		head    = pm.blockchain.CurrentHeader()
-		td      = pm.blockchain.GetTd(head.Hash(), head.Number.Uint64())
+		td      = common.Big0 // difficulty isn't tracked, peers announce zero
 	)
 	defer pm.Stop()
 
@@ -92,6 +93,41 @@ func testStatusMsgErrors(t *testing.T, protocol int) {
 	}
 }
 
+// Tests that optional protocol extensions are negotiated in the goolabackend/64
+// handshake, tolerating fields appended by future protocol versions.
+func TestCapabilityNegotiation(t *testing.T) {
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+
+	var id discover.NodeID
+	p := newPeer(eth64, p2p.NewPeer(id, "peer", nil), net)
+
+	extra, _ := rlp.EncodeToBytes("future")
+	go func() {
+		p2p.Send(app, StatusMsg, &statusData64{
+			ProtocolVersion: eth64,
+			NetworkId:       DefaultConfig.NetworkId,
+			Capabilities:    CapTxAnnounce | CapSnapshot | 1<<40,
+			Rest:            []rlp.RawValue{extra},
+		})
+		if msg, err := app.ReadMsg(); err == nil {
+			msg.Discard()
+		}
+	}()
+	if err := p.Handshake(DefaultConfig.NetworkId, common.Hash{}, common.Hash{}, CapSnapshot|CapCompression); err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	if !p.Supports(CapSnapshot) {
+		t.Errorf("common extension not negotiated")
+	}
+	if p.Supports(CapTxAnnounce) || p.Supports(CapCompression) {
+		t.Errorf("one-sided extension negotiated")
+	}
+	if names := p.Info().Capabilities; len(names) != 1 || names[0] != "snapshot" {
+		t.Errorf("peer info capabilities mismatch: have %v, want [snapshot]", names)
+	}
+}
+
 // This test checks that received transactions are added to the local pool.
 func TestRecvTransactions62(t *testing.T) { testRecvTransactions(t, 62) }
 func TestRecvTransactions63(t *testing.T) { testRecvTransactions(t, 63) }
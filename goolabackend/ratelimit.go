@@ -0,0 +1,192 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/mclock"
+	"github.com/goola-team/goola/p2p"
+)
+
+// Costs of the items requested by the data retrieval queries, in query budget
+// units. Bodies and receipts are far heavier to look up and ship than headers.
+const (
+	headerQueryCost   = 1
+	bodyQueryCost     = 4
+	nodeDataQueryCost = 2
+	receiptQueryCost  = 4
+	blobQueryCost     = 16
+)
+
+const (
+	// queryBurstSeconds is the number of seconds worth of refill a query budget
+	// may accumulate, allowing short bursts above the sustained rate.
+	queryBurstSeconds = 4
+
+	// queryStrikeWindow is the period over which a peer's rate limited queries
+	// are counted towards disconnecting it.
+	queryStrikeWindow = time.Minute
+)
+
+// QueryLimitConfig are the rate limits on the data retrieval queries served to
+// remote peers, protecting the node from being overloaded by them.
+type QueryLimitConfig struct {
+	PeerRate   uint64 // Budget refilled per second for each peer (0 = unlimited)
+	GlobalRate uint64 // Budget refilled per second shared by all peers (0 = unlimited)
+	MaxStrikes int    // Rate limited queries per minute tolerated before dropping a peer
+}
+
+// DefaultQueryLimits are the query rate limits used by default, generous enough
+// for a single peer to sync the chain off the node at full speed.
+var DefaultQueryLimits = QueryLimitConfig{
+	PeerRate:   4096,
+	GlobalRate: 32768,
+	MaxStrikes: 32,
+}
+
+// tokenBucket is a budget refilling at a constant rate up to a maximum.
+type tokenBucket struct {
+	tokens, rate, capacity float64
+	updated                mclock.AbsTime
+}
+
+func newTokenBucket(rate uint64, now mclock.AbsTime) *tokenBucket {
+	capacity := float64(rate * queryBurstSeconds)
+	return &tokenBucket{tokens: capacity, rate: float64(rate), capacity: capacity, updated: now}
+}
+
+// take removes the given amount from the bucket, returning false (and taking
+// nothing) if not enough is available.
+func (b *tokenBucket) take(amount float64, now mclock.AbsTime) bool {
+	b.tokens += b.rate * float64(now-b.updated) / float64(time.Second)
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.updated = now
+
+	if b.tokens < amount {
+		return false
+	}
+	b.tokens -= amount
+	return true
+}
+
+// peerQueryLimit is the query budget and rate limiting record of a peer.
+type peerQueryLimit struct {
+	bucket  *tokenBucket
+	strikes int            // Rate limited queries within the current window
+	window  mclock.AbsTime // Start of the current strike window
+}
+
+// queryLimiter enforces the per-peer and global query rate limits.
+type queryLimiter struct {
+	config QueryLimitConfig
+	global *tokenBucket
+	peers  map[string]*peerQueryLimit
+	lock   sync.Mutex
+}
+
+// newQueryLimiter creates a query rate limiter.
+func newQueryLimiter(config QueryLimitConfig) *queryLimiter {
+	l := &queryLimiter{
+		config: config,
+		peers:  make(map[string]*peerQueryLimit),
+	}
+	if config.GlobalRate > 0 {
+		l.global = newTokenBucket(config.GlobalRate, mclock.Now())
+	}
+	return l
+}
+
+// allow charges a query's cost to the budgets of the peer and the node. It
+// returns whether the query may be served and whether the peer exceeded its
+// budget persistently enough to be dropped.
+func (l *queryLimiter) allow(id string, cost uint64, now mclock.AbsTime) (bool, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.config.PeerRate > 0 {
+		peer := l.peers[id]
+		if peer == nil {
+			peer = &peerQueryLimit{bucket: newTokenBucket(l.config.PeerRate, now), window: now}
+			l.peers[id] = peer
+		}
+		if !peer.bucket.take(float64(cost), now) {
+			if time.Duration(now-peer.window) > queryStrikeWindow {
+				peer.strikes, peer.window = 0, now
+			}
+			peer.strikes++
+			return false, l.config.MaxStrikes > 0 && peer.strikes > l.config.MaxStrikes
+		}
+	}
+	// Overloaded by all the peers together is not the fault of a single one
+	if l.global != nil && !l.global.take(float64(cost), now) {
+		return false, false
+	}
+	return true, false
+}
+
+// forget drops the rate limiting record of a disconnected peer.
+func (l *queryLimiter) forget(id string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	delete(l.peers, id)
+}
+
+// SetQueryLimits enables rate limiting the data retrieval queries of the remote
+// peers with the given limits.
+func (pm *ProtocolManager) SetQueryLimits(config QueryLimitConfig) {
+	if config.PeerRate == 0 && config.GlobalRate == 0 {
+		pm.queryLimiter = nil
+		return
+	}
+	pm.queryLimiter = newQueryLimiter(config)
+}
+
+// limitQuery charges the cost of a query to the rate limits. It returns whether
+// the query may be served, or an error if the peer exceeds its limit persistently
+// and should be dropped.
+func (pm *ProtocolManager) limitQuery(p *peer, cost uint64) (bool, error) {
+	if pm.queryLimiter == nil {
+		return true, nil
+	}
+	allowed, drop := pm.queryLimiter.allow(p.id, cost, mclock.Now())
+	if drop {
+		return false, errResp(ErrRateLimited, "more than %d rate limited queries per %v", pm.queryLimiter.config.MaxStrikes, queryStrikeWindow)
+	}
+	if !allowed {
+		p.Log().Trace("Rate limited query", "cost", cost)
+	}
+	return allowed, nil
+}
+
+// hashQueryItems estimates the number of hashes requested by a hash list query
+// from its size, without decoding it, capped at the serving limit.
+func hashQueryItems(msg p2p.Msg, limit int) uint64 {
+	items := uint64(msg.Size) / (common.HashLength + 1)
+	if items == 0 {
+		items = 1
+	}
+	if items > uint64(limit) {
+		items = uint64(limit)
+	}
+	return items
+}
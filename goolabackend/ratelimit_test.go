@@ -0,0 +1,126 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/common/mclock"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/goolabackend/downloader"
+	"github.com/goola-team/goola/p2p"
+)
+
+// Tests that the per-peer budgets are exhausted and refilled independently, and
+// that only persistently exceeding them gets a peer dropped.
+func TestQueryLimiterPeerRate(t *testing.T) {
+	limiter := newQueryLimiter(QueryLimitConfig{PeerRate: 10, MaxStrikes: 2})
+	now := mclock.AbsTime(0)
+
+	// A full bucket allows a burst, after which queries are limited
+	if allowed, _ := limiter.allow("a", 10*queryBurstSeconds, now); !allowed {
+		t.Fatalf("burst within budget refused")
+	}
+	for i := 1; i <= 2; i++ {
+		if allowed, drop := limiter.allow("a", 1, now); allowed || drop {
+			t.Fatalf("strike %d: allowed %v, drop %v, want limited only", i, allowed, drop)
+		}
+	}
+	// Other peers have their own budget
+	if allowed, _ := limiter.allow("b", 1, now); !allowed {
+		t.Fatalf("query of other peer refused")
+	}
+	// The budget refills over time
+	now += mclock.AbsTime(100 * time.Millisecond)
+	if allowed, _ := limiter.allow("a", 1, now); !allowed {
+		t.Fatalf("query refused after refill")
+	}
+	// One more strike within the window gets the peer dropped
+	if allowed, drop := limiter.allow("a", 1, now); allowed || !drop {
+		t.Fatalf("persistent offender: allowed %v, drop %v, want dropped", allowed, drop)
+	}
+	// Strikes from a past window are forgiven, as are the ones of forgotten peers
+	now += mclock.AbsTime(queryStrikeWindow + time.Second)
+	limiter.allow("a", 10*queryBurstSeconds, now)
+	if allowed, drop := limiter.allow("a", 1, now); allowed || drop {
+		t.Fatalf("strike in new window: allowed %v, drop %v, want limited only", allowed, drop)
+	}
+	limiter.forget("a")
+	if allowed, _ := limiter.allow("a", 10*queryBurstSeconds, now); !allowed {
+		t.Fatalf("forgotten peer not given a new budget")
+	}
+}
+
+// Tests that the global budget is shared by all peers, without blaming any of
+// them for exhausting it.
+func TestQueryLimiterGlobalRate(t *testing.T) {
+	limiter := newQueryLimiter(QueryLimitConfig{PeerRate: 100, GlobalRate: 10, MaxStrikes: 1})
+	now := mclock.Now()
+
+	if allowed, _ := limiter.allow("a", 6*queryBurstSeconds, now); !allowed {
+		t.Fatalf("query within global budget refused")
+	}
+	for i := 0; i < 3; i++ {
+		if allowed, drop := limiter.allow("b", 5*queryBurstSeconds, now); allowed || drop {
+			t.Fatalf("query %d above global budget: allowed %v, drop %v, want limited only", i, allowed, drop)
+		}
+	}
+	if allowed, _ := limiter.allow("b", 4*queryBurstSeconds, now); !allowed {
+		t.Fatalf("query within remaining global budget refused")
+	}
+}
+
+// Tests that rate limited queries are answered empty and that peers flooding
+// the node with them are disconnected.
+func TestQueryRateLimit62(t *testing.T) { testQueryRateLimit(t, 62) }
+func TestQueryRateLimit63(t *testing.T) { testQueryRateLimit(t, 63) }
+
+func testQueryRateLimit(t *testing.T, protocol int) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 8, nil, nil)
+	pm.SetQueryLimits(QueryLimitConfig{PeerRate: 1, MaxStrikes: 2})
+	peer, errc := newTestPeer("peer", protocol, pm, true)
+	defer peer.close()
+	defer pm.Stop()
+
+	query := &getBlockHeadersData{Origin: hashOrNumber{Number: 1}, Amount: 1}
+	header := []*types.Header{pm.blockchain.GetBlockByNumber(1).Header()}
+
+	// The burst allowance is served, anything above answered empty
+	for i := 0; i < queryBurstSeconds; i++ {
+		p2p.Send(peer.app, GetBlockHeadersMsg, query)
+		if err := p2p.ExpectMsg(peer.app, BlockHeadersMsg, header); err != nil {
+			t.Fatalf("query %d: headers mismatch: %v", i, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		p2p.Send(peer.app, GetBlockHeadersMsg, query)
+		if err := p2p.ExpectMsg(peer.app, BlockHeadersMsg, []*types.Header{}); err != nil {
+			t.Fatalf("limited query %d: headers mismatch: %v", i, err)
+		}
+	}
+	// Exceeding the tolerated strikes drops the peer
+	go p2p.Send(peer.app, GetBlockHeadersMsg, query)
+	select {
+	case err := <-errc:
+		if want := errResp(ErrRateLimited, "more than 2 rate limited queries per %v", queryStrikeWindow); err == nil || err.Error() != want.Error() {
+			t.Errorf("disconnect error mismatch: have %v, want %v", err, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("flooding peer not disconnected")
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/goola-team/goola/log"
+)
+
+// errReloadUnsupported is returned if the node was not started with a source
+// to reload its configuration from.
+var errReloadUnsupported = errors.New("configuration reloading not supported")
+
+// reloadable is the set of configuration fields that can be changed without
+// restarting the node. Logging verbosity is not part of the service config, it
+// can be adjusted at runtime through debug_verbosity.
+var reloadable = map[string]bool{
+	"GasPrice":            true,
+	"TxPool.PriceBump":    true,
+	"TxPool.AccountSlots": true,
+	"TxPool.GlobalSlots":  true,
+	"TxPool.AccountQueue": true,
+	"TxPool.GlobalQueue":  true,
+	"TxPool.Lifetime":     true,
+	"GPO.Blocks":          true,
+	"GPO.Percentile":      true,
+}
+
+// ReloadReport is the outcome of a configuration reload, listing the changed
+// fields that were applied and those that only take effect after a restart.
+type ReloadReport struct {
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// SetConfigLoader sets the source the configuration is reloaded from.
+func (s *FullGoola) SetConfigLoader(loader func() (*Config, error)) {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+
+	s.configLoader = loader
+}
+
+// ReloadConfig reloads the configuration and applies the changes to the runtime
+// safe fields. The new configuration is validated first, nothing is applied if
+// it's invalid.
+func (s *FullGoola) ReloadConfig() (*ReloadReport, error) {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+
+	if s.configLoader == nil {
+		return nil, errReloadUnsupported
+	}
+	config, err := s.configLoader()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateReload(config); err != nil {
+		return nil, err
+	}
+	report := &ReloadReport{Applied: []string{}, RestartRequired: []string{}}
+	for _, field := range diffConfig(s.config, config) {
+		if reloadable[field] {
+			report.Applied = append(report.Applied, field)
+		} else {
+			report.RestartRequired = append(report.RestartRequired, field)
+		}
+	}
+	if changed(report.Applied, "GasPrice") {
+		s.lock.Lock()
+		s.gasPrice = config.GasPrice
+		s.config.GasPrice = config.GasPrice
+		s.lock.Unlock()
+
+		s.txPool.SetGasPrice(config.GasPrice)
+	}
+	if changed(report.Applied, "TxPool.") {
+		pool := &s.config.TxPool
+		pool.PriceBump = config.TxPool.PriceBump
+		pool.AccountSlots, pool.GlobalSlots = config.TxPool.AccountSlots, config.TxPool.GlobalSlots
+		pool.AccountQueue, pool.GlobalQueue = config.TxPool.AccountQueue, config.TxPool.GlobalQueue
+		pool.Lifetime = config.TxPool.Lifetime
+
+		s.txPool.SetLimits(*pool)
+	}
+	if changed(report.Applied, "GPO.") {
+		s.config.GPO.Blocks, s.config.GPO.Percentile = config.GPO.Blocks, config.GPO.Percentile
+		s.ApiBackend.gpo.SetParams(s.config.GPO)
+	}
+	log.Info("Reloaded configuration", "applied", len(report.Applied), "restart", len(report.RestartRequired))
+	if len(report.RestartRequired) > 0 {
+		log.Warn("Configuration changes require a restart", "fields", strings.Join(report.RestartRequired, ", "))
+	}
+	return report, nil
+}
+
+// changed reports whether any of the fields starts with the given prefix.
+func changed(fields []string, prefix string) bool {
+	for _, field := range fields {
+		if strings.HasPrefix(field, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateReload checks that the runtime safe fields of a reloaded configuration
+// are sound.
+func validateReload(config *Config) error {
+	if config.GasPrice == nil || config.GasPrice.Sign() < 0 {
+		return fmt.Errorf("invalid GasPrice %v: must be non-negative", config.GasPrice)
+	}
+	pool := config.TxPool
+	if pool.PriceBump < 1 {
+		return fmt.Errorf("invalid TxPool.PriceBump %d: must be positive", pool.PriceBump)
+	}
+	if pool.AccountSlots < 1 || pool.GlobalSlots < 1 || pool.AccountQueue < 1 || pool.GlobalQueue < 1 {
+		return errors.New("invalid TxPool slots: allowances must be positive")
+	}
+	if pool.Lifetime <= 0 {
+		return fmt.Errorf("invalid TxPool.Lifetime %v: must be positive", pool.Lifetime)
+	}
+	if config.GPO.Blocks < 1 {
+		return fmt.Errorf("invalid GPO.Blocks %d: must be positive", config.GPO.Blocks)
+	}
+	if config.GPO.Percentile < 0 || config.GPO.Percentile > 100 {
+		return fmt.Errorf("invalid GPO.Percentile %d: must be within [0, 100]", config.GPO.Percentile)
+	}
+	return nil
+}
+
+// diffConfig returns the sorted names of the fields differing between two
+// configurations, descending into the option groups (e.g. "TxPool.Lifetime").
+// The genesis is ignored as it's only used to initialise an empty database.
+func diffConfig(old, new *Config) []string {
+	var fields []string
+
+	a, b := reflect.ValueOf(old).Elem(), reflect.ValueOf(new).Elem()
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		if field.Name == "Genesis" {
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			for j := 0; j < field.Type.NumField(); j++ {
+				if !equalField(a.Field(i).Field(j), b.Field(i).Field(j)) {
+					fields = append(fields, field.Name+"."+field.Type.Field(j).Name)
+				}
+			}
+			continue
+		}
+		if !equalField(a.Field(i), b.Field(i)) {
+			fields = append(fields, field.Name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// equalField reports whether two configuration values are equal, comparing big
+// integers numerically.
+func equalField(a, b reflect.Value) bool {
+	if x, ok := a.Interface().(*big.Int); ok {
+		y := b.Interface().(*big.Int)
+		if x == nil || y == nil {
+			return x == y
+		}
+		return x.Cmp(y) == 0
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
@@ -0,0 +1,147 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/consensus/dpos"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/goolabackend/gasprice"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
+)
+
+// newReloadTestNode creates a full node stripped down to the parts touched by
+// configuration reloads.
+func newReloadTestNode(t *testing.T) *FullGoola {
+	var (
+		db, _  = gooladb.NewMemDatabase()
+		gspec  = &core.Genesis{Config: params.TestChainConfig}
+		config = DefaultConfig
+		pool   = config.TxPool
+	)
+	gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, dpos.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	pool.Journal = ""
+
+	goola := &FullGoola{
+		config:      &config,
+		chainConfig: gspec.Config,
+		chainDb:     db,
+		blockchain:  chain,
+		gasPrice:    config.GasPrice,
+	}
+	goola.txPool = core.NewTxPool(pool, gspec.Config, chain)
+	goola.ApiBackend = &GoolaApiBackend{goola, nil}
+	goola.ApiBackend.gpo = gasprice.NewOracle(goola.ApiBackend, config.GPO)
+	return goola
+}
+
+// Tests that reloading the configuration applies the runtime safe changes and
+// reports those requiring a restart.
+func TestReloadConfig(t *testing.T) {
+	goola := newReloadTestNode(t)
+	defer goola.blockchain.Stop()
+	defer goola.txPool.Stop()
+
+	if _, err := NewPrivateAdminAPI(goola).ReloadConfig(); err != errReloadUnsupported {
+		t.Fatalf("reload error mismatch: have %v, want %v", err, errReloadUnsupported)
+	}
+	reloaded := *goola.config
+	reloaded.GasPrice = big.NewInt(42)
+	reloaded.TxPool.GlobalSlots = 1
+	reloaded.TxPool.Lifetime = time.Minute
+	reloaded.GPO.Percentile = 90
+	reloaded.NetworkId = 1234
+	reloaded.DatabaseCache = 1024
+
+	goola.SetConfigLoader(func() (*Config, error) {
+		config := reloaded
+		return &config, nil
+	})
+	report, err := NewPrivateAdminAPI(goola).ReloadConfig()
+	if err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	want := &ReloadReport{
+		Applied:         []string{"GPO.Percentile", "GasPrice", "TxPool.GlobalSlots", "TxPool.Lifetime"},
+		RestartRequired: []string{"DatabaseCache", "NetworkId"},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("reload report mismatch: have %+v, want %+v", report, want)
+	}
+	if price := goola.txPool.GasPrice(); price.Cmp(reloaded.GasPrice) != 0 {
+		t.Errorf("pool gas price mismatch: have %v, want %v", price, reloaded.GasPrice)
+	}
+	if goola.gasPrice.Cmp(reloaded.GasPrice) != 0 {
+		t.Errorf("miner gas price mismatch: have %v, want %v", goola.gasPrice, reloaded.GasPrice)
+	}
+	config := goola.config
+	if config.TxPool.GlobalSlots != 1 || config.TxPool.Lifetime != time.Minute || config.GPO.Percentile != 90 {
+		t.Errorf("applied fields mismatch: have %+v, %+v", config.TxPool, config.GPO)
+	}
+	if config.NetworkId != DefaultConfig.NetworkId || config.DatabaseCache != DefaultConfig.DatabaseCache {
+		t.Errorf("restart fields applied: network %d, cache %d", config.NetworkId, config.DatabaseCache)
+	}
+	// Reloading the same configuration again changes nothing
+	if report, err = goola.ReloadConfig(); err != nil {
+		t.Fatalf("failed to reload config: %v", err)
+	}
+	if len(report.Applied) != 0 || !reflect.DeepEqual(report.RestartRequired, want.RestartRequired) {
+		t.Errorf("repeated reload report mismatch: have %+v", report)
+	}
+}
+
+// Tests that invalid reloaded configurations are refused as a whole.
+func TestReloadConfigValidation(t *testing.T) {
+	tests := []func(*Config){
+		func(c *Config) { c.GasPrice = nil },
+		func(c *Config) { c.GasPrice = big.NewInt(-1) },
+		func(c *Config) { c.TxPool.PriceBump = 0 },
+		func(c *Config) { c.TxPool.AccountSlots = 0 },
+		func(c *Config) { c.TxPool.GlobalQueue = 0 },
+		func(c *Config) { c.TxPool.Lifetime = 0 },
+		func(c *Config) { c.GPO.Blocks = 0 },
+		func(c *Config) { c.GPO.Percentile = 101 },
+	}
+	for i, tamper := range tests {
+		goola := newReloadTestNode(t)
+
+		reloaded := *goola.config
+		reloaded.TxPool.GlobalSlots = 1
+		reloaded.GPO.Percentile = 90
+		tamper(&reloaded)
+
+		goola.SetConfigLoader(func() (*Config, error) { return &reloaded, nil })
+		if _, err := goola.ReloadConfig(); err == nil {
+			t.Errorf("test %d: invalid config accepted", i)
+		}
+		if goola.config.TxPool.GlobalSlots != DefaultConfig.TxPool.GlobalSlots || goola.config.GPO.Percentile != DefaultConfig.GPO.Percentile {
+			t.Errorf("test %d: invalid config partially applied", i)
+		}
+		goola.txPool.Stop()
+		goola.blockchain.Stop()
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/goola-team/goola/accounts"
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
+)
+
+// minFreeDisk is the free space below which the data directory is reported as
+// running out of disk.
+const minFreeDisk = 1024 * 1024 * 1024
+
+// errDiskFreeUnsupported is returned if free disk space can't be retrieved on
+// the running platform.
+var errDiskFreeUnsupported = errors.New("free disk space retrieval not supported")
+
+// SelfTestEnv is the node level environment validated by the self-test, along
+// with the service configuration.
+type SelfTestEnv struct {
+	DataDir   string   // Directory holding the chain data, empty for ephemeral nodes
+	Listeners []string // TCP addresses the node is about to listen on
+	Mining    bool     // Whether the node is about to seal blocks
+}
+
+// SelfTestReport is the outcome of a node self-test.
+type SelfTestReport struct {
+	Passed bool         `json:"passed"`
+	Checks []*SelfCheck `json:"checks"`
+}
+
+// SelfCheck is the outcome of a single self-test check.
+type SelfCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail"` // What was found, or why the check failed
+}
+
+// SelfTest validates the database, the genesis and chain configuration, the
+// availability of the sealing key, the listening ports and the free disk space,
+// reporting every problem found instead of failing on the first one.
+func SelfTest(db gooladb.Database, config *Config, am *accounts.Manager, env SelfTestEnv) *SelfTestReport {
+	report := &SelfTestReport{Passed: true}
+	check := func(name string, detail string, err error) {
+		result := &SelfCheck{Name: name, Passed: err == nil, Detail: detail}
+		if err != nil {
+			result.Detail = err.Error()
+			report.Passed = false
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	detail, err := selfTestDatabase(db, config.SkipBcVersionCheck)
+	check("database", detail, err)
+
+	chainConfig, hash, err := core.CheckGenesisBlock(db, config.Genesis)
+	check("genesis", fmt.Sprintf("genesis %x, chain config %v", hash, chainConfig), err)
+	if err != nil {
+		chainConfig = nil
+	}
+	detail, err = selfTestEngine(chainConfig, config.Etherbase, am, env.Mining)
+	check("engine", detail, err)
+
+	detail, err = selfTestPorts(env.Listeners)
+	check("ports", detail, err)
+
+	detail, err = selfTestDisk(env.DataDir)
+	check("disk", detail, err)
+
+	return report
+}
+
+// selfTestDatabase checks that the head block can be read, and that the
+// database layout is the supported one.
+func selfTestDatabase(db gooladb.Database, skipVersion bool) (string, error) {
+	hash := core.GetHeadBlockHash(db)
+	if hash == (common.Hash{}) {
+		return "empty database", nil
+	}
+	number := core.GetBlockNumber(db, hash)
+	if core.GetHeader(db, hash, number) == nil {
+		return "", fmt.Errorf("head block %x unreadable", hash)
+	}
+	version := core.GetBlockChainVersion(db)
+	if !skipVersion && version != core.BlockChainVersion && version != 0 {
+		return "", fmt.Errorf("database version %d, want %d: run goola upgradedb", version, core.BlockChainVersion)
+	}
+	return fmt.Sprintf("head #%d [%x], version %d", number, hash[:4], version), nil
+}
+
+// selfTestEngine checks that a key is available to seal blocks with, if the
+// node is to produce blocks of a scheduled chain.
+func selfTestEngine(config *params.ChainConfig, etherbase common.Address, am *accounts.Manager, mining bool) (string, error) {
+	if config == nil {
+		return "", errors.New("chain configuration unavailable")
+	}
+	if config.Ethash == nil || !config.Ethash.Scheduled() {
+		return "unscheduled chain, no sealing key needed", nil
+	}
+	if !mining {
+		return "not sealing", nil
+	}
+	if etherbase == (common.Address{}) {
+		if wallets := am.Wallets(); len(wallets) > 0 {
+			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
+				etherbase = accounts[0].Address
+			}
+		}
+	}
+	if etherbase == (common.Address{}) {
+		return "", errGoolaseMissing
+	}
+	if _, err := am.Find(accounts.Account{Address: etherbase}); err != nil {
+		return "", fmt.Errorf("no key for etherbase %x: %v", etherbase, err)
+	}
+	for _, validator := range config.Ethash.Validators {
+		if validator == etherbase {
+			return fmt.Sprintf("sealing as genesis validator %x", etherbase), nil
+		}
+	}
+	return fmt.Sprintf("sealing as %x, not a genesis validator", etherbase), nil
+}
+
+// selfTestPorts checks that the addresses the node is to listen on are free.
+func selfTestPorts(addrs []string) (string, error) {
+	if len(addrs) == 0 {
+		return "no listeners to check", nil
+	}
+	for _, addr := range addrs {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return "", fmt.Errorf("cannot listen on %s: %v", addr, err)
+		}
+		listener.Close()
+	}
+	return "free: " + strings.Join(addrs, ", "), nil
+}
+
+// selfTestDisk checks that the data directory isn't running out of space.
+func selfTestDisk(dir string) (string, error) {
+	if dir == "" {
+		return "ephemeral node", nil
+	}
+	free, err := diskFree(dir)
+	if err == errDiskFreeUnsupported {
+		return err.Error(), nil
+	} else if err != nil {
+		return "", err
+	}
+	if free < minFreeDisk {
+		return "", fmt.Errorf("only %v free in %s", common.StorageSize(free), dir)
+	}
+	return fmt.Sprintf("%v free in %s", common.StorageSize(free), dir), nil
+}
+
+// SelfTest runs the self-test against the running node. The listening ports
+// being bound by the node itself, they are not checked.
+func (s *FullGoola) SelfTest() *SelfTestReport {
+	config := *s.config
+
+	s.lock.RLock()
+	config.Etherbase = s.etherbase
+	s.lock.RUnlock()
+
+	return SelfTest(s.chainDb, &config, s.accountManager, SelfTestEnv{DataDir: s.dataDir, Mining: s.IsMining()})
+}
+
+// SelfTest validates the node's setup, reporting the problems found.
+func (api *PrivateAdminAPI) SelfTest() *SelfTestReport {
+	return api.fullGoola.SelfTest()
+}
@@ -0,0 +1,79 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"net"
+	"testing"
+
+	"github.com/goola-team/goola/accounts"
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
+)
+
+// Tests that the self-test reports every failing check of a broken setup, and
+// passes a sound one.
+func TestSelfTest(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	ethash := &params.EthashConfig{Period: 1, Validators: []common.Address{{0x01}}}
+	genesis := &core.Genesis{Config: &params.ChainConfig{ChainId: common.Big1, Ethash: ethash}, GasLimit: params.GenesisGasLimit}
+	genesis.MustCommit(db)
+
+	am := accounts.NewManager()
+	sound := SelfTest(db, &Config{Genesis: genesis}, am, SelfTestEnv{Listeners: []string{"127.0.0.1:0"}})
+	if !sound.Passed {
+		t.Fatalf("sound setup failed: %+v", sound.Checks)
+	}
+	// Break the genesis, bind the port and seal without a key
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to bind port: %v", err)
+	}
+	defer listener.Close()
+
+	other := *genesis
+	other.ExtraData = []byte("other")
+	env := SelfTestEnv{Listeners: []string{listener.Addr().String()}, Mining: true}
+
+	broken := SelfTest(db, &Config{Genesis: &other}, am, env)
+	if broken.Passed {
+		t.Fatalf("broken setup passed")
+	}
+	failed := make(map[string]bool)
+	for _, check := range broken.Checks {
+		if !check.Passed {
+			failed[check.Name] = true
+		}
+	}
+	for _, name := range []string{"genesis", "engine", "ports"} {
+		if !failed[name] {
+			t.Errorf("check %s passed", name)
+		}
+	}
+	if len(failed) != 3 {
+		t.Errorf("failed checks mismatch: have %v, want genesis, engine and ports", failed)
+	}
+	// A sound genesis lets the sealing key be checked on its own
+	report := SelfTest(db, &Config{Genesis: genesis}, am, SelfTestEnv{Mining: true})
+	for _, check := range report.Checks {
+		if check.Passed != (check.Name != "engine") {
+			t.Errorf("check %s: passed %v: %s", check.Name, check.Passed, check.Detail)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/p2p"
+	"github.com/goola-team/goola/rlp"
+	"github.com/goola-team/goola/trie"
+)
+
+const (
+	// softResponseLimit is the target maximum size of replies to data retrievals.
+	softResponseLimit = 2 * 1024 * 1024
+
+	// maxCodeLookups is the maximum number of bytecodes to serve. This number is
+	// there to limit the number of disk lookups.
+	maxCodeLookups = 1024
+)
+
+// Handle is the callback invoked to manage the life cycle of a snap peer. It
+// serves the state of the given database to the peer and delivers its replies
+// to the syncer. When this function terminates, the peer is disconnected.
+func Handle(db state.Database, syncer *Syncer, peer *Peer) error {
+	syncer.Register(peer)
+	defer syncer.Unregister(peer.ID())
+
+	for {
+		if err := handleMessage(db, syncer, peer); err != nil {
+			peer.Log().Debug("Message handling failed in snap", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMessage is invoked whenever an inbound message is received from a
+// remote peer. The remote connection is torn down upon returning any error.
+func handleMessage(db state.Database, syncer *Syncer, peer *Peer) error {
+	// Read the next message from the remote peer, and ensure it's fully consumed
+	msg, err := peer.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errMsgTooLarge
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case GetAccountRangeMsg:
+		var req GetAccountRangePacket
+		if err := msg.Decode(&req); err != nil {
+			return errDecode
+		}
+		res := serviceAccountRange(db, &req)
+		return p2p.Send(peer.rw, AccountRangeMsg, res)
+
+	case AccountRangeMsg:
+		res := new(AccountRangePacket)
+		if err := msg.Decode(res); err != nil {
+			return errDecode
+		}
+		syncer.deliver(peer.ID(), res.ID, res)
+		return nil
+
+	case GetStorageRangesMsg:
+		var req GetStorageRangesPacket
+		if err := msg.Decode(&req); err != nil {
+			return errDecode
+		}
+		res := serviceStorageRanges(db, &req)
+		return p2p.Send(peer.rw, StorageRangesMsg, res)
+
+	case StorageRangesMsg:
+		res := new(StorageRangesPacket)
+		if err := msg.Decode(res); err != nil {
+			return errDecode
+		}
+		syncer.deliver(peer.ID(), res.ID, res)
+		return nil
+
+	case GetByteCodesMsg:
+		var req GetByteCodesPacket
+		if err := msg.Decode(&req); err != nil {
+			return errDecode
+		}
+		res := serviceByteCodes(db, &req)
+		return p2p.Send(peer.rw, ByteCodesMsg, res)
+
+	case ByteCodesMsg:
+		res := new(ByteCodesPacket)
+		if err := msg.Decode(res); err != nil {
+			return errDecode
+		}
+		syncer.deliver(peer.ID(), res.ID, res)
+		return nil
+
+	default:
+		return errInvalidMsgCode
+	}
+}
+
+// responseBytes caps the requested response size at the soft response limit.
+func responseBytes(requested uint64) uint64 {
+	if requested > softResponseLimit {
+		return softResponseLimit
+	}
+	return requested
+}
+
+// proofList flattens the nodes collected in a proof database into a list.
+func proofList(proof *gooladb.MemDatabase) [][]byte {
+	var nodes [][]byte
+	for _, key := range proof.Keys() {
+		node, _ := proof.Get(key)
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// serviceAccountRange assembles the response to an account range query, which
+// is empty if the requested state is not available.
+func serviceAccountRange(db state.Database, req *GetAccountRangePacket) *AccountRangePacket {
+	res := &AccountRangePacket{ID: req.ID}
+
+	tr, err := trie.New(req.Root, db.TrieDB())
+	if err != nil {
+		return res
+	}
+	var (
+		size  uint64
+		limit = responseBytes(req.Bytes)
+	)
+	it := trie.NewIterator(tr.NodeIterator(req.Origin[:]))
+	for size < limit && it.Next() {
+		if bytes.Compare(it.Key, req.Limit[:]) > 0 {
+			break
+		}
+		res.Accounts = append(res.Accounts, &AccountData{
+			Hash: common.BytesToHash(it.Key),
+			Body: common.CopyBytes(it.Value),
+		})
+		size += uint64(common.HashLength + len(it.Value))
+	}
+	if it.Err != nil {
+		return &AccountRangePacket{ID: req.ID}
+	}
+	// Prove the origin and the last account, the range being verified in bulk
+	// against the state root once retrieved in full
+	proof, _ := gooladb.NewMemDatabase()
+	if err := tr.Prove(req.Origin[:], 0, proof); err != nil {
+		return &AccountRangePacket{ID: req.ID}
+	}
+	if len(res.Accounts) > 0 {
+		if err := tr.Prove(res.Accounts[len(res.Accounts)-1].Hash[:], 0, proof); err != nil {
+			return &AccountRangePacket{ID: req.ID}
+		}
+	}
+	res.Proof = proofList(proof)
+	return res
+}
+
+// serviceStorageRanges assembles the response to a storage ranges query. The
+// reply is cut short at the first account whose storage is not available.
+func serviceStorageRanges(db state.Database, req *GetStorageRangesPacket) *StorageRangesPacket {
+	res := &StorageRangesPacket{ID: req.ID}
+
+	accTrie, err := trie.New(req.Root, db.TrieDB())
+	if err != nil {
+		return res
+	}
+	var (
+		size  uint64
+		limit = responseBytes(req.Bytes)
+	)
+	for i, hash := range req.Accounts {
+		if size >= limit {
+			break
+		}
+		blob, err := accTrie.TryGet(hash[:])
+		if err != nil || blob == nil {
+			break
+		}
+		var account state.Account
+		if err := rlp.DecodeBytes(blob, &account); err != nil {
+			break
+		}
+		stTrie, err := trie.New(account.Root, db.TrieDB())
+		if err != nil {
+			break
+		}
+		// The origin only applies to the first account and the limit to the last
+		var origin, last common.Hash
+		if i == 0 {
+			origin = req.Origin
+		}
+		if i == len(req.Accounts)-1 && req.Limit != (common.Hash{}) {
+			last = req.Limit
+		} else {
+			last = maxHash
+		}
+		var (
+			slots []*StorageData
+			cut   bool
+		)
+		it := trie.NewIterator(stTrie.NodeIterator(origin[:]))
+		for it.Next() {
+			if bytes.Compare(it.Key, last[:]) > 0 {
+				break
+			}
+			if size >= limit {
+				cut = true
+				break
+			}
+			slots = append(slots, &StorageData{
+				Hash: common.BytesToHash(it.Key),
+				Body: common.CopyBytes(it.Value),
+			})
+			size += uint64(common.HashLength + len(it.Value))
+		}
+		if it.Err != nil {
+			break
+		}
+		res.Slots = append(res.Slots, slots)
+
+		// Prove partial ranges, either continuing an earlier one or cut short
+		if cut || origin != (common.Hash{}) {
+			proof, _ := gooladb.NewMemDatabase()
+			if err := stTrie.Prove(origin[:], 0, proof); err != nil {
+				return &StorageRangesPacket{ID: req.ID}
+			}
+			if len(slots) > 0 {
+				if err := stTrie.Prove(slots[len(slots)-1].Hash[:], 0, proof); err != nil {
+					return &StorageRangesPacket{ID: req.ID}
+				}
+			}
+			res.Proof = proofList(proof)
+			break
+		}
+	}
+	return res
+}
+
+// serviceByteCodes assembles the response to a bytecode query, leaving out the
+// codes not available.
+func serviceByteCodes(db state.Database, req *GetByteCodesPacket) *ByteCodesPacket {
+	res := &ByteCodesPacket{ID: req.ID}
+
+	var (
+		size  uint64
+		limit = responseBytes(req.Bytes)
+	)
+	for i, hash := range req.Hashes {
+		if i >= maxCodeLookups || size >= limit {
+			break
+		}
+		if hash == emptyCode {
+			// Peers should not request the empty code, but if they do, at
+			// least sent them back a correct response without db lookups
+			res.Codes = append(res.Codes, []byte{})
+			continue
+		}
+		code, err := db.TrieDB().Node(hash)
+		if err != nil || crypto.Keccak256Hash(code) != hash {
+			continue
+		}
+		res.Codes = append(res.Codes, code)
+		size += uint64(len(code))
+	}
+	return res
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snap implements the snapshot sync protocol, retrieving the state of a
+// block as contiguous ranges of accounts and storage slots instead of individual
+// trie nodes.
+package snap
+
+import (
+	"errors"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/rlp"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	ProtocolName       = "snap"
+	ProtocolVersion    = 1
+	ProtocolLength     = 6
+	ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
+)
+
+// snap protocol message codes
+const (
+	GetAccountRangeMsg  = 0x00
+	AccountRangeMsg     = 0x01
+	GetStorageRangesMsg = 0x02
+	StorageRangesMsg    = 0x03
+	GetByteCodesMsg     = 0x04
+	ByteCodesMsg        = 0x05
+)
+
+var (
+	errMsgTooLarge    = errors.New("message too long")
+	errDecode         = errors.New("invalid message")
+	errInvalidMsgCode = errors.New("invalid message code")
+)
+
+// GetAccountRangePacket requests the accounts of a state trie starting at the
+// origin hash, up to the limit hash or the given response size.
+type GetAccountRangePacket struct {
+	ID     uint64      // Request ID to match up responses with
+	Root   common.Hash // Root hash of the account trie to serve
+	Origin common.Hash // Hash of the first account to retrieve
+	Limit  common.Hash // Hash of the last account to retrieve
+	Bytes  uint64      // Soft limit at which to stop returning data
+}
+
+// AccountRangePacket is the reply to a GetAccountRangePacket, carrying the Merkle
+// proofs of the origin and the last returned account.
+type AccountRangePacket struct {
+	ID       uint64         // ID of the request this is a response for
+	Accounts []*AccountData // List of consecutive accounts from the trie
+	Proof    [][]byte       // List of trie nodes proving the account range
+}
+
+// AccountData is a single account in a range, in its consensus encoding.
+type AccountData struct {
+	Hash common.Hash  // Hash of the account
+	Body rlp.RawValue // Account body in consensus format
+}
+
+// GetStorageRangesPacket requests the storage slots of a list of accounts. The
+// origin only applies to the first account and the limit to the last one, all
+// the others are served in full.
+type GetStorageRangesPacket struct {
+	ID       uint64        // Request ID to match up responses with
+	Root     common.Hash   // Root hash of the account trie to serve
+	Accounts []common.Hash // Account hashes of the storage tries to serve
+	Origin   common.Hash   // Hash of the first storage slot to retrieve
+	Limit    common.Hash   // Hash of the last storage slot to retrieve
+	Bytes    uint64        // Soft limit at which to stop returning data
+}
+
+// StorageRangesPacket is the reply to a GetStorageRangesPacket. If the storage
+// of the last account is incomplete, the Merkle proofs of its origin and last
+// returned slot are included.
+type StorageRangesPacket struct {
+	ID    uint64           // ID of the request this is a response for
+	Slots [][]*StorageData // Lists of consecutive storage slots for the requested accounts
+	Proof [][]byte         // Merkle proofs for the last, incomplete storage range
+}
+
+// StorageData is a single storage slot in a range, in its trie encoding.
+type StorageData struct {
+	Hash common.Hash // Hash of the storage slot
+	Body []byte      // Data content of the slot
+}
+
+// GetByteCodesPacket requests a batch of contract codes by hash.
+type GetByteCodesPacket struct {
+	ID     uint64        // Request ID to match up responses with
+	Hashes []common.Hash // Code hashes to retrieve the code for
+	Bytes  uint64        // Soft limit at which to stop returning data
+}
+
+// ByteCodesPacket is the reply to a GetByteCodesPacket, in request order with
+// the unavailable codes left out.
+type ByteCodesPacket struct {
+	ID    uint64   // ID of the request this is a response for
+	Codes [][]byte // Requested contract bytecodes
+}
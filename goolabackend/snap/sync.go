@@ -0,0 +1,807 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rlp"
+	"github.com/goola-team/goola/trie"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = types.EmptyRootHash
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256Hash(nil)
+
+	// maxHash is the last hash of the key space.
+	maxHash = common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+)
+
+const (
+	// accountConcurrency is the number of chunks to split the account trie into
+	// to allow concurrent retrievals.
+	accountConcurrency = 16
+
+	// maxStorageAccounts is the maximum number of accounts whose storage is
+	// requested at once.
+	maxStorageAccounts = 64
+
+	// maxCodeRequests is the maximum number of bytecodes requested at once.
+	maxCodeRequests = 64
+
+	// maxPendingRequests is the maximum number of requests in flight at once.
+	maxPendingRequests = 256
+
+	// requestTimeout is the maximum time to wait for a reply.
+	requestTimeout = 10 * time.Second
+)
+
+// requestBytes is the soft limit on the size of the replies requested.
+var requestBytes uint64 = 512 * 1024
+
+var (
+	errCancelled      = errors.New("snap sync cancelled")
+	errNoPeers        = errors.New("no snap peers available")
+	errStaleRoot      = errors.New("state root not served by any snap peer")
+	errRootMismatch   = errors.New("account trie root mismatch")
+	errInvalidRange   = errors.New("invalid range in reply")
+	errInvalidProof   = errors.New("invalid range boundary proof")
+	errStorageMissing = errors.New("storage root mismatch")
+)
+
+// request is a single data retrieval in flight to a snap peer.
+type request struct {
+	id      uint64
+	peer    string
+	deliver chan<- *response // Channel of the sync run to deliver the reply on
+	timer   *time.Timer      // Timer to fail the request if the peer stalls
+
+	account *accountTask  // Account range to retrieve
+	storage []common.Hash // Accounts whose storage to retrieve
+	large   *storageTask  // Large storage range to continue, if any
+	codes   []common.Hash // Bytecodes to retrieve
+}
+
+// response is the reply to a request, nil if it failed or timed out.
+type response struct {
+	req    *request
+	packet interface{}
+}
+
+// accountTask is a chunk of the account hash space to retrieve.
+type accountTask struct {
+	next common.Hash // Hash of the next account to retrieve
+	last common.Hash // Hash of the last account in the chunk
+	done bool
+	busy bool
+}
+
+// storageTask is the storage of an account too large to retrieve in a single
+// reply, retrieved range by range.
+type storageTask struct {
+	account common.Hash // Hash of the account owning the storage
+	root    common.Hash // Storage root of the account
+	next    common.Hash // Hash of the next slot to retrieve
+	trie    *trie.Trie  // Storage trie being reconstructed
+	busy    bool
+}
+
+// Syncer retrieves the state of a block from snap peers as ranges of accounts
+// and storage slots, reconstructing the tries locally. The boundaries of every
+// range are proven, while the completeness of the ranges is verified in bulk:
+// every storage trie against the root of its account, and the account trie
+// against the state root once all the chunks are retrieved. Any gap left (e.g.
+// by a failed sync) is expected to be healed by a trie node sync.
+type Syncer struct {
+	db gooladb.Database // Database to store the retrieved state into
+
+	peers    map[string]*Peer    // Currently registered snap peers
+	requests map[uint64]*request // Requests in flight to any peer
+	nextID   uint64              // Identifier of the next request
+
+	update chan struct{} // Notification channel of peer set changes
+	lock   sync.Mutex
+}
+
+// NewSyncer creates a snap syncer storing the retrieved state into the given
+// database.
+func NewSyncer(db gooladb.Database) *Syncer {
+	return &Syncer{
+		db:       db,
+		peers:    make(map[string]*Peer),
+		requests: make(map[uint64]*request),
+		update:   make(chan struct{}, 1),
+	}
+}
+
+// Register injects a new snap peer to retrieve state from.
+func (s *Syncer) Register(peer *Peer) {
+	s.lock.Lock()
+	s.peers[peer.ID()] = peer
+	s.lock.Unlock()
+
+	s.notify()
+}
+
+// Unregister removes a snap peer, failing all its requests in flight.
+func (s *Syncer) Unregister(id string) {
+	s.lock.Lock()
+	delete(s.peers, id)
+
+	var failed []*request
+	for reqID, req := range s.requests {
+		if req.peer == id {
+			delete(s.requests, reqID)
+			failed = append(failed, req)
+		}
+	}
+	s.lock.Unlock()
+
+	for _, req := range failed {
+		req.timer.Stop()
+		req.deliver <- &response{req: req}
+	}
+	s.notify()
+}
+
+// notify signals a change in the peer set to the running sync, if any.
+func (s *Syncer) notify() {
+	select {
+	case s.update <- struct{}{}:
+	default:
+	}
+}
+
+// deliver hands the reply of a peer to the sync run that requested it.
+func (s *Syncer) deliver(peer string, id uint64, packet interface{}) {
+	s.lock.Lock()
+	req := s.requests[id]
+	if req == nil || req.peer != peer {
+		s.lock.Unlock()
+		log.Debug("Unrequested snap reply", "peer", peer, "reqid", id)
+		return
+	}
+	delete(s.requests, id)
+	s.lock.Unlock()
+
+	req.timer.Stop()
+	req.deliver <- &response{req: req, packet: packet}
+}
+
+// fail aborts a request still in flight.
+func (s *Syncer) fail(req *request) {
+	s.lock.Lock()
+	if s.requests[req.id] != req {
+		s.lock.Unlock()
+		return
+	}
+	delete(s.requests, req.id)
+	s.lock.Unlock()
+
+	req.timer.Stop()
+	req.deliver <- &response{req: req}
+}
+
+// Sync retrieves the state with the given root, blocking until it's available
+// locally, the sync fails or it's cancelled.
+func (s *Syncer) Sync(root common.Hash, cancel <-chan struct{}) error {
+	s.lock.Lock()
+	peers := len(s.peers)
+	s.lock.Unlock()
+
+	if peers == 0 {
+		return errNoPeers
+	}
+	run, err := newSyncRun(s, root)
+	if err != nil {
+		return err
+	}
+	defer run.abort()
+
+	log.Info("Starting snap sync", "root", root, "peers", peers)
+	var (
+		start  = time.Now()
+		report = time.NewTicker(8 * time.Second)
+	)
+	defer report.Stop()
+
+	for !run.done() {
+		run.assign()
+		if run.pending == 0 {
+			if err := run.unservable(); err != nil {
+				return err
+			}
+		}
+		select {
+		case res := <-run.responses:
+			run.pending--
+			if err := run.process(res); err != nil {
+				return err
+			}
+		case <-s.update:
+		case <-report.C:
+			log.Info("Snap sync in progress", "accounts", run.accounts, "slots", run.slots, "codes", run.codes, "elapsed", common.PrettyDuration(time.Since(start)))
+		case <-cancel:
+			return errCancelled
+		}
+	}
+	committed, err := run.commitTrie(run.accTrie)
+	if err != nil {
+		return err
+	}
+	if committed != root {
+		return fmt.Errorf("%v: have %x, want %x", errRootMismatch, committed, root)
+	}
+	log.Info("Snap sync completed", "accounts", run.accounts, "slots", run.slots, "codes", run.codes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// syncRun is the state of the retrieval of a single state root.
+type syncRun struct {
+	s    *Syncer
+	root common.Hash
+
+	triedb  *trie.Database // Database to reconstruct the tries in
+	accTrie *trie.Trie     // Account trie being reconstructed
+	accSize int            // Size of the accounts not committed yet
+
+	accountTasks []*accountTask               // Chunks of the account trie to retrieve
+	storageQueue []common.Hash                // Accounts whose storage to retrieve
+	storageRoots map[common.Hash]common.Hash  // Storage roots of the queued accounts
+	seenRoots    map[common.Hash]struct{}     // Storage roots already scheduled
+	largeTasks   map[common.Hash]*storageTask // Large storages retrieved range by range
+	codeQueue    []common.Hash                // Bytecodes to retrieve
+	seenCodes    map[common.Hash]struct{}     // Bytecodes already scheduled
+	stale        map[string]struct{}          // Peers not serving the root
+
+	responses chan *response // Replies and failures of the requests in flight
+	pending   int            // Number of requests in flight
+
+	accounts, slots, codes uint64 // Progress statistics
+}
+
+func newSyncRun(s *Syncer, root common.Hash) (*syncRun, error) {
+	triedb := trie.NewDatabase(s.db)
+	accTrie, err := trie.New(common.Hash{}, triedb)
+	if err != nil {
+		return nil, err
+	}
+	run := &syncRun{
+		s:            s,
+		root:         root,
+		triedb:       triedb,
+		accTrie:      accTrie,
+		storageRoots: make(map[common.Hash]common.Hash),
+		seenRoots:    make(map[common.Hash]struct{}),
+		largeTasks:   make(map[common.Hash]*storageTask),
+		seenCodes:    make(map[common.Hash]struct{}),
+		stale:        make(map[string]struct{}),
+		responses:    make(chan *response, maxPendingRequests),
+	}
+	// Split the account hash space into evenly sized chunks
+	var (
+		next = new(big.Int)
+		step = new(big.Int).Div(new(big.Int).Lsh(common.Big1, 256), big.NewInt(accountConcurrency))
+	)
+	for i := 0; i < accountConcurrency; i++ {
+		last := new(big.Int).Sub(new(big.Int).Add(next, step), common.Big1)
+		if i == accountConcurrency-1 {
+			last = maxHash.Big()
+		}
+		run.accountTasks = append(run.accountTasks, &accountTask{
+			next: common.BigToHash(next),
+			last: common.BigToHash(last),
+		})
+		next = new(big.Int).Add(last, common.Big1)
+	}
+	return run, nil
+}
+
+// done reports whether the entire state has been retrieved.
+func (r *syncRun) done() bool {
+	for _, task := range r.accountTasks {
+		if !task.done {
+			return false
+		}
+	}
+	return len(r.storageQueue) == 0 && len(r.largeTasks) == 0 && len(r.codeQueue) == 0 && r.pending == 0
+}
+
+// unservable returns an error if no registered peer is able to serve the
+// state, nil if some peers may still do so.
+func (r *syncRun) unservable() error {
+	r.s.lock.Lock()
+	defer r.s.lock.Unlock()
+
+	if len(r.s.peers) == 0 {
+		return errNoPeers
+	}
+	for id := range r.s.peers {
+		if _, stale := r.stale[id]; !stale {
+			return nil
+		}
+	}
+	return errStaleRoot
+}
+
+// abort drops all the requests of the run still in flight.
+func (r *syncRun) abort() {
+	r.s.lock.Lock()
+	defer r.s.lock.Unlock()
+
+	for id, req := range r.s.requests {
+		if req.deliver == (chan<- *response)(r.responses) {
+			req.timer.Stop()
+			delete(r.s.requests, id)
+		}
+	}
+}
+
+// assign sends requests for the outstanding data to all the idle peers.
+func (r *syncRun) assign() {
+	r.s.lock.Lock()
+	busy := make(map[string]struct{})
+	for _, req := range r.s.requests {
+		busy[req.peer] = struct{}{}
+	}
+	var idle []*Peer
+	for id, peer := range r.s.peers {
+		_, isBusy := busy[id]
+		_, isStale := r.stale[id]
+		if !isBusy && !isStale {
+			idle = append(idle, peer)
+		}
+	}
+	r.s.lock.Unlock()
+
+	for _, peer := range idle {
+		if r.pending >= maxPendingRequests {
+			return
+		}
+		req := r.nextRequest()
+		if req == nil {
+			return
+		}
+		r.issue(peer, req)
+	}
+}
+
+// nextRequest reserves the next batch of outstanding data to retrieve, nil if
+// there is nothing left to request.
+func (r *syncRun) nextRequest() *request {
+	for _, task := range r.largeTasks {
+		if !task.busy {
+			task.busy = true
+			return &request{storage: []common.Hash{task.account}, large: task}
+		}
+	}
+	if len(r.storageQueue) > 0 {
+		n := len(r.storageQueue)
+		if n > maxStorageAccounts {
+			n = maxStorageAccounts
+		}
+		req := &request{storage: r.storageQueue[:n:n]}
+		r.storageQueue = r.storageQueue[n:]
+		return req
+	}
+	if len(r.codeQueue) > 0 {
+		n := len(r.codeQueue)
+		if n > maxCodeRequests {
+			n = maxCodeRequests
+		}
+		req := &request{codes: r.codeQueue[:n:n]}
+		r.codeQueue = r.codeQueue[n:]
+		return req
+	}
+	for _, task := range r.accountTasks {
+		if !task.done && !task.busy {
+			task.busy = true
+			return &request{account: task}
+		}
+	}
+	return nil
+}
+
+// issue tracks a request and sends it to a peer.
+func (r *syncRun) issue(peer *Peer, req *request) {
+	s := r.s
+
+	s.lock.Lock()
+	s.nextID++
+	req.id, req.peer, req.deliver = s.nextID, peer.ID(), r.responses
+	s.requests[req.id] = req
+	req.timer = time.AfterFunc(requestTimeout, func() { s.fail(req) })
+	s.lock.Unlock()
+
+	r.pending++
+
+	var err error
+	switch {
+	case req.account != nil:
+		err = peer.RequestAccountRange(req.id, r.root, req.account.next, req.account.last, requestBytes)
+	case req.large != nil:
+		err = peer.RequestStorageRanges(req.id, r.root, req.storage, req.large.next, common.Hash{}, requestBytes)
+	case len(req.storage) > 0:
+		err = peer.RequestStorageRanges(req.id, r.root, req.storage, common.Hash{}, common.Hash{}, requestBytes)
+	default:
+		err = peer.RequestByteCodes(req.id, req.codes, requestBytes)
+	}
+	if err != nil {
+		peer.Log().Debug("Failed to send snap request", "err", err)
+		s.fail(req)
+	}
+}
+
+// revert returns the data of a failed request into the retrieval queues.
+func (r *syncRun) revert(req *request) {
+	switch {
+	case req.account != nil:
+		req.account.busy = false
+	case req.large != nil:
+		req.large.busy = false
+	case len(req.storage) > 0:
+		r.storageQueue = append(r.storageQueue, req.storage...)
+	default:
+		r.codeQueue = append(r.codeQueue, req.codes...)
+	}
+}
+
+// process handles the reply to a request. Invalid replies mark the peer as not
+// serving the state and are dropped, only local failures are returned.
+func (r *syncRun) process(res *response) error {
+	req := res.req
+	if res.packet == nil {
+		r.revert(req)
+		return nil
+	}
+	var err error
+	switch packet := res.packet.(type) {
+	case *AccountRangePacket:
+		if req.account == nil {
+			err = errInvalidRange
+			break
+		}
+		err = r.processAccounts(req, packet)
+	case *StorageRangesPacket:
+		if len(req.storage) == 0 {
+			err = errInvalidRange
+			break
+		}
+		return r.processStorage(req, packet)
+	case *ByteCodesPacket:
+		if req.account != nil || len(req.storage) > 0 {
+			err = errInvalidRange
+			break
+		}
+		err = r.processCodes(req, packet)
+	}
+	if err != nil {
+		log.Debug("Dropping invalid snap reply", "peer", req.peer, "err", err)
+		r.stale[req.peer] = struct{}{}
+		r.revert(req)
+	}
+	return nil
+}
+
+// proofDatabase collects the nodes of a reply's Merkle proofs by hash.
+func proofDatabase(nodes [][]byte) *gooladb.MemDatabase {
+	db, _ := gooladb.NewMemDatabase()
+	for _, node := range nodes {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
+
+// verifyBoundary checks that an entry at the edge of a range is proven to be
+// part of the trie with the given root.
+func verifyBoundary(root common.Hash, key common.Hash, value []byte, proof *gooladb.MemDatabase) error {
+	proven, err, _ := trie.VerifyProof(root, key[:], proof)
+	if err != nil || !bytes.Equal(proven, value) {
+		return errInvalidProof
+	}
+	return nil
+}
+
+// verifyOrigin checks the proof of the origin of a range, which must match the
+// first entry if it's at the origin itself.
+func verifyOrigin(root common.Hash, origin common.Hash, first common.Hash, value []byte, proof *gooladb.MemDatabase) error {
+	if first == origin {
+		return verifyBoundary(root, first, value, proof)
+	}
+	if _, err, _ := trie.VerifyProof(root, origin[:], proof); err != nil {
+		return errInvalidProof
+	}
+	return nil
+}
+
+// incHash returns the hash following the given one, and false on overflow.
+func incHash(h common.Hash) (common.Hash, bool) {
+	next := new(big.Int).Add(h.Big(), common.Big1)
+	if next.BitLen() > 256 {
+		return common.Hash{}, false
+	}
+	return common.BigToHash(next), true
+}
+
+// processAccounts handles a reply to an account range request.
+func (r *syncRun) processAccounts(req *request, res *AccountRangePacket) error {
+	task := req.account
+	if len(res.Accounts) == 0 && len(res.Proof) == 0 {
+		return errStaleRoot
+	}
+	proof := proofDatabase(res.Proof)
+	if len(res.Accounts) == 0 {
+		// No accounts left in the chunk, the proof of the origin proves the gap
+		if _, err, _ := trie.VerifyProof(r.root, task.next[:], proof); err != nil {
+			return errInvalidProof
+		}
+		task.done, task.busy = true, false
+		return nil
+	}
+	// Ensure the accounts are ordered within the chunk and the edges are proven
+	prev := task.next
+	for i, account := range res.Accounts {
+		if (i > 0 && bytes.Compare(account.Hash[:], prev[:]) <= 0) || bytes.Compare(account.Hash[:], prev[:]) < 0 || bytes.Compare(account.Hash[:], task.last[:]) > 0 {
+			return errInvalidRange
+		}
+		prev = account.Hash
+	}
+	if err := verifyOrigin(r.root, task.next, res.Accounts[0].Hash, res.Accounts[0].Body, proof); err != nil {
+		return err
+	}
+	last := res.Accounts[len(res.Accounts)-1]
+	if err := verifyBoundary(r.root, last.Hash, last.Body, proof); err != nil {
+		return err
+	}
+	accounts := make([]state.Account, len(res.Accounts))
+	for i, account := range res.Accounts {
+		if err := rlp.DecodeBytes(account.Body, &accounts[i]); err != nil {
+			return err
+		}
+	}
+	// Reply valid, insert the accounts and schedule their storage and code
+	for i, account := range res.Accounts {
+		if err := r.accTrie.TryUpdate(account.Hash[:], account.Body); err != nil {
+			return err
+		}
+		r.accSize += common.HashLength + len(account.Body)
+		r.schedule(account.Hash, &accounts[i])
+	}
+	r.accounts += uint64(len(res.Accounts))
+
+	task.busy = false
+	if next, ok := incHash(last.Hash); ok && last.Hash != task.last {
+		task.next = next
+	} else {
+		task.done = true
+	}
+	if r.accSize >= gooladb.IdealBatchSize {
+		if _, err := r.commitTrie(r.accTrie); err != nil {
+			return err
+		}
+		r.accSize = 0
+	}
+	return nil
+}
+
+// schedule queues the retrieval of the storage and code of an account, unless
+// already available locally.
+func (r *syncRun) schedule(hash common.Hash, account *state.Account) {
+	if account.Root != emptyRoot {
+		if _, seen := r.seenRoots[account.Root]; !seen {
+			r.seenRoots[account.Root] = struct{}{}
+			if ok, _ := r.s.db.Has(account.Root[:]); !ok {
+				r.storageRoots[hash] = account.Root
+				r.storageQueue = append(r.storageQueue, hash)
+			}
+		}
+	}
+	code := common.BytesToHash(account.CodeHash)
+	if code != emptyCode {
+		if _, seen := r.seenCodes[code]; !seen {
+			r.seenCodes[code] = struct{}{}
+			if ok, _ := r.s.db.Has(code[:]); !ok {
+				r.codeQueue = append(r.codeQueue, code)
+			}
+		}
+	}
+}
+
+// processStorage handles a reply to a storage ranges request. The storage of
+// the accounts not covered by the reply is requested again.
+func (r *syncRun) processStorage(req *request, res *StorageRangesPacket) error {
+	if len(res.Slots) == 0 && len(res.Proof) == 0 {
+		r.stale[req.peer] = struct{}{}
+		r.revert(req)
+		return nil
+	}
+	if len(res.Slots) > len(req.storage) {
+		log.Debug("Dropping invalid snap reply", "peer", req.peer, "err", errInvalidRange)
+		r.stale[req.peer] = struct{}{}
+		r.revert(req)
+		return nil
+	}
+	proof := proofDatabase(res.Proof)
+	for i, slots := range res.Slots {
+		var err error
+		if req.large != nil {
+			err = r.continueStorage(req.large, slots, len(res.Proof) > 0, proof)
+		} else {
+			partial := i == len(res.Slots)-1 && len(res.Proof) > 0
+			err = r.storeStorage(req.storage[i], slots, partial, proof)
+		}
+		if err != nil {
+			if err == errInvalidRange || err == errInvalidProof || err == errStorageMissing {
+				log.Debug("Dropping invalid snap reply", "peer", req.peer, "err", err)
+				r.stale[req.peer] = struct{}{}
+				r.revert(&request{storage: req.storage[i:], large: req.large})
+				return nil
+			}
+			return err
+		}
+	}
+	if req.large != nil {
+		req.large.busy = false
+	} else if len(res.Slots) < len(req.storage) {
+		r.storageQueue = append(r.storageQueue, req.storage[len(res.Slots):]...)
+	}
+	return nil
+}
+
+// verifySlots ensures the slots of a storage range are strictly ordered and
+// start at the given origin.
+func verifySlots(slots []*StorageData, origin common.Hash) error {
+	prev := origin
+	for i, slot := range slots {
+		if (i > 0 && bytes.Compare(slot.Hash[:], prev[:]) <= 0) || bytes.Compare(slot.Hash[:], prev[:]) < 0 {
+			return errInvalidRange
+		}
+		prev = slot.Hash
+	}
+	return nil
+}
+
+// storeStorage reconstructs the storage trie of an account from its slots. A
+// partial range is continued as a large storage task, a complete one must hash
+// to the storage root of the account.
+func (r *syncRun) storeStorage(account common.Hash, slots []*StorageData, partial bool, proof *gooladb.MemDatabase) error {
+	root := r.storageRoots[account]
+	if err := verifySlots(slots, common.Hash{}); err != nil {
+		return err
+	}
+	tr, err := trie.New(common.Hash{}, r.triedb)
+	if err != nil {
+		return err
+	}
+	for _, slot := range slots {
+		if err := tr.TryUpdate(slot.Hash[:], slot.Body); err != nil {
+			return err
+		}
+	}
+	r.slots += uint64(len(slots))
+
+	if partial && len(slots) > 0 {
+		last := slots[len(slots)-1]
+		if err := verifyBoundary(root, last.Hash, last.Body, proof); err != nil {
+			return err
+		}
+		if next, ok := incHash(last.Hash); ok {
+			r.largeTasks[account] = &storageTask{account: account, root: root, next: next, trie: tr}
+			return nil
+		}
+	}
+	return r.finishStorage(account, tr)
+}
+
+// continueStorage extends a large storage task with the next range of slots.
+func (r *syncRun) continueStorage(task *storageTask, slots []*StorageData, proven bool, proof *gooladb.MemDatabase) error {
+	if err := verifySlots(slots, task.next); err != nil {
+		return err
+	}
+	if len(slots) > 0 && proven {
+		first, last := slots[0], slots[len(slots)-1]
+		if err := verifyOrigin(task.root, task.next, first.Hash, first.Body, proof); err != nil {
+			return err
+		}
+		if err := verifyBoundary(task.root, last.Hash, last.Body, proof); err != nil {
+			return err
+		}
+	}
+	for _, slot := range slots {
+		if err := task.trie.TryUpdate(slot.Hash[:], slot.Body); err != nil {
+			return err
+		}
+	}
+	r.slots += uint64(len(slots))
+
+	if len(slots) > 0 && proven {
+		if next, ok := incHash(slots[len(slots)-1].Hash); ok {
+			// Flush the slots retrieved so far to keep memory use bounded
+			if _, err := r.commitTrie(task.trie); err != nil {
+				return err
+			}
+			task.next = next
+			return nil
+		}
+	}
+	delete(r.largeTasks, task.account)
+	return r.finishStorage(task.account, task.trie)
+}
+
+// finishStorage commits a complete storage trie, checking it against the root
+// of its account.
+func (r *syncRun) finishStorage(account common.Hash, tr *trie.Trie) error {
+	root := r.storageRoots[account]
+	if tr.Hash() != root {
+		return errStorageMissing
+	}
+	if _, err := r.commitTrie(tr); err != nil {
+		return err
+	}
+	delete(r.storageRoots, account)
+	return nil
+}
+
+// processCodes handles a reply to a bytecode request, requesting the codes not
+// delivered again.
+func (r *syncRun) processCodes(req *request, res *ByteCodesPacket) error {
+	if len(res.Codes) == 0 {
+		return errStaleRoot
+	}
+	delivered := make(map[common.Hash][]byte, len(res.Codes))
+	for _, code := range res.Codes {
+		delivered[crypto.Keccak256Hash(code)] = code
+	}
+	for _, hash := range req.codes {
+		code, ok := delivered[hash]
+		if !ok {
+			r.codeQueue = append(r.codeQueue, hash)
+			continue
+		}
+		if err := r.s.db.Put(hash[:], code); err != nil {
+			return err
+		}
+		r.codes++
+	}
+	return nil
+}
+
+// commitTrie flushes a reconstructed trie into the database.
+func (r *syncRun) commitTrie(tr *trie.Trie) (common.Hash, error) {
+	root, err := tr.Commit(nil)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := r.triedb.Commit(root, false); err != nil {
+		return common.Hash{}, err
+	}
+	return root, nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/p2p"
+	"github.com/goola-team/goola/p2p/discover"
+)
+
+// makeTestState creates a state with plain accounts, contracts with code and
+// accounts with both small and large storage tries.
+func makeTestState(t *testing.T) (state.Database, common.Hash) {
+	diskdb, _ := gooladb.NewMemDatabase()
+	db := state.NewDatabase(diskdb)
+	statedb, _ := state.New(common.Hash{}, db)
+
+	for i := byte(0); i < 200; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		statedb.SetBalance(addr, big.NewInt(int64(i)+1))
+		statedb.SetNonce(addr, uint64(i))
+
+		if i%5 == 0 {
+			statedb.SetCode(addr, []byte{i, i, i})
+		}
+		if i%7 == 0 {
+			slots := 3
+			if i%49 == 0 {
+				slots = 2000
+			}
+			for j := 0; j < slots; j++ {
+				statedb.SetState(addr, common.BigToHash(big.NewInt(int64(j))), common.BigToHash(big.NewInt(int64(j)+1)))
+			}
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := db.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	return db, root
+}
+
+// Tests that a state is fully retrieved over the snap protocol, including the
+// storage tries needing multiple replies.
+func TestSnapSync(t *testing.T) {
+	defer func(old uint64) { requestBytes = old }(requestBytes)
+	requestBytes = 4 * 1024
+
+	source, root := makeTestState(t)
+
+	clientdb, _ := gooladb.NewMemDatabase()
+	serverdb, _ := gooladb.NewMemDatabase()
+
+	client, server := NewSyncer(clientdb), NewSyncer(serverdb)
+	clientrw, serverrw := p2p.MsgPipe()
+	defer clientrw.Close()
+
+	go Handle(state.NewDatabase(clientdb), client, NewPeer(p2p.NewPeer(discover.NodeID{1}, "server", nil), clientrw))
+	go Handle(source, server, NewPeer(p2p.NewPeer(discover.NodeID{2}, "client", nil), serverrw))
+
+	// Wait for the server to be registered and run the sync
+	for i := 0; ; i++ {
+		client.lock.Lock()
+		peers := len(client.peers)
+		client.lock.Unlock()
+		if peers > 0 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("server peer not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := client.Sync(root, make(chan struct{})); err != nil {
+		t.Fatalf("failed to snap sync: %v", err)
+	}
+	// Ensure the state is complete and matches the source
+	if missing := state.NewStateSync(root, clientdb).Missing(1); len(missing) != 0 {
+		t.Fatalf("state incomplete after sync: missing %x", missing)
+	}
+	want, _ := state.New(root, source)
+	have, err := state.New(root, state.NewDatabase(clientdb))
+	if err != nil {
+		t.Fatalf("failed to open synced state: %v", err)
+	}
+	for i := byte(0); i < 200; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		if have.GetBalance(addr).Cmp(want.GetBalance(addr)) != 0 {
+			t.Errorf("account %x: balance mismatch: have %v, want %v", addr, have.GetBalance(addr), want.GetBalance(addr))
+		}
+		if string(have.GetCode(addr)) != string(want.GetCode(addr)) {
+			t.Errorf("account %x: code mismatch: have %x, want %x", addr, have.GetCode(addr), want.GetCode(addr))
+		}
+		key := common.BigToHash(big.NewInt(1))
+		if have.GetState(addr, key) != want.GetState(addr, key) {
+			t.Errorf("account %x: storage mismatch: have %x, want %x", addr, have.GetState(addr, key), want.GetState(addr, key))
+		}
+	}
+}
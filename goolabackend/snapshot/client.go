@@ -0,0 +1,118 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/log"
+)
+
+// fetchTimeout is the time allowance for downloading a manifest or a chunk.
+const fetchTimeout = 5 * time.Minute
+
+// Bootstrap imports the snapshot served by the seeder at the given URL into the
+// chain, verifying the manifest was signed by the trusted signer and every chunk
+// against the manifest. Blocks already present locally are skipped, and nothing
+// is done if the local chain is already past the snapshot. The number of blocks
+// imported is returned.
+func Bootstrap(chain Chain, url string, signer common.Address) (int, error) {
+	client := &http.Client{Timeout: fetchTimeout}
+	url = strings.TrimSuffix(url, "/")
+
+	manifest, err := fetchManifest(client, url)
+	if err != nil {
+		return 0, err
+	}
+	if manifest.Version != manifestVersion {
+		return 0, fmt.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+	if have, err := manifest.Signer(); err != nil || have != signer {
+		return 0, errBadSignature
+	}
+	head := chain.CurrentBlock().NumberU64()
+	if head >= uint64(manifest.Number) {
+		return 0, nil
+	}
+	log.Info("Bootstrapping from snapshot", "url", url, "number", manifest.Number, "hash", manifest.Hash)
+
+	imported := 0
+	for index := head / chunkBlocks; index < uint64(len(manifest.Chunks)); index++ {
+		blob, err := fetch(client, fmt.Sprintf("%s/chunk/%d", url, index))
+		if err != nil {
+			return imported, err
+		}
+		if crypto.Keccak256Hash(blob) != manifest.Chunks[index] {
+			return imported, fmt.Errorf("chunk %d: %v", index, errBadChunk)
+		}
+		blocks, err := decodeChunk(blob)
+		if err != nil {
+			return imported, fmt.Errorf("chunk %d: %v", index, err)
+		}
+		first, _ := chunkRange(index)
+		if len(blocks) != chunkBlocks || blocks[0].NumberU64() != first {
+			return imported, fmt.Errorf("chunk %d: %v", index, errBadChunk)
+		}
+		// Skip any blocks already imported and feed the rest into the chain
+		if head >= first {
+			blocks = blocks[head-first+1:]
+		}
+		if n, err := chain.InsertChain(blocks); err != nil {
+			return imported + n, fmt.Errorf("chunk %d: block #%d: %v", index, blocks[n].NumberU64(), err)
+		}
+		imported += len(blocks)
+		log.Info("Imported snapshot chunk", "index", index, "chunks", len(manifest.Chunks), "number", blocks[len(blocks)-1].NumberU64())
+	}
+	if block := chain.GetBlockByNumber(uint64(manifest.Number)); block == nil || block.Hash() != manifest.Hash {
+		return imported, fmt.Errorf("snapshot head mismatch: want %x", manifest.Hash)
+	}
+	return imported, nil
+}
+
+// fetchManifest downloads and parses the manifest of a seeder.
+func fetchManifest(client *http.Client, url string) (*Manifest, error) {
+	blob, err := fetch(client, url+"/manifest")
+	if err != nil {
+		return nil, err
+	}
+	manifest := new(Manifest)
+	if err := json.Unmarshal(blob, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %v", err)
+	}
+	return manifest, nil
+}
+
+// fetch downloads the resource at the given URL.
+func fetch(client *http.Client, url string) ([]byte, error) {
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
@@ -0,0 +1,182 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/log"
+)
+
+// errNoSnapshot is returned if the local chain is too short to be snapshotted.
+var errNoSnapshot = errors.New("chain too short for a snapshot")
+
+// chunkHash is the cached hash of an encoded chunk, together with the hash of
+// its last block to detect reorgs invalidating it.
+type chunkHash struct {
+	last common.Hash
+	hash common.Hash
+}
+
+// Server serves the snapshot of the local canonical chain over HTTP.
+type Server struct {
+	chain Chain
+	key   *ecdsa.PrivateKey
+
+	chunks   []chunkHash // Hashes of the already encoded chunks
+	manifest *Manifest   // Last signed manifest, reused until the chain advances
+	lock     sync.Mutex
+
+	listener net.Listener
+}
+
+// NewServer creates a snapshot server signing its manifests with the given key.
+func NewServer(chain Chain, key *ecdsa.PrivateKey) *Server {
+	return &Server{chain: chain, key: key}
+}
+
+// Start opens the HTTP endpoint on the given address and starts serving.
+func (s *Server) Start(listen string) error {
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	go http.Serve(listener, s)
+
+	log.Info("Snapshot seeder started", "url", "http://"+listener.Addr().String())
+	return nil
+}
+
+// Stop closes the HTTP endpoint.
+func (s *Server) Stop() {
+	if s.listener != nil {
+		s.listener.Close()
+		s.listener = nil
+		log.Info("Snapshot seeder stopped")
+	}
+}
+
+// ServeHTTP implements http.Handler, serving the manifest on /manifest and the
+// chunks on /chunk/<index>.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch path := strings.TrimSuffix(r.URL.Path, "/"); {
+	case path == "/manifest":
+		manifest, err := s.Manifest()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(manifest)
+
+	case strings.HasPrefix(path, "/chunk/"):
+		index, err := strconv.ParseUint(strings.TrimPrefix(path, "/chunk/"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid chunk index", http.StatusBadRequest)
+			return
+		}
+		manifest, err := s.Manifest()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if index >= uint64(len(manifest.Chunks)) {
+			http.NotFound(w, r)
+			return
+		}
+		blob, err := encodeChunk(s.chain, index)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(blob)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Manifest returns the signed manifest of the current snapshot, which covers
+// all full chunks at least snapshotConfirmations blocks behind the head.
+func (s *Server) Manifest() (*Manifest, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	head := s.chain.CurrentBlock().NumberU64()
+	if head < chunkBlocks+snapshotConfirmations {
+		return nil, errNoSnapshot
+	}
+	count := (head - snapshotConfirmations) / chunkBlocks
+	_, number := chunkRange(count - 1)
+
+	last := s.chain.GetBlockByNumber(number)
+	if last == nil {
+		return nil, errNoSnapshot
+	}
+	if s.manifest != nil && s.manifest.Hash == last.Hash() {
+		return s.manifest, nil
+	}
+	// The snapshot moved, drop any cached chunks reorged out and hash the new ones
+	for i, chunk := range s.chunks {
+		_, end := chunkRange(uint64(i))
+		if block := s.chain.GetBlockByNumber(end); block == nil || block.Hash() != chunk.last {
+			s.chunks = s.chunks[:i]
+			break
+		}
+	}
+	for index := uint64(len(s.chunks)); index < count; index++ {
+		blob, err := encodeChunk(s.chain, index)
+		if err != nil {
+			return nil, err
+		}
+		_, end := chunkRange(index)
+		s.chunks = append(s.chunks, chunkHash{
+			last: s.chain.GetBlockByNumber(end).Hash(),
+			hash: crypto.Keccak256Hash(blob),
+		})
+	}
+	manifest := &Manifest{
+		Version: manifestVersion,
+		Number:  hexutil.Uint64(number),
+		Hash:    last.Hash(),
+		Chunks:  make([]common.Hash, count),
+	}
+	for i := range manifest.Chunks {
+		manifest.Chunks[i] = s.chunks[i].hash
+	}
+	if err := manifest.sign(s.key); err != nil {
+		return nil, err
+	}
+	s.manifest = manifest
+	return manifest, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements the distribution of chain snapshots over HTTP,
+// allowing new nodes to bootstrap from a trusted seeder before syncing.
+//
+// A snapshot is the canonical chain up to a recent block, split into chunks of
+// consecutive RLP encoded blocks. The chunk hashes are listed in a manifest
+// signed by the seeding node, so chunks can be verified one by one as they are
+// downloaded.
+package snapshot
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/rlp"
+)
+
+const (
+	// manifestVersion is the version of the snapshot manifest format.
+	manifestVersion = 1
+
+	// chunkBlocks is the number of blocks contained in a snapshot chunk.
+	chunkBlocks = 2048
+
+	// snapshotConfirmations is the number of blocks a snapshot lags behind the
+	// chain head, keeping it clear of reorgs.
+	snapshotConfirmations = 128
+)
+
+var (
+	errBadSignature = errors.New("manifest not signed by the trusted signer")
+	errBadChunk     = errors.New("chunk hash mismatch")
+)
+
+// Config are the configuration parameters of snapshot seeding.
+type Config struct {
+	Listen string         `toml:",omitempty"` // HTTP endpoint to serve the local snapshot on (empty = disabled)
+	URL    string         `toml:",omitempty"` // Seeder to bootstrap an empty chain from (empty = disabled)
+	Signer common.Address `toml:",omitempty"` // Node account trusted to sign the fetched manifests
+}
+
+// Chain is the blockchain access needed to serve and import snapshots.
+type Chain interface {
+	CurrentBlock() *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	InsertChain(blocks types.Blocks) (int, error)
+}
+
+// Manifest describes a snapshot, listing the hashes of its chunks.
+type Manifest struct {
+	Version   uint           `json:"version"`
+	Number    hexutil.Uint64 `json:"number"` // Number of the last block in the snapshot
+	Hash      common.Hash    `json:"hash"`   // Hash of the last block in the snapshot
+	Chunks    []common.Hash  `json:"chunks"` // Keccak256 hashes of the chunk contents
+	Signature hexutil.Bytes  `json:"signature"`
+}
+
+// sigHash returns the hash of the signed manifest fields.
+func (m *Manifest) sigHash() common.Hash {
+	blob, _ := rlp.EncodeToBytes([]interface{}{m.Version, uint64(m.Number), m.Hash, m.Chunks})
+	return crypto.Keccak256Hash(blob)
+}
+
+// sign signs the manifest with the given key.
+func (m *Manifest) sign(key *ecdsa.PrivateKey) error {
+	sig, err := crypto.Sign(m.sigHash().Bytes(), key)
+	if err != nil {
+		return err
+	}
+	m.Signature = sig
+	return nil
+}
+
+// Signer recovers the account which signed the manifest.
+func (m *Manifest) Signer() (common.Address, error) {
+	pubkey, err := crypto.SigToPub(m.sigHash().Bytes(), m.Signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
+
+// chunkRange returns the first and last block number contained in a chunk. The
+// genesis block is never part of a snapshot.
+func chunkRange(index uint64) (uint64, uint64) {
+	return index*chunkBlocks + 1, (index + 1) * chunkBlocks
+}
+
+// encodeChunk RLP encodes the blocks of a chunk from the canonical chain.
+func encodeChunk(chain Chain, index uint64) ([]byte, error) {
+	first, last := chunkRange(index)
+
+	buf := new(bytes.Buffer)
+	for number := first; number <= last; number++ {
+		block := chain.GetBlockByNumber(number)
+		if block == nil {
+			return nil, fmt.Errorf("block #%d unavailable", number)
+		}
+		if err := block.EncodeRLP(buf); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeChunk parses the blocks of a downloaded chunk.
+func decodeChunk(blob []byte) (types.Blocks, error) {
+	var (
+		blocks types.Blocks
+		stream = rlp.NewStream(bytes.NewReader(blob), uint64(len(blob)))
+	)
+	for {
+		block := new(types.Block)
+		if err := stream.Decode(block); err == io.EOF {
+			return blocks, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("block %d: %v", len(blocks), err)
+		}
+		blocks = append(blocks, block)
+	}
+}
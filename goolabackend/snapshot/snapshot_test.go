@@ -0,0 +1,108 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+)
+
+// testChain is a header-only canonical chain.
+type testChain struct {
+	blocks []*types.Block
+}
+
+func newTestChain(n int) *testChain {
+	chain := &testChain{blocks: []*types.Block{types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0)})}}
+	for i := 1; i <= n; i++ {
+		parent := chain.blocks[i-1]
+		chain.blocks = append(chain.blocks, types.NewBlockWithHeader(&types.Header{ParentHash: parent.Hash(), Number: big.NewInt(int64(i))}))
+	}
+	return chain
+}
+
+func (c *testChain) CurrentBlock() *types.Block { return c.blocks[len(c.blocks)-1] }
+
+func (c *testChain) GetBlockByNumber(number uint64) *types.Block {
+	if number < uint64(len(c.blocks)) {
+		return c.blocks[number]
+	}
+	return nil
+}
+
+func (c *testChain) InsertChain(blocks types.Blocks) (int, error) {
+	for i, block := range blocks {
+		if block.ParentHash() != c.CurrentBlock().Hash() {
+			return i, fmt.Errorf("unknown parent")
+		}
+		c.blocks = append(c.blocks, block)
+	}
+	return len(blocks), nil
+}
+
+// Tests that a snapshot can be served and bootstrapped from, with the manifest
+// covering only the confirmed full chunks.
+func TestBootstrap(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	source := newTestChain(2*chunkBlocks + snapshotConfirmations + 10)
+	seeder := httptest.NewServer(NewServer(source, key))
+	defer seeder.Close()
+
+	// Import the snapshot into an empty chain
+	chain := newTestChain(0)
+	imported, err := Bootstrap(chain, seeder.URL, crypto.PubkeyToAddress(key.PublicKey))
+	if err != nil {
+		t.Fatalf("failed to bootstrap: %v", err)
+	}
+	if imported != 2*chunkBlocks {
+		t.Errorf("imported block count mismatch: have %d, want %d", imported, 2*chunkBlocks)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != source.GetBlockByNumber(2*chunkBlocks).Hash() {
+		t.Errorf("head mismatch: have #%d, want #%d", head.NumberU64(), 2*chunkBlocks)
+	}
+	// Ensure a partially synced chain only imports the missing blocks
+	chain = &testChain{blocks: append([]*types.Block{}, source.blocks[:chunkBlocks+100]...)}
+	if imported, err = Bootstrap(chain, seeder.URL, crypto.PubkeyToAddress(key.PublicKey)); err != nil {
+		t.Fatalf("failed to resume bootstrap: %v", err)
+	}
+	if want := chunkBlocks - 99; imported != want {
+		t.Errorf("resumed block count mismatch: have %d, want %d", imported, want)
+	}
+}
+
+// Tests that manifests signed by an untrusted key are rejected.
+func TestBootstrapUntrusted(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+
+	seeder := httptest.NewServer(NewServer(newTestChain(chunkBlocks+snapshotConfirmations), key))
+	defer seeder.Close()
+
+	chain := newTestChain(0)
+	if _, err := Bootstrap(chain, seeder.URL, crypto.PubkeyToAddress(other.PublicKey)); err != errBadSignature {
+		t.Fatalf("error mismatch: have %v, want %v", err, errBadSignature)
+	}
+	if head := chain.CurrentBlock().NumberU64(); head != 0 {
+		t.Fatalf("untrusted snapshot imported up to #%d", head)
+	}
+}
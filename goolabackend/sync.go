@@ -47,7 +47,13 @@ func (pm *ProtocolManager) syncTransactions(p *peer) {
 	var txs types.Transactions
 	pending, _ := pm.txpool.Pending()
 	for _, batch := range pending {
-		txs = append(txs, batch...)
+		for _, tx := range batch {
+			// Local transactions awaiting private forwarding must not leak
+			if pm.txPrivacy != nil && pm.txPrivacy.holding(tx.Hash()) {
+				continue
+			}
+			txs = append(txs, tx)
+		}
 	}
 	if len(txs) == 0 {
 		return
@@ -162,8 +168,8 @@ func (pm *ProtocolManager) syncer() {
 
 // synchronise tries to sync up our local block chain with a remote peer.
 func (pm *ProtocolManager) synchronise(peer *peer) {
-	// Short circuit if no peers are available
-	if peer == nil {
+	// Short circuit if no peers are available or the chain is frozen
+	if peer == nil || pm.blockchain.Frozen() {
 		return
 	}
 	// Make sure the peer's TD is higher than our own
@@ -176,6 +182,9 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 	if atomic.LoadUint32(&pm.fastSync) == 1 {
 		// Fast sync was explicitly requested, and explicitly granted
 		mode = downloader.FastSync
+		if pm.snapSync {
+			mode = downloader.SnapSync
+		}
 	} else if currentBlock.NumberU64() == 0 && pm.blockchain.CurrentFastBlock().NumberU64() > 0 {
 		// The database seems empty as the current block is the genesis. Yet the fast
 		// block is ahead, so fast sync was enabled for this node at a certain point.
@@ -0,0 +1,344 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"path/filepath"
+	"plugin"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/rpc"
+)
+
+const (
+	// pluginPrefix selects a Go plugin tracer in a trace config.
+	pluginPrefix = "plugin:"
+
+	// sidecarPrefix selects an out-of-process tracer in a trace config.
+	sidecarPrefix = "sidecar:"
+
+	// pluginSymbol is the constructor every tracer plugin must export, of type
+	// func() vm.Tracer.
+	pluginSymbol = "NewTracer"
+
+	// sidecarBatchSize is the number of step events buffered before they are
+	// streamed to a sidecar.
+	sidecarBatchSize = 1024
+)
+
+var (
+	errPluginsDisabled = errors.New("tracer plugins disabled")
+	errUnknownSidecar  = errors.New("unknown tracer sidecar")
+	errInvalidPlugin   = errors.New("invalid tracer plugin name")
+)
+
+// ExternalConfig lists the tracers living outside the node binary which trace
+// calls may select by name. Only configured tracers can ever be loaded.
+type ExternalConfig struct {
+	PluginDir string            `toml:",omitempty"` // Directory of Go plugin tracers (<name>.so)
+	Sidecars  map[string]string `toml:",omitempty"` // JSON-RPC endpoints of named tracer sidecars
+}
+
+// ExternalTracer is a tracer implemented outside of the node binary.
+type ExternalTracer interface {
+	vm.Tracer
+
+	// GetResult returns the JSON encoded result of the trace.
+	GetResult() (json.RawMessage, error)
+
+	// Stop aborts the trace at the first opportunity.
+	Stop(err error)
+}
+
+// IsExternal reports whether a tracer name selects an external tracer.
+func IsExternal(name string) bool {
+	return strings.HasPrefix(name, pluginPrefix) || strings.HasPrefix(name, sidecarPrefix)
+}
+
+// NewExternal instantiates the external tracer selected by name, which is
+// either "plugin:<name>" or "sidecar:<name>".
+func (c *ExternalConfig) NewExternal(name string) (ExternalTracer, error) {
+	switch {
+	case strings.HasPrefix(name, pluginPrefix):
+		if c.PluginDir == "" {
+			return nil, errPluginsDisabled
+		}
+		name = strings.TrimPrefix(name, pluginPrefix)
+		if name == "" || filepath.Base(name) != name {
+			return nil, errInvalidPlugin
+		}
+		return newPluginTracer(filepath.Join(c.PluginDir, name+".so"))
+
+	case strings.HasPrefix(name, sidecarPrefix):
+		url, ok := c.Sidecars[strings.TrimPrefix(name, sidecarPrefix)]
+		if !ok {
+			return nil, errUnknownSidecar
+		}
+		return newSidecarTracer(url)
+	}
+	return nil, fmt.Errorf("not an external tracer: %s", name)
+}
+
+// pluginTracer wraps a tracer loaded from a Go plugin, adding the result and
+// interruption support the plugin may not implement itself.
+type pluginTracer struct {
+	vm.Tracer
+
+	lock sync.Mutex
+	err  error // Reason the trace was stopped, if any
+}
+
+// newPluginTracer opens the Go plugin at path and instantiates its tracer.
+func newPluginTracer(path string) (*pluginTracer, error) {
+	plug, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := plug.Lookup(pluginSymbol)
+	if err != nil {
+		return nil, err
+	}
+	var constructor func() vm.Tracer
+	switch fn := sym.(type) {
+	case func() vm.Tracer:
+		constructor = fn
+	case *func() vm.Tracer:
+		constructor = *fn
+	default:
+		return nil, fmt.Errorf("plugin %s: %s has type %T, want func() vm.Tracer", path, pluginSymbol, sym)
+	}
+	return &pluginTracer{Tracer: constructor()}, nil
+}
+
+// CaptureState forwards the step to the plugin unless the trace was stopped.
+func (t *pluginTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	t.lock.Lock()
+	stopped := t.err
+	t.lock.Unlock()
+
+	if stopped != nil {
+		env.Cancel()
+		return stopped
+	}
+	return t.Tracer.CaptureState(env, pc, op, gas, cost, memory, stack, contract, depth, err)
+}
+
+// GetResult returns the plugin's result if it provides one.
+func (t *pluginTracer) GetResult() (json.RawMessage, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.err != nil {
+		return nil, t.err
+	}
+	if res, ok := t.Tracer.(interface {
+		GetResult() (json.RawMessage, error)
+	}); ok {
+		return res.GetResult()
+	}
+	return json.Marshal(t.Tracer)
+}
+
+// Stop aborts the trace.
+func (t *pluginTracer) Stop(err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.err == nil {
+		t.err = err
+	}
+	if stopper, ok := t.Tracer.(interface{ Stop(error) }); ok {
+		stopper.Stop(err)
+	}
+}
+
+// SidecarStep is a single execution step streamed to a tracer sidecar.
+type SidecarStep struct {
+	Pc      uint64         `json:"pc"`
+	Op      string         `json:"op"`
+	Gas     uint64         `json:"gas"`
+	Cost    uint64         `json:"cost"`
+	Depth   int            `json:"depth"`
+	Stack   []*hexutil.Big `json:"stack"`
+	MemSize int            `json:"memSize"`
+	Address common.Address `json:"address"`
+	Fault   bool           `json:"fault,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// SidecarStart is the transaction context sent to a sidecar before execution.
+type SidecarStart struct {
+	From   common.Address `json:"from"`
+	To     common.Address `json:"to"`
+	Create bool           `json:"create"`
+	Input  hexutil.Bytes  `json:"input"`
+	Gas    hexutil.Uint64 `json:"gas"`
+	Value  *hexutil.Big   `json:"value"`
+}
+
+// SidecarEnd is the execution outcome sent to a sidecar after execution.
+type SidecarEnd struct {
+	Output  hexutil.Bytes  `json:"output"`
+	GasUsed hexutil.Uint64 `json:"gasUsed"`
+	Time    string         `json:"time"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// sidecarTracer streams execution events to an external process over JSON-RPC.
+// The sidecar must serve tracer_start, tracer_steps, tracer_end and
+// tracer_result, the latter returning the final trace result.
+type sidecarTracer struct {
+	client *rpc.Client
+	steps  []*SidecarStep
+
+	lock sync.Mutex
+	err  error // First failure or stop reason, aborting the trace
+}
+
+// newSidecarTracer connects to the tracer sidecar at the given endpoint.
+func newSidecarTracer(url string) (*sidecarTracer, error) {
+	client, err := rpc.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	return &sidecarTracer{client: client, steps: make([]*SidecarStep, 0, sidecarBatchSize)}, nil
+}
+
+// fail records the first error encountered, returning the effective one.
+func (t *sidecarTracer) fail(err error) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.err == nil {
+		t.err = err
+	}
+	return t.err
+}
+
+// failed returns the error aborting the trace, if any.
+func (t *sidecarTracer) failed() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.err
+}
+
+// CaptureStart implements vm.Tracer, sending the transaction context.
+func (t *sidecarTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	start := &SidecarStart{From: from, To: to, Create: create, Input: input, Gas: hexutil.Uint64(gas), Value: (*hexutil.Big)(value)}
+	if err := t.client.Call(nil, "tracer_start", start); err != nil {
+		return t.fail(err)
+	}
+	return nil
+}
+
+// CaptureState implements vm.Tracer, buffering the step for streaming.
+func (t *sidecarTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return t.capture(env, pc, op, gas, cost, memory, stack, contract, depth, err, false)
+}
+
+// CaptureFault implements vm.Tracer, buffering the faulting step for streaming.
+func (t *sidecarTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return t.capture(env, pc, op, gas, cost, memory, stack, contract, depth, err, true)
+}
+
+func (t *sidecarTracer) capture(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error, fault bool) error {
+	if failure := t.failed(); failure != nil {
+		env.Cancel()
+		return failure
+	}
+	step := &SidecarStep{
+		Pc:      pc,
+		Op:      op.String(),
+		Gas:     gas,
+		Cost:    cost,
+		Depth:   depth,
+		Stack:   make([]*hexutil.Big, len(stack.Data())),
+		MemSize: memory.Len(),
+		Address: contract.Address(),
+		Fault:   fault,
+	}
+	for i, item := range stack.Data() {
+		step.Stack[i] = (*hexutil.Big)(new(big.Int).Set(item))
+	}
+	if err != nil {
+		step.Error = err.Error()
+	}
+	t.steps = append(t.steps, step)
+	if len(t.steps) >= sidecarBatchSize {
+		return t.flush()
+	}
+	return nil
+}
+
+// flush streams the buffered steps to the sidecar.
+func (t *sidecarTracer) flush() error {
+	if len(t.steps) == 0 {
+		return nil
+	}
+	err := t.client.Call(nil, "tracer_steps", t.steps)
+	t.steps = t.steps[:0]
+	if err != nil {
+		return t.fail(err)
+	}
+	return nil
+}
+
+// CaptureEnd implements vm.Tracer, flushing the remaining steps and sending the
+// execution outcome.
+func (t *sidecarTracer) CaptureEnd(output []byte, gasUsed uint64, elapsed time.Duration, err error) error {
+	if ferr := t.flush(); ferr != nil {
+		return ferr
+	}
+	end := &SidecarEnd{Output: output, GasUsed: hexutil.Uint64(gasUsed), Time: elapsed.String()}
+	if err != nil {
+		end.Error = err.Error()
+	}
+	if err := t.client.Call(nil, "tracer_end", end); err != nil {
+		return t.fail(err)
+	}
+	return nil
+}
+
+// GetResult retrieves the trace result computed by the sidecar and closes the
+// connection.
+func (t *sidecarTracer) GetResult() (json.RawMessage, error) {
+	defer t.client.Close()
+
+	if err := t.failed(); err != nil {
+		return nil, err
+	}
+	var result json.RawMessage
+	if err := t.client.Call(&result, "tracer_result"); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Stop aborts the trace.
+func (t *sidecarTracer) Stop(err error) {
+	t.fail(err)
+}
@@ -0,0 +1,101 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/params"
+	"github.com/goola-team/goola/rpc"
+)
+
+// SidecarService is a tracer sidecar collecting the executed opcodes.
+type SidecarService struct {
+	started bool
+	ops     []string
+	ended   bool
+}
+
+func (s *SidecarService) Start(start SidecarStart) { s.started = true }
+func (s *SidecarService) End(end SidecarEnd)       { s.ended = true }
+
+func (s *SidecarService) Steps(steps []*SidecarStep) {
+	for _, step := range steps {
+		s.ops = append(s.ops, step.Op)
+	}
+}
+
+func (s *SidecarService) Result() string {
+	return strings.Join(s.ops, ",")
+}
+
+func TestSidecarTracer(t *testing.T) {
+	service := new(SidecarService)
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("tracer", service); err != nil {
+		t.Fatalf("failed to register sidecar: %v", err)
+	}
+	tracer := &sidecarTracer{client: rpc.DialInProc(server)}
+
+	env := vm.NewEVM(vm.Context{BlockNumber: big.NewInt(1)}, nil, params.TestChainConfig, vm.Config{Debug: true, Tracer: tracer})
+	contract := vm.NewContract(account{}, account{}, big.NewInt(0), 10000)
+	contract.Code = []byte{byte(vm.PUSH1), 0x1, byte(vm.PUSH1), 0x1, 0x0}
+
+	if err := tracer.CaptureStart(common.Address{}, common.Address{}, false, nil, 10000, big.NewInt(0)); err != nil {
+		t.Fatalf("failed to start trace: %v", err)
+	}
+	if _, err := env.Interpreter().Run(contract, []byte{}); err != nil {
+		t.Fatalf("failed to execute: %v", err)
+	}
+	if err := tracer.CaptureEnd(nil, 0, time.Millisecond, nil); err != nil {
+		t.Fatalf("failed to end trace: %v", err)
+	}
+	ret, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve result: %v", err)
+	}
+	if string(ret) != `"PUSH1,PUSH1,STOP"` {
+		t.Errorf("result mismatch: have %s, want %s", ret, `"PUSH1,PUSH1,STOP"`)
+	}
+	if !service.started || !service.ended {
+		t.Errorf("sidecar lifecycle incomplete: started %v, ended %v", service.started, service.ended)
+	}
+}
+
+// Tests that only configured external tracers can be selected.
+func TestExternalTracerSelection(t *testing.T) {
+	config := &ExternalConfig{Sidecars: map[string]string{}}
+	if _, err := config.NewExternal("plugin:counter"); err != errPluginsDisabled {
+		t.Errorf("plugin loaded without plugin dir: %v", err)
+	}
+	config.PluginDir = "plugins"
+	if _, err := config.NewExternal("plugin:../counter"); err != errInvalidPlugin {
+		t.Errorf("plugin outside plugin dir accepted: %v", err)
+	}
+	if _, err := config.NewExternal("sidecar:unknown"); err != errUnknownSidecar {
+		t.Errorf("unknown sidecar accepted: %v", err)
+	}
+	if IsExternal("{step: function() {}}") || !IsExternal("sidecar:x") || !IsExternal("plugin:x") {
+		t.Errorf("external tracer detection mismatch")
+	}
+}
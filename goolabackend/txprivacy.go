@@ -0,0 +1,160 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/p2p/discover"
+)
+
+// TxPrivacyConfig is the broadcast policy of the transactions sent from local
+// accounts. Instead of being pushed to every peer at once, these are forwarded
+// to a few random peers or to trusted relays after a random delay, making it
+// harder for the network to link the node's IP address to its accounts.
+type TxPrivacyConfig struct {
+	Enabled  bool             // Whether local transactions are forwarded privately
+	Fanout   int              // Random peers a local transaction is forwarded to
+	MaxDelay time.Duration    // Upper bound of the random delay before forwarding
+	Relays   []*discover.Node // Trusted nodes forwarded to instead of random peers while connected
+}
+
+// DefaultTxPrivacy is the private broadcast policy used when enabled without
+// further configuration.
+var DefaultTxPrivacy = TxPrivacyConfig{
+	Fanout:   2,
+	MaxDelay: 5 * time.Second,
+}
+
+// txPrivacy tracks the local transactions awaiting their private forwarding,
+// which must not leak to peers by any other route in the meantime.
+type txPrivacy struct {
+	config TxPrivacyConfig
+	relays map[discover.NodeID]bool
+	held   map[common.Hash]struct{}
+	lock   sync.Mutex
+}
+
+// newTxPrivacy creates a private transaction forwarder.
+func newTxPrivacy(config TxPrivacyConfig) *txPrivacy {
+	t := &txPrivacy{
+		config: config,
+		relays: make(map[discover.NodeID]bool),
+		held:   make(map[common.Hash]struct{}),
+	}
+	for _, node := range config.Relays {
+		t.relays[node.ID] = true
+	}
+	return t
+}
+
+// delay returns a random waiting time before forwarding a transaction.
+func (t *txPrivacy) delay() time.Duration {
+	if t.config.MaxDelay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(t.config.MaxDelay)))
+}
+
+// hold marks a transaction as awaiting its private forwarding.
+func (t *txPrivacy) hold(hash common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.held[hash] = struct{}{}
+}
+
+// release marks a transaction as forwarded, free to be shared with new peers.
+func (t *txPrivacy) release(hash common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.held, hash)
+}
+
+// holding returns whether a transaction still awaits its private forwarding.
+func (t *txPrivacy) holding(hash common.Hash) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	_, ok := t.held[hash]
+	return ok
+}
+
+// recipients selects the peers to forward a local transaction to out of the ones
+// not knowing it yet: all the connected relays, or if none is connected, a random
+// subset of the peers.
+func (t *txPrivacy) recipients(peers []*peer) []*peer {
+	var relays []*peer
+	for _, p := range peers {
+		if t.relays[p.ID()] {
+			relays = append(relays, p)
+		}
+	}
+	if len(relays) > 0 {
+		return relays
+	}
+	fanout := t.config.Fanout
+	if fanout <= 0 {
+		fanout = 1
+	}
+	if len(peers) <= fanout {
+		return peers
+	}
+	subset := make([]*peer, fanout)
+	for i, j := range rand.Perm(len(peers))[:fanout] {
+		subset[i] = peers[j]
+	}
+	return subset
+}
+
+// SetTxPrivacy sets the broadcast policy of the transactions sent from local
+// accounts, broadcasting them like any other if disabled.
+func (pm *ProtocolManager) SetTxPrivacy(config TxPrivacyConfig) {
+	if !config.Enabled {
+		pm.txPrivacy = nil
+		return
+	}
+	pm.txPrivacy = newTxPrivacy(config)
+}
+
+// relayTx forwards a local transaction to a few peers after a random delay,
+// withholding it from the initial transaction sync of new peers until then.
+func (pm *ProtocolManager) relayTx(tx *types.Transaction) {
+	privacy, hash := pm.txPrivacy, tx.Hash()
+
+	privacy.hold(hash)
+	time.AfterFunc(privacy.delay(), func() {
+		defer privacy.release(hash)
+
+		select {
+		case <-pm.quitSync:
+			return
+		default:
+		}
+		peers := privacy.recipients(pm.peers.PeersWithoutTx(hash))
+		for _, peer := range peers {
+			peer.SendTransactions(types.Transactions{tx})
+		}
+		log.Trace("Relayed local transaction", "hash", hash, "recipients", len(peers))
+	})
+}
@@ -0,0 +1,66 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolabackend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/p2p"
+	"github.com/goola-team/goola/p2p/discover"
+)
+
+// Tests that local transactions are forwarded to the connected relays if any,
+// otherwise to a random subset of the peers, and withheld until then.
+func TestTxPrivacyRecipients(t *testing.T) {
+	relay := &discover.Node{ID: discover.NodeID{0xff}}
+	privacy := newTxPrivacy(TxPrivacyConfig{Enabled: true, Fanout: 2, MaxDelay: time.Second, Relays: []*discover.Node{relay}})
+
+	var peers []*peer
+	for i := 0; i < 5; i++ {
+		peers = append(peers, newPeer(63, p2p.NewPeer(discover.NodeID{byte(i)}, "peer", nil), nil))
+	}
+	// Without the relay connected, a random subset of the fanout size is picked
+	picked := privacy.recipients(peers)
+	if len(picked) != 2 || picked[0] == picked[1] {
+		t.Fatalf("random recipients mismatch: have %d distinct of 2", len(picked))
+	}
+	if have := privacy.recipients(peers[:1]); len(have) != 1 {
+		t.Errorf("recipients among fewer peers than fanout: have %d, want 1", len(have))
+	}
+	// Once connected, only the relay is forwarded to
+	withRelay := append(peers, newPeer(63, p2p.NewPeer(relay.ID, "relay", nil), nil))
+	if have := privacy.recipients(withRelay); len(have) != 1 || have[0].ID() != relay.ID {
+		t.Errorf("relay recipients mismatch: have %v", have)
+	}
+	// Delays stay within the configured bound
+	for i := 0; i < 100; i++ {
+		if delay := privacy.delay(); delay < 0 || delay >= time.Second {
+			t.Fatalf("delay out of bounds: %v", delay)
+		}
+	}
+	hash := common.Hash{0x01}
+	privacy.hold(hash)
+	if !privacy.holding(hash) {
+		t.Errorf("held transaction not withheld")
+	}
+	privacy.release(hash)
+	if privacy.holding(hash) {
+		t.Errorf("released transaction still withheld")
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolaclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/goola-team/goola/rpc"
+)
+
+// Errors reported by the node when it refuses a transaction. The messages mirror
+// the ones produced by the transaction pool, so the same error may be compared
+// against regardless of whether it originated locally or remotely.
+var (
+	ErrNonceTooLow        = errors.New("nonce too low")
+	ErrNonceTooHigh       = errors.New("nonce too high")
+	ErrInsufficientFunds  = errors.New("insufficient funds for gas * price + value")
+	ErrKnownTransaction   = errors.New("known transaction")
+	ErrUnderpriced        = errors.New("transaction underpriced")
+	ErrReplaceUnderpriced = errors.New("replacement transaction underpriced")
+	ErrIntrinsicGas       = errors.New("intrinsic gas too low")
+	ErrGasLimit           = errors.New("exceeds block gas limit")
+	ErrNegativeValue      = errors.New("negative value")
+	ErrOversizedData      = errors.New("oversized data")
+	ErrInvalidSender      = errors.New("invalid sender")
+)
+
+// knownErrors lists the typed errors in matching order. Entries sharing a common
+// prefix must list the longer message first.
+var knownErrors = []error{
+	ErrReplaceUnderpriced,
+	ErrUnderpriced,
+	ErrNonceTooLow,
+	ErrNonceTooHigh,
+	ErrInsufficientFunds,
+	ErrKnownTransaction,
+	ErrIntrinsicGas,
+	ErrGasLimit,
+	ErrNegativeValue,
+	ErrOversizedData,
+	ErrInvalidSender,
+}
+
+// rejectReasons maps the reasons reported in the data of transaction rejection
+// errors to the typed errors.
+var rejectReasons = map[string]error{
+	"nonceTooLow":        ErrNonceTooLow,
+	"nonceTooHigh":       ErrNonceTooHigh,
+	"insufficientFunds":  ErrInsufficientFunds,
+	"knownTransaction":   ErrKnownTransaction,
+	"underpriced":        ErrUnderpriced,
+	"replaceUnderpriced": ErrReplaceUnderpriced,
+	"intrinsicGas":       ErrIntrinsicGas,
+	"gasLimit":           ErrGasLimit,
+	"negativeValue":      ErrNegativeValue,
+	"oversizedData":      ErrOversizedData,
+	"invalidSender":      ErrInvalidSender,
+}
+
+// matchError returns the typed error whose message prefixes msg, or nil.
+func matchError(msg string) error {
+	for _, known := range knownErrors {
+		if strings.HasPrefix(msg, known.Error()) {
+			return known
+		}
+	}
+	return nil
+}
+
+// errorFromMessage maps an error message returned by the node to one of the
+// typed errors, falling back to a plain error if the message is not recognised.
+func errorFromMessage(msg string) error {
+	if err := matchError(msg); err != nil {
+		return err
+	}
+	return errors.New(msg)
+}
+
+// errorCode returns the JSON-RPC error code of an error returned by the node,
+// or zero for transport and decoding failures.
+func errorCode(err error) int {
+	if rpcErr, ok := err.(rpc.Error); ok {
+		return rpcErr.ErrorCode()
+	}
+	return 0
+}
+
+// errorField returns a field of the data attached to an error returned by the
+// node, or an empty string if missing.
+func errorField(err error, field string) string {
+	dataErr, ok := err.(rpc.DataError)
+	if !ok {
+		return ""
+	}
+	data, _ := dataErr.ErrorData().(map[string]interface{})
+	value, _ := data[field].(string)
+	return value
+}
+
+// typedError converts an error returned by the node into its typed counterpart,
+// based on the rejection reason if reported and the message otherwise. Transport
+// and decoding failures are returned unmodified.
+func typedError(err error) error {
+	if _, ok := err.(rpc.Error); !ok {
+		return err
+	}
+	if errorCode(err) == rpc.ErrCodeTxRejected {
+		if known := rejectReasons[errorField(err, "reason")]; known != nil {
+			return known
+		}
+	}
+	if known := matchError(err.Error()); known != nil {
+		return known
+	}
+	return err
+}
+
+// IsPruned reports whether a call failed because the node pruned the historical
+// data it needed.
+func IsPruned(err error) bool {
+	return errorCode(err) == rpc.ErrCodePruned
+}
+
+// IsNotFound reports whether a call failed because the requested block,
+// transaction or receipt is unknown to the node.
+func IsNotFound(err error) bool {
+	return errorCode(err) == rpc.ErrCodeNotFound
+}
+
+// IsRetryable reports whether a failed call may succeed if attempted again,
+// possibly after adjusting the gas price. Transport failures and timeouts are
+// retryable, whereas errors rejected by the node on validity grounds are not.
+func IsRetryable(err error) bool {
+	switch err {
+	case nil:
+		return false
+	case ErrUnderpriced, ErrReplaceUnderpriced, context.DeadlineExceeded:
+		return true
+	case rpc.ErrClientQuit, context.Canceled:
+		return false
+	}
+	if matchError(err.Error()) == err {
+		return false
+	}
+	_, remote := err.(rpc.Error)
+	return !remote
+}
@@ -0,0 +1,100 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolaclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/internal/ethapi"
+	"github.com/goola-team/goola/rpc"
+)
+
+// RejectingService fails every submitted transaction with a preset error.
+type RejectingService struct {
+	err error
+}
+
+func (s *RejectingService) SendRawTransaction(raw hexutil.Bytes) (common.Hash, error) {
+	return common.Hash{}, s.err
+}
+
+// Tests that the typed errors stay in sync with the transaction pool messages.
+func TestTypedErrorMessages(t *testing.T) {
+	pairs := []struct{ local, remote error }{
+		{ErrNonceTooLow, core.ErrNonceTooLow},
+		{ErrNonceTooHigh, core.ErrNonceTooHigh},
+		{ErrInsufficientFunds, core.ErrInsufficientFunds},
+		{ErrUnderpriced, core.ErrUnderpriced},
+		{ErrReplaceUnderpriced, core.ErrReplaceUnderpriced},
+		{ErrIntrinsicGas, core.ErrIntrinsicGas},
+		{ErrGasLimit, core.ErrGasLimit},
+		{ErrNegativeValue, core.ErrNegativeValue},
+		{ErrOversizedData, core.ErrOversizedData},
+		{ErrInvalidSender, core.ErrInvalidSender},
+	}
+	for _, pair := range pairs {
+		if pair.local.Error() != pair.remote.Error() {
+			t.Errorf("message mismatch: have %q, want %q", pair.local, pair.remote)
+		}
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	tests := []struct {
+		remote    error
+		want      error
+		retryable bool
+	}{
+		{core.ErrNonceTooLow, ErrNonceTooLow, false},
+		{core.ErrInsufficientFunds, ErrInsufficientFunds, false},
+		{core.ErrReplaceUnderpriced, ErrReplaceUnderpriced, true},
+		{core.ErrUnderpriced, ErrUnderpriced, true},
+		{fmt.Errorf("known transaction: %x", common.Hash{1}), ErrKnownTransaction, false},
+		{&ethapi.TxRejectedError{Reason: "nonceTooLow", Err: errors.New("stale nonce")}, ErrNonceTooLow, false},
+		{errors.New("something else"), nil, false},
+	}
+	for i, tt := range tests {
+		server := rpc.NewServer()
+		if err := server.RegisterName("eth", &RejectingService{err: tt.remote}); err != nil {
+			t.Fatalf("test %d: failed to register service: %v", i, err)
+		}
+		client := NewClient(rpc.DialInProc(server))
+
+		err := client.SendTransaction(context.Background(), types.NewTransaction(0, common.Address{}, common.Big0, 0, common.Big0, 0, nil))
+		if tt.want != nil && err != tt.want {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+		if tt.want == nil && (err == nil || err.Error() != tt.remote.Error()) {
+			t.Errorf("test %d: unknown error mangled: have %v, want %v", i, err, tt.remote)
+		}
+		if IsRetryable(err) != tt.retryable {
+			t.Errorf("test %d: retryable mismatch: have %v, want %v", i, IsRetryable(err), tt.retryable)
+		}
+		client.Close()
+		server.Stop()
+	}
+	if !IsRetryable(context.DeadlineExceeded) {
+		t.Errorf("timeout not retryable")
+	}
+}
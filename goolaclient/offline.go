@@ -0,0 +1,132 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolaclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/goola-team/goola"
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/rlp"
+)
+
+// UnsignedTx is a transaction filled in by the node, awaiting an offline
+// signature of its sender.
+type UnsignedTx struct {
+	From    common.Address     // Account expected to sign the transaction
+	Tx      *types.Transaction // Transaction without signature
+	SigHash common.Hash        // Hash to sign for the bundle's chain
+}
+
+// UnsignedTxBundle is a batch of unsigned transactions exported for signing on
+// an air gapped machine.
+type UnsignedTxBundle struct {
+	ChainID      *big.Int
+	Transactions []*UnsignedTx
+}
+
+// Sign signs all transactions of the bundle using the given signer and returns
+// them in the same order, ready to be imported back into a node.
+func (b *UnsignedTxBundle) Sign(sign func(from common.Address, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)) ([]*types.Transaction, error) {
+	signed := make([]*types.Transaction, len(b.Transactions))
+	for i, utx := range b.Transactions {
+		tx, err := sign(utx.From, utx.Tx, b.ChainID)
+		if err != nil {
+			return nil, err
+		}
+		signed[i] = tx
+	}
+	return signed, nil
+}
+
+// SignedTxResult is the outcome of importing a single offline signed transaction.
+type SignedTxResult struct {
+	Hash common.Hash
+	From common.Address
+	Err  error // Reason the transaction pool rejected the transaction, if any
+}
+
+// ExportUnsignedTransactions asks the node to fill in the missing fields (nonce,
+// gas, gas price) of a batch of transactions and returns them unsigned.
+func (ec *Client) ExportUnsignedTransactions(ctx context.Context, msgs []goola.CallMsg) (*UnsignedTxBundle, error) {
+	args := make([]interface{}, len(msgs))
+	for i, msg := range msgs {
+		args[i] = toCallArg(msg)
+	}
+	var result struct {
+		ChainID      *hexutil.Big `json:"chainId"`
+		Transactions []struct {
+			Args struct {
+				From common.Address `json:"from"`
+			} `json:"args"`
+			Raw     hexutil.Bytes `json:"raw"`
+			SigHash common.Hash   `json:"sigHash"`
+		} `json:"transactions"`
+	}
+	if err := ec.callContext(ctx, &result, "goolabackend_exportUnsignedTransactions", args); err != nil {
+		return nil, err
+	}
+	if result.ChainID == nil {
+		return nil, errors.New("missing chain id in exported bundle")
+	}
+	bundle := &UnsignedTxBundle{
+		ChainID:      (*big.Int)(result.ChainID),
+		Transactions: make([]*UnsignedTx, len(result.Transactions)),
+	}
+	for i, exported := range result.Transactions {
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(exported.Raw, tx); err != nil {
+			return nil, err
+		}
+		bundle.Transactions[i] = &UnsignedTx{From: exported.Args.From, Tx: tx, SigHash: exported.SigHash}
+	}
+	return bundle, nil
+}
+
+// ImportSignedTransactions submits a batch of offline signed transactions to the
+// node. The node rejects the whole batch if any transaction is invalid, and
+// otherwise reports the transaction pool's verdict on each one.
+func (ec *Client) ImportSignedTransactions(ctx context.Context, txs []*types.Transaction) ([]*SignedTxResult, error) {
+	batch := make([]hexutil.Bytes, len(txs))
+	for i, tx := range txs {
+		data, err := rlp.EncodeToBytes(tx)
+		if err != nil {
+			return nil, err
+		}
+		batch[i] = data
+	}
+	var result []struct {
+		Hash  common.Hash    `json:"hash"`
+		From  common.Address `json:"from"`
+		Error string         `json:"error"`
+	}
+	if err := ec.callContext(ctx, &result, "goolabackend_importSignedTransactions", batch); err != nil {
+		return nil, err
+	}
+	imported := make([]*SignedTxResult, len(result))
+	for i, res := range result {
+		imported[i] = &SignedTxResult{Hash: res.Hash, From: res.From}
+		if res.Error != "" {
+			imported[i].Err = errorFromMessage(res.Error)
+		}
+	}
+	return imported, nil
+}
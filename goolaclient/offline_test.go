@@ -0,0 +1,118 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolaclient
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola"
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/rlp"
+	"github.com/goola-team/goola/rpc"
+)
+
+// OfflineService is a minimal node side of the offline signing workflow.
+type OfflineService struct {
+	chainID *big.Int
+	signer  types.Signer
+}
+
+func (s *OfflineService) ExportUnsignedTransactions(batch []map[string]interface{}) (map[string]interface{}, error) {
+	txs := make([]map[string]interface{}, len(batch))
+	for i, args := range batch {
+		tx := types.NewTransaction(uint64(i), common.Address{0x02}, big.NewInt(1), 21000, big.NewInt(1), types.TxTypeTransfer, nil)
+		raw, err := rlp.EncodeToBytes(tx)
+		if err != nil {
+			return nil, err
+		}
+		txs[i] = map[string]interface{}{
+			"args":    map[string]interface{}{"from": args["from"]},
+			"raw":     hexutil.Bytes(raw),
+			"sigHash": s.signer.Hash(tx),
+		}
+	}
+	return map[string]interface{}{"chainId": (*hexutil.Big)(s.chainID), "transactions": txs}, nil
+}
+
+func (s *OfflineService) ImportSignedTransactions(batch []hexutil.Bytes) ([]map[string]interface{}, error) {
+	results := make([]map[string]interface{}, len(batch))
+	for i, raw := range batch {
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(raw, tx); err != nil {
+			return nil, err
+		}
+		from, err := types.Sender(s.signer, tx)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = map[string]interface{}{"hash": tx.Hash(), "from": from}
+	}
+	return results, nil
+}
+
+// Tests that transactions exported for offline signing can be signed and
+// imported back, retaining their senders.
+func TestOfflineSigning(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	chainID := big.NewInt(1337)
+	service := &OfflineService{chainID: chainID, signer: types.NewEIP155Signer(chainID)}
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("goolabackend", service); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	rpcClient := rpc.DialInProc(server)
+	defer rpcClient.Close()
+	client := NewClient(rpcClient)
+
+	to := common.Address{0x02}
+	msgs := []goola.CallMsg{{From: addr, To: &to, Value: big.NewInt(1)}, {From: addr, To: &to, Value: big.NewInt(2)}}
+
+	bundle, err := client.ExportUnsignedTransactions(context.Background(), msgs)
+	if err != nil {
+		t.Fatalf("failed to export transactions: %v", err)
+	}
+	if bundle.ChainID.Cmp(chainID) != 0 || len(bundle.Transactions) != len(msgs) {
+		t.Fatalf("exported bundle mismatch: chain %v, %d transactions", bundle.ChainID, len(bundle.Transactions))
+	}
+	signed, err := bundle.Sign(func(from common.Address, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+		if from != addr {
+			t.Fatalf("signer mismatch: have %x, want %x", from, addr)
+		}
+		return types.SignTx(tx, types.NewEIP155Signer(chainID), key)
+	})
+	if err != nil {
+		t.Fatalf("failed to sign bundle: %v", err)
+	}
+	results, err := client.ImportSignedTransactions(context.Background(), signed)
+	if err != nil {
+		t.Fatalf("failed to import transactions: %v", err)
+	}
+	for i, res := range results {
+		if res.Hash != signed[i].Hash() || res.From != addr || res.Err != nil {
+			t.Errorf("result %d mismatch: %+v", i, res)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolaclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rpc"
+)
+
+// ErrNoEndpoints is returned when a pool is created without any endpoint.
+var ErrNoEndpoints = errors.New("no rpc endpoints")
+
+// PoolConfig contains the tuning parameters of a multi-endpoint client.
+type PoolConfig struct {
+	Timeout        time.Duration // Per-call timeout applied on every endpoint attempt
+	Retries        int           // Number of additional endpoints tried after a failed call
+	HealthInterval time.Duration // Interval between endpoint health checks (0 = disabled)
+	MaxBlockLag    uint64        // Blocks an endpoint may trail the best one before being skipped
+}
+
+// DefaultPoolConfig contains the default settings used by DialPool.
+var DefaultPoolConfig = PoolConfig{
+	Timeout:        10 * time.Second,
+	Retries:        2,
+	HealthInterval: 15 * time.Second,
+	MaxBlockLag:    8,
+}
+
+// DialPool connects a client to several RPC endpoints, balancing calls across
+// the healthy ones and failing over whenever an endpoint stops responding.
+// Subscriptions stick to the endpoint they were created on.
+func DialPool(rawurls []string) (*Client, error) {
+	return DialPoolWithConfig(rawurls, DefaultPoolConfig)
+}
+
+// DialPoolWithConfig is like DialPool but allows tuning the pool behaviour.
+func DialPoolWithConfig(rawurls []string, config PoolConfig) (*Client, error) {
+	if len(rawurls) == 0 {
+		return nil, ErrNoEndpoints
+	}
+	clients := make([]*rpc.Client, 0, len(rawurls))
+	for _, rawurl := range rawurls {
+		c, err := rpc.Dial(rawurl)
+		if err != nil {
+			for _, c := range clients {
+				c.Close()
+			}
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+	return &Client{newPool(rawurls, clients, config)}, nil
+}
+
+// poolEndpoint is a single RPC connection tracked by a pool.
+type poolEndpoint struct {
+	url    string
+	client *rpc.Client
+
+	healthy int32  // Atomic flag whether the endpoint is currently usable
+	head    uint64 // Atomic block number last reported by the endpoint
+}
+
+func (e *poolEndpoint) isHealthy() bool {
+	return atomic.LoadInt32(&e.healthy) == 1
+}
+
+func (e *poolEndpoint) setHealthy(healthy bool) {
+	var flag int32
+	if healthy {
+		flag = 1
+	}
+	if atomic.SwapInt32(&e.healthy, flag) != flag {
+		log.Debug("RPC endpoint health changed", "url", e.url, "healthy", healthy)
+	}
+}
+
+// pool is an rpcClient spreading calls across multiple endpoints.
+type pool struct {
+	config    PoolConfig
+	endpoints []*poolEndpoint
+	next      uint32 // Round robin counter for endpoint selection
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+func newPool(urls []string, clients []*rpc.Client, config PoolConfig) *pool {
+	p := &pool{
+		config: config,
+		quit:   make(chan struct{}),
+	}
+	for i, c := range clients {
+		p.endpoints = append(p.endpoints, &poolEndpoint{url: urls[i], client: c, healthy: 1})
+	}
+	if config.HealthInterval > 0 {
+		p.wg.Add(1)
+		go p.healthLoop()
+	}
+	return p
+}
+
+// candidates returns the endpoints in the order they should be attempted: the
+// healthy ones first, rotated round robin, followed by the unhealthy ones as a
+// last resort.
+func (p *pool) candidates() []*poolEndpoint {
+	start := int(atomic.AddUint32(&p.next, 1)-1) % len(p.endpoints)
+
+	healthy := make([]*poolEndpoint, 0, len(p.endpoints))
+	var unhealthy []*poolEndpoint
+	for i := range p.endpoints {
+		e := p.endpoints[(start+i)%len(p.endpoints)]
+		if e.isHealthy() {
+			healthy = append(healthy, e)
+		} else {
+			unhealthy = append(unhealthy, e)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// attempts returns the maximum number of endpoints a single call may use.
+func (p *pool) attempts() int {
+	if n := p.config.Retries + 1; n < len(p.endpoints) {
+		return n
+	}
+	return len(p.endpoints)
+}
+
+// CallContext performs the call on a healthy endpoint, retrying on the next
+// one if the endpoint failed to deliver a response. Errors returned by the
+// remote node itself are passed through without retrying.
+func (p *pool) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	var err error
+	for _, e := range p.candidates()[:p.attempts()] {
+		callCtx, cancel := ctx, context.CancelFunc(func() {})
+		if p.config.Timeout > 0 {
+			callCtx, cancel = context.WithTimeout(ctx, p.config.Timeout)
+		}
+		err = e.client.CallContext(callCtx, result, method, args...)
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(rpc.Error); ok {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Debug("RPC endpoint call failed", "url", e.url, "method", method, "err", err)
+		e.setHealthy(false)
+	}
+	return err
+}
+
+// EthSubscribe creates a subscription on the first healthy endpoint that
+// supports notifications. The subscription stays bound to that endpoint; when
+// it fails, its error channel fires and resubscribing picks a fresh endpoint.
+func (p *pool) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*rpc.ClientSubscription, error) {
+	var err error
+	for _, e := range p.candidates() {
+		var sub *rpc.ClientSubscription
+		if sub, err = e.client.EthSubscribe(ctx, channel, args...); err == nil {
+			return sub, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != rpc.ErrNotificationsUnsupported {
+			e.setHealthy(false)
+		}
+	}
+	return nil, err
+}
+
+// Close stops the health checks and disconnects all endpoints.
+func (p *pool) Close() {
+	p.once.Do(func() {
+		close(p.quit)
+		p.wg.Wait()
+		for _, e := range p.endpoints {
+			e.client.Close()
+		}
+	})
+}
+
+// healthLoop periodically probes all endpoints.
+func (p *pool) healthLoop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.config.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.checkHealth()
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// checkHealth queries the head of every endpoint, marking the unresponsive ones
+// and those lagging too far behind the best known head as unhealthy.
+func (p *pool) checkHealth() {
+	var (
+		wg   sync.WaitGroup
+		best uint64
+		mu   sync.Mutex
+		live = make([]bool, len(p.endpoints))
+	)
+	for i, e := range p.endpoints {
+		wg.Add(1)
+		go func(i int, e *poolEndpoint) {
+			defer wg.Done()
+
+			timeout := p.config.Timeout
+			if timeout <= 0 {
+				timeout = p.config.HealthInterval
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+
+			var head hexutil.Uint64
+			if err := e.client.CallContext(ctx, &head, "eth_blockNumber"); err != nil {
+				return
+			}
+			atomic.StoreUint64(&e.head, uint64(head))
+
+			mu.Lock()
+			live[i] = true
+			if uint64(head) > best {
+				best = uint64(head)
+			}
+			mu.Unlock()
+		}(i, e)
+	}
+	wg.Wait()
+
+	for i, e := range p.endpoints {
+		switch {
+		case !live[i]:
+			e.setHealthy(false)
+		case best > atomic.LoadUint64(&e.head)+p.config.MaxBlockLag:
+			e.setHealthy(false)
+		default:
+			e.setHealthy(true)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolaclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/rpc"
+)
+
+// PoolService is a minimal eth namespace reporting a fixed head.
+type PoolService struct {
+	head uint64
+}
+
+func (s *PoolService) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(s.head)
+}
+
+func (s *PoolService) Fail() error {
+	return errors.New("application failure")
+}
+
+func newPoolServer(t *testing.T, head uint64) (*rpc.Server, *rpc.Client) {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", &PoolService{head: head}); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	return server, rpc.DialInProc(server)
+}
+
+func TestPoolFailover(t *testing.T) {
+	down, downClient := newPoolServer(t, 10)
+	up, upClient := newPoolServer(t, 10)
+	defer up.Stop()
+
+	// Close the first endpoint's connection so calls on it fail at the transport
+	downClient.Close()
+	down.Stop()
+
+	p := newPool([]string{"down", "up"}, []*rpc.Client{downClient, upClient}, PoolConfig{Timeout: time.Second, Retries: 1})
+	defer p.Close()
+
+	for i := 0; i < 4; i++ {
+		var head hexutil.Uint64
+		if err := p.CallContext(context.Background(), &head, "eth_blockNumber"); err != nil {
+			t.Fatalf("call %d: failed to fail over: %v", i, err)
+		}
+		if head != 10 {
+			t.Fatalf("call %d: head mismatch: have %d, want 10", i, head)
+		}
+	}
+	if p.endpoints[0].isHealthy() {
+		t.Errorf("failing endpoint still marked healthy")
+	}
+	// Errors returned by the node itself must not be retried or penalised
+	if err := p.CallContext(context.Background(), nil, "eth_fail"); err == nil {
+		t.Fatalf("application error swallowed")
+	} else if _, ok := err.(rpc.Error); !ok {
+		t.Fatalf("application error type mismatch: %T", err)
+	}
+	if !p.endpoints[1].isHealthy() {
+		t.Errorf("application error marked endpoint unhealthy")
+	}
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	synced, syncedClient := newPoolServer(t, 100)
+	defer synced.Stop()
+	lagging, laggingClient := newPoolServer(t, 50)
+	defer lagging.Stop()
+
+	p := newPool([]string{"synced", "lagging"}, []*rpc.Client{syncedClient, laggingClient}, PoolConfig{Timeout: time.Second, MaxBlockLag: 8})
+	defer p.Close()
+
+	p.checkHealth()
+	if !p.endpoints[0].isHealthy() {
+		t.Errorf("synced endpoint marked unhealthy")
+	}
+	if p.endpoints[1].isHealthy() {
+		t.Errorf("lagging endpoint marked healthy")
+	}
+	// Healthy endpoints are always preferred, regardless of the rotation
+	for i := 0; i < 3; i++ {
+		if c := p.candidates(); c[0] != p.endpoints[0] {
+			t.Fatalf("rotation %d: lagging endpoint preferred", i)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolaclient
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/goola-team/goola"
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+)
+
+// RawBlockByNumber returns the consensus RLP encoding of a block from the current
+// canonical chain, exactly as stored by the node. If number is nil, the latest
+// known block is returned.
+func (ec *Client) RawBlockByNumber(ctx context.Context, number *big.Int) ([]byte, error) {
+	var raw hexutil.Bytes
+	if err := ec.callContext(ctx, &raw, "debug_getRawBlockByNumber", toBlockNumArg(number)); err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, goola.NotFound
+	}
+	return raw, nil
+}
+
+// RawReceiptsByHash returns the consensus RLP encoding of the receipt list of the
+// block with the given hash.
+func (ec *Client) RawReceiptsByHash(ctx context.Context, hash common.Hash) ([]byte, error) {
+	var raw hexutil.Bytes
+	if err := ec.callContext(ctx, &raw, "debug_getRawReceipts", hash); err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, goola.NotFound
+	}
+	return raw, nil
+}
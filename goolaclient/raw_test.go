@@ -0,0 +1,104 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolaclient
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/internal/ethapi"
+	"github.com/goola-team/goola/rlp"
+	"github.com/goola-team/goola/rpc"
+)
+
+// rawBackend is a backend serving a single block and its receipts, any other
+// call panics.
+type rawBackend struct {
+	ethapi.Backend
+	block    *types.Block
+	receipts types.Receipts
+}
+
+func (b *rawBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	if number == rpc.LatestBlockNumber || uint64(number) == b.block.NumberU64() {
+		return b.block, nil
+	}
+	return nil, nil
+}
+
+func (b *rawBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	if hash == b.block.Hash() {
+		return b.block, nil
+	}
+	return nil, nil
+}
+
+func (b *rawBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.receipts, nil
+}
+
+// Tests that raw blocks and receipts are delivered in their consensus encoding.
+func TestRawBlockAndReceipts(t *testing.T) {
+	tx := types.NewTransaction(0, common.Address{0x01}, big.NewInt(1), 21000, big.NewInt(1), types.TxTypeTransfer, nil)
+	receipt := types.NewReceipt(common.Hash{0x02}.Bytes(), false, 21000)
+	receipt.Logs = []*types.Log{{Address: common.Address{0x03}, Data: []byte{0x04}}}
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+	block := types.NewBlock(&types.Header{Number: big.NewInt(7)}, []*types.Transaction{tx}, []*types.Receipt{receipt})
+	backend := &rawBackend{block: block, receipts: types.Receipts{receipt}}
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("debug", ethapi.NewPublicDebugAPI(backend)); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	client := NewClient(rpc.DialInProc(server))
+	defer client.Close()
+
+	raw, err := client.RawBlockByNumber(context.Background(), big.NewInt(7))
+	if err != nil {
+		t.Fatalf("failed to retrieve raw block: %v", err)
+	}
+	if want, _ := rlp.EncodeToBytes(block); string(raw) != string(want) {
+		t.Errorf("raw block mismatch: have %x, want %x", raw, want)
+	}
+	decoded := new(types.Block)
+	if err := rlp.DecodeBytes(raw, decoded); err != nil || decoded.Hash() != block.Hash() {
+		t.Errorf("raw block doesn't decode to the block: %v", err)
+	}
+	raw, err = client.RawReceiptsByHash(context.Background(), block.Hash())
+	if err != nil {
+		t.Fatalf("failed to retrieve raw receipts: %v", err)
+	}
+	var receipts types.Receipts
+	if err := rlp.DecodeBytes(raw, &receipts); err != nil {
+		t.Fatalf("failed to decode raw receipts: %v", err)
+	}
+	if len(receipts) != 1 || receipts[0].Bloom != receipt.Bloom || len(receipts[0].Logs) != 1 || receipts[0].Logs[0].Address != (common.Address{0x03}) {
+		t.Errorf("raw receipts mismatch: have %+v", receipts)
+	}
+	// Unknown blocks are reported as not found
+	if _, err := client.RawBlockByNumber(context.Background(), big.NewInt(8)); !IsNotFound(err) {
+		t.Errorf("missing block error mismatch: have %v, want not found", err)
+	}
+	if _, err := client.RawReceiptsByHash(context.Background(), common.Hash{0xff}); !IsNotFound(err) {
+		t.Errorf("missing receipts error mismatch: have %v, want not found", err)
+	}
+}
@@ -79,6 +79,7 @@ type Service struct {
 
 	pongCh chan struct{} // Pong notifications are fed into this channel
 	histCh chan []uint64 // History request block numbers are fed into this channel
+	quit   chan struct{} // Quit channel to terminate the reporting daemon
 }
 
 // New returns a monitoring service ready for stats reporting.
@@ -105,6 +106,7 @@ func New(url string, ethServ *goolabackend.FullGoola, lesServ *les.LightGoola) (
 		host:      parts[4],
 		pongCh:    make(chan struct{}),
 		histCh:    make(chan []uint64, 1),
+		quit:      make(chan struct{}),
 	}, nil
 }
 
@@ -127,6 +129,7 @@ func (s *Service) Start(server *p2p.Server) error {
 
 // Stop implements node.Service, terminating the monitoring and reporting daemon.
 func (s *Service) Stop() error {
+	close(s.quit)
 	log.Info("Stats daemon stopped")
 	return nil
 }
@@ -189,6 +192,8 @@ func (s *Service) loop() {
 				break HandleLoop
 			case <-headSub.Err():
 				break HandleLoop
+			case <-s.quit:
+				break HandleLoop
 			}
 		}
 		close(quitCh)
@@ -219,14 +224,18 @@ func (s *Service) loop() {
 		}
 		if err != nil {
 			log.Warn("Stats server unreachable", "err", err)
-			time.Sleep(10 * time.Second)
+			if !s.wait(quitCh, 10*time.Second) {
+				return
+			}
 			continue
 		}
 		// Authenticate the client with the server
 		if err = s.login(conn); err != nil {
 			log.Warn("Stats login failed", "err", err)
 			conn.Close()
-			time.Sleep(10 * time.Second)
+			if !s.wait(quitCh, 10*time.Second) {
+				return
+			}
 			continue
 		}
 		go s.readLoop(conn)
@@ -272,6 +281,17 @@ func (s *Service) loop() {
 	}
 }
 
+// wait blocks for the given duration before a reconnection attempt, returning
+// false if the daemon was terminated in the meantime.
+func (s *Service) wait(quitCh chan struct{}, timeout time.Duration) bool {
+	select {
+	case <-quitCh:
+		return false
+	case <-time.After(timeout):
+		return true
+	}
+}
+
 // readLoop loops as long as the connection is alive and retrieves data packets
 // from the network socket. If any of them match an active request, it forwards
 // it, if they themselves are requests it initiates a reply, and lastly it drops
@@ -615,25 +635,27 @@ func (s *Service) reportHistory(conn *websocket.Conn, list []uint64) error {
 // pendStats is the information to report about pending transactions.
 type pendStats struct {
 	Pending int `json:"pending"`
+	Queued  int `json:"queued"`
 }
 
 // reportPending retrieves the current number of pending transactions and reports
 // it to the stats server.
 func (s *Service) reportPending(conn *websocket.Conn) error {
 	// Retrieve the pending count from the local blockchain
-	var pending int
+	var pending, queued int
 	if s.fullGoola != nil {
-		pending, _ = s.fullGoola.TxPool().Stats()
+		pending, queued = s.fullGoola.TxPool().Stats()
 	} else {
 		pending = s.les.TxPool().Stats()
 	}
 	// Assemble the transaction stats and send it to the server
-	log.Trace("Sending pending transactions to goolastats", "count", pending)
+	log.Trace("Sending pending transactions to goolastats", "pending", pending, "queued", queued)
 
 	stats := map[string]interface{}{
 		"id": s.node,
 		"stats": &pendStats{
 			Pending: pending,
+			Queued:  queued,
 		},
 	}
 	report := map[string][]interface{}{
@@ -665,13 +687,13 @@ func (s *Service) reportStats(conn *websocket.Conn) error {
 		mining = s.fullGoola.Miner().Mining()
 
 		sync := s.fullGoola.Downloader().Progress()
-		syncing = s.fullGoola.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestBlock
+		syncing = s.fullGoola.BlockChain().CurrentHeader().Number.Uint64() < sync.HighestBlock
 
 		price, _ := s.fullGoola.ApiBackend.SuggestPrice(context.Background())
 		gasprice = int(price.Uint64())
 	} else {
 		sync := s.les.Downloader().Progress()
-		syncing = s.les.BlockChain().CurrentHeader().Number.Uint64() >= sync.HighestBlock
+		syncing = s.les.BlockChain().CurrentHeader().Number.Uint64() < sync.HighestBlock
 	}
 	// Assemble the node stats and send it to the server
 	log.Trace("Sending node details to goolastats")
@@ -0,0 +1,88 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package goolastats
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goola-team/goola/goolabackend"
+	"github.com/goola-team/goola/p2p"
+	"golang.org/x/net/websocket"
+)
+
+// newTestServer starts a monitoring server accepting logins with the given secret,
+// feeding the names of the nodes logged in into the given channel.
+func newTestServer(secret string, logins chan<- string) *httptest.Server {
+	return httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		var hello struct {
+			Emit []json.RawMessage `json:"emit"`
+		}
+		if err := websocket.JSON.Receive(conn, &hello); err != nil || len(hello.Emit) != 2 || string(hello.Emit[0]) != `"hello"` {
+			return
+		}
+		var auth authMsg
+		if err := json.Unmarshal(hello.Emit[1], &auth); err != nil {
+			return
+		}
+		if auth.Secret != secret {
+			websocket.JSON.Send(conn, map[string][]string{"emit": {"unauthorized"}})
+			return
+		}
+		logins <- auth.Id
+		websocket.JSON.Send(conn, map[string][]string{"emit": {"ready"}})
+	}))
+}
+
+// Tests that nodes log into the monitoring server with the name and secret of
+// their stats url, and that logins with the wrong secret are refused.
+func TestLogin(t *testing.T) {
+	logins := make(chan string, 1)
+	server := newTestServer("secret", logins)
+	defer server.Close()
+
+	p2pServer := &p2p.Server{Config: p2p.Config{
+		Protocols: []p2p.Protocol{{Name: "goolabackend", NodeInfo: func() interface{} { return &goolabackend.NodeInfo{Network: 1} }}},
+	}}
+	for _, tt := range []struct {
+		pass string
+		ok   bool
+	}{{"secret", true}, {"wrong", false}} {
+		s := &Service{server: p2pServer, node: "node", pass: tt.pass}
+
+		url := "ws://" + strings.TrimPrefix(server.URL, "http://")
+		conn, err := websocket.Dial(url, "", "http://localhost/")
+		if err != nil {
+			t.Fatalf("failed to connect to stats server: %v", err)
+		}
+		err = s.login(conn)
+		conn.Close()
+
+		if tt.ok {
+			if err != nil {
+				t.Fatalf("login with secret %q failed: %v", tt.pass, err)
+			}
+			if name := <-logins; name != "node" {
+				t.Errorf("login name mismatch: have %q, want %q", name, "node")
+			}
+		} else if err == nil {
+			t.Errorf("login with secret %q accepted", tt.pass)
+		}
+	}
+}
@@ -20,7 +20,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -38,14 +40,15 @@ type Environment struct {
 	Name                string // name of the environment
 	Repo                string // name of GitHub repo
 	Commit, Branch, Tag string // Git info
+	Date                string // Date of the commit as YYYYMMDD
 	Buildnum            string
 	IsPullRequest       bool
 	IsCronJob           bool
 }
 
 func (env Environment) String() string {
-	return fmt.Sprintf("%s env (commit:%s branch:%s tag:%s buildnum:%s pr:%t)",
-		env.Name, env.Commit, env.Branch, env.Tag, env.Buildnum, env.IsPullRequest)
+	return fmt.Sprintf("%s env (commit:%s date:%s branch:%s tag:%s buildnum:%s pr:%t)",
+		env.Name, env.Commit, env.Date, env.Branch, env.Tag, env.Buildnum, env.IsPullRequest)
 }
 
 // Env returns metadata about the current CI environment, falling back to LocalEnv
@@ -57,6 +60,7 @@ func Env() Environment {
 			Name:          "travis",
 			Repo:          os.Getenv("TRAVIS_REPO_SLUG"),
 			Commit:        os.Getenv("TRAVIS_COMMIT"),
+			Date:          getDate(os.Getenv("TRAVIS_COMMIT")),
 			Branch:        os.Getenv("TRAVIS_BRANCH"),
 			Tag:           os.Getenv("TRAVIS_TAG"),
 			Buildnum:      os.Getenv("TRAVIS_BUILD_NUMBER"),
@@ -68,6 +72,7 @@ func Env() Environment {
 			Name:          "appveyor",
 			Repo:          os.Getenv("APPVEYOR_REPO_NAME"),
 			Commit:        os.Getenv("APPVEYOR_REPO_COMMIT"),
+			Date:          getDate(os.Getenv("APPVEYOR_REPO_COMMIT")),
 			Branch:        os.Getenv("APPVEYOR_REPO_BRANCH"),
 			Tag:           os.Getenv("APPVEYOR_REPO_TAG_NAME"),
 			Buildnum:      os.Getenv("APPVEYOR_BUILD_NUMBER"),
@@ -92,6 +97,7 @@ func LocalEnv() Environment {
 	if env.Commit == "" {
 		env.Commit = readGitFile(head)
 	}
+	env.Date = getDate(env.Commit)
 	if env.Branch == "" {
 		if head != "HEAD" {
 			env.Branch = strings.TrimPrefix(head, "refs/heads/")
@@ -103,6 +109,20 @@ func LocalEnv() Environment {
 	return env
 }
 
+// getDate returns the date of a commit as YYYYMMDD, or an empty string if it's
+// unknown.
+func getDate(commit string) string {
+	if commit == "" {
+		return ""
+	}
+	out := strings.TrimSpace(RunGit("show", "-s", "--format=%ct", commit))
+	date, err := strconv.ParseInt(out, 10, 64)
+	if err != nil {
+		return ""
+	}
+	return time.Unix(date, 0).UTC().Format("20060102")
+}
+
 func firstLine(s string) string {
 	return strings.Split(s, "\n")[0]
 }
@@ -31,6 +31,7 @@ import (
 	"github.com/goola-team/goola/common/hexutil"
 	"github.com/goola-team/goola/common/math"
 	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/state"
 	"github.com/goola-team/goola/core/types"
 	"github.com/goola-team/goola/core/vm"
 	"github.com/goola-team/goola/crypto"
@@ -201,14 +202,16 @@ func (s *PublicAccountAPI) Accounts() []common.Address {
 type PrivateAccountAPI struct {
 	am        *accounts.Manager
 	nonceLock *AddrLocker
+	nonces    *NonceReserver
 	b         Backend
 }
 
 // NewPrivateAccountAPI create a new PrivateAccountAPI.
-func NewPrivateAccountAPI(b Backend, nonceLock *AddrLocker) *PrivateAccountAPI {
+func NewPrivateAccountAPI(b Backend, nonceLock *AddrLocker, nonces *NonceReserver) *PrivateAccountAPI {
 	return &PrivateAccountAPI{
 		am:        b.AccountManager(),
 		nonceLock: nonceLock,
+		nonces:    nonces,
 		b:         b,
 	}
 }
@@ -343,9 +346,13 @@ func (s *PrivateAccountAPI) signTransaction(ctx context.Context, args SendTxArgs
 		return nil, err
 	}
 	// Set some sanity defaults and terminate on failure
+	autoNonce := args.Nonce == nil
 	if err := args.setDefaults(ctx, s.b); err != nil {
 		return nil, err
 	}
+	if autoNonce {
+		*args.Nonce = hexutil.Uint64(s.nonces.avoid(args.From, uint64(*args.Nonce)))
+	}
 	// Assemble the transaction and sign with the wallet
 	tx := args.toTransaction()
 
@@ -474,12 +481,13 @@ func (s *PrivateAccountAPI) SignAndSendTransaction(ctx context.Context, args Sen
 // PublicBlockChainAPI provides an API to access the Goola blockchain.
 // It offers only methods that operate on public data that is freely available to anyone.
 type PublicBlockChainAPI struct {
-	b Backend
+	b     Backend
+	calls *callCache // Cache of the call results on sealed blocks, nil if disabled
 }
 
 // NewPublicBlockChainAPI creates a new Goola blockchain API.
 func NewPublicBlockChainAPI(b Backend) *PublicBlockChainAPI {
-	return &PublicBlockChainAPI{b}
+	return &PublicBlockChainAPI{b: b, calls: newCallCache(b.CallCacheConfig())}
 }
 
 // BlockNumber returns the block number of the chain head.
@@ -567,13 +575,37 @@ type CallArgs struct {
 }
 
 func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config) ([]byte, uint64, bool, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
-
+	defer func(start time.Time) {
+		rpc.RequestLogger(ctx).Debug("Executing EVM call finished", "runtime", time.Since(start))
+	}(time.Now())
+
+	addr := s.callSender(args)
+
+	// Serve calls on sealed blocks from the cache if they were already executed
+	cacheable := s.calls != nil && blockNr != rpc.PendingBlockNumber
+	if cacheable {
+		header, err := s.b.HeaderByNumber(ctx, blockNr)
+		if header == nil || err != nil {
+			return nil, 0, false, err
+		}
+		if res, ok := s.calls.get(callCacheKey(header.Hash(), addr, args, !vmCfg.DisableGasMetering)); ok {
+			return res.ret, res.gas, res.failed, nil
+		}
+	}
 	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
 		return nil, 0, false, err
 	}
-	// Set sender address or use a default if none specified
+	res, gas, failed, err := s.applyCall(ctx, addr, args, state, header, vmCfg)
+	if cacheable && err == nil {
+		s.calls.put(callCacheKey(header.Hash(), addr, args, !vmCfg.DisableGasMetering), res, gas, failed)
+	}
+	return res, gas, failed, err
+}
+
+// callSender returns the sender of a call, defaulting to the first local account
+// if none was specified.
+func (s *PublicBlockChainAPI) callSender(args CallArgs) common.Address {
 	addr := args.From
 	if addr == (common.Address{}) {
 		if wallets := s.b.AccountManager().Wallets(); len(wallets) > 0 {
@@ -582,6 +614,12 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 			}
 		}
 	}
+	return addr
+}
+
+// applyCall executes a call from the given sender on top of the given state,
+// modifying it.
+func (s *PublicBlockChainAPI) applyCall(ctx context.Context, addr common.Address, args CallArgs, state *state.StateDB, header *types.Header, vmCfg vm.Config) ([]byte, uint64, bool, error) {
 	// Set default gas & gas price if none were set
 	gas, gasPrice := uint64(args.Gas), args.GasPrice.ToInt()
 	if gas == 0 {
@@ -875,11 +913,12 @@ func newRPCTransactionFromBlockHash(b *types.Block, hash common.Hash) *RPCTransa
 type PublicTransactionPoolAPI struct {
 	b         Backend
 	nonceLock *AddrLocker
+	nonces    *NonceReserver
 }
 
 // NewPublicTransactionPoolAPI creates a new RPC service with methods specific for the transaction pool.
-func NewPublicTransactionPoolAPI(b Backend, nonceLock *AddrLocker) *PublicTransactionPoolAPI {
-	return &PublicTransactionPoolAPI{b, nonceLock}
+func NewPublicTransactionPoolAPI(b Backend, nonceLock *AddrLocker, nonces *NonceReserver) *PublicTransactionPoolAPI {
+	return &PublicTransactionPoolAPI{b, nonceLock, nonces}
 }
 
 // GetBlockTransactionCountByNumber returns the number of transactions in the block with the given block number.
@@ -975,11 +1014,11 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 func (s *PublicTransactionPoolAPI) GetTransactionReceipt(hash common.Hash) (map[string]interface{}, error) {
 	tx, blockHash, blockNumber, index := core.GetTransaction(s.b.ChainDb(), hash)
 	if tx == nil {
-		return nil, errors.New("unknown transaction")
+		return nil, &NotFoundError{Kind: "transaction", Message: "unknown transaction"}
 	}
 	receipt, _, _, _ := core.GetReceipt(s.b.ChainDb(), hash) // Old receipts don't have the lookup data available
 	if receipt == nil {
-		return nil, errors.New("unknown receipt")
+		return nil, &NotFoundError{Kind: "receipt", Message: "unknown receipt"}
 	}
 
 	var signer types.Signer = types.NewEIP155Signer(tx.ChainId())
@@ -1091,7 +1130,7 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 // submitTransaction is a helper function that submits tx to txPool and logs a message.
 func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
 	if err := b.SendTx(ctx, tx); err != nil {
-		return common.Hash{}, err
+		return common.Hash{}, newTxRejectedError(err)
 	}
 	if tx.To() == nil {
 		signer := types.MakeSigner(b.ChainConfig(), b.CurrentBlock().Number())
@@ -1100,9 +1139,9 @@ func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 			return common.Hash{}, err
 		}
 		addr := crypto.CreateAddress(from, tx.Nonce())
-		log.Info("Submitted contract creation", "fullhash", tx.Hash().Hex(), "contract", addr.Hex())
+		rpc.RequestLogger(ctx).Info("Submitted contract creation", "fullhash", tx.Hash().Hex(), "contract", addr.Hex())
 	} else {
-		log.Info("Submitted transaction", "fullhash", tx.Hash().Hex(), "recipient", tx.To())
+		rpc.RequestLogger(ctx).Info("Submitted transaction", "fullhash", tx.Hash().Hex(), "recipient", tx.To())
 	}
 	return tx.Hash(), nil
 }
@@ -1119,7 +1158,8 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 		return common.Hash{}, err
 	}
 
-	if args.Nonce == nil {
+	autoNonce := args.Nonce == nil
+	if autoNonce {
 		// Hold the addresse's mutex around signing to prevent concurrent assignment of
 		// the same nonce to multiple accounts.
 		s.nonceLock.LockAddr(args.From)
@@ -1130,6 +1170,9 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 	if err := args.setDefaults(ctx, s.b); err != nil {
 		return common.Hash{}, err
 	}
+	if autoNonce {
+		*args.Nonce = hexutil.Uint64(s.nonces.avoid(args.From, uint64(*args.Nonce)))
+	}
 	// Assemble the transaction and sign with the wallet
 	tx := args.toTransaction()
 
@@ -1268,7 +1311,7 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 		}
 	}
 
-	return common.Hash{}, fmt.Errorf("Transaction %#x not found", matchTx.Hash())
+	return common.Hash{}, &NotFoundError{Kind: "transaction", Message: fmt.Sprintf("Transaction %#x not found", matchTx.Hash())}
 }
 
 // PublicDebugAPI is the collection of Goola APIs exposed over the public
@@ -1287,7 +1330,7 @@ func NewPublicDebugAPI(b Backend) *PublicDebugAPI {
 func (api *PublicDebugAPI) GetBlockRlp(ctx context.Context, number uint64) (string, error) {
 	block, _ := api.b.BlockByNumber(ctx, rpc.BlockNumber(number))
 	if block == nil {
-		return "", fmt.Errorf("block #%d not found", number)
+		return "", &NotFoundError{Kind: "block", Message: fmt.Sprintf("block #%d not found", number)}
 	}
 	encoded, err := rlp.EncodeToBytes(block)
 	if err != nil {
@@ -1296,11 +1339,34 @@ func (api *PublicDebugAPI) GetBlockRlp(ctx context.Context, number uint64) (stri
 	return fmt.Sprintf("%x", encoded), nil
 }
 
+// GetRawBlockByNumber retrieves the consensus RLP encoding of a block, allowing
+// archival consumers to store the canonical form without re-serializing JSON.
+func (api *PublicDebugAPI) GetRawBlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	block, _ := api.b.BlockByNumber(ctx, blockNr)
+	if block == nil {
+		return nil, &NotFoundError{Kind: "block", Message: fmt.Sprintf("block #%d not found", blockNr)}
+	}
+	return rlp.EncodeToBytes(block)
+}
+
+// GetRawReceipts retrieves the consensus RLP encoding of the receipts of the
+// block with the given hash.
+func (api *PublicDebugAPI) GetRawReceipts(ctx context.Context, blockHash common.Hash) (hexutil.Bytes, error) {
+	if block, _ := api.b.GetBlock(ctx, blockHash); block == nil {
+		return nil, &NotFoundError{Kind: "block", Message: fmt.Sprintf("block %x not found", blockHash)}
+	}
+	receipts, err := api.b.GetReceipts(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(receipts)
+}
+
 // PrintBlock retrieves a block and returns its pretty printed form.
 func (api *PublicDebugAPI) PrintBlock(ctx context.Context, number uint64) (string, error) {
 	block, _ := api.b.BlockByNumber(ctx, rpc.BlockNumber(number))
 	if block == nil {
-		return "", fmt.Errorf("block #%d not found", number)
+		return "", &NotFoundError{Kind: "block", Message: fmt.Sprintf("block #%d not found", number)}
 	}
 	return block.String(), nil
 }
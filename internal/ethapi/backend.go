@@ -44,6 +44,7 @@ type Backend interface {
 	ChainDb() gooladb.Database
 	EventMux() *event.TypeMux
 	AccountManager() *accounts.Manager
+	CallCacheConfig() CallCacheConfig
 
 	// BlockChain API
 	SetHead(number uint64)
@@ -72,6 +73,7 @@ type Backend interface {
 
 func GetAPIs(apiBackend Backend) []rpc.API {
 	nonceLock := new(AddrLocker)
+	nonces := NewNonceReserver(apiBackend, nonceLock)
 	return []rpc.API{
 		{
 			Namespace: "goolabackend",
@@ -86,7 +88,7 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 		}, {
 			Namespace: "goolabackend",
 			Version:   "1.0",
-			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock),
+			Service:   NewPublicTransactionPoolAPI(apiBackend, nonceLock, nonces),
 			Public:    true,
 		}, {
 			Namespace: "txpool",
@@ -110,7 +112,17 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 		}, {
 			Namespace: "personal",
 			Version:   "1.0",
-			Service:   NewPrivateAccountAPI(apiBackend, nonceLock),
+			Service:   NewPrivateAccountAPI(apiBackend, nonceLock, nonces),
+			Public:    false,
+		}, {
+			Namespace: "personal",
+			Version:   "1.0",
+			Service:   NewPrivateNonceAPI(nonces),
+			Public:    false,
+		}, {
+			Namespace: "dpos",
+			Version:   "1.0",
+			Service:   NewPrivateDposAPI(apiBackend, nonceLock, nonces),
 			Public:    false,
 		},
 	}
@@ -0,0 +1,127 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/crypto/sha3"
+	"github.com/goola-team/goola/metrics"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+var (
+	callCacheHitMeter  = metrics.NewMeter("ethapi/callcache/hit")
+	callCacheMissMeter = metrics.NewMeter("ethapi/callcache/miss")
+)
+
+// CallCacheConfig are the limits of the cache of call results executed on
+// sealed blocks.
+type CallCacheConfig struct {
+	Size int           // Maximum number of results cached, 0 = caching disabled
+	TTL  time.Duration // Maximum time a result is cached for, 0 = unlimited
+}
+
+// DefaultCallCacheConfig is the call result cache configuration used by default.
+var DefaultCallCacheConfig = CallCacheConfig{
+	Size: 4096,
+	TTL:  10 * time.Minute,
+}
+
+// callResult is the cached outcome of a call.
+type callResult struct {
+	ret     []byte
+	gas     uint64
+	failed  bool
+	expires time.Time // Zero if the result never expires
+}
+
+// callCache caches the results of calls executed on sealed blocks. The state
+// of a block never changes, so a result is keyed by the hash of the block it was
+// executed on, along with everything else the execution depends on.
+type callCache struct {
+	cache *lru.Cache
+	ttl   time.Duration
+}
+
+// newCallCache creates a call result cache, or returns nil if caching is
+// disabled.
+func newCallCache(config CallCacheConfig) *callCache {
+	if config.Size <= 0 {
+		return nil
+	}
+	cache, _ := lru.New(config.Size)
+	return &callCache{cache: cache, ttl: config.TTL}
+}
+
+// callCacheKey derives the cache key of a call executed on the given block.
+func callCacheKey(block common.Hash, from common.Address, args CallArgs, metered bool) common.Hash {
+	var (
+		hasher = sha3.NewKeccak256()
+		enc    [8]byte
+		key    common.Hash
+	)
+	hasher.Write(block[:])
+	hasher.Write(from[:])
+	if args.To != nil {
+		hasher.Write(args.To[:])
+	} else {
+		hasher.Write([]byte{0})
+	}
+	binary.BigEndian.PutUint64(enc[:], uint64(args.Gas))
+	hasher.Write(enc[:])
+	binary.BigEndian.PutUint64(enc[:], uint64(args.TxType))
+	hasher.Write(enc[:])
+	if metered {
+		hasher.Write([]byte{1})
+	} else {
+		hasher.Write([]byte{0})
+	}
+	// Length prefix the variable sized fields so they can't be confused
+	for _, field := range [][]byte{args.GasPrice.ToInt().Bytes(), args.Value.ToInt().Bytes(), args.Data} {
+		binary.BigEndian.PutUint64(enc[:], uint64(len(field)))
+		hasher.Write(enc[:])
+		hasher.Write(field)
+	}
+	hasher.Sum(key[:0])
+	return key
+}
+
+// get retrieves a cached call result, if available and not yet expired.
+func (c *callCache) get(key common.Hash) (*callResult, bool) {
+	if cached, ok := c.cache.Get(key); ok {
+		res := cached.(*callResult)
+		if res.expires.IsZero() || time.Now().Before(res.expires) {
+			callCacheHitMeter.Mark(1)
+			return res, true
+		}
+		c.cache.Remove(key)
+	}
+	callCacheMissMeter.Mark(1)
+	return nil, false
+}
+
+// put caches the result of a call.
+func (c *callCache) put(key common.Hash, ret []byte, gas uint64, failed bool) {
+	res := &callResult{ret: common.CopyBytes(ret), gas: gas, failed: failed}
+	if c.ttl > 0 {
+		res.expires = time.Now().Add(c.ttl)
+	}
+	c.cache.Add(key, res)
+}
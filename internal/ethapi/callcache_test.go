@@ -0,0 +1,153 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/common/math"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
+	"github.com/goola-team/goola/rpc"
+)
+
+// callBackend is a backend serving a few sealed states, counting the number of
+// calls actually executed. Any other call panics.
+type callBackend struct {
+	Backend
+	sdb     state.Database
+	headers map[rpc.BlockNumber]*types.Header
+	execs   int32
+}
+
+// slotReader is contract code returning its first storage slot.
+//
+// PUSH1 0 SLOAD PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+var slotReader = common.FromHex("0x60005460005260206000f3")
+
+// newCallBackend creates a backend with a contract of the given code, whose first
+// storage slot holds the block number in every block.
+func newCallBackend(contract common.Address, code []byte, blocks int) *callBackend {
+	db, _ := gooladb.NewMemDatabase()
+	b := &callBackend{sdb: state.NewDatabase(db), headers: make(map[rpc.BlockNumber]*types.Header)}
+
+	for i := 1; i <= blocks; i++ {
+		statedb, _ := state.New(common.Hash{}, b.sdb)
+		statedb.SetCode(contract, code)
+		statedb.SetState(contract, common.Hash{}, common.BigToHash(big.NewInt(int64(i))))
+		root, _ := statedb.Commit(false)
+		statedb.Database().TrieDB().Commit(root, false)
+
+		header := &types.Header{Number: big.NewInt(int64(i)), Time: big.NewInt(int64(i)), GasLimit: params.GenesisGasLimit, Root: root}
+		b.headers[rpc.BlockNumber(i)] = header
+	}
+	b.headers[rpc.PendingBlockNumber] = b.headers[rpc.BlockNumber(blocks)]
+	return b
+}
+
+func (b *callBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	return b.headers[blockNr], nil
+}
+
+func (b *callBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	header := b.headers[blockNr]
+	statedb, err := state.New(header.Root, b.sdb)
+	return statedb, header, err
+}
+
+func (b *callBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header, vmCfg vm.Config) (*vm.EVM, func() error, error) {
+	atomic.AddInt32(&b.execs, 1)
+	state.SetBalance(msg.From(), math.MaxBig256)
+	context := core.NewEVMContext(msg, header, nil, &common.Address{})
+	return vm.NewEVM(context, state, params.TestChainConfig, vmCfg), func() error { return nil }, nil
+}
+
+// Tests that calls on sealed blocks are served from the cache, keyed by the
+// block and the call itself, while pending calls are always executed.
+func TestCallCache(t *testing.T) {
+	contract := common.Address{0xcc}
+	backend := newCallBackend(contract, slotReader, 2)
+	api := &PublicBlockChainAPI{b: backend, calls: newCallCache(CallCacheConfig{Size: 16})}
+
+	call := func(args CallArgs, blockNr rpc.BlockNumber, want int64, execs int32) {
+		t.Helper()
+
+		ret, err := api.Call(context.Background(), args, blockNr)
+		if err != nil {
+			t.Fatalf("block %d: call failed: %v", blockNr, err)
+		}
+		if have := new(big.Int).SetBytes(ret); have.Int64() != want {
+			t.Errorf("block %d: result mismatch: have %v, want %v", blockNr, have, want)
+		}
+		if backend.execs != execs {
+			t.Errorf("block %d: executions mismatch: have %d, want %d", blockNr, backend.execs, execs)
+		}
+	}
+	args := CallArgs{From: common.Address{0x01}, To: &contract, TxType: types.TxTypeContract}
+
+	call(args, 1, 1, 1)
+	call(args, 1, 1, 1) // Cached
+	call(args, 2, 2, 2) // Different block
+	call(args, 2, 2, 2) // Cached
+
+	other := args
+	other.Data = hexutil.Bytes{0x01}
+	call(other, 1, 1, 3) // Different call data
+	other = args
+	other.From = common.Address{0x02}
+	call(other, 1, 1, 4) // Different sender
+	call(other, 1, 1, 4) // Cached
+
+	call(args, rpc.PendingBlockNumber, 2, 5) // Pending calls are never cached
+	call(args, rpc.PendingBlockNumber, 2, 6)
+
+	// Disabled caching executes every call
+	api.calls = newCallCache(CallCacheConfig{})
+	if api.calls != nil {
+		t.Fatalf("call cache created with zero size")
+	}
+	call(args, 1, 1, 7)
+	call(args, 1, 1, 8)
+}
+
+// Tests that cached call results expire after their lifetime.
+func TestCallCacheExpiry(t *testing.T) {
+	cache := newCallCache(CallCacheConfig{Size: 16, TTL: 50 * time.Millisecond})
+
+	key := callCacheKey(common.Hash{0x01}, common.Address{0x01}, CallArgs{}, true)
+	cache.put(key, []byte{0x01}, 21000, false)
+	if res, ok := cache.get(key); !ok || res.gas != 21000 || len(res.ret) != 1 {
+		t.Fatalf("cached result mismatch: have %v (%v)", res, ok)
+	}
+	if _, ok := cache.get(callCacheKey(common.Hash{0x01}, common.Address{0x01}, CallArgs{}, false)); ok {
+		t.Fatalf("unmetered call served from metered result")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if res, ok := cache.get(key); ok {
+		t.Fatalf("expired result served: %v", res)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/consensus/dpos"
+	"github.com/goola-team/goola/core/types"
+)
+
+// PrivateDposAPI submits the delegate registry actions of the accounts managed
+// by the node, so they can vote without crafting vote transactions themselves.
+type PrivateDposAPI struct {
+	txs *PublicTransactionPoolAPI
+}
+
+// NewPrivateDposAPI creates a new RPC service sending vote transactions, sharing
+// the nonce bookkeeping of the transaction pool API.
+func NewPrivateDposAPI(b Backend, nonceLock *AddrLocker, nonces *NonceReserver) *PrivateDposAPI {
+	return &PrivateDposAPI{txs: NewPublicTransactionPoolAPI(b, nonceLock, nonces)}
+}
+
+// Vote sends a transaction from an unlocked account backing the given delegate,
+// replacing any earlier vote of the account.
+func (s *PrivateDposAPI) Vote(ctx context.Context, from common.Address, delegate common.Address) (common.Hash, error) {
+	return s.send(ctx, from, dpos.VoteData(delegate))
+}
+
+// Unvote sends a transaction from an unlocked account withdrawing its vote.
+func (s *PrivateDposAPI) Unvote(ctx context.Context, from common.Address) (common.Hash, error) {
+	return s.send(ctx, from, dpos.UnvoteData())
+}
+
+// send submits a vote transaction to the delegate registry.
+func (s *PrivateDposAPI) send(ctx context.Context, from common.Address, data []byte) (common.Hash, error) {
+	var (
+		to    = dpos.RegistryAddress
+		input = hexutil.Bytes(data)
+	)
+	return s.txs.SendTransaction(ctx, SendTxArgs{From: from, To: &to, TxType: types.TxTypeVote, Data: &input})
+}
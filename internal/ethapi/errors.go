@@ -0,0 +1,105 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"strings"
+
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/rpc"
+)
+
+// txRejectReasons maps the errors of the transaction pool to the identifiers
+// reported in the data of TxRejectedError.
+var txRejectReasons = map[error]string{
+	core.ErrInvalidSender:      "invalidSender",
+	core.ErrNonceTooLow:        "nonceTooLow",
+	core.ErrNonceTooHigh:       "nonceTooHigh",
+	core.ErrUnderpriced:        "underpriced",
+	core.ErrReplaceUnderpriced: "replaceUnderpriced",
+	core.ErrInsufficientFunds:  "insufficientFunds",
+	core.ErrIntrinsicGas:       "intrinsicGas",
+	core.ErrGasLimit:           "gasLimit",
+	core.ErrNegativeValue:      "negativeValue",
+	core.ErrOversizedData:      "oversizedData",
+	core.ErrBlobFeeTooLow:      "blobFeeTooLow",
+	core.ErrTxPoolFrozen:       "poolFrozen",
+}
+
+// TxRejectedError is returned when the transaction pool refuses a submitted
+// transaction. Its data carries a stable identifier of the violated rule.
+type TxRejectedError struct {
+	Reason string // Identifier of the violated pool rule, "rejected" if unclassified
+	Err    error  // Error returned by the transaction pool
+}
+
+// newTxRejectedError classifies an error of the transaction pool.
+func newTxRejectedError(err error) *TxRejectedError {
+	reason, ok := txRejectReasons[err]
+	switch {
+	case ok:
+	case strings.HasPrefix(err.Error(), "known transaction"):
+		reason = "knownTransaction"
+	default:
+		reason = "rejected"
+	}
+	return &TxRejectedError{Reason: reason, Err: err}
+}
+
+func (e *TxRejectedError) Error() string { return e.Err.Error() }
+
+// ErrorCode implements rpc.Error.
+func (e *TxRejectedError) ErrorCode() int { return rpc.ErrCodeTxRejected }
+
+// ErrorData implements rpc.DataError.
+func (e *TxRejectedError) ErrorData() interface{} {
+	return map[string]string{"reason": e.Reason}
+}
+
+// NotFoundError is returned when a requested block, transaction or receipt is
+// unknown to the node.
+type NotFoundError struct {
+	Kind    string // Kind of the missing object: block, transaction or receipt
+	Message string
+}
+
+func (e *NotFoundError) Error() string { return e.Message }
+
+// ErrorCode implements rpc.Error.
+func (e *NotFoundError) ErrorCode() int { return rpc.ErrCodeNotFound }
+
+// ErrorData implements rpc.DataError.
+func (e *NotFoundError) ErrorData() interface{} {
+	return map[string]string{"kind": e.Kind}
+}
+
+// UnavailableError is returned when a functionality is unavailable with the
+// configuration of the node, such as mining without a goolase.
+type UnavailableError struct {
+	Feature string // Identifier of the unavailable functionality
+	Message string
+}
+
+func (e *UnavailableError) Error() string { return e.Message }
+
+// ErrorCode implements rpc.Error.
+func (e *UnavailableError) ErrorCode() int { return rpc.ErrCodeUnavailable }
+
+// ErrorData implements rpc.DataError.
+func (e *UnavailableError) ErrorData() interface{} {
+	return map[string]string{"feature": e.Feature}
+}
@@ -0,0 +1,88 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/rpc"
+)
+
+// maxMulticallCalls is the maximum number of calls that can be executed by a
+// single multicall request.
+const maxMulticallCalls = 1024
+
+// MulticallResult is the outcome of a single call of a multicall request. Calls
+// that could not be executed at all report an error instead.
+type MulticallResult struct {
+	ReturnData hexutil.Bytes  `json:"returnData"`
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	Failed     bool           `json:"failed"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// Multicall executes many independent calls against the state of the given
+// block, each one on its own copy of the state as if it was the only one. The
+// calls are run in parallel and their results returned in order, a failing call
+// not affecting the others.
+func (s *PublicBlockChainAPI) Multicall(ctx context.Context, calls []CallArgs, blockNr rpc.BlockNumber) ([]*MulticallResult, error) {
+	if len(calls) > maxMulticallCalls {
+		return nil, fmt.Errorf("too many calls: %d > %d", len(calls), maxMulticallCalls)
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	var (
+		results = make([]*MulticallResult, len(calls))
+		tasks   = make(chan int, len(calls))
+		pend    sync.WaitGroup
+	)
+	for i := range calls {
+		tasks <- i
+	}
+	close(tasks)
+
+	threads := runtime.NumCPU()
+	if threads > len(calls) {
+		threads = len(calls)
+	}
+	for i := 0; i < threads; i++ {
+		pend.Add(1)
+		go func() {
+			defer pend.Done()
+
+			for task := range tasks {
+				args := calls[task]
+				res, gas, failed, err := s.applyCall(ctx, s.callSender(args), args, state.Copy(), header, vm.Config{DisableGasMetering: true})
+
+				result := &MulticallResult{ReturnData: res, GasUsed: hexutil.Uint64(gas), Failed: failed}
+				if err != nil {
+					result.Error = err.Error()
+				}
+				results[task] = result
+			}
+		}()
+	}
+	pend.Wait()
+	return results, nil
+}
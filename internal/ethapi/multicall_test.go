@@ -0,0 +1,84 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core/types"
+)
+
+// slotIncrementer is contract code incrementing its first storage slot, returning
+// the new value.
+//
+// PUSH1 1 PUSH1 0 SLOAD ADD DUP1 PUSH1 0 SSTORE PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+var slotIncrementer = common.FromHex("0x6001600054018060005560005260206000f3")
+
+// Tests that multicalls execute every call on its own copy of the state,
+// returning the results in order and reporting failed calls individually.
+func TestMulticall(t *testing.T) {
+	contract := common.Address{0xcc}
+	backend := newCallBackend(contract, slotIncrementer, 2)
+	api := &PublicBlockChainAPI{b: backend}
+
+	calls := make([]CallArgs, 64)
+	for i := range calls {
+		calls[i] = CallArgs{From: common.Address{byte(i + 1)}, To: &contract, TxType: types.TxTypeContract}
+	}
+	calls[10].Gas = hexutil.Uint64(1) // Below the intrinsic gas, not executable
+
+	results, err := api.Multicall(context.Background(), calls, 2)
+	if err != nil {
+		t.Fatalf("failed to execute multicall: %v", err)
+	}
+	if len(results) != len(calls) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(results), len(calls))
+	}
+	for i, res := range results {
+		if i == 10 {
+			if res.Error == "" || len(res.ReturnData) != 0 {
+				t.Errorf("call %d: error mismatch: have %q with %x, want error", i, res.Error, res.ReturnData)
+			}
+			continue
+		}
+		if res.Error != "" || res.Failed {
+			t.Errorf("call %d: unexpected failure: %q (failed %v)", i, res.Error, res.Failed)
+			continue
+		}
+		// Every call sees the block's state untouched by the others
+		if have := new(big.Int).SetBytes(res.ReturnData); have.Int64() != 3 {
+			t.Errorf("call %d: result mismatch: have %v, want %v", i, have, 3)
+		}
+		if res.GasUsed == 0 {
+			t.Errorf("call %d: no gas used", i)
+		}
+	}
+	if backend.execs != int32(len(calls)) {
+		t.Errorf("executions mismatch: have %d, want %d", backend.execs, len(calls))
+	}
+	// Oversized batches are rejected without executing anything
+	if _, err := api.Multicall(context.Background(), make([]CallArgs, maxMulticallCalls+1), 2); err == nil {
+		t.Errorf("oversized multicall accepted")
+	}
+	if backend.execs != int32(len(calls)) {
+		t.Errorf("oversized multicall executed calls: have %d, want %d", backend.execs, len(calls))
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+)
+
+const (
+	// defaultNonceLease is the time a reserved nonce is held if no lease is given.
+	defaultNonceLease = 30 * time.Second
+
+	// maxNonceLease is the longest time a nonce may be reserved for.
+	maxNonceLease = 10 * time.Minute
+)
+
+// errUnknownReservation is returned if a nonce reservation to confirm or release
+// doesn't exist, was reserved by someone else or its lease already expired.
+var errUnknownReservation = errors.New("unknown or expired nonce reservation")
+
+// nonceReservation is a nonce held for a sender until its lease expires.
+type nonceReservation struct {
+	id        common.Hash // Random token proving ownership of the reservation
+	expires   time.Time   // Time the nonce is freed up again if not confirmed
+	confirmed bool        // Whether the sender submitted a transaction with the nonce
+}
+
+// NonceReserver hands out the nonces of an account to multiple concurrent
+// senders, ensuring that no two of them sign with the same nonce. Reserved
+// nonces are either confirmed once the transaction was submitted, released if
+// it was abandoned, or freed up automatically when their lease expires.
+type NonceReserver struct {
+	b         Backend
+	nonceLock *AddrLocker
+
+	reserved map[common.Address]map[uint64]*nonceReservation
+	lock     sync.Mutex
+}
+
+// NewNonceReserver creates a nonce reservation tracker on top of the pending
+// pool state, coordinating with local senders via the given nonce lock.
+func NewNonceReserver(b Backend, nonceLock *AddrLocker) *NonceReserver {
+	return &NonceReserver{
+		b:         b,
+		nonceLock: nonceLock,
+		reserved:  make(map[common.Address]map[uint64]*nonceReservation),
+	}
+}
+
+// reserve allocates the lowest nonce of an account not yet used by the pending
+// pool state nor held by another sender.
+func (r *NonceReserver) reserve(ctx context.Context, addr common.Address, lease time.Duration) (uint64, *nonceReservation, error) {
+	// Hold the account's nonce lock so in-flight local sends are accounted for
+	r.nonceLock.LockAddr(addr)
+	defer r.nonceLock.UnlockAddr(addr)
+
+	pending, err := r.b.GetPoolNonce(ctx, addr)
+	if err != nil {
+		return 0, nil, err
+	}
+	var id common.Hash
+	if _, err := rand.Read(id[:]); err != nil {
+		return 0, nil, err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.prune(addr, pending)
+	nonce := r.free(addr, pending)
+
+	res := &nonceReservation{id: id, expires: time.Now().Add(lease)}
+	if r.reserved[addr] == nil {
+		r.reserved[addr] = make(map[uint64]*nonceReservation)
+	}
+	r.reserved[addr][nonce] = res
+	return nonce, res, nil
+}
+
+// confirm marks a reserved nonce as used by a submitted transaction, holding it
+// until the pending pool state moves past it.
+func (r *NonceReserver) confirm(addr common.Address, nonce uint64, id common.Hash) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	res := r.reserved[addr][nonce]
+	if res == nil || res.id != id || (!res.confirmed && time.Now().After(res.expires)) {
+		return errUnknownReservation
+	}
+	res.confirmed = true
+	return nil
+}
+
+// release frees up a reserved nonce that the sender won't use after all.
+func (r *NonceReserver) release(addr common.Address, nonce uint64, id common.Hash) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	res := r.reserved[addr][nonce]
+	if res == nil || res.id != id {
+		return errUnknownReservation
+	}
+	delete(r.reserved[addr], nonce)
+	if len(r.reserved[addr]) == 0 {
+		delete(r.reserved, addr)
+	}
+	return nil
+}
+
+// avoid returns the lowest nonce of an account starting from the given pending
+// one which is not reserved by any sender. The caller needs to hold the nonce
+// lock of the account.
+func (r *NonceReserver) avoid(addr common.Address, pending uint64) uint64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.prune(addr, pending)
+	return r.free(addr, pending)
+}
+
+// prune drops the reservations of an account already used by the pending pool
+// state, as well as the unconfirmed ones whose lease expired.
+//
+// The caller needs to hold the reservation lock.
+func (r *NonceReserver) prune(addr common.Address, pending uint64) {
+	now := time.Now()
+	for nonce, res := range r.reserved[addr] {
+		if nonce < pending || (!res.confirmed && now.After(res.expires)) {
+			delete(r.reserved[addr], nonce)
+		}
+	}
+	if len(r.reserved[addr]) == 0 {
+		delete(r.reserved, addr)
+	}
+}
+
+// free returns the lowest unreserved nonce of an account starting from the given
+// one.
+//
+// The caller needs to hold the reservation lock.
+func (r *NonceReserver) free(addr common.Address, nonce uint64) uint64 {
+	for r.reserved[addr][nonce] != nil {
+		nonce++
+	}
+	return nonce
+}
+
+// PrivateNonceAPI exposes the nonce reservations to external senders sharing the
+// accounts of the node.
+type PrivateNonceAPI struct {
+	r *NonceReserver
+}
+
+// NewPrivateNonceAPI creates a new RPC service for reserving account nonces.
+func NewPrivateNonceAPI(r *NonceReserver) *PrivateNonceAPI {
+	return &PrivateNonceAPI{r}
+}
+
+// NonceReservation is a nonce held for the caller until it expires.
+type NonceReservation struct {
+	Address common.Address `json:"address"`
+	Nonce   hexutil.Uint64 `json:"nonce"`
+	ID      common.Hash    `json:"id"`
+	Expires hexutil.Uint64 `json:"expires"`
+}
+
+// ReserveNonce allocates the next free nonce of an account for the caller, held
+// for the given lease in seconds (30 if omitted) unless confirmed or released.
+func (api *PrivateNonceAPI) ReserveNonce(ctx context.Context, addr common.Address, lease *hexutil.Uint64) (*NonceReservation, error) {
+	duration := defaultNonceLease
+	if lease != nil {
+		duration = time.Duration(*lease) * time.Second
+	}
+	if duration > maxNonceLease {
+		duration = maxNonceLease
+	}
+	nonce, res, err := api.r.reserve(ctx, addr, duration)
+	if err != nil {
+		return nil, err
+	}
+	return &NonceReservation{
+		Address: addr,
+		Nonce:   hexutil.Uint64(nonce),
+		ID:      res.id,
+		Expires: hexutil.Uint64(res.expires.Unix()),
+	}, nil
+}
+
+// ConfirmNonce marks a reserved nonce as used by a submitted transaction.
+func (api *PrivateNonceAPI) ConfirmNonce(addr common.Address, nonce hexutil.Uint64, id common.Hash) error {
+	return api.r.confirm(addr, uint64(nonce), id)
+}
+
+// ReleaseNonce frees up a reserved nonce for other senders.
+func (api *PrivateNonceAPI) ReleaseNonce(addr common.Address, nonce hexutil.Uint64, id common.Hash) error {
+	return api.r.release(addr, uint64(nonce), id)
+}
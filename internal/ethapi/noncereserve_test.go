@@ -0,0 +1,158 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+)
+
+// nonceBackend is a backend serving the pending pool nonces of accounts, any
+// other call panics.
+type nonceBackend struct {
+	Backend
+	nonces map[common.Address]uint64
+	lock   sync.Mutex
+}
+
+func (b *nonceBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.nonces[addr], nil
+}
+
+func (b *nonceBackend) setPoolNonce(addr common.Address, nonce uint64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.nonces[addr] = nonce
+}
+
+// Tests that reserved nonces are never handed out twice, and that they are freed
+// up again once released, expired or used by the pending pool state.
+func TestNonceReservations(t *testing.T) {
+	var (
+		addr    = common.Address{0x01}
+		backend = &nonceBackend{nonces: map[common.Address]uint64{addr: 5}}
+		nonces  = NewNonceReserver(backend, new(AddrLocker))
+		api     = NewPrivateNonceAPI(nonces)
+		ctx     = context.Background()
+	)
+	reserve := func(lease *hexutil.Uint64, want uint64) *NonceReservation {
+		t.Helper()
+
+		res, err := api.ReserveNonce(ctx, addr, lease)
+		if err != nil {
+			t.Fatalf("failed to reserve nonce: %v", err)
+		}
+		if uint64(res.Nonce) != want {
+			t.Fatalf("reserved nonce mismatch: have %d, want %d", res.Nonce, want)
+		}
+		return res
+	}
+	first := reserve(nil, 5)
+	second := reserve(nil, 6)
+
+	// Local senders skip the reserved nonces
+	if nonce := nonces.avoid(addr, 5); nonce != 7 {
+		t.Errorf("local nonce mismatch: have %d, want %d", nonce, 7)
+	}
+	// Only the owner may confirm or release a reservation
+	if err := api.ReleaseNonce(addr, first.Nonce, second.ID); err != errUnknownReservation {
+		t.Errorf("foreign release error mismatch: have %v, want %v", err, errUnknownReservation)
+	}
+	if err := api.ConfirmNonce(addr, second.Nonce, first.ID); err != errUnknownReservation {
+		t.Errorf("foreign confirm error mismatch: have %v, want %v", err, errUnknownReservation)
+	}
+	// Released nonces are handed out again, confirmed ones are not
+	if err := api.ReleaseNonce(addr, first.Nonce, first.ID); err != nil {
+		t.Fatalf("failed to release nonce: %v", err)
+	}
+	if err := api.ConfirmNonce(addr, second.Nonce, second.ID); err != nil {
+		t.Fatalf("failed to confirm nonce: %v", err)
+	}
+	if err := api.ReleaseNonce(addr, first.Nonce, first.ID); err != errUnknownReservation {
+		t.Errorf("repeated release error mismatch: have %v, want %v", err, errUnknownReservation)
+	}
+	third := reserve(nil, 5)
+	reserve(nil, 7)
+
+	// Expired reservations are freed up and can't be confirmed any more
+	expired := reserve(new(hexutil.Uint64), 8)
+	if err := api.ConfirmNonce(addr, expired.Nonce, expired.ID); err != errUnknownReservation {
+		t.Errorf("expired confirm error mismatch: have %v, want %v", err, errUnknownReservation)
+	}
+	reserve(nil, 8)
+
+	// Nonces used by the pending pool state are dropped, confirmed or not
+	backend.setPoolNonce(addr, 7)
+	reserve(nil, 9)
+	if err := api.ReleaseNonce(addr, third.Nonce, third.ID); err != errUnknownReservation {
+		t.Errorf("used release error mismatch: have %v, want %v", err, errUnknownReservation)
+	}
+	backend.setPoolNonce(addr, 10)
+	reserve(nil, 10)
+
+	// Other accounts are unaffected
+	if res, err := api.ReserveNonce(ctx, common.Address{0x02}, nil); err != nil || res.Nonce != 0 {
+		t.Errorf("other account reservation mismatch: have %v (%v), want nonce 0", res, err)
+	}
+}
+
+// Tests that concurrent senders are all handed out distinct nonces.
+func TestNonceReservationsConcurrent(t *testing.T) {
+	var (
+		addr    = common.Address{0x01}
+		api     = NewPrivateNonceAPI(NewNonceReserver(&nonceBackend{nonces: make(map[common.Address]uint64)}, new(AddrLocker)))
+		senders = 32
+		results = make(chan uint64, senders)
+		pend    sync.WaitGroup
+	)
+	for i := 0; i < senders; i++ {
+		pend.Add(1)
+		go func() {
+			defer pend.Done()
+
+			res, err := api.ReserveNonce(context.Background(), addr, nil)
+			if err != nil {
+				t.Errorf("failed to reserve nonce: %v", err)
+				return
+			}
+			results <- uint64(res.Nonce)
+		}()
+	}
+	pend.Wait()
+	close(results)
+
+	seen := make(map[uint64]bool)
+	for nonce := range results {
+		if seen[nonce] {
+			t.Errorf("nonce %d reserved twice", nonce)
+		}
+		seen[nonce] = true
+	}
+	for i := 0; i < senders; i++ {
+		if !seen[uint64(i)] {
+			t.Errorf("nonce %d not reserved", i)
+		}
+	}
+}
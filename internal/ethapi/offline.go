@@ -0,0 +1,126 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/rlp"
+)
+
+// UnsignedTx is a fully filled in transaction awaiting an offline signature.
+// Raw is the RLP encoding of the transaction without signature, SigHash is the
+// hash the sender needs to sign for the bundle's chain.
+type UnsignedTx struct {
+	Args    SendTxArgs    `json:"args"`
+	Raw     hexutil.Bytes `json:"raw"`
+	SigHash common.Hash   `json:"sigHash"`
+}
+
+// UnsignedTxBundle is a batch of transactions exported for signing on an air
+// gapped machine.
+type UnsignedTxBundle struct {
+	ChainId      *hexutil.Big  `json:"chainId"`
+	Transactions []*UnsignedTx `json:"transactions"`
+}
+
+// SignedTxResult is the outcome of importing a single offline signed transaction.
+type SignedTxResult struct {
+	Hash  common.Hash    `json:"hash"`
+	From  common.Address `json:"from"`
+	Error string         `json:"error,omitempty"`
+}
+
+// ExportUnsignedTransactions fills in the missing fields of a batch of
+// transactions and exports them unsigned, ready to be signed offline. Nonces
+// not explicitly set are assigned consecutively per sender, starting from the
+// sender's next pending nonce.
+func (s *PublicTransactionPoolAPI) ExportUnsignedTransactions(ctx context.Context, batch []SendTxArgs) (*UnsignedTxBundle, error) {
+	signer := types.MakeSigner(s.b.ChainConfig(), s.b.CurrentBlock().Number())
+
+	bundle := &UnsignedTxBundle{
+		ChainId:      (*hexutil.Big)(s.b.ChainConfig().ChainId),
+		Transactions: make([]*UnsignedTx, 0, len(batch)),
+	}
+	nonces := make(map[common.Address]uint64)
+	for i, args := range batch {
+		if args.Nonce == nil {
+			nonce, ok := nonces[args.From]
+			if !ok {
+				pending, err := s.b.GetPoolNonce(ctx, args.From)
+				if err != nil {
+					return nil, err
+				}
+				nonce = pending
+			}
+			args.Nonce = (*hexutil.Uint64)(&nonce)
+		}
+		if err := args.setDefaults(ctx, s.b); err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		nonces[args.From] = uint64(*args.Nonce) + 1
+
+		tx := args.toTransaction()
+		raw, err := rlp.EncodeToBytes(tx)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Transactions = append(bundle.Transactions, &UnsignedTx{
+			Args:    args,
+			Raw:     raw,
+			SigHash: signer.Hash(tx),
+		})
+	}
+	return bundle, nil
+}
+
+// ImportSignedTransactions validates a batch of offline signed transactions and
+// submits them to the transaction pool. The whole batch is rejected if any of
+// the transactions is malformed or signed for a different chain, otherwise the
+// pool's verdict on each transaction is reported individually.
+func (s *PublicTransactionPoolAPI) ImportSignedTransactions(ctx context.Context, batch []hexutil.Bytes) ([]*SignedTxResult, error) {
+	var (
+		chainId = s.b.ChainConfig().ChainId
+		signer  = types.MakeSigner(s.b.ChainConfig(), s.b.CurrentBlock().Number())
+		txs     = make([]*types.Transaction, len(batch))
+		results = make([]*SignedTxResult, len(batch))
+	)
+	for i, encoded := range batch {
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(encoded, tx); err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		if !tx.Protected() || tx.ChainId().Cmp(chainId) != 0 {
+			return nil, fmt.Errorf("transaction %d: not signed for this chain", i)
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %v", i, err)
+		}
+		txs[i], results[i] = tx, &SignedTxResult{Hash: tx.Hash(), From: from}
+	}
+	for i, tx := range txs {
+		if _, err := submitTransaction(ctx, s.b, tx); err != nil {
+			results[i].Error = err.Error()
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/light"
+	"github.com/goola-team/goola/rpc"
+)
+
+// maxProofEntries is the maximum number of accounts and storage slots that can
+// be proven by a single request.
+const maxProofEntries = 1024
+
+// proofPrefetcher is implemented by backends that retrieve state on demand,
+// allowing them to fetch all the proofs of a request in bulk up front.
+type proofPrefetcher interface {
+	PrefetchProofs(ctx context.Context, header *types.Header, accounts []common.Address, storage map[common.Address][]common.Hash) error
+}
+
+// ProofQuery selects an account and some of its storage slots to prove.
+type ProofQuery struct {
+	Address     common.Address `json:"address"`
+	StorageKeys []common.Hash  `json:"storageKeys"`
+}
+
+// AccountProof is the content of a proven account. The entry is zero if the
+// account doesn't exist, the proof of its absence is included instead.
+type AccountProof struct {
+	Address     common.Address `json:"address"`
+	Balance     *hexutil.Big   `json:"balance"`
+	Nonce       hexutil.Uint64 `json:"nonce"`
+	CodeHash    common.Hash    `json:"codeHash"`
+	StorageHash common.Hash    `json:"storageHash"`
+	Storage     []StorageProof `json:"storage"`
+}
+
+// StorageProof is the content of a proven storage slot.
+type StorageProof struct {
+	Key   common.Hash `json:"key"`
+	Value common.Hash `json:"value"`
+}
+
+// BatchProof is a set of account and storage proofs at a single state root. The
+// trie nodes of all the proofs are merged into one deduplicated set, any entry
+// can be verified by walking it from the state root (or from the account's
+// storage hash) along the Keccak256 hash of the address (or slot key).
+type BatchProof struct {
+	BlockNumber hexutil.Uint64  `json:"blockNumber"`
+	BlockHash   common.Hash     `json:"blockHash"`
+	StateRoot   common.Hash     `json:"stateRoot"`
+	Accounts    []*AccountProof `json:"accounts"`
+	Nodes       []hexutil.Bytes `json:"nodes"`
+}
+
+// GetProofs returns the Merkle proofs of many accounts and storage slots at the
+// state of the given block, sharing a single deduplicated set of trie nodes.
+func (s *PublicBlockChainAPI) GetProofs(ctx context.Context, queries []ProofQuery, blockNr rpc.BlockNumber) (*BatchProof, error) {
+	var (
+		accounts = make([]common.Address, 0, len(queries))
+		storage  = make(map[common.Address][]common.Hash)
+		entries  int
+	)
+	for _, query := range queries {
+		accounts = append(accounts, query.Address)
+		if len(query.StorageKeys) > 0 {
+			storage[query.Address] = append(storage[query.Address], query.StorageKeys...)
+		}
+		entries += 1 + len(query.StorageKeys)
+	}
+	if entries > maxProofEntries {
+		return nil, fmt.Errorf("too many proof entries: %d > %d", entries, maxProofEntries)
+	}
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if prefetcher, ok := s.b.(proofPrefetcher); ok {
+		if err := prefetcher.PrefetchProofs(ctx, header, accounts, storage); err != nil {
+			return nil, err
+		}
+	}
+	nodes := light.NewNodeSet()
+
+	result := &BatchProof{
+		BlockNumber: hexutil.Uint64(header.Number.Uint64()),
+		BlockHash:   header.Hash(),
+		StateRoot:   header.Root,
+		Accounts:    make([]*AccountProof, 0, len(queries)),
+	}
+	for _, query := range queries {
+		if err := state.ProveAccount(query.Address, nodes); err != nil {
+			return nil, err
+		}
+		account := &AccountProof{
+			Address:     query.Address,
+			Balance:     (*hexutil.Big)(state.GetBalance(query.Address)),
+			Nonce:       hexutil.Uint64(state.GetNonce(query.Address)),
+			CodeHash:    state.GetCodeHash(query.Address),
+			StorageHash: types.EmptyRootHash,
+			Storage:     make([]StorageProof, 0, len(query.StorageKeys)),
+		}
+		if storageTrie := state.StorageTrie(query.Address); storageTrie != nil {
+			account.StorageHash = storageTrie.Hash()
+			for _, key := range query.StorageKeys {
+				if err := storageTrie.Prove(crypto.Keccak256(key[:]), 0, nodes); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, key := range query.StorageKeys {
+			account.Storage = append(account.Storage, StorageProof{Key: key, Value: state.GetState(query.Address, key)})
+		}
+		if err := state.Error(); err != nil {
+			return nil, err
+		}
+		result.Accounts = append(result.Accounts, account)
+	}
+	for _, node := range nodes.NodeList() {
+		result.Nodes = append(result.Nodes, hexutil.Bytes(node))
+	}
+	return result, nil
+}
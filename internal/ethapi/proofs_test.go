@@ -0,0 +1,137 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/light"
+	"github.com/goola-team/goola/rlp"
+	"github.com/goola-team/goola/rpc"
+	"github.com/goola-team/goola/trie"
+)
+
+// proofBackend is a backend serving a single state, any other call panics.
+type proofBackend struct {
+	Backend
+	state  *state.StateDB
+	header *types.Header
+}
+
+func (b *proofBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	return b.state, b.header, nil
+}
+
+// Tests that batched proofs share a deduplicated node set, which proves every
+// reported account and storage slot.
+func TestGetProofs(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	sdb := state.NewDatabase(db)
+	statedb, _ := state.New(common.Hash{}, sdb)
+
+	contract := common.Address{0xcc}
+	for i := byte(1); i <= 64; i++ {
+		statedb.AddBalance(common.Address{i}, big.NewInt(int64(i)))
+		statedb.SetState(contract, common.Hash{i}, common.Hash{i, i})
+	}
+	root, _ := statedb.Commit(false)
+	statedb, _ = state.New(root, sdb)
+
+	api := &PublicBlockChainAPI{b: &proofBackend{state: statedb, header: &types.Header{Number: big.NewInt(1), Root: root}}}
+	queries := []ProofQuery{
+		{Address: common.Address{1}},
+		{Address: common.Address{2}},
+		{Address: common.Address{0xee}}, // Non-existent account
+		{Address: contract, StorageKeys: []common.Hash{{1}, {2}, {0xff}}},
+	}
+	proof, err := api.GetProofs(context.Background(), queries, rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to retrieve proofs: %v", err)
+	}
+	if proof.StateRoot != root || len(proof.Accounts) != len(queries) {
+		t.Fatalf("proof mismatch: have root %x with %d accounts, want %x with %d", proof.StateRoot, len(proof.Accounts), root, len(queries))
+	}
+	// The node set holds no duplicates and fewer nodes than the single proofs
+	nodes := light.NewNodeSet()
+	for _, node := range proof.Nodes {
+		nodes.Put(crypto.Keccak256(node), node)
+	}
+	if nodes.KeyCount() != len(proof.Nodes) {
+		t.Errorf("duplicate proof nodes: %d unique out of %d", nodes.KeyCount(), len(proof.Nodes))
+	}
+	single := 0
+	for _, query := range queries {
+		set := light.NewNodeSet()
+		statedb.ProveAccount(query.Address, set)
+		single += set.KeyCount()
+
+		for _, key := range query.StorageKeys {
+			set := light.NewNodeSet()
+			statedb.StorageTrie(query.Address).Prove(crypto.Keccak256(key[:]), 0, set)
+			single += set.KeyCount()
+		}
+	}
+	if len(proof.Nodes) >= single {
+		t.Errorf("proof nodes not shared: %d in batch, %d in single proofs", len(proof.Nodes), single)
+	}
+	// Every reported entry is proven by the node set
+	for i, account := range proof.Accounts {
+		enc, err, _ := trie.VerifyProof(root, crypto.Keccak256(account.Address[:]), nodes)
+		if err != nil {
+			t.Fatalf("account %d: proof verification failed: %v", i, err)
+		}
+		if account.Address == (common.Address{0xee}) {
+			if enc != nil || account.Balance.ToInt().Sign() != 0 {
+				t.Errorf("account %d: non-existent account proven with %x, balance %v", i, enc, account.Balance)
+			}
+			continue
+		}
+		var acc state.Account
+		if err := rlp.DecodeBytes(enc, &acc); err != nil {
+			t.Fatalf("account %d: failed to decode proven account: %v", i, err)
+		}
+		if acc.Balance.Cmp(account.Balance.ToInt()) != 0 || acc.Root != account.StorageHash {
+			t.Errorf("account %d: proven %v/%x, reported %v/%x", i, acc.Balance, acc.Root, account.Balance, account.StorageHash)
+		}
+		for j, slot := range account.Storage {
+			enc, err, _ := trie.VerifyProof(account.StorageHash, crypto.Keccak256(slot.Key[:]), nodes)
+			if err != nil {
+				t.Fatalf("account %d slot %d: proof verification failed: %v", i, j, err)
+			}
+			var value []byte
+			if enc != nil {
+				if _, value, _, err = rlp.Split(enc); err != nil {
+					t.Fatalf("account %d slot %d: failed to decode proven value: %v", i, j, err)
+				}
+			}
+			if common.BytesToHash(value) != slot.Value {
+				t.Errorf("account %d slot %d: proven %x, reported %x", i, j, value, slot.Value)
+			}
+		}
+	}
+	// Requests above the entry limit are refused
+	if _, err := api.GetProofs(context.Background(), []ProofQuery{{StorageKeys: make([]common.Hash, maxProofEntries)}}, rpc.LatestBlockNumber); err == nil {
+		t.Errorf("oversized request accepted")
+	}
+}
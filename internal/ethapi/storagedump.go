@@ -0,0 +1,100 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/rlp"
+	"github.com/goola-team/goola/rpc"
+	"github.com/goola-team/goola/trie"
+)
+
+const (
+	// maxStorageDumpResults is the maximum number of storage entries returned by
+	// a single storage dump request.
+	maxStorageDumpResults = 1024
+
+	// storageDumpTimeout bounds the time spent on a storage dump, limiting the
+	// on-demand trie node retrievals of light clients.
+	storageDumpTimeout = 30 * time.Second
+)
+
+// StorageDumpEntry is a single storage slot of a contract.
+type StorageDumpEntry struct {
+	Hash  common.Hash  `json:"hash"` // Hash of the slot key, the order of the dump
+	Key   *common.Hash `json:"key"`  // Slot key, nil if its preimage is unknown
+	Value common.Hash  `json:"value"`
+}
+
+// StorageDumpResult is a page of consecutive storage entries of a contract.
+type StorageDumpResult struct {
+	Storage []StorageDumpEntry `json:"storage"`
+	NextKey *common.Hash       `json:"nextKey"` // Hash to continue the dump from, nil if complete
+}
+
+// StorageDump returns up to maxResults consecutive storage entries of a contract
+// at the given block, starting from the slot with the given key hash.
+func (api *PrivateDebugAPI) StorageDump(ctx context.Context, address common.Address, blockNr rpc.BlockNumber, startKey hexutil.Bytes, maxResults int) (*StorageDumpResult, error) {
+	if maxResults <= 0 || maxResults > maxStorageDumpResults {
+		maxResults = maxStorageDumpResults
+	}
+	ctx, cancel := context.WithTimeout(ctx, storageDumpTimeout)
+	defer cancel()
+
+	state, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	st := state.StorageTrie(address)
+	if err := state.Error(); err != nil {
+		return nil, err
+	}
+	if st == nil {
+		return nil, fmt.Errorf("account %x doesn't exist", address)
+	}
+	var (
+		nodes  = st.NodeIterator(startKey)
+		it     = trie.NewIterator(nodes)
+		result = &StorageDumpResult{Storage: []StorageDumpEntry{}}
+	)
+	for len(result.Storage) < maxResults && it.Next() {
+		_, content, _, err := rlp.Split(it.Value)
+		if err != nil {
+			return nil, err
+		}
+		entry := StorageDumpEntry{Hash: common.BytesToHash(it.Key), Value: common.BytesToHash(content)}
+		if preimage := st.GetKey(it.Key); preimage != nil {
+			key := common.BytesToHash(preimage)
+			entry.Key = &key
+		}
+		result.Storage = append(result.Storage, entry)
+	}
+	// Add the key of the next entry so clients can continue the dump
+	if it.Next() {
+		next := common.BytesToHash(it.Key)
+		result.NextKey = &next
+	}
+	if err := nodes.Error(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/rpc"
+)
+
+// Tests that storage dumps page through all the slots of a contract in hash
+// order, resolving the slot keys.
+func TestStorageDump(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	sdb := state.NewDatabase(db)
+	statedb, _ := state.New(common.Hash{}, sdb)
+
+	contract := common.Address{0xcc}
+	want := make(map[common.Hash]common.Hash)
+	for i := 1; i <= 50; i++ {
+		key, value := common.BigToHash(big.NewInt(int64(i))), common.BigToHash(big.NewInt(int64(1000+i)))
+		statedb.SetState(contract, key, value)
+		want[key] = value
+	}
+	root, _ := statedb.Commit(false)
+	statedb.Database().TrieDB().Commit(root, true)
+	statedb, _ = state.New(root, sdb)
+
+	api := NewPrivateDebugAPI(&proofBackend{state: statedb, header: &types.Header{Number: big.NewInt(1), Root: root}})
+
+	var (
+		start []byte
+		pages int
+		last  common.Hash
+		seen  = make(map[common.Hash]bool)
+	)
+	for {
+		res, err := api.StorageDump(context.Background(), contract, rpc.LatestBlockNumber, start, 16)
+		if err != nil {
+			t.Fatalf("page %d: failed to dump storage: %v", pages, err)
+		}
+		pages++
+		if len(res.Storage) > 16 {
+			t.Fatalf("page %d: too many entries: have %d, want at most %d", pages, len(res.Storage), 16)
+		}
+		for _, entry := range res.Storage {
+			if bytes.Compare(entry.Hash[:], last[:]) <= 0 {
+				t.Fatalf("page %d: entry %x out of order after %x", pages, entry.Hash, last)
+			}
+			last = entry.Hash
+			if entry.Key == nil {
+				t.Fatalf("page %d: preimage missing for %x", pages, entry.Hash)
+			}
+			if hash := crypto.Keccak256Hash(entry.Key[:]); hash != entry.Hash {
+				t.Errorf("page %d: key %x hash mismatch: have %x, want %x", pages, *entry.Key, entry.Hash, hash)
+			}
+			if value := want[*entry.Key]; entry.Value != value {
+				t.Errorf("page %d: slot %x value mismatch: have %x, want %x", pages, *entry.Key, entry.Value, value)
+			}
+			seen[*entry.Key] = true
+		}
+		if res.NextKey == nil {
+			break
+		}
+		if *res.NextKey == last {
+			t.Fatalf("page %d: next key repeats the last entry %x", pages, last)
+		}
+		start = res.NextKey[:]
+	}
+	if pages != 4 {
+		t.Errorf("page count mismatch: have %d, want %d", pages, 4)
+	}
+	if len(seen) != len(want) {
+		t.Errorf("dumped slot count mismatch: have %d, want %d", len(seen), len(want))
+	}
+	// Missing accounts are reported, not dumped as empty
+	if _, err := api.StorageDump(context.Background(), common.Address{0xee}, rpc.LatestBlockNumber, nil, 0); err == nil {
+		t.Errorf("storage dump of missing account succeeded")
+	}
+}
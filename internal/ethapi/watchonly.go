@@ -0,0 +1,90 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/goola-team/goola/accounts"
+	"github.com/goola-team/goola/accounts/watchonly"
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/rpc"
+)
+
+// errNoWatchOnly is returned if watch-only accounts are requested from an
+// account manager without a watch-only backend.
+var errNoWatchOnly = errors.New("watch-only accounts not supported")
+
+// fetchWatchOnly retrieves the watch-only backend from the account manager.
+func fetchWatchOnly(am *accounts.Manager) (*watchonly.Backend, error) {
+	backends := am.Backends(watchonly.BackendType)
+	if len(backends) == 0 {
+		return nil, errNoWatchOnly
+	}
+	return backends[0].(*watchonly.Backend), nil
+}
+
+// WatchAccount starts tracking an address as a watch-only account.
+func (s *PrivateAccountAPI) WatchAccount(addr common.Address) (accounts.Account, error) {
+	backend, err := fetchWatchOnly(s.am)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	return backend.Watch(addr)
+}
+
+// UnwatchAccount stops tracking a watch-only address.
+func (s *PrivateAccountAPI) UnwatchAccount(addr common.Address) error {
+	backend, err := fetchWatchOnly(s.am)
+	if err != nil {
+		return err
+	}
+	return backend.Unwatch(addr)
+}
+
+// AccountOverview is the balance and nonce of an account managed by the node.
+type AccountOverview struct {
+	Address   common.Address `json:"address"`
+	Balance   *hexutil.Big   `json:"balance"`
+	Nonce     hexutil.Uint64 `json:"nonce"`
+	WatchOnly bool           `json:"watchOnly"`
+}
+
+// GetAccountsOverview returns the balances and nonces of all accounts managed by
+// the node, including watch-only ones, in the state of the given block number.
+func (s *PublicBlockChainAPI) GetAccountsOverview(ctx context.Context, blockNr rpc.BlockNumber) ([]*AccountOverview, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	watched, _ := fetchWatchOnly(s.b.AccountManager())
+
+	overview := make([]*AccountOverview, 0)
+	for _, wallet := range s.b.AccountManager().Wallets() {
+		for _, account := range wallet.Accounts() {
+			overview = append(overview, &AccountOverview{
+				Address:   account.Address,
+				Balance:   (*hexutil.Big)(state.GetBalance(account.Address)),
+				Nonce:     hexutil.Uint64(state.GetNonce(account.Address)),
+				WatchOnly: watched != nil && watched.Watching(account.Address),
+			})
+		}
+	}
+	return overview, state.Error()
+}
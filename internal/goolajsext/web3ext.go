@@ -22,9 +22,14 @@ var Modules = map[string]string{
 	"chequebook": Chequebook_JS,
 	"clique":     Clique_JS,
 	"debug":      Debug_JS,
+	"dpos":       Dpos_JS,
+	"goola":      Goola_JS,
 	"goolabackend":        Eth_JS,
+	"les":        LES_JS,
 	"miner":      Miner_JS,
+	"multisig":   Multisig_JS,
 	"net":        Net_JS,
+	"node":       Node_JS,
 	"personal":   Personal_JS,
 	"rpc":        RPC_JS,
 	"shh":        Shh_JS,
@@ -109,6 +114,59 @@ goolajs._extend({
 });
 `
 
+const Dpos_JS = `
+goolajs._extend({
+	property: 'dpos',
+	methods: [
+		new goolajs._extend.Method({
+			name: 'getSnapshot',
+			call: 'dpos_getSnapshot',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new goolajs._extend.Method({
+			name: 'getSnapshotAtHash',
+			call: 'dpos_getSnapshotAtHash',
+			params: 1
+		}),
+		new goolajs._extend.Method({
+			name: 'getDelegates',
+			call: 'dpos_getDelegates',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new goolajs._extend.Method({
+			name: 'getVotes',
+			call: 'dpos_getVotes',
+			params: 2,
+			inputFormatter: [goolajs._extend.formatters.inputAddressFormatter, null]
+		}),
+		new goolajs._extend.Method({
+			name: 'getLiveness',
+			call: 'dpos_getLiveness',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new goolajs._extend.Method({
+			name: 'getDrifts',
+			call: 'dpos_getDrifts',
+		}),
+		new goolajs._extend.Method({
+			name: 'vote',
+			call: 'dpos_vote',
+			params: 2,
+			inputFormatter: [goolajs._extend.formatters.inputAddressFormatter, goolajs._extend.formatters.inputAddressFormatter]
+		}),
+		new goolajs._extend.Method({
+			name: 'unvote',
+			call: 'dpos_unvote',
+			params: 1,
+			inputFormatter: [goolajs._extend.formatters.inputAddressFormatter]
+		}),
+	]
+});
+`
+
 const Admin_JS = `
 goolajs._extend({
 	property: 'admin',
@@ -123,6 +181,12 @@ goolajs._extend({
 			call: 'admin_removePeer',
 			params: 1
 		}),
+		new goolajs._extend.Method({
+			name: 'peerHistory',
+			call: 'admin_peerHistory',
+			params: 1,
+			inputFormatter: [null]
+		}),
 		new goolajs._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -159,6 +223,43 @@ goolajs._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new goolajs._extend.Method({
+			name: 'registerContractABI',
+			call: 'admin_registerContractABI',
+			params: 2
+		}),
+		new goolajs._extend.Method({
+			name: 'unregisterContractABI',
+			call: 'admin_unregisterContractABI',
+			params: 1
+		}),
+		new goolajs._extend.Method({
+			name: 'reloadConfig',
+			call: 'admin_reloadConfig'
+		}),
+		new goolajs._extend.Method({
+			name: 'selfTest',
+			call: 'admin_selfTest'
+		}),
+		new goolajs._extend.Method({
+			name: 'runMaintenance',
+			call: 'admin_runMaintenance',
+			params: 1
+		}),
+		new goolajs._extend.Method({
+			name: 'suspendMaintenance',
+			call: 'admin_suspendMaintenance',
+			params: 1,
+			inputFormatter: [goolajs._extend.utils.fromDecimal]
+		}),
+		new goolajs._extend.Method({
+			name: 'freeze',
+			call: 'admin_freeze'
+		}),
+		new goolajs._extend.Method({
+			name: 'unfreeze',
+			call: 'admin_unfreeze'
+		}),
 	],
 	properties: [
 		new goolajs._extend.Property({
@@ -173,6 +274,22 @@ goolajs._extend({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new goolajs._extend.Property({
+			name: 'contractABIs',
+			getter: 'admin_contractABIs'
+		}),
+		new goolajs._extend.Property({
+			name: 'propagationStats',
+			getter: 'admin_propagationStats'
+		}),
+		new goolajs._extend.Property({
+			name: 'maintenanceSchedule',
+			getter: 'admin_maintenanceSchedule'
+		}),
+		new goolajs._extend.Property({
+			name: 'frozen',
+			getter: 'admin_frozen'
+		}),
 	]
 });
 `
@@ -191,11 +308,47 @@ goolajs._extend({
 			call: 'debug_getBlockRlp',
 			params: 1
 		}),
+		new goolajs._extend.Method({
+			name: 'getRawBlockByNumber',
+			call: 'debug_getRawBlockByNumber',
+			params: 1,
+			inputFormatter: [goolajs._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new goolajs._extend.Method({
+			name: 'getRawReceipts',
+			call: 'debug_getRawReceipts',
+			params: 1
+		}),
+		new goolajs._extend.Method({
+			name: 'getTrieProofs',
+			call: 'debug_getTrieProofs',
+			params: 2
+		}),
+		new goolajs._extend.Method({
+			name: 'getCodeByHash',
+			call: 'debug_getCodeByHash',
+			params: 1
+		}),
 		new goolajs._extend.Method({
 			name: 'setHead',
 			call: 'debug_setHead',
 			params: 1
 		}),
+		new goolajs._extend.Method({
+			name: 'recordPeer',
+			call: 'debug_recordPeer',
+			params: 2
+		}),
+		new goolajs._extend.Method({
+			name: 'cancelRecording',
+			call: 'debug_cancelRecording',
+			params: 1
+		}),
+		new goolajs._extend.Method({
+			name: 'replayCassette',
+			call: 'debug_replayCassette',
+			params: 1
+		}),
 		new goolajs._extend.Method({
 			name: 'seedHash',
 			call: 'debug_seedHash',
@@ -358,6 +511,12 @@ goolajs._extend({
 			call: 'debug_storageRangeAt',
 			params: 5,
 		}),
+		new goolajs._extend.Method({
+			name: 'storageDump',
+			call: 'debug_storageDump',
+			params: 4,
+			inputFormatter: [null, goolajs._extend.formatters.inputDefaultBlockNumberFormatter, null, null]
+		}),
 		new goolajs._extend.Method({
 			name: 'getModifiedAccountsByNumber',
 			call: 'debug_getModifiedAccountsByNumber',
@@ -370,8 +529,18 @@ goolajs._extend({
 			params: 2,
 			inputFormatter:[null, null],
 		}),
+		new goolajs._extend.Method({
+			name: 'getTouchedState',
+			call: 'debug_getTouchedState',
+			params: 1
+		}),
 	],
-	properties: []
+	properties: [
+		new goolajs._extend.Property({
+			name: 'slowQueries',
+			getter: 'debug_slowQueries'
+		}),
+	]
 });
 `
 
@@ -403,6 +572,34 @@ goolajs._extend({
 			params: 1,
 			inputFormatter: [goolajs._extend.formatters.inputTransactionFormatter]
 		}),
+		new goolajs._extend.Method({
+			name: 'getProofs',
+			call: 'goolabackend_getProofs',
+			params: 2,
+			inputFormatter: [null, goolajs._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new goolajs._extend.Method({
+			name: 'multicall',
+			call: 'goolabackend_multicall',
+			params: 2,
+			inputFormatter: [null, goolajs._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new goolajs._extend.Method({
+			name: 'getAccountsOverview',
+			call: 'goolabackend_getAccountsOverview',
+			params: 1,
+			inputFormatter: [goolajs._extend.formatters.inputDefaultBlockNumberFormatter]
+		}),
+		new goolajs._extend.Method({
+			name: 'exportUnsignedTransactions',
+			call: 'goolabackend_exportUnsignedTransactions',
+			params: 1
+		}),
+		new goolajs._extend.Method({
+			name: 'importSignedTransactions',
+			call: 'goolabackend_importSignedTransactions',
+			params: 1
+		}),
 		new goolajs._extend.Method({
 			name: 'getRawTransaction',
 			call: 'eth_getRawTransactionByHash',
@@ -430,6 +627,137 @@ goolajs._extend({
 				return formatted;
 			}
 		}),
+		new goolajs._extend.Property({
+			name: 'finalizedBlock',
+			getter: 'goolabackend_finalizedBlock'
+		}),
+	]
+});
+`
+
+const Goola_JS = `
+goolajs._extend({
+	property: 'goola',
+	methods: [
+		new goolajs._extend.Method({
+			name: 'getInternalTransactions',
+			call: 'goola_getInternalTransactions',
+			params: 1
+		}),
+		new goolajs._extend.Method({
+			name: 'recentIncomingPayments',
+			call: 'goola_recentIncomingPayments',
+			params: 2,
+			inputFormatter: [null, goolajs._extend.utils.fromDecimal]
+		}),
+		new goolajs._extend.Method({
+			name: 'pendingBlockPreview',
+			call: 'goola_pendingBlockPreview',
+			params: 0
+		}),
+		new goolajs._extend.Method({
+			name: 'sendBlobTransaction',
+			call: 'goola_sendBlobTransaction',
+			params: 2
+		}),
+		new goolajs._extend.Method({
+			name: 'getBlob',
+			call: 'goola_getBlob',
+			params: 1
+		}),
+		new goolajs._extend.Method({
+			name: 'blockStats',
+			call: 'goola_blockStats',
+			params: 2,
+			inputFormatter: [goolajs._extend.utils.fromDecimal, goolajs._extend.utils.fromDecimal]
+		}),
+		new goolajs._extend.Method({
+			name: 'blockTransferSummary',
+			call: 'goola_blockTransferSummary',
+			params: 1,
+			inputFormatter: [goolajs._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new goolajs._extend.Method({
+			name: 'balanceHistory',
+			call: 'goola_balanceHistory',
+			params: 4,
+			inputFormatter: [goolajs._extend.formatters.inputAddressFormatter, goolajs._extend.formatters.inputBlockNumberFormatter, goolajs._extend.formatters.inputBlockNumberFormatter, goolajs._extend.utils.fromDecimal]
+		}),
+	],
+	properties: [
+		new goolajs._extend.Property({
+			name: 'rejectedReorgs',
+			getter: 'goola_rejectedReorgs'
+		}),
+		new goolajs._extend.Property({
+			name: 'blobFee',
+			getter: 'goola_blobFee',
+			outputFormatter: goolajs._extend.utils.toBigNumber
+		}),
+		new goolajs._extend.Property({
+			name: 'nodeConfig',
+			getter: 'goola_nodeConfig'
+		}),
+		new goolajs._extend.Property({
+			name: 'forkSchedule',
+			getter: 'goola_forkSchedule'
+		}),
+	]
+});
+`
+
+const LES_JS = `
+goolajs._extend({
+	property: 'les',
+	methods: [],
+	properties: [
+		new goolajs._extend.Property({
+			name: 'bandwidthUsage',
+			getter: 'les_bandwidthUsage'
+		}),
+	]
+});
+`
+
+const Multisig_JS = `
+goolajs._extend({
+	property: 'multisig',
+	methods: [
+		new goolajs._extend.Method({
+			name: 'pendingProposals',
+			call: 'multisig_pendingProposals',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new goolajs._extend.Method({
+			name: 'awaitingConfirmation',
+			call: 'multisig_awaitingConfirmation',
+			params: 1
+		}),
+		new goolajs._extend.Method({
+			name: 'craftConfirmation',
+			call: 'multisig_craftConfirmation',
+			params: 3,
+			inputFormatter: [null, goolajs._extend.utils.fromDecimal, null]
+		}),
+		new goolajs._extend.Method({
+			name: 'craftRevocation',
+			call: 'multisig_craftRevocation',
+			params: 3,
+			inputFormatter: [null, goolajs._extend.utils.fromDecimal, null]
+		}),
+		new goolajs._extend.Method({
+			name: 'craftExecution',
+			call: 'multisig_craftExecution',
+			params: 3,
+			inputFormatter: [null, goolajs._extend.utils.fromDecimal, null]
+		}),
+	],
+	properties: [
+		new goolajs._extend.Property({
+			name: 'wallets',
+			getter: 'multisig_wallets'
+		}),
 	]
 });
 `
@@ -454,17 +782,32 @@ goolajs._extend({
 			params: 1,
 			inputFormatter: [goolajs._extend.formatters.inputAddressFormatter]
 		}),
+		new goolajs._extend.Method({
+			name: 'setNextCoinbase',
+			call: 'miner_setNextCoinbase',
+			params: 1,
+			inputFormatter: [goolajs._extend.formatters.inputAddressFormatter]
+		}),
 		new goolajs._extend.Method({
 			name: 'setExtra',
 			call: 'miner_setExtra',
 			params: 1
 		}),
+		new goolajs._extend.Method({
+			name: 'setExtraTemplate',
+			call: 'miner_setExtraTemplate',
+			params: 2
+		}),
 		new goolajs._extend.Method({
 			name: 'setGasPrice',
 			call: 'miner_setGasPrice',
 			params: 1,
 			inputFormatter: [goolajs._extend.utils.fromDecimal]
 		}),
+		new goolajs._extend.Method({
+			name: 'lastPackingReport',
+			call: 'miner_lastPackingReport'
+		}),
 	],
 	properties: []
 });
@@ -483,6 +826,19 @@ goolajs._extend({
 });
 `
 
+const Node_JS = `
+goolajs._extend({
+	property: 'node',
+	methods: [],
+	properties: [
+		new goolajs._extend.Property({
+			name: 'health',
+			getter: 'node_health'
+		}),
+	]
+});
+`
+
 const Personal_JS = `
 goolajs._extend({
 	property: 'personal',
@@ -492,6 +848,18 @@ goolajs._extend({
 			call: 'personal_importRawKey',
 			params: 2
 		}),
+		new goolajs._extend.Method({
+			name: 'watchAccount',
+			call: 'personal_watchAccount',
+			params: 1,
+			inputFormatter: [goolajs._extend.formatters.inputAddressFormatter]
+		}),
+		new goolajs._extend.Method({
+			name: 'unwatchAccount',
+			call: 'personal_unwatchAccount',
+			params: 1,
+			inputFormatter: [goolajs._extend.formatters.inputAddressFormatter]
+		}),
 		new goolajs._extend.Method({
 			name: 'sign',
 			call: 'personal_sign',
@@ -519,6 +887,24 @@ goolajs._extend({
 			params: 2,
 			inputFormatter: [goolajs._extend.formatters.inputTransactionFormatter, null]
 		}),
+		new goolajs._extend.Method({
+			name: 'reserveNonce',
+			call: 'personal_reserveNonce',
+			params: 2,
+			inputFormatter: [goolajs._extend.formatters.inputAddressFormatter, goolajs._extend.utils.fromDecimal]
+		}),
+		new goolajs._extend.Method({
+			name: 'confirmNonce',
+			call: 'personal_confirmNonce',
+			params: 3,
+			inputFormatter: [goolajs._extend.formatters.inputAddressFormatter, goolajs._extend.utils.fromDecimal, null]
+		}),
+		new goolajs._extend.Method({
+			name: 'releaseNonce',
+			call: 'personal_releaseNonce',
+			params: 3,
+			inputFormatter: [goolajs._extend.formatters.inputAddressFormatter, goolajs._extend.utils.fromDecimal, null]
+		}),
 	],
 	properties: [
 		new goolajs._extend.Property({
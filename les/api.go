@@ -0,0 +1,79 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/p2p/discover"
+)
+
+// errNoBudget is returned if the bandwidth usage is requested but the light
+// client isn't running with a bandwidth budget.
+var errNoBudget = errors.New("no bandwidth budget configured")
+
+// PublicLesAPI provides an API to inspect the light client protocol.
+type PublicLesAPI struct {
+	les *LightGoola
+}
+
+// NewPublicLesAPI creates a new light client protocol API.
+func NewPublicLesAPI(les *LightGoola) *PublicLesAPI {
+	return &PublicLesAPI{les}
+}
+
+// BandwidthUsage returns the traffic accounted against the bandwidth budget in
+// the current hour and day.
+func (api *PublicLesAPI) BandwidthUsage() (*BandwidthUsage, error) {
+	if api.les.budget == nil {
+		return nil, errNoBudget
+	}
+	return api.les.budget.usage(), nil
+}
+
+// PrivateLightServerAPI provides an API to manage the prepaid balances of the
+// clients of a light server.
+type PrivateLightServerAPI struct {
+	server *LesServer
+}
+
+// NewPrivateLightServerAPI creates a new light server management API.
+func NewPrivateLightServerAPI(server *LesServer) *PrivateLightServerAPI {
+	return &PrivateLightServerAPI{server}
+}
+
+// Balance returns the prepaid balance of a client, in request cost units.
+func (api *PrivateLightServerAPI) Balance(node string) (hexutil.Uint64, error) {
+	id, err := discover.HexID(node)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(api.server.payments.balance(id)), nil
+}
+
+// TopUp credits the prepaid balance of a client upon receiving a payment, and
+// returns the new balance. Clients connected without a balance are prioritized
+// once they reconnect.
+func (api *PrivateLightServerAPI) TopUp(node string, amount hexutil.Uint64) (hexutil.Uint64, error) {
+	id, err := discover.HexID(node)
+	if err != nil {
+		return 0, err
+	}
+	balance, err := api.server.payments.topUp(id, uint64(amount))
+	return hexutil.Uint64(balance), err
+}
@@ -28,13 +28,18 @@ import (
 	"github.com/goola-team/goola/core/state"
 	"github.com/goola-team/goola/core/types"
 	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/crypto"
 	"github.com/goola-team/goola/goolabackend/downloader"
 	"github.com/goola-team/goola/goolabackend/gasprice"
 	"github.com/goola-team/goola/gooladb"
 	"github.com/goola-team/goola/event"
+	"github.com/goola-team/goola/internal/ethapi"
 	"github.com/goola-team/goola/light"
 	"github.com/goola-team/goola/params"
+	"github.com/goola-team/goola/rlp"
 	"github.com/goola-team/goola/rpc"
+	"github.com/goola-team/goola/tracing"
+	"github.com/goola-team/goola/trie"
 )
 
 type LesApiBackend struct {
@@ -56,6 +61,9 @@ func (b *LesApiBackend) SetHead(number uint64) {
 }
 
 func (b *LesApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	ctx, span := tracing.Start(ctx, "backend.HeaderByNumber", "number", int64(blockNr))
+	defer span.End()
+
 	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
 		return b.lightGoola.blockchain.CurrentHeader(), nil
 	}
@@ -64,6 +72,9 @@ func (b *LesApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 }
 
 func (b *LesApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error) {
+	ctx, span := tracing.Start(ctx, "backend.BlockByNumber", "number", int64(blockNr))
+	defer span.End()
+
 	header, err := b.HeaderByNumber(ctx, blockNr)
 	if header == nil || err != nil {
 		return nil, err
@@ -72,6 +83,9 @@ func (b *LesApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 }
 
 func (b *LesApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	ctx, span := tracing.Start(ctx, "backend.StateAndHeaderByNumber", "number", int64(blockNr))
+	defer span.End()
+
 	header, err := b.HeaderByNumber(ctx, blockNr)
 	if header == nil || err != nil {
 		return nil, nil, err
@@ -80,10 +94,16 @@ func (b *LesApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.
 }
 
 func (b *LesApiBackend) GetBlock(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	ctx, span := tracing.Start(ctx, "backend.GetBlock", "hash", blockHash)
+	defer span.End()
+
 	return b.lightGoola.blockchain.GetBlockByHash(ctx, blockHash)
 }
 
 func (b *LesApiBackend) GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error) {
+	ctx, span := tracing.Start(ctx, "backend.GetReceipts", "hash", blockHash)
+	defer span.End()
+
 	return light.GetBlockReceipts(ctx, b.lightGoola.odr, blockHash, core.GetBlockNumber(b.lightGoola.chainDb, blockHash))
 }
 
@@ -95,6 +115,9 @@ func (b *LesApiBackend) GetEVM(ctx context.Context, msg core.Message, state *sta
 }
 
 func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	ctx, span := tracing.Start(ctx, "backend.SendTx", "tx", signedTx.Hash())
+	defer span.End()
+
 	return b.lightGoola.txPool.Add(ctx, signedTx)
 }
 
@@ -111,6 +134,9 @@ func (b *LesApiBackend) GetPoolTransaction(txHash common.Hash) *types.Transactio
 }
 
 func (b *LesApiBackend) GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error) {
+	ctx, span := tracing.Start(ctx, "backend.GetPoolNonce", "address", addr)
+	defer span.End()
+
 	return b.lightGoola.txPool.GetNonce(ctx, addr)
 }
 
@@ -126,6 +152,15 @@ func (b *LesApiBackend) SubscribeTxPreEvent(ch chan<- core.TxPreEvent) event.Sub
 	return b.lightGoola.txPool.SubscribeTxPreEvent(ch)
 }
 
+// SubscribeTxDropEvent returns an inert subscription, the light transaction pool
+// only tracks local transactions and never evicts any.
+func (b *LesApiBackend) SubscribeTxDropEvent(ch chan<- core.TxDropEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *LesApiBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.lightGoola.blockchain.SubscribeChainEvent(ch)
 }
@@ -155,6 +190,9 @@ func (b *LesApiBackend) ProtocolVersion() int {
 }
 
 func (b *LesApiBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
+	ctx, span := tracing.Start(ctx, "backend.SuggestPrice")
+	defer span.End()
+
 	return b.gpo.SuggestPrice(ctx)
 }
 
@@ -170,6 +208,10 @@ func (b *LesApiBackend) AccountManager() *accounts.Manager {
 	return b.lightGoola.accountManager
 }
 
+func (b *LesApiBackend) CallCacheConfig() ethapi.CallCacheConfig {
+	return b.lightGoola.config.CallCache
+}
+
 func (b *LesApiBackend) BloomStatus() (uint64, uint64) {
 	if b.lightGoola.bloomIndexer == nil {
 		return 0, 0
@@ -183,3 +225,66 @@ func (b *LesApiBackend) ServiceFilter(ctx context.Context, session *bloombits.Ma
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.lightGoola.bloomRequests)
 	}
 }
+
+// PrefetchProofs retrieves the proofs of the given accounts and storage slots
+// from the network in batches, so that they can be served afterwards from the
+// local database instead of one trie node at a time. The accounts of all the
+// storage slots must be listed in accounts too.
+func (b *LesApiBackend) PrefetchProofs(ctx context.Context, header *types.Header, accounts []common.Address, storage map[common.Address][]common.Hash) error {
+	ctx, span := tracing.Start(ctx, "backend.PrefetchProofs", "number", header.Number.Uint64(), "accounts", len(accounts))
+	defer span.End()
+
+	stateID := light.StateTrieID(header)
+
+	// Fetch the account proofs first, the storage tries are rooted in them
+	var reqs []*light.TrieRequest
+	for _, addr := range accounts {
+		reqs = append(reqs, &light.TrieRequest{Id: stateID, Key: crypto.Keccak256(addr[:])})
+	}
+	if err := b.retrieveProofs(ctx, reqs); err != nil {
+		return err
+	}
+	db := b.lightGoola.odr.Database()
+	tr, err := trie.New(header.Root, trie.NewDatabase(db))
+	if err != nil {
+		return err
+	}
+	reqs = nil
+	for addr, keys := range storage {
+		addrHash := crypto.Keccak256Hash(addr[:])
+		enc, err := tr.TryGet(addrHash[:])
+		if err != nil {
+			return err
+		}
+		if len(enc) == 0 {
+			continue
+		}
+		var acc state.Account
+		if err := rlp.DecodeBytes(enc, &acc); err != nil {
+			return err
+		}
+		if acc.Root == types.EmptyRootHash {
+			continue
+		}
+		id := light.StorageTrieID(stateID, addrHash, acc.Root)
+		for _, key := range keys {
+			reqs = append(reqs, &light.TrieRequest{Id: id, Key: crypto.Keccak256(key[:])})
+		}
+	}
+	return b.retrieveProofs(ctx, reqs)
+}
+
+// retrieveProofs retrieves trie proofs in batches of at most MaxProofsFetch.
+func (b *LesApiBackend) retrieveProofs(ctx context.Context, reqs []*light.TrieRequest) error {
+	for len(reqs) > 0 {
+		n := len(reqs)
+		if n > MaxProofsFetch {
+			n = MaxProofsFetch
+		}
+		if err := b.lightGoola.odr.Retrieve(ctx, &light.BatchTrieRequest{Reqs: reqs[:n]}); err != nil {
+			return err
+		}
+		reqs = reqs[n:]
+	}
+	return nil
+}
@@ -60,6 +60,7 @@ type LightGoola struct {
 	serverPool      *serverPool
 	reqDist         *requestDistributor
 	retriever       *retrieveManager
+	budget          *bandwidthBudget
 	// DB interfaces
 	chainDb gooladb.Database // Block chain database
 
@@ -110,10 +111,21 @@ func New(ctx *node.ServiceContext, config *goolabackend.Config) (*LightGoola, er
 	}
 
 	lightGoola.relay = NewLesTxRelay(peers, lightGoola.reqDist)
+	lightGoola.budget = newBandwidthBudget(config.LightBudgetHourly, config.LightBudgetDaily)
 	lightGoola.serverPool = newServerPool(chainDb, quitSync, &lightGoola.wg)
+	lightGoola.serverPool.budget = lightGoola.budget
+	lightGoola.serverPool.sections = func() uint64 {
+		sections, _, _ := lightGoola.chtIndexer.Sections()
+		return sections
+	}
 	lightGoola.retriever = newRetrieveManager(peers, lightGoola.reqDist, lightGoola.serverPool)
+	lightGoola.retriever.budget = lightGoola.budget
 	lightGoola.odr = NewLesOdr(chainDb, lightGoola.chtIndexer, lightGoola.bloomTrieIndexer, lightGoola.bloomIndexer, lightGoola.retriever)
-	if lightGoola.blockchain, err = light.NewLightChain(lightGoola.odr, lightGoola.chainConfig, lightGoola.engine); err != nil {
+	if len(config.LightRelay) > 0 {
+		lightGoola.odr.rpcRelay = newRPCRelay(config.LightRelay)
+	}
+	checkpoint := params.CheckpointOf(config.NetworkId, genesisHash)
+	if lightGoola.blockchain, err = light.NewLightChain(lightGoola.odr, lightGoola.chainConfig, lightGoola.engine, checkpoint); err != nil {
 		return nil, err
 	}
 	lightGoola.bloomIndexer.Start(lightGoola.blockchain)
@@ -128,6 +140,9 @@ func New(ctx *node.ServiceContext, config *goolabackend.Config) (*LightGoola, er
 	if lightGoola.protocolManager, err = NewProtocolManager(lightGoola.chainConfig, true, ClientProtocolVersions, config.NetworkId, lightGoola.eventMux, lightGoola.engine, lightGoola.peers, lightGoola.blockchain, nil, chainDb, lightGoola.odr, lightGoola.relay, quitSync, &lightGoola.wg); err != nil {
 		return nil, err
 	}
+	lightGoola.protocolManager.budget = lightGoola.budget
+	lightGoola.protocolManager.downloader.SetLightHeaderSampling(config.LightVerifyRecent)
+	lightGoola.protocolManager.downloader.SetCheckpoint(checkpoint)
 	lightGoola.ApiBackend = &LesApiBackend{lightGoola, nil}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
@@ -144,6 +159,8 @@ func lesTopic(genesisHash common.Hash, protocolVersion uint) discv5.Topic {
 		name = "LES"
 	case lpv2:
 		name = "LES2"
+	case lpv3:
+		name = "LES3"
 	default:
 		panic(nil)
 	}
@@ -187,11 +204,20 @@ func (lightGoola *LightGoola) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   filters.NewPublicFilterAPI(lightGoola.ApiBackend, true),
 			Public:    true,
+		}, {
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   filters.NewPrivateABIRegistryAPI(filters.NewABIRegistry(lightGoola.chainDb)),
 		}, {
 			Namespace: "net",
 			Version:   "1.0",
 			Service:   lightGoola.netRPCService,
 			Public:    true,
+		}, {
+			Namespace: "les",
+			Version:   "1.0",
+			Service:   NewPublicLesAPI(lightGoola),
+			Public:    true,
 		},
 	}...)
 }
@@ -0,0 +1,178 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/p2p"
+)
+
+// errBudgetExhausted is returned by on-demand retrievals if the bandwidth budget
+// of the current accounting period has been used up.
+var errBudgetExhausted = errors.New("bandwidth budget exhausted")
+
+// budgetWindow accumulates the traffic of a single fixed accounting period.
+type budgetWindow struct {
+	length time.Duration // Length of the accounting period
+	limit  uint64        // Maximum number of bytes per period, 0 if unlimited
+	start  time.Time     // Start of the current period
+	used   uint64        // Bytes transferred in the current period
+}
+
+// roll starts a new accounting period if the current one has ended.
+func (w *budgetWindow) roll(now time.Time) {
+	if now.Sub(w.start) >= w.length {
+		w.start, w.used = now.Truncate(w.length), 0
+	}
+}
+
+// exhausted reports whether the period's traffic reached its limit.
+func (w *budgetWindow) exhausted() bool {
+	return w.limit > 0 && w.used >= w.limit
+}
+
+// bandwidthBudget tracks the LES traffic of a light client against hourly and
+// daily limits, allowing the client to hold back non essential network activity
+// on metered connections. A nil budget is unlimited.
+type bandwidthBudget struct {
+	hour, day budgetWindow
+	clock     func() time.Time // Source of time, replaceable for testing
+	lock      sync.Mutex
+}
+
+// newBandwidthBudget creates a budget from the hourly and daily limits in MB,
+// returning nil if neither is set.
+func newBandwidthBudget(hourlyMB, dailyMB int) *bandwidthBudget {
+	if hourlyMB <= 0 && dailyMB <= 0 {
+		return nil
+	}
+	b := &bandwidthBudget{clock: time.Now}
+	b.hour = budgetWindow{length: time.Hour, limit: megabytes(hourlyMB)}
+	b.day = budgetWindow{length: 24 * time.Hour, limit: megabytes(dailyMB)}
+
+	now := b.clock()
+	b.hour.roll(now)
+	b.day.roll(now)
+	return b
+}
+
+// megabytes converts a non-negative MB amount to bytes.
+func megabytes(mb int) uint64 {
+	if mb <= 0 {
+		return 0
+	}
+	return uint64(mb) * 1024 * 1024
+}
+
+// add accounts for the given amount of transferred bytes.
+func (b *bandwidthBudget) add(bytes uint64) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.clock()
+	b.hour.roll(now)
+	b.day.roll(now)
+
+	wasExhausted := b.hour.exhausted() || b.day.exhausted()
+	b.hour.used += bytes
+	b.day.used += bytes
+	if !wasExhausted && (b.hour.exhausted() || b.day.exhausted()) {
+		log.Warn("Light client bandwidth budget exhausted", "hourly", b.hour.used, "daily", b.day.used)
+	}
+}
+
+// exhausted reports whether the traffic of either the current hour or day
+// reached its limit.
+func (b *bandwidthBudget) exhausted() bool {
+	if b == nil {
+		return false
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.clock()
+	b.hour.roll(now)
+	b.day.roll(now)
+	return b.hour.exhausted() || b.day.exhausted()
+}
+
+// BandwidthUsage is the traffic accounted against the bandwidth budget in the
+// current accounting periods. Limits of zero mean unlimited.
+type BandwidthUsage struct {
+	HourlyUsed  uint64    `json:"hourlyUsed"`
+	HourlyLimit uint64    `json:"hourlyLimit"`
+	HourlyReset time.Time `json:"hourlyReset"`
+	DailyUsed   uint64    `json:"dailyUsed"`
+	DailyLimit  uint64    `json:"dailyLimit"`
+	DailyReset  time.Time `json:"dailyReset"`
+	Exhausted   bool      `json:"exhausted"`
+}
+
+// usage returns the current state of the budget.
+func (b *bandwidthBudget) usage() *BandwidthUsage {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.clock()
+	b.hour.roll(now)
+	b.day.roll(now)
+	return &BandwidthUsage{
+		HourlyUsed:  b.hour.used,
+		HourlyLimit: b.hour.limit,
+		HourlyReset: b.hour.start.Add(b.hour.length),
+		DailyUsed:   b.day.used,
+		DailyLimit:  b.day.limit,
+		DailyReset:  b.day.start.Add(b.day.length),
+		Exhausted:   b.hour.exhausted() || b.day.exhausted(),
+	}
+}
+
+// budgetMsgReadWriter is a wrapper around a p2p.MsgReadWriter, accounting the
+// size of all messages against a bandwidth budget.
+type budgetMsgReadWriter struct {
+	p2p.MsgReadWriter
+	budget *bandwidthBudget
+}
+
+// newBudgetMsgReadWriter wraps a p2p MsgReadWriter with budget accounting. If no
+// budget is configured, this function returns the original object.
+func newBudgetMsgReadWriter(rw p2p.MsgReadWriter, budget *bandwidthBudget) p2p.MsgReadWriter {
+	if budget == nil {
+		return rw
+	}
+	return &budgetMsgReadWriter{MsgReadWriter: rw, budget: budget}
+}
+
+func (rw *budgetMsgReadWriter) ReadMsg() (p2p.Msg, error) {
+	msg, err := rw.MsgReadWriter.ReadMsg()
+	if err == nil {
+		rw.budget.add(uint64(msg.Size))
+	}
+	return msg, err
+}
+
+func (rw *budgetMsgReadWriter) WriteMsg(msg p2p.Msg) error {
+	rw.budget.add(uint64(msg.Size))
+	return rw.MsgReadWriter.WriteMsg(msg)
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that the bandwidth budget is exhausted once either limit is reached and
+// replenished when the accounting period rolls over.
+func TestBandwidthBudget(t *testing.T) {
+	if newBandwidthBudget(0, 0) != nil {
+		t.Fatalf("unlimited budget created")
+	}
+	now := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	budget := newBandwidthBudget(1, 2)
+	budget.clock = func() time.Time { return now }
+	budget.hour.start, budget.day.start = now, now
+
+	budget.add(1024 * 1024)
+	if !budget.exhausted() {
+		t.Fatalf("hourly budget not exhausted")
+	}
+	now = now.Add(time.Hour)
+	if budget.exhausted() {
+		t.Fatalf("budget exhausted after hourly rollover")
+	}
+	budget.add(1024 * 1024)
+	now = now.Add(time.Hour)
+	if !budget.exhausted() {
+		t.Fatalf("daily budget not exhausted")
+	}
+	if usage := budget.usage(); usage.HourlyUsed != 0 || usage.DailyUsed != 2*1024*1024 || !usage.Exhausted {
+		t.Fatalf("usage mismatch: %+v", usage)
+	}
+	now = now.Add(24 * time.Hour)
+	if budget.exhausted() {
+		t.Fatalf("budget exhausted after daily rollover")
+	}
+}
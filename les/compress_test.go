@@ -0,0 +1,68 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/goola-team/goola/light"
+	"github.com/goola-team/goola/p2p"
+	"github.com/goola-team/goola/rlp"
+)
+
+// Tests that les/3 replies are compressed on the wire and decode into the same
+// content as uncompressed les/2 ones once unpacked.
+func TestCompressedResponse(t *testing.T) {
+	var proofs light.NodeList
+	for i := byte(1); i <= 2; i++ {
+		node, _ := rlp.EncodeToBytes(bytes.Repeat([]byte{i}, 1024))
+		proofs = append(proofs, node)
+	}
+
+	sizes := make(map[int]uint32)
+	for _, version := range []int{lpv2, lpv3} {
+		app, net := p2p.MsgPipe()
+		p := &peer{version: version, rw: app}
+		go p.SendProofsV2(1, 2, proofs)
+
+		msg, err := net.ReadMsg()
+		if err != nil {
+			t.Fatalf("les/%d: failed to read reply: %v", version, err)
+		}
+		sizes[version] = msg.Size
+		if version >= lpv3 {
+			if err := decompressResponse(&msg); err != nil {
+				t.Fatalf("les/%d: failed to decompress reply: %v", version, err)
+			}
+		}
+		var resp struct {
+			ReqID, BV uint64
+			Data      light.NodeList
+		}
+		if err := msg.Decode(&resp); err != nil {
+			t.Fatalf("les/%d: failed to decode reply: %v", version, err)
+		}
+		if resp.ReqID != 1 || resp.BV != 2 || len(resp.Data) != 2 || !bytes.Equal(resp.Data[1], proofs[1]) {
+			t.Errorf("les/%d: reply mismatch: have %d/%d with %d nodes", version, resp.ReqID, resp.BV, len(resp.Data))
+		}
+		app.Close()
+	}
+	if sizes[lpv3] >= sizes[lpv2] {
+		t.Errorf("compressed reply not smaller: have %d, uncompressed %d", sizes[lpv3], sizes[lpv2])
+	}
+}
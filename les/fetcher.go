@@ -391,6 +391,11 @@ func (f *lightFetcher) requestedID(reqID uint64) bool {
 // nextRequest selects the peer and announced head to be requested next, amount
 // to be downloaded starting from the head backwards is also returned
 func (f *lightFetcher) nextRequest() (*distReq, uint64) {
+	// Hold back header retrievals until the bandwidth budget is replenished, the
+	// next announcement will restart them.
+	if f.pm.budget.exhausted() {
+		return nil, 0
+	}
 	var (
 		bestHash   common.Hash
 		bestAmount uint64
@@ -400,6 +405,10 @@ func (f *lightFetcher) nextRequest() (*distReq, uint64) {
 
 	for p, fp := range f.peers {
 		for hash, n := range fp.nodeByHash {
+			// Announcements carry no difficulty, so nodes only get one once validated
+			if n.td == nil {
+				continue
+			}
 			if !f.checkKnownNode(p, n) && !n.requested && (bestTd == nil || n.td.Cmp(bestTd) >= 0) {
 				amount := f.requestAmount(p, n)
 				if bestTd == nil || n.td.Cmp(bestTd) > 0 || amount < bestAmount {
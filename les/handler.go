@@ -27,6 +27,7 @@ import (
 
 	"github.com/goola-team/goola/common"
 	"github.com/goola-team/goola/consensus"
+	"github.com/goola-team/goola/consensus/dpos"
 	"github.com/goola-team/goola/core"
 	"github.com/goola-team/goola/core/state"
 	"github.com/goola-team/goola/core/types"
@@ -65,8 +66,26 @@ const (
 // not compatible (low protocol version restrictions and high requirements).
 var errIncompatibleConfig = errors.New("incompatible configuration")
 
+// protoError is an error caused by a remote peer, tagged with its error code.
+type protoError struct {
+	code errCode
+	msg  string
+}
+
+func (e *protoError) Error() string { return e.msg }
+
 func errResp(code errCode, format string, v ...interface{}) error {
-	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+	return &protoError{code: code, msg: fmt.Sprintf("%v - %v", code, fmt.Sprintf(format, v...))}
+}
+
+// reportViolation reports the last message received from a peer to the p2p
+// layer if the error was caused by it being malformed or violating the protocol.
+func reportViolation(p *peer, err error) {
+	if perr, ok := err.(*protoError); ok {
+		if violation, ok := errorToViolation[perr.code]; ok {
+			p.ReportViolation("les", violation, err)
+		}
+	}
 }
 
 type BlockChain interface {
@@ -103,6 +122,7 @@ type ProtocolManager struct {
 	lesTopic    discv5.Topic
 	reqDist     *requestDistributor
 	retriever   *retrieveManager
+	budget      *bandwidthBudget // Bandwidth budget of light clients on metered connections
 
 	downloader *downloader.Downloader
 	fetcher    *lightFetcher
@@ -252,13 +272,14 @@ func (pm *ProtocolManager) Stop() {
 }
 
 func (pm *ProtocolManager) newPeer(pv int, nv uint64, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
-	return newPeer(pv, nv, p, newMeteredMsgWriter(rw))
+	return newPeer(pv, nv, p, newMeteredMsgWriter(newBudgetMsgReadWriter(rw, pm.budget)))
 }
 
 // handle is the callback invoked to manage the life cycle of a les peer. When
 // this function terminates, the peer is disconnected.
 func (pm *ProtocolManager) handle(p *peer) error {
-	if pm.peers.Len() >= pm.maxPeers {
+	// Clients with a prepaid balance are admitted even if the free slots are taken
+	if pm.peers.Len() >= pm.maxPeers && (pm.server == nil || !pm.server.prioritized(p.ID())) {
 		return p2p.DiscTooManyPeers
 	}
 
@@ -273,6 +294,7 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	)
 	if err := p.Handshake( hash, number, genesis.Hash(), pm.server); err != nil {
 		p.Log().Debug("Light Goola handshake failed", "err", err)
+		reportViolation(p, err)
 		return err
 	}
 	if rw, ok := p.rw.(*meteredMsgReadWriter); ok {
@@ -287,6 +309,9 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		if pm.server != nil && pm.server.fcManager != nil && p.fcClient != nil {
 			p.fcClient.Remove(pm.server.fcManager)
 		}
+		if p.paid {
+			pm.server.payments.store()
+		}
 		pm.removePeer(p.id)
 	}()
 	// Register the peer in the downloader. If the downloader considers it banned, we disconnect
@@ -321,11 +346,26 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	for {
 		if err := pm.handleMsg(p); err != nil {
 			p.Log().Debug("Light Goola message handling failed", "err", err)
+			reportViolation(p, err)
 			return err
 		}
 	}
 }
 
+// responseMsgs are the message codes of the replies to requests, their payloads
+// being snappy-compressed on les/3 connections.
+var responseMsgs = map[uint64]bool{
+	BlockHeadersMsg:     true,
+	BlockBodiesMsg:      true,
+	CodeMsg:             true,
+	ReceiptsMsg:         true,
+	ProofsV1Msg:         true,
+	ProofsV2Msg:         true,
+	HeaderProofsMsg:     true,
+	HelperTrieProofsMsg: true,
+	TxStatusMsg:         true,
+}
+
 var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsV1Msg, SendTxMsg, SendTxV2Msg, GetTxStatusMsg, GetHeaderProofsMsg, GetProofsV2Msg, GetHelperTrieProofsMsg}
 
 // handleMsg is invoked whenever an inbound message is received from a remote
@@ -345,14 +385,20 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 		bufValue, _ := p.fcClient.AcceptRequest()
 		cost := costs.baseCost + reqCnt*costs.reqCost
-		if cost > pm.server.defParams.BufLimit {
-			cost = pm.server.defParams.BufLimit
+		if cost > p.fcParams.BufLimit {
+			cost = p.fcParams.BufLimit
 		}
 		if cost > bufValue {
-			recharge := time.Duration((cost - bufValue) * 1000000 / pm.server.defParams.MinRecharge)
+			recharge := time.Duration((cost - bufValue) * 1000000 / p.fcParams.MinRecharge)
 			p.Log().Error("Request came too early", "recharge", common.PrettyDuration(recharge))
 			return true
 		}
+		if p.paid {
+			if err := pm.server.payments.charge(p.ID(), cost); err != nil {
+				p.Log().Debug("Rejecting prioritized request", "cost", cost, "err", err)
+				return true
+			}
+		}
 		return false
 	}
 
@@ -361,6 +407,12 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 	}
 	defer msg.Discard()
 
+	// Unpack the compressed payloads of les/3 replies
+	if p.version >= lpv3 && responseMsgs[msg.Code] {
+		if err := decompressResponse(&msg); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+	}
 	var deliverMsg *Msg
 
 	// Handle the message depending on its contents
@@ -1126,6 +1178,20 @@ func (pm *ProtocolManager) getHelperTrieAuxData(req HelperTrieReq) []byte {
 		blockNum := binary.BigEndian.Uint64(req.Key)
 		hash := core.GetCanonicalHash(pm.chainDb, blockNum)
 		return core.GetHeaderRLP(pm.chainDb, hash, blockNum)
+	case req.Type == htEpochs && req.AuxReq == auxEpochProof:
+		if config := pm.chainConfig.Ethash; config == nil || config.Epoch == 0 {
+			return nil
+		}
+		chain, ok := pm.blockchain.(consensus.ChainReader)
+		if !ok {
+			return nil
+		}
+		headers, err := dpos.EpochProof(chain, req.TrieIdx)
+		if err != nil {
+			return nil
+		}
+		data, _ := rlp.EncodeToBytes(headers)
+		return data
 	}
 	return nil
 }
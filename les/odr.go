@@ -18,11 +18,13 @@ package les
 
 import (
 	"context"
+	"reflect"
 
 	"github.com/goola-team/goola/core"
 	"github.com/goola-team/goola/gooladb"
 	"github.com/goola-team/goola/light"
-	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rpc"
+	"github.com/goola-team/goola/tracing"
 )
 
 // LesOdr implements light.OdrBackend
@@ -30,6 +32,7 @@ type LesOdr struct {
 	db                                         gooladb.Database
 	chtIndexer, bloomTrieIndexer, bloomIndexer *core.ChainIndexer
 	retriever                                  *retrieveManager
+	rpcRelay                                   *rpcRelay // Optional fallback if no LES server can serve a request
 	stop                                       chan struct{}
 }
 
@@ -47,6 +50,9 @@ func NewLesOdr(db gooladb.Database, chtIndexer, bloomTrieIndexer, bloomIndexer *
 // Stop cancels all pending retrievals
 func (odr *LesOdr) Stop() {
 	close(odr.stop)
+	if odr.rpcRelay != nil {
+		odr.rpcRelay.stop()
+	}
 }
 
 // Database returns the backing database
@@ -86,9 +92,15 @@ type Msg struct {
 	Obj     interface{}
 }
 
-// Retrieve tries to fetch an object from the LES network.
+// Retrieve tries to fetch an object from the LES network, falling back to the
+// trusted JSON-RPC relay servers (if any) when no LES server is able to serve it.
 // If the network retrieval was successful, it stores the object in local db.
 func (odr *LesOdr) Retrieve(ctx context.Context, req light.OdrRequest) (err error) {
+	ctx, span := tracing.Start(ctx, "odr.retrieve", "request", reflect.TypeOf(req))
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
 	lreq := LesRequest(req)
 
 	reqID := genReqID()
@@ -108,11 +120,15 @@ func (odr *LesOdr) Retrieve(ctx context.Context, req light.OdrRequest) (err erro
 		},
 	}
 
-	if err = odr.retriever.retrieve(ctx, reqID, rq, func(p distPeer, msg *Msg) error { return lreq.Validate(odr.db, msg) }, odr.stop); err == nil {
+	err = odr.retriever.retrieve(ctx, reqID, rq, func(p distPeer, msg *Msg) error { return lreq.Validate(odr.db, msg) }, odr.stop)
+	if err == ErrNoPeers && odr.rpcRelay != nil {
+		err = odr.rpcRelay.retrieve(ctx, odr.db, req)
+	}
+	if err == nil {
 		// retrieved from network, store in db
 		req.StoreResult(odr.db)
 	} else {
-		log.Debug("Failed to retrieve data from network", "err", err)
+		rpc.RequestLogger(ctx).Debug("Failed to retrieve data from network", "err", err)
 	}
 	return
 }
@@ -24,6 +24,7 @@ import (
 	"fmt"
 
 	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/consensus/dpos"
 	"github.com/goola-team/goola/core"
 	"github.com/goola-team/goola/core/types"
 	"github.com/goola-team/goola/crypto"
@@ -61,12 +62,16 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*ReceiptsRequest)(r)
 	case *light.TrieRequest:
 		return (*TrieRequest)(r)
+	case *light.BatchTrieRequest:
+		return (*BatchTrieRequest)(r)
 	case *light.CodeRequest:
 		return (*CodeRequest)(r)
 	case *light.ChtRequest:
 		return (*ChtRequest)(r)
 	case *light.BloomRequest:
 		return (*BloomRequest)(r)
+	case *light.EpochProofRequest:
+		return (*EpochProofRequest)(r)
 	default:
 		return nil
 	}
@@ -83,7 +88,7 @@ func (r *BlockRequest) GetCost(peer *peer) uint64 {
 
 // CanSend tells if a certain peer is suitable for serving the given request
 func (r *BlockRequest) CanSend(peer *peer) bool {
-	return peer.HasBlock(r.Hash, r.Number)
+	return peer.HasBlock(r.Hash, r.Number) && peer.ServesHistory(r.Number)
 }
 
 // Request sends an ODR request to the LES network (implementation of LesOdrRequest)
@@ -137,7 +142,7 @@ func (r *ReceiptsRequest) GetCost(peer *peer) uint64 {
 
 // CanSend tells if a certain peer is suitable for serving the given request
 func (r *ReceiptsRequest) CanSend(peer *peer) bool {
-	return peer.HasBlock(r.Hash, r.Number)
+	return peer.HasBlock(r.Hash, r.Number) && peer.ServesHistory(r.Number)
 }
 
 // Request sends an ODR request to the LES network (implementation of LesOdrRequest)
@@ -190,7 +195,7 @@ func (r *TrieRequest) GetCost(peer *peer) uint64 {
 	switch peer.version {
 	case lpv1:
 		return peer.GetRequestCost(GetProofsV1Msg, 1)
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.GetRequestCost(GetProofsV2Msg, 1)
 	default:
 		panic(nil)
@@ -253,6 +258,65 @@ func (r *TrieRequest) Validate(db gooladb.Database, msg *Msg) error {
 	}
 }
 
+// ODR request type for batches of state/storage trie entries, see LesOdrRequest
+// interface
+type BatchTrieRequest light.BatchTrieRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *BatchTrieRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetProofsV2Msg, len(r.Reqs))
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request.
+// Only les/2 peers can return the proofs as a single deduplicated node set.
+func (r *BatchTrieRequest) CanSend(peer *peer) bool {
+	if peer.version < lpv2 || len(r.Reqs) == 0 {
+		return false
+	}
+	return peer.HasBlock(r.Reqs[0].Id.BlockHash, r.Reqs[0].Id.BlockNumber)
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *BatchTrieRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting batched trie proofs", "count", len(r.Reqs))
+	reqs := make([]ProofReq, len(r.Reqs))
+	for i, req := range r.Reqs {
+		reqs[i] = ProofReq{
+			BHash:  req.Id.BlockHash,
+			AccKey: req.Id.AccKey,
+			Key:    req.Key,
+		}
+	}
+	return peer.RequestProofs(reqID, r.GetCost(peer), reqs)
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *BatchTrieRequest) Validate(db gooladb.Database, msg *Msg) error {
+	log.Debug("Validating batched trie proofs", "count", len(r.Reqs))
+
+	if msg.MsgType != MsgProofsV2 {
+		return errInvalidMessageType
+	}
+	proofs := msg.Obj.(light.NodeList)
+	// Verify all the proofs against the shared node set and store if checks out
+	nodeSet := proofs.NodeSet()
+	reads := &readTraceDB{db: nodeSet}
+	for _, req := range r.Reqs {
+		if _, err, _ := trie.VerifyProof(req.Id.Root, req.Key, reads); err != nil {
+			return fmt.Errorf("merkle proof verification failed: %v", err)
+		}
+	}
+	// check if all nodes have been read by VerifyProof
+	if len(reads.reads) != nodeSet.KeyCount() {
+		return errUselessNodes
+	}
+	r.Proof = nodeSet
+	return nil
+}
+
 type CodeReq struct {
 	BHash  common.Hash
 	AccKey []byte
@@ -310,11 +374,14 @@ const (
 	// helper trie type constants
 	htCanonical = iota // Canonical hash trie
 	htBloomBits        // BloomBits trie
+	htEpochs           // Dpos epoch transitions, not backed by a trie
 
 	// applicable for all helper trie requests
 	auxRoot = 1
 	// applicable for htCanonical
 	auxHeader = 2
+	// applicable for htEpochs
+	auxEpochProof = 3
 )
 
 type HelperTrieReq struct {
@@ -350,7 +417,7 @@ func (r *ChtRequest) GetCost(peer *peer) uint64 {
 	switch peer.version {
 	case lpv1:
 		return peer.GetRequestCost(GetHeaderProofsMsg, 1)
-	case lpv2:
+	case lpv2, lpv3:
 		return peer.GetRequestCost(GetHelperTrieProofsMsg, 1)
 	default:
 		panic(nil)
@@ -559,3 +626,59 @@ func (db *readTraceDB) Has(key []byte) (bool, error) {
 	_, err := db.Get(key)
 	return err == nil, nil
 }
+
+// ODR request type for requesting dpos epoch transition proofs, see LesOdrRequest interface
+type EpochProofRequest light.EpochProofRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *EpochProofRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetHelperTrieProofsMsg, 1)
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *EpochProofRequest) CanSend(peer *peer) bool {
+	peer.lock.RLock()
+	defer peer.lock.RUnlock()
+
+	return peer.version >= lpv2 && peer.headInfo.Number > r.Epoch*r.Config.Epoch
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *EpochProofRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting epoch proof", "epoch", r.Epoch)
+	req := HelperTrieReq{
+		Type:    htEpochs,
+		TrieIdx: r.Epoch,
+		AuxReq:  auxEpochProof,
+	}
+	return peer.RequestHelperTrieProofs(reqID, r.GetCost(peer), []HelperTrieReq{req})
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *EpochProofRequest) Validate(db gooladb.Database, msg *Msg) error {
+	log.Debug("Validating epoch proof", "epoch", r.Epoch)
+
+	if msg.MsgType != MsgHelperTrieProofs {
+		return errInvalidMessageType
+	}
+	resp := msg.Obj.(HelperTrieResps)
+	if len(resp.AuxData) != 1 {
+		return errInvalidEntryCount
+	}
+	if len(resp.AuxData[0]) == 0 {
+		return errHeaderUnavailable
+	}
+	var headers []*types.Header
+	if err := rlp.DecodeBytes(resp.AuxData[0], &headers); err != nil {
+		return err
+	}
+	validators, err := dpos.VerifyEpochProof(r.Config, r.Epoch, r.Previous, headers)
+	if err != nil {
+		return err
+	}
+	r.Headers, r.Validators = headers, validators
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/light"
+	"github.com/goola-team/goola/trie"
+)
+
+// Tests that batched trie proofs are verified against their root and delivered
+// as a single deduplicated node set.
+func TestBatchTrieRequestValidate(t *testing.T) {
+	// Create a trie large enough for the proofs to share their upper nodes
+	diskdb, _ := gooladb.NewMemDatabase()
+	tr, _ := trie.New(common.Hash{}, trie.NewDatabase(diskdb))
+	for i := 0; i < 256; i++ {
+		key := crypto.Keccak256([]byte(fmt.Sprintf("key%d", i)))
+		tr.Update(key, []byte(fmt.Sprintf("value%d", i)))
+	}
+	root, _ := tr.Commit(nil)
+
+	id := &light.TrieID{Root: root}
+	keys := [][]byte{
+		crypto.Keccak256([]byte("key1")),
+		crypto.Keccak256([]byte("key2")),
+		crypto.Keccak256([]byte("missing")), // Proof of absence
+	}
+	var (
+		reqs  = make([]*light.TrieRequest, len(keys))
+		proof = light.NewNodeSet()
+		nodes int
+	)
+	for i, key := range keys {
+		reqs[i] = &light.TrieRequest{Id: id, Key: key}
+
+		single := light.NewNodeSet()
+		if err := tr.Prove(key, 0, single); err != nil {
+			t.Fatalf("failed to prove key %x: %v", key, err)
+		}
+		nodes += single.KeyCount()
+
+		tr.Prove(key, 0, proof)
+	}
+	if proof.KeyCount() >= nodes {
+		t.Fatalf("proof nodes not shared: %d in batch, %d in single proofs", proof.KeyCount(), nodes)
+	}
+	// A reply with the shared node set is accepted
+	db, _ := gooladb.NewMemDatabase()
+
+	req := &BatchTrieRequest{Reqs: reqs}
+	if err := req.Validate(db, &Msg{MsgType: MsgProofsV2, Obj: proof.NodeList()}); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+	if req.Proof.KeyCount() != proof.KeyCount() {
+		t.Errorf("delivered proof size mismatch: have %d, want %d", req.Proof.KeyCount(), proof.KeyCount())
+	}
+	// Replies of the wrong type, with missing or superfluous nodes are rejected
+	if err := (&BatchTrieRequest{Reqs: reqs}).Validate(db, &Msg{MsgType: MsgProofsV1, Obj: proof.NodeList()}); err != errInvalidMessageType {
+		t.Errorf("wrong message type error mismatch: have %v, want %v", err, errInvalidMessageType)
+	}
+	list := proof.NodeList()
+	if err := (&BatchTrieRequest{Reqs: reqs}).Validate(db, &Msg{MsgType: MsgProofsV2, Obj: list[1:]}); err == nil {
+		t.Errorf("incomplete proof accepted")
+	}
+	extra := append(light.NodeList{[]byte("superfluous")}, list...)
+	if err := (&BatchTrieRequest{Reqs: reqs}).Validate(db, &Msg{MsgType: MsgProofsV2, Obj: extra}); err != errUselessNodes {
+		t.Errorf("superfluous node error mismatch: have %v, want %v", err, errUselessNodes)
+	}
+	other := &light.TrieID{Root: common.Hash{0x01}}
+	forged := []*light.TrieRequest{reqs[0], {Id: other, Key: keys[1]}}
+	if err := (&BatchTrieRequest{Reqs: forged}).Validate(db, &Msg{MsgType: MsgProofsV2, Obj: list}); err == nil {
+		t.Errorf("proof for foreign root accepted")
+	}
+}
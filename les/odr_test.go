@@ -29,6 +29,7 @@ import (
 	"github.com/goola-team/goola/core/state"
 	"github.com/goola-team/goola/core/types"
 	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/crypto"
 	"github.com/goola-team/goola/goolabackend"
 	"github.com/goola-team/goola/gooladb"
 	"github.com/goola-team/goola/light"
@@ -104,6 +105,39 @@ func odrAccounts(ctx context.Context, db gooladb.Database, config *params.ChainC
 	return res
 }
 
+func TestOdrBatchProofsLes2(t *testing.T) { testOdr(t, 2, 1, odrBatchProofs) }
+
+func odrBatchProofs(ctx context.Context, db gooladb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte {
+	dummyAddr := common.HexToAddress("1234567812345678123456781234567812345678")
+	acc := []common.Address{testBankAddress, acc1Addr, acc2Addr, dummyAddr}
+
+	var header *types.Header
+	if bc != nil {
+		header = bc.GetHeaderByHash(bhash)
+	} else {
+		// Retrieve all the account proofs in one go, the state is read locally after
+		header = lc.GetHeaderByHash(bhash)
+		reqs := make([]*light.TrieRequest, len(acc))
+		for i, addr := range acc {
+			reqs[i] = &light.TrieRequest{Id: light.StateTrieID(header), Key: crypto.Keccak256(addr[:])}
+		}
+		lc.Odr().Retrieve(ctx, &light.BatchTrieRequest{Reqs: reqs})
+	}
+	st, err := state.New(header.Root, state.NewDatabase(db))
+	if err != nil {
+		return nil
+	}
+	var res []byte
+	for _, addr := range acc {
+		rlp, _ := rlp.EncodeToBytes(st.GetBalance(addr))
+		res = append(res, rlp...)
+	}
+	if st.Error() != nil {
+		return nil
+	}
+	return res
+}
+
 func TestOdrContractCallLes1(t *testing.T) { testOdr(t, 1, 2, odrContractCall) }
 
 func TestOdrContractCallLes2(t *testing.T) { testOdr(t, 2, 2, odrContractCall) }
@@ -128,7 +162,7 @@ func odrContractCall(ctx context.Context, db gooladb.Database, config *params.Ch
 				from := statedb.GetOrNewStateObject(testBankAddress)
 				from.SetBalance(math.MaxBig256)
 
-				msg := callmsg{types.NewMessage(from.Address(), &testContractAddr, 0, new(big.Int), 100000, new(big.Int), data, false)}
+				msg := callmsg{types.NewMessage(from.Address(), &testContractAddr, 0, new(big.Int), 100000, new(big.Int), types.TxTypeContract, data, false)}
 
 				context := core.NewEVMContext(msg, header, bc, nil)
 				vmenv := vm.NewEVM(context, statedb, config, vm.Config{})
@@ -142,7 +176,7 @@ func odrContractCall(ctx context.Context, db gooladb.Database, config *params.Ch
 			header := lc.GetHeaderByHash(bhash)
 			state := light.NewState(ctx, header, lc.Odr())
 			state.SetBalance(testBankAddress, math.MaxBig256)
-			msg := callmsg{types.NewMessage(testBankAddress, &testContractAddr, 0, new(big.Int), 100000, new(big.Int), data, false)}
+			msg := callmsg{types.NewMessage(testBankAddress, &testContractAddr, 0, new(big.Int), 100000, new(big.Int), types.TxTypeContract, data, false)}
 			context := core.NewEVMContext(msg, header, lc, nil)
 			vmenv := vm.NewEVM(context, state, config, vm.Config{})
 			gp := new(core.GasPool).AddGas(math.MaxUint64)
@@ -0,0 +1,131 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/p2p/discover"
+)
+
+// paidBufferBoost is the factor by which the flow control buffer and recharge
+// rate of clients with a positive balance are raised over the free defaults.
+const paidBufferBoost = 4
+
+var (
+	balancePrefix = []byte("les-balance-") // balancePrefix + node id -> prepaid balance
+
+	errBalanceExhausted = errors.New("prepaid balance exhausted")
+)
+
+// paymentLedger keeps the prepaid service balances of light clients. Clients
+// with a positive balance are served with priority: they are admitted even if
+// the free client slots are taken and enjoy boosted flow control parameters,
+// while the cost of every request they make is charged against their balance.
+//
+// Balances are topped up by the server operator upon receiving off-chain
+// payments (e.g. vouchers or tickets) and are denominated in request cost units.
+type paymentLedger struct {
+	db       gooladb.Database
+	balances map[discover.NodeID]uint64 // Balances loaded from the database
+	dirty    map[discover.NodeID]struct{}
+	lock     sync.Mutex
+}
+
+func newPaymentLedger(db gooladb.Database) *paymentLedger {
+	return &paymentLedger{
+		db:       db,
+		balances: make(map[discover.NodeID]uint64),
+		dirty:    make(map[discover.NodeID]struct{}),
+	}
+}
+
+// load retrieves the balance of a client, caching it. The lock must be held.
+func (l *paymentLedger) load(id discover.NodeID) uint64 {
+	if balance, ok := l.balances[id]; ok {
+		return balance
+	}
+	var balance uint64
+	if data, err := l.db.Get(append(balancePrefix, id[:]...)); err == nil && len(data) == 8 {
+		balance = binary.BigEndian.Uint64(data)
+	}
+	l.balances[id] = balance
+	return balance
+}
+
+// balance returns the prepaid balance of a client.
+func (l *paymentLedger) balance(id discover.NodeID) uint64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.load(id)
+}
+
+// topUp credits a client's balance, persisting it immediately, and returns the
+// new balance.
+func (l *paymentLedger) topUp(id discover.NodeID, amount uint64) (uint64, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	balance := l.load(id) + amount
+	if balance < amount {
+		return 0, errors.New("balance overflow")
+	}
+	l.balances[id] = balance
+	delete(l.dirty, id)
+
+	return balance, l.write(id, balance)
+}
+
+// charge deducts the cost of a request from a client's balance, failing if the
+// balance doesn't cover it. Charges are persisted lazily by store.
+func (l *paymentLedger) charge(id discover.NodeID, cost uint64) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	balance := l.load(id)
+	if balance < cost {
+		return errBalanceExhausted
+	}
+	l.balances[id] = balance - cost
+	l.dirty[id] = struct{}{}
+	return nil
+}
+
+// store persists the balances charged since the last store.
+func (l *paymentLedger) store() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for id := range l.dirty {
+		if err := l.write(id, l.balances[id]); err != nil {
+			log.Error("Failed to store light client balance", "id", id, "err", err)
+		}
+	}
+	l.dirty = make(map[discover.NodeID]struct{})
+}
+
+// write stores the balance of a client in the database.
+func (l *paymentLedger) write(id discover.NodeID, balance uint64) error {
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], balance)
+	return l.db.Put(append(balancePrefix, id[:]...), data[:])
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"testing"
+
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/p2p/discover"
+)
+
+// Tests that prepaid balances are charged, refuse requests they can't cover and
+// survive restarts once stored.
+func TestPaymentLedger(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	ledger := newPaymentLedger(db)
+
+	id := discover.NodeID{0x01}
+	if balance := ledger.balance(id); balance != 0 {
+		t.Fatalf("initial balance mismatch: have %d, want 0", balance)
+	}
+	if err := ledger.charge(id, 1); err != errBalanceExhausted {
+		t.Fatalf("unfunded charge error mismatch: have %v, want %v", err, errBalanceExhausted)
+	}
+	if balance, err := ledger.topUp(id, 1000); err != nil || balance != 1000 {
+		t.Fatalf("top up mismatch: have %d (%v), want 1000", balance, err)
+	}
+	if err := ledger.charge(id, 400); err != nil {
+		t.Fatalf("failed to charge funded request: %v", err)
+	}
+	if err := ledger.charge(id, 700); err != errBalanceExhausted {
+		t.Fatalf("overdraft error mismatch: have %v, want %v", err, errBalanceExhausted)
+	}
+	// Charges are only persisted when stored
+	if balance := newPaymentLedger(db).balance(id); balance != 1000 {
+		t.Fatalf("unstored balance mismatch: have %d, want 1000", balance)
+	}
+	ledger.store()
+	if balance := newPaymentLedger(db).balance(id); balance != 600 {
+		t.Fatalf("stored balance mismatch: have %d, want 600", balance)
+	}
+}
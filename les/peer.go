@@ -18,15 +18,17 @@
 package les
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"encoding/binary"
 	"errors"
 	"fmt"
-	"math/big"
 	"sync"
 	"time"
 
+	"github.com/golang/snappy"
 	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core"
 	"github.com/goola-team/goola/core/types"
 	"github.com/goola-team/goola/les/flowcontrol"
 	"github.com/goola-team/goola/light"
@@ -70,11 +72,14 @@ type peer struct {
 
 	poolEntry      *poolEntry
 	hasBlock       func(common.Hash, uint64) bool
+	chainSince     uint64 // Oldest block the server retains bodies and receipts of
 	responseErrors int
 
 	fcClient       *flowcontrol.ClientNode // nil if the peer is server only
 	fcServer       *flowcontrol.ServerNode // nil if the peer is client only
 	fcServerParams *flowcontrol.ServerParams
+	fcParams       *flowcontrol.ServerParams // Flow control parameters assigned to the client, nil if the peer is server only
+	paid           bool                      // Whether the client's requests are charged against its prepaid balance
 	fcCosts        requestCostTable
 }
 
@@ -156,6 +161,51 @@ func sendResponse(w p2p.MsgWriter, msgcode, reqID, bv uint64, data interface{})
 	return p2p.Send(w, msgcode, resp{reqID, bv, data})
 }
 
+// respond sends the reply to a request, snappy-compressing its RLP encoded
+// payload on les/3 connections.
+func (p *peer) respond(msgcode, reqID, bv uint64, data interface{}) error {
+	if p.version < lpv3 {
+		return sendResponse(p.rw, msgcode, reqID, bv, data)
+	}
+	enc, err := rlp.EncodeToBytes(data)
+	if err != nil {
+		return err
+	}
+	return sendResponse(p.rw, msgcode, reqID, bv, snappy.Encode(nil, enc))
+}
+
+// decompressResponse replaces the payload of an les/3 reply with its decompressed
+// les/2 equivalent, bounding the decompressed size by the maximum message size.
+func decompressResponse(msg *p2p.Msg) error {
+	var resp struct {
+		ReqID, BV uint64
+		Data      []byte
+	}
+	if err := msg.Decode(&resp); err != nil {
+		return err
+	}
+	size, err := snappy.DecodedLen(resp.Data)
+	if err != nil {
+		return err
+	}
+	if size > ProtocolMaxMsgSize {
+		return fmt.Errorf("decompressed payload too large: %d > %d", size, ProtocolMaxMsgSize)
+	}
+	data, err := snappy.Decode(nil, resp.Data)
+	if err != nil {
+		return err
+	}
+	payload, err := rlp.EncodeToBytes(struct {
+		ReqID, BV uint64
+		Data      rlp.RawValue
+	}{resp.ReqID, resp.BV, data})
+	if err != nil {
+		return err
+	}
+	msg.Payload, msg.Size = bytes.NewReader(payload), uint32(len(payload))
+	return nil
+}
+
 func (p *peer) GetRequestCost(msgcode uint64, amount int) uint64 {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
@@ -175,6 +225,12 @@ func (p *peer) HasBlock(hash common.Hash, number uint64) bool {
 	return hasBlock != nil && hasBlock(hash, number)
 }
 
+// ServesHistory reports whether the server retains the body and receipts of
+// the given block, as advertised in the handshake.
+func (p *peer) ServesHistory(number uint64) bool {
+	return number == 0 || number >= p.chainSince
+}
+
 // SendAnnounce announces the availability of a number of blocks through
 // a hash notification.
 func (p *peer) SendAnnounce(request announceData) error {
@@ -183,50 +239,50 @@ func (p *peer) SendAnnounce(request announceData) error {
 
 // SendBlockHeaders sends a batch of block headers to the remote peer.
 func (p *peer) SendBlockHeaders(reqID, bv uint64, headers []*types.Header) error {
-	return sendResponse(p.rw, BlockHeadersMsg, reqID, bv, headers)
+	return p.respond(BlockHeadersMsg, reqID, bv, headers)
 }
 
 // SendBlockBodiesRLP sends a batch of block contents to the remote peer from
 // an already RLP encoded format.
 func (p *peer) SendBlockBodiesRLP(reqID, bv uint64, bodies []rlp.RawValue) error {
-	return sendResponse(p.rw, BlockBodiesMsg, reqID, bv, bodies)
+	return p.respond(BlockBodiesMsg, reqID, bv, bodies)
 }
 
 // SendCodeRLP sends a batch of arbitrary internal data, corresponding to the
 // hashes requested.
 func (p *peer) SendCode(reqID, bv uint64, data [][]byte) error {
-	return sendResponse(p.rw, CodeMsg, reqID, bv, data)
+	return p.respond(CodeMsg, reqID, bv, data)
 }
 
 // SendReceiptsRLP sends a batch of transaction receipts, corresponding to the
 // ones requested from an already RLP encoded format.
 func (p *peer) SendReceiptsRLP(reqID, bv uint64, receipts []rlp.RawValue) error {
-	return sendResponse(p.rw, ReceiptsMsg, reqID, bv, receipts)
+	return p.respond(ReceiptsMsg, reqID, bv, receipts)
 }
 
 // SendProofs sends a batch of legacy LES/1 merkle proofs, corresponding to the ones requested.
 func (p *peer) SendProofs(reqID, bv uint64, proofs proofsData) error {
-	return sendResponse(p.rw, ProofsV1Msg, reqID, bv, proofs)
+	return p.respond(ProofsV1Msg, reqID, bv, proofs)
 }
 
 // SendProofsV2 sends a batch of merkle proofs, corresponding to the ones requested.
 func (p *peer) SendProofsV2(reqID, bv uint64, proofs light.NodeList) error {
-	return sendResponse(p.rw, ProofsV2Msg, reqID, bv, proofs)
+	return p.respond(ProofsV2Msg, reqID, bv, proofs)
 }
 
 // SendHeaderProofs sends a batch of legacy LES/1 header proofs, corresponding to the ones requested.
 func (p *peer) SendHeaderProofs(reqID, bv uint64, proofs []ChtResp) error {
-	return sendResponse(p.rw, HeaderProofsMsg, reqID, bv, proofs)
+	return p.respond(HeaderProofsMsg, reqID, bv, proofs)
 }
 
 // SendHelperTrieProofs sends a batch of HelperTrie proofs, corresponding to the ones requested.
 func (p *peer) SendHelperTrieProofs(reqID, bv uint64, resp HelperTrieResps) error {
-	return sendResponse(p.rw, HelperTrieProofsMsg, reqID, bv, resp)
+	return p.respond(HelperTrieProofsMsg, reqID, bv, resp)
 }
 
 // SendTxStatus sends a batch of transaction status records, corresponding to the ones requested.
 func (p *peer) SendTxStatus(reqID, bv uint64, stats []txStatus) error {
-	return sendResponse(p.rw, TxStatusMsg, reqID, bv, stats)
+	return p.respond(TxStatusMsg, reqID, bv, stats)
 }
 
 // RequestHeadersByHash fetches a batch of blocks' headers corresponding to the
@@ -269,7 +325,7 @@ func (p *peer) RequestProofs(reqID, cost uint64, reqs []ProofReq) error {
 	switch p.version {
 	case lpv1:
 		return sendRequest(p.rw, GetProofsV1Msg, reqID, cost, reqs)
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, GetProofsV2Msg, reqID, cost, reqs)
 	default:
 		panic(nil)
@@ -291,7 +347,7 @@ func (p *peer) RequestHelperTrieProofs(reqID, cost uint64, reqs []HelperTrieReq)
 			reqsV1[i] = ChtReq{ChtNum: (req.TrieIdx + 1) * (light.CHTFrequencyClient / light.CHTFrequencyServer), BlockNum: blockNum, FromLevel: req.FromLevel}
 		}
 		return sendRequest(p.rw, GetHeaderProofsMsg, reqID, cost, reqsV1)
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, GetHelperTrieProofsMsg, reqID, cost, reqs)
 	default:
 		panic(nil)
@@ -310,7 +366,7 @@ func (p *peer) SendTxs(reqID, cost uint64, txs types.Transactions) error {
 	switch p.version {
 	case lpv1:
 		return p2p.Send(p.rw, SendTxMsg, txs) // old message format does not include reqID
-	case lpv2:
+	case lpv2, lpv3:
 		return sendRequest(p.rw, SendTxV2Msg, reqID, cost, txs)
 	default:
 		panic(nil)
@@ -398,11 +454,12 @@ func (p *peer) Handshake( head common.Hash, headNum uint64, genesis common.Hash,
 	send = send.add("genesisHash", genesis)
 	if server != nil {
 		send = send.add("serveHeaders", nil)
-		send = send.add("serveChainSince", uint64(0))
+		send = send.add("serveChainSince", core.GetHistoryTail(server.protocolManager.chainDb))
 		send = send.add("serveStateSince", uint64(0))
 		send = send.add("txRelay", nil)
-		send = send.add("flowControl/BL", server.defParams.BufLimit)
-		send = send.add("flowControl/MRR", server.defParams.MinRecharge)
+		p.fcParams, p.paid = server.clientParams(p.ID())
+		send = send.add("flowControl/BL", p.fcParams.BufLimit)
+		send = send.add("flowControl/MRR", p.fcParams.MinRecharge)
 		list := server.fcCostStats.getCurrentList()
 		send = send.add("flowControl/MRC", list)
 		p.fcCosts = list.decode()
@@ -418,7 +475,6 @@ func (p *peer) Handshake( head common.Hash, headNum uint64, genesis common.Hash,
 
 	var rGenesis, rHash common.Hash
 	var rVersion, rNetwork, rNum uint64
-	var rTd *big.Int
 
 	if err := recv.get("protocolVersion", &rVersion); err != nil {
 		return err
@@ -426,9 +482,6 @@ func (p *peer) Handshake( head common.Hash, headNum uint64, genesis common.Hash,
 	if err := recv.get("networkId", &rNetwork); err != nil {
 		return err
 	}
-	if err := recv.get("headTd", &rTd); err != nil {
-		return err
-	}
 	if err := recv.get("headHash", &rHash); err != nil {
 		return err
 	}
@@ -456,9 +509,9 @@ func (p *peer) Handshake( head common.Hash, headNum uint64, genesis common.Hash,
 		if recv.get("announceType", &p.announceType) != nil {
 			p.announceType = announceTypeSimple
 		}
-		p.fcClient = flowcontrol.NewClientNode(server.fcManager, server.defParams)
+		p.fcClient = flowcontrol.NewClientNode(server.fcManager, p.fcParams)
 	} else {
-		if recv.get("serveChainSince", nil) != nil {
+		if recv.get("serveChainSince", &p.chainSince) != nil {
 			return errResp(ErrUselessPeer, "peer cannot serve chain")
 		}
 		if recv.get("serveStateSince", nil) != nil {
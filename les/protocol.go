@@ -36,17 +36,18 @@ import (
 const (
 	lpv1 = 1
 	lpv2 = 2
+	lpv3 = 3
 )
 
 // Supported versions of the les protocol (first is primary)
 var (
-	ClientProtocolVersions    = []uint{lpv2, lpv1}
-	ServerProtocolVersions    = []uint{lpv2, lpv1}
-	AdvertiseProtocolVersions = []uint{lpv2} // clients are searching for the first advertised protocol in the list
+	ClientProtocolVersions    = []uint{lpv3, lpv2, lpv1}
+	ServerProtocolVersions    = []uint{lpv3, lpv2, lpv1}
+	AdvertiseProtocolVersions = []uint{lpv3, lpv2} // clients are searching for the first advertised protocol in the list
 )
 
 // Number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = map[uint]uint64{lpv1: 15, lpv2: 22}
+var ProtocolLengths = map[uint]uint64{lpv1: 15, lpv2: 22, lpv3: 22}
 
 const (
 	NetworkId          = 1
@@ -101,6 +102,16 @@ const (
 	ErrMissingKey
 )
 
+// errorToViolation maps the error codes caused by malformed or protocol
+// violating messages to the violation types reported to the p2p layer.
+var errorToViolation = map[errCode]string{
+	ErrMsgTooLarge:    "msg-too-large",
+	ErrDecode:         "decode",
+	ErrInvalidMsgCode: "invalid-msg-code",
+	ErrNoStatusMsg:    "no-status",
+	ErrExtraStatusMsg: "extra-status",
+}
+
 func (e errCode) String() string {
 	return errorToString[int(e)]
 }
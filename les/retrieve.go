@@ -41,6 +41,7 @@ type retrieveManager struct {
 	dist       *requestDistributor
 	peers      *peerSet
 	serverPool peerSelector
+	budget     *bandwidthBudget // Bandwidth budget rejecting retrievals when exhausted
 
 	lock     sync.RWMutex
 	sentReqs map[uint64]*sentReq
@@ -113,6 +114,9 @@ func newRetrieveManager(peers *peerSet, dist *requestDistributor, serverPool pee
 // validator callback. It returns when a valid answer is delivered or the context is
 // cancelled.
 func (rm *retrieveManager) retrieve(ctx context.Context, reqID uint64, req *distReq, val validatorFunc, shutdown chan struct{}) error {
+	if rm.budget.exhausted() {
+		return errBudgetExhausted
+	}
 	sentReq := rm.sendReq(reqID, req, val)
 	select {
 	case <-sentReq.stopCh:
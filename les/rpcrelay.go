@@ -0,0 +1,187 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/light"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rlp"
+	"github.com/goola-team/goola/rpc"
+)
+
+// errRelayUnsupported is returned if an ODR request can't be satisfied through
+// the JSON-RPC interface of a full node.
+var errRelayUnsupported = errors.New("request not supported by rpc relay")
+
+// relayProofQuery is the JSON-RPC form of a trie entry to prove, see
+// debug_getTrieProofs.
+type relayProofQuery struct {
+	AccKey hexutil.Bytes `json:"accKey"`
+	Key    hexutil.Bytes `json:"key"`
+}
+
+// rpcRelay is a fallback transport satisfying ODR requests from trusted JSON-RPC
+// servers when no LES server is able to serve them. The replies are validated
+// the same way as LES replies: block bodies and receipts against the local
+// headers, state entries against Merkle proofs and code against its hash. State
+// and code are only available from servers exposing debug_getTrieProofs and
+// debug_getCodeByHash.
+type rpcRelay struct {
+	urls    []string
+	clients []*rpc.Client // Lazily dialed connections to the servers
+	lock    sync.Mutex
+}
+
+// newRPCRelay creates a relay to the given trusted JSON-RPC servers, tried in
+// the order given.
+func newRPCRelay(urls []string) *rpcRelay {
+	return &rpcRelay{
+		urls:    urls,
+		clients: make([]*rpc.Client, len(urls)),
+	}
+}
+
+// client returns the connection to the i-th relay server, dialing it if needed.
+func (r *rpcRelay) client(ctx context.Context, i int) (*rpc.Client, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.clients[i] == nil {
+		client, err := rpc.DialContext(ctx, r.urls[i])
+		if err != nil {
+			return nil, err
+		}
+		r.clients[i] = client
+	}
+	return r.clients[i], nil
+}
+
+// stop closes all the connections to the relay servers.
+func (r *rpcRelay) stop() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i, client := range r.clients {
+		if client != nil {
+			client.Close()
+			r.clients[i] = nil
+		}
+	}
+}
+
+// retrieve tries the relay servers in order until one of them delivers a valid
+// reply to the request.
+func (r *rpcRelay) retrieve(ctx context.Context, db gooladb.Database, req light.OdrRequest) error {
+	lreq := LesRequest(req)
+	if lreq == nil {
+		return errRelayUnsupported
+	}
+	err := errRelayUnsupported
+	for i, url := range r.urls {
+		var (
+			client *rpc.Client
+			msg    *Msg
+		)
+		if client, err = r.client(ctx, i); err == nil {
+			if msg, err = relayRequest(ctx, client, req); err == errRelayUnsupported {
+				return err
+			}
+			if err == nil {
+				if err = lreq.Validate(db, msg); err == nil {
+					return nil
+				}
+			}
+		}
+		log.Debug("Failed to relay request", "url", url, "err", err)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+// relayRequest retrieves the reply to an ODR request through JSON-RPC, packed
+// into the LES message that would carry it.
+func relayRequest(ctx context.Context, client *rpc.Client, req light.OdrRequest) (*Msg, error) {
+	switch req := req.(type) {
+	case *light.BlockRequest:
+		var blob hexutil.Bytes
+		if err := client.CallContext(ctx, &blob, "debug_getRawBlockByNumber", hexutil.Uint64(req.Number)); err != nil {
+			return nil, err
+		}
+		block := new(types.Block)
+		if err := rlp.DecodeBytes(blob, block); err != nil {
+			return nil, err
+		}
+		return &Msg{MsgType: MsgBlockBodies, Obj: []*types.Body{{Transactions: block.Transactions()}}}, nil
+
+	case *light.ReceiptsRequest:
+		var blob hexutil.Bytes
+		if err := client.CallContext(ctx, &blob, "debug_getRawReceipts", req.Hash); err != nil {
+			return nil, err
+		}
+		var receipts types.Receipts
+		if err := rlp.DecodeBytes(blob, &receipts); err != nil {
+			return nil, err
+		}
+		return &Msg{MsgType: MsgReceipts, Obj: []types.Receipts{receipts}}, nil
+
+	case *light.TrieRequest:
+		query := relayProofQuery{AccKey: req.Id.AccKey, Key: req.Key}
+		return relayProofs(ctx, client, req.Id, []relayProofQuery{query})
+
+	case *light.BatchTrieRequest:
+		if len(req.Reqs) == 0 {
+			return nil, errRelayUnsupported
+		}
+		queries := make([]relayProofQuery, len(req.Reqs))
+		for i, r := range req.Reqs {
+			queries[i] = relayProofQuery{AccKey: r.Id.AccKey, Key: r.Key}
+		}
+		return relayProofs(ctx, client, req.Reqs[0].Id, queries)
+
+	case *light.CodeRequest:
+		var code hexutil.Bytes
+		if err := client.CallContext(ctx, &code, "debug_getCodeByHash", req.Hash); err != nil {
+			return nil, err
+		}
+		return &Msg{MsgType: MsgCode, Obj: [][]byte{code}}, nil
+
+	default:
+		return nil, errRelayUnsupported
+	}
+}
+
+// relayProofs retrieves the merged Merkle proofs of trie entries of a block.
+func relayProofs(ctx context.Context, client *rpc.Client, id *light.TrieID, queries []relayProofQuery) (*Msg, error) {
+	var nodes []hexutil.Bytes
+	if err := client.CallContext(ctx, &nodes, "debug_getTrieProofs", id.BlockHash, queries); err != nil {
+		return nil, err
+	}
+	proof := make(light.NodeList, len(nodes))
+	for i, node := range nodes {
+		proof[i] = rlp.RawValue(node)
+	}
+	return &Msg{MsgType: MsgProofsV2, Obj: proof}, nil
+}
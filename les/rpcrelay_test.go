@@ -0,0 +1,113 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/light"
+	"github.com/goola-team/goola/rlp"
+	"github.com/goola-team/goola/rpc"
+)
+
+// RelayTestService is a fake debug API of a trusted relay server.
+type RelayTestService struct {
+	block    *types.Block
+	receipts types.Receipts
+	code     []byte
+}
+
+func (s *RelayTestService) GetRawBlockByNumber(number hexutil.Uint64) (hexutil.Bytes, error) {
+	return rlp.EncodeToBytes(s.block)
+}
+
+func (s *RelayTestService) GetRawReceipts(hash common.Hash) (hexutil.Bytes, error) {
+	return rlp.EncodeToBytes(s.receipts)
+}
+
+func (s *RelayTestService) GetCodeByHash(hash common.Hash) (hexutil.Bytes, error) {
+	return s.code, nil
+}
+
+func newTestRelay(t *testing.T, service *RelayTestService) *rpcRelay {
+	server := rpc.NewServer()
+	if err := server.RegisterName("debug", service); err != nil {
+		t.Fatalf("failed to register relay service: %v", err)
+	}
+	return &rpcRelay{urls: []string{"inproc"}, clients: []*rpc.Client{rpc.DialInProc(server)}}
+}
+
+// Tests that requests satisfied through the rpc relay are validated against the
+// local chain like LES replies.
+func TestRPCRelayRetrieve(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+
+	txs := []*types.Transaction{types.NewTransaction(0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), 0, nil)}
+	receipts := types.Receipts{types.NewReceipt(nil, false, 21000)}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, txs, receipts)
+	core.WriteHeader(db, block.Header())
+
+	code := []byte{0x60, 0x00}
+	service := &RelayTestService{block: block, receipts: receipts, code: code}
+	relay := newTestRelay(t, service)
+	defer relay.stop()
+
+	blockReq := &light.BlockRequest{Hash: block.Hash(), Number: 1}
+	if err := relay.retrieve(context.Background(), db, blockReq); err != nil {
+		t.Fatalf("failed to relay block body: %v", err)
+	}
+	body := new(types.Body)
+	if err := rlp.DecodeBytes(blockReq.Rlp, body); err != nil || len(body.Transactions) != 1 || body.Transactions[0].Hash() != txs[0].Hash() {
+		t.Fatalf("block body mismatch: %v", err)
+	}
+	receiptsReq := &light.ReceiptsRequest{Hash: block.Hash(), Number: 1}
+	if err := relay.retrieve(context.Background(), db, receiptsReq); err != nil {
+		t.Fatalf("failed to relay receipts: %v", err)
+	}
+	if len(receiptsReq.Receipts) != 1 || receiptsReq.Receipts[0].CumulativeGasUsed != 21000 {
+		t.Fatalf("receipts mismatch: %v", receiptsReq.Receipts)
+	}
+	codeReq := &light.CodeRequest{Id: &light.TrieID{BlockHash: block.Hash()}, Hash: crypto.Keccak256Hash(code)}
+	if err := relay.retrieve(context.Background(), db, codeReq); err != nil {
+		t.Fatalf("failed to relay code: %v", err)
+	}
+	if !bytes.Equal(codeReq.Data, code) {
+		t.Fatalf("code mismatch: have %x, want %x", codeReq.Data, code)
+	}
+	// Tamper with the served data and ensure it's rejected
+	service.block = types.NewBlock(&types.Header{Number: big.NewInt(1)}, nil, nil)
+	if err := relay.retrieve(context.Background(), db, &light.BlockRequest{Hash: block.Hash(), Number: 1}); err != errTxHashMismatch {
+		t.Errorf("tampered block body: have %v, want %v", err, errTxHashMismatch)
+	}
+	service.code = []byte{0x60, 0x01}
+	if err := relay.retrieve(context.Background(), db, &light.CodeRequest{Id: &light.TrieID{BlockHash: block.Hash()}, Hash: crypto.Keccak256Hash(code)}); err != errDataHashMismatch {
+		t.Errorf("tampered code: have %v, want %v", err, errDataHashMismatch)
+	}
+	// Requests not servable through JSON-RPC are refused outright
+	if err := relay.retrieve(context.Background(), db, &light.ChtRequest{}); err != errRelayUnsupported {
+		t.Errorf("cht request: have %v, want %v", err, errRelayUnsupported)
+	}
+}
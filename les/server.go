@@ -21,6 +21,7 @@ import (
 	"crypto/ecdsa"
 	"encoding/binary"
 	"math"
+	"sort"
 	"sync"
 
 	"github.com/goola-team/goola/core"
@@ -31,8 +32,10 @@ import (
 	"github.com/goola-team/goola/light"
 	"github.com/goola-team/goola/log"
 	"github.com/goola-team/goola/p2p"
+	"github.com/goola-team/goola/p2p/discover"
 	"github.com/goola-team/goola/p2p/discv5"
 	"github.com/goola-team/goola/rlp"
+	"github.com/goola-team/goola/rpc"
 )
 
 type LesServer struct {
@@ -41,6 +44,8 @@ type LesServer struct {
 	fcManager       *flowcontrol.ClientManager // nil if our node is client only
 	fcCostStats     *requestCostStats
 	defParams       *flowcontrol.ServerParams
+	paidParams      *flowcontrol.ServerParams // Boosted flow control parameters of paying clients
+	payments        *paymentLedger            // Prepaid client balances, nil if payments are disabled
 	lesTopics       []discv5.Topic
 	privateKey      *ecdsa.PrivateKey
 	quitSync        chan struct{}
@@ -98,9 +103,46 @@ func NewLesServer(backend *goolabackend.FullGoola, config *goolabackend.Config)
 	}
 	srv.fcManager = flowcontrol.NewClientManager(uint64(config.LightServ), 10, 1000000000)
 	srv.fcCostStats = newCostStats(backend.ChainDb())
+
+	if config.LightPayments {
+		srv.payments = newPaymentLedger(backend.ChainDb())
+		srv.paidParams = &flowcontrol.ServerParams{
+			BufLimit:    srv.defParams.BufLimit * paidBufferBoost,
+			MinRecharge: srv.defParams.MinRecharge * paidBufferBoost,
+		}
+	}
 	return srv, nil
 }
 
+// APIs returns the RPC APIs of the LES server.
+func (s *LesServer) APIs() []rpc.API {
+	if s.payments == nil {
+		return nil
+	}
+	return []rpc.API{
+		{
+			Namespace: "les",
+			Version:   "1.0",
+			Service:   NewPrivateLightServerAPI(s),
+			Public:    false,
+		},
+	}
+}
+
+// prioritized reports whether a client has a prepaid balance to be served with
+// priority.
+func (s *LesServer) prioritized(id discover.NodeID) bool {
+	return s.payments != nil && s.payments.balance(id) > 0
+}
+
+// clientParams returns the flow control parameters to assign to a client.
+func (s *LesServer) clientParams(id discover.NodeID) (params *flowcontrol.ServerParams, paid bool) {
+	if s.prioritized(id) {
+		return s.paidParams, true
+	}
+	return s.defParams, false
+}
+
 func (s *LesServer) Protocols() []p2p.Protocol {
 	return s.protocolManager.SubProtocols
 }
@@ -133,6 +175,9 @@ func (s *LesServer) Stop() {
 	s.chtIndexer.Close()
 	// bloom trie indexer is closed by parent bloombits indexer
 	s.fcCostStats.store()
+	if s.payments != nil {
+		s.payments.store()
+	}
 	s.fcManager.Stop()
 	go func() {
 		<-s.protocolManager.noMorePeers
@@ -161,6 +206,17 @@ func (list RequestCostList) decode() requestCostTable {
 	return table
 }
 
+// encode converts a request cost table back into its network representation,
+// ordered by message code.
+func (table requestCostTable) encode() RequestCostList {
+	list := make(RequestCostList, 0, len(table))
+	for code, costs := range table {
+		list = append(list, struct{ MsgCode, BaseCost, ReqCost uint64 }{code, costs.baseCost, costs.reqCost})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].MsgCode < list[j].MsgCode })
+	return list
+}
+
 type linReg struct {
 	sumX, sumY, sumXX, sumXY float64
 	cnt                      uint64
@@ -117,6 +117,12 @@ type serverPool struct {
 	knownSelect, newSelect     *weightedRandomSelect
 	knownSelected, newSelected int
 	fastDiscover               bool
+
+	budget *bandwidthBudget // Bandwidth budget to stop dialing new servers when exhausted
+
+	sessionKey []byte                             // Database key of the stored server sessions
+	sessions   map[discover.NodeID]*serverSession // Sessions of recently registered servers
+	sections   func() uint64                      // Number of verified local CHT sections (optional)
 }
 
 // newServerPool creates a new serverPool instance
@@ -126,6 +132,7 @@ func newServerPool(db gooladb.Database, quit chan struct{}, wg *sync.WaitGroup)
 		quit:         quit,
 		wg:           wg,
 		entries:      make(map[discover.NodeID]*poolEntry),
+		sessions:     make(map[discover.NodeID]*serverSession),
 		timeout:      make(chan *poolEntry, 1),
 		adjustStats:  make(chan poolStatAdjust, 100),
 		enableRetry:  make(chan *poolEntry, 1),
@@ -142,8 +149,11 @@ func (pool *serverPool) start(server *p2p.Server, topic discv5.Topic) {
 	pool.server = server
 	pool.topic = topic
 	pool.dbKey = append([]byte("serverPool/"), []byte(topic)...)
+	pool.sessionKey = append([]byte("serverSessions/"), []byte(topic)...)
 	pool.wg.Add(1)
 	pool.loadNodes()
+	pool.loadSessions()
+	pool.resumeSessions()
 
 	if pool.server.DiscV5 != nil {
 		pool.discSetPeriod = make(chan time.Duration, 1)
@@ -202,6 +212,7 @@ func (pool *serverPool) registered(entry *poolEntry) {
 	}
 	pool.knownQueue.setLatest(entry)
 	entry.shortRetry = shortRetryCnt
+	pool.updateSession(entry)
 }
 
 // disconnect should be called when ending a connection. Service quality statistics
@@ -213,6 +224,8 @@ func (pool *serverPool) disconnect(entry *poolEntry) {
 	defer pool.lock.Unlock()
 
 	if entry.state == psRegistered {
+		pool.updateSession(entry)
+
 		connTime := mclock.Now() - entry.regTime
 		connAdjust := float64(connTime) / float64(targetConnTime)
 		if connAdjust > 1 {
@@ -337,6 +350,7 @@ func (pool *serverPool) eventLoop() {
 			}
 			pool.connWg.Wait()
 			pool.saveNodes()
+			pool.saveSessions()
 			pool.wg.Done()
 			return
 
@@ -457,8 +471,12 @@ func (pool *serverPool) updateCheckDial(entry *poolEntry) {
 }
 
 // checkDial checks if new dials can/should be made. It tries to select servers both
-// based on good statistics and recent discovery.
+// based on good statistics and recent discovery. No new servers are dialed while
+// the bandwidth budget is exhausted.
 func (pool *serverPool) checkDial() {
+	if pool.budget.exhausted() {
+		return
+	}
 	fillWithKnownSelects := !pool.fastDiscover
 	for pool.knownSelected < targetKnownSelect {
 		entry := pool.knownSelect.choose()
@@ -0,0 +1,155 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/les/flowcontrol"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/p2p/discover"
+	"github.com/goola-team/goola/rlp"
+)
+
+// sessionExpiry is the age after which a stored server session is not resumed
+// any more, the server set having most probably changed in the meantime.
+const sessionExpiry = 24 * time.Hour
+
+// serverSession is the persisted state of a connection to a light server: its
+// address, the parameters negotiated in the handshake, the last head it
+// announced and the local helper trie progress while it was connected. Sessions
+// let a restarted client redial the same servers right away instead of waiting
+// for discovery.
+type serverSession struct {
+	ID   discover.NodeID
+	IP   net.IP
+	Port uint16
+
+	BufLimit    uint64          // Flow control buffer limit granted by the server
+	MinRecharge uint64          // Flow control buffer recharge rate granted by the server
+	Costs       RequestCostList // Maximum request costs announced by the server
+	ChainSince  uint64          // Oldest block the server serves bodies and receipts of
+
+	HeadHash   common.Hash // Last head announced by the server
+	HeadNumber uint64
+	Sections   uint64 // Verified local CHT sections while connected
+
+	Updated uint64 // Unix time of the last update
+}
+
+// matches returns whether the server granted the same flow control parameters
+// as in the stored session.
+func (s *serverSession) matches(params *flowcontrol.ServerParams) bool {
+	return params != nil && s.BufLimit == params.BufLimit && s.MinRecharge == params.MinRecharge
+}
+
+// expired returns whether the session is too old to be resumed.
+func (s *serverSession) expired(now time.Time) bool {
+	return now.Sub(time.Unix(int64(s.Updated), 0)) > sessionExpiry
+}
+
+// loadSessions loads the stored server sessions from the database.
+func (pool *serverPool) loadSessions() {
+	enc, err := pool.db.Get(pool.sessionKey)
+	if err != nil {
+		return
+	}
+	var list []*serverSession
+	if err := rlp.DecodeBytes(enc, &list); err != nil {
+		log.Debug("Failed to decode server sessions", "err", err)
+		return
+	}
+	now := time.Now()
+	for _, s := range list {
+		if !s.expired(now) {
+			pool.sessions[s.ID] = s
+		}
+	}
+}
+
+// saveSessions stores the most recently updated server sessions into the
+// database.
+func (pool *serverPool) saveSessions() {
+	list := make([]*serverSession, 0, len(pool.sessions))
+	for _, s := range pool.sessions {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Updated > list[j].Updated })
+	if len(list) > targetServerCount {
+		list = list[:targetServerCount]
+	}
+	enc, err := rlp.EncodeToBytes(list)
+	if err == nil {
+		pool.db.Put(pool.sessionKey, enc)
+	}
+}
+
+// resumeSessions dials the servers of the stored sessions, most recent first,
+// before any discovery based selection takes place.
+func (pool *serverPool) resumeSessions() {
+	list := make([]*serverSession, 0, len(pool.sessions))
+	for _, s := range pool.sessions {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Updated > list[j].Updated })
+
+	for _, s := range list {
+		if pool.knownSelected+pool.newSelected >= targetServerCount {
+			break
+		}
+		entry := pool.entries[s.ID]
+		if entry == nil {
+			entry = pool.findOrNewNode(s.ID, s.IP, s.Port)
+		}
+		log.Debug("Resuming server session", "id", s.ID, "head", s.HeadNumber, "sections", s.Sections)
+		pool.dial(entry, entry.known)
+	}
+}
+
+// updateSession records the current state of the connection to a registered
+// server. It should be called with the pool lock held.
+func (pool *serverPool) updateSession(entry *poolEntry) {
+	p := entry.peer
+	if p == nil || entry.lastConnected == nil {
+		return
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.fcServerParams == nil || p.headInfo == nil {
+		return
+	}
+	s := pool.sessions[entry.id]
+	if s == nil {
+		s = &serverSession{ID: entry.id}
+		pool.sessions[entry.id] = s
+	} else if !s.matches(p.fcServerParams) {
+		log.Debug("Server renegotiated session", "id", entry.id, "buflimit", p.fcServerParams.BufLimit, "recharge", p.fcServerParams.MinRecharge)
+	}
+	s.IP, s.Port = entry.lastConnected.ip, entry.lastConnected.port
+	s.BufLimit, s.MinRecharge = p.fcServerParams.BufLimit, p.fcServerParams.MinRecharge
+	s.Costs = p.fcCosts.encode()
+	s.ChainSince = p.chainSince
+	s.HeadHash, s.HeadNumber = p.headInfo.Hash, p.headInfo.Number
+	if pool.sections != nil {
+		s.Sections = pool.sections()
+	}
+	s.Updated = uint64(time.Now().Unix())
+}
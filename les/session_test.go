@@ -0,0 +1,83 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/les/flowcontrol"
+	"github.com/goola-team/goola/p2p/discover"
+)
+
+// Tests that the sessions of registered servers survive a restart of the
+// server pool, and that stale sessions are not resumed.
+func TestServerSessionPersistence(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	quit := make(chan struct{})
+
+	pool := newServerPool(db, quit, new(sync.WaitGroup))
+	pool.sessionKey = []byte("serverSessions/test")
+	pool.sections = func() uint64 { return 3 }
+
+	id := discover.NodeID{0x01}
+	entry := pool.findOrNewNode(id, net.IP{127, 0, 0, 1}, 30303)
+	entry.lastConnected = &poolEntryAddress{ip: net.IP{127, 0, 0, 1}, port: 30303}
+	entry.peer = &peer{
+		fcServerParams: &flowcontrol.ServerParams{BufLimit: 300000000, MinRecharge: 50000},
+		fcCosts:        testRCL().decode(),
+		chainSince:     10,
+		headInfo:       &announceData{Hash: common.Hash{0xaa}, Number: 1000},
+	}
+	pool.registered(entry)
+
+	// Head announcements must be picked up when the session is updated again
+	entry.peer.headInfo = &announceData{Hash: common.Hash{0xbb}, Number: 1001}
+	pool.updateSession(entry)
+
+	stale := &serverSession{ID: discover.NodeID{0x02}, Updated: uint64(time.Now().Add(-2 * sessionExpiry).Unix())}
+	pool.sessions[stale.ID] = stale
+	pool.saveSessions()
+
+	restarted := newServerPool(db, quit, new(sync.WaitGroup))
+	restarted.sessionKey = pool.sessionKey
+	restarted.loadSessions()
+
+	if len(restarted.sessions) != 1 {
+		t.Fatalf("resumable session count mismatch: have %d, want 1", len(restarted.sessions))
+	}
+	s := restarted.sessions[id]
+	if s == nil {
+		t.Fatalf("session of registered server missing")
+	}
+	if !s.matches(entry.peer.fcServerParams) {
+		t.Errorf("flow control parameters mismatch: have %d/%d", s.BufLimit, s.MinRecharge)
+	}
+	if len(s.Costs) != len(testRCL()) {
+		t.Errorf("request cost list length mismatch: have %d, want %d", len(s.Costs), len(testRCL()))
+	}
+	if s.HeadHash != (common.Hash{0xbb}) || s.HeadNumber != 1001 {
+		t.Errorf("head mismatch: have #%d [%x]", s.HeadNumber, s.HeadHash[:4])
+	}
+	if s.ChainSince != 10 || s.Sections != 3 || s.Port != 30303 {
+		t.Errorf("session mismatch: chain since %d, sections %d, port %d", s.ChainSince, s.Sections, s.Port)
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/goola-team/goola/goolabackend/downloader"
 	"github.com/goola-team/goola/light"
+	"github.com/goola-team/goola/log"
 )
 
 const (
@@ -72,4 +73,11 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 	defer cancel()
 	pm.blockchain.(*light.LightChain).SyncCht(ctx)
 	pm.downloader.Synchronise(peer.id, peer.Head(), downloader.LightSync)
+
+	// Make sure the validator set transitions crossed are proven
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := pm.blockchain.(*light.LightChain).VerifyEpochs(ctx); err != nil {
+		log.Debug("Failed to verify epoch transitions", "err", err)
+	}
 }
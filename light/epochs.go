@@ -0,0 +1,107 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/consensus/dpos"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rlp"
+)
+
+// epochValidatorsPrefix + epoch (uint64 big endian) + checkpoint hash -> validators
+var epochValidatorsPrefix = []byte("epochValidators-")
+
+// ErrUnprovenEpoch is returned if the canonical header chain crosses into an
+// epoch whose validator set transition doesn't match the proven one.
+var ErrUnprovenEpoch = errors.New("checkpoint not matching epoch proof")
+
+// epochValidatorsKey returns the database key of an epoch's proven validators.
+func epochValidatorsKey(epoch uint64, checkpoint common.Hash) []byte {
+	var encNumber [8]byte
+	binary.BigEndian.PutUint64(encNumber[:], epoch)
+	return append(append(epochValidatorsPrefix, encNumber[:]...), checkpoint.Bytes()...)
+}
+
+// GetEpochValidators reads the proven validators of an epoch, entered through the
+// given checkpoint, from the database.
+func GetEpochValidators(db gooladb.Database, epoch uint64, checkpoint common.Hash) []common.Address {
+	data, _ := db.Get(epochValidatorsKey(epoch, checkpoint))
+	if len(data) == 0 {
+		return nil
+	}
+	var validators []common.Address
+	if err := rlp.DecodeBytes(data, &validators); err != nil {
+		log.Error("Invalid epoch validators RLP", "epoch", epoch, "checkpoint", checkpoint, "err", err)
+		return nil
+	}
+	return validators
+}
+
+// StoreEpochValidators writes the proven validators of an epoch into the database.
+func StoreEpochValidators(db gooladb.Database, epoch uint64, checkpoint common.Hash, validators []common.Address) {
+	data, err := rlp.EncodeToBytes(validators)
+	if err != nil {
+		log.Crit("Failed to RLP encode epoch validators", "err", err)
+	}
+	if err := db.Put(epochValidatorsKey(epoch, checkpoint), data); err != nil {
+		log.Crit("Failed to store epoch validators", "err", err)
+	}
+}
+
+// VerifyEpochs retrieves the proofs of the dpos validator set transitions the
+// canonical header chain went through, verifying each one against the validators
+// of the epoch before, starting from the genesis validators. If a checkpoint
+// doesn't match the proven one, the header chain is rolled back to before it.
+// Transitions not yet final on the network are left for a later call.
+func (self *LightChain) VerifyEpochs(ctx context.Context) error {
+	config := self.Config().Ethash
+	if config == nil || !config.Scheduled() || config.Epoch == 0 {
+		return nil
+	}
+	previous := config.Validators
+	for epoch := uint64(1); dpos.IsCheckpoint(config, epoch*config.Epoch); epoch++ {
+		checkpoint := self.GetHeaderByNumber(epoch * config.Epoch)
+		if checkpoint == nil {
+			return nil
+		}
+		if validators := GetEpochValidators(self.chainDb, epoch, checkpoint.Hash()); validators != nil {
+			previous = validators
+			continue
+		}
+		req := &EpochProofRequest{Config: config, Epoch: epoch, Previous: previous}
+		if err := self.odr.Retrieve(ctx, req); err != nil {
+			return err
+		}
+		if proven := req.Headers[0]; proven.Hash() != checkpoint.Hash() {
+			log.Warn("Rolling back unproven epoch transition", "epoch", epoch, "checkpoint", checkpoint.Hash(), "proven", proven.Hash())
+
+			self.mu.Lock()
+			self.hc.SetCurrentHeader(self.GetHeader(checkpoint.ParentHash, checkpoint.Number.Uint64()-1))
+			self.mu.Unlock()
+			return ErrUnprovenEpoch
+		}
+		log.Debug("Verified epoch transition", "epoch", epoch, "checkpoint", checkpoint.Hash(), "validators", len(req.Validators))
+		previous = req.Validators
+	}
+	return nil
+}
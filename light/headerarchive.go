@@ -0,0 +1,206 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/rlp"
+)
+
+// Header archives are a compact framed encoding of a header chain, optionally
+// followed by the CHT roots of its sections, used to distribute verified header
+// chains out-of-band. An archive starts with a magic string, a version byte and
+// the genesis hash of the chain, followed by frames of
+//
+//	kind (1 byte) || payload length (uvarint) || payload || CRC32 of kind and payload (4 bytes)
+//
+// where the payload is the RLP encoding of a header or of a CHT root entry.
+const (
+	headerArchiveMagic   = "GOOLAHDR"
+	headerArchiveVersion = 1
+
+	frameHeader  = 0x01 // RLP encoded block header
+	frameChtRoot = 0x02 // RLP encoded CHT root entry
+
+	maxFrameSize = 64 * 1024 // Maximum payload size, far above any valid header
+)
+
+var (
+	errBadArchiveMagic = errors.New("not a header archive")
+	errFrameChecksum   = errors.New("archive frame checksum mismatch")
+)
+
+// ChtRootEntry is the CHT root of a LES/2 sized (CHTFrequencyClient) section,
+// along with the hash of the last header in the section.
+type ChtRootEntry struct {
+	Section uint64
+	Head    common.Hash
+	Root    common.Hash
+}
+
+// HeaderArchiveEntry is an item read from a header archive, exactly one of the
+// fields is set.
+type HeaderArchiveEntry struct {
+	Header  *types.Header
+	ChtRoot *ChtRootEntry
+}
+
+// HeaderArchiveWriter encodes a header chain into a header archive.
+type HeaderArchiveWriter struct {
+	w *bufio.Writer
+}
+
+// NewHeaderArchiveWriter creates a header archive writer for the chain with the
+// given genesis, writing the archive preamble.
+func NewHeaderArchiveWriter(w io.Writer, genesis common.Hash) (*HeaderArchiveWriter, error) {
+	aw := &HeaderArchiveWriter{w: bufio.NewWriter(w)}
+	aw.w.WriteString(headerArchiveMagic)
+	aw.w.WriteByte(headerArchiveVersion)
+	if _, err := aw.w.Write(genesis[:]); err != nil {
+		return nil, err
+	}
+	return aw, nil
+}
+
+// WriteHeader appends a header frame to the archive.
+func (aw *HeaderArchiveWriter) WriteHeader(header *types.Header) error {
+	return aw.writeFrame(frameHeader, header)
+}
+
+// WriteChtRoot appends a CHT root frame to the archive.
+func (aw *HeaderArchiveWriter) WriteChtRoot(entry *ChtRootEntry) error {
+	return aw.writeFrame(frameChtRoot, entry)
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (aw *HeaderArchiveWriter) Flush() error {
+	return aw.w.Flush()
+}
+
+func (aw *HeaderArchiveWriter) writeFrame(kind byte, val interface{}) error {
+	payload, err := rlp.EncodeToBytes(val)
+	if err != nil {
+		return err
+	}
+	return writeArchiveFrame(aw.w, kind, payload)
+}
+
+// HeaderArchiveReader decodes a header archive.
+type HeaderArchiveReader struct {
+	r       *bufio.Reader
+	genesis common.Hash
+}
+
+// NewHeaderArchiveReader creates a header archive reader, checking the archive
+// preamble.
+func NewHeaderArchiveReader(r io.Reader) (*HeaderArchiveReader, error) {
+	ar := &HeaderArchiveReader{r: bufio.NewReader(r)}
+
+	preamble := make([]byte, len(headerArchiveMagic)+1+common.HashLength)
+	if _, err := io.ReadFull(ar.r, preamble); err != nil {
+		return nil, errBadArchiveMagic
+	}
+	if !bytes.Equal(preamble[:len(headerArchiveMagic)], []byte(headerArchiveMagic)) {
+		return nil, errBadArchiveMagic
+	}
+	if version := preamble[len(headerArchiveMagic)]; version != headerArchiveVersion {
+		return nil, fmt.Errorf("unsupported header archive version %d", version)
+	}
+	ar.genesis = common.BytesToHash(preamble[len(headerArchiveMagic)+1:])
+	return ar, nil
+}
+
+// Genesis returns the genesis hash of the archived chain.
+func (ar *HeaderArchiveReader) Genesis() common.Hash {
+	return ar.genesis
+}
+
+// Next reads the next entry of the archive, returning io.EOF at its end.
+func (ar *HeaderArchiveReader) Next() (*HeaderArchiveEntry, error) {
+	kind, payload, err := readArchiveFrame(ar.r)
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case frameHeader:
+		header := new(types.Header)
+		if err := rlp.DecodeBytes(payload, header); err != nil {
+			return nil, err
+		}
+		return &HeaderArchiveEntry{Header: header}, nil
+	case frameChtRoot:
+		entry := new(ChtRootEntry)
+		if err := rlp.DecodeBytes(payload, entry); err != nil {
+			return nil, err
+		}
+		return &HeaderArchiveEntry{ChtRoot: entry}, nil
+	default:
+		return nil, fmt.Errorf("unknown header archive frame kind %d", kind)
+	}
+}
+
+// writeArchiveFrame writes a single checksummed frame of an archive.
+func writeArchiveFrame(w *bufio.Writer, kind byte, payload []byte) error {
+	var buf [binary.MaxVarintLen64]byte
+
+	w.WriteByte(kind)
+	w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(payload)))])
+	w.Write(payload)
+
+	binary.BigEndian.PutUint32(buf[:4], frameChecksum(kind, payload))
+	_, err := w.Write(buf[:4])
+	return err
+}
+
+// readArchiveFrame reads and checksums the next frame of an archive, returning
+// io.EOF if there are no more frames.
+func readArchiveFrame(r *bufio.Reader) (byte, []byte, error) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	if size > maxFrameSize {
+		return 0, nil, fmt.Errorf("archive frame too large: %d > %d", size, maxFrameSize)
+	}
+	frame := make([]byte, size+4)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	payload := frame[:size]
+	if binary.BigEndian.Uint32(frame[size:]) != frameChecksum(kind, payload) {
+		return 0, nil, errFrameChecksum
+	}
+	return kind, payload, nil
+}
+
+// frameChecksum calculates the checksum of a frame's kind and payload.
+func frameChecksum(kind byte, payload []byte) uint32 {
+	return crc32.Update(crc32.ChecksumIEEE([]byte{kind}), crc32.IEEETable, payload)
+}
@@ -0,0 +1,125 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core/types"
+)
+
+// makeHeaderArchive encodes a short header chain followed by a CHT root.
+func makeHeaderArchive(t *testing.T, genesis common.Hash) ([]*types.Header, *ChtRootEntry, []byte) {
+	var (
+		headers []*types.Header
+		parent  = genesis
+	)
+	for i := 1; i <= 8; i++ {
+		header := &types.Header{ParentHash: parent, Number: big.NewInt(int64(i)), Time: big.NewInt(int64(10 * i)), GasLimit: 4700000, Extra: []byte("archived")}
+		headers = append(headers, header)
+		parent = header.Hash()
+	}
+	cht := &ChtRootEntry{Section: 0, Head: parent, Root: common.Hash{0xcc}}
+
+	buf := new(bytes.Buffer)
+	aw, err := NewHeaderArchiveWriter(buf, genesis)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	for _, header := range headers {
+		if err := aw.WriteHeader(header); err != nil {
+			t.Fatalf("failed to write header #%d: %v", header.Number, err)
+		}
+	}
+	if err := aw.WriteChtRoot(cht); err != nil {
+		t.Fatalf("failed to write CHT root: %v", err)
+	}
+	if err := aw.Flush(); err != nil {
+		t.Fatalf("failed to flush archive: %v", err)
+	}
+	return headers, cht, buf.Bytes()
+}
+
+// Tests that header chains and CHT roots survive a round trip through an archive.
+func TestHeaderArchiveRoundTrip(t *testing.T) {
+	genesis := common.Hash{0x01}
+	headers, cht, archive := makeHeaderArchive(t, genesis)
+
+	ar, err := NewHeaderArchiveReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	if ar.Genesis() != genesis {
+		t.Errorf("genesis mismatch: have %x, want %x", ar.Genesis(), genesis)
+	}
+	for i, want := range headers {
+		entry, err := ar.Next()
+		if err != nil {
+			t.Fatalf("header %d: failed to read: %v", i, err)
+		}
+		if entry.Header == nil || entry.ChtRoot != nil || entry.Header.Hash() != want.Hash() {
+			t.Fatalf("header %d mismatch: have %+v, want %x", i, entry, want.Hash())
+		}
+	}
+	entry, err := ar.Next()
+	if err != nil {
+		t.Fatalf("failed to read CHT root: %v", err)
+	}
+	if entry.ChtRoot == nil || *entry.ChtRoot != *cht {
+		t.Fatalf("CHT root mismatch: have %+v, want %+v", entry.ChtRoot, cht)
+	}
+	if _, err := ar.Next(); err != io.EOF {
+		t.Errorf("end of archive error mismatch: have %v, want %v", err, io.EOF)
+	}
+}
+
+// Tests that damaged archives are detected.
+func TestHeaderArchiveCorruption(t *testing.T) {
+	_, _, archive := makeHeaderArchive(t, common.Hash{0x01})
+	preamble := len(headerArchiveMagic) + 1 + common.HashLength
+
+	// Archives with a foreign preamble are refused up front
+	if _, err := NewHeaderArchiveReader(bytes.NewReader([]byte("not an archive at all, really not"))); err != errBadArchiveMagic {
+		t.Errorf("bad magic error mismatch: have %v, want %v", err, errBadArchiveMagic)
+	}
+	future := common.CopyBytes(archive)
+	future[len(headerArchiveMagic)]++
+	if _, err := NewHeaderArchiveReader(bytes.NewReader(future)); err == nil {
+		t.Errorf("unsupported version accepted")
+	}
+	// Flipped payload bits fail the frame checksum
+	flipped := common.CopyBytes(archive)
+	flipped[preamble+10] ^= 0x01
+	ar, _ := NewHeaderArchiveReader(bytes.NewReader(flipped))
+	if _, err := ar.Next(); err != errFrameChecksum {
+		t.Errorf("flipped payload error mismatch: have %v, want %v", err, errFrameChecksum)
+	}
+	// Archives cut mid-frame are reported as such
+	ar, _ = NewHeaderArchiveReader(bytes.NewReader(archive[:len(archive)-1]))
+	for {
+		if _, err := ar.Next(); err != nil {
+			if err != io.ErrUnexpectedEOF {
+				t.Errorf("truncated archive error mismatch: have %v, want %v", err, io.ErrUnexpectedEOF)
+			}
+			break
+		}
+	}
+}
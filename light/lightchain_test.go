@@ -54,7 +54,7 @@ func newCanonical(n int) (gooladb.Database, *LightChain, error) {
 	db, _ := gooladb.NewMemDatabase()
 	gspec := core.Genesis{Config: params.TestChainConfig}
 	genesis := gspec.MustCommit(db)
-	blockchain, _ := NewLightChain(&dummyOdr{db: db}, gspec.Config, dpos.NewFaker())
+	blockchain, _ := NewLightChain(&dummyOdr{db: db}, gspec.Config, dpos.NewFaker(), nil)
 
 	// Create and inject the requested chain
 	if n == 0 {
@@ -73,44 +73,13 @@ func newTestLightChain() *LightChain {
 		Config:     params.TestChainConfig,
 	}
 	gspec.MustCommit(db)
-	lc, err := NewLightChain(&dummyOdr{db: db}, gspec.Config, dpos.NewFullFaker())
+	lc, err := NewLightChain(&dummyOdr{db: db}, gspec.Config, dpos.NewFullFaker(), nil)
 	if err != nil {
 		panic(err)
 	}
 	return lc
 }
 
-// Test fork of length N starting from block i
-func testFork(t *testing.T, LightChain *LightChain, i, n int, comparator func(td1, td2 *big.Int)) {
-	// Copy old chain up to #i into a new db
-	db, LightChain2, err := newCanonical(i)
-	if err != nil {
-		t.Fatal("could not make new canonical in testFork", err)
-	}
-	// Assert the chains have the same header/block at #i
-	var hash1, hash2 common.Hash
-	hash1 = LightChain.GetHeaderByNumber(uint64(i)).Hash()
-	hash2 = LightChain2.GetHeaderByNumber(uint64(i)).Hash()
-	if hash1 != hash2 {
-		t.Errorf("chain content mismatch at %d: have hash %v, want hash %v", i, hash2, hash1)
-	}
-	// Extend the newly created chain
-	headerChainB := makeHeaderChain(LightChain2.CurrentHeader(), n, db, forkSeed)
-	if _, err := LightChain2.InsertHeaderChain(headerChainB, 1); err != nil {
-		t.Fatalf("failed to insert forking chain: %v", err)
-	}
-	// Sanity check that the forked chain can be imported into the original
-	var tdPre, tdPost *big.Int
-
-	tdPre = LightChain.GetTdByHash(LightChain.CurrentHeader().Hash())
-	if err := testHeaderChainImport(headerChainB, LightChain); err != nil {
-		t.Fatalf("failed to import forked header chain: %v", err)
-	}
-	tdPost = LightChain.GetTdByHash(headerChainB[len(headerChainB)-1].Hash())
-	// Compare the total difficulties of the chains
-	comparator(tdPre, tdPost)
-}
-
 // testHeaderChainImport tries to process a chain of header, writing them into
 // the database if successful.
 func testHeaderChainImport(chain []*types.Header, lightchain *LightChain) error {
@@ -144,11 +113,16 @@ func TestBrokenHeaderChain(t *testing.T) {
 }
 
 func makeHeaderChainWithDiff(genesis *types.Block, d []int, seed byte) []*types.Header {
-	var chain []*types.Header
-	for i, difficulty := range d {
+	var (
+		chain []*types.Header
+		time  = new(big.Int).Set(genesis.Time())
+	)
+	for i, gap := range d {
 		header := &types.Header{
 			Coinbase:    common.Address{seed},
 			Number:      big.NewInt(int64(i + 1)),
+			Time:        new(big.Int).Add(time, big.NewInt(int64(gap))),
+			GasLimit:    genesis.GasLimit(),
 			TxHash:      types.EmptyRootHash,
 			ReceiptHash: types.EmptyRootHash,
 		}
@@ -157,6 +131,7 @@ func makeHeaderChainWithDiff(genesis *types.Block, d []int, seed byte) []*types.
 		} else {
 			header.ParentHash = chain[i-1].Hash()
 		}
+		time = header.Time
 		chain = append(chain, types.CopyHeader(header))
 	}
 	return chain
@@ -234,7 +209,7 @@ func TestReorgBadHeaderHashes(t *testing.T) {
 	defer func() { delete(core.BadHashes, headers[3].Hash()) }()
 
 	// Create a new LightChain and check that it rolled back the state.
-	ncm, err := NewLightChain(&dummyOdr{db: bc.chainDb}, params.TestChainConfig, dpos.NewFaker())
+	ncm, err := NewLightChain(&dummyOdr{db: bc.chainDb}, params.TestChainConfig, dpos.NewFaker(), nil)
 	if err != nil {
 		t.Fatalf("failed to create new chain manager: %v", err)
 	}
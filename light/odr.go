@@ -26,6 +26,7 @@ import (
 	"github.com/goola-team/goola/core"
 	"github.com/goola-team/goola/core/types"
 	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
 )
 
 // NoOdr is the default context passed to an ODR capable function when the ODR
@@ -89,6 +90,20 @@ func (req *TrieRequest) StoreResult(db gooladb.Database) {
 	req.Proof.Store(db)
 }
 
+// BatchTrieRequest is the ODR request type for proving many state/storage trie
+// entries of the same block at once, retrieving their proofs as a single
+// deduplicated node set.
+type BatchTrieRequest struct {
+	OdrRequest
+	Reqs  []*TrieRequest // Entries to prove, all belonging to the same block
+	Proof *NodeSet
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *BatchTrieRequest) StoreResult(db gooladb.Database) {
+	req.Proof.Store(db)
+}
+
 // CodeRequest is the ODR request type for retrieving contract code
 type CodeRequest struct {
 	OdrRequest
@@ -168,3 +183,20 @@ func (req *BloomRequest) StoreResult(db gooladb.Database) {
 		core.WriteBloomBits(db, req.BitIdx, sectionIdx, sectionHead, req.BloomBits[i])
 	}
 }
+
+// EpochProofRequest is the ODR request type for retrieving the proof of a dpos
+// validator set transition, verified against the trusted validators of the
+// previous epoch.
+type EpochProofRequest struct {
+	OdrRequest
+	Config     *params.EthashConfig
+	Epoch      uint64
+	Previous   []common.Address // Trusted validators of the previous epoch
+	Headers    []*types.Header  // Checkpoint header and its confirming descendants
+	Validators []common.Address // Proven validators of the requested epoch
+}
+
+// StoreResult stores the retrieved data in local database
+func (req *EpochProofRequest) StoreResult(db gooladb.Database) {
+	StoreEpochValidators(db, req.Epoch, req.Headers[0].Hash(), req.Validators)
+}
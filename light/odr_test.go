@@ -190,7 +190,7 @@ func odrContractCall(ctx context.Context, db gooladb.Database, bc *core.BlockCha
 }
 
 func testChainGen(i int, block *core.BlockGen) {
-	signer := types.EIP155Signer{}
+	signer := types.NewEIP155Signer(params.TestChainConfig.ChainId)
 	switch i {
 	case 0:
 		// In block 1, the test bank sends account #1 some goolabackend.
@@ -243,7 +243,7 @@ func testChainOdr(t *testing.T, protocol int, fn odrTestFn) {
 	}
 
 	odr := &testOdr{sdb: sdb, ldb: ldb}
-	lightchain, err := NewLightChain(odr, params.TestChainConfig, dpos.NewFullFaker())
+	lightchain, err := NewLightChain(odr, params.TestChainConfig, dpos.NewFullFaker(), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
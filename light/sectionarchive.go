@@ -0,0 +1,323 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
+	"github.com/goola-team/goola/rlp"
+	"github.com/goola-team/goola/trie"
+)
+
+// Section archives carry complete CHT and bloom trie sections, allowing light
+// clients to be bootstrapped in bulk without retrieving the tries on demand. An
+// archive uses the same framing as header archives: a preamble of magic string,
+// version byte and genesis hash, followed by frames of sections, each section
+// frame being followed by the frames of every node of its trie.
+const (
+	sectionArchiveMagic   = "GOOLASEC"
+	sectionArchiveVersion = 1
+
+	frameSection = 0x01 // RLP encoded section descriptor
+	frameNode    = 0x02 // Raw trie node
+)
+
+// Kinds of helper tries stored in section archives.
+const (
+	SectionCht       = 0x00 // Canonical hash trie of a CHTFrequencyClient section
+	SectionBloomTrie = 0x01 // Bloom trie of a BloomTrieFrequency section
+)
+
+var (
+	errBadSectionArchive = errors.New("not a section archive")
+	errSectionIncomplete = errors.New("section trie incomplete")
+	errSectionHead       = errors.New("section head mismatch")
+	errSectionRoot       = errors.New("section root mismatch")
+)
+
+// HelperTrieSection is a single CHT or bloom trie section of a section archive.
+type HelperTrieSection struct {
+	Kind    uint8
+	Section uint64
+	Head    common.Hash
+	Root    common.Hash
+
+	Nodes [][]byte `rlp:"-"` // Every node of the section trie
+}
+
+// sectionTable returns the database table a helper trie kind is stored in.
+func sectionTable(db gooladb.Database, kind uint8) (gooladb.Database, error) {
+	switch kind {
+	case SectionCht:
+		return gooladb.NewTable(db, ChtTablePrefix), nil
+	case SectionBloomTrie:
+		return gooladb.NewTable(db, BloomTrieTablePrefix), nil
+	}
+	return nil, fmt.Errorf("unknown section kind %d", kind)
+}
+
+// sectionEnd returns the number of the last block in a section.
+func sectionEnd(kind uint8, section uint64) uint64 {
+	if kind == SectionCht {
+		return (section+1)*CHTFrequencyClient - 1
+	}
+	return (section+1)*BloomTrieFrequency - 1
+}
+
+// ReadHelperTrieSection reads a complete section of the given kind from the
+// database. Sections indexed by a server (LES/2 sized CHTs) are converted to
+// client sections. Nil is returned if the section is not known.
+func ReadHelperTrieSection(db gooladb.Database, kind uint8, section uint64) (*HelperTrieSection, error) {
+	table, err := sectionTable(db, kind)
+	if err != nil {
+		return nil, err
+	}
+	head := core.GetCanonicalHash(db, sectionEnd(kind, section))
+
+	var root common.Hash
+	switch kind {
+	case SectionCht:
+		if root = GetChtV2Root(db, section, head); root == (common.Hash{}) {
+			root = GetChtRoot(db, section, head)
+		}
+	case SectionBloomTrie:
+		root = GetBloomTrieRoot(db, section, head)
+	}
+	if head == (common.Hash{}) || root == (common.Hash{}) {
+		return nil, nil
+	}
+	triedb := trie.NewDatabase(table)
+	t, err := trie.New(root, triedb)
+	if err != nil {
+		return nil, err
+	}
+	sec := &HelperTrieSection{Kind: kind, Section: section, Head: head, Root: root}
+	for it := t.NodeIterator(nil); it.Next(true); {
+		// Nodes embedded into their parent have no hash of their own
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			blob, err := triedb.Node(hash)
+			if err != nil {
+				return nil, err
+			}
+			sec.Nodes = append(sec.Nodes, blob)
+		}
+		if err := it.Error(); err != nil {
+			return nil, err
+		}
+	}
+	return sec, nil
+}
+
+// genesisCheckpoint returns the trusted checkpoint of the chain with the given
+// genesis hash, independently of the network id it runs under.
+func genesisCheckpoint(genesis common.Hash) *params.TrustedCheckpoint {
+	for _, cp := range params.TrustedCheckpoints {
+		if cp.Genesis == genesis {
+			return cp
+		}
+	}
+	return nil
+}
+
+// VerifyHelperTrieSection checks that the nodes of a section form its complete
+// trie and that the section is consistent with the local database: its head is
+// the canonical one if the header is known, and both head and root match the
+// trusted checkpoint of the chain if it covers the section. CHT sections are
+// additionally checked to map their last block to the section head.
+func VerifyHelperTrieSection(db gooladb.Database, sec *HelperTrieSection) error {
+	if _, err := sectionTable(db, sec.Kind); err != nil {
+		return err
+	}
+	if head := core.GetCanonicalHash(db, sectionEnd(sec.Kind, sec.Section)); head != (common.Hash{}) && head != sec.Head {
+		return errSectionHead
+	}
+	if cp := genesisCheckpoint(core.GetCanonicalHash(db, 0)); cp != nil && cp.SectionIndex == sec.Section {
+		root := cp.CHTRoot
+		if sec.Kind == SectionBloomTrie {
+			root = cp.BloomRoot
+		}
+		if sec.Head != cp.SectionHead {
+			return errSectionHead
+		}
+		if sec.Root != root {
+			return errSectionRoot
+		}
+	}
+	// Every node is addressed by its own hash, so a trie which can be fully
+	// iterated from the root is exactly the one committed to by the root
+	nodes, _ := gooladb.NewMemDatabase()
+	for _, blob := range sec.Nodes {
+		nodes.Put(crypto.Keccak256(blob), blob)
+	}
+	t, err := trie.New(sec.Root, trie.NewDatabase(nodes))
+	if err != nil {
+		return errSectionIncomplete
+	}
+	it := t.NodeIterator(nil)
+	for it.Next(true) {
+	}
+	if it.Error() != nil {
+		return errSectionIncomplete
+	}
+	if sec.Kind == SectionCht {
+		var encNumber [8]byte
+		binary.BigEndian.PutUint64(encNumber[:], sectionEnd(sec.Kind, sec.Section))
+
+		var node ChtNode
+		if err := rlp.DecodeBytes(t.Get(encNumber[:]), &node); err != nil || node.Hash != sec.Head {
+			return errSectionRoot
+		}
+	}
+	return nil
+}
+
+// WriteHelperTrieSection verifies a section and stores its trie and root into
+// the database, as if it was indexed by a light client.
+func WriteHelperTrieSection(db gooladb.Database, sec *HelperTrieSection) error {
+	if err := VerifyHelperTrieSection(db, sec); err != nil {
+		return err
+	}
+	table, _ := sectionTable(db, sec.Kind)
+
+	batch := table.NewBatch()
+	for _, blob := range sec.Nodes {
+		if err := batch.Put(crypto.Keccak256(blob), blob); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= gooladb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	switch sec.Kind {
+	case SectionCht:
+		StoreChtRoot(db, sec.Section, sec.Head, sec.Root)
+	case SectionBloomTrie:
+		StoreBloomTrieRoot(db, sec.Section, sec.Head, sec.Root)
+	}
+	return nil
+}
+
+// SectionArchiveWriter encodes helper trie sections into a section archive.
+type SectionArchiveWriter struct {
+	w *bufio.Writer
+}
+
+// NewSectionArchiveWriter creates a section archive writer for the chain with
+// the given genesis, writing the archive preamble.
+func NewSectionArchiveWriter(w io.Writer, genesis common.Hash) (*SectionArchiveWriter, error) {
+	aw := &SectionArchiveWriter{w: bufio.NewWriter(w)}
+	aw.w.WriteString(sectionArchiveMagic)
+	aw.w.WriteByte(sectionArchiveVersion)
+	if _, err := aw.w.Write(genesis[:]); err != nil {
+		return nil, err
+	}
+	return aw, nil
+}
+
+// WriteSection appends a section along with all its trie nodes to the archive.
+func (aw *SectionArchiveWriter) WriteSection(sec *HelperTrieSection) error {
+	payload, err := rlp.EncodeToBytes(sec)
+	if err != nil {
+		return err
+	}
+	if err := writeArchiveFrame(aw.w, frameSection, payload); err != nil {
+		return err
+	}
+	for _, blob := range sec.Nodes {
+		if err := writeArchiveFrame(aw.w, frameNode, blob); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (aw *SectionArchiveWriter) Flush() error {
+	return aw.w.Flush()
+}
+
+// SectionArchiveReader decodes a section archive.
+type SectionArchiveReader struct {
+	r       *bufio.Reader
+	genesis common.Hash
+}
+
+// NewSectionArchiveReader creates a section archive reader, checking the
+// archive preamble.
+func NewSectionArchiveReader(r io.Reader) (*SectionArchiveReader, error) {
+	ar := &SectionArchiveReader{r: bufio.NewReader(r)}
+
+	preamble := make([]byte, len(sectionArchiveMagic)+1+common.HashLength)
+	if _, err := io.ReadFull(ar.r, preamble); err != nil {
+		return nil, errBadSectionArchive
+	}
+	if !bytes.Equal(preamble[:len(sectionArchiveMagic)], []byte(sectionArchiveMagic)) {
+		return nil, errBadSectionArchive
+	}
+	if version := preamble[len(sectionArchiveMagic)]; version != sectionArchiveVersion {
+		return nil, fmt.Errorf("unsupported section archive version %d", version)
+	}
+	ar.genesis = common.BytesToHash(preamble[len(sectionArchiveMagic)+1:])
+	return ar, nil
+}
+
+// Genesis returns the genesis hash of the archived chain.
+func (ar *SectionArchiveReader) Genesis() common.Hash {
+	return ar.genesis
+}
+
+// Next reads the next section of the archive along with its trie nodes,
+// returning io.EOF at the end of the archive.
+func (ar *SectionArchiveReader) Next() (*HelperTrieSection, error) {
+	kind, payload, err := readArchiveFrame(ar.r)
+	if err != nil {
+		return nil, err
+	}
+	if kind != frameSection {
+		return nil, fmt.Errorf("unexpected section archive frame kind %d", kind)
+	}
+	sec := new(HelperTrieSection)
+	if err := rlp.DecodeBytes(payload, sec); err != nil {
+		return nil, err
+	}
+	for {
+		if next, err := ar.r.Peek(1); err == io.EOF || (err == nil && next[0] != frameNode) {
+			return sec, nil
+		}
+		_, blob, err := readArchiveFrame(ar.r)
+		if err != nil {
+			return nil, err
+		}
+		sec.Nodes = append(sec.Nodes, blob)
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/rlp"
+	"github.com/goola-team/goola/trie"
+)
+
+// makeChtSection builds a fake CHT section 0 into db, mapping a sparse set of
+// block numbers including the last one of the section to fake hashes.
+func makeChtSection(t *testing.T, db gooladb.Database) (head, root common.Hash) {
+	triedb := trie.NewDatabase(gooladb.NewTable(db, ChtTablePrefix))
+	tr, _ := trie.New(common.Hash{}, triedb)
+	for num := uint64(0); num < CHTFrequencyClient; num += 1024 {
+		last := num + 1023
+		var encNumber [8]byte
+		binary.BigEndian.PutUint64(encNumber[:], last)
+
+		head = common.BytesToHash(encNumber[:])
+		data, _ := rlp.EncodeToBytes(ChtNode{head})
+		tr.Update(encNumber[:], data)
+	}
+	root, err := tr.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit CHT: %v", err)
+	}
+	triedb.Commit(root, false)
+	core.WriteCanonicalHash(db, head, CHTFrequencyClient-1)
+	StoreChtRoot(db, 0, head, root)
+	return head, root
+}
+
+func TestSectionArchiveRoundTrip(t *testing.T) {
+	server, _ := gooladb.NewMemDatabase()
+	head, root := makeChtSection(t, server)
+
+	sec, err := ReadHelperTrieSection(server, SectionCht, 0)
+	if err != nil || sec == nil {
+		t.Fatalf("failed to read section: %v", err)
+	}
+	if missing, _ := ReadHelperTrieSection(server, SectionBloomTrie, 0); missing != nil {
+		t.Fatalf("unknown bloom trie section read")
+	}
+	buf := new(bytes.Buffer)
+	w, _ := NewSectionArchiveWriter(buf, common.Hash{})
+	if err := w.WriteSection(sec); err != nil {
+		t.Fatalf("failed to write section: %v", err)
+	}
+	w.Flush()
+
+	r, err := NewSectionArchiveReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	imported, err := r.Next()
+	if err != nil {
+		t.Fatalf("failed to read archived section: %v", err)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Fatalf("archive end mismatch: have %v, want %v", err, io.EOF)
+	}
+	if imported.Head != head || imported.Root != root || len(imported.Nodes) != len(sec.Nodes) {
+		t.Fatalf("archived section mismatch")
+	}
+	// Import into an empty client database and check the trie is accessible
+	client, _ := gooladb.NewMemDatabase()
+	if err := WriteHelperTrieSection(client, imported); err != nil {
+		t.Fatalf("failed to import section: %v", err)
+	}
+	if have := GetChtRoot(client, 0, head); have != root {
+		t.Fatalf("CHT root mismatch: have %x, want %x", have, root)
+	}
+	tr, err := trie.New(root, trie.NewDatabase(gooladb.NewTable(client, ChtTablePrefix)))
+	if err != nil {
+		t.Fatalf("imported trie inaccessible: %v", err)
+	}
+	var encNumber [8]byte
+	binary.BigEndian.PutUint64(encNumber[:], CHTFrequencyClient-1)
+	if tr.Get(encNumber[:]) == nil {
+		t.Fatalf("imported trie missing section head")
+	}
+}
+
+func TestSectionArchiveVerification(t *testing.T) {
+	server, _ := gooladb.NewMemDatabase()
+	makeChtSection(t, server)
+	sec, _ := ReadHelperTrieSection(server, SectionCht, 0)
+
+	client, _ := gooladb.NewMemDatabase()
+
+	// Dropping a node must be detected
+	truncated := *sec
+	truncated.Nodes = sec.Nodes[:len(sec.Nodes)-1]
+	if err := VerifyHelperTrieSection(client, &truncated); err != errSectionIncomplete {
+		t.Errorf("truncated section: have %v, want %v", err, errSectionIncomplete)
+	}
+	// A head not committed to by the trie must be detected
+	forged := *sec
+	forged.Head = common.HexToHash("0xdeadbeef")
+	if err := VerifyHelperTrieSection(client, &forged); err != errSectionRoot {
+		t.Errorf("forged head: have %v, want %v", err, errSectionRoot)
+	}
+	// A section conflicting with the local canonical chain must be rejected
+	core.WriteCanonicalHash(client, common.HexToHash("0xcafebabe"), CHTFrequencyClient-1)
+	if err := VerifyHelperTrieSection(client, sec); err != errSectionHead {
+		t.Errorf("non-canonical section: have %v, want %v", err, errSectionHead)
+	}
+}
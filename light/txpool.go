@@ -19,6 +19,7 @@ package light
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"sync"
 	"time"
 
@@ -375,8 +376,12 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 		return core.ErrInsufficientFunds
 	}
 
-	// Should supply enough intrinsic gas
-	gas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil)
+	// Should fit the size limit and supply enough intrinsic gas
+	rules := pool.config.DataRules(new(big.Int).Add(header.Number, common.Big1))
+	if uint64(tx.Size()) > rules.MaxTxSize {
+		return core.ErrOversizedData
+	}
+	gas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, rules)
 	if err != nil {
 		return err
 	}
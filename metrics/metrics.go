@@ -75,6 +75,15 @@ func NewTimer(name string) metrics.Timer {
 	return metrics.GetOrRegisterTimer(name, metrics.DefaultRegistry)
 }
 
+// NewHistogram create a new metrics Histogram, either a real one of a NOP stub
+// depending on the metrics flag.
+func NewHistogram(name string) metrics.Histogram {
+	if !Enabled {
+		return new(metrics.NilHistogram)
+	}
+	return metrics.GetOrRegisterHistogram(name, metrics.DefaultRegistry, metrics.NewExpDecaySample(1028, 0.015))
+}
+
 // CollectProcessMetrics periodically collects various metrics about the running
 // process.
 func CollectProcessMetrics(refresh time.Duration) {
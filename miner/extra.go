@@ -0,0 +1,116 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/params"
+)
+
+// Variables built into every extra-data template, rendered per sealed block.
+const (
+	extraVarBlockNumber = "blockNumber" // Number of the block being sealed
+	extraVarTimestamp   = "timestamp"   // Unix timestamp of the block being sealed
+	extraVarNodeName    = "nodeName"    // Identity of the sealing node
+)
+
+// extraPart is a literal piece or a variable reference of an extra template.
+type extraPart struct {
+	text     string
+	variable bool
+}
+
+// extraTemplate is a parsed extra-data template. Variables are written as
+// {{name}} and resolve to either a built-in per-block value or one of the
+// custom variables (e.g. pool tags) supplied along with the template.
+type extraTemplate struct {
+	source string
+	parts  []extraPart
+	vars   map[string]string
+}
+
+// parseExtraTemplate parses an extra-data template, rejecting references to
+// variables that are neither built in nor supplied.
+func parseExtraTemplate(source string, vars map[string]string) (*extraTemplate, error) {
+	tmpl := &extraTemplate{source: source, vars: make(map[string]string, len(vars))}
+	for name, value := range vars {
+		tmpl.vars[name] = value
+	}
+	for rest := source; rest != ""; {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			tmpl.parts = append(tmpl.parts, extraPart{text: rest})
+			break
+		}
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated variable at offset %d", len(source)-len(rest)+start)
+		}
+		if start > 0 {
+			tmpl.parts = append(tmpl.parts, extraPart{text: rest[:start]})
+		}
+		name := strings.TrimSpace(rest[start+2 : start+end])
+		switch name {
+		case extraVarBlockNumber, extraVarTimestamp, extraVarNodeName:
+		default:
+			if _, ok := tmpl.vars[name]; !ok {
+				return nil, fmt.Errorf("unknown variable %q", name)
+			}
+		}
+		tmpl.parts = append(tmpl.parts, extraPart{text: name, variable: true})
+		rest = rest[start+end+2:]
+	}
+	return tmpl, nil
+}
+
+// render expands the template for the given header.
+func (t *extraTemplate) render(header *types.Header, nodeName string) []byte {
+	var out bytes.Buffer
+	for _, part := range t.parts {
+		if !part.variable {
+			out.WriteString(part.text)
+			continue
+		}
+		switch part.text {
+		case extraVarBlockNumber:
+			out.WriteString(header.Number.String())
+		case extraVarTimestamp:
+			out.WriteString(header.Time.String())
+		case extraVarNodeName:
+			out.WriteString(nodeName)
+		default:
+			out.WriteString(t.vars[part.text])
+		}
+	}
+	return out.Bytes()
+}
+
+// renderExtra expands the template for the given header, truncating it to the
+// maximum allowed extra-data size should a variable grow past the limit.
+func (t *extraTemplate) renderExtra(header *types.Header, nodeName string) []byte {
+	extra := t.render(header, nodeName)
+	if uint64(len(extra)) > params.MaximumExtraDataSize {
+		log.Warn("Miner extra template exceeds limit, truncating", "template", t.source, "size", len(extra), "limit", params.MaximumExtraDataSize)
+		extra = extra[:params.MaximumExtraDataSize]
+	}
+	return extra
+}
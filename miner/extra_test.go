@@ -0,0 +1,63 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/params"
+)
+
+func TestExtraTemplate(t *testing.T) {
+	header := &types.Header{Number: big.NewInt(1234), Time: big.NewInt(1520000000)}
+
+	tests := []struct {
+		source string
+		vars   map[string]string
+		want   string
+		fail   bool
+	}{
+		{source: "static", want: "static"},
+		{source: "#{{blockNumber}}@{{ timestamp }}", want: "#1234@1520000000"},
+		{source: "{{nodeName}}/{{pool}}", vars: map[string]string{"pool": "acme"}, want: "node/acme"},
+		{source: "{{pool}}", fail: true},
+		{source: "{{blockNumber", fail: true},
+	}
+	for i, tt := range tests {
+		tmpl, err := parseExtraTemplate(tt.source, tt.vars)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("test %d: invalid template %q accepted", i, tt.source)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to parse template %q: %v", i, tt.source, err)
+			continue
+		}
+		if extra := string(tmpl.render(header, "node")); extra != tt.want {
+			t.Errorf("test %d: rendered extra mismatch: have %q, want %q", i, extra, tt.want)
+		}
+	}
+	// Oversized renders must be truncated to the protocol limit
+	tmpl, _ := parseExtraTemplate("{{tag}}{{tag}}", map[string]string{"tag": "0123456789abcdef0123"})
+	if extra := tmpl.renderExtra(header, ""); uint64(len(extra)) != params.MaximumExtraDataSize {
+		t.Errorf("oversized extra not truncated: %d bytes", len(extra))
+	}
+}
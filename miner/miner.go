@@ -19,7 +19,9 @@ package miner
 
 import (
 	"fmt"
+	"math/big"
 	"sync/atomic"
+	"time"
 
 	"github.com/goola-team/goola/accounts"
 	"github.com/goola-team/goola/common"
@@ -150,6 +152,41 @@ func (self *Miner) SetExtra(extra []byte) error {
 	return nil
 }
 
+// SetExtraTemplate sets a template rendered into the extra-data of every sealed
+// block, supporting the {{blockNumber}}, {{timestamp}} and {{nodeName}} built-in
+// variables along with any custom ones in vars. The template is validated by
+// rendering it for the next block. An empty template reverts to the static
+// extra-data.
+func (self *Miner) SetExtraTemplate(source string, vars map[string]string) error {
+	if source == "" {
+		self.worker.setExtraTemplate(nil)
+		return nil
+	}
+	tmpl, err := parseExtraTemplate(source, vars)
+	if err != nil {
+		return err
+	}
+	parent := self.worker.chain.CurrentBlock()
+	next := &types.Header{
+		Number: new(big.Int).Add(parent.Number(), common.Big1),
+		Time:   big.NewInt(time.Now().Unix()),
+	}
+	self.worker.mu.Lock()
+	nodeName := self.worker.nodeName
+	self.worker.mu.Unlock()
+
+	if extra := tmpl.render(next, nodeName); uint64(len(extra)) > params.MaximumExtraDataSize {
+		return fmt.Errorf("Extra template exceeds max length. %d > %v", len(extra), params.MaximumExtraDataSize)
+	}
+	self.worker.setExtraTemplate(tmpl)
+	return nil
+}
+
+// SetNodeName sets the node identity available to extra-data templates.
+func (self *Miner) SetNodeName(name string) {
+	self.worker.setNodeName(name)
+}
+
 // Pending returns the currently pending block and associated state.
 func (self *Miner) Pending() (*types.Block, *state.StateDB) {
 	return self.worker.pending()
@@ -164,6 +201,22 @@ func (self *Miner) PendingBlock() *types.Block {
 	return self.worker.pendingBlock()
 }
 
+// LastPackingReport returns how pending transactions were packed into the last
+// block sealed by this miner, or nil if none was sealed yet.
+func (self *Miner) LastPackingReport() *PackingReport {
+	return self.worker.lastPackingReport()
+}
+
+// SetNextCoinbase sets the coinbase of the next sealed block only, the regular
+// etherbase being credited again afterwards. Mining work in progress is restarted
+// to take the override into account.
+func (self *Miner) SetNextCoinbase(addr common.Address) {
+	self.worker.setNextCoinbase(addr)
+	if self.Mining() {
+		self.worker.commitNewWork()
+	}
+}
+
 func (self *Miner) SetEtherbase(addr common.Address) {
 	self.coinbase = addr
 	self.worker.setEtherbase(addr)
@@ -0,0 +1,94 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+)
+
+// Preview is the block the miner would produce next on top of the current head,
+// packed from the transaction pool but not sealed.
+type Preview struct {
+	Block    *types.Block
+	Receipts types.Receipts
+	Fees     *big.Int // Transaction fees paid to the block's coinbase
+}
+
+// preview packs the pending transactions into a new block exactly as a mining
+// cycle would, without publishing the pending state or sealing the result.
+func (self *worker) preview() (*Preview, error) {
+	self.mu.Lock()
+	coinbase, extra, tmpl, name := self.coinbase, self.extra, self.extraTmpl, self.nodeName
+	self.mu.Unlock()
+
+	parent := self.chain.CurrentBlock()
+
+	tstamp := time.Now().Unix()
+	if parent.Time().Cmp(big.NewInt(tstamp)) >= 0 {
+		tstamp = parent.Time().Int64() + 1
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   core.CalcGasLimit(parent),
+		Extra:      extra,
+		Time:       big.NewInt(tstamp),
+		Coinbase:   coinbase,
+	}
+	if tmpl != nil {
+		header.Extra = tmpl.renderExtra(header, name)
+	}
+	if err := self.engine.Prepare(self.chain, header); err != nil {
+		return nil, err
+	}
+	state, err := self.chain.StateAt(parent.Root())
+	if err != nil {
+		return nil, err
+	}
+	work := &Work{
+		config:    self.config,
+		signer:    types.NewEIP155Signer(self.config.ChainId),
+		state:     state,
+		header:    header,
+		createdAt: time.Now(),
+	}
+	if err := self.commitPending(work, nil, coinbase); err != nil {
+		return nil, err
+	}
+	fees := new(big.Int)
+	for i, tx := range work.txs {
+		fee := new(big.Int).SetUint64(work.receipts[i].GasUsed)
+		fees.Add(fees, fee.Mul(fee, tx.GasPrice()))
+	}
+	block, err := self.engine.Finalize(self.chain, header, work.state, work.txs, work.receipts)
+	if err != nil {
+		return nil, err
+	}
+	return &Preview{Block: block, Receipts: work.receipts, Fees: fees}, nil
+}
+
+// Preview returns the block the miner would produce next, running the packing
+// of the pending transactions without sealing. The preview doesn't affect the
+// miner's own pending block.
+func (self *Miner) Preview() (*Preview, error) {
+	return self.worker.preview()
+}
@@ -0,0 +1,119 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/consensus/dpos"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
+)
+
+// Tests that previews pack the pool into the next block by price, accounting the
+// fees, without touching the chain, the pool or the miner's own work.
+func TestPreview(t *testing.T) {
+	var (
+		cheap, _  = crypto.GenerateKey()
+		pricey, _ = crypto.GenerateKey()
+		coinbase  = common.Address{0xcb}
+		alloc     = make(core.GenesisAlloc)
+	)
+	for _, key := range []*ecdsa.PrivateKey{cheap, pricey} {
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{Balance: big.NewInt(1000000000)}
+	}
+	db, _ := gooladb.NewMemDatabase()
+	genesis := (&core.Genesis{Config: params.TestChainConfig, GasLimit: params.GenesisGasLimit, Alloc: alloc}).MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, dpos.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	poolConfig := core.DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	pool := core.NewTxPool(poolConfig, params.TestChainConfig, chain)
+	defer pool.Stop()
+
+	signer := types.NewEIP155Signer(params.TestChainConfig.ChainId)
+	transfer := func(nonce uint64, price int64, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{0xaa}, big.NewInt(1), params.TxGas, big.NewInt(price), types.TxTypeTransfer, nil), signer, key)
+		return tx
+	}
+	txs := []*types.Transaction{transfer(0, 1, cheap), transfer(1, 1, cheap), transfer(0, 3, pricey)}
+	for _, err := range pool.AddRemotes(txs) {
+		if err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	worker := &worker{
+		config:   params.TestChainConfig,
+		engine:   dpos.NewFaker(),
+		chain:    chain,
+		backend:  &testBackend{chain: chain, txPool: pool},
+		coinbase: coinbase,
+	}
+	preview, err := worker.preview()
+	if err != nil {
+		t.Fatalf("failed to preview block: %v", err)
+	}
+	block := preview.Block
+	if block.NumberU64() != 1 || block.ParentHash() != genesis.Hash() || block.Coinbase() != coinbase {
+		t.Errorf("block mismatch: have #%d [parent %x, coinbase %x], want #1 [parent %x, coinbase %x]", block.NumberU64(), block.ParentHash(), block.Coinbase(), genesis.Hash(), coinbase)
+	}
+	want := []common.Hash{txs[2].Hash(), txs[0].Hash(), txs[1].Hash()}
+	if len(block.Transactions()) != len(want) || len(preview.Receipts) != len(want) {
+		t.Fatalf("packed transactions mismatch: have %d txs, %d receipts, want %d", len(block.Transactions()), len(preview.Receipts), len(want))
+	}
+	for i, tx := range block.Transactions() {
+		if tx.Hash() != want[i] {
+			t.Errorf("tx %d: hash mismatch: have %x, want %x", i, tx.Hash(), want[i])
+		}
+	}
+	if block.GasUsed() != 3*params.TxGas {
+		t.Errorf("gas used mismatch: have %d, want %d", block.GasUsed(), 3*params.TxGas)
+	}
+	if fees := new(big.Int).SetUint64((3 + 1 + 1) * params.TxGas); preview.Fees.Cmp(fees) != 0 {
+		t.Errorf("fees mismatch: have %v, want %v", preview.Fees, fees)
+	}
+	// Nothing was sealed, published or dropped
+	if head := chain.CurrentBlock(); head.Hash() != genesis.Hash() {
+		t.Errorf("chain head moved to #%d [%x]", head.NumberU64(), head.Hash())
+	}
+	if pending, _ := pool.Stats(); pending != len(txs) {
+		t.Errorf("pending transactions mismatch: have %d, want %d", pending, len(txs))
+	}
+	if worker.current != nil {
+		t.Errorf("preview published as the current work")
+	}
+	// Repeated previews pack the same block
+	again, err := worker.preview()
+	if err != nil {
+		t.Fatalf("failed to repeat preview: %v", err)
+	}
+	if again.Block.TxHash() != block.TxHash() || again.Block.Root() != block.Root() {
+		t.Errorf("repeated preview mismatch: have txs %x root %x, want %x %x", again.Block.TxHash(), again.Block.Root(), block.TxHash(), block.Root())
+	}
+}
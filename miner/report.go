@@ -0,0 +1,76 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/metrics"
+)
+
+// Reasons for skipping a transaction while packing a block.
+const (
+	skipUnderpriced = "underpriced" // Blob fee cap below the block's blob fee
+	skipGasLimit    = "gasLimit"    // Not enough gas left in the block
+	skipNonceTooLow = "nonceTooLow" // Already included, pool lagging behind the head
+	skipNonceGap    = "nonceGap"    // Nonce ahead of the account, rest of the account skipped
+	skipOversized   = "oversized"   // Over the size limit of the data policy
+	skipBlobs       = "blobs"       // No room for or no local copy of the blobs
+	skipFailed      = "failed"      // Any other execution failure
+)
+
+var (
+	packingConsideredMeter = metrics.NewMeter("miner/packing/considered")
+	packingIncludedMeter   = metrics.NewMeter("miner/packing/included")
+)
+
+// PackingReport summarizes how pending transactions were packed into a sealed
+// block.
+type PackingReport struct {
+	Number     uint64         `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	Considered int            `json:"considered"` // Transactions attempted, including skipped ones
+	Included   int            `json:"included"`
+	Skipped    map[string]int `json:"skipped"` // Skipped transactions by reason
+}
+
+// skip records a transaction skipped for the given reason.
+func (r *PackingReport) skip(reason string) {
+	if r.Skipped == nil {
+		r.Skipped = make(map[string]int)
+	}
+	r.Skipped[reason]++
+}
+
+// sealed finalizes the report for the sealed block and exports it as metrics.
+func (r *PackingReport) sealed(number uint64, hash common.Hash) *PackingReport {
+	report := &PackingReport{
+		Number:     number,
+		Hash:       hash,
+		Considered: r.Considered,
+		Included:   r.Included,
+		Skipped:    make(map[string]int, len(r.Skipped)),
+	}
+	for reason, count := range r.Skipped {
+		report.Skipped[reason] = count
+	}
+	packingConsideredMeter.Mark(int64(report.Considered))
+	packingIncludedMeter.Mark(int64(report.Included))
+	for reason, count := range report.Skipped {
+		metrics.NewMeter("miner/packing/skipped/" + reason).Mark(int64(count))
+	}
+	return report
+}
@@ -63,6 +63,9 @@ type Work struct {
 
 	state     *state.StateDB // apply state changes here
 	tcount    int            // tx count in cycle
+	blobs     int            // data blob count in cycle
+	gasPool   *core.GasPool  // available gas used to pack transactions
+	report    PackingReport  // outcome of packing the pending transactions
 
 	Block *types.Block // the new block
 
@@ -103,11 +106,17 @@ type worker struct {
 	proc    core.Validator
 	chainDb gooladb.Database
 
-	coinbase common.Address
-	extra    []byte
+	coinbase     common.Address
+	nextCoinbase *common.Address // One-off coinbase of the next sealed block, overriding coinbase
+	extra        []byte
+	extraTmpl    *extraTemplate // Per-block extra-data template, overriding extra if set
+	nodeName     string         // Identity of the node, available to extra templates
 
 	currentMu sync.Mutex
 	current   *Work
+	snapshot  atomic.Value // *pendingSnapshot of the current work (nil = stale), for lock-free pending reads
+
+	lastReport atomic.Value // *PackingReport of the last sealed block
 
 
 	unconfirmed *unconfirmedBlocks // set of locally mined blocks pending canonicalness confirmations
@@ -153,38 +162,98 @@ func (self *worker) setEtherbase(addr common.Address) {
 	self.coinbase = addr
 }
 
+func (self *worker) setNextCoinbase(addr common.Address) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.nextCoinbase = &addr
+}
+
+// sealedCoinbase clears the one-off coinbase override once a block crediting it
+// has been sealed.
+func (self *worker) sealedCoinbase(block *types.Block) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.nextCoinbase != nil && *self.nextCoinbase == block.Coinbase() {
+		log.Info("Sealed block with one-off coinbase", "number", block.Number(), "coinbase", block.Coinbase())
+		self.nextCoinbase = nil
+	}
+}
+
+// lastPackingReport returns the packing report of the last block sealed by this
+// worker, or nil if none was sealed yet.
+func (self *worker) lastPackingReport() *PackingReport {
+	report, _ := self.lastReport.Load().(*PackingReport)
+	return report
+}
+
 func (self *worker) setExtra(extra []byte) {
 	self.mu.Lock()
 	defer self.mu.Unlock()
 	self.extra = extra
+	self.extraTmpl = nil
 }
 
-func (self *worker) pending() (*types.Block, *state.StateDB) {
-	self.currentMu.Lock()
-	defer self.currentMu.Unlock()
+func (self *worker) setExtraTemplate(tmpl *extraTemplate) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.extraTmpl = tmpl
+}
+
+func (self *worker) setNodeName(name string) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.nodeName = name
+}
+
+// pendingSnapshot is an immutable view of the pending block and its state. The
+// state is only ever copied, never read directly, so any number of readers can
+// use the snapshot concurrently while the worker moves on.
+type pendingSnapshot struct {
+	block *types.Block
+	state *state.StateDB
+}
 
-	if atomic.LoadInt32(&self.mining) == 0 {
-		return types.NewBlock(
+// updateSnapshot replaces the pending snapshot with a copy of the current work.
+//
+// Note, this method assumes that the current work mutex is held!
+func (self *worker) updateSnapshot() *pendingSnapshot {
+	block := self.current.Block
+	if atomic.LoadInt32(&self.mining) == 0 || block == nil {
+		block = types.NewBlock(
 			self.current.header,
 			self.current.txs,
 			self.current.receipts,
-		), self.current.state.Copy()
+		)
 	}
-	return self.current.Block, self.current.state.Copy()
+	snap := &pendingSnapshot{block: block, state: self.current.state.Copy()}
+	self.snapshot.Store(snap)
+	return snap
 }
 
-func (self *worker) pendingBlock() *types.Block {
+// loadSnapshot returns the pending snapshot, recreating it if the current work
+// changed since it was taken.
+func (self *worker) loadSnapshot() *pendingSnapshot {
+	if snap, _ := self.snapshot.Load().(*pendingSnapshot); snap != nil {
+		return snap
+	}
 	self.currentMu.Lock()
 	defer self.currentMu.Unlock()
 
-	if atomic.LoadInt32(&self.mining) == 0 {
-		return types.NewBlock(
-			self.current.header,
-			self.current.txs,
-			self.current.receipts,
-		)
+	if snap, _ := self.snapshot.Load().(*pendingSnapshot); snap != nil {
+		return snap
 	}
-	return self.current.Block
+	return self.updateSnapshot()
+}
+
+// pending returns the pending block and a private copy of its state, consistent
+// with each other and safe to modify.
+func (self *worker) pending() (*types.Block, *state.StateDB) {
+	snap := self.loadSnapshot()
+	return snap.block, snap.state.Copy()
+}
+
+func (self *worker) pendingBlock() *types.Block {
+	return self.loadSnapshot().block
 }
 
 func (self *worker) start() {
@@ -251,6 +320,7 @@ func (self *worker) update() {
 				txset := types.NewTransactionsByPriceAndNonce(self.current.signer, txs)
 
 				self.current.commitTransactions(self.mux, txset, self.chain, self.coinbase)
+				self.snapshot.Store((*pendingSnapshot)(nil)) // Recreated by the next pending read
 				self.currentMu.Unlock()
 			} else {
 				// If we're mining, but nothing is being processed, wake on new transactions
@@ -297,6 +367,9 @@ func (self *worker) wait() {
 				log.Error("Failed writing block to chain", "err", err)
 				continue
 			}
+			self.sealedCoinbase(block)
+			self.lastReport.Store(work.report.sealed(block.NumberU64(), block.Hash()))
+
 			// check if canon block and write transactions
 			if stat == core.CanonStatTy {
 				// implicit by posting ChainHeadEvent
@@ -310,6 +383,9 @@ func (self *worker) wait() {
 			)
 			events = append(events, core.ChainEvent{Block: block, Hash: block.Hash(), Logs: logs})
 			if stat == core.CanonStatTy {
+				if touched := self.chain.TouchedState(block.Hash()); touched != nil {
+					events = append(events, core.StateTouchEvent{Block: block, Touched: touched})
+				}
 				events = append(events, core.ChainHeadEvent{Block: block})
 			}
 			self.chain.PostChainEvents(events, logs)
@@ -356,6 +432,7 @@ func (self *worker) makeCurrent(parent *types.Block, header *types.Header) error
 	// Keep track of transactions which return errors so they can be removed
 	work.tcount = 0
 	self.current = work
+	self.snapshot.Store((*pendingSnapshot)(nil))
 	return nil
 }
 
@@ -388,9 +465,16 @@ func (self *worker) commitNewWork() {
 		Extra:      self.extra,
 		Time:       big.NewInt(tstamp),
 	}
+	if self.extraTmpl != nil {
+		header.Extra = self.extraTmpl.renderExtra(header, self.nodeName)
+	}
 	// Only set the coinbase if we are mining (avoid spurious block rewards)
+	coinbase := self.coinbase
+	if self.nextCoinbase != nil {
+		coinbase = *self.nextCoinbase
+	}
 	if atomic.LoadInt32(&self.mining) == 1 {
-		header.Coinbase = self.coinbase
+		header.Coinbase = coinbase
 	}
 	if err := self.engine.Prepare(self.chain, header); err != nil {
 		log.Error("Failed to prepare header for mining", "err", err)
@@ -404,20 +488,18 @@ func (self *worker) commitNewWork() {
 	}
 	// Create the current work task and check any fork transitions needed
 	work := self.current
-	pending, err := self.backend.TxPool().Pending()
-	if err != nil {
+	if err := self.commitPending(work, self.mux, coinbase); err != nil {
 		log.Error("Failed to fetch pending transactions", "err", err)
 		return
 	}
-	txs := types.NewTransactionsByPriceAndNonce(self.current.signer, pending)
-	work.commitTransactions(self.mux, txs, self.chain, self.coinbase)
-
 
 	// Create the new block to seal with the consensus engine
 	if work.Block, err = self.engine.Finalize(self.chain, header, work.state, work.txs, work.receipts); err != nil {
 		log.Error("Failed to finalize block for sealing", "err", err)
 		return
 	}
+	self.updateSnapshot()
+
 	// We only care about logging if we're actually mining.
 	if atomic.LoadInt32(&self.mining) == 1 {
 		log.Info("Commit new mining work", "number", work.Block.Number(), "txs", work.tcount, "elapsed", common.PrettyDuration(time.Since(tstart)))
@@ -428,8 +510,38 @@ func (self *worker) commitNewWork() {
 
 
 
+// commitPending packs the executable transactions of the pool into the work,
+// ordered by price and nonce, after the gas-free lane if any.
+func (self *worker) commitPending(work *Work, mux *event.TypeMux, coinbase common.Address) error {
+	pending, err := self.backend.TxPool().Pending()
+	if err != nil {
+		return err
+	}
+	// Commit the gas-free lane first, its transactions can't outbid paying ones
+	if self.config.GasFree != nil {
+		lane := make(map[common.Address]types.Transactions)
+		for from, txs := range pending {
+			if self.config.IsGasFreeSender(from) {
+				lane[from] = txs
+				delete(pending, from)
+			}
+		}
+		if len(lane) > 0 {
+			txs := types.NewTransactionsByPriceAndNonce(work.signer, lane)
+			work.commitTransactions(mux, txs, self.chain, coinbase)
+		}
+	}
+	txs := types.NewTransactionsByPriceAndNonce(work.signer, pending)
+	work.commitTransactions(mux, txs, self.chain, coinbase)
+	return nil
+}
+
 func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsByPriceAndNonce, bc *core.BlockChain, coinbase common.Address) {
-	gp := new(core.GasPool).AddGas(env.header.GasLimit)
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	gp := env.gasPool
+	rules := env.config.DataRules(env.header.Number)
 
 	var coalescedLogs []*types.Log
 
@@ -449,6 +561,24 @@ func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsB
 		//
 		// We use the eip155 signer regardless of the current hf.
 		from, _ := types.Sender(env.signer, tx)
+		// Transactions over the size limit of the data policy are invalid in the block
+		env.report.Considered++
+		if rules.Enforced && uint64(tx.Size()) > rules.MaxTxSize {
+			log.Trace("Skipping oversized transaction", "hash", tx.Hash(), "size", tx.Size())
+			env.report.skip(skipOversized)
+			txs.Pop()
+			continue
+		}
+		// Blob transactions need room in the block and their blobs at hand to serve
+		blobs := tx.BlobCount()
+		if blobs > 0 && env.config.IsBlob(env.header.Number) {
+			if env.blobs+blobs > params.MaxBlobsPerBlock || !bc.HasBlobs(tx) {
+				log.Trace("Skipping blob transaction", "hash", tx.Hash(), "blobs", blobs)
+				env.report.skip(skipBlobs)
+				txs.Pop()
+				continue
+			}
+		}
 		// Start executing the transaction
 		env.state.Prepare(tx.Hash(), common.Hash{}, env.tcount)
 
@@ -457,33 +587,46 @@ func (env *Work) commitTransactions(mux *event.TypeMux, txs *types.TransactionsB
 		case core.ErrGasLimitReached:
 			// Pop the current out-of-gas transaction without shifting in the next from the account
 			log.Trace("Gas limit exceeded for current block", "sender", from)
+			env.report.skip(skipGasLimit)
 			txs.Pop()
 
 		case core.ErrNonceTooLow:
 			// New head notification data race between the transaction pool and miner, shift
 			log.Trace("Skipping transaction with low nonce", "sender", from, "nonce", tx.Nonce())
+			env.report.skip(skipNonceTooLow)
 			txs.Shift()
 
 		case core.ErrNonceTooHigh:
 			// Reorg notification data race between the transaction pool and miner, skip account =
 			log.Trace("Skipping account with hight nonce", "sender", from, "nonce", tx.Nonce())
+			env.report.skip(skipNonceGap)
 			txs.Pop()
 
+		case core.ErrBlobFeeTooLow:
+			// Priced below the blob fee of the block, may become includable later
+			log.Trace("Skipping underpriced blob transaction", "hash", tx.Hash())
+			env.report.skip(skipUnderpriced)
+			txs.Shift()
+
 		case nil:
 			// Everything ok, collect the logs and shift in the next transaction from the same account
 			coalescedLogs = append(coalescedLogs, logs...)
 			env.tcount++
+			env.blobs += blobs
+			env.report.Included++
 			txs.Shift()
 
 		default:
 			// Strange error, discard the transaction and get the next in line (note, the
 			// nonce-too-high clause will prevent us from executing in vain).
 			log.Debug("Transaction failed, account skipped", "hash", tx.Hash(), "err", err)
+			env.report.skip(skipFailed)
 			txs.Shift()
 		}
 	}
 
-	if len(coalescedLogs) > 0 || env.tcount > 0 {
+	// Announce the pending state changes unless packing a throwaway block
+	if mux != nil && (len(coalescedLogs) > 0 || env.tcount > 0) {
 		// make a copy, the state caches the logs and these logs get "upgraded" from pending to mined
 		// logs by filling in the block hash when the block was mined by the local miner. This can
 		// cause a race condition if a log was "upgraded" before the PendingLogsEvent is processed.
@@ -0,0 +1,202 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/goola-team/goola/accounts"
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/consensus/dpos"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/state"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/core/vm"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/gooladb"
+	"github.com/goola-team/goola/params"
+)
+
+// Tests that the one-off coinbase override is only cleared by sealing a block
+// crediting it.
+func TestNextCoinbase(t *testing.T) {
+	var (
+		worker   = &worker{coinbase: common.Address{0x01}}
+		override = common.Address{0x02}
+	)
+	worker.setNextCoinbase(override)
+
+	worker.sealedCoinbase(types.NewBlockWithHeader(&types.Header{Coinbase: worker.coinbase}))
+	if worker.nextCoinbase == nil {
+		t.Fatalf("override cleared by a block crediting the etherbase")
+	}
+	worker.sealedCoinbase(types.NewBlockWithHeader(&types.Header{Coinbase: override}))
+	if worker.nextCoinbase != nil {
+		t.Fatalf("override not cleared after sealing, have %x", *worker.nextCoinbase)
+	}
+}
+
+// Tests that pending reads get private copies of a consistent snapshot, which is
+// recreated once the current work changes.
+func TestPendingSnapshot(t *testing.T) {
+	db, _ := gooladb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	addr := common.Address{0x01}
+	statedb.SetBalance(addr, big.NewInt(1))
+
+	worker := &worker{current: &Work{header: &types.Header{Number: big.NewInt(1)}, state: statedb}}
+
+	// Concurrent readers modifying their copies mustn't interfere
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			block, pending := worker.pending()
+			if block.NumberU64() != 1 {
+				t.Errorf("pending block mismatch: have #%d, want #1", block.NumberU64())
+			}
+			if balance := pending.GetBalance(addr); balance.Cmp(big.NewInt(1)) != 0 {
+				t.Errorf("pending balance mismatch: have %v, want 1", balance)
+			}
+			pending.SetBalance(addr, big.NewInt(100))
+		}()
+	}
+	wg.Wait()
+
+	// Changes to the current work are only visible once the snapshot is dropped
+	worker.current.state.SetBalance(addr, big.NewInt(2))
+	if _, pending := worker.pending(); pending.GetBalance(addr).Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("snapshot changed before being dropped: have %v, want 1", pending.GetBalance(addr))
+	}
+	worker.snapshot.Store((*pendingSnapshot)(nil))
+	if _, pending := worker.pending(); pending.GetBalance(addr).Cmp(big.NewInt(2)) != 0 {
+		t.Errorf("snapshot not recreated: have %v, want 2", pending.GetBalance(addr))
+	}
+}
+
+// Tests that the packing report of a sealed block is detached from the work it
+// was collected on.
+func TestPackingReport(t *testing.T) {
+	worker := new(worker)
+	if report := worker.lastPackingReport(); report != nil {
+		t.Fatalf("report available before sealing: %+v", report)
+	}
+	work := &Work{report: PackingReport{Considered: 4, Included: 1}}
+	work.report.skip(skipGasLimit)
+	work.report.skip(skipNonceGap)
+	work.report.skip(skipNonceGap)
+
+	worker.lastReport.Store(work.report.sealed(1, common.Hash{0x01}))
+	work.report.skip(skipFailed)
+
+	report := worker.lastPackingReport()
+	if report.Number != 1 || report.Hash != (common.Hash{0x01}) {
+		t.Errorf("block mismatch: have #%d [%x], want #1 [%x]", report.Number, report.Hash, common.Hash{0x01})
+	}
+	if report.Considered != 4 || report.Included != 1 {
+		t.Errorf("count mismatch: have %d/%d, want 4/1", report.Considered, report.Included)
+	}
+	want := map[string]int{skipGasLimit: 1, skipNonceGap: 2}
+	if len(report.Skipped) != len(want) {
+		t.Fatalf("skip reasons mismatch: have %v, want %v", report.Skipped, want)
+	}
+	for reason, count := range want {
+		if report.Skipped[reason] != count {
+			t.Errorf("skipped %s mismatch: have %d, want %d", reason, report.Skipped[reason], count)
+		}
+	}
+}
+
+// testBackend is a miner backend around a blockchain and its transaction pool.
+type testBackend struct {
+	chain  *core.BlockChain
+	txPool *core.TxPool
+}
+
+func (b *testBackend) AccountManager() *accounts.Manager { return nil }
+func (b *testBackend) BlockChain() *core.BlockChain      { return b.chain }
+func (b *testBackend) TxPool() *core.TxPool              { return b.txPool }
+func (b *testBackend) ChainDb() gooladb.Database         { return nil }
+
+// Tests that the transactions of the gas-free senders are packed ahead of the
+// paying ones, while free calls to gas-free targets compete on price.
+func TestGasFreeOrdering(t *testing.T) {
+	var (
+		sender, _ = crypto.GenerateKey()
+		caller, _ = crypto.GenerateKey()
+		payer, _  = crypto.GenerateKey()
+		target    = common.Address{0xaa}
+		config    = *params.TestChainConfig
+		alloc     = make(core.GenesisAlloc)
+	)
+	config.GasFree = &params.GasFreeConfig{
+		Senders: []common.Address{crypto.PubkeyToAddress(sender.PublicKey)},
+		Targets: []common.Address{target},
+	}
+	for _, key := range []*ecdsa.PrivateKey{sender, caller, payer} {
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{Balance: big.NewInt(1000000)}
+	}
+	db, _ := gooladb.NewMemDatabase()
+	genesis := (&core.Genesis{Config: &config, GasLimit: params.GenesisGasLimit, Alloc: alloc}).MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, &config, dpos.NewFaker(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	poolConfig := core.DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	pool := core.NewTxPool(poolConfig, &config, chain)
+	defer pool.Stop()
+
+	signer := types.NewEIP155Signer(config.ChainId)
+	transfer := func(to common.Address, price int64, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(0, to, big.NewInt(1), params.TxGas, big.NewInt(price), types.TxTypeTransfer, nil), signer, key)
+		return tx
+	}
+	var (
+		paying   = transfer(common.Address{}, 2, payer)
+		free     = transfer(common.Address{}, 0, sender)
+		freeCall = transfer(target, 0, caller)
+	)
+	for _, err := range pool.AddRemotes([]*types.Transaction{paying, freeCall, free}) {
+		if err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	// Pack a block with room for two transactions only
+	statedb, _ := chain.StateAt(genesis.Root())
+	work := &Work{
+		config: &config,
+		signer: signer,
+		state:  statedb,
+		header: &types.Header{ParentHash: genesis.Hash(), Number: big.NewInt(1), Time: big.NewInt(1), GasLimit: 2 * params.TxGas},
+	}
+	worker := &worker{config: &config, chain: chain, backend: &testBackend{chain: chain, txPool: pool}}
+	if err := worker.commitPending(work, nil, common.Address{}); err != nil {
+		t.Fatalf("failed to pack transactions: %v", err)
+	}
+	if len(work.txs) != 2 || work.txs[0].Hash() != free.Hash() || work.txs[1].Hash() != paying.Hash() {
+		t.Errorf("packed transactions mismatch: have %v, want [%x %x]", work.txs, free.Hash(), paying.Hash())
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package multisig
+
+import (
+	"strings"
+
+	"github.com/goola-team/goola/accounts/abi"
+)
+
+// walletABIJSON is the subset of the widely deployed multisig wallet contract's
+// interface used to track and drive proposals.
+const walletABIJSON = `[
+	{"type":"function","name":"submitTransaction","constant":false,"inputs":[{"name":"destination","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"}],"outputs":[{"name":"transactionId","type":"uint256"}]},
+	{"type":"function","name":"confirmTransaction","constant":false,"inputs":[{"name":"transactionId","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"revokeConfirmation","constant":false,"inputs":[{"name":"transactionId","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"executeTransaction","constant":false,"inputs":[{"name":"transactionId","type":"uint256"}],"outputs":[]},
+	{"type":"event","name":"Confirmation","anonymous":false,"inputs":[{"indexed":true,"name":"sender","type":"address"},{"indexed":true,"name":"transactionId","type":"uint256"}]},
+	{"type":"event","name":"Revocation","anonymous":false,"inputs":[{"indexed":true,"name":"sender","type":"address"},{"indexed":true,"name":"transactionId","type":"uint256"}]},
+	{"type":"event","name":"Submission","anonymous":false,"inputs":[{"indexed":true,"name":"transactionId","type":"uint256"}]},
+	{"type":"event","name":"Execution","anonymous":false,"inputs":[{"indexed":true,"name":"transactionId","type":"uint256"}]},
+	{"type":"event","name":"ExecutionFailure","anonymous":false,"inputs":[{"indexed":true,"name":"transactionId","type":"uint256"}]},
+	{"type":"event","name":"OwnerAddition","anonymous":false,"inputs":[{"indexed":true,"name":"owner","type":"address"}]},
+	{"type":"event","name":"OwnerRemoval","anonymous":false,"inputs":[{"indexed":true,"name":"owner","type":"address"}]},
+	{"type":"event","name":"RequirementChange","anonymous":false,"inputs":[{"indexed":false,"name":"required","type":"uint256"}]}
+]`
+
+// walletABI is the parsed interface of the multisig wallet contract.
+var walletABI = mustParseABI(walletABIJSON)
+
+// Topics of the tracked multisig wallet events.
+var (
+	confirmationTopic      = walletABI.Events["Confirmation"].Id()
+	revocationTopic        = walletABI.Events["Revocation"].Id()
+	submissionTopic        = walletABI.Events["Submission"].Id()
+	executionTopic         = walletABI.Events["Execution"].Id()
+	executionFailureTopic  = walletABI.Events["ExecutionFailure"].Id()
+	ownerAdditionTopic     = walletABI.Events["OwnerAddition"].Id()
+	ownerRemovalTopic      = walletABI.Events["OwnerRemoval"].Id()
+	requirementChangeTopic = walletABI.Events["RequirementChange"].Id()
+)
+
+// mustParseABI parses a contract interface definition, panicking on failure.
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
@@ -0,0 +1,208 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package multisig
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+)
+
+var (
+	errUnknownWallet   = errors.New("unknown multisig wallet")
+	errNotOwner        = errors.New("account is not an owner of the wallet")
+	errAlreadyExecuted = errors.New("proposal already executed")
+)
+
+// PublicMultisigAPI provides an API to inspect the tracked multisig wallets and
+// to craft the transactions driving their proposals.
+type PublicMultisigAPI struct {
+	s *Service
+}
+
+// NewPublicMultisigAPI creates a new multisig API.
+func NewPublicMultisigAPI(s *Service) *PublicMultisigAPI {
+	return &PublicMultisigAPI{s}
+}
+
+// RPCWallet is the RPC representation of a tracked multisig wallet.
+type RPCWallet struct {
+	Address  common.Address   `json:"address"`
+	Owners   []common.Address `json:"owners"`
+	Required hexutil.Uint64   `json:"required"`
+	Pending  hexutil.Uint     `json:"pending"`
+}
+
+// RPCProposal is the RPC representation of a multisig proposal.
+type RPCProposal struct {
+	Wallet        common.Address   `json:"wallet"`
+	ID            hexutil.Uint64   `json:"id"`
+	Destination   *common.Address  `json:"destination"`
+	Value         *hexutil.Big     `json:"value"`
+	Data          hexutil.Bytes    `json:"data"`
+	Submitted     hexutil.Uint64   `json:"submitted"`
+	Confirmations []common.Address `json:"confirmations"`
+	Executed      bool             `json:"executed"`
+	Failed        bool             `json:"failed"`
+}
+
+// CraftedTx is an unsigned call to a multisig wallet, ready to be passed on to
+// eth_sendTransaction or signed offline.
+type CraftedTx struct {
+	From common.Address `json:"from"`
+	To   common.Address `json:"to"`
+	Data hexutil.Bytes  `json:"data"`
+}
+
+// Wallets returns the tracked multisig wallets with their current owners and
+// confirmation requirement.
+func (api *PublicMultisigAPI) Wallets() []RPCWallet {
+	api.s.lock.RLock()
+	defer api.s.lock.RUnlock()
+
+	wallets := make([]RPCWallet, 0, len(api.s.wallets))
+	for addr, w := range api.s.wallets {
+		owners := make([]common.Address, 0, len(w.owners))
+		for owner := range w.owners {
+			owners = append(owners, owner)
+		}
+		sort.Slice(owners, func(i, j int) bool { return owners[i].Hex() < owners[j].Hex() })
+
+		pending := 0
+		for _, p := range w.proposals {
+			if !p.Executed {
+				pending++
+			}
+		}
+		wallets = append(wallets, RPCWallet{
+			Address:  addr,
+			Owners:   owners,
+			Required: hexutil.Uint64(w.required),
+			Pending:  hexutil.Uint(pending),
+		})
+	}
+	sort.Slice(wallets, func(i, j int) bool { return wallets[i].Address.Hex() < wallets[j].Address.Hex() })
+	return wallets
+}
+
+// PendingProposals returns the proposals not executed yet, of a single wallet
+// if given, or of all tracked wallets otherwise.
+func (api *PublicMultisigAPI) PendingProposals(wallet *common.Address) []RPCProposal {
+	return newRPCProposals(api.s.Pending(wallet))
+}
+
+// AwaitingConfirmation returns the pending proposals of the wallets owned by
+// the given account, which the account has not confirmed yet.
+func (api *PublicMultisigAPI) AwaitingConfirmation(owner common.Address) []RPCProposal {
+	return newRPCProposals(api.s.Awaiting(owner))
+}
+
+// CraftConfirmation returns the transaction for an owner to confirm a proposal.
+func (api *PublicMultisigAPI) CraftConfirmation(wallet common.Address, id hexutil.Uint64, from common.Address) (*CraftedTx, error) {
+	return api.craft(wallet, uint64(id), from, "confirmTransaction", func(p *Proposal) error {
+		if p.confirmed(from) {
+			return fmt.Errorf("proposal %d already confirmed by %x", p.ID, from)
+		}
+		return nil
+	})
+}
+
+// CraftRevocation returns the transaction for an owner to revoke a previously
+// given confirmation of a proposal.
+func (api *PublicMultisigAPI) CraftRevocation(wallet common.Address, id hexutil.Uint64, from common.Address) (*CraftedTx, error) {
+	return api.craft(wallet, uint64(id), from, "revokeConfirmation", func(p *Proposal) error {
+		if !p.confirmed(from) {
+			return fmt.Errorf("proposal %d not confirmed by %x", p.ID, from)
+		}
+		return nil
+	})
+}
+
+// CraftExecution returns the transaction for an owner to (re)try executing a
+// sufficiently confirmed proposal.
+func (api *PublicMultisigAPI) CraftExecution(wallet common.Address, id hexutil.Uint64, from common.Address) (*CraftedTx, error) {
+	api.s.lock.RLock()
+	required := uint64(0)
+	if w, ok := api.s.wallets[wallet]; ok {
+		required = w.required
+	}
+	api.s.lock.RUnlock()
+
+	return api.craft(wallet, uint64(id), from, "executeTransaction", func(p *Proposal) error {
+		if have := uint64(len(p.Confirmations)); have < required {
+			return fmt.Errorf("proposal %d has %d of %d required confirmations", p.ID, have, required)
+		}
+		return nil
+	})
+}
+
+// craft validates that the sender owns the wallet and that the proposal is
+// still pending, runs the method specific check, and packs the call.
+func (api *PublicMultisigAPI) craft(wallet common.Address, id uint64, from common.Address, method string, check func(*Proposal) error) (*CraftedTx, error) {
+	api.s.lock.RLock()
+	w, ok := api.s.wallets[wallet]
+	if !ok {
+		api.s.lock.RUnlock()
+		return nil, errUnknownWallet
+	}
+	if !w.owners[from] {
+		api.s.lock.RUnlock()
+		return nil, errNotOwner
+	}
+	p, ok := w.proposals[id]
+	if !ok {
+		api.s.lock.RUnlock()
+		return nil, fmt.Errorf("unknown proposal %d", id)
+	}
+	p = p.copy()
+	api.s.lock.RUnlock()
+
+	if p.Executed {
+		return nil, errAlreadyExecuted
+	}
+	if err := check(p); err != nil {
+		return nil, err
+	}
+	data, err := walletABI.Pack(method, new(big.Int).SetUint64(id))
+	if err != nil {
+		return nil, err
+	}
+	return &CraftedTx{From: from, To: wallet, Data: data}, nil
+}
+
+// newRPCProposals converts tracked proposals into their RPC representation.
+func newRPCProposals(proposals []*Proposal) []RPCProposal {
+	result := make([]RPCProposal, len(proposals))
+	for i, p := range proposals {
+		result[i] = RPCProposal{
+			Wallet:        p.Wallet,
+			ID:            hexutil.Uint64(p.ID),
+			Destination:   p.Destination,
+			Value:         (*hexutil.Big)(p.Value),
+			Data:          p.Data,
+			Submitted:     hexutil.Uint64(p.Submitted),
+			Confirmations: p.Confirmations,
+			Executed:      p.Executed,
+			Failed:        p.Failed,
+		}
+	}
+	return result
+}
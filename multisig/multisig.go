@@ -0,0 +1,478 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package multisig implements a service tracking the proposals of multisig
+// wallet contracts and helping their owners to drive them to execution.
+package multisig
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/event"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/p2p"
+	"github.com/goola-team/goola/rpc"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// undoDepth is the number of most recently processed blocks whose changes are
+	// kept revertible. Reorgs forking off deeper trigger a full rescan.
+	undoDepth = 128
+)
+
+// WalletConfig is the configuration of a single tracked multisig wallet.
+type WalletConfig struct {
+	Address   common.Address   // Address of the multisig wallet contract
+	Owners    []common.Address `toml:",omitempty"` // Owners at deployment, which no event announces
+	Required  uint64           `toml:",omitempty"` // Confirmations required at deployment
+	FromBlock uint64           `toml:",omitempty"` // Block to start tracking the contract from
+}
+
+// Config contains the multisig wallets tracked by the service.
+type Config struct {
+	Wallets []WalletConfig `toml:",omitempty"`
+}
+
+// Backend is the chain access needed to track the multisig wallets.
+type Backend interface {
+	CurrentBlock() *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Proposal is a transaction submitted to a multisig wallet, along with the
+// owners having confirmed it so far.
+type Proposal struct {
+	Wallet        common.Address
+	ID            uint64
+	Destination   *common.Address // Nil if the submission wasn't a direct call
+	Value         *big.Int        // Nil if the submission wasn't a direct call
+	Data          []byte
+	Submitted     uint64 // Block number of the submission
+	Confirmations []common.Address
+	Executed      bool
+	Failed        bool // Whether the last execution attempt failed
+}
+
+// confirmed returns whether the given owner confirmed the proposal.
+func (p *Proposal) confirmed(owner common.Address) bool {
+	for _, addr := range p.Confirmations {
+		if addr == owner {
+			return true
+		}
+	}
+	return false
+}
+
+// copy returns a deep copy of the proposal, safe to hand out.
+func (p *Proposal) copy() *Proposal {
+	cpy := *p
+	cpy.Confirmations = append([]common.Address{}, p.Confirmations...)
+	cpy.Data = common.CopyBytes(p.Data)
+	if p.Value != nil {
+		cpy.Value = new(big.Int).Set(p.Value)
+	}
+	return &cpy
+}
+
+// wallet is the tracked state of a single multisig wallet contract.
+type wallet struct {
+	config    WalletConfig
+	owners    map[common.Address]bool
+	required  uint64
+	proposals map[uint64]*Proposal
+}
+
+// newWallet creates the initial tracking state of a wallet from its config.
+func newWallet(config WalletConfig) *wallet {
+	w := &wallet{
+		config:    config,
+		owners:    make(map[common.Address]bool),
+		required:  config.Required,
+		proposals: make(map[uint64]*Proposal),
+	}
+	for _, owner := range config.Owners {
+		w.owners[owner] = true
+	}
+	return w
+}
+
+// journal is the list of operations reverting the changes a block made to the
+// tracked wallets.
+type journal []func()
+
+// proposal retrieves a proposal of a wallet by id for modification, creating it
+// if not yet seen, and journals its current state.
+func (j *journal) proposal(w *wallet, id uint64) *Proposal {
+	if p, ok := w.proposals[id]; ok {
+		prev := p.copy()
+		*j = append(*j, func() { w.proposals[id] = prev })
+		return p
+	}
+	*j = append(*j, func() { delete(w.proposals, id) })
+
+	p := &Proposal{Wallet: w.config.Address, ID: id}
+	w.proposals[id] = p
+	return p
+}
+
+// owners journals the current owners and confirmation requirement of a wallet,
+// before they are modified.
+func (j *journal) owners(w *wallet) {
+	owners, required := make(map[common.Address]bool, len(w.owners)), w.required
+	for owner := range w.owners {
+		owners[owner] = true
+	}
+	*j = append(*j, func() { w.owners, w.required = owners, required })
+}
+
+// revert undoes the journaled changes, newest first.
+func (j journal) revert() {
+	for i := len(j) - 1; i >= 0; i-- {
+		j[i]()
+	}
+}
+
+// processed is a recently processed block, along with the journal reverting its
+// changes in case it's reorged out.
+type processed struct {
+	number uint64
+	hash   common.Hash
+	undo   journal
+}
+
+// Service tracks the events of the configured multisig wallets, aggregating the
+// pending proposals and the confirmations of their owners.
+type Service struct {
+	config  *Config
+	backend Backend
+
+	wallets map[common.Address]*wallet // Tracked wallets, guarded by lock
+	lock    sync.RWMutex
+
+	next   uint64       // Number of the next block to process
+	recent []*processed // Most recently processed blocks, oldest first
+	pruned bool         // Whether older processed blocks were dropped from recent
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a multisig orchestration service tracking the configured wallets.
+func New(config *Config, backend Backend) *Service {
+	s := &Service{
+		config:  config,
+		backend: backend,
+		quit:    make(chan struct{}),
+	}
+	s.reset()
+	return s
+}
+
+// reset drops all tracked state, restarting from the configured blocks.
+func (s *Service) reset() {
+	s.wallets = make(map[common.Address]*wallet)
+	s.next, s.recent, s.pruned = ^uint64(0), nil, false
+	for _, config := range s.config.Wallets {
+		s.wallets[config.Address] = newWallet(config)
+		if config.FromBlock < s.next {
+			s.next = config.FromBlock
+		}
+	}
+}
+
+// Protocols implements node.Service, returning the P2P network protocols used
+// by the multisig service (nil as it doesn't use the devp2p overlay network).
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the RPC API endpoints provided by the
+// multisig service.
+func (s *Service) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "multisig",
+			Version:   "1.0",
+			Service:   NewPublicMultisigAPI(s),
+			Public:    true,
+		},
+	}
+}
+
+// Start implements node.Service, starting to track the multisig wallets.
+func (s *Service) Start(server *p2p.Server) error {
+	s.wg.Add(1)
+	go s.loop()
+
+	log.Info("Multisig tracker started", "wallets", len(s.wallets))
+	return nil
+}
+
+// Stop implements node.Service, terminating the tracking of the wallets.
+func (s *Service) Stop() error {
+	close(s.quit)
+	s.wg.Wait()
+
+	log.Info("Multisig tracker stopped")
+	return nil
+}
+
+// loop processes all blocks up to the current head, and keeps processing new
+// ones as the chain progresses.
+func (s *Service) loop() {
+	defer s.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	sub := s.backend.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	s.sync(s.backend.CurrentBlock())
+	for {
+		select {
+		case ev := <-heads:
+			s.sync(ev.Block)
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// sync processes all blocks up to the given head. Blocks reorged out are rolled
+// back first, or if the reorg is too deep, tracking restarts from scratch. The
+// blocks are read without holding the lock, which is only taken to apply them.
+func (s *Service) sync(head *types.Block) {
+	if head == nil {
+		return
+	}
+	if !s.rollback() {
+		log.Warn("Multisig tracked blocks reorged too deep, rescanning", "number", s.next-1)
+		s.rescan(head)
+		return
+	}
+	for s.next <= head.NumberU64() {
+		select {
+		case <-s.quit:
+			return
+		default:
+		}
+		block := s.backend.GetBlockByNumber(s.next)
+		if block == nil {
+			return
+		}
+		receipts := s.backend.GetReceiptsByHash(block.Hash())
+
+		s.lock.Lock()
+		undo := s.processBlock(block, receipts)
+		s.lock.Unlock()
+
+		if s.recent = append(s.recent, &processed{block.NumberU64(), block.Hash(), undo}); len(s.recent) > undoDepth {
+			s.recent, s.pruned = s.recent[1:], true
+		}
+		s.next++
+	}
+}
+
+// rollback reverts the processed blocks no longer in the canonical chain. It
+// returns false, reverting nothing, if the chain forked off before the oldest
+// revertible block.
+func (s *Service) rollback() bool {
+	keep := len(s.recent)
+	for ; keep > 0; keep-- {
+		last := s.recent[keep-1]
+		if block := s.backend.GetBlockByNumber(last.number); block != nil && block.Hash() == last.hash {
+			break
+		}
+	}
+	if keep == len(s.recent) {
+		return true
+	}
+	if keep == 0 && s.pruned {
+		return false
+	}
+	s.lock.Lock()
+	for i := len(s.recent) - 1; i >= keep; i-- {
+		s.recent[i].undo.revert()
+	}
+	s.lock.Unlock()
+
+	s.recent, s.next = s.recent[:keep], s.recent[keep].number
+	return true
+}
+
+// rescan restarts tracking from the configured blocks, processing all blocks up
+// to the given head aside and only replacing the tracked state once done.
+func (s *Service) rescan(head *types.Block) {
+	fresh := &Service{config: s.config, backend: s.backend, quit: s.quit}
+	fresh.reset()
+	fresh.sync(head)
+
+	s.lock.Lock()
+	s.wallets = fresh.wallets
+	s.lock.Unlock()
+
+	s.next, s.recent, s.pruned = fresh.next, fresh.recent, fresh.pruned
+}
+
+// processBlock applies the multisig events within a block's receipts, returning
+// the journal to revert them.
+func (s *Service) processBlock(block *types.Block, receipts types.Receipts) journal {
+	var undo journal
+
+	txs := block.Transactions()
+	for i, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			w, ok := s.wallets[l.Address]
+			if !ok || block.NumberU64() < w.config.FromBlock || len(l.Topics) == 0 {
+				continue
+			}
+			var tx *types.Transaction
+			if i < len(txs) {
+				tx = txs[i]
+			}
+			s.processLog(w, l, block.NumberU64(), tx, &undo)
+		}
+	}
+	return undo
+}
+
+// processLog applies a single multisig event to the wallet's state, journaling
+// the changes.
+func (s *Service) processLog(w *wallet, l *types.Log, number uint64, tx *types.Transaction, undo *journal) {
+	switch {
+	case l.Topics[0] == submissionTopic && len(l.Topics) == 2:
+		p := undo.proposal(w, topicUint64(l.Topics[1]))
+		p.Submitted = number
+		if tx != nil {
+			decodeSubmission(p, w.config.Address, tx)
+		}
+	case l.Topics[0] == confirmationTopic && len(l.Topics) == 3:
+		owner, p := common.BytesToAddress(l.Topics[1].Bytes()), undo.proposal(w, topicUint64(l.Topics[2]))
+		if !p.confirmed(owner) {
+			p.Confirmations = append(p.Confirmations, owner)
+		}
+		undo.owners(w)
+		w.owners[owner] = true
+	case l.Topics[0] == revocationTopic && len(l.Topics) == 3:
+		owner, p := common.BytesToAddress(l.Topics[1].Bytes()), undo.proposal(w, topicUint64(l.Topics[2]))
+		for j, addr := range p.Confirmations {
+			if addr == owner {
+				p.Confirmations = append(p.Confirmations[:j], p.Confirmations[j+1:]...)
+				break
+			}
+		}
+	case l.Topics[0] == executionTopic && len(l.Topics) == 2:
+		p := undo.proposal(w, topicUint64(l.Topics[1]))
+		p.Executed, p.Failed = true, false
+	case l.Topics[0] == executionFailureTopic && len(l.Topics) == 2:
+		undo.proposal(w, topicUint64(l.Topics[1])).Failed = true
+	case l.Topics[0] == ownerAdditionTopic && len(l.Topics) == 2:
+		undo.owners(w)
+		w.owners[common.BytesToAddress(l.Topics[1].Bytes())] = true
+	case l.Topics[0] == ownerRemovalTopic && len(l.Topics) == 2:
+		undo.owners(w)
+		delete(w.owners, common.BytesToAddress(l.Topics[1].Bytes()))
+	case l.Topics[0] == requirementChangeTopic && len(l.Data) >= 32:
+		undo.owners(w)
+		w.required = new(big.Int).SetBytes(l.Data[:32]).Uint64()
+	}
+}
+
+// decodeSubmission fills in the details of a proposal from the transaction that
+// submitted it, if it called the wallet's submitTransaction method directly.
+func decodeSubmission(p *Proposal, wallet common.Address, tx *types.Transaction) {
+	method := walletABI.Methods["submitTransaction"]
+	if tx.To() == nil || *tx.To() != wallet || len(tx.Data()) < 4 || !bytes.Equal(tx.Data()[:4], method.Id()) {
+		return
+	}
+	var args struct {
+		Destination common.Address
+		Value       *big.Int
+		Data        []byte
+	}
+	if err := method.Inputs.Unpack(&args, tx.Data()[4:]); err != nil {
+		log.Debug("Failed to decode multisig submission", "wallet", wallet, "id", p.ID, "err", err)
+		return
+	}
+	p.Destination, p.Value, p.Data = &args.Destination, args.Value, args.Data
+}
+
+// topicUint64 interprets an indexed uint256 topic as a proposal id.
+func topicUint64(topic common.Hash) uint64 {
+	return new(big.Int).SetBytes(topic.Bytes()).Uint64()
+}
+
+// Pending returns the proposals not yet executed, optionally restricted to a
+// single wallet, ordered by wallet and id.
+func (s *Service) Pending(wallet *common.Address) []*Proposal {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var pending []*Proposal
+	for addr, w := range s.wallets {
+		if wallet != nil && *wallet != addr {
+			continue
+		}
+		for _, p := range w.proposals {
+			if !p.Executed {
+				pending = append(pending, p.copy())
+			}
+		}
+	}
+	sortProposals(pending)
+	return pending
+}
+
+// Awaiting returns the pending proposals of all wallets owned by the given
+// account, which the account has not confirmed yet.
+func (s *Service) Awaiting(owner common.Address) []*Proposal {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var awaiting []*Proposal
+	for _, w := range s.wallets {
+		if !w.owners[owner] {
+			continue
+		}
+		for _, p := range w.proposals {
+			if !p.Executed && !p.confirmed(owner) {
+				awaiting = append(awaiting, p.copy())
+			}
+		}
+	}
+	sortProposals(awaiting)
+	return awaiting
+}
+
+// sortProposals orders proposals by wallet address and id.
+func sortProposals(proposals []*Proposal) {
+	sort.Slice(proposals, func(i, j int) bool {
+		if proposals[i].Wallet != proposals[j].Wallet {
+			return bytes.Compare(proposals[i].Wallet[:], proposals[j].Wallet[:]) < 0
+		}
+		return proposals[i].ID < proposals[j].ID
+	})
+}
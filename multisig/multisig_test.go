@@ -0,0 +1,216 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package multisig
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/event"
+)
+
+// testBackend is a canned chain of blocks and receipts.
+type testBackend struct {
+	blocks   []*types.Block
+	receipts map[common.Hash]types.Receipts
+	feed     event.Feed
+	fork     byte // Marker of the blocks added, to create competing chains
+}
+
+func (b *testBackend) CurrentBlock() *types.Block { return b.blocks[len(b.blocks)-1] }
+
+func (b *testBackend) GetBlockByNumber(number uint64) *types.Block {
+	if number < uint64(len(b.blocks)) {
+		return b.blocks[number]
+	}
+	return nil
+}
+
+func (b *testBackend) GetReceiptsByHash(hash common.Hash) types.Receipts { return b.receipts[hash] }
+
+func (b *testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.feed.Subscribe(ch)
+}
+
+// addBlock appends a block with the given transactions, each emitting the logs
+// at the same position.
+func (b *testBackend) addBlock(txs []*types.Transaction, logs [][]*types.Log) {
+	header := &types.Header{Number: big.NewInt(int64(len(b.blocks))), Extra: []byte{byte(len(b.blocks)), b.fork}}
+	if len(b.blocks) > 0 {
+		header.ParentHash = b.blocks[len(b.blocks)-1].Hash()
+	}
+	block := types.NewBlock(header, txs, nil)
+	receipts := make(types.Receipts, len(logs))
+	for i := range logs {
+		receipts[i] = &types.Receipt{Logs: logs[i]}
+	}
+	b.blocks = append(b.blocks, block)
+	b.receipts[block.Hash()] = receipts
+}
+
+func idTopic(id uint64) common.Hash {
+	return common.BigToHash(new(big.Int).SetUint64(id))
+}
+
+func addrTopic(addr common.Address) common.Hash {
+	return common.BytesToHash(addr.Bytes())
+}
+
+// Tests that proposals are aggregated from the wallet events, and that the
+// confirmation transactions are crafted for the owners still missing.
+func TestProposalTracking(t *testing.T) {
+	var (
+		wallet = common.Address{0xaa}
+		alice  = common.Address{0x01}
+		bob    = common.Address{0x02}
+		carol  = common.Address{0x03}
+		dest   = common.Address{0xdd}
+	)
+	backend := &testBackend{receipts: make(map[common.Hash]types.Receipts)}
+	backend.addBlock(nil, nil)
+
+	input, err := walletABI.Pack("submitTransaction", dest, big.NewInt(1000), []byte{0xca, 0xfe})
+	if err != nil {
+		t.Fatalf("failed to pack submission: %v", err)
+	}
+	submit := types.NewTransaction(0, wallet, new(big.Int), 100000, new(big.Int), types.TxTypeTransfer, input)
+	backend.addBlock([]*types.Transaction{submit}, [][]*types.Log{{
+		{Address: wallet, Topics: []common.Hash{submissionTopic, idTopic(0)}},
+		{Address: wallet, Topics: []common.Hash{confirmationTopic, addrTopic(alice), idTopic(0)}},
+		{Address: common.Address{0xbb}, Topics: []common.Hash{submissionTopic, idTopic(7)}},
+	}})
+	backend.addBlock(nil, [][]*types.Log{{
+		{Address: wallet, Topics: []common.Hash{submissionTopic, idTopic(1)}},
+		{Address: wallet, Topics: []common.Hash{confirmationTopic, addrTopic(bob), idTopic(1)}},
+		{Address: wallet, Topics: []common.Hash{executionTopic, idTopic(1)}},
+	}})
+
+	service := New(&Config{Wallets: []WalletConfig{{Address: wallet, Owners: []common.Address{alice, bob, carol}, Required: 2}}}, backend)
+	service.sync(backend.CurrentBlock())
+
+	pending := service.Pending(nil)
+	if len(pending) != 1 {
+		t.Fatalf("pending proposal count mismatch: have %d, want 1", len(pending))
+	}
+	if p := pending[0]; p.ID != 0 || p.Destination == nil || *p.Destination != dest || p.Value.Int64() != 1000 || !bytes.Equal(p.Data, []byte{0xca, 0xfe}) {
+		t.Fatalf("submission decoded incorrectly: %+v", p)
+	}
+	if awaiting := service.Awaiting(alice); len(awaiting) != 0 {
+		t.Errorf("confirming owner still awaited: %+v", awaiting)
+	}
+	if awaiting := service.Awaiting(bob); len(awaiting) != 1 || awaiting[0].ID != 0 {
+		t.Errorf("awaiting proposals mismatch: %+v", awaiting)
+	}
+	api := NewPublicMultisigAPI(service)
+	if _, err := api.CraftExecution(wallet, 0, alice); err == nil {
+		t.Errorf("execution crafted without enough confirmations")
+	}
+	if _, err := api.CraftConfirmation(wallet, 0, common.Address{0xff}); err != errNotOwner {
+		t.Errorf("non-owner confirmation error mismatch: have %v, want %v", err, errNotOwner)
+	}
+	tx, err := api.CraftConfirmation(wallet, 0, bob)
+	if err != nil {
+		t.Fatalf("failed to craft confirmation: %v", err)
+	}
+	want, _ := walletABI.Pack("confirmTransaction", big.NewInt(0))
+	if tx.From != bob || tx.To != wallet || !bytes.Equal(tx.Data, want) {
+		t.Errorf("crafted confirmation mismatch: have %+v, want data %x", tx, want)
+	}
+	// Revoke the confirmation and ensure it's reflected on the next head
+	backend.addBlock(nil, [][]*types.Log{{
+		{Address: wallet, Topics: []common.Hash{revocationTopic, addrTopic(alice), idTopic(0)}},
+	}})
+	service.sync(backend.CurrentBlock())
+
+	if awaiting := service.Awaiting(alice); len(awaiting) != 1 {
+		t.Errorf("revoking owner not awaited: %+v", awaiting)
+	}
+	if _, err := api.CraftRevocation(wallet, hexutil.Uint64(0), alice); err == nil {
+		t.Errorf("revocation crafted for unconfirmed proposal")
+	}
+}
+
+// Tests that the changes of blocks reorged out are rolled back, and that reorgs
+// deeper than the revertible blocks rescan the chain.
+func TestReorgRollback(t *testing.T) {
+	var (
+		wallet = common.Address{0xaa}
+		alice  = common.Address{0x01}
+		dave   = common.Address{0x04}
+	)
+	backend := &testBackend{receipts: make(map[common.Hash]types.Receipts)}
+	backend.addBlock(nil, nil)
+	backend.addBlock(nil, [][]*types.Log{{
+		{Address: wallet, Topics: []common.Hash{submissionTopic, idTopic(0)}},
+		{Address: wallet, Topics: []common.Hash{confirmationTopic, addrTopic(alice), idTopic(0)}},
+	}})
+	backend.addBlock(nil, [][]*types.Log{{
+		{Address: wallet, Topics: []common.Hash{revocationTopic, addrTopic(alice), idTopic(0)}},
+		{Address: wallet, Topics: []common.Hash{submissionTopic, idTopic(1)}},
+		{Address: wallet, Topics: []common.Hash{ownerAdditionTopic, addrTopic(dave)}},
+	}})
+	service := New(&Config{Wallets: []WalletConfig{{Address: wallet, Owners: []common.Address{alice}, Required: 1}}}, backend)
+	service.sync(backend.CurrentBlock())
+
+	if pending := service.Pending(nil); len(pending) != 2 || len(pending[0].Confirmations) != 0 {
+		t.Fatalf("pending proposals mismatch: %+v", pending)
+	}
+	// Replace the last block with a competing one
+	backend.blocks, backend.fork = backend.blocks[:2], 1
+	backend.addBlock(nil, [][]*types.Log{{
+		{Address: wallet, Topics: []common.Hash{submissionTopic, idTopic(2)}},
+	}})
+	backend.addBlock(nil, nil)
+	service.sync(backend.CurrentBlock())
+
+	pending := service.Pending(nil)
+	if len(pending) != 2 || pending[0].ID != 0 || pending[1].ID != 2 {
+		t.Fatalf("pending proposals mismatch after reorg: %+v", pending)
+	}
+	if len(pending[0].Confirmations) != 1 || pending[0].Confirmations[0] != alice {
+		t.Errorf("revocation not rolled back: %+v", pending[0].Confirmations)
+	}
+	if awaiting := service.Awaiting(dave); len(awaiting) != 0 {
+		t.Errorf("owner addition not rolled back: %+v", awaiting)
+	}
+	// Grow the chain beyond the revertible blocks and reorg it from the start
+	for len(backend.blocks) <= 2*undoDepth {
+		backend.addBlock(nil, nil)
+	}
+	service.sync(backend.CurrentBlock())
+
+	backend.blocks, backend.fork = backend.blocks[:1], 2
+	backend.addBlock(nil, [][]*types.Log{{
+		{Address: wallet, Topics: []common.Hash{submissionTopic, idTopic(3)}},
+	}})
+	for len(backend.blocks) <= 2*undoDepth+1 {
+		backend.addBlock(nil, nil)
+	}
+	service.sync(backend.CurrentBlock())
+
+	if pending := service.Pending(nil); len(pending) != 1 || pending[0].ID != 3 {
+		t.Fatalf("pending proposals mismatch after deep reorg: %+v", pending)
+	}
+	if service.next != uint64(len(backend.blocks)) {
+		t.Errorf("next block mismatch: have %d, want %d", service.next, len(backend.blocks))
+	}
+}
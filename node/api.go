@@ -75,6 +75,22 @@ func (api *PrivateAdminAPI) RemovePeer(url string) (bool, error) {
 	return true, nil
 }
 
+// PeerHistory retrieves the timeline of the connections of the peers whose node
+// ID or enode URL starts with the given prefix, or of all peers if omitted. The
+// connections are listed oldest first, with the ones still alive last.
+func (api *PrivateAdminAPI) PeerHistory(id *string) ([]*p2p.PeerConnection, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	var prefix string
+	if id != nil {
+		prefix = *id
+	}
+	return server.PeerHistory(prefix), nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *PrivateAdminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
@@ -266,6 +282,24 @@ func (api *PublicAdminAPI) Datadir() string {
 	return api.node.DataDir()
 }
 
+// PrivateDebugAPI is the collection of debugging related API methods exposed
+// only over a secure RPC channel.
+type PrivateDebugAPI struct {
+	node *Node // Node interfaced by this API
+}
+
+// NewPrivateDebugAPI creates a new API definition for the private debug methods
+// of the node itself.
+func NewPrivateDebugAPI(node *Node) *PrivateDebugAPI {
+	return &PrivateDebugAPI{node: node}
+}
+
+// SlowQueries retrieves the most recent RPC requests served slower than the
+// configured slow query threshold, oldest first.
+func (api *PrivateDebugAPI) SlowQueries() []*rpc.SlowQuery {
+	return rpc.SlowQueries()
+}
+
 // PublicDebugAPI is the collection of debugging related API methods exposed over
 // both secure and unsecure RPC channels.
 type PublicDebugAPI struct {
@@ -0,0 +1,146 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/rpc"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	// archiveCacheSize is the number of delegated call results cached. Pruned
+	// data is historical, so the results never go stale.
+	archiveCacheSize = 1024
+
+	// archiveCallTimeout is the maximum time a delegated call may take.
+	archiveCallTimeout = 10 * time.Second
+)
+
+// prunedError is implemented by the errors of calls failing because the data
+// they need was pruned locally.
+type prunedError interface {
+	Pruned() bool
+}
+
+// archiveDelegate forwards the method calls failing on pruned data to an
+// upstream archive node, making a pruned node appear as an archive one.
+type archiveDelegate struct {
+	url   string
+	cache *lru.Cache
+
+	lock    sync.Mutex
+	client  *rpc.Client // Connection to the archive node, dialed on first use
+	rate    float64     // Calls delegated per second, 0 = unlimited
+	tokens  float64     // Calls which may currently be delegated without waiting
+	updated time.Time   // Time the available calls were last refilled
+}
+
+func newArchiveDelegate(url string, rate int) *archiveDelegate {
+	cache, _ := lru.New(archiveCacheSize)
+	return &archiveDelegate{
+		url:     url,
+		cache:   cache,
+		rate:    float64(rate),
+		tokens:  float64(rate),
+		updated: time.Now(),
+	}
+}
+
+// call serves a method call which failed locally on pruned data from the
+// archive node. It implements rpc.CallFallback.
+func (d *archiveDelegate) call(ctx context.Context, method string, params interface{}, err error) (json.RawMessage, bool) {
+	if err, ok := err.(prunedError); !ok || !err.Pruned() {
+		return nil, false
+	}
+	raw, _ := params.(json.RawMessage)
+
+	key := method + string(raw)
+	if result, ok := d.cache.Get(key); ok {
+		return result.(json.RawMessage), true
+	}
+	client, ok := d.acquire(ctx)
+	if !ok {
+		return nil, false
+	}
+	var args []json.RawMessage
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return nil, false
+		}
+	}
+	ifaces := make([]interface{}, len(args))
+	for i, arg := range args {
+		ifaces[i] = arg
+	}
+	ctx, cancel := context.WithTimeout(ctx, archiveCallTimeout)
+	defer cancel()
+
+	var result json.RawMessage
+	if err := client.CallContext(ctx, &result, method, ifaces...); err != nil {
+		log.Debug("Archive delegation failed", "method", method, "err", err)
+		return nil, false
+	}
+	d.cache.Add(key, result)
+	return result, true
+}
+
+// acquire returns the connection to the archive node if the delegation rate
+// limit allows another call, dialing it if not yet connected.
+func (d *archiveDelegate) acquire(ctx context.Context) (*rpc.Client, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.rate > 0 {
+		now := time.Now()
+		d.tokens += d.rate * now.Sub(d.updated).Seconds()
+		if d.tokens > d.rate {
+			d.tokens = d.rate
+		}
+		d.updated = now
+		if d.tokens < 1 {
+			log.Debug("Archive delegation rate limited", "url", d.url)
+			return nil, false
+		}
+		d.tokens--
+	}
+	if d.client == nil {
+		client, err := rpc.DialContext(ctx, d.url)
+		if err != nil {
+			log.Warn("Failed to connect to archive node", "url", d.url, "err", err)
+			return nil, false
+		}
+		d.client = client
+	}
+	return d.client, true
+}
+
+// close disconnects from the archive node.
+func (d *archiveDelegate) close() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.client != nil {
+		d.client.Close()
+		d.client = nil
+	}
+}
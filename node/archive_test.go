@@ -0,0 +1,76 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goola-team/goola/rpc"
+)
+
+// EchoService is the API of a fake archive node, counting the calls served.
+type EchoService struct{ calls int }
+
+func (s *EchoService) Echo(arg string) string {
+	s.calls++
+	return arg
+}
+
+// testPrunedError is a local call failure caused by pruned data.
+type testPrunedError struct{}
+
+func (testPrunedError) Error() string { return "pruned" }
+func (testPrunedError) Pruned() bool  { return true }
+
+// Tests that only calls failing on pruned data are delegated to the archive
+// node, and that the results are cached and the delegation rate limited.
+func TestArchiveDelegation(t *testing.T) {
+	service := new(EchoService)
+	server := rpc.NewServer()
+	if err := server.RegisterName("test", service); err != nil {
+		t.Fatalf("failed to register archive service: %v", err)
+	}
+	upstream := httptest.NewServer(server)
+	defer upstream.Close()
+
+	delegate := newArchiveDelegate(upstream.URL, 1)
+	defer delegate.close()
+
+	ctx := context.Background()
+	if _, ok := delegate.call(ctx, "test_echo", json.RawMessage(`["foo"]`), errors.New("failure")); ok {
+		t.Fatalf("delegated call failing on unpruned data")
+	}
+	for i := 0; i < 2; i++ {
+		result, ok := delegate.call(ctx, "test_echo", json.RawMessage(`["foo"]`), testPrunedError{})
+		if !ok {
+			t.Fatalf("call %d: failed to delegate", i)
+		}
+		if string(result) != `"foo"` {
+			t.Fatalf("call %d: result mismatch: have %s, want %s", i, result, `"foo"`)
+		}
+	}
+	if service.calls != 1 {
+		t.Fatalf("cached result not reused: %d calls served", service.calls)
+	}
+	if _, ok := delegate.call(ctx, "test_echo", json.RawMessage(`["bar"]`), testPrunedError{}); ok {
+		t.Fatalf("delegation not rate limited")
+	}
+}
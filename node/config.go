@@ -24,15 +24,18 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/goola-team/goola/accounts"
 	"github.com/goola-team/goola/accounts/keystore"
 	"github.com/goola-team/goola/accounts/usbwallet"
+	"github.com/goola-team/goola/accounts/watchonly"
 	"github.com/goola-team/goola/common"
 	"github.com/goola-team/goola/crypto"
 	"github.com/goola-team/goola/log"
 	"github.com/goola-team/goola/p2p"
 	"github.com/goola-team/goola/p2p/discover"
+	"github.com/goola-team/goola/rpc"
 )
 
 const (
@@ -41,6 +44,8 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirPeerHistory     = "peerhistory"        // Path within the datadir to store the peer connectivity timeline
+	datadirWatchOnly       = "watchonly.json"     // Path within the datadir to the watch-only address list
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -85,6 +90,11 @@ type Config struct {
 	// NoUSB disables hardware wallet monitoring and connectivity.
 	NoUSB bool `toml:",omitempty"`
 
+	// WatchOnlyAccounts is a list of addresses to track without holding their keys.
+	// They are listed alongside the other accounts, but can't sign anything. Any
+	// addresses watched at runtime are persisted in the data directory.
+	WatchOnlyAccounts []common.Address `toml:",omitempty"`
+
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
@@ -145,6 +155,55 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// WSNotifyBatchSize is the maximum number of subscription notifications sent
+	// in a single websocket frame to clients opting in to batching (via
+	// rpc_batchNotifications). Batching is disabled if below two.
+	WSNotifyBatchSize int `toml:",omitempty"`
+
+	// WSNotifyBatchDelay is the maximum time a subscription notification is held
+	// back waiting for a batch to fill up.
+	WSNotifyBatchDelay time.Duration `toml:",omitempty"`
+
+	// RPCNamespacePolicies restricts the origins and virtual hostnames allowed
+	// to access individual API namespaces over the HTTP and websocket RPC
+	// interfaces (e.g. leave "goolabackend" open to all, but serve "debug" to
+	// localhost only). Namespaces without a policy use the endpoint settings.
+	RPCNamespacePolicies map[string]rpc.NamespacePolicy `toml:",omitempty"`
+
+	// RPCSlowQueryThreshold is the minimum duration of RPC requests logged and
+	// retained as slow queries (debug_slowQueries). Zero disables the log.
+	RPCSlowQueryThreshold time.Duration `toml:",omitempty"`
+
+	// TracingEndpoint is the OTLP/HTTP traces endpoint of the OpenTelemetry
+	// collector the spans of RPC requests, sync stages and block imports are
+	// exported to. Empty disables tracing.
+	TracingEndpoint string `toml:",omitempty"`
+
+	// ArchiveURL is the RPC endpoint of an archive node the calls failing on
+	// locally pruned state or chain history are forwarded to, instead of
+	// returning an error. Empty disables delegation.
+	ArchiveURL string `toml:",omitempty"`
+
+	// ArchiveRate is the maximum number of calls delegated to the archive node
+	// per second. Zero lifts the limit.
+	ArchiveRate int `toml:",omitempty"`
+
+	// RPCTLSCert and RPCTLSKey are the PEM encoded certificate and private key
+	// files used to serve the HTTP and websocket RPC interfaces over TLS. If not
+	// set, the endpoints are served in plain text.
+	RPCTLSCert string `toml:",omitempty"`
+	RPCTLSKey  string `toml:",omitempty"`
+
+	// RPCTLSClientCA is a PEM encoded bundle of the authorities allowed to sign
+	// client certificates. Clients presenting such a certificate are granted the
+	// namespaces of the permission tiers listed in its organizational units.
+	RPCTLSClientCA string `toml:",omitempty"`
+
+	// RPCTLSTiers maps permission tiers to the API namespaces they grant access
+	// to over the HTTP and websocket RPC interfaces. These namespaces are only
+	// served to clients authenticated with a certificate of the tier.
+	RPCTLSTiers map[string][]string `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 }
@@ -416,8 +475,13 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 		return nil, "", err
 	}
 	// Assemble the account manager and supported backends
+	watched, err := watchonly.NewBackend(conf.resolvePath(datadirWatchOnly), conf.WatchOnlyAccounts)
+	if err != nil {
+		return nil, "", err
+	}
 	backends := []accounts.Backend{
 		keystore.NewKeyStore(keydir, scryptN, scryptP),
+		watched,
 	}
 	if !conf.NoUSB {
 		// Start a USB hub for Ledger hardware wallets
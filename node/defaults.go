@@ -21,6 +21,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/goola-team/goola/p2p"
 	"github.com/goola-team/goola/p2p/nat"
@@ -40,6 +41,10 @@ var DefaultConfig = Config{
 	HTTPModules: []string{"net", "goolajs"},
 	WSPort:      DefaultWSPort,
 	WSModules:   []string{"net", "goolajs"},
+
+	WSNotifyBatchSize:  128,
+	WSNotifyBatchDelay: 50 * time.Millisecond,
+	ArchiveRate:        16,
 	P2P: p2p.Config{
 		ListenAddr: ":31318",
 		MaxPeers:   25,
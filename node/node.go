@@ -17,9 +17,11 @@
 package node
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -33,6 +35,7 @@ import (
 	"github.com/goola-team/goola/log"
 	"github.com/goola-team/goola/p2p"
 	"github.com/goola-team/goola/rpc"
+	"github.com/goola-team/goola/tracing"
 	"github.com/prometheus/prometheus/util/flock"
 )
 
@@ -51,8 +54,10 @@ type Node struct {
 	serviceFuncs []ServiceConstructor     // Service constructors (in dependency order)
 	services     map[reflect.Type]Service // Currently running services
 
-	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
-	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	rpcAPIs       []rpc.API         // List of APIs currently provided by the node
+	archive       *archiveDelegate  // Delegate of the calls failing on pruned data, nil if none
+	tracer        *tracing.Exporter // Exporter of the recorded trace spans, nil if tracing is disabled
+	inprocHandler *rpc.Server       // In-process RPC request handler to process the API requests
 
 	ipcEndpoint string       // IPC endpoint to listen at (empty = IPC disabled)
 	ipcListener net.Listener // IPC RPC listener socket to serve API requests
@@ -162,9 +167,22 @@ func (n *Node) Start() error {
 	if n.serverConfig.NodeDatabase == "" {
 		n.serverConfig.NodeDatabase = n.config.NodeDB()
 	}
+	if n.serverConfig.PeerHistoryDatabase == "" {
+		n.serverConfig.PeerHistoryDatabase = n.config.resolvePath(datadirPeerHistory)
+	}
+	if n.serverConfig.QuarantineDir != "" {
+		n.serverConfig.QuarantineDir = n.config.resolvePath(n.serverConfig.QuarantineDir)
+	}
 	running := &p2p.Server{Config: n.serverConfig}
 	n.log.Info("Starting peer-to-peer node", "instance", n.serverConfig.Name)
 
+	// Export the trace spans of the services from their construction on
+	if n.config.TracingEndpoint != "" {
+		n.tracer = tracing.NewExporter(n.config.TracingEndpoint, n.config.name())
+		tracing.SetExporter(n.tracer)
+		n.log.Info("Exporting traces", "endpoint", n.config.TracingEndpoint)
+	}
+
 	// Otherwise copy and specialize the P2P configuration
 	services := make(map[reflect.Type]Service)
 	for _, constructor := range n.serviceFuncs {
@@ -250,6 +268,11 @@ func (n *Node) openDataDir() error {
 // startup. It's not meant to be called at any time afterwards as it makes certain
 // assumptions about the state of the node.
 func (n *Node) startRPC(services map[reflect.Type]Service) error {
+	rpc.SetSlowQueryThreshold(n.config.RPCSlowQueryThreshold)
+	if n.config.ArchiveURL != "" {
+		n.archive = newArchiveDelegate(n.config.ArchiveURL, n.config.ArchiveRate)
+	}
+
 	// Gather all the possible APIs to surface
 	apis := n.apis()
 	for _, service := range services {
@@ -279,10 +302,20 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 	return nil
 }
 
+// newRPCHandler creates an RPC server, delegating the calls failing on pruned
+// data to the archive node if one is configured.
+func (n *Node) newRPCHandler() *rpc.Server {
+	handler := rpc.NewServer()
+	if n.archive != nil {
+		handler.SetCallFallback(n.archive.call)
+	}
+	return handler
+}
+
 // startInProc initializes an in-process RPC endpoint.
 func (n *Node) startInProc(apis []rpc.API) error {
 	// Register all the APIs exposed by the services
-	handler := rpc.NewServer()
+	handler := n.newRPCHandler()
 	for _, api := range apis {
 		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 			return err
@@ -308,7 +341,7 @@ func (n *Node) startIPC(apis []rpc.API) error {
 		return nil
 	}
 	// Register all the APIs exposed by the services
-	handler := rpc.NewServer()
+	handler := n.newRPCHandler()
 	for _, api := range apis {
 		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 			return err
@@ -376,25 +409,34 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 		whitelist[module] = true
 	}
 	// Register all the APIs exposed by the services
-	handler := rpc.NewServer()
+	var (
+		handler = n.newRPCHandler()
+		tiered  = n.tieredNamespaces()
+		public  []string
+	)
 	for _, api := range apis {
-		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
+		exposed := whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public)
+		if exposed || tiered[api.Namespace] {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 				return err
 			}
 			n.log.Debug("HTTP registered", "service", api.Service, "namespace", api.Namespace)
 		}
+		if exposed {
+			public = append(public, api.Namespace)
+		}
 	}
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	listener, scheme, err := n.listenRPC(endpoint)
+	if err != nil {
 		return err
 	}
-	go rpc.NewHTTPServer(cors, vhosts, handler).Serve(listener)
-	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
+	server := rpc.NewHTTPServerWithPolicies(cors, vhosts, n.config.RPCNamespacePolicies, handler)
+	if len(tiered) > 0 {
+		server.Handler = rpc.NewClientCertHandler(server.Handler, public, n.config.RPCTLSTiers)
+	}
+	go server.Serve(listener)
+	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("%s://%s", scheme, endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
 	n.httpListener = listener
@@ -429,25 +471,35 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 		whitelist[module] = true
 	}
 	// Register all the APIs exposed by the services
-	handler := rpc.NewServer()
+	var (
+		handler = n.newRPCHandler()
+		tiered  = n.tieredNamespaces()
+		public  []string
+	)
+	handler.SetNotificationBatching(n.config.WSNotifyBatchSize, n.config.WSNotifyBatchDelay)
 	for _, api := range apis {
-		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
+		exposed := exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public)
+		if exposed || tiered[api.Namespace] {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 				return err
 			}
 			n.log.Debug("WebSocket registered", "service", api.Service, "namespace", api.Namespace)
 		}
+		if exposed {
+			public = append(public, api.Namespace)
+		}
 	}
 	// All APIs registered, start the HTTP listener
-	var (
-		listener net.Listener
-		err      error
-	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	listener, scheme, err := n.listenRPC(endpoint)
+	if err != nil {
 		return err
 	}
-	go rpc.NewWSServer(wsOrigins, handler).Serve(listener)
-	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()))
+	wsHandler := handler.WebsocketHandlerWithPolicies(wsOrigins, n.config.RPCNamespacePolicies)
+	if len(tiered) > 0 {
+		wsHandler = rpc.NewClientCertHandler(wsHandler, public, n.config.RPCTLSTiers)
+	}
+	go (&http.Server{Handler: wsHandler}).Serve(listener)
+	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("%s://%s", strings.Replace(scheme, "http", "ws", 1), listener.Addr()))
 
 	// All listeners booted successfully
 	n.wsEndpoint = endpoint
@@ -457,6 +509,43 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	return nil
 }
 
+// listenRPC opens the TCP listener of an HTTP based RPC endpoint, terminating
+// TLS on it if a certificate is configured. The URL scheme of the endpoint is
+// returned alongside the listener.
+func (n *Node) listenRPC(endpoint string) (net.Listener, string, error) {
+	var config *tls.Config
+	if n.config.RPCTLSCert != "" || n.config.RPCTLSKey != "" {
+		var err error
+		if config, err = rpc.NewTLSConfig(n.config.RPCTLSCert, n.config.RPCTLSKey, n.config.RPCTLSClientCA); err != nil {
+			return nil, "", err
+		}
+	}
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, "", err
+	}
+	if config == nil {
+		return listener, "http", nil
+	}
+	return tls.NewListener(listener, config), "https", nil
+}
+
+// tieredNamespaces returns the set of API namespaces granted by the permission
+// tiers of client certificates, which is empty unless TLS client authentication
+// is configured.
+func (n *Node) tieredNamespaces() map[string]bool {
+	tiered := make(map[string]bool)
+	if n.config.RPCTLSCert == "" || n.config.RPCTLSClientCA == "" {
+		return tiered
+	}
+	for _, namespaces := range n.config.RPCTLSTiers {
+		for _, namespace := range namespaces {
+			tiered[namespace] = true
+		}
+	}
+	return tiered
+}
+
 // stopWS terminates the websocket RPC endpoint.
 func (n *Node) stopWS() {
 	if n.wsListener != nil {
@@ -487,6 +576,10 @@ func (n *Node) Stop() error {
 	n.stopHTTP()
 	n.stopIPC()
 	n.rpcAPIs = nil
+	if n.archive != nil {
+		n.archive.close()
+		n.archive = nil
+	}
 	failure := &StopError{
 		Services: make(map[reflect.Type]error),
 	}
@@ -499,6 +592,12 @@ func (n *Node) Stop() error {
 	n.services = nil
 	n.server = nil
 
+	if n.tracer != nil {
+		tracing.SetExporter(nil)
+		n.tracer.Close()
+		n.tracer = nil
+	}
+
 	// Release instance directory lock.
 	if n.instanceDirLock != nil {
 		if err := n.instanceDirLock.Release(); err != nil {
@@ -674,6 +773,10 @@ func (n *Node) apis() []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicDebugAPI(n),
 			Public:    true,
+		}, {
+			Namespace: "debug",
+			Version:   "1.0",
+			Service:   NewPrivateDebugAPI(n),
 		}, {
 			Namespace: "goolajs",
 			Version:   "1.0",
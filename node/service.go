@@ -17,6 +17,7 @@
 package node
 
 import (
+	"path/filepath"
 	"reflect"
 
 	"github.com/goola-team/goola/accounts"
@@ -57,6 +58,34 @@ func (ctx *ServiceContext) ResolvePath(path string) string {
 	return ctx.config.resolvePath(path)
 }
 
+// Subcontext derives a service context keeping its data in the given directory
+// within the node's data directory, and with its own event multiplexer. It is
+// meant for running multiple instances of the same service side by side.
+func (ctx *ServiceContext) Subcontext(dir string) *ServiceContext {
+	config := *ctx.config
+	if config.DataDir != "" {
+		config.DataDir = filepath.Join(config.DataDir, dir)
+	}
+	return &ServiceContext{
+		config:         &config,
+		services:       ctx.services,
+		EventMux:       new(event.TypeMux),
+		AccountManager: ctx.AccountManager,
+	}
+}
+
+// Identity returns the short identity of the node, the user supplied one if set
+// or the client name otherwise.
+func (ctx *ServiceContext) Identity() string {
+	if ctx.config == nil {
+		return ""
+	}
+	if ctx.config.UserIdent != "" {
+		return ctx.config.UserIdent
+	}
+	return ctx.config.name()
+}
+
 // Service retrieves a currently running service registered of a specific type.
 func (ctx *ServiceContext) Service(service interface{}) error {
 	element := reflect.ValueOf(service).Elem()
@@ -61,6 +61,34 @@ func TestContextDatabases(t *testing.T) {
 	}
 }
 
+// Tests that subcontexts keep their databases isolated within their own folder
+// of the data directory.
+func TestSubcontextDatabases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := &ServiceContext{config: &Config{Name: "unit-test", DataDir: dir}}
+	sub := ctx.Subcontext("network5")
+	if sub.EventMux == ctx.EventMux {
+		t.Fatalf("subcontext shares the event mux")
+	}
+	db, err := sub.OpenDatabase("persistent", 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open persistent database: %v", err)
+	}
+	db.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, "network5", "unit-test", "persistent")); err != nil {
+		t.Fatalf("subcontext database doesn't exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "unit-test", "persistent")); err == nil {
+		t.Fatalf("subcontext database created in the parent folder")
+	}
+}
+
 // Tests that already constructed services can be retrieves by later ones.
 func TestContextServices(t *testing.T) {
 	stack, err := New(testNodeConfig())
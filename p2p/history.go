@@ -0,0 +1,256 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/p2p/discover"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// defaultPeerHistoryRetention is the time the connections of peers are kept
+	// in the history if no retention is configured.
+	defaultPeerHistoryRetention = 7 * 24 * time.Hour
+
+	// peerHistoryCleanupCycle is the time period of dropping expired connections
+	// from the history.
+	peerHistoryCleanupCycle = time.Hour
+)
+
+// peerHistoryPrefix is the database key prefix of the finished connections,
+// followed by the big endian connection time and the peer ID.
+var peerHistoryPrefix = []byte("c:")
+
+// PeerConnection is a connection of a remote peer in the connectivity history.
+type PeerConnection struct {
+	ID              discover.NodeID `json:"id"`
+	Name            string          `json:"name"`
+	RemoteAddress   string          `json:"remoteAddress"`
+	Inbound         bool            `json:"inbound"`
+	Connected       time.Time       `json:"connected"`
+	Disconnected    *time.Time      `json:"disconnected,omitempty"`    // Nil while the peer is still connected
+	Duration        string          `json:"duration"`                  // Time the connection lasted (so far)
+	Reason          string          `json:"reason,omitempty"`          // Error the connection was dropped with
+	RemoteRequested bool            `json:"remoteRequested,omitempty"` // Whether the remote side disconnected
+}
+
+// peerHistory keeps a rolling timeline of the connections and disconnections of
+// remote peers, persisting the finished connections into a database so flapping
+// peers and network partitions can be diagnosed after the fact.
+type peerHistory struct {
+	db        *leveldb.DB
+	retention time.Duration
+
+	live map[discover.NodeID]*PeerConnection // Connections still alive
+	lock sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newPeerHistory opens the peer history database at the given path, keeping
+// the connections for the given retention. If no path is given, an in-memory
+// database is used.
+func newPeerHistory(path string, retention time.Duration) (*peerHistory, error) {
+	var (
+		db  *leveldb.DB
+		err error
+	)
+	if path == "" {
+		db, err = leveldb.Open(storage.NewMemStorage(), nil)
+	} else {
+		db, err = leveldb.OpenFile(path, &opt.Options{OpenFilesCacheCapacity: 5})
+		if _, corrupted := err.(*errors.ErrCorrupted); corrupted {
+			db, err = leveldb.RecoverFile(path, nil)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if retention == 0 {
+		retention = defaultPeerHistoryRetention
+	}
+	h := &peerHistory{
+		db:        db,
+		retention: retention,
+		live:      make(map[discover.NodeID]*PeerConnection),
+		quit:      make(chan struct{}),
+	}
+	h.expire(time.Now())
+
+	h.wg.Add(1)
+	go h.expirer()
+	return h, nil
+}
+
+// close stops the expiration of old connections and closes the database.
+func (h *peerHistory) close() {
+	close(h.quit)
+	h.wg.Wait()
+	h.db.Close()
+}
+
+// connected records that a peer connected.
+func (h *peerHistory) connected(p *Peer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.live[p.ID()] = &PeerConnection{
+		ID:            p.ID(),
+		Name:          p.Name(),
+		RemoteAddress: p.RemoteAddr().String(),
+		Inbound:       p.Inbound(),
+		Connected:     time.Now(),
+	}
+}
+
+// disconnected records that a peer disconnected, persisting its connection.
+func (h *peerHistory) disconnected(p *Peer, err error, requested bool) {
+	h.lock.Lock()
+	conn := h.live[p.ID()]
+	delete(h.live, p.ID())
+	h.lock.Unlock()
+
+	if conn == nil {
+		return
+	}
+	now := time.Now()
+	conn.Disconnected = &now
+	conn.Duration = common.PrettyDuration(now.Sub(conn.Connected)).String()
+	conn.Reason = err.Error()
+	conn.RemoteRequested = requested
+
+	blob, err := json.Marshal(conn)
+	if err != nil {
+		log.Warn("Failed to encode peer connection", "err", err)
+		return
+	}
+	if err := h.db.Put(peerHistoryKey(conn.Connected, conn.ID), blob, nil); err != nil {
+		log.Warn("Failed to persist peer connection", "err", err)
+	}
+}
+
+// peerHistoryKey returns the database key of a finished connection.
+func peerHistoryKey(connected time.Time, id discover.NodeID) []byte {
+	key := make([]byte, len(peerHistoryPrefix)+8+len(id))
+	copy(key, peerHistoryPrefix)
+	binary.BigEndian.PutUint64(key[len(peerHistoryPrefix):], uint64(connected.UnixNano()))
+	copy(key[len(peerHistoryPrefix)+8:], id[:])
+	return key
+}
+
+// connections returns the connections of the peers whose ID starts with the
+// given hex prefix (all peers if empty), oldest first. The connections still
+// alive are returned last.
+func (h *peerHistory) connections(prefix string) []*PeerConnection {
+	var conns []*PeerConnection
+
+	it := h.db.NewIterator(util.BytesPrefix(peerHistoryPrefix), nil)
+	for it.Next() {
+		key := it.Key()[len(peerHistoryPrefix)+8:]
+		if !strings.HasPrefix(common.Bytes2Hex(key), prefix) {
+			continue
+		}
+		conn := new(PeerConnection)
+		if err := json.Unmarshal(it.Value(), conn); err != nil {
+			log.Warn("Failed to decode peer connection", "err", err)
+			continue
+		}
+		conns = append(conns, conn)
+	}
+	it.Release()
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var live []*PeerConnection
+	for id, conn := range h.live {
+		if strings.HasPrefix(id.String(), prefix) {
+			cpy := *conn
+			cpy.Duration = common.PrettyDuration(time.Since(conn.Connected)).String()
+			live = append(live, &cpy)
+		}
+	}
+	sort.Slice(live, func(i, j int) bool { return live[i].Connected.Before(live[j].Connected) })
+	return append(conns, live...)
+}
+
+// expirer periodically drops the connections older than the retention.
+func (h *peerHistory) expirer() {
+	defer h.wg.Done()
+
+	tick := time.NewTicker(peerHistoryCleanupCycle)
+	defer tick.Stop()
+
+	for {
+		select {
+		case now := <-tick.C:
+			h.expire(now)
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// expire deletes the connections which started before the retention period.
+func (h *peerHistory) expire(now time.Time) {
+	limit := peerHistoryKey(now.Add(-h.retention), discover.NodeID{})
+
+	it := h.db.NewIterator(&util.Range{Start: peerHistoryPrefix, Limit: limit}, nil)
+	defer it.Release()
+
+	batch := new(leveldb.Batch)
+	for it.Next() {
+		batch.Delete(it.Key())
+	}
+	if batch.Len() == 0 {
+		return
+	}
+	if err := h.db.Write(batch, nil); err != nil {
+		log.Error("Failed to expire peer history", "err", err)
+	}
+}
+
+// PeerHistory returns the connectivity history of the peers whose node ID or
+// enode URL starts with the given prefix (all peers if empty), oldest first.
+// It returns nil if the server is not running.
+func (srv *Server) PeerHistory(prefix string) []*PeerConnection {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	if !srv.running || srv.history == nil {
+		return nil
+	}
+	prefix = strings.TrimPrefix(strings.TrimPrefix(prefix, "enode://"), "0x")
+	if at := strings.IndexByte(prefix, '@'); at >= 0 {
+		prefix = prefix[:at]
+	}
+	return srv.history.connections(strings.ToLower(prefix))
+}
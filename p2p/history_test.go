@@ -0,0 +1,84 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/p2p/discover"
+)
+
+// Tests that the peer history records connections with their disconnect reasons,
+// persists them across restarts and drops them after the retention.
+func TestPeerHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "peerhistory")
+
+	history, err := newPeerHistory(path, time.Hour)
+	if err != nil {
+		t.Fatalf("failed to open peer history: %v", err)
+	}
+	flapping := NewPeer(discover.NodeID{0x01}, "flapping", nil)
+	stable := NewPeer(discover.NodeID{0x02}, "stable", nil)
+
+	history.connected(flapping)
+	history.disconnected(flapping, DiscTooManyPeers, true)
+	history.connected(flapping)
+	history.disconnected(flapping, DiscReadTimeout, false)
+	history.connected(stable)
+
+	conns := history.connections("")
+	if len(conns) != 3 {
+		t.Fatalf("connection count mismatch: have %d, want %d", len(conns), 3)
+	}
+	if conns[0].Reason != DiscTooManyPeers.Error() || !conns[0].RemoteRequested || conns[0].Disconnected == nil {
+		t.Errorf("first connection mismatch: %+v", conns[0])
+	}
+	if conns[1].Reason != DiscReadTimeout.Error() || conns[1].RemoteRequested {
+		t.Errorf("second connection mismatch: %+v", conns[1])
+	}
+	if conns[2].ID != stable.ID() || conns[2].Disconnected != nil {
+		t.Errorf("live connection mismatch: %+v", conns[2])
+	}
+	if conns := history.connections("02"); len(conns) != 1 || conns[0].ID != stable.ID() {
+		t.Errorf("filtered connections mismatch: %+v", conns)
+	}
+	history.close()
+
+	// Reopen the history and ensure the finished connections are retained
+	if history, err = newPeerHistory(path, time.Hour); err != nil {
+		t.Fatalf("failed to reopen peer history: %v", err)
+	}
+	defer history.close()
+
+	if conns := history.connections(""); len(conns) != 2 || conns[0].Name != "flapping" {
+		t.Fatalf("persisted connections mismatch: %+v", conns)
+	}
+	// Expire the connections and ensure they are dropped
+	history.expire(time.Now().Add(2 * time.Hour))
+	if conns := history.connections(""); len(conns) != 0 {
+		t.Errorf("expired connections retained: %+v", conns)
+	}
+}
@@ -17,8 +17,10 @@
 package p2p
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"sort"
 	"sync"
@@ -109,6 +111,9 @@ type Peer struct {
 
 	// events receives message send / receive events if set
 	events *event.Feed
+
+	// quarantine persists protocol violating messages if set
+	quarantine *quarantine
 }
 
 // NewPeer returns a peer for testing purposes.
@@ -284,7 +289,13 @@ func (p *Peer) handle(msg Msg) error {
 		// it's a subprotocol message
 		proto, err := p.getProto(msg.Code)
 		if err != nil {
-			return fmt.Errorf("msg code out of range: %v", msg.Code)
+			err = fmt.Errorf("msg code out of range: %v", msg.Code)
+			countViolation("p2p", "invalid-msg-code")
+			if p.quarantine != nil {
+				payload, _ := ioutil.ReadAll(msg.Payload)
+				p.quarantineMsg("p2p", msg.Code, payload, "invalid-msg-code", err)
+			}
+			return err
 		}
 		select {
 		case proto.in <- msg:
@@ -340,6 +351,7 @@ func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error)
 		proto.closed = p.closed
 		proto.wstart = writeStart
 		proto.werr = writeErr
+		proto.capture = p.quarantine != nil
 		var rw MsgReadWriter = proto
 		if p.events != nil {
 			rw = newMsgEventer(rw, p.events, p.ID(), proto.Name)
@@ -378,6 +390,12 @@ type protoRW struct {
 	werr   chan<- error    // for write results
 	offset uint64
 	w      MsgWriter
+
+	// The last message read is retained if captured, to be quarantined if it
+	// turns out to violate the protocol
+	capture     bool
+	lastCode    uint64
+	lastPayload []byte
 }
 
 func (rw *protoRW) WriteMsg(msg Msg) (err error) {
@@ -403,6 +421,14 @@ func (rw *protoRW) ReadMsg() (Msg, error) {
 	select {
 	case msg := <-rw.in:
 		msg.Code -= rw.offset
+		if rw.capture {
+			payload, err := ioutil.ReadAll(msg.Payload)
+			if err != nil {
+				return Msg{}, err
+			}
+			rw.lastCode, rw.lastPayload = msg.Code, payload
+			msg.Payload = bytes.NewReader(payload)
+		}
 		return msg, nil
 	case <-rw.closed:
 		return Msg{}, io.EOF
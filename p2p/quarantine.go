@@ -0,0 +1,136 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/common/hexutil"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/metrics"
+	"github.com/goola-team/goola/p2p/discover"
+)
+
+const (
+	// maxQuarantinedMsgs is the maximum number of messages persisted during the
+	// lifetime of a server, so misbehaving peers can't fill up the disk.
+	maxQuarantinedMsgs = 10000
+
+	// maxQuarantinedPayload is the maximum number of payload bytes persisted of
+	// a single message, the rest is truncated.
+	maxQuarantinedPayload = 1024 * 1024
+)
+
+// QuarantinedMsg is a malformed or protocol-violating message received from a
+// remote peer, as persisted into the quarantine directory.
+type QuarantinedMsg struct {
+	Time       time.Time       `json:"time"`
+	Peer       discover.NodeID `json:"peer"`
+	Name       string          `json:"name"`
+	RemoteAddr string          `json:"remoteAddr"`
+	Protocol   string          `json:"protocol"`
+	Code       uint64          `json:"code"`
+	Size       uint32          `json:"size"`
+	Violation  string          `json:"violation"`
+	Error      string          `json:"error"`
+	Payload    hexutil.Bytes   `json:"payload"`
+	Truncated  bool            `json:"truncated,omitempty"`
+}
+
+// quarantine persists the messages violating the protocols into a directory,
+// building a corpus for protocol fuzzing and attack forensics.
+type quarantine struct {
+	dir string
+
+	lock  sync.Mutex
+	count int
+}
+
+// newQuarantine creates a message quarantine in the given directory.
+func newQuarantine(dir string) (*quarantine, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &quarantine{dir: dir}, nil
+}
+
+// store persists a quarantined message, one file per message.
+func (q *quarantine) store(msg *QuarantinedMsg) {
+	q.lock.Lock()
+	if q.count >= maxQuarantinedMsgs {
+		q.lock.Unlock()
+		return
+	}
+	q.count++
+	q.lock.Unlock()
+
+	if len(msg.Payload) > maxQuarantinedPayload {
+		msg.Payload, msg.Truncated = msg.Payload[:maxQuarantinedPayload], true
+	}
+	blob, err := json.MarshalIndent(msg, "", "  ")
+	if err != nil {
+		log.Warn("Failed to encode quarantined message", "err", err)
+		return
+	}
+	name := fmt.Sprintf("%d-%x-%s-%s.json", msg.Time.UnixNano(), msg.Peer[:8], msg.Protocol, msg.Violation)
+	if err := ioutil.WriteFile(filepath.Join(q.dir, name), blob, 0600); err != nil {
+		log.Warn("Failed to persist quarantined message", "err", err)
+	}
+}
+
+// ReportViolation records that the last message received on the given protocol
+// was malformed or violated the protocol. The violation is counted and, if the
+// server has a quarantine directory configured, the message is persisted with
+// the identity of the peer. It must be called from the protocol handler before
+// it returns the error disconnecting the peer.
+func (p *Peer) ReportViolation(protocol string, violation string, err error) {
+	countViolation(protocol, violation)
+
+	rw := p.running[protocol]
+	if p.quarantine == nil || rw == nil {
+		return
+	}
+	p.log.Debug("Quarantining protocol violation", "protocol", protocol, "violation", violation, "err", err)
+	p.quarantineMsg(protocol, rw.lastCode, rw.lastPayload, violation, err)
+}
+
+// quarantineMsg persists a message of the peer into the quarantine.
+func (p *Peer) quarantineMsg(protocol string, code uint64, payload []byte, violation string, err error) {
+	p.quarantine.store(&QuarantinedMsg{
+		Time:       time.Now(),
+		Peer:       p.ID(),
+		Name:       p.Name(),
+		RemoteAddr: p.RemoteAddr().String(),
+		Protocol:   protocol,
+		Code:       code,
+		Size:       uint32(len(payload)),
+		Violation:  violation,
+		Error:      fmt.Sprint(err),
+		Payload:    payload,
+	})
+}
+
+// countViolation increments the counter of a protocol violation type.
+func countViolation(protocol string, violation string) {
+	metrics.NewCounter("p2p/violations/" + protocol + "/" + violation).Inc(1)
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goola-team/goola/rlp"
+)
+
+// Tests that messages reported as protocol violations are persisted into the
+// quarantine along with the identity of the sending peer.
+func TestPeerQuarantine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quarantine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	q, err := newQuarantine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errBad := errors.New("bad message")
+	proto := Protocol{
+		Name:   "a",
+		Length: 5,
+		Run: func(peer *Peer, rw MsgReadWriter) error {
+			msg, err := rw.ReadMsg()
+			if err != nil {
+				return err
+			}
+			// Consume the payload, the quarantine must still have it
+			if err := msg.Discard(); err != nil {
+				return err
+			}
+			peer.ReportViolation("a", "decode", errBad)
+			return errBad
+		},
+	}
+	fd1, fd2 := net.Pipe()
+	c1 := &conn{fd: fd1, transport: newTestTransport(randomID(), fd1), caps: []Cap{proto.cap()}}
+	c2 := &conn{fd: fd2, transport: newTestTransport(randomID(), fd2), caps: []Cap{proto.cap()}}
+	defer c2.close(errors.New("test done"))
+
+	peer := newPeer(c1, []Protocol{proto})
+	peer.quarantine = q
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := peer.run()
+		errc <- err
+	}()
+	Send(c2, baseProtocolLength+3, []uint{1, 2, 3})
+
+	select {
+	case err := <-errc:
+		if err != errBad {
+			t.Fatalf("peer returned error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("protocol did not fail")
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("quarantined files mismatch: have %v (%v), want 1", files, err)
+	}
+	blob, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg QuarantinedMsg
+	if err := json.Unmarshal(blob, &msg); err != nil {
+		t.Fatalf("failed to decode quarantined message: %v", err)
+	}
+	want, _ := rlp.EncodeToBytes([]uint{1, 2, 3})
+	if msg.Peer != peer.ID() || msg.Protocol != "a" || msg.Code != 3 || msg.Violation != "decode" || msg.Error != errBad.Error() {
+		t.Errorf("quarantined message mismatch: %+v", msg)
+	}
+	if !bytes.Equal(msg.Payload, want) {
+		t.Errorf("quarantined payload mismatch: have %x, want %x", msg.Payload, want)
+	}
+}
@@ -27,6 +27,7 @@ import (
 
 	"github.com/goola-team/goola/common"
 	"github.com/goola-team/goola/common/mclock"
+	"github.com/goola-team/goola/common/timesync"
 	"github.com/goola-team/goola/event"
 	"github.com/goola-team/goola/log"
 	"github.com/goola-team/goola/p2p/discover"
@@ -137,6 +138,23 @@ type Config struct {
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool `toml:",omitempty"`
 
+	// NTPServer is queried to estimate the drift of the local clock, which is
+	// compensated for when validating block timestamps. Empty disables it.
+	NTPServer string `toml:",omitempty"`
+
+	// QuarantineDir is the directory malformed and protocol-violating messages
+	// are persisted into along with the sending peer's identity, as a corpus for
+	// protocol fuzzing and attack forensics. Empty disables it.
+	QuarantineDir string `toml:",omitempty"`
+
+	// PeerHistoryDatabase is the path to the database persisting the timeline
+	// of peer connections and disconnections. Empty keeps it in memory only.
+	PeerHistoryDatabase string `toml:",omitempty"`
+
+	// PeerHistoryRetention is the time peer connections are kept in the timeline
+	// (zero = one week).
+	PeerHistoryRetention time.Duration `toml:",omitempty"`
+
 	// If EnableMsgEvents is set then the server will emit PeerEvents
 	// whenever a message is sent to or received from a peer
 	EnableMsgEvents bool
@@ -175,6 +193,9 @@ type Server struct {
 	addpeer       chan *conn
 	delpeer       chan peerDrop
 	loopWG        sync.WaitGroup // loop, listenLoop
+	clock         *timesync.Monitor
+	quarantine    *quarantine
+	history       *peerHistory
 	peerFeed      event.Feed
 	log           log.Logger
 }
@@ -353,6 +374,14 @@ func (srv *Server) Stop() {
 	}
 	close(srv.quit)
 	srv.loopWG.Wait()
+	if srv.history != nil {
+		srv.history.close()
+		srv.history = nil
+	}
+	if srv.clock != nil {
+		srv.clock.Stop()
+		srv.clock = nil
+	}
 }
 
 // sharedUDPConn implements a shared connection. Write sends messages to the underlying connection while read returns
@@ -415,6 +444,16 @@ func (srv *Server) Start() (err error) {
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
 
+	if srv.QuarantineDir != "" {
+		if srv.quarantine, err = newQuarantine(srv.QuarantineDir); err != nil {
+			return err
+		}
+		srv.log.Info("Quarantining protocol violations", "dir", srv.QuarantineDir)
+	}
+	if srv.history, err = newPeerHistory(srv.PeerHistoryDatabase, srv.PeerHistoryRetention); err != nil {
+		return err
+	}
+
 	var (
 		conn      *net.UDPConn
 		sconn     *sharedUDPConn
@@ -502,6 +541,11 @@ func (srv *Server) Start() (err error) {
 		srv.log.Warn("P2P server will be useless, neither dialing nor listening")
 	}
 
+	// Start estimating the clock drift if requested
+	if srv.NTPServer != "" {
+		srv.clock = timesync.NewMonitor(srv.NTPServer)
+		srv.clock.Start()
+	}
 	srv.loopWG.Add(1)
 	go srv.run(dialer)
 	srv.running = true
@@ -643,6 +687,7 @@ running:
 				if srv.EnableMsgEvents {
 					p.events = &srv.peerFeed
 				}
+				p.quarantine = srv.quarantine
 				name := truncateName(c.name)
 				srv.log.Debug("Adding p2p peer", "name", name, "addr", c.fd.RemoteAddr(), "peers", len(peers)+1)
 				go srv.runPeer(p)
@@ -886,7 +931,8 @@ func (srv *Server) runPeer(p *Peer) {
 		srv.newPeerHook(p)
 	}
 
-	// broadcast peer add
+	// record and broadcast peer add
+	srv.history.connected(p)
 	srv.peerFeed.Send(&PeerEvent{
 		Type: PeerEventTypeAdd,
 		Peer: p.ID(),
@@ -895,7 +941,8 @@ func (srv *Server) runPeer(p *Peer) {
 	// run the protocol
 	remoteRequested, err := p.run()
 
-	// broadcast peer drop
+	// record and broadcast peer drop
+	srv.history.disconnected(p, err, remoteRequested)
 	srv.peerFeed.Send(&PeerEvent{
 		Type:  PeerEventTypeDrop,
 		Peer:  p.ID(),
@@ -919,6 +966,10 @@ type NodeInfo struct {
 	} `json:"ports"`
 	ListenAddr string                 `json:"listenAddr"`
 	Protocols  map[string]interface{} `json:"protocols"`
+	Clock      struct {
+		Drift   string `json:"drift"`   // Estimated drift of the local clock, positive if ahead
+		Warning bool   `json:"warning"` // Whether the drift exceeds the warning threshold
+	} `json:"clock"`
 }
 
 // NodeInfo gathers and returns a collection of metadata known about the host.
@@ -937,6 +988,9 @@ func (srv *Server) NodeInfo() *NodeInfo {
 	info.Ports.Discovery = int(node.UDP)
 	info.Ports.Listener = int(node.TCP)
 
+	clock := timesync.CurrentStatus()
+	info.Clock.Drift, info.Clock.Warning = clock.Drift.String(), clock.Warning
+
 	// Gather all the running protocol infos (only once per protocol type)
 	for _, proto := range srv.Protocols {
 		if _, ok := info.Protocols[proto.Name]; !ok {
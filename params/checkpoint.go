@@ -0,0 +1,79 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import "github.com/goola-team/goola/common"
+
+// CheckpointSectionSize is the number of blocks covered by a checkpoint section.
+const CheckpointSectionSize = 32768
+
+// TrustedCheckpoint represents a set of post-processed trie roots (CHT and
+// BloomTrie) associated with the appropriate section index and head hash. The
+// head hash commits to every header up to it, allowing clients to skip their
+// verification and sync from the checkpoint onwards.
+type TrustedCheckpoint struct {
+	Name         string      // Human readable name of the chain
+	Genesis      common.Hash // Genesis hash of the chain, guarding against networks sharing an id
+	SectionIndex uint64      // Index of the last section covered by the checkpoint
+	SectionHead  common.Hash // Hash of the last header of the section
+	CHTRoot      common.Hash // Root of the canonical hash trie of the section
+	BloomRoot    common.Hash // Root of the bloom trie of the section
+}
+
+// HeadNumber returns the number of the last header covered by the checkpoint.
+func (c *TrustedCheckpoint) HeadNumber() uint64 {
+	return (c.SectionIndex+1)*CheckpointSectionSize - 1
+}
+
+var (
+	// MainnetCheckpoint contains the trusted checkpoint of the main network.
+	MainnetCheckpoint = &TrustedCheckpoint{
+		Name:         "mainnet",
+		Genesis:      MainnetGenesisHash,
+		SectionIndex: 153,
+		SectionHead:  common.HexToHash("04c2114a8cbe49ba5c37a03cc4b4b8d3adfc0bd2c78e0e726405dd84afca1d63"),
+		CHTRoot:      common.HexToHash("d7ec603e5d30b567a6e894ee7704e4603232f206d3e5a589794cec0c57bf318e"),
+		BloomRoot:    common.HexToHash("0b139b8fb692e21f663ff200da287192201c28ef5813c1ac6ba02a0a4799eef9"),
+	}
+
+	// TestnetCheckpoint contains the trusted checkpoint of the test network.
+	TestnetCheckpoint = &TrustedCheckpoint{
+		Name:         "testnet",
+		Genesis:      TestnetGenesisHash,
+		SectionIndex: 79,
+		SectionHead:  common.HexToHash("1b1ba890510e06411fdee9bb64ca7705c56a1a4ce3559ddb34b3680c526cb419"),
+		CHTRoot:      common.HexToHash("71d60207af74e5a22a3e1cfbfc89f9944f91b49aa980c86fba94d568369eaf44"),
+		BloomRoot:    common.HexToHash("70aca4b3b6d08dde8704c95cedb1420394453c1aec390947751e69ff8c436360"),
+	}
+)
+
+// TrustedCheckpoints associates each known checkpoint with the network id of
+// the chain it belongs to.
+var TrustedCheckpoints = map[uint64]*TrustedCheckpoint{
+	1: MainnetCheckpoint,
+	3: TestnetCheckpoint,
+}
+
+// CheckpointOf returns the trusted checkpoint of the chain with the given
+// network id and genesis hash, or nil if none is known. Private networks reusing
+// the id of a public one don't match its genesis, so don't get its checkpoint.
+func CheckpointOf(networkId uint64, genesis common.Hash) *TrustedCheckpoint {
+	if cp, ok := TrustedCheckpoints[networkId]; ok && cp.Genesis == genesis {
+		return cp
+	}
+	return nil
+}
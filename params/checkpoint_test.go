@@ -0,0 +1,46 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"testing"
+
+	"github.com/goola-team/goola/common"
+)
+
+func TestCheckpointOf(t *testing.T) {
+	tests := []struct {
+		networkId uint64
+		genesis   common.Hash
+		want      *TrustedCheckpoint
+	}{
+		{1, MainnetGenesisHash, MainnetCheckpoint},
+		{3, TestnetGenesisHash, TestnetCheckpoint},
+		{1, TestnetGenesisHash, nil},    // private network reusing the mainnet id
+		{1, common.Hash{0x01}, nil},     // private network reusing the mainnet id
+		{1337, MainnetGenesisHash, nil}, // mainnet genesis on an unknown network
+		{1337, common.Hash{0x01}, nil},  // unknown network
+	}
+	for i, tt := range tests {
+		if have := CheckpointOf(tt.networkId, tt.genesis); have != tt.want {
+			t.Errorf("test %d: checkpoint mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	if have, want := MainnetCheckpoint.HeadNumber(), uint64(154*CheckpointSectionSize-1); have != want {
+		t.Errorf("head number mismatch: have %d, want %d", have, want)
+	}
+}
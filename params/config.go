@@ -56,16 +56,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0),new(EthashConfig), nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(0), new(EthashConfig), nil, nil, nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Goola core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, &CliqueConfig{Period: 0, Epoch: 30000}}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(0), nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil, nil}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0),new(EthashConfig), nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), big.NewInt(0), new(EthashConfig), nil, nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -77,14 +77,96 @@ var (
 type ChainConfig struct {
 	ChainId *big.Int `json:"chainId"` // Chain id identifies the current chain and is used for replay protection
 	ByzantiumBlock *big.Int `json:"byzantiumBlock,omitempty"` // Byzantium switch block (nil = no fork, 0 = already on byzantium)
+	BlobBlock      *big.Int `json:"blobBlock,omitempty"`      // Blob transactions switch block (nil = no fork, 0 = already activated)
 
 	// Various consensus engines
 	Ethash *EthashConfig `json:"dpos,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
+
+	// GasFree is the gas-free transaction lane of private networks
+	GasFree *GasFreeConfig `json:"gasFree,omitempty"`
+
+	// DataPolicies tune the transaction data throughput from their activation
+	// blocks on
+	DataPolicies []DataPolicy `json:"dataPolicies,omitempty"`
+}
+
+// EthashConfig is the consensus engine configs for dpos based sealing. If a
+// period and validators are configured, block production is scheduled in slots
+// of the given period, assigned round-robin to the validators. Standbys take
+// over the slots missed by their scheduled validator, each one a standby delay
+// after the previous one. With epochs, the last block of every epoch commits to
+// the validator set of the next one, elected by the votes cast in the delegate
+// registry, and validators are penalised for the slots they miss.
+type EthashConfig struct {
+	Period       uint64           `json:"period,omitempty"`       // Seconds per block production slot
+	Validators   []common.Address `json:"validators,omitempty"`   // Validators producing the slots round-robin
+	Standbys     []common.Address `json:"standbys,omitempty"`     // Standbys taking over missed slots
+	StandbyDelay uint64           `json:"standbyDelay,omitempty"` // Seconds into a slot before the next standby may produce (0 = no standbys)
+	Epoch        uint64           `json:"epoch,omitempty"`        // Blocks per epoch, checkpoints committing to the next validator set (0 = no epochs)
+	Delegates    uint64           `json:"delegates,omitempty"`    // Validators elected by vote at every checkpoint (0 = as many as initially configured)
+	Payouts      []PayoutSplit    `json:"payouts,omitempty"`      // Split of the block reward among several recipients (empty = all to the coinbase)
+	MissPenalty  uint64           `json:"missPenalty,omitempty"`  // Percent of the block reward withheld per slot its producer missed in the epoch (0 = none)
+	MissLimit    uint64           `json:"missLimit,omitempty"`    // Slots missed in an epoch suspending a validator from the next one (0 = never)
+	Rewards      *RewardSchedule  `json:"rewards,omitempty"`      // Block reward schedule replacing the protocol defaults (nil = defaults)
+
+	InTurnWeight  uint64 `json:"inTurnWeight,omitempty"`  // Difficulty of the blocks produced by their slot's validator (0 = 2)
+	StandbyWeight uint64 `json:"standbyWeight,omitempty"` // Difficulty of the blocks produced by standbys or without a schedule (0 = 1)
+}
+
+// PayoutSplit is a single recipient's share of the block reward.
+type PayoutSplit struct {
+	Address common.Address `json:"address"` // Recipient of the share, the zero address denoting the block's coinbase
+	Percent uint64         `json:"percent"` // Percentage of the block reward paid to the recipient
+}
+
+// CheckPayouts verifies that the block reward splits, if any, are all non-zero
+// and sum up to exactly 100 percent.
+func (c *EthashConfig) CheckPayouts() error {
+	if len(c.Payouts) == 0 {
+		return nil
+	}
+	var total uint64
+	for i, split := range c.Payouts {
+		if split.Percent == 0 {
+			return fmt.Errorf("payout %d (%x) has a zero share", i, split.Address)
+		}
+		total += split.Percent
+	}
+	if total != 100 {
+		return fmt.Errorf("payout shares sum to %d%%, want 100%%", total)
+	}
+	return nil
 }
 
-// EthashConfig is the consensus engine configs for proof-of-work based sealing.
-type EthashConfig struct{}
+// Weights returns the difficulties of the blocks produced in turn and by standbys,
+// substituting the defaults for the unset ones.
+func (c *EthashConfig) Weights() (inTurn, standby uint64) {
+	if c != nil {
+		inTurn, standby = c.InTurnWeight, c.StandbyWeight
+	}
+	if inTurn == 0 {
+		inTurn = 2
+	}
+	if standby == 0 {
+		standby = 1
+	}
+	return inTurn, standby
+}
+
+// CheckWeights verifies that blocks produced in turn outweigh those of standbys,
+// so that a chain of missed slots loses against one produced as scheduled.
+func (c *EthashConfig) CheckWeights() error {
+	if inTurn, standby := c.Weights(); inTurn <= standby {
+		return fmt.Errorf("in-turn weight %d doesn't exceed standby weight %d", inTurn, standby)
+	}
+	return nil
+}
+
+// Scheduled returns whether block production follows a validator schedule.
+func (c *EthashConfig) Scheduled() bool {
+	return c.Period > 0 && len(c.Validators) > 0
+}
 
 // String implements the stringer interface, returning the consensus engine details.
 func (c *EthashConfig) String() string {
@@ -102,6 +184,52 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// GasFreeConfig is the allowlist of the gas-free transaction lane, meant for the
+// system maintenance transactions of consortium chains. Transactions sent by or
+// to the listed addresses are accepted regardless of their gas price, the gas
+// they use is still charged at the price they specify (usually zero).
+//
+// Only the transactions of the listed senders are mined ahead of paying ones and
+// kept in a full transaction pool. Calls to the listed targets may be sent by
+// anyone, so they compete on price like any other transaction.
+type GasFreeConfig struct {
+	Senders []common.Address `json:"senders,omitempty"` // Accounts whose transactions are gas-free
+	Targets []common.Address `json:"targets,omitempty"` // Contracts the calls to which are gas-free
+}
+
+// IsGasFreeSender returns whether all the transactions of the given account are
+// in the gas-free lane.
+func (c *ChainConfig) IsGasFreeSender(from common.Address) bool {
+	if c.GasFree == nil {
+		return false
+	}
+	for _, addr := range c.GasFree.Senders {
+		if addr == from {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGasFree returns whether a transaction from the given sender to the given
+// recipient (nil for contract creations) is in the gas-free lane.
+func (c *ChainConfig) IsGasFree(from common.Address, to *common.Address) bool {
+	if c.GasFree == nil {
+		return false
+	}
+	if c.IsGasFreeSender(from) {
+		return true
+	}
+	if to != nil {
+		for _, addr := range c.GasFree.Targets {
+			if addr == *to {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -124,6 +252,12 @@ func (c *ChainConfig) IsByzantium(num *big.Int) bool {
 	return isForked(c.ByzantiumBlock, num)
 }
 
+// IsBlob returns whether num is either equal to the blob transactions fork block
+// or greater.
+func (c *ChainConfig) IsBlob(num *big.Int) bool {
+	return isForked(c.BlobBlock, num)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -154,6 +288,12 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ByzantiumBlock, newcfg.ByzantiumBlock, head) {
 		return newCompatError("Byzantium fork block", c.ByzantiumBlock, newcfg.ByzantiumBlock)
 	}
+	if isForkIncompatible(c.BlobBlock, newcfg.BlobBlock, head) {
+		return newCompatError("Blob fork block", c.BlobBlock, newcfg.BlobBlock)
+	}
+	if block := c.dataPolicyConflict(newcfg, head); block != nil {
+		return newCompatError("data policy", block, block)
+	}
 	return nil
 }
 
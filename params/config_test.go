@@ -17,8 +17,11 @@
 package params
 
 import (
+	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/goola-team/goola/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -39,3 +42,132 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestIsGasFree(t *testing.T) {
+	var (
+		system   = common.HexToAddress("0x01")
+		contract = common.HexToAddress("0x02")
+		user     = common.HexToAddress("0x03")
+	)
+	config := &ChainConfig{GasFree: &GasFreeConfig{
+		Senders: []common.Address{system},
+		Targets: []common.Address{contract},
+	}}
+	tests := []struct {
+		from common.Address
+		to   *common.Address
+		want bool
+	}{
+		{system, &user, true},
+		{system, nil, true},
+		{user, &contract, true},
+		{user, &system, false},
+		{user, nil, false},
+	}
+	for i, tt := range tests {
+		if have := config.IsGasFree(tt.from, tt.to); have != tt.want {
+			t.Errorf("test %d: gas-free mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+	if (&ChainConfig{}).IsGasFree(system, &contract) {
+		t.Errorf("gas-free lane active without allowlist")
+	}
+}
+
+func TestUnsupportedForks(t *testing.T) {
+	config := []byte(`{"chainId": 1, "byzantiumBlock": 0, "petersburgBlock": 200, "constantinopleBlock": 100, "futureBlock": null}`)
+
+	forks, err := UnsupportedForks(config)
+	if err != nil {
+		t.Fatalf("failed to check forks: %v", err)
+	}
+	if len(forks) != 2 {
+		t.Fatalf("unsupported fork count mismatch: have %d, want 2", len(forks))
+	}
+	for i, want := range []struct {
+		name  string
+		block int64
+	}{{"constantinople", 100}, {"petersburg", 200}} {
+		if forks[i].Name != want.name || forks[i].Block.Int64() != want.block {
+			t.Errorf("fork %d: have %s@%v, want %s@%d", i, forks[i].Name, forks[i].Block, want.name, want.block)
+		}
+	}
+}
+
+func TestDataRules(t *testing.T) {
+	config := &ChainConfig{DataPolicies: []DataPolicy{
+		{Block: big.NewInt(200), MaxTxSize: 64 * 1024},
+		{Block: big.NewInt(100), MaxTxSize: 128 * 1024, TxDataNonZeroGas: 16},
+	}}
+	tests := []struct {
+		number int64
+		want   DataRules
+	}{
+		{0, DefaultDataRules},
+		{99, DefaultDataRules},
+		{100, DataRules{Enforced: true, MaxTxSize: 128 * 1024, TxDataZeroGas: TxDataZeroGas, TxDataNonZeroGas: 16}},
+		{200, DataRules{Enforced: true, MaxTxSize: 64 * 1024, TxDataZeroGas: TxDataZeroGas, TxDataNonZeroGas: TxDataNonZeroGas}},
+	}
+	for _, tt := range tests {
+		if have := config.DataRules(big.NewInt(tt.number)); have != tt.want {
+			t.Errorf("block %d: rules mismatch: have %+v, want %+v", tt.number, have, tt.want)
+		}
+	}
+	// Changing a policy already in force must rewind the chain before it
+	changed := &ChainConfig{DataPolicies: []DataPolicy{
+		{Block: big.NewInt(100), MaxTxSize: 128 * 1024, TxDataNonZeroGas: 16},
+		{Block: big.NewInt(250), MaxTxSize: 64 * 1024},
+	}}
+	if err := config.CheckCompatible(changed, 150); err != nil {
+		t.Errorf("unexpected incompatibility before the changed policy: %v", err)
+	}
+	if err := config.CheckCompatible(changed, 300); err == nil || err.RewindTo != 199 {
+		t.Errorf("incompatibility mismatch: have %v, want rewind to 199", err)
+	}
+}
+
+func TestRewardSchedule(t *testing.T) {
+	base := big.NewInt(5000)
+	schedule := &RewardSchedule{
+		Eras: []RewardEra{
+			{Block: big.NewInt(100), Reward: big.NewInt(800)},
+			{Block: big.NewInt(1000), Reward: big.NewInt(0)},
+		},
+		HalvingInterval: 40,
+	}
+	tests := []struct {
+		number int64
+		want   int64
+	}{
+		{0, 5000},
+		{39, 5000},
+		{40, 2500},
+		{99, 1250},
+		{100, 800},
+		{139, 800},
+		{140, 400},
+		{180, 200},
+		{999, 0},
+		{1000, 0},
+	}
+	for _, tt := range tests {
+		if have := schedule.Reward(big.NewInt(tt.number), base); have.Cmp(big.NewInt(tt.want)) != 0 {
+			t.Errorf("block %d: reward mismatch: have %v, want %d", tt.number, have, tt.want)
+		}
+	}
+	if err := schedule.Check(); err != nil {
+		t.Errorf("valid schedule rejected: %v", err)
+	}
+	// Eras must be ordered and treasuries addressable
+	for i, invalid := range []*RewardSchedule{
+		{Eras: []RewardEra{{Block: big.NewInt(10), Reward: big.NewInt(1)}, {Block: big.NewInt(10), Reward: big.NewInt(2)}}},
+		{Eras: []RewardEra{{Block: big.NewInt(10), Reward: big.NewInt(-1)}}},
+		{Eras: []RewardEra{{Block: big.NewInt(10)}}},
+		{Treasury: common.Address{0x01}, TreasuryPercent: 101},
+		{TreasuryPercent: 10},
+	} {
+		if err := invalid.Check(); err == nil {
+			t.Errorf("invalid schedule %d accepted", i)
+		}
+	}
+}
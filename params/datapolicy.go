@@ -0,0 +1,105 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"math/big"
+	"sort"
+)
+
+// DataPolicy overrides the transaction size limit and the calldata pricing of a
+// network from its activation block on, until superseded by a later policy.
+// Zero fields keep the protocol defaults.
+type DataPolicy struct {
+	Block            *big.Int `json:"block"`                      // Activation block of the policy
+	MaxTxSize        uint64   `json:"maxTxSize,omitempty"`        // Maximum encoded size in bytes of a transaction
+	TxDataZeroGas    uint64   `json:"txDataZeroGas,omitempty"`    // Gas per zero byte of transaction data
+	TxDataNonZeroGas uint64   `json:"txDataNonZeroGas,omitempty"` // Gas per non-zero byte of transaction data
+}
+
+// DataRules are the transaction data limits and costs in force at a block.
+type DataRules struct {
+	Enforced         bool   // Whether a data policy is active, making the size limit a consensus rule
+	MaxTxSize        uint64 // Maximum encoded size in bytes of a transaction
+	TxDataZeroGas    uint64 // Gas per zero byte of transaction data
+	TxDataNonZeroGas uint64 // Gas per non-zero byte of transaction data
+}
+
+// DefaultDataRules are the transaction data rules in force without any data
+// policy. The size limit is then only a heuristic of the transaction pool.
+var DefaultDataRules = DataRules{
+	MaxTxSize:        MaxTxSize,
+	TxDataZeroGas:    TxDataZeroGas,
+	TxDataNonZeroGas: TxDataNonZeroGas,
+}
+
+// dataPolicyAt returns the data policy active at the given block, or nil if no
+// policy was activated yet.
+func (c *ChainConfig) dataPolicyAt(num *big.Int) *DataPolicy {
+	var active *DataPolicy
+	for i := range c.DataPolicies {
+		policy := &c.DataPolicies[i]
+		if !isForked(policy.Block, num) {
+			continue
+		}
+		if active == nil || active.Block.Cmp(policy.Block) <= 0 {
+			active = policy
+		}
+	}
+	return active
+}
+
+// DataRules returns the transaction data rules in force at the given block.
+func (c *ChainConfig) DataRules(num *big.Int) DataRules {
+	rules := DefaultDataRules
+
+	policy := c.dataPolicyAt(num)
+	if policy == nil {
+		return rules
+	}
+	rules.Enforced = true
+	if policy.MaxTxSize != 0 {
+		rules.MaxTxSize = policy.MaxTxSize
+	}
+	if policy.TxDataZeroGas != 0 {
+		rules.TxDataZeroGas = policy.TxDataZeroGas
+	}
+	if policy.TxDataNonZeroGas != 0 {
+		rules.TxDataNonZeroGas = policy.TxDataNonZeroGas
+	}
+	return rules
+}
+
+// dataPolicyConflict returns the first block not past head at which the data
+// rules of the two configs differ, or nil if they agree up to head. The rules
+// only change at policy activations, so these are the only blocks checked.
+func (c *ChainConfig) dataPolicyConflict(newcfg *ChainConfig, head *big.Int) *big.Int {
+	var blocks []*big.Int
+	for _, policy := range append(append([]DataPolicy{}, c.DataPolicies...), newcfg.DataPolicies...) {
+		if isForked(policy.Block, head) {
+			blocks = append(blocks, policy.Block)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Cmp(blocks[j]) < 0 })
+
+	for _, block := range blocks {
+		if c.DataRules(block) != newcfg.DataRules(block) {
+			return block
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"encoding/json"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Fork is a protocol upgrade activated at a block number of the chain config.
+type Fork struct {
+	Name  string   // Human readable name of the fork
+	Key   string   // Field of the fork block in the JSON chain config
+	Block *big.Int // Activation block (nil = not scheduled)
+}
+
+// Forks returns the protocol upgrades supported by this version of the node, in
+// activation order.
+func (c *ChainConfig) Forks() []Fork {
+	return []Fork{
+		{Name: "Byzantium", Key: "byzantiumBlock", Block: c.ByzantiumBlock},
+		{Name: "Blob", Key: "blobBlock", Block: c.BlobBlock},
+	}
+}
+
+// UnsupportedForks returns the forks of a JSON encoded chain config that this
+// version of the node does not know, ordered by activation block. Such forks
+// were scheduled by a newer version, and the chain cannot be followed across
+// them without upgrading.
+func UnsupportedForks(config []byte) ([]Fork, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(config, &fields); err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool)
+	for _, fork := range new(ChainConfig).Forks() {
+		known[fork.Key] = true
+	}
+	var unknown []Fork
+	for key, value := range fields {
+		if !strings.HasSuffix(key, "Block") || known[key] || string(value) == "null" {
+			continue
+		}
+		block := new(big.Int)
+		if err := json.Unmarshal(value, block); err != nil {
+			continue // Not a fork block number
+		}
+		unknown = append(unknown, Fork{Name: strings.TrimSuffix(key, "Block"), Key: key, Block: block})
+	}
+	sort.Slice(unknown, func(i, j int) bool {
+		if cmp := unknown[i].Block.Cmp(unknown[j].Block); cmp != 0 {
+			return cmp < 0
+		}
+		return unknown[i].Key < unknown[j].Key
+	})
+	return unknown, nil
+}
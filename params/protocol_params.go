@@ -63,6 +63,15 @@ const (
 
 	MaxCodeSize = 24576 // Maximum bytecode to permit for a contract
 
+	MaxTxSize uint64 = 32 * 1024 // Maximum encoded size in bytes of a transaction, unless overridden by a data policy
+
+	MaxBlobSize         = 128 * 1024 // Maximum size in bytes of a data blob
+	MaxBlobsPerTx       = 4          // Maximum number of blobs carried by a single transaction
+	TargetBlobsPerBlock = 4          // Blobs per block above which the blob fee of the next block rises
+	MaxBlobsPerBlock    = 8          // Maximum number of blobs carried by a single block
+
+	MinBlobFee uint64 = 1000000000000 // Fee per blob when the blob space isn't congested (1 szabo)
+
 	// Precompiled contract gas prices
 
 	EcrecoverGas            uint64 = 3000   // Elliptic curve sender recovery gas price
@@ -0,0 +1,85 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/goola-team/goola/common"
+)
+
+// RewardSchedule defines the monetary policy of a network, replacing the
+// protocol's default block rewards.
+type RewardSchedule struct {
+	Eras            []RewardEra    `json:"eras,omitempty"`            // Block rewards in force from their activation blocks on
+	HalvingInterval uint64         `json:"halvingInterval,omitempty"` // Blocks after which the reward of an era halves (0 = never)
+	Treasury        common.Address `json:"treasury,omitempty"`        // Recipient of the treasury's share of every block reward
+	TreasuryPercent uint64         `json:"treasuryPercent,omitempty"` // Percentage of every block reward paid to the treasury
+}
+
+// RewardEra is a block reward in force from its activation block on, until
+// superseded by a later era.
+type RewardEra struct {
+	Block  *big.Int `json:"block"`  // Activation block of the era
+	Reward *big.Int `json:"reward"` // Block reward in wei at the start of the era
+}
+
+// Reward returns the block reward in force at the given block. Before the first
+// era the given protocol default applies, halving from the genesis block on.
+func (s *RewardSchedule) Reward(num *big.Int, base *big.Int) *big.Int {
+	start, reward := common.Big0, base
+	for _, era := range s.Eras {
+		if !isForked(era.Block, num) {
+			break
+		}
+		start, reward = era.Block, era.Reward
+	}
+	if s.HalvingInterval == 0 {
+		return new(big.Int).Set(reward)
+	}
+	halvings := new(big.Int).Sub(num, start)
+	halvings.Div(halvings, new(big.Int).SetUint64(s.HalvingInterval))
+	if !halvings.IsUint64() || halvings.Uint64() >= uint64(reward.BitLen()) {
+		return new(big.Int)
+	}
+	return new(big.Int).Rsh(reward, uint(halvings.Uint64()))
+}
+
+// Check verifies that the eras are activated in strictly increasing order with
+// non-negative rewards, and that the treasury, if any, is addressable.
+func (s *RewardSchedule) Check() error {
+	for i, era := range s.Eras {
+		if era.Block == nil || era.Reward == nil {
+			return fmt.Errorf("reward era %d is incomplete", i)
+		}
+		if era.Reward.Sign() < 0 {
+			return fmt.Errorf("reward era %d has a negative reward", i)
+		}
+		if i > 0 && era.Block.Cmp(s.Eras[i-1].Block) <= 0 {
+			return fmt.Errorf("reward era %d (block %v) not after era %d (block %v)", i, era.Block, i-1, s.Eras[i-1].Block)
+		}
+	}
+	if s.TreasuryPercent > 100 {
+		return fmt.Errorf("treasury share of %d%% exceeds 100%%", s.TreasuryPercent)
+	}
+	if s.TreasuryPercent > 0 && s.Treasury == (common.Address{}) {
+		return errors.New("treasury share without a treasury address")
+	}
+	return nil
+}
@@ -36,10 +36,22 @@ var Version = func() string {
 	return v
 }()
 
-func VersionWithCommit(gitCommit string) string {
+// Build metadata of the running binary, set by the main package from its linker
+// flags. Empty if unknown.
+var (
+	GitCommit = "" // Git SHA1 commit hash of the release
+	GitDate   = "" // Date of the release commit, formatted as YYYYMMDD
+)
+
+// VersionWithCommit returns the textual version string, suffixed with the short
+// commit hash and the commit date if known.
+func VersionWithCommit(gitCommit, gitDate string) string {
 	vsn := Version
 	if len(gitCommit) >= 8 {
 		vsn += "-" + gitCommit[:8]
 	}
+	if gitDate != "" {
+		vsn += "-" + gitDate
+	}
 	return vsn
 }
@@ -0,0 +1,78 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package payments
+
+import (
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/common/hexutil"
+)
+
+// defaultRecentLimit is the number of payments returned if no limit is given.
+const defaultRecentLimit = 100
+
+// PrivatePaymentsAPI provides an API to query the payments received by the
+// local accounts.
+type PrivatePaymentsAPI struct {
+	s *Service
+}
+
+// NewPrivatePaymentsAPI creates a new payments API.
+func NewPrivatePaymentsAPI(s *Service) *PrivatePaymentsAPI {
+	return &PrivatePaymentsAPI{s}
+}
+
+// RPCPayment is the RPC representation of a payment to a local account.
+type RPCPayment struct {
+	Token       *common.Address `json:"token"`
+	From        common.Address  `json:"from"`
+	To          common.Address  `json:"to"`
+	Value       *hexutil.Big    `json:"value"`
+	TxHash      common.Hash     `json:"transactionHash"`
+	BlockHash   common.Hash     `json:"blockHash"`
+	BlockNumber hexutil.Uint64  `json:"blockNumber"`
+	Timestamp   hexutil.Uint64  `json:"timestamp"`
+}
+
+// newRPCPayment converts a payment into its RPC representation.
+func newRPCPayment(p *Payment) *RPCPayment {
+	return &RPCPayment{
+		Token:       p.Token,
+		From:        p.From,
+		To:          p.To,
+		Value:       (*hexutil.Big)(p.Value),
+		TxHash:      p.TxHash,
+		BlockHash:   p.BlockHash,
+		BlockNumber: hexutil.Uint64(p.BlockNumber),
+		Timestamp:   hexutil.Uint64(p.Time),
+	}
+}
+
+// RecentIncomingPayments returns the most recent value and token transfers to
+// the given local account, or to any of them if omitted, newest first.
+func (api *PrivatePaymentsAPI) RecentIncomingPayments(account *common.Address, limit *hexutil.Uint) []*RPCPayment {
+	n := defaultRecentLimit
+	if limit != nil {
+		n = int(*limit)
+	}
+	payments := api.s.Recent(account, n)
+
+	results := make([]*RPCPayment, len(payments))
+	for i, p := range payments {
+		results[i] = newRPCPayment(p)
+	}
+	return results
+}
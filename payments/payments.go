@@ -0,0 +1,343 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package payments implements a service watching the chain for value and token
+// transfers to the local keystore accounts, notifying about them over a feed, a
+// webhook and RPC.
+package payments
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/crypto"
+	"github.com/goola-team/goola/event"
+	"github.com/goola-team/goola/goolabackend/alerts"
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/p2p"
+	"github.com/goola-team/goola/params"
+	"github.com/goola-team/goola/rpc"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// reorgHistory is the number of processed block hashes kept to detect reorgs.
+	reorgHistory = 128
+)
+
+// Notification types posted to the webhook.
+const (
+	IncomingPayment = "incomingPayment" // A payment to a local account was included in a block
+	RevertedPayment = "revertedPayment" // A previously notified payment was reorged out
+)
+
+// transferTopic is the topic of the ERC20 Transfer(address,address,uint256) event.
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// Config are the configuration parameters of the payment watcher.
+type Config struct {
+	Enabled bool   `toml:",omitempty"` // Whether to watch for incoming payments
+	URL     string `toml:",omitempty"` // Webhook endpoint the payments are POSTed to (empty = none)
+	Secret  string `toml:",omitempty"` // Key of the HMAC-SHA256 signature of the payloads
+	Retries int    // Number of times a failed webhook delivery is retried
+	History int    // Number of recent payments retained for RPC queries
+}
+
+// DefaultConfig contains the default payment watcher settings.
+var DefaultConfig = Config{
+	Retries: 3,
+	History: 1024,
+}
+
+// Backend is the chain access needed to watch for payments.
+type Backend interface {
+	Config() *params.ChainConfig
+	CurrentBlock() *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Payment is a value or token transfer to a local account.
+type Payment struct {
+	Token       *common.Address // Token contract, nil for a plain value transfer
+	From        common.Address
+	To          common.Address
+	Value       *big.Int
+	TxHash      common.Hash
+	BlockHash   common.Hash
+	BlockNumber uint64
+	Time        uint64
+}
+
+// PaymentEvent is posted when a payment to a local account is included in the
+// canonical chain, or when it gets reorged out again.
+type PaymentEvent struct {
+	Payment  *Payment
+	Reverted bool
+}
+
+// Service watches the blocks added to the canonical chain for transfers to the
+// local accounts.
+type Service struct {
+	config   *Config
+	backend  Backend
+	accounts func() []common.Address // Local accounts to watch for payments to
+
+	hook *alerts.Webhook // Webhook notifier, nil if not configured
+	feed event.Feed
+	subs event.SubscriptionScope
+
+	history []*Payment             // Most recent payments, oldest first
+	next    uint64                 // Number of the next block to process
+	hashes  map[uint64]common.Hash // Hashes of the recently processed blocks
+	lock    sync.RWMutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a payment watcher for the accounts returned by the given function.
+func New(config *Config, backend Backend, accounts func() []common.Address) *Service {
+	s := &Service{
+		config:   config,
+		backend:  backend,
+		accounts: accounts,
+		hashes:   make(map[uint64]common.Hash),
+		quit:     make(chan struct{}),
+	}
+	if config.URL != "" {
+		s.hook = alerts.NewWebhook(config.URL, config.Secret, config.Retries, time.Second)
+	}
+	return s
+}
+
+// Protocols implements node.Service, returning the P2P network protocols used
+// by the payment watcher (nil as it doesn't use the devp2p overlay network).
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the RPC API endpoints provided by the
+// payment watcher.
+func (s *Service) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "goola",
+			Version:   "1.0",
+			Service:   NewPrivatePaymentsAPI(s),
+		},
+	}
+}
+
+// Start implements node.Service, starting to watch the blocks following the
+// current head.
+func (s *Service) Start(server *p2p.Server) error {
+	if head := s.backend.CurrentBlock(); head != nil {
+		s.next = head.NumberU64() + 1
+		s.hashes[head.NumberU64()] = head.Hash()
+	}
+	if s.hook != nil {
+		s.hook.Start()
+	}
+	s.wg.Add(1)
+	go s.loop()
+
+	log.Info("Payment watcher started", "webhook", s.config.URL)
+	return nil
+}
+
+// Stop implements node.Service, terminating the payment watcher.
+func (s *Service) Stop() error {
+	close(s.quit)
+	s.wg.Wait()
+	s.subs.Close()
+	if s.hook != nil {
+		s.hook.Stop()
+	}
+	log.Info("Payment watcher stopped")
+	return nil
+}
+
+// SubscribePaymentEvent registers a subscription of PaymentEvent.
+func (s *Service) SubscribePaymentEvent(ch chan<- PaymentEvent) event.Subscription {
+	return s.subs.Track(s.feed.Subscribe(ch))
+}
+
+// Recent returns the most recent payments to the given account, or to any local
+// account if nil, newest first.
+func (s *Service) Recent(account *common.Address, limit int) []*Payment {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var payments []*Payment
+	for i := len(s.history) - 1; i >= 0 && len(payments) < limit; i-- {
+		if account == nil || s.history[i].To == *account {
+			payments = append(payments, s.history[i])
+		}
+	}
+	return payments
+}
+
+// loop processes the new chain heads until stopped.
+func (s *Service) loop() {
+	defer s.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	sub := s.backend.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-heads:
+			s.sync(ev.Block)
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// sync processes all blocks up to the given head, reverting the payments of
+// processed blocks which were reorged out.
+func (s *Service) sync(head *types.Block) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Rewind to the last processed block still canonical
+	for s.next > 0 {
+		hash, ok := s.hashes[s.next-1]
+		if !ok {
+			break
+		}
+		if block := s.backend.GetBlockByNumber(s.next - 1); block != nil && block.Hash() == hash {
+			break
+		}
+		delete(s.hashes, s.next-1)
+		s.next--
+		s.revert(s.next)
+	}
+	watched := make(map[common.Address]bool)
+	for _, addr := range s.accounts() {
+		watched[addr] = true
+	}
+	for ; s.next <= head.NumberU64(); s.next++ {
+		block := s.backend.GetBlockByNumber(s.next)
+		if block == nil {
+			return
+		}
+		for _, payment := range s.scan(block, watched) {
+			s.record(payment)
+		}
+		s.hashes[s.next] = block.Hash()
+		delete(s.hashes, s.next-reorgHistory)
+	}
+}
+
+// scan collects the payments to the watched accounts within a block.
+func (s *Service) scan(block *types.Block, watched map[common.Address]bool) []*Payment {
+	if len(watched) == 0 {
+		return nil
+	}
+	var (
+		payments []*Payment
+		signer   = types.MakeSigner(s.backend.Config(), block.Number())
+		receipts = s.backend.GetReceiptsByHash(block.Hash())
+	)
+	for i, tx := range block.Transactions() {
+		if i >= len(receipts) {
+			break
+		}
+		// Skip failed transactions (pre-Byzantium receipts carry a state root instead)
+		receipt := receipts[i]
+		if len(receipt.PostState) == 0 && receipt.Status == types.ReceiptStatusFailed {
+			continue
+		}
+		payment := func(token *common.Address, from, to common.Address, value *big.Int) *Payment {
+			return &Payment{
+				Token:       token,
+				From:        from,
+				To:          to,
+				Value:       value,
+				TxHash:      tx.Hash(),
+				BlockHash:   block.Hash(),
+				BlockNumber: block.NumberU64(),
+				Time:        block.Time().Uint64(),
+			}
+		}
+		if to := tx.To(); to != nil && watched[*to] && tx.Value().Sign() > 0 {
+			from, err := types.Sender(signer, tx)
+			if err != nil {
+				log.Debug("Failed to derive payment sender", "hash", tx.Hash(), "err", err)
+			}
+			payments = append(payments, payment(nil, from, *to, tx.Value()))
+		}
+		for _, l := range receipt.Logs {
+			if len(l.Topics) != 3 || l.Topics[0] != transferTopic || len(l.Data) != 32 {
+				continue
+			}
+			if to := common.BytesToAddress(l.Topics[2].Bytes()); watched[to] {
+				token := l.Address
+				payments = append(payments, payment(&token, common.BytesToAddress(l.Topics[1].Bytes()), to, new(big.Int).SetBytes(l.Data)))
+			}
+		}
+	}
+	return payments
+}
+
+// record retains a new payment and notifies about it.
+func (s *Service) record(payment *Payment) {
+	s.history = append(s.history, payment)
+	if len(s.history) > s.config.History {
+		s.history = s.history[len(s.history)-s.config.History:]
+	}
+	log.Info("Incoming payment", "to", payment.To, "value", payment.Value, "token", payment.Token, "tx", payment.TxHash)
+	s.notify(payment, false)
+}
+
+// revert drops the payments of a block number reorged out and notifies about
+// them.
+func (s *Service) revert(number uint64) {
+	kept := s.history[:0]
+	for _, payment := range s.history {
+		if payment.BlockNumber != number {
+			kept = append(kept, payment)
+			continue
+		}
+		log.Warn("Incoming payment reverted", "to", payment.To, "value", payment.Value, "token", payment.Token, "tx", payment.TxHash)
+		s.notify(payment, true)
+	}
+	s.history = kept
+}
+
+// notify posts a payment event to the subscribers and the webhook.
+func (s *Service) notify(payment *Payment, reverted bool) {
+	s.feed.Send(PaymentEvent{Payment: payment, Reverted: reverted})
+	if s.hook == nil {
+		return
+	}
+	typ, message := IncomingPayment, fmt.Sprintf("Incoming payment to %x in block #%d", payment.To, payment.BlockNumber)
+	if reverted {
+		typ, message = RevertedPayment, fmt.Sprintf("Payment to %x in block #%d reverted", payment.To, payment.BlockNumber)
+	}
+	s.hook.Send(&alerts.Alert{Type: typ, Time: time.Now().Unix(), Message: message, Details: newRPCPayment(payment)})
+}
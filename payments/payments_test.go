@@ -0,0 +1,137 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package payments
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/goola-team/goola/common"
+	"github.com/goola-team/goola/core"
+	"github.com/goola-team/goola/core/types"
+	"github.com/goola-team/goola/event"
+	"github.com/goola-team/goola/params"
+)
+
+// testBackend is a canned chain of blocks and receipts.
+type testBackend struct {
+	blocks   []*types.Block
+	receipts map[common.Hash]types.Receipts
+	feed     event.Feed
+}
+
+func (b *testBackend) Config() *params.ChainConfig { return params.TestChainConfig }
+
+func (b *testBackend) CurrentBlock() *types.Block { return b.blocks[len(b.blocks)-1] }
+
+func (b *testBackend) GetBlockByNumber(number uint64) *types.Block {
+	if number < uint64(len(b.blocks)) {
+		return b.blocks[number]
+	}
+	return nil
+}
+
+func (b *testBackend) GetReceiptsByHash(hash common.Hash) types.Receipts { return b.receipts[hash] }
+
+func (b *testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return b.feed.Subscribe(ch)
+}
+
+// addBlock appends a block with the given successful transactions, each emitting
+// the logs at the same position. The salt differentiates sibling blocks.
+func (b *testBackend) addBlock(txs []*types.Transaction, logs [][]*types.Log, salt byte) {
+	header := &types.Header{Number: big.NewInt(int64(len(b.blocks))), Extra: []byte{byte(len(b.blocks)), salt}}
+	if len(b.blocks) > 0 {
+		header.ParentHash = b.blocks[len(b.blocks)-1].Hash()
+	}
+	block := types.NewBlock(header, txs, nil)
+	receipts := make(types.Receipts, len(txs))
+	for i := range txs {
+		receipts[i] = &types.Receipt{Status: types.ReceiptStatusSuccessful}
+		if i < len(logs) {
+			receipts[i].Logs = logs[i]
+		}
+	}
+	b.blocks = append(b.blocks, block)
+	b.receipts[block.Hash()] = receipts
+}
+
+func addrTopic(addr common.Address) common.Hash {
+	return common.BytesToHash(addr.Bytes())
+}
+
+// Tests that value and token transfers to the local accounts are recorded, and
+// that they are reverted if their block gets reorged out.
+func TestPaymentTracking(t *testing.T) {
+	var (
+		alice = common.Address{0x01}
+		bob   = common.Address{0x02}
+		token = common.Address{0xaa}
+	)
+	backend := &testBackend{receipts: make(map[common.Hash]types.Receipts)}
+	backend.addBlock(nil, nil, 0)
+
+	service := New(&Config{History: 16}, backend, func() []common.Address { return []common.Address{alice} })
+	service.next = 1
+
+	events := make(chan PaymentEvent, 16)
+	sub := service.SubscribePaymentEvent(events)
+	defer sub.Unsubscribe()
+
+	// Pay alice with a value transfer and a token transfer, and bob with a value transfer
+	value := types.NewTransaction(0, alice, big.NewInt(1000), 21000, new(big.Int), types.TxTypeTransfer, nil)
+	other := types.NewTransaction(1, bob, big.NewInt(2000), 21000, new(big.Int), types.TxTypeTransfer, nil)
+	call := types.NewTransaction(2, token, new(big.Int), 100000, new(big.Int), types.TxTypeTransfer, nil)
+	backend.addBlock([]*types.Transaction{value, other, call}, [][]*types.Log{nil, nil, {
+		{Address: token, Topics: []common.Hash{transferTopic, addrTopic(bob), addrTopic(alice)}, Data: common.LeftPadBytes([]byte{0x05}, 32)},
+		{Address: token, Topics: []common.Hash{transferTopic, addrTopic(alice), addrTopic(bob)}, Data: common.LeftPadBytes([]byte{0x06}, 32)},
+	}}, 0)
+	service.sync(backend.CurrentBlock())
+
+	recent := service.Recent(nil, 10)
+	if len(recent) != 2 {
+		t.Fatalf("payment count mismatch: have %d, want 2", len(recent))
+	}
+	if p := recent[1]; p.Token != nil || p.To != alice || p.Value.Int64() != 1000 || p.TxHash != value.Hash() {
+		t.Errorf("value transfer mismatch: %+v", p)
+	}
+	if p := recent[0]; p.Token == nil || *p.Token != token || p.From != bob || p.To != alice || p.Value.Int64() != 5 {
+		t.Errorf("token transfer mismatch: %+v", p)
+	}
+	if recent := service.Recent(&bob, 10); len(recent) != 0 {
+		t.Errorf("payments to unwatched account recorded: %+v", recent)
+	}
+	for i := 0; i < 2; i++ {
+		if ev := <-events; ev.Reverted {
+			t.Errorf("event %d: payment reported reverted", i)
+		}
+	}
+	// Replace the paying block with an empty sibling and ensure the payments are reverted
+	backend.blocks = backend.blocks[:1]
+	backend.addBlock(nil, nil, 1)
+	backend.addBlock(nil, nil, 1)
+	service.sync(backend.CurrentBlock())
+
+	if recent := service.Recent(nil, 10); len(recent) != 0 {
+		t.Errorf("reorged payments retained: %+v", recent)
+	}
+	for i := 0; i < 2; i++ {
+		if ev := <-events; !ev.Reverted {
+			t.Errorf("event %d: payment not reported reverted", i)
+		}
+	}
+}
@@ -565,3 +565,74 @@ func (l *flakeyListener) Accept() (net.Conn, error) {
 	}
 	return c, err
 }
+
+// TypedErrorService fails its calls with a typed error.
+type TypedErrorService struct{}
+
+type typedError struct{}
+
+func (e *typedError) Error() string          { return "typed failure" }
+func (e *typedError) ErrorCode() int         { return ErrCodeNotFound }
+func (e *typedError) ErrorData() interface{} { return map[string]string{"kind": "block"} }
+
+func (s *TypedErrorService) Fail() error { return new(typedError) }
+
+// Tests that the code and data of typed errors returned by methods reach the
+// client.
+func TestClientTypedError(t *testing.T) {
+	server := newTestServer("service", new(TypedErrorService))
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	err := client.Call(nil, "service_fail")
+	if err == nil || err.Error() != "typed failure" {
+		t.Fatalf("error mismatch: have %v, want typed failure", err)
+	}
+	if code := err.(Error).ErrorCode(); code != ErrCodeNotFound {
+		t.Errorf("error code mismatch: have %d, want %d", code, ErrCodeNotFound)
+	}
+	data, _ := err.(DataError).ErrorData().(map[string]interface{})
+	if data["kind"] != "block" {
+		t.Errorf("error data mismatch: have %v", err.(DataError).ErrorData())
+	}
+}
+
+// RequestIDService returns the correlation ID of the serving request.
+type RequestIDService struct{}
+
+func (s *RequestIDService) Get(ctx context.Context) string { return RequestID(ctx) }
+
+// Tests that every served request, batched or not, carries its own correlation
+// ID in its context.
+func TestClientRequestID(t *testing.T) {
+	server := newTestServer("service", new(RequestIDService))
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var first, second string
+	if err := client.Call(&first, "service_get"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(&second, "service_get"); err != nil {
+		t.Fatal(err)
+	}
+	batch := []BatchElem{
+		{Method: "service_get", Result: new(string)},
+		{Method: "service_get", Result: new(string)},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, id := range []string{first, second, *batch[0].Result.(*string), *batch[1].Result.(*string)} {
+		if id == "" {
+			t.Fatal("request served without correlation ID")
+		}
+		if seen[id] {
+			t.Fatalf("correlation ID %s reused", id)
+		}
+		seen[id] = true
+	}
+}
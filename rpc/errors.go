@@ -18,6 +18,18 @@ package rpc
 
 import "fmt"
 
+// Error codes of the server errors, in the range the JSON-RPC specification
+// reserves for implementation defined ones. Clients should branch on these and
+// the error data rather than on error messages.
+const (
+	ErrCodeDefault     = -32000 // Unclassified failure of a method call
+	ErrCodeForbidden   = -32001 // Namespace not accessible to the caller
+	ErrCodePruned      = -32002 // Requested historical data was pruned locally
+	ErrCodeNotFound    = -32003 // Requested block, transaction or receipt is unknown
+	ErrCodeTxRejected  = -32004 // Transaction refused by the transaction pool
+	ErrCodeUnavailable = -32005 // Functionality unavailable with the node's configuration
+)
+
 // request is for an unknown service
 type methodNotFoundError struct {
 	service string
@@ -54,13 +66,23 @@ func (e *invalidParamsError) Error() string { return e.message }
 // logic error, callback returned an error
 type callbackError struct{ message string }
 
-func (e *callbackError) ErrorCode() int { return -32000 }
+func (e *callbackError) ErrorCode() int { return ErrCodeDefault }
 
 func (e *callbackError) Error() string { return e.message }
 
 // issued when a request is received after the server is issued to stop.
 type shutdownError struct{}
 
-func (e *shutdownError) ErrorCode() int { return -32000 }
+func (e *shutdownError) ErrorCode() int { return ErrCodeDefault }
 
 func (e *shutdownError) Error() string { return "server is shutting down" }
+
+// request into a namespace not accessible from the caller's origin, host or
+// client certificate
+type namespaceForbiddenError struct{ namespace string }
+
+func (e *namespaceForbiddenError) ErrorCode() int { return ErrCodeForbidden }
+
+func (e *namespaceForbiddenError) Error() string {
+	return fmt.Sprintf("namespace %s not allowed for this connection", e.namespace)
+}
@@ -156,6 +156,31 @@ func NewHTTPServer(cors []string, vhosts []string, srv *Server) *http.Server {
 	return &http.Server{Handler: handler}
 }
 
+// NewHTTPServerWithPolicies creates a new HTTP RPC server around an API provider,
+// restricting the origins and virtual hosts allowed to access individual API
+// namespaces beyond the endpoint wide CORS and virtual host settings.
+func NewHTTPServerWithPolicies(cors []string, vhosts []string, policies map[string]NamespacePolicy, srv *Server) *http.Server {
+	if len(policies) == 0 {
+		return NewHTTPServer(cors, vhosts, srv)
+	}
+	// Namespaces without a CORS setting inherit the endpoint's, where an empty
+	// list disables CORS, but doesn't reject the request itself.
+	origins := newAllowList(cors)
+	if len(cors) == 0 {
+		origins.all = true
+	}
+	enforcer := newPolicyEnforcer(origins, newAllowList(vhosts), policies)
+
+	// Let anything allowed by any namespace through the endpoint wide handlers,
+	// the enforcer will reject individual requests based on their namespaces.
+	var handler http.Handler = &policyHandler{next: srv, enforcer: enforcer}
+	if len(cors) > 0 {
+		handler = newCorsHandler(handler, mergeLists(cors, policies, func(p NamespacePolicy) []string { return p.Cors }))
+	}
+	handler = newVHostHandler(mergeLists(vhosts, policies, func(p NamespacePolicy) []string { return p.VirtualHosts }), handler)
+	return &http.Server{Handler: handler}
+}
+
 // ServeHTTP serves JSON-RPC requests over HTTP.
 func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Permit dumb empty requests for remote health-checks (AWS)
@@ -169,7 +194,7 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// All checks passed, create a codec that reads direct from the request body
 	// untilEOF and writes the response to w and order the server to process a
 	// single request.
-	codec := NewJSONCodec(&httpReadWriteNopCloser{r.Body, w})
+	codec := withCaller(filterCodec(r, NewJSONCodec(&httpReadWriteNopCloser{r.Body, w})), r.RemoteAddr)
 	defer codec.Close()
 
 	w.Header().Set("content-type", contentType)
@@ -194,7 +219,7 @@ func validateRequest(r *http.Request) (int, error) {
 	return 0, nil
 }
 
-func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
+func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
 		return srv
@@ -17,6 +17,9 @@
 package rpc
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -52,3 +55,78 @@ func testHTTPErrorResponse(t *testing.T, method, contentType, body string, expec
 		t.Fatalf("response code should be %d not %d", expected, code)
 	}
 }
+
+func TestHTTPNamespacePolicies(t *testing.T) {
+	server := NewServer()
+	defer server.Stop()
+	server.RegisterName("open", new(Service))
+	server.RegisterName("closed", new(Service))
+
+	policies := map[string]NamespacePolicy{
+		"closed": {Cors: []string{"http://localhost"}, VirtualHosts: []string{"localhost"}},
+	}
+	handler := NewHTTPServerWithPolicies([]string{"*"}, []string{"*"}, policies, server).Handler
+
+	tests := []struct {
+		method, origin, host string
+		allowed              bool
+	}{
+		{"open_rets", "http://evil.com", "evil.com", true},
+		{"closed_rets", "", "127.0.0.1:8545", true},
+		{"closed_rets", "http://localhost", "localhost:8545", true},
+		{"closed_rets", "http://evil.com", "localhost:8545", false},
+		{"closed_rets", "http://localhost", "evil.com", false},
+	}
+	for i, tt := range tests {
+		body := `{"jsonrpc":"2.0","id":1,"method":"` + tt.method + `","params":[]}`
+		request := httptest.NewRequest(http.MethodPost, "http://"+tt.host, strings.NewReader(body))
+		request.Header.Set("content-type", contentType)
+		if tt.origin != "" {
+			request.Header.Set("Origin", tt.origin)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		forbidden := strings.Contains(recorder.Body.String(), "not allowed")
+		if forbidden == tt.allowed {
+			t.Errorf("test %d: %s from %q to %q allowed mismatch: have %v, want %v (response %s)", i, tt.method, tt.origin, tt.host, !forbidden, tt.allowed, recorder.Body.String())
+		}
+	}
+}
+
+func TestHTTPClientCertTiers(t *testing.T) {
+	server := NewServer()
+	defer server.Stop()
+	server.RegisterName("open", new(Service))
+	server.RegisterName("admin", new(Service))
+
+	handler := NewClientCertHandler(NewHTTPServer([]string{"*"}, []string{"*"}, server).Handler, []string{"open"}, map[string][]string{
+		"operators": {"admin"},
+	})
+	tests := []struct {
+		method  string
+		tiers   []string // nil for no client certificate
+		allowed bool
+	}{
+		{"open_rets", nil, true},
+		{"admin_rets", nil, false},
+		{"admin_rets", []string{"readers"}, false},
+		{"admin_rets", []string{"readers", "operators"}, true},
+	}
+	for i, tt := range tests {
+		body := `{"jsonrpc":"2.0","id":1,"method":"` + tt.method + `","params":[]}`
+		request := httptest.NewRequest(http.MethodPost, "https://localhost", strings.NewReader(body))
+		request.Header.Set("content-type", contentType)
+		if tt.tiers != nil {
+			cert := &x509.Certificate{Subject: pkix.Name{OrganizationalUnit: tt.tiers}}
+			request.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		forbidden := strings.Contains(recorder.Body.String(), "not allowed")
+		if forbidden == tt.allowed {
+			t.Errorf("test %d: %s with tiers %v allowed mismatch: have %v, want %v (response %s)", i, tt.method, tt.tiers, !forbidden, tt.allowed, recorder.Body.String())
+		}
+	}
+}
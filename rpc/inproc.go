@@ -26,7 +26,7 @@ func DialInProc(handler *Server) *Client {
 	initctx := context.Background()
 	c, _ := newClient(initctx, func(context.Context) (net.Conn, error) {
 		p1, p2 := net.Pipe()
-		go handler.ServeCodec(NewJSONCodec(p1), OptionMethodInvocation|OptionSubscriptions)
+		go handler.ServeCodec(withCaller(NewJSONCodec(p1), "inproc"), OptionMethodInvocation|OptionSubscriptions)
 		return p2, nil
 	})
 	return c
@@ -0,0 +1,193 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// NamespacePolicy restricts the browser origins and virtual hosts allowed to
+// access the methods of a single API namespace. Empty lists inherit the policy
+// of the endpoint serving the namespace.
+type NamespacePolicy struct {
+	Cors         []string `toml:",omitempty"` // Origins allowed to call the namespace
+	VirtualHosts []string `toml:",omitempty"` // Host headers allowed to call the namespace
+}
+
+// allowList is a case insensitive set of origins or hosts, optionally matching
+// anything.
+type allowList struct {
+	all     bool
+	entries map[string]struct{}
+}
+
+// newAllowList creates an allow list from the given entries, where "*" is a
+// wildcard matching anything.
+func newAllowList(entries []string) *allowList {
+	list := &allowList{entries: make(map[string]struct{})}
+	for _, entry := range entries {
+		if entry == "*" {
+			list.all = true
+		}
+		list.entries[strings.ToLower(entry)] = struct{}{}
+	}
+	return list
+}
+
+// has checks whether the given origin or host is permitted by the list.
+func (l *allowList) has(entry string) bool {
+	if l.all {
+		return true
+	}
+	_, ok := l.entries[strings.ToLower(entry)]
+	return ok
+}
+
+// policyEnforcer checks the origin and virtual host of requests against the
+// policies of the namespaces they call.
+type policyEnforcer struct {
+	origins map[string]*allowList // Allowed origins per namespace
+	hosts   map[string]*allowList // Allowed virtual hosts per namespace
+
+	defaultOrigins *allowList // Origins allowed for namespaces without a policy
+	defaultHosts   *allowList // Virtual hosts allowed for namespaces without a policy
+}
+
+// newPolicyEnforcer creates an enforcer for the given namespace policies,
+// falling back to the endpoint's origins and virtual hosts.
+func newPolicyEnforcer(origins, hosts *allowList, policies map[string]NamespacePolicy) *policyEnforcer {
+	enforcer := &policyEnforcer{
+		origins:        make(map[string]*allowList),
+		hosts:          make(map[string]*allowList),
+		defaultOrigins: origins,
+		defaultHosts:   hosts,
+	}
+	for namespace, policy := range policies {
+		if len(policy.Cors) > 0 {
+			enforcer.origins[namespace] = newAllowList(policy.Cors)
+		}
+		if len(policy.VirtualHosts) > 0 {
+			enforcer.hosts[namespace] = newAllowList(policy.VirtualHosts)
+		}
+	}
+	return enforcer
+}
+
+// allowed checks whether a request from the given origin and to the given host
+// may call into a namespace. Requests without an origin are not issued by
+// browsers and requests addressing an IP directly cannot be rebound, so neither
+// of those are restricted.
+func (e *policyEnforcer) allowed(namespace, origin, host string) bool {
+	if origin != "" {
+		list, ok := e.origins[namespace]
+		if !ok {
+			list = e.defaultOrigins
+		}
+		if !list.has(origin) {
+			return false
+		}
+	}
+	if host != "" {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if net.ParseIP(host) == nil {
+			list, ok := e.hosts[namespace]
+			if !ok {
+				list = e.defaultHosts
+			}
+			if !list.has(host) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// policyHandler is an HTTP middleware attaching the namespace policies to each
+// request, which are then enforced by the codec serving the request.
+type policyHandler struct {
+	next     http.Handler
+	enforcer *policyEnforcer
+}
+
+// ServeHTTP implements http.Handler, restricting the namespaces accessible by
+// the request based on its origin and host.
+func (h *policyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin, host := r.Header.Get("Origin"), r.Host
+	h.next.ServeHTTP(w, withNamespaceFilter(r, func(namespace string) bool {
+		return h.enforcer.allowed(namespace, origin, host)
+	}))
+}
+
+// namespaceFilterKey is the context key of the namespace filter of a request.
+type namespaceFilterKey struct{}
+
+// withNamespaceFilter returns a copy of the HTTP request, restricting the API
+// namespaces it may access to those allowed by all attached filters.
+func withNamespaceFilter(r *http.Request, allow func(namespace string) bool) *http.Request {
+	if prev, ok := r.Context().Value(namespaceFilterKey{}).(func(string) bool); ok {
+		outer := allow
+		allow = func(namespace string) bool { return prev(namespace) && outer(namespace) }
+	}
+	return r.WithContext(context.WithValue(r.Context(), namespaceFilterKey{}, allow))
+}
+
+// filterCodec wraps a server codec serving the given HTTP request, rejecting
+// all calls into namespaces the request's filters don't allow.
+func filterCodec(r *http.Request, codec ServerCodec) ServerCodec {
+	allow, ok := r.Context().Value(namespaceFilterKey{}).(func(string) bool)
+	if !ok {
+		return codec
+	}
+	return &namespaceFilterCodec{ServerCodec: codec, allow: allow}
+}
+
+// namespaceFilterCodec is a server codec which marks requests into namespaces
+// not allowed for the connection as invalid.
+type namespaceFilterCodec struct {
+	ServerCodec
+	allow func(namespace string) bool
+}
+
+// ReadRequestHeaders implements ServerCodec, reading the next batch of requests
+// and rejecting those calling into forbidden namespaces.
+func (c *namespaceFilterCodec) ReadRequestHeaders() ([]rpcRequest, bool, Error) {
+	reqs, batch, err := c.ServerCodec.ReadRequestHeaders()
+	if err != nil {
+		return reqs, batch, err
+	}
+	for i := range reqs {
+		if reqs[i].err == nil && !c.allow(reqs[i].service) {
+			reqs[i].err = &namespaceForbiddenError{reqs[i].service}
+		}
+	}
+	return reqs, batch, nil
+}
+
+// mergeLists returns the union of the endpoint wide list and all the lists of
+// the individual namespace policies.
+func mergeLists(endpoint []string, policies map[string]NamespacePolicy, field func(NamespacePolicy) []string) []string {
+	merged := append([]string{}, endpoint...)
+	for _, policy := range policies {
+		merged = append(merged, field(policy)...)
+	}
+	return merged
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/goola-team/goola/log"
+)
+
+var (
+	requestIDPrefix = randomRequestIDPrefix() // Random per process, keeping IDs unique across nodes and restarts
+	requestIDNext   uint64                    // Sequence number of the next request ID
+)
+
+// requestIDKey is the context key of the correlation ID of a request.
+type requestIDKey struct{}
+
+// randomRequestIDPrefix returns the random prefix of the request IDs assigned
+// by this process.
+func randomRequestIDPrefix() uint32 {
+	var buf [4]byte
+	rand.Read(buf[:])
+	return binary.BigEndian.Uint32(buf[:])
+}
+
+// newRequestID returns a new correlation ID for a served request.
+func newRequestID() string {
+	return fmt.Sprintf("%08x%08x", requestIDPrefix, atomic.AddUint64(&requestIDNext, 1))
+}
+
+// WithRequestID returns a context carrying the given correlation ID, attributing
+// the work done with it to the request.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the correlation ID the server assigned to the request served
+// with the given context, or an empty string outside of requests.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestLogger returns a logger attaching the correlation ID of the request
+// served with the given context to every record, or the root logger outside of
+// requests.
+func RequestLogger(ctx context.Context) log.Logger {
+	if id := RequestID(ctx); id != "" {
+		return log.New("reqid", id)
+	}
+	return log.Root()
+}
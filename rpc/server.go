@@ -18,14 +18,17 @@ package rpc
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/tracing"
 	"gopkg.in/fatih/set.v0"
 )
 
@@ -73,6 +76,42 @@ func (s *RPCService) Modules() map[string]string {
 	return modules
 }
 
+// BatchNotifications enables or disables batching of the subscription
+// notifications sent over the calling connection. Batched notifications are
+// delivered as JSON-RPC batches, holding back each notification for at most the
+// delay configured on the server.
+func (s *RPCService) BatchNotifications(ctx context.Context, enabled bool) error {
+	notifier, supported := NotifierFromContext(ctx)
+	if !supported {
+		return ErrNotificationsUnsupported
+	}
+	if enabled && s.server.batchSize < 2 {
+		return ErrBatchingDisabled
+	}
+	notifier.setBatching(enabled)
+	return nil
+}
+
+// SetNotificationBatching sets the limits of the notification batching clients
+// may opt in to: the maximum number of notifications sent in a single batch and
+// the maximum time a notification may be held back. A maximum size below two
+// disables batching. It must be called before the server starts serving.
+func (s *Server) SetNotificationBatching(maxSize int, maxDelay time.Duration) {
+	s.batchSize, s.batchDelay = maxSize, maxDelay
+}
+
+// CallFallback is an alternative handler of method calls which failed locally,
+// given the full method name, the raw request parameters and the local error.
+// It returns the result to respond with, or false if it can't serve the call.
+type CallFallback func(ctx context.Context, method string, params interface{}, err error) (json.RawMessage, bool)
+
+// SetCallFallback sets the handler consulted when a method call fails, before
+// the error is returned to the client. It must be called before the server
+// starts serving.
+func (s *Server) SetCallFallback(fallback CallFallback) {
+	s.fallback = fallback
+}
+
 // RegisterName will create a service for the given rcvr type under the given name. When no methods on the given rcvr
 // match the criteria to be either a RPC method or a subscription an error is returned. Otherwise a new service is
 // created and added to the service collection this server instance serves.
@@ -147,7 +186,7 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 	// to send notification to clients. It is thight to the codec/connection. If the
 	// connection is closed the notifier will stop and cancels all active subscriptions.
 	if options&OptionSubscriptions == OptionSubscriptions {
-		ctx = context.WithValue(ctx, notifierKey{}, newNotifier(codec))
+		ctx = context.WithValue(ctx, notifierKey{}, newNotifier(codec, s.batchSize, s.batchDelay))
 	}
 	s.codecsMu.Lock()
 	if atomic.LoadInt32(&s.run) != 1 { // server stopped
@@ -258,6 +297,8 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.err != nil {
 		return codec.CreateErrorResponse(&req.id, req.err), nil
 	}
+	ctx, span := tracing.StartServer(ctx, req.method, "rpc.method", req.method, "rpc.request_id", req.reqid, "rpc.caller", codecCaller(codec))
+	defer span.End()
 
 	if req.isUnsubscribe { // cancel subscription, first param must be the subscription id
 		if len(req.args) >= 1 && req.args[0].Kind() == reflect.String {
@@ -279,7 +320,8 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.callb.isSubscribe {
 		subid, err := s.createSubscription(ctx, codec, req)
 		if err != nil {
-			return codec.CreateErrorResponse(&req.id, &callbackError{err.Error()}), nil
+			span.SetError(err)
+			return callbackErrorResponse(codec, req.id, err), nil
 		}
 
 		// active the subscription after the sub id was successfully sent to the client
@@ -316,27 +358,58 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
-			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
-			return res, nil
+			span.SetError(e)
+			if s.fallback != nil {
+				if result, ok := s.fallback(ctx, req.method, req.params, e); ok {
+					return codec.CreateResponse(req.id, result), nil
+				}
+			}
+			return callbackErrorResponse(codec, req.id, e), nil
 		}
 	}
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
 }
 
+// callbackErrorResponse creates the error response of a failed method call,
+// preserving the code and data of typed errors.
+func callbackErrorResponse(codec ServerCodec, id interface{}, err error) interface{} {
+	rpcErr, ok := err.(Error)
+	if !ok {
+		rpcErr = &callbackError{err.Error()}
+	}
+	if dataErr, ok := err.(DataError); ok {
+		if data := dataErr.ErrorData(); data != nil {
+			return codec.CreateErrorResponseWithInfo(&id, rpcErr, data)
+		}
+	}
+	return codec.CreateErrorResponse(&id, rpcErr)
+}
+
+// withNewRequestID assigns a correlation ID to a request, returning the context
+// to serve it with.
+func withNewRequestID(ctx context.Context, req *serverRequest) context.Context {
+	req.reqid = newRequestID()
+	return WithRequestID(ctx, req.reqid)
+}
+
 // exec executes the given request and writes the result back using the codec.
 func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest) {
 	var response interface{}
 	var callback func()
+
+	started := time.Now()
 	if req.err != nil {
 		response = codec.CreateErrorResponse(&req.id, req.err)
 	} else {
-		response, callback = s.handle(ctx, codec, req)
+		response, callback = s.handle(withNewRequestID(ctx, req), codec, req)
 	}
+	executed := time.Now()
 
 	if err := codec.Write(response); err != nil {
 		log.Error(fmt.Sprintf("%v\n", err))
 		codec.Close()
 	}
+	logRequest(codec, req, started, executed, time.Now())
 
 	// when request was a subscribe request this allows these subscriptions to be actived
 	if callback != nil {
@@ -349,21 +422,30 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*serverRequest) {
 	responses := make([]interface{}, len(requests))
 	var callbacks []func()
+
+	started, executed := make([]time.Time, len(requests)), make([]time.Time, len(requests))
 	for i, req := range requests {
+		started[i] = time.Now()
 		if req.err != nil {
 			responses[i] = codec.CreateErrorResponse(&req.id, req.err)
 		} else {
 			var callback func()
-			if responses[i], callback = s.handle(ctx, codec, req); callback != nil {
+			if responses[i], callback = s.handle(withNewRequestID(ctx, req), codec, req); callback != nil {
 				callbacks = append(callbacks, callback)
 			}
 		}
+		executed[i] = time.Now()
 	}
 
 	if err := codec.Write(responses); err != nil {
 		log.Error(fmt.Sprintf("%v\n", err))
 		codec.Close()
 	}
+	// The responses are serialized together, each one waiting for the entire batch
+	written := time.Now()
+	for i, req := range requests {
+		logRequest(codec, req, started[i], executed[i], written)
+	}
 
 	// when request holds one of more subscribe requests this allows these subscriptions to be activated
 	for _, c := range callbacks {
@@ -440,6 +522,13 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 
 		requests[i] = &serverRequest{id: r.id, err: &methodNotFoundError{r.service, r.method}}
 	}
-
+	received := time.Now()
+	for i, r := range reqs {
+		if requests[i].method = r.method; r.service != "" {
+			requests[i].method = r.service + serviceMethodSeparator + r.method
+		}
+		requests[i].params = r.params
+		requests[i].received = received
+	}
 	return requests, batch, nil
 }
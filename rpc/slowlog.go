@@ -0,0 +1,181 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goola-team/goola/log"
+)
+
+const (
+	// slowQueryLimit is the number of most recent slow requests retained.
+	slowQueryLimit = 256
+
+	// slowQueryParamsLimit is the maximum length of the logged parameter summary.
+	slowQueryParamsLimit = 256
+)
+
+// redactedNamespaces are the API namespaces whose request parameters may carry
+// credentials such as passphrases, and are never logged.
+var redactedNamespaces = map[string]bool{
+	"personal": true,
+}
+
+// redactedMethods are the methods outside the redacted namespaces whose request
+// parameters may carry credentials or key material, and are never logged.
+var redactedMethods = map[string]bool{
+	"shh_addPrivateKey":              true,
+	"shh_addSymKey":                  true,
+	"shh_generateSymKeyFromPassword": true,
+}
+
+// redactedParams replaces the parameter summary of requests carrying credentials.
+const redactedParams = "<redacted>"
+
+var (
+	slowQueryThreshold int64 // Minimum duration (ns) of requests logged as slow, 0 = disabled
+
+	slowQueriesLock sync.Mutex
+	slowQueries     []*SlowQuery // Ring buffer of recent slow requests
+	slowQueriesNext int          // Index of the next ring buffer slot to fill
+)
+
+// SlowQuery is a request which took longer than the slow query threshold to be
+// served, along with the breakdown of where the time was spent. Durations are
+// measured in milliseconds.
+type SlowQuery struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"requestId"`
+	Method    string    `json:"method"`
+	Params    string    `json:"params"`
+	Caller    string    `json:"caller"`
+	Queue     float64   `json:"queue"`     // Time between reading the request and starting its execution
+	Execute   float64   `json:"execute"`   // Time spent executing the request
+	Serialize float64   `json:"serialize"` // Time spent encoding and writing the response
+	Total     float64   `json:"total"`
+}
+
+// SetSlowQueryThreshold sets the minimum duration of requests recorded in the
+// slow query log, across all RPC servers. Zero disables the slow query log.
+func SetSlowQueryThreshold(threshold time.Duration) {
+	atomic.StoreInt64(&slowQueryThreshold, int64(threshold))
+}
+
+// SlowQueries returns the most recent slow requests served by any RPC server,
+// oldest first.
+func SlowQueries() []*SlowQuery {
+	slowQueriesLock.Lock()
+	defer slowQueriesLock.Unlock()
+
+	queries := make([]*SlowQuery, 0, len(slowQueries))
+	queries = append(queries, slowQueries[slowQueriesNext:]...)
+	return append(queries, slowQueries[:slowQueriesNext]...)
+}
+
+// logRequest logs a served request at trace level with its timing breakdown,
+// recording it in the slow query log if it exceeded the threshold.
+func logRequest(codec ServerCodec, req *serverRequest, started, executed, written time.Time) {
+	var (
+		queue     = started.Sub(req.received)
+		execute   = executed.Sub(started)
+		serialize = written.Sub(executed)
+		total     = written.Sub(req.received)
+		caller    = codecCaller(codec)
+	)
+	log.Trace("Served RPC request", "reqid", req.reqid, "method", req.method, "caller", caller, "queue", queue, "execute", execute, "serialize", serialize)
+
+	threshold := time.Duration(atomic.LoadInt64(&slowQueryThreshold))
+	if threshold == 0 || total < threshold {
+		return
+	}
+	query := &SlowQuery{
+		Time:      req.received,
+		RequestID: req.reqid,
+		Method:    req.method,
+		Params:    summarizeParams(req.method, req.params),
+		Caller:    caller,
+		Queue:     milliseconds(queue),
+		Execute:   milliseconds(execute),
+		Serialize: milliseconds(serialize),
+		Total:     milliseconds(total),
+	}
+	log.Warn("Slow RPC request", "reqid", req.reqid, "method", query.Method, "params", query.Params, "caller", caller,
+		"queue", queue, "execute", execute, "serialize", serialize, "total", total)
+
+	slowQueriesLock.Lock()
+	defer slowQueriesLock.Unlock()
+
+	if len(slowQueries) < slowQueryLimit {
+		slowQueries = append(slowQueries, query)
+		return
+	}
+	slowQueries[slowQueriesNext] = query
+	slowQueriesNext = (slowQueriesNext + 1) % slowQueryLimit
+}
+
+// summarizeParams returns the raw request parameters, truncated to a length
+// suitable for logging. The parameters of methods which may carry credentials
+// are redacted.
+func summarizeParams(method string, params interface{}) string {
+	if params == nil {
+		return ""
+	}
+	namespace := strings.SplitN(method, serviceMethodSeparator, 2)[0]
+	if redactedNamespaces[namespace] || redactedMethods[method] {
+		return redactedParams
+	}
+	var summary string
+	switch params := params.(type) {
+	case json.RawMessage:
+		summary = string(params)
+	default:
+		summary = fmt.Sprint(params)
+	}
+	if len(summary) > slowQueryParamsLimit {
+		summary = summary[:slowQueryParamsLimit] + "..."
+	}
+	return summary
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// callerCodec is a server codec annotated with the remote party it serves.
+type callerCodec struct {
+	ServerCodec
+	caller string
+}
+
+// withCaller annotates a server codec with the remote party it serves.
+func withCaller(codec ServerCodec, caller string) ServerCodec {
+	return &callerCodec{ServerCodec: codec, caller: caller}
+}
+
+// codecCaller returns the remote party served by a codec, if known.
+func codecCaller(codec ServerCodec) string {
+	if c, ok := codec.(*callerCodec); ok {
+		return c.caller
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type SlowService struct{}
+
+func (s *SlowService) Sleep(ms int) int {
+	time.Sleep(time.Duration(ms) * time.Millisecond)
+	return ms
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	defer SetSlowQueryThreshold(0)
+	SetSlowQueryThreshold(20 * time.Millisecond)
+
+	server := NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("slow", new(SlowService)); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	before := len(SlowQueries())
+	for _, ms := range []int{0, 30} {
+		if err := client.Call(nil, "slow_sleep", ms); err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+	}
+	// Requests are logged after the response is written, wait for the server
+	queries := SlowQueries()
+	for i := 0; i < 50 && len(queries) == before; i++ {
+		time.Sleep(10 * time.Millisecond)
+		queries = SlowQueries()
+	}
+	if len(queries) != before+1 {
+		t.Fatalf("slow query count mismatch: have %d, want %d", len(queries), before+1)
+	}
+	query := queries[len(queries)-1]
+	if query.Method != "slow_sleep" || query.Params != "[30]" || query.Caller != "inproc" {
+		t.Errorf("slow query mismatch: have %s(%s) from %q, want slow_sleep([30]) from inproc", query.Method, query.Params, query.Caller)
+	}
+	if query.Execute < 30 || query.Total < query.Execute {
+		t.Errorf("slow query timing mismatch: execute %vms, total %vms", query.Execute, query.Total)
+	}
+}
+
+// Tests that the parameters of requests which may carry credentials are never
+// recorded in the slow query log.
+func TestSlowQueryLogRedaction(t *testing.T) {
+	defer SetSlowQueryThreshold(0)
+	SetSlowQueryThreshold(20 * time.Millisecond)
+
+	server := NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("personal", new(SlowService)); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	before := len(SlowQueries())
+	if err := client.Call(nil, "personal_sleep", 30); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	queries := SlowQueries()
+	for i := 0; i < 50 && len(queries) == before; i++ {
+		time.Sleep(10 * time.Millisecond)
+		queries = SlowQueries()
+	}
+	if len(queries) != before+1 {
+		t.Fatalf("slow query count mismatch: have %d, want %d", len(queries), before+1)
+	}
+	if query := queries[len(queries)-1]; query.Method != "personal_sleep" || query.Params != redactedParams {
+		t.Errorf("slow query mismatch: have %s(%s), want personal_sleep(%s)", query.Method, query.Params, redactedParams)
+	}
+	// Credential carrying methods of other namespaces are redacted too
+	params := json.RawMessage(`["0x1234"]`)
+	if summary := summarizeParams("shh_addPrivateKey", params); summary != redactedParams {
+		t.Errorf("private key not redacted: have %s", summary)
+	}
+	if summary := summarizeParams("shh_getPublicKey", params); summary != string(params) {
+		t.Errorf("params summary mismatch: have %s, want %s", summary, params)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"sync"
+	"time"
 )
 
 var (
@@ -27,6 +28,9 @@ var (
 	ErrNotificationsUnsupported = errors.New("notifications not supported")
 	// ErrNotificationNotFound is returned when the notification for the given id is not found
 	ErrSubscriptionNotFound = errors.New("subscription not found")
+	// ErrBatchingDisabled is returned when a client opts in to notification batching
+	// which the server does not allow
+	ErrBatchingDisabled = errors.New("notification batching disabled")
 )
 
 // ID defines a pseudo random number that is used to identify RPC subscriptions.
@@ -55,15 +59,25 @@ type Notifier struct {
 	subMu    sync.RWMutex // guards active and inactive maps
 	active   map[ID]*Subscription
 	inactive map[ID]*Subscription
+
+	batchSize  int           // Maximum number of notifications per batch
+	batchDelay time.Duration // Maximum time a notification is held back
+	batchMu    sync.Mutex    // guards the batching fields below
+	batching   bool          // Whether the client opted in to batching
+	pending    []interface{} // Notifications waiting to be sent as a batch
+	flushTimer *time.Timer   // Timer sending the pending batch once the delay expires
 }
 
 // newNotifier creates a new notifier that can be used to send subscription
-// notifications to the client.
-func newNotifier(codec ServerCodec) *Notifier {
+// notifications to the client, batching them within the given limits if the
+// client opts in.
+func newNotifier(codec ServerCodec, batchSize int, batchDelay time.Duration) *Notifier {
 	return &Notifier{
-		codec:    codec,
-		active:   make(map[ID]*Subscription),
-		inactive: make(map[ID]*Subscription),
+		codec:      codec,
+		active:     make(map[ID]*Subscription),
+		inactive:   make(map[ID]*Subscription),
+		batchSize:  batchSize,
+		batchDelay: batchDelay,
 	}
 }
 
@@ -94,6 +108,9 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	sub, active := n.active[id]
 	if active {
 		notification := n.codec.CreateNotification(string(id), sub.namespace, data)
+		if n.enqueue(notification) {
+			return nil
+		}
 		if err := n.codec.Write(notification); err != nil {
 			n.codec.Close()
 			return err
@@ -102,6 +119,61 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	return nil
 }
 
+// setBatching enables or disables notification batching, sending any pending
+// notifications when disabled.
+func (n *Notifier) setBatching(enabled bool) {
+	n.batchMu.Lock()
+	n.batching = enabled
+	n.batchMu.Unlock()
+
+	if !enabled {
+		n.flush()
+	}
+}
+
+// enqueue adds a notification to the pending batch if batching is enabled,
+// sending the batch once it is full. It reports whether the notification was
+// taken care of.
+func (n *Notifier) enqueue(notification interface{}) bool {
+	n.batchMu.Lock()
+	if !n.batching {
+		n.batchMu.Unlock()
+		return false
+	}
+	n.pending = append(n.pending, notification)
+	full := len(n.pending) >= n.batchSize
+	if !full && n.flushTimer == nil {
+		n.flushTimer = time.AfterFunc(n.batchDelay, n.flush)
+	}
+	n.batchMu.Unlock()
+
+	if full {
+		n.flush()
+	}
+	return true
+}
+
+// flush sends the pending notifications as a single batch. If an error occurs
+// the RPC connection is closed.
+func (n *Notifier) flush() {
+	n.batchMu.Lock()
+	defer n.batchMu.Unlock()
+
+	if n.flushTimer != nil {
+		n.flushTimer.Stop()
+		n.flushTimer = nil
+	}
+	if len(n.pending) == 0 {
+		return
+	}
+	batch := n.pending
+	n.pending = nil
+
+	if err := n.codec.Write(batch); err != nil {
+		n.codec.Close()
+	}
+}
+
 // Closed returns a channel that is closed when the RPC connection is closed.
 func (n *Notifier) Closed() <-chan interface{} {
 	return n.codec.Closed()
@@ -110,6 +182,9 @@ func (n *Notifier) Closed() <-chan interface{} {
 // unsubscribe a subscription.
 // If the subscription could not be found ErrSubscriptionNotFound is returned.
 func (n *Notifier) unsubscribe(id ID) error {
+	// Deliver the notifications created before the unsubscription first
+	n.flush()
+
 	n.subMu.Lock()
 	defer n.subMu.Unlock()
 	if s, found := n.active[id]; found {
@@ -328,3 +328,42 @@ func TestSubscriptionMultipleNamespaces(t *testing.T) {
 		}
 	}
 }
+
+func TestNotificationBatching(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	notifier := newNotifier(NewJSONCodec(serverConn), 3, time.Hour)
+	sub := notifier.CreateSubscription()
+	notifier.activate(sub.ID, "goolabackend")
+	notifier.setBatching(true)
+
+	go func() {
+		for i := 0; i < 4; i++ {
+			notifier.Notify(sub.ID, i)
+		}
+		// Disabling batching sends the incomplete batch right away
+		notifier.setBatching(false)
+		notifier.Notify(sub.ID, 4)
+	}()
+
+	in := json.NewDecoder(clientConn)
+	for i, want := range []int{3, 1, 1} {
+		var raw json.RawMessage
+		if err := in.Decode(&raw); err != nil {
+			t.Fatalf("frame %d: failed to read: %v", i, err)
+		}
+		var batch []jsonNotification
+		if want == 1 && !isBatch(raw) {
+			batch = make([]jsonNotification, 1)
+			if err := json.Unmarshal(raw, &batch[0]); err != nil {
+				t.Fatalf("frame %d: invalid notification: %v", i, err)
+			}
+		} else if err := json.Unmarshal(raw, &batch); err != nil {
+			t.Fatalf("frame %d: invalid notification batch: %v", i, err)
+		}
+		if len(batch) != want {
+			t.Fatalf("frame %d: batch size mismatch: have %d, want %d", i, len(batch), want)
+		}
+	}
+}
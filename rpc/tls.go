@@ -0,0 +1,101 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// NewTLSConfig creates the TLS configuration for serving RPC endpoints from the
+// given PEM encoded certificate and key files. If a client CA bundle is given,
+// clients may authenticate with a certificate signed by one of its authorities.
+// Clients without a certificate are still accepted, but only gain access to the
+// namespaces not requiring a permission tier.
+func NewTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile != "" {
+		blob, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA bundle: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(blob) {
+			return nil, errors.New("no certificates found in client CA bundle")
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+// certTierHandler is an HTTP middleware granting access to API namespaces based
+// on the permission tiers of the verified client certificate of a request.
+type certTierHandler struct {
+	next   http.Handler
+	public map[string]bool            // Namespaces accessible without a client certificate
+	tiers  map[string]map[string]bool // Namespaces granted by each permission tier
+}
+
+// NewClientCertHandler wraps an RPC handler, restricting the namespaces outside
+// of the public ones to clients presenting a verified certificate. The tier of a
+// certificate is taken from the organizational units of its subject, each tier
+// granting access to the namespaces mapped to it.
+func NewClientCertHandler(next http.Handler, public []string, tiers map[string][]string) http.Handler {
+	handler := &certTierHandler{
+		next:   next,
+		public: make(map[string]bool),
+		tiers:  make(map[string]map[string]bool),
+	}
+	for _, namespace := range public {
+		handler.public[namespace] = true
+	}
+	for tier, namespaces := range tiers {
+		handler.tiers[tier] = make(map[string]bool)
+		for _, namespace := range namespaces {
+			handler.tiers[tier][namespace] = true
+		}
+	}
+	return handler
+}
+
+// ServeHTTP implements http.Handler, restricting the namespaces accessible by
+// the request to the public ones and those granted by its client certificate.
+func (h *certTierHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	granted := make(map[string]bool)
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		for _, tier := range r.TLS.VerifiedChains[0][0].Subject.OrganizationalUnit {
+			for namespace := range h.tiers[tier] {
+				granted[namespace] = true
+			}
+		}
+	}
+	h.next.ServeHTTP(w, withNamespaceFilter(r, func(namespace string) bool {
+		return h.public[namespace] || granted[namespace]
+	}))
+}
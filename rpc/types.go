@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/goola-team/goola/common/hexutil"
 	"gopkg.in/fatih/set.v0"
@@ -61,6 +62,11 @@ type serverRequest struct {
 	args          []reflect.Value
 	isUnsubscribe bool
 	err           Error
+
+	method   string      // Full method name as requested, for logging and fallbacks
+	params   interface{} // Raw request parameters, for logging and fallbacks
+	received time.Time   // Time the request was read from the connection
+	reqid    string      // Correlation ID assigned to the request
 }
 
 type serviceRegistry map[string]*service // collection of services
@@ -74,6 +80,11 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	batchSize  int           // Maximum number of notifications per batch, batching disabled if below 2
+	batchDelay time.Duration // Maximum time a notification is held back for batching
+
+	fallback CallFallback // Handler of the method calls failing locally, nil if none
 }
 
 // rpcRequest represents a raw incoming RPC request
@@ -92,6 +103,13 @@ type Error interface {
 	ErrorCode() int // returns the code
 }
 
+// DataError is implemented by RPC errors carrying structured details in the
+// data field of the error response.
+type DataError interface {
+	Error() string          // returns the message
+	ErrorData() interface{} // returns the error data
+}
+
 // ServerCodec implements reading, parsing and writing RPC messages for the server side of
 // a RPC session. Implementations must be go-routine safe since the codec can be called in
 // multiple go-routines concurrently.
@@ -40,11 +40,31 @@ func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
 	return websocket.Server{
 		Handshake: wsHandshakeValidator(allowedOrigins),
 		Handler: func(conn *websocket.Conn) {
-			srv.ServeCodec(NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions)
+			srv.ServeCodec(withCaller(filterCodec(conn.Request(), NewJSONCodec(conn)), conn.Request().RemoteAddr), OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
 }
 
+// WebsocketHandlerWithPolicies returns a handler that serves JSON-RPC to WebSocket
+// connections, restricting the origins and virtual hosts allowed to access
+// individual API namespaces beyond the endpoint wide allowed origins.
+func (srv *Server) WebsocketHandlerWithPolicies(allowedOrigins []string, policies map[string]NamespacePolicy) http.Handler {
+	if len(policies) == 0 {
+		return srv.WebsocketHandler(allowedOrigins)
+	}
+	// Namespaces without a policy inherit the origins accepted by the endpoint,
+	// whereas virtual hosts are not checked on websocket endpoints by default.
+	origins := newAllowList(wsOrigins(allowedOrigins))
+	hosts := newAllowList([]string{"*"})
+	enforcer := newPolicyEnforcer(origins, hosts, policies)
+
+	merged := mergeLists(allowedOrigins, policies, func(p NamespacePolicy) []string { return p.Cors })
+	if len(allowedOrigins) == 0 {
+		merged = append(merged, wsOrigins(nil)...)
+	}
+	return &policyHandler{next: srv.WebsocketHandler(merged), enforcer: enforcer}
+}
+
 // NewWSServer creates a new websocket RPC server around an API provider.
 //
 // Deprecated: use Server.WebsocketHandler
@@ -59,21 +79,11 @@ func wsHandshakeValidator(allowedOrigins []string) func(*websocket.Config, *http
 	origins := set.New()
 	allowAllOrigins := false
 
-	for _, origin := range allowedOrigins {
+	for _, origin := range wsOrigins(allowedOrigins) {
 		if origin == "*" {
 			allowAllOrigins = true
 		}
-		if origin != "" {
-			origins.Add(strings.ToLower(origin))
-		}
-	}
-
-	// allow localhost if no allowedOrigins are specified.
-	if len(origins.List()) == 0 {
-		origins.Add("http://localhost")
-		if hostname, err := os.Hostname(); err == nil {
-			origins.Add("http://" + strings.ToLower(hostname))
-		}
+		origins.Add(strings.ToLower(origin))
 	}
 
 	log.Debug(fmt.Sprintf("Allowed origin(s) for WS RPC interface %v\n", origins.List()))
@@ -90,6 +100,25 @@ func wsHandshakeValidator(allowedOrigins []string) func(*websocket.Config, *http
 	return f
 }
 
+// wsOrigins returns the non-empty allowed origins, defaulting to the local
+// machine if none are specified.
+func wsOrigins(allowedOrigins []string) []string {
+	var origins []string
+	for _, origin := range allowedOrigins {
+		if origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	// allow localhost if no allowedOrigins are specified.
+	if len(origins) == 0 {
+		origins = append(origins, "http://localhost")
+		if hostname, err := os.Hostname(); err == nil {
+			origins = append(origins, "http://"+strings.ToLower(hostname))
+		}
+	}
+	return origins
+}
+
 // DialWebsocket creates a new RPC client that communicates with a JSON-RPC server
 // that is listening on the given endpoint.
 //
@@ -0,0 +1,251 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goola-team/goola/log"
+	"github.com/goola-team/goola/metrics"
+)
+
+const (
+	exportQueueSize = 4096             // Number of finished spans queued for export before dropping new ones
+	exportBatchSize = 512              // Maximum number of spans exported in a single request
+	exportInterval  = 5 * time.Second  // Interval between exports of the queued spans
+	exportTimeout   = 10 * time.Second // Maximum duration of an export request
+)
+
+var (
+	exportedSpanMeter = metrics.NewMeter("tracing/spans/exported")
+	droppedSpanMeter  = metrics.NewMeter("tracing/spans/dropped")
+)
+
+// Exporter sends finished spans in batches to an OpenTelemetry collector, using
+// the JSON encoding of the OTLP/HTTP protocol.
+type Exporter struct {
+	endpoint string
+	service  string
+	client   *http.Client
+
+	queue chan *Span
+	quit  chan chan struct{}
+}
+
+// NewExporter creates an exporter sending spans to the given OTLP/HTTP traces
+// endpoint (e.g. http://localhost:4318/v1/traces), attributing them to the named
+// service.
+func NewExporter(endpoint string, service string) *Exporter {
+	e := &Exporter{
+		endpoint: endpoint,
+		service:  service,
+		client:   &http.Client{Timeout: exportTimeout},
+		queue:    make(chan *Span, exportQueueSize),
+		quit:     make(chan chan struct{}),
+	}
+	go e.loop()
+	return e
+}
+
+// Close exports the spans still queued and stops the exporter.
+func (e *Exporter) Close() {
+	done := make(chan struct{})
+	e.quit <- done
+	<-done
+}
+
+// export queues a finished span for export, dropping it if the queue is full.
+func (e *Exporter) export(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+		droppedSpanMeter.Mark(1)
+	}
+}
+
+// loop gathers the finished spans into batches, sending them either when full
+// or periodically.
+func (e *Exporter) loop() {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, exportBatchSize)
+	for {
+		select {
+		case span := <-e.queue:
+			if batch = append(batch, span); len(batch) == exportBatchSize {
+				e.send(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				e.send(batch)
+				batch = batch[:0]
+			}
+		case done := <-e.quit:
+			for len(e.queue) > 0 {
+				batch = append(batch, <-e.queue)
+			}
+			for len(batch) > 0 {
+				n := len(batch)
+				if n > exportBatchSize {
+					n = exportBatchSize
+				}
+				e.send(batch[:n])
+				batch = batch[n:]
+			}
+			close(done)
+			return
+		}
+	}
+}
+
+// send exports a batch of spans to the collector.
+func (e *Exporter) send(batch []*Span) {
+	blob, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		log.Warn("Failed to encode trace spans", "err", err)
+		return
+	}
+	res, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(blob))
+	if err != nil {
+		droppedSpanMeter.Mark(int64(len(batch)))
+		log.Debug("Failed to export trace spans", "endpoint", e.endpoint, "err", err)
+		return
+	}
+	res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		droppedSpanMeter.Mark(int64(len(batch)))
+		log.Debug("Trace collector refused spans", "endpoint", e.endpoint, "status", res.Status)
+		return
+	}
+	exportedSpanMeter.Mark(int64(len(batch)))
+}
+
+// OTLP/JSON representation of an export request, as specified by the
+// opentelemetry-proto JSON mapping.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              int             `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            *otlpStatus     `json:"status,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+)
+
+// otlpStatusError is the OTLP status code of failed spans.
+const otlpStatusError = 2
+
+// encode converts a batch of spans into an OTLP export request.
+func (e *Exporter) encode(batch []*Span) *otlpRequest {
+	spans := make([]otlpSpan, len(batch))
+	for i, span := range batch {
+		spans[i] = otlpSpan{
+			TraceID:           fmt.Sprintf("%x", span.trace),
+			SpanID:            fmt.Sprintf("%x", span.id),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.attrs),
+		}
+		if span.parent != ([8]byte{}) {
+			spans[i].ParentSpanID = fmt.Sprintf("%x", span.parent)
+		}
+		if span.err != nil {
+			spans[i].Status = &otlpStatus{Code: otlpStatusError, Message: span.err.Error()}
+		}
+	}
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource:   otlpResource{Attributes: otlpAttributes([]interface{}{"service.name", e.service})},
+			ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/goola-team/goola"}, Spans: spans}},
+		}},
+	}
+}
+
+// otlpAttributes converts key/value pairs into OTLP attributes. Values of types
+// without an OTLP counterpart are formatted as strings.
+func otlpAttributes(kv []interface{}) []otlpAttribute {
+	attrs := make([]otlpAttribute, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		var value otlpValue
+		switch v := kv[i+1].(type) {
+		case string:
+			value.StringValue = &v
+		case int:
+			s := strconv.FormatInt(int64(v), 10)
+			value.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case uint64:
+			s := strconv.FormatUint(v, 10)
+			value.IntValue = &s
+		case float64:
+			value.DoubleValue = &v
+		case bool:
+			value.BoolValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		attrs = append(attrs, otlpAttribute{Key: fmt.Sprint(kv[i]), Value: value})
+	}
+	return attrs
+}
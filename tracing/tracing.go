@@ -0,0 +1,145 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package tracing records the spans of timed operations across the node, such as
+// served RPC requests, sync stages and block imports, and exports them to an
+// OpenTelemetry collector. Spans are only recorded while an exporter is set, the
+// tracing calls being no-ops otherwise.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Kinds of spans, as defined by OpenTelemetry.
+const (
+	kindInternal = 1 // Operation internal to the node
+	kindServer   = 2 // Request served to a remote party
+)
+
+// active is the exporter the finished spans are handed to, nil if tracing is
+// disabled.
+var active atomic.Value
+
+// SetExporter sets the exporter of the spans recorded from now on. Nil disables
+// tracing.
+func SetExporter(exporter *Exporter) {
+	active.Store(exporter)
+}
+
+// activeExporter returns the exporter of the spans, or nil if tracing is disabled.
+func activeExporter() *Exporter {
+	exporter, _ := active.Load().(*Exporter)
+	return exporter
+}
+
+// Span is a single timed operation of a trace. All its methods are safe to call
+// on a nil span, which is what's returned while tracing is disabled.
+type Span struct {
+	trace  [16]byte
+	id     [8]byte
+	parent [8]byte
+	name   string
+	kind   int
+	start  time.Time
+	end    time.Time
+	attrs  []interface{}
+	err    error
+
+	exporter *Exporter
+}
+
+// spanKey is the context key of the span an operation runs in.
+type spanKey struct{}
+
+// Start begins a span with the given name and key/value attributes, as a child
+// of the span carried by the context if any. It returns the context to run the
+// operation with, carrying the new span.
+func Start(ctx context.Context, name string, attrs ...interface{}) (context.Context, *Span) {
+	return start(ctx, kindInternal, name, attrs)
+}
+
+// StartServer begins a span of a request served to a remote party, otherwise
+// the same as Start.
+func StartServer(ctx context.Context, name string, attrs ...interface{}) (context.Context, *Span) {
+	return start(ctx, kindServer, name, attrs)
+}
+
+func start(ctx context.Context, kind int, name string, attrs []interface{}) (context.Context, *Span) {
+	exporter := activeExporter()
+	if exporter == nil {
+		return ctx, nil
+	}
+	span := &Span{
+		name:     name,
+		kind:     kind,
+		start:    time.Now(),
+		attrs:    attrs,
+		exporter: exporter,
+	}
+	if parent := FromContext(ctx); parent != nil {
+		span.trace, span.parent = parent.trace, parent.id
+	} else {
+		rand.Read(span.trace[:])
+	}
+	rand.Read(span.id[:])
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// FromContext returns the span carried by the context, or nil if none.
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// TraceID returns the hex identifier of the trace the span belongs to, or an
+// empty string for a nil span.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", s.trace)
+}
+
+// SetAttributes adds key/value attributes to the span.
+func (s *Span) SetAttributes(attrs ...interface{}) {
+	if s == nil {
+		return
+	}
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// SetError marks the span as failed with the given error, if not nil.
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.err = err
+}
+
+// End finishes the span, handing it to the exporter.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	s.exporter.export(s)
+}
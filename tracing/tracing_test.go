@@ -0,0 +1,121 @@
+// Copyright 2018 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Tests that spans are not recorded while no exporter is set.
+func TestDisabled(t *testing.T) {
+	SetExporter(nil)
+
+	ctx, span := Start(context.Background(), "op", "key", "value")
+	if span != nil {
+		t.Fatalf("span recorded without exporter")
+	}
+	if FromContext(ctx) != nil {
+		t.Fatalf("context carries span without exporter")
+	}
+	// Nil spans must be safe to use
+	span.SetAttributes("other", 1)
+	span.SetError(errors.New("failure"))
+	span.End()
+
+	if id := span.TraceID(); id != "" {
+		t.Fatalf("trace id mismatch: have %q, want empty", id)
+	}
+}
+
+// Tests that finished spans are exported to the collector on close, children
+// being linked to their parents within the same trace.
+func TestExport(t *testing.T) {
+	requests := make(chan *otlpRequest, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := new(otlpRequest)
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			t.Errorf("failed to decode export request: %v", err)
+		}
+		requests <- req
+	}))
+	defer server.Close()
+
+	exporter := NewExporter(server.URL, "goola")
+	SetExporter(exporter)
+	defer SetExporter(nil)
+
+	ctx, root := StartServer(context.Background(), "root", "method", "eth_call", "id", 7)
+	_, child := Start(ctx, "child", "number", uint64(42), "ok", false)
+	child.SetError(errors.New("failure"))
+	child.End()
+	root.End()
+
+	exporter.Close()
+	close(requests)
+
+	var spans []otlpSpan
+	for req := range requests {
+		if len(req.ResourceSpans) != 1 {
+			t.Fatalf("resource spans count mismatch: have %d, want 1", len(req.ResourceSpans))
+		}
+		res := req.ResourceSpans[0]
+		if attrs := res.Resource.Attributes; len(attrs) != 1 || attrs[0].Key != "service.name" || *attrs[0].Value.StringValue != "goola" {
+			t.Errorf("resource attributes mismatch: have %+v", attrs)
+		}
+		for _, scope := range res.ScopeSpans {
+			spans = append(spans, scope.Spans...)
+		}
+	}
+	if len(spans) != 2 {
+		t.Fatalf("exported span count mismatch: have %d, want 2", len(spans))
+	}
+	// Spans are exported in the order they ended
+	exChild, exRoot := spans[0], spans[1]
+	if exRoot.Name != "root" || exChild.Name != "child" {
+		t.Fatalf("span names mismatch: have %q and %q", exRoot.Name, exChild.Name)
+	}
+	if exRoot.TraceID != root.TraceID() || exChild.TraceID != root.TraceID() {
+		t.Errorf("trace id mismatch: root %s, child %s, want %s", exRoot.TraceID, exChild.TraceID, root.TraceID())
+	}
+	if exRoot.ParentSpanID != "" {
+		t.Errorf("root span has parent %s", exRoot.ParentSpanID)
+	}
+	if exChild.ParentSpanID != exRoot.SpanID {
+		t.Errorf("child parent mismatch: have %s, want %s", exChild.ParentSpanID, exRoot.SpanID)
+	}
+	if exRoot.Kind != kindServer || exChild.Kind != kindInternal {
+		t.Errorf("span kinds mismatch: root %d, child %d", exRoot.Kind, exChild.Kind)
+	}
+	if exRoot.Status != nil {
+		t.Errorf("root span failed: %+v", exRoot.Status)
+	}
+	if exChild.Status == nil || exChild.Status.Code != otlpStatusError || exChild.Status.Message != "failure" {
+		t.Errorf("child status mismatch: have %+v", exChild.Status)
+	}
+	// Check the attribute encodings
+	if attrs := exRoot.Attributes; len(attrs) != 2 || *attrs[0].Value.StringValue != "eth_call" || *attrs[1].Value.IntValue != "7" {
+		t.Errorf("root attributes mismatch: have %+v", attrs)
+	}
+	if attrs := exChild.Attributes; len(attrs) != 2 || *attrs[0].Value.IntValue != "42" || *attrs[1].Value.BoolValue {
+		t.Errorf("child attributes mismatch: have %+v", attrs)
+	}
+}